@@ -0,0 +1,11 @@
+// Package api holds the contract of the REST API of the gateway.
+package api
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 3 document of the REST API.
+//
+// It is generated by "make generate" from libs/protos and openapi.base.yaml.
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"api-gateway/internal/app"
 	"api-gateway/internal/config"
@@ -14,6 +16,9 @@ const (
 	envLocal = "local"
 	envDev   = "dev"
 	envProd  = "prod"
+
+	// shutdownTimeout limits the time the active requests are finished on stop.
+	shutdownTimeout = 10 * time.Second
 )
 
 func main() {
@@ -23,7 +28,9 @@ func main() {
 
 	log.Info("API Gateway started", slog.String("env", cfg.Env))
 
-	application := app.New(log)
+	application := app.New(log, cfg)
+
+	go application.HTTPServer.MustRun()
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
@@ -32,6 +39,12 @@ func main() {
 
 	log.Info("stopping API Gateway", slog.String("signal", sysSign.String()))
 
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	application.HTTPServer.Stop(ctx)
+	application.Close()
+
 	log.Info("API Gateway stopped")
 }
 
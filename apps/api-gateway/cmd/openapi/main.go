@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// publicOperations are the ids of the operations which are called without the token,
+// they match the routes left out of the auth middleware by the router.
+var publicOperations = []string{"Auth_Register", "Auth_Login"}
+
+const header = "Code generated by cmd/openapi from libs/protos and api/openapi.base.yaml. DO NOT EDIT."
+
+// openapi completes the OpenAPI document generated from the protos
+// with the parts the protos do not describe: the routes of the handlers
+// of the gateway, the auth schemes and the error model of the responses.
+//
+// Usage: openapi --in=libs/gen/openapi/openapi.yaml --base=api/openapi.base.yaml --out=api/openapi.yaml
+func main() {
+	var in, base, out string
+
+	flag.StringVar(&in, "in", "", "path to the document generated from the protos")
+	flag.StringVar(&base, "base", "", "path to the document merged into the generated one")
+	flag.StringVar(&out, "out", "", "path to the resulting document")
+	flag.Parse()
+
+	if in == "" || base == "" || out == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(in, base, out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(in, base, out string) error {
+	doc, err := read(in)
+	if err != nil {
+		return err
+	}
+
+	baseDoc, err := read(base)
+	if err != nil {
+		return err
+	}
+
+	merge(doc.Content[0], baseDoc.Content[0])
+	completeOperations(doc.Content[0])
+
+	doc.HeadComment = header
+
+	var buf bytes.Buffer
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+
+	if err := os.WriteFile(out, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write document: %w", err)
+	}
+
+	return nil
+}
+
+// read reads the YAML document, keeping the order of the keys.
+func read(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s is not the YAML mapping", path)
+	}
+
+	return &doc, nil
+}
+
+// merge merges the src mapping into the dst one.
+// The nested mappings are merged, the other values of src replace the ones of dst.
+func merge(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i].Value, src.Content[i+1]
+
+		if existing := get(dst, key); existing != nil &&
+			existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			merge(existing, value)
+
+			continue
+		}
+
+		set(dst, key, value)
+	}
+}
+
+// completeOperations adds the error response to every operation
+// and lets the public operations be called without the token.
+func completeOperations(doc *yaml.Node) {
+	paths := get(doc, "paths")
+	if paths == nil {
+		return
+	}
+
+	for i := 1; i < len(paths.Content); i += 2 {
+		item := paths.Content[i]
+
+		for j := 1; j < len(item.Content); j += 2 {
+			op := item.Content[j]
+			if op.Kind != yaml.MappingNode {
+				continue
+			}
+
+			responses := get(op, "responses")
+			if responses == nil {
+				responses = &yaml.Node{Kind: yaml.MappingNode}
+				set(op, "responses", responses)
+			}
+
+			if get(responses, "default") == nil {
+				set(responses, "default", mapping("$ref", "#/components/responses/Error"))
+			}
+
+			if get(op, "security") == nil && isPublic(op) {
+				set(op, "security", &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle})
+			}
+		}
+	}
+}
+
+func isPublic(op *yaml.Node) bool {
+	id := get(op, "operationId")
+
+	return id != nil && slices.Contains(publicOperations, id.Value)
+}
+
+// get returns the value of the key of the mapping, or nil if there is no such key.
+func get(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// set sets the value of the key of the mapping, appending the key if there is no such one.
+func set(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+
+			return
+		}
+	}
+
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+// mapping returns the mapping of the scalar keys and values, like {key: value}.
+func mapping(pairs ...string) *yaml.Node {
+	m := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		set(m, pairs[i], &yaml.Node{Kind: yaml.ScalarNode, Value: pairs[i+1]})
+	}
+
+	return m
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const generated = `openapi: 3.0.3
+info:
+  title: Creative Learning Platform API
+  version: v1
+paths:
+  /v1/auth/login:
+    post:
+      operationId: Auth_Login
+      responses:
+        "200":
+          description: OK
+  /v1/assignments/{id}:
+    get:
+      operationId: Tasks_GetTeacherAssignment
+      responses:
+        "200":
+          description: OK
+    delete:
+      operationId: Tasks_DeleteAssignment
+      responses:
+        default:
+          description: Custom
+    parameters:
+      - name: id
+        in: path
+components:
+  schemas:
+    Assignment:
+      type: object
+`
+
+const base = `info:
+  description: REST API of the platform.
+paths:
+  /calendar/{token}:
+    get:
+      operationId: Calendar_Feed
+      security: []
+      responses:
+        "200":
+          description: iCalendar feed
+security:
+  - bearer: []
+components:
+  securitySchemes:
+    bearer:
+      type: http
+      scheme: bearer
+`
+
+// process runs the post-processor on the documents and returns the result.
+func process(t *testing.T, in string, baseDoc string) (string, error) {
+	t.Helper()
+
+	dir := t.TempDir()
+	inPath := filepath.Join(dir, "in.yaml")
+	basePath := filepath.Join(dir, "base.yaml")
+	outPath := filepath.Join(dir, "out.yaml")
+
+	require.NoError(t, os.WriteFile(inPath, []byte(in), 0o644))
+	require.NoError(t, os.WriteFile(basePath, []byte(baseDoc), 0o644))
+
+	if err := run(inPath, basePath, outPath); err != nil {
+		return "", err
+	}
+
+	out, err := os.ReadFile(outPath)
+	require.NoError(t, err)
+
+	return string(out), nil
+}
+
+// document is the part of the resulting document checked by the tests.
+type document struct {
+	Info struct {
+		Title       string `yaml:"title"`
+		Description string `yaml:"description"`
+	} `yaml:"info"`
+	Paths      map[string]map[string]any `yaml:"paths"`
+	Security   []map[string][]string     `yaml:"security"`
+	Components struct {
+		Schemas         map[string]any `yaml:"schemas"`
+		SecuritySchemes map[string]any `yaml:"securitySchemes"`
+	} `yaml:"components"`
+}
+
+// operation returns the operation of the path of the document.
+func operation(t *testing.T, doc document, path string, method string) map[string]any {
+	t.Helper()
+
+	require.Contains(t, doc.Paths, path)
+	require.Contains(t, doc.Paths[path], method)
+
+	op, ok := doc.Paths[path][method].(map[string]any)
+	require.True(t, ok)
+
+	return op
+}
+
+func TestRun(t *testing.T) {
+	out, err := process(t, generated, base)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(out, "# "+header+"\n"), "header marks the document as generated")
+
+	var doc document
+	require.NoError(t, yaml.Unmarshal([]byte(out), &doc))
+
+	t.Run("merge", func(t *testing.T) {
+		assert.Equal(t, "Creative Learning Platform API", doc.Info.Title, "nested mappings are merged")
+		assert.Equal(t, "REST API of the platform.", doc.Info.Description)
+		assert.Contains(t, doc.Components.Schemas, "Assignment")
+		assert.Contains(t, doc.Components.SecuritySchemes, "bearer")
+		assert.Equal(t, []map[string][]string{{"bearer": {}}}, doc.Security)
+		assert.Contains(t, doc.Paths, "/calendar/{token}", "routes of the gateway handlers are added")
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		responses := operation(t, doc, "/v1/assignments/{id}", "get")["responses"].(map[string]any)
+		assert.Equal(t, map[string]any{"$ref": "#/components/responses/Error"}, responses["default"])
+		assert.Contains(t, responses, "200")
+
+		responses = operation(t, doc, "/v1/assignments/{id}", "delete")["responses"].(map[string]any)
+		assert.Equal(t, map[string]any{"description": "Custom"}, responses["default"],
+			"existing default response is kept")
+	})
+
+	t.Run("security", func(t *testing.T) {
+		login := operation(t, doc, "/v1/auth/login", "post")
+		assert.Equal(t, []any{}, login["security"], "public operation is called without the token")
+
+		assert.NotContains(t, operation(t, doc, "/v1/assignments/{id}", "get"), "security",
+			"other operations require the token of the document")
+	})
+
+	t.Run("order", func(t *testing.T) {
+		assert.Less(t, strings.Index(out, "/v1/auth/login:"), strings.Index(out, "/v1/assignments/{id}:"),
+			"order of the generated paths is kept")
+		assert.Less(t, strings.Index(out, "/v1/assignments/{id}:"), strings.Index(out, "/calendar/{token}:"),
+			"paths of the base follow the generated ones")
+	})
+}
+
+func TestRun_Idempotent(t *testing.T) {
+	once, err := process(t, generated, base)
+	require.NoError(t, err)
+
+	twice, err := process(t, once, base)
+	require.NoError(t, err)
+
+	assert.Equal(t, once, twice)
+}
+
+func TestRun_Invalid(t *testing.T) {
+	_, err := process(t, "- not\n- a mapping\n", base)
+	assert.ErrorContains(t, err, "is not the YAML mapping")
+
+	_, err = process(t, generated, "paths: [")
+	assert.ErrorContains(t, err, "failed to decode")
+}
@@ -2,14 +2,96 @@ package app
 
 import (
 	"log/slog"
+
+	httpapp "api-gateway/internal/app/http"
+	"api-gateway/internal/clients/notifications"
+	"api-gateway/internal/clients/sso"
+	"api-gateway/internal/clients/tasks"
+	"api-gateway/internal/config"
+	"api-gateway/internal/http/router"
+	"api-gateway/internal/pkg/grpcconn"
+	"api-gateway/internal/services/auth"
+
+	notificationsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/notifications/v1"
+	ssov1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/sso/v1"
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
 )
 
 type App struct {
-	log *slog.Logger
+	log                 *slog.Logger
+	HTTPServer          *httpapp.App
+	ssoClient           *grpcconn.GRPCClient[ssov1.AuthClient]
+	tasksClient         *grpcconn.GRPCClient[tasksv1.TasksClient]
+	notificationsClient *grpcconn.GRPCClient[notificationsv1.NotificationsClient]
 }
 
-func New(log *slog.Logger) *App {
+// New creates a new instance of the App struct.
+func New(log *slog.Logger, cfg *config.Config) *App {
+	ssoClient, err := grpcconn.New(log, &cfg.Clients.SSO, ssov1.NewAuthClient)
+	if err != nil {
+		log.Error("failed to create sso client", slog.Any("error", err))
+
+		return nil
+	}
+
+	tasksClient, err := grpcconn.New(log, &cfg.Clients.Tasks, tasksv1.NewTasksClient)
+	if err != nil {
+		log.Error("failed to create tasks client", slog.Any("error", err))
+
+		return nil
+	}
+
+	notificationsClient, err := grpcconn.New(
+		log,
+		&cfg.Clients.Notifications,
+		notificationsv1.NewNotificationsClient,
+	)
+	if err != nil {
+		log.Error("failed to create notifications client", slog.Any("error", err))
+
+		return nil
+	}
+
+	ssoAdapter := sso.New(log, ssoClient.API)
+	tasksAdapter := tasks.New(log, tasksClient.API)
+	notificationsAdapter := notifications.New(log, notificationsClient.API)
+
+	authService := auth.New(log, ssoAdapter, cfg.Auth.CacheTTL)
+
+	handler, err := router.New(
+		log,
+		ssoAdapter,
+		tasksAdapter,
+		notificationsAdapter,
+		authService,
+		cfg.Auth.CookieName,
+	)
+	if err != nil {
+		log.Error("failed to create router", slog.Any("error", err))
+
+		return nil
+	}
+
 	return &App{
-		log: log,
+		log:                 log,
+		HTTPServer:          httpapp.New(log, cfg.HTTPServer, handler),
+		ssoClient:           ssoClient,
+		tasksClient:         tasksClient,
+		notificationsClient: notificationsClient,
+	}
+}
+
+// Close closes the connections to the backend services.
+func (a *App) Close() {
+	if err := a.ssoClient.Close(); err != nil {
+		a.log.Error("failed to close sso client", slog.Any("error", err))
+	}
+
+	if err := a.tasksClient.Close(); err != nil {
+		a.log.Error("failed to close tasks client", slog.Any("error", err))
+	}
+
+	if err := a.notificationsClient.Close(); err != nil {
+		a.log.Error("failed to close notifications client", slog.Any("error", err))
 	}
 }
@@ -0,0 +1,73 @@
+package httpapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"api-gateway/internal/config"
+)
+
+type App struct {
+	log        *slog.Logger
+	httpServer *http.Server
+}
+
+// New creates a new instance of the HTTP app struct.
+func New(
+	log *slog.Logger,
+	cfg config.HTTPServer,
+	handler http.Handler,
+) *App {
+	return &App{
+		log: log,
+		httpServer: &http.Server{
+			Addr:         cfg.Address,
+			Handler:      handler,
+			ReadTimeout:  cfg.Timeout,
+			WriteTimeout: cfg.Timeout,
+			IdleTimeout:  cfg.IdleTimeout,
+		},
+	}
+}
+
+// MustRun runs HTTP server and panics if error occurs.
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		a.log.Error("failed to run app", "error", err)
+		panic(err)
+	}
+}
+
+// Run runs HTTP server until it is stopped.
+func (a *App) Run() error {
+	const op = "httpapp.Run"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("address", a.httpServer.Addr),
+	)
+
+	log.Info("HTTP server is running")
+	if err := a.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Stop stops HTTP server, waiting for the active requests
+// until ctx is done.
+func (a *App) Stop(ctx context.Context) {
+	const op = "httpapp.Stop"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	if err := a.httpServer.Shutdown(ctx); err != nil {
+		log.Error("failed to stop HTTP server gracefully", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,21 @@
+package notifications
+
+import (
+	"log/slog"
+
+	notificationsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/notifications/v1"
+)
+
+// Adapter is the client of the notifications service.
+// The RPCs of the service are called through the embedded client.
+type Adapter struct {
+	notificationsv1.NotificationsClient
+	log *slog.Logger
+}
+
+func New(log *slog.Logger, api notificationsv1.NotificationsClient) *Adapter {
+	return &Adapter{
+		NotificationsClient: api,
+		log:                 log,
+	}
+}
@@ -1,19 +1,55 @@
 package sso
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
 	ssov1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/sso/v1"
 )
 
+var ErrInvalidToken = errors.New("invalid token")
+
+// Adapter is the client of the SSO service.
+// The RPCs of the service are called through the embedded client.
 type Adapter struct {
+	ssov1.AuthClient
 	log *slog.Logger
-	api ssov1.AuthClient
 }
 
 func New(log *slog.Logger, api ssov1.AuthClient) *Adapter {
 	return &Adapter{
-		log: log,
-		api: api,
+		AuthClient: api,
+		log:        log,
+	}
+}
+
+// VerifyToken returns the identity of the owner of the access token.
+// If the token is malformed, expired or revoked, it returns ErrInvalidToken.
+func (a *Adapter) VerifyToken(
+	ctx context.Context,
+	token string,
+) (*ssov1.IntrospectResponse, error) {
+	const op = "clients.sso.VerifyToken"
+
+	res, err := a.Introspect(ctx, &ssov1.IntrospectRequest{
+		Token: token,
+	})
+	if err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		return nil, fmt.Errorf("%s: %v", op, err)
 	}
+
+	if !res.GetActive() {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	return res, nil
 }
@@ -1,15 +1,52 @@
 package tasks
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
 )
 
+var ErrCalendarFeedNotFound = errors.New("calendar feed not found")
+
+// Adapter is the client of the tasks service.
+// The RPCs of the service are called through the embedded client.
 type Adapter struct {
+	tasksv1.TasksClient
 	log *slog.Logger
 }
 
-func New(log *slog.Logger) *Adapter {
+func New(log *slog.Logger, api tasksv1.TasksClient) *Adapter {
 	return &Adapter{
-		log: log,
+		TasksClient: api,
+		log:         log,
 	}
 }
+
+// CalendarFeed returns the assignments of the calendar feed with the token.
+// If the token is unknown or revoked, it returns ErrCalendarFeedNotFound.
+func (a *Adapter) CalendarFeed(
+	ctx context.Context,
+	token string,
+) ([]*tasksv1.CalendarEntry, error) {
+	const op = "clients.tasks.CalendarFeed"
+
+	res, err := a.GetCalendarFeed(ctx, &tasksv1.GetCalendarFeedRequest{
+		Token: token,
+	})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.NotFound, codes.InvalidArgument:
+			return nil, fmt.Errorf("%s: %w", op, ErrCalendarFeedNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return res.GetEntries(), nil
+}
@@ -13,6 +13,7 @@ type Config struct {
 	Version    string     `yaml:"version" env-default:"v0.0.1"`
 	HTTPServer HTTPServer `yaml:"http_server"`
 	Clients    Clients    `yaml:"clients"`
+	Auth       Auth       `yaml:"auth"`
 }
 
 type HTTPServer struct {
@@ -21,9 +22,18 @@ type HTTPServer struct {
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
 }
 
+type Auth struct {
+	// CookieName is the name of the cookie the browsers keep the access token in.
+	CookieName string `yaml:"cookie_name" env-default:"session"`
+	// CacheTTL is how long the verified access token is trusted without asking SSO,
+	// the revoked token is accepted at most this long after the logout.
+	CacheTTL time.Duration `yaml:"cache_ttl" env-default:"30s"`
+}
+
 type Clients struct {
-	SSO   GRPCClient `yaml:"sso"`
-	Tasks GRPCClient `yaml:"tasks"`
+	SSO           GRPCClient `yaml:"sso"`
+	Tasks         GRPCClient `yaml:"tasks"`
+	Notifications GRPCClient `yaml:"notifications"`
 }
 
 type GRPCClient struct {
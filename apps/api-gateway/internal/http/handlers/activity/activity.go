@@ -0,0 +1,114 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"api-gateway/internal/http/request"
+	"api-gateway/internal/http/response"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+type Watcher interface {
+	WatchAssignment(
+		ctx context.Context,
+		in *tasksv1.WatchAssignmentRequest,
+		opts ...grpc.CallOption,
+	) (grpc.ServerStreamingClient[tasksv1.WatchAssignmentResponse], error)
+}
+
+// Handler streams the live activity of the assignments as server-sent events.
+type Handler struct {
+	log     *slog.Logger
+	watcher Watcher
+}
+
+// New creates a new Handler instance.
+func New(log *slog.Logger, watcher Watcher) *Handler {
+	return &Handler{
+		log:     log,
+		watcher: watcher,
+	}
+}
+
+// Watch streams the activity of the assignment of the path,
+// like "/v1/assignments/{assignment_id}/activity".
+//
+// Every activity is the event with its id, so the EventSource
+// resumes after the reconnect with the Last-Event-ID header.
+// The clients without the EventSource pass the "last_event_id" query parameter.
+// The failure of the stream, e.g. the denied access, is sent
+// as the "error" event with the JSON error body, as the headers are sent already.
+func (h *Handler) Watch(w http.ResponseWriter, r *http.Request) {
+	const op = "http.handlers.activity.Watch"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	req := &tasksv1.WatchAssignmentRequest{}
+	if err := request.Decode(r, req); err != nil {
+		response.Error(w, log, err)
+
+		return
+	}
+
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		req.LastEventId = lastEventID
+	}
+
+	rc := http.NewResponseController(w)
+	// the stream lasts longer than the timeout of the server
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	stream, err := h.watcher.WatchAssignment(request.Context(r), req)
+	if err != nil {
+		response.Error(w, log, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) || r.Context().Err() != nil {
+			return
+		}
+		if err != nil {
+			_, body := response.ErrorBody(log, err)
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", body)
+			_ = rc.Flush()
+
+			return
+		}
+
+		data, err := protojson.Marshal(msg.GetActivity())
+		if err != nil {
+			log.Error("failed to encode activity", slog.Any("error", err))
+
+			return
+		}
+
+		if _, err := fmt.Fprintf(w, "id: %s\ndata: %s\n\n", msg.GetActivity().GetId(), data); err != nil {
+			return
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package attachments
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"time"
+
+	"api-gateway/internal/http/request"
+	"api-gateway/internal/http/response"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// chunkSize is the size of the chunks of the uploaded file.
+const chunkSize = 64 << 10
+
+type Attachments interface {
+	UploadAttachment(
+		ctx context.Context,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStreamingClient[tasksv1.UploadAttachmentRequest, tasksv1.UploadAttachmentResponse], error)
+	DownloadAttachment(
+		ctx context.Context,
+		in *tasksv1.DownloadAttachmentRequest,
+		opts ...grpc.CallOption,
+	) (grpc.ServerStreamingClient[tasksv1.DownloadAttachmentResponse], error)
+}
+
+// Handler streams the attachments of the submissions
+// between the client and the tasks service.
+type Handler struct {
+	log         *slog.Logger
+	attachments Attachments
+}
+
+// New creates a new Handler instance.
+func New(log *slog.Logger, attachments Attachments) *Handler {
+	return &Handler{
+		log:         log,
+		attachments: attachments,
+	}
+}
+
+// Upload uploads the body of the request as the attachment
+// of the submission of the path, like "/v1/submissions/{submission_id}/attachments?filename=essay.pdf".
+// The body is the content of the file as is, not the form.
+func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
+	const op = "http.handlers.attachments.Upload"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	submissionID := r.PathValue("submission_id")
+	filename := r.URL.Query().Get("filename")
+	if filename == "" {
+		response.Error(w, log, status.Error(codes.InvalidArgument, "filename is required"))
+
+		return
+	}
+
+	// the large files take longer than the timeout of the server
+	clearDeadlines(w)
+
+	stream, err := h.attachments.UploadAttachment(request.Context(r))
+	if err != nil {
+		response.Error(w, log, err)
+
+		return
+	}
+
+	err = stream.Send(&tasksv1.UploadAttachmentRequest{
+		Data: &tasksv1.UploadAttachmentRequest_Info{
+			Info: &tasksv1.AttachmentInfo{
+				SubmissionId: submissionID,
+				Filename:     filename,
+			},
+		},
+	})
+	if err != nil && !errors.Is(err, io.EOF) {
+		response.Error(w, log, err)
+
+		return
+	}
+
+	buf := make([]byte, chunkSize)
+	for err == nil {
+		var n int
+
+		n, err = io.ReadFull(r.Body, buf)
+		if n > 0 {
+			sendErr := stream.Send(&tasksv1.UploadAttachmentRequest{
+				Data: &tasksv1.UploadAttachmentRequest_Chunk{
+					Chunk: buf[:n],
+				},
+			})
+			if sendErr != nil {
+				// the service has rejected the upload, its status is received below
+				break
+			}
+		}
+	}
+
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		response.Error(w, log, status.Error(codes.InvalidArgument, "failed to read body"))
+
+		return
+	}
+
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		response.Error(w, log, err)
+
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, res)
+}
+
+// Download streams the attachment of the path, like "/v1/attachments/{attachment_id}".
+// The query parameters "rendition" and "offset" choose the preview
+// and resume the interrupted download.
+func (h *Handler) Download(w http.ResponseWriter, r *http.Request) {
+	const op = "http.handlers.attachments.Download"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	req := &tasksv1.DownloadAttachmentRequest{}
+	if err := request.Decode(r, req); err != nil {
+		response.Error(w, log, err)
+
+		return
+	}
+
+	clearDeadlines(w)
+
+	stream, err := h.attachments.DownloadAttachment(request.Context(r), req)
+	if err != nil {
+		response.Error(w, log, err)
+
+		return
+	}
+
+	// the first message carries the attachment, so the errors
+	// of the call are still reported as JSON
+	first, err := stream.Recv()
+	if err != nil {
+		response.Error(w, log, err)
+
+		return
+	}
+
+	attachment := first.GetAttachment()
+	if attachment == nil {
+		response.Error(w, log, errors.New("first message carries no attachment"))
+
+		return
+	}
+
+	contentType := attachment.GetContentType()
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": attachment.GetFilename(),
+	}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			// the headers are sent, so the client sees the truncated body
+			log.Warn("failed to receive attachment", slog.Any("error", err))
+
+			return
+		}
+
+		if _, err := w.Write(msg.GetChunk()); err != nil {
+			log.Warn("failed to write attachment", slog.Any("error", err))
+
+			return
+		}
+	}
+}
+
+// clearDeadlines lifts the timeouts of the server from the streaming request.
+func clearDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+}
@@ -0,0 +1,149 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"api-gateway/internal/clients/tasks"
+	"api-gateway/internal/http/response"
+	"api-gateway/internal/lib/ical"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+const (
+	prodID = "-//Creative Learning Platform//Assignments//EN"
+	// refreshInterval is how often the calendar apps are asked to fetch the feed.
+	refreshInterval = time.Hour
+	// uidDomain makes the uids of the events globally unique.
+	uidDomain = "creative-learning-platform"
+)
+
+type Feeds interface {
+	CalendarFeed(
+		ctx context.Context,
+		token string,
+	) ([]*tasksv1.CalendarEntry, error)
+}
+
+// Handler serves the calendar feeds of the due dates.
+type Handler struct {
+	log   *slog.Logger
+	feeds Feeds
+}
+
+// New creates a new Handler instance.
+func New(log *slog.Logger, feeds Feeds) *Handler {
+	return &Handler{
+		log:   log,
+		feeds: feeds,
+	}
+}
+
+// Feed serves the feed with the token of the path, like "/calendar/{token}.ics".
+//
+// The token is the only credential, as the calendar apps fetch the feed
+// without the session of the user. Every assignment becomes the event
+// at its due date and, if late submissions are accepted, the one at its cutoff date.
+func (h *Handler) Feed(w http.ResponseWriter, r *http.Request) {
+	const op = "http.handlers.calendar.Feed"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	token := strings.TrimSuffix(r.PathValue("token"), ".ics")
+
+	entries, err := h.feeds.CalendarFeed(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, tasks.ErrCalendarFeedNotFound) {
+			response.Error(w, log, status.Error(codes.NotFound, "calendar feed not found"))
+
+			return
+		}
+
+		log.Error("failed to get calendar feed", slog.Any("error", err))
+
+		response.Error(w, log, status.Error(codes.Unavailable, "failed to get calendar feed"))
+
+		return
+	}
+
+	cal := ical.Calendar{
+		ProdID:          prodID,
+		Name:            "Assignments",
+		RefreshInterval: refreshInterval,
+		Events:          make([]ical.Event, 0, 2*len(entries)),
+	}
+
+	now := time.Now().UTC()
+	for _, entry := range entries {
+		cal.Events = append(cal.Events, toEvents(entry, now)...)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	// the token in the url must not leak through the links of the feed
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	if err := cal.Write(w); err != nil {
+		log.Warn("failed to write calendar feed", slog.Any("error", err))
+	}
+}
+
+// toEvents converts the assignment of the feed to the events of the calendar.
+func toEvents(entry *tasksv1.CalendarEntry, now time.Time) []ical.Event {
+	stamp := now
+	if entry.GetUpdatedAt() != nil {
+		stamp = entry.GetUpdatedAt().AsTime()
+	}
+
+	description := statusDescription(entry.GetStatus())
+
+	due := entry.GetDueDate().AsTime()
+	events := []ical.Event{
+		{
+			UID:         entry.GetStudentAssignmentId() + "-due@" + uidDomain,
+			Summary:     "Due: " + entry.GetTitle(),
+			Description: description,
+			Start:       due,
+			Stamp:       stamp,
+		},
+	}
+
+	if cutoff := entry.GetCutoffDate().AsTime(); entry.GetCutoffDate() != nil && cutoff.After(due) {
+		events = append(events, ical.Event{
+			UID:         entry.GetStudentAssignmentId() + "-cutoff@" + uidDomain,
+			Summary:     "Late submissions close: " + entry.GetTitle(),
+			Description: description,
+			Start:       cutoff,
+			Stamp:       stamp,
+		})
+	}
+
+	return events
+}
+
+func statusDescription(status tasksv1.SubmissionStatus) string {
+	switch status {
+	case tasksv1.SubmissionStatus_SUBMISSION_STATUS_NOT_STARTED:
+		return "Not started"
+	case tasksv1.SubmissionStatus_SUBMISSION_STATUS_IN_PROGRESS:
+		return "In progress"
+	case tasksv1.SubmissionStatus_SUBMISSION_STATUS_SUBMITTED:
+		return "Submitted"
+	case tasksv1.SubmissionStatus_SUBMISSION_STATUS_GRADED:
+		return "Graded"
+	case tasksv1.SubmissionStatus_SUBMISSION_STATUS_RETURNED:
+		return "Returned for rework"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,104 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"api-gateway/internal/http/request"
+	"api-gateway/internal/http/response"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	notificationsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/notifications/v1"
+)
+
+type Subscriber interface {
+	SubscribeNotifications(
+		ctx context.Context,
+		in *notificationsv1.SubscribeNotificationsRequest,
+		opts ...grpc.CallOption,
+	) (grpc.ServerStreamingClient[notificationsv1.SubscribeNotificationsResponse], error)
+}
+
+// Handler streams the new notifications of the inbox as server-sent events.
+type Handler struct {
+	log        *slog.Logger
+	subscriber Subscriber
+}
+
+// New creates a new Handler instance.
+func New(log *slog.Logger, subscriber Subscriber) *Handler {
+	return &Handler{
+		log:        log,
+		subscriber: subscriber,
+	}
+}
+
+// Subscribe streams the notifications of the caller as they arrive.
+//
+// Every notification is the event with its id. The stream does not resume,
+// the client lists the inbox after the reconnect and drops the notifications
+// it has already listed by id. The failure of the stream is sent
+// as the "error" event with the JSON error body, as the headers are sent already.
+func (h *Handler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	const op = "http.handlers.notifications.Subscribe"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	rc := http.NewResponseController(w)
+	// the stream lasts longer than the timeout of the server
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	stream, err := h.subscriber.SubscribeNotifications(
+		request.Context(r),
+		&notificationsv1.SubscribeNotificationsRequest{},
+	)
+	if err != nil {
+		response.Error(w, log, err)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) || r.Context().Err() != nil {
+			return
+		}
+		if err != nil {
+			_, body := response.ErrorBody(log, err)
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", body)
+			_ = rc.Flush()
+
+			return
+		}
+
+		data, err := protojson.Marshal(msg.GetNotification())
+		if err != nil {
+			log.Error("failed to encode notification", slog.Any("error", err))
+
+			return
+		}
+
+		if _, err := fmt.Fprintf(w, "id: %s\ndata: %s\n\n", msg.GetNotification().GetId(), data); err != nil {
+			return
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Handler serves the OpenAPI document of the REST API.
+type Handler struct {
+	yaml []byte
+	json []byte
+}
+
+// New creates a new Handler instance serving the document
+// both as YAML and as JSON.
+func New(spec []byte) (*Handler, error) {
+	const op = "http.handlers.openapi.New"
+
+	var doc map[string]any
+	if err := yaml.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Handler{
+		yaml: spec,
+		json: data,
+	}, nil
+}
+
+// YAML serves the document as YAML.
+func (h *Handler) YAML(w http.ResponseWriter, _ *http.Request) {
+	write(w, "application/yaml", h.yaml)
+}
+
+// JSON serves the document as JSON.
+func (h *Handler) JSON(w http.ResponseWriter, _ *http.Request) {
+	write(w, "application/json", h.json)
+}
+
+func write(w http.ResponseWriter, contentType string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	// the clients are generated from the document by the other origins
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Write(body)
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"api-gateway/internal/http/response"
+	"api-gateway/internal/services/auth"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (auth.Identity, error)
+}
+
+// Auth returns the middleware which identifies the caller of the request.
+//
+// The access token is read from the "Authorization: Bearer" header or,
+// for the browsers, from the session cookie. The request without the valid
+// token is rejected with 401. The identity of the caller is put
+// into the context of the request and is forwarded to the backend services
+// in the metadata, see request.Metadata.
+func Auth(
+	log *slog.Logger,
+	authenticator Authenticator,
+	cookieName string,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "http.middleware.Auth"
+
+			log := log.With(
+				slog.String("op", op),
+			)
+
+			token, fromCookie := accessToken(r, cookieName)
+			if token == "" {
+				unauthenticated(w, log, "access token is required")
+
+				return
+			}
+
+			identity, err := authenticator.Authenticate(r.Context(), token)
+			if err != nil {
+				if errors.Is(err, auth.ErrInvalidToken) {
+					unauthenticated(w, log, "invalid or expired access token")
+
+					return
+				}
+
+				log.Error("failed to authenticate", slog.Any("error", err))
+
+				response.Error(w, log, status.Error(codes.Unavailable, "failed to authenticate"))
+
+				return
+			}
+
+			r = r.WithContext(auth.WithIdentity(r.Context(), identity))
+
+			// the token of the cookie is passed on like the bearer one,
+			// e.g. it is the token revoked by the logout
+			if fromCookie {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SessionCookie returns the cookie which keeps the access token in the browser.
+// It is not available to the scripts of the page and is sent only over HTTPS
+// and only by the pages of the platform itself.
+func SessionCookie(name string, token string) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    token,
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
+// ExpiredSessionCookie returns the cookie which removes the session cookie from the browser.
+func ExpiredSessionCookie(name string) *http.Cookie {
+	cookie := SessionCookie(name, "")
+	cookie.MaxAge = -1
+
+	return cookie
+}
+
+// accessToken returns the access token of the request
+// and whether it is taken from the cookie.
+func accessToken(r *http.Request, cookieName string) (string, bool) {
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		scheme, token, ok := strings.Cut(authorization, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return "", false
+		}
+
+		return strings.TrimSpace(token), false
+	}
+
+	if cookie, err := r.Cookie(cookieName); err == nil && cookie.Value != "" {
+		return cookie.Value, true
+	}
+
+	return "", false
+}
+
+func unauthenticated(w http.ResponseWriter, log *slog.Logger, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+
+	response.Error(w, log, status.Error(codes.Unauthenticated, message))
+}
@@ -0,0 +1,254 @@
+package request
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"api-gateway/internal/services/auth"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/Kaptoshka/creative-learning-platform/libs/platform/identity"
+)
+
+// MaxBodySize limits the size of the JSON body of the request.
+const MaxBodySize = 32 << 20
+
+// wildcard matches the wildcards of the pattern of the route, like "{id}".
+var wildcard = regexp.MustCompile(`\{(\w+)(?:\.\.\.)?\}`)
+
+var unmarshalOptions = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// Decode fills the message of the request of the RPC from the HTTP request.
+//
+// The JSON body is decoded first, if the method has the body.
+// Then the fields named after the wildcards of the route, like "{id}",
+// are set from the path. The query parameters, e.g. "page_size",
+// are applied to the requests of the methods without the body.
+//
+// The invalid request is reported as the gRPC status with INVALID_ARGUMENT.
+func Decode(r *http.Request, message proto.Message) error {
+	if hasBody(r.Method) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodySize+1))
+		if err != nil {
+			return status.Error(codes.InvalidArgument, "failed to read body")
+		}
+
+		if len(body) > MaxBodySize {
+			return status.Errorf(codes.InvalidArgument, "body is larger than %d bytes", MaxBodySize)
+		}
+
+		if len(strings.TrimSpace(string(body))) > 0 {
+			if err := unmarshalOptions.Unmarshal(body, message); err != nil {
+				return status.Errorf(codes.InvalidArgument, "invalid body: %v", err)
+			}
+		}
+	} else {
+		for name, values := range r.URL.Query() {
+			if err := SetField(message, name, values...); err != nil {
+				return status.Errorf(codes.InvalidArgument, "invalid query parameter %q: %v", name, err)
+			}
+		}
+	}
+
+	for _, name := range PathParams(r.Pattern) {
+		if err := SetField(message, name, r.PathValue(name)); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid path parameter %q: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// The metadata keys of the identity of the caller verified by the gateway.
+// The backend services trust them instead of parsing the token themselves.
+const (
+	MetadataUserID = identity.MetadataUserID
+	MetadataRole   = identity.MetadataRole
+	MetadataAppID  = identity.MetadataAppID
+)
+
+// Context returns the context of the call of the backend service
+// with the identity of the caller in the metadata.
+func Context(r *http.Request) context.Context {
+	return metadata.NewOutgoingContext(r.Context(), Metadata(r))
+}
+
+// Metadata returns the identity of the caller of the request as the metadata
+// of the call of the backend service. It is empty for the public routes.
+func Metadata(r *http.Request) metadata.MD {
+	md := metadata.MD{}
+
+	identity, ok := auth.IdentityFromContext(r.Context())
+	if !ok {
+		return md
+	}
+
+	md.Set(MetadataUserID, strconv.FormatInt(identity.UserID, 10))
+	md.Set(MetadataRole, identity.Role)
+
+	if identity.AppID > 0 {
+		md.Set(MetadataAppID, strconv.FormatInt(int64(identity.AppID), 10))
+	}
+
+	return md
+}
+
+// PathParams returns the names of the wildcards of the pattern of the route.
+func PathParams(pattern string) []string {
+	var names []string
+	for _, m := range wildcard.FindAllStringSubmatch(pattern, -1) {
+		names = append(names, m[1])
+	}
+
+	return names
+}
+
+var errUnknownField = errors.New("unknown field")
+
+// SetField sets the scalar field of the message with the proto or JSON name
+// from its text values. The repeated fields get every value,
+// the others the last one.
+func SetField(message proto.Message, name string, values ...string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	m := message.ProtoReflect()
+	fields := m.Descriptor().Fields()
+
+	fd := fields.ByName(protoreflect.Name(name))
+	if fd == nil {
+		fd = fields.ByJSONName(name)
+	}
+	if fd == nil {
+		return errUnknownField
+	}
+
+	if fd.IsList() {
+		list := m.Mutable(fd).List()
+		for _, value := range values {
+			v, err := parseValue(fd, value)
+			if err != nil {
+				return err
+			}
+
+			list.Append(v)
+		}
+
+		return nil
+	}
+
+	if fd.IsMap() {
+		return errUnknownField
+	}
+
+	v, err := parseValue(fd, values[len(values)-1])
+	if err != nil {
+		return err
+	}
+
+	m.Set(fd, v)
+
+	return nil
+}
+
+// parseValue parses the text value of the scalar field.
+func parseValue(fd protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(value)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(value, 10, 32)
+		return protoreflect.ValueOfInt32(int32(v)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(value, 10, 64)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(value, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(v)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(value, 10, 64)
+		return protoreflect.ValueOfUint64(v), err
+	case protoreflect.FloatKind:
+		v, err := strconv.ParseFloat(value, 32)
+		return protoreflect.ValueOfFloat32(float32(v)), err
+	case protoreflect.DoubleKind:
+		v, err := strconv.ParseFloat(value, 64)
+		return protoreflect.ValueOfFloat64(v), err
+	case protoreflect.BytesKind:
+		v, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			v, err = base64.URLEncoding.DecodeString(value)
+		}
+		return protoreflect.ValueOfBytes(v), err
+	case protoreflect.EnumKind:
+		if ev := enumValue(fd.Enum(), value); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+
+		v, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("unknown value %q", value)
+		}
+
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(v)), nil
+	default:
+		return protoreflect.Value{}, fmt.Errorf("%s fields are not supported", fd.Kind())
+	}
+}
+
+// enumValue returns the value of the enum by its full name, like "ATTACHMENT_RENDITION_THUMBNAIL",
+// or by the short one without the prefix of the enum in any case, like "thumbnail".
+func enumValue(enum protoreflect.EnumDescriptor, name string) protoreflect.EnumValueDescriptor {
+	values := enum.Values()
+
+	if ev := values.ByName(protoreflect.Name(name)); ev != nil {
+		return ev
+	}
+
+	return values.ByName(protoreflect.Name(enumPrefix(enum) + strings.ToUpper(name)))
+}
+
+// enumPrefix returns the prefix of the values of the enum,
+// the name of the enum in the upper snake case, e.g. "ATTACHMENT_RENDITION_".
+func enumPrefix(enum protoreflect.EnumDescriptor) string {
+	var b strings.Builder
+	for i, r := range string(enum.Name()) {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte('_')
+		}
+
+		b.WriteRune(unicode.ToUpper(r))
+	}
+
+	b.WriteByte('_')
+
+	return b.String()
+}
+
+// hasBody reports whether the request of the method carries the body.
+func hasBody(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	default:
+		return false
+	}
+}
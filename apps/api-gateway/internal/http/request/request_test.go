@@ -0,0 +1,155 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"api-gateway/internal/services/auth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// serve decodes the request routed by the pattern.
+func serve(t *testing.T, pattern string, r *http.Request, msg *tasksv1.ResolveRegradeRequest) error {
+	t.Helper()
+
+	var err error
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, func(_ http.ResponseWriter, r *http.Request) {
+		err = Decode(r, msg)
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+
+	return err
+}
+
+func TestDecodeBodyAndPath(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/v1/regrades/r-1/resolve", strings.NewReader(
+		`{"outcome":"REGRADE_STATUS_ACCEPTED","resolution":"ok","newScore":90,"unknown":1}`,
+	))
+
+	var msg tasksv1.ResolveRegradeRequest
+	require.NoError(t, serve(t, "POST /v1/regrades/{regrade_id}/resolve", r, &msg))
+
+	assert.Equal(t, "r-1", msg.GetRegradeId())
+	assert.Equal(t, tasksv1.RegradeStatus_REGRADE_STATUS_ACCEPTED, msg.GetOutcome())
+	assert.Equal(t, "ok", msg.GetResolution())
+	assert.InDelta(t, 90.0, msg.GetNewScore(), 0.001)
+}
+
+func TestDecodeQuery(t *testing.T) {
+	r := httptest.NewRequest(
+		http.MethodGet,
+		"/v1/regrades/r-2?outcome=2&resolution=fine&new_score=7.5",
+		nil,
+	)
+
+	var msg tasksv1.ResolveRegradeRequest
+	require.NoError(t, serve(t, "GET /v1/regrades/{regrade_id}", r, &msg))
+
+	assert.Equal(t, "r-2", msg.GetRegradeId())
+	assert.Equal(t, tasksv1.RegradeStatus(2), msg.GetOutcome())
+	assert.Equal(t, "fine", msg.GetResolution())
+	assert.InDelta(t, 7.5, msg.GetNewScore(), 0.001)
+}
+
+func TestDecodeEnumShortName(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v1/attachments/a-1?rendition=thumbnail", nil)
+
+	var msg tasksv1.DownloadAttachmentRequest
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/attachments/{attachment_id}", func(_ http.ResponseWriter, r *http.Request) {
+		require.NoError(t, Decode(r, &msg))
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, "a-1", msg.GetAttachmentId())
+	assert.Equal(t, tasksv1.AttachmentRendition_ATTACHMENT_RENDITION_THUMBNAIL, msg.GetRendition())
+
+	for _, value := range []string{"ATTACHMENT_RENDITION_PREVIEW", "preview", "Preview", "3"} {
+		require.NoError(t, SetField(&msg, "rendition", value), value)
+		assert.Equal(t, tasksv1.AttachmentRendition_ATTACHMENT_RENDITION_PREVIEW, msg.GetRendition(), value)
+	}
+
+	assert.Error(t, SetField(&msg, "rendition", "poster"))
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		pattern string
+	}{
+		{
+			name:    "malformed body",
+			method:  http.MethodPost,
+			target:  "/v1/regrades/r-1",
+			body:    `{"resolution":`,
+			pattern: "POST /v1/regrades/{regrade_id}",
+		},
+		{
+			name:    "unknown query parameter",
+			method:  http.MethodGet,
+			target:  "/v1/regrades/r-1?foo=bar",
+			pattern: "GET /v1/regrades/{regrade_id}",
+		},
+		{
+			name:    "invalid number",
+			method:  http.MethodGet,
+			target:  "/v1/regrades/r-1?new_score=high",
+			pattern: "GET /v1/regrades/{regrade_id}",
+		},
+		{
+			name:    "unknown enum value",
+			method:  http.MethodGet,
+			target:  "/v1/regrades/r-1?outcome=MAYBE",
+			pattern: "GET /v1/regrades/{regrade_id}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+
+			var msg tasksv1.ResolveRegradeRequest
+			err := serve(t, tt.pattern, r, &msg)
+
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
+
+func TestPathParams(t *testing.T) {
+	assert.Equal(t,
+		[]string{"submission_id", "path"},
+		PathParams("GET /v1/submissions/{submission_id}/files/{path...}"),
+	)
+	assert.Empty(t, PathParams("GET /v1/trash"))
+}
+
+func TestContextForwardsIdentity(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v1/assignments", nil)
+	r.Header.Set("Authorization", "Bearer token")
+
+	md, _ := metadata.FromOutgoingContext(Context(r))
+	assert.Empty(t, md)
+
+	r = r.WithContext(auth.WithIdentity(r.Context(), auth.Identity{UserID: 42, Role: "teacher"}))
+
+	md, ok := metadata.FromOutgoingContext(Context(r))
+	require.True(t, ok)
+	assert.Equal(t, []string{"42"}, md.Get(MetadataUserID))
+	assert.Equal(t, []string{"teacher"}, md.Get(MetadataRole))
+	assert.Empty(t, md.Get("authorization"))
+}
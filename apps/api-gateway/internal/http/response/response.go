@@ -0,0 +1,160 @@
+package response
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// errorBody is the body of the failed response,
+// which follows the error model of the Google APIs.
+type errorBody struct {
+	Error errorStatus `json:"error"`
+}
+
+type errorStatus struct {
+	// Code is the HTTP status of the response.
+	Code int `json:"code"`
+	// Status is the name of the gRPC code, like "NOT_FOUND".
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	// Details are the details of the gRPC status,
+	// e.g. the current submission on the revision conflict.
+	Details []json.RawMessage `json:"details,omitempty"`
+}
+
+// codeStatus is the HTTP status and the name of the gRPC code.
+type codeStatus struct {
+	httpStatus int
+	name       string
+}
+
+var codeStatuses = map[codes.Code]codeStatus{
+	codes.OK:                 {http.StatusOK, "OK"},
+	codes.Canceled:           {499, "CANCELLED"},
+	codes.Unknown:            {http.StatusInternalServerError, "UNKNOWN"},
+	codes.InvalidArgument:    {http.StatusBadRequest, "INVALID_ARGUMENT"},
+	codes.DeadlineExceeded:   {http.StatusGatewayTimeout, "DEADLINE_EXCEEDED"},
+	codes.NotFound:           {http.StatusNotFound, "NOT_FOUND"},
+	codes.AlreadyExists:      {http.StatusConflict, "ALREADY_EXISTS"},
+	codes.PermissionDenied:   {http.StatusForbidden, "PERMISSION_DENIED"},
+	codes.ResourceExhausted:  {http.StatusTooManyRequests, "RESOURCE_EXHAUSTED"},
+	codes.FailedPrecondition: {http.StatusBadRequest, "FAILED_PRECONDITION"},
+	codes.Aborted:            {http.StatusConflict, "ABORTED"},
+	codes.OutOfRange:         {http.StatusBadRequest, "OUT_OF_RANGE"},
+	codes.Unimplemented:      {http.StatusNotImplemented, "UNIMPLEMENTED"},
+	codes.Internal:           {http.StatusInternalServerError, "INTERNAL"},
+	codes.Unavailable:        {http.StatusServiceUnavailable, "UNAVAILABLE"},
+	codes.DataLoss:           {http.StatusInternalServerError, "DATA_LOSS"},
+	codes.Unauthenticated:    {http.StatusUnauthorized, "UNAUTHENTICATED"},
+}
+
+// HTTPStatus returns the HTTP status matching the gRPC code.
+func HTTPStatus(code codes.Code) int {
+	if s, ok := codeStatuses[code]; ok {
+		return s.httpStatus
+	}
+
+	return http.StatusInternalServerError
+}
+
+// JSON writes the message as JSON with the HTTP status.
+func JSON(w http.ResponseWriter, httpStatus int, message proto.Message) {
+	body, err := protojson.Marshal(message)
+	if err != nil {
+		Error(w, nil, status.Error(codes.Internal, "failed to encode response"))
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	w.Write(body)
+}
+
+// Error writes the error as JSON with the HTTP status matching its gRPC code.
+// The error which is not the gRPC status is logged and reported as internal,
+// so its message does not leak to the client.
+func Error(w http.ResponseWriter, log *slog.Logger, err error) {
+	httpStatus, body := ErrorBody(log, err)
+
+	writeError(w, httpStatus, body)
+}
+
+// RouteError writes the error of the request matching no route,
+// like 404 for the unknown path and 405 for the method the path does not support.
+func RouteError(w http.ResponseWriter, httpStatus int) {
+	var st *status.Status
+	switch httpStatus {
+	case http.StatusNotFound:
+		st = status.New(codes.NotFound, "route not found")
+	case http.StatusMethodNotAllowed:
+		st = status.New(codes.Unimplemented, "method not allowed")
+	case http.StatusBadRequest:
+		st = status.New(codes.InvalidArgument, "bad request")
+	default:
+		st = status.New(codes.Internal, "unexpected routing error")
+	}
+
+	writeError(w, httpStatus, encodeError(httpStatus, st))
+}
+
+// ErrorBody returns the HTTP status and the JSON body of the error,
+// like {"error":{"code":404,"status":"NOT_FOUND","message":"..."}}.
+// It is used where the error is not the whole response,
+// e.g. the event of the stream.
+func ErrorBody(log *slog.Logger, err error) (int, []byte) {
+	st, ok := status.FromError(err)
+	if !ok {
+		if log != nil {
+			log.Error("request failed", slog.Any("error", err))
+		}
+
+		st = status.New(codes.Internal, "internal error")
+	}
+
+	httpStatus := HTTPStatus(st.Code())
+
+	return httpStatus, encodeError(httpStatus, st)
+}
+
+func encodeError(httpStatus int, st *status.Status) []byte {
+	name := codeStatuses[codes.Unknown].name
+	if s, ok := codeStatuses[st.Code()]; ok {
+		name = s.name
+	}
+
+	body := errorBody{
+		Error: errorStatus{
+			Code:    httpStatus,
+			Status:  name,
+			Message: st.Message(),
+		},
+	}
+
+	for _, detail := range st.Proto().GetDetails() {
+		raw, err := protojson.Marshal(detail)
+		if err != nil {
+			continue
+		}
+
+		body.Error.Details = append(body.Error.Details, raw)
+	}
+
+	// the body consists of the plain values and the valid JSON only
+	data, _ := json.Marshal(body)
+
+	return data
+}
+
+func writeError(w http.ResponseWriter, httpStatus int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(httpStatus)
+	w.Write(body)
+}
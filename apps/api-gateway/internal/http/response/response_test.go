@@ -0,0 +1,64 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want int
+	}{
+		{codes.InvalidArgument, http.StatusBadRequest},
+		{codes.Unauthenticated, http.StatusUnauthorized},
+		{codes.PermissionDenied, http.StatusForbidden},
+		{codes.NotFound, http.StatusNotFound},
+		{codes.Aborted, http.StatusConflict},
+		{codes.ResourceExhausted, http.StatusTooManyRequests},
+		{codes.Canceled, 499},
+		{codes.Unavailable, http.StatusServiceUnavailable},
+		{codes.Code(100), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			assert.Equal(t, tt.want, HTTPStatus(tt.code))
+		})
+	}
+}
+
+func TestErrorBody_Details(t *testing.T) {
+	st, err := status.New(codes.Aborted, "submission revision conflict").WithDetails(
+		&tasksv1.Submission{Id: "s-1", Revision: 3},
+	)
+	require.NoError(t, err)
+
+	httpStatus, body := ErrorBody(nil, st.Err())
+	assert.Equal(t, http.StatusConflict, httpStatus)
+
+	var res struct {
+		Error struct {
+			Code    int              `json:"code"`
+			Status  string           `json:"status"`
+			Message string           `json:"message"`
+			Details []map[string]any `json:"details"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(body, &res))
+
+	assert.Equal(t, http.StatusConflict, res.Error.Code)
+	assert.Equal(t, "ABORTED", res.Error.Status)
+	assert.Equal(t, "submission revision conflict", res.Error.Message)
+	require.Len(t, res.Error.Details, 1)
+	assert.Equal(t, "type.googleapis.com/tasks.Submission", res.Error.Details[0]["@type"])
+	assert.Equal(t, "s-1", res.Error.Details[0]["id"])
+}
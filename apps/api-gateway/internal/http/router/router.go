@@ -0,0 +1,158 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"api-gateway/api"
+	"api-gateway/internal/clients/notifications"
+	"api-gateway/internal/clients/sso"
+	"api-gateway/internal/clients/tasks"
+	"api-gateway/internal/http/handlers/activity"
+	"api-gateway/internal/http/handlers/attachments"
+	"api-gateway/internal/http/handlers/calendar"
+	notificationshandler "api-gateway/internal/http/handlers/notifications"
+	"api-gateway/internal/http/handlers/openapi"
+	"api-gateway/internal/http/middleware"
+	"api-gateway/internal/http/request"
+	"api-gateway/internal/http/response"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	notificationsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/notifications/v1"
+	ssov1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/sso/v1"
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// New returns the handler of the REST API.
+//
+// The routes of the RPCs are generated from their google.api.http annotations
+// in the protos, so the annotated RPC is served without any changes here.
+// The streaming RPCs and the calendar feed, which are not JSON,
+// are served by the handlers of their own. The OpenAPI document
+// of all the routes is served at "/v1/openapi.yaml" and "/v1/openapi.json".
+//
+// Every route except the registration, the login, the calendar feed
+// and the OpenAPI document requires the access token, see middleware.Auth.
+func New(
+	log *slog.Logger,
+	ssoClient *sso.Adapter,
+	tasksClient *tasks.Adapter,
+	notificationsClient *notifications.Adapter,
+	authenticator middleware.Authenticator,
+	cookieName string,
+) (http.Handler, error) {
+	const op = "http.router.New"
+
+	gateway := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+		runtime.WithErrorHandler(func(
+			_ context.Context,
+			_ *runtime.ServeMux,
+			_ runtime.Marshaler,
+			w http.ResponseWriter,
+			_ *http.Request,
+			err error,
+		) {
+			response.Error(w, log, err)
+		}),
+		runtime.WithRoutingErrorHandler(func(
+			_ context.Context,
+			_ *runtime.ServeMux,
+			_ runtime.Marshaler,
+			w http.ResponseWriter,
+			_ *http.Request,
+			httpStatus int,
+		) {
+			response.RouteError(w, httpStatus)
+		}),
+		runtime.WithIncomingHeaderMatcher(headerMatcher),
+		runtime.WithMetadata(func(_ context.Context, r *http.Request) metadata.MD {
+			return request.Metadata(r)
+		}),
+		runtime.WithForwardResponseOption(func(
+			_ context.Context,
+			w http.ResponseWriter,
+			resp proto.Message,
+		) error {
+			switch resp := resp.(type) {
+			case *ssov1.LoginResponse:
+				http.SetCookie(w, middleware.SessionCookie(cookieName, resp.GetToken()))
+			case *ssov1.LogoutResponse:
+				http.SetCookie(w, middleware.ExpiredSessionCookie(cookieName))
+			}
+
+			return nil
+		}),
+	)
+
+	ctx := context.Background()
+
+	if err := ssov1.RegisterAuthHandlerClient(ctx, gateway, ssoClient); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tasksv1.RegisterTasksHandlerClient(ctx, gateway, tasksClient); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err := notificationsv1.RegisterNotificationsHandlerClient(ctx, gateway, notificationsClient)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	openapiHandler, err := openapi.New(api.OpenAPI)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	protected := http.NewServeMux()
+
+	attachmentsHandler := attachments.New(log, tasksClient)
+	protected.HandleFunc("POST /v1/submissions/{submission_id}/attachments", attachmentsHandler.Upload)
+	protected.HandleFunc("GET /v1/attachments/{attachment_id}", attachmentsHandler.Download)
+	protected.HandleFunc("GET /v1/assignments/{assignment_id}/activity", activity.New(log, tasksClient).Watch)
+	protected.HandleFunc(
+		"GET /v1/notifications/events",
+		notificationshandler.New(log, notificationsClient).Subscribe,
+	)
+
+	protected.Handle("/", gateway)
+
+	mux := http.NewServeMux()
+
+	mux.Handle("POST /v1/auth/register", gateway)
+	mux.Handle("POST /v1/auth/login", gateway)
+	mux.HandleFunc("GET /calendar/{token}", calendar.New(log, tasksClient).Feed)
+
+	mux.HandleFunc("GET /v1/openapi.yaml", openapiHandler.YAML)
+	mux.HandleFunc("GET /v1/openapi.json", openapiHandler.JSON)
+
+	mux.Handle("/", middleware.Auth(log, authenticator, cookieName)(protected))
+
+	return mux, nil
+}
+
+// headerMatcher forwards the headers to the backend services like the default one,
+// except the identity of the caller, which only the gateway sets.
+func headerMatcher(key string) (string, bool) {
+	name, ok := runtime.DefaultHeaderMatcher(key)
+	if !ok {
+		return "", false
+	}
+
+	switch strings.ToLower(name) {
+	case request.MetadataUserID, request.MetadataRole, request.MetadataAppID:
+		return "", false
+	}
+
+	return name, true
+}
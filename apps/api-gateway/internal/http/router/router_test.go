@@ -0,0 +1,367 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"api-gateway/internal/clients/notifications"
+	"api-gateway/internal/clients/sso"
+	"api-gateway/internal/clients/tasks"
+	"api-gateway/internal/services/auth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	notificationsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/notifications/v1"
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+const testToken = "token"
+
+// fakeTasks records the call of GetTeacherAssignment.
+// The other RPCs are not called by the tests.
+type fakeTasks struct {
+	tasksv1.TasksClient
+	md  metadata.MD
+	req *tasksv1.GetTeacherAssignmentRequest
+	err error
+}
+
+func (f *fakeTasks) GetTeacherAssignment(
+	ctx context.Context,
+	req *tasksv1.GetTeacherAssignmentRequest,
+	_ ...grpc.CallOption,
+) (*tasksv1.GetTeacherAssignmentResponse, error) {
+	f.md, _ = metadata.FromOutgoingContext(ctx)
+	f.req = req
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return &tasksv1.GetTeacherAssignmentResponse{
+		Assignment: &tasksv1.Assignment{Id: req.GetId(), Title: "Essay"},
+	}, nil
+}
+
+// fakeNotifications records the calls of UnreadCount and SubscribeNotifications.
+// The other RPCs are not called by the tests.
+type fakeNotifications struct {
+	notificationsv1.NotificationsClient
+	md     metadata.MD
+	stream *fakeStream
+}
+
+func (f *fakeNotifications) UnreadCount(
+	ctx context.Context,
+	_ *notificationsv1.UnreadCountRequest,
+	_ ...grpc.CallOption,
+) (*notificationsv1.UnreadCountResponse, error) {
+	f.md, _ = metadata.FromOutgoingContext(ctx)
+
+	return &notificationsv1.UnreadCountResponse{Count: 3}, nil
+}
+
+func (f *fakeNotifications) SubscribeNotifications(
+	ctx context.Context,
+	_ *notificationsv1.SubscribeNotificationsRequest,
+	_ ...grpc.CallOption,
+) (grpc.ServerStreamingClient[notificationsv1.SubscribeNotificationsResponse], error) {
+	f.md, _ = metadata.FromOutgoingContext(ctx)
+
+	return f.stream, nil
+}
+
+// fakeStream sends the notifications and then fails with err.
+type fakeStream struct {
+	grpc.ClientStream
+	notifications []*notificationsv1.Notification
+	err           error
+}
+
+func (s *fakeStream) Recv() (*notificationsv1.SubscribeNotificationsResponse, error) {
+	if len(s.notifications) == 0 {
+		return nil, s.err
+	}
+
+	notification := s.notifications[0]
+	s.notifications = s.notifications[1:]
+
+	return &notificationsv1.SubscribeNotificationsResponse{Notification: notification}, nil
+}
+
+type fakeAuthenticator struct{}
+
+func (fakeAuthenticator) Authenticate(_ context.Context, token string) (auth.Identity, error) {
+	if token != testToken {
+		return auth.Identity{}, auth.ErrInvalidToken
+	}
+
+	return auth.Identity{UserID: 7, Role: "teacher", AppID: 1}, nil
+}
+
+func newTestRouter(
+	t *testing.T,
+	tasksClient *fakeTasks,
+	notificationsClient *fakeNotifications,
+) http.Handler {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	router, err := New(
+		log,
+		sso.New(log, nil),
+		tasks.New(log, tasksClient),
+		notifications.New(log, notificationsClient),
+		fakeAuthenticator{},
+		"session",
+	)
+	require.NoError(t, err)
+
+	return router
+}
+
+// errorResponse is the body of the failed response.
+type errorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func serve(t *testing.T, router http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestRoute(t *testing.T) {
+	tasksClient := &fakeTasks{}
+	router := newTestRouter(t, tasksClient, &fakeNotifications{})
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/assignments/a-1", nil)
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	// the identity sent by the client is not forwarded
+	r.Header.Set("Grpc-Metadata-X-User-Id", "1")
+	r.Header.Set("Grpc-Metadata-X-User-Role", "admin")
+	r.Header.Set("Grpc-Metadata-X-App-Id", "9")
+
+	w := serve(t, router, r)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	require.NotNil(t, tasksClient.req)
+	assert.Equal(t, "a-1", tasksClient.req.GetId(), "path parameter is set in the request")
+	assert.Equal(t, []string{"7"}, tasksClient.md.Get("x-user-id"))
+	assert.Equal(t, []string{"teacher"}, tasksClient.md.Get("x-user-role"))
+	assert.Equal(t, []string{"1"}, tasksClient.md.Get("x-app-id"))
+
+	var res struct {
+		Assignment struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+		} `json:"assignment"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, "a-1", res.Assignment.ID)
+	assert.Equal(t, "Essay", res.Assignment.Title)
+}
+
+func TestRoute_Notifications(t *testing.T) {
+	notificationsClient := &fakeNotifications{}
+	router := newTestRouter(t, &fakeTasks{}, notificationsClient)
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/notifications/unread-count", nil)
+	r.Header.Set("Authorization", "Bearer "+testToken)
+
+	w := serve(t, router, r)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"count":"3"}`, w.Body.String())
+
+	assert.Equal(t, []string{"7"}, notificationsClient.md.Get("x-user-id"))
+	assert.Equal(t, []string{"1"}, notificationsClient.md.Get("x-app-id"),
+		"app of the caller scopes the webhooks")
+}
+
+func TestRoute_NotificationEvents(t *testing.T) {
+	t.Run("delivered", func(t *testing.T) {
+		notificationsClient := &fakeNotifications{stream: &fakeStream{
+			notifications: []*notificationsv1.Notification{
+				{Id: "n-1", Title: "Graded"},
+				{Id: "n-2", Title: "Due soon"},
+			},
+			err: io.EOF,
+		}}
+		router := newTestRouter(t, &fakeTasks{}, notificationsClient)
+
+		r := httptest.NewRequest(http.MethodGet, "/v1/notifications/events", nil)
+		r.Header.Set("Authorization", "Bearer "+testToken)
+
+		w := serve(t, router, r)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		events := strings.Split(strings.TrimSuffix(w.Body.String(), "\n\n"), "\n\n")
+		require.Len(t, events, 2, "stream ends when the service closes it")
+
+		for i, want := range []string{"n-1", "n-2"} {
+			id, data, ok := strings.Cut(events[i], "\ndata: ")
+			require.True(t, ok, events[i])
+			assert.Equal(t, "id: "+want, id)
+
+			var notification struct {
+				ID string `json:"id"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(data), &notification))
+			assert.Equal(t, want, notification.ID)
+		}
+
+		assert.Equal(t, []string{"7"}, notificationsClient.md.Get("x-user-id"))
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		router := newTestRouter(t, &fakeTasks{}, &fakeNotifications{stream: &fakeStream{
+			err: status.Error(codes.Unavailable, "inbox is unavailable"),
+		}})
+
+		r := httptest.NewRequest(http.MethodGet, "/v1/notifications/events", nil)
+		r.Header.Set("Authorization", "Bearer "+testToken)
+
+		w := serve(t, router, r)
+		require.Equal(t, http.StatusOK, w.Code, "headers are sent before the failure")
+
+		event, data, ok := strings.Cut(w.Body.String(), "\ndata: ")
+		require.True(t, ok, w.Body.String())
+		assert.Equal(t, "event: error", event)
+
+		var res errorResponse
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(data)), &res))
+		assert.Equal(t, http.StatusServiceUnavailable, res.Error.Code)
+		assert.Equal(t, "inbox is unavailable", res.Error.Message)
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		notificationsClient := &fakeNotifications{}
+		router := newTestRouter(t, &fakeTasks{}, notificationsClient)
+
+		w := serve(t, router, httptest.NewRequest(http.MethodGet, "/v1/notifications/events", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Nil(t, notificationsClient.md, "service is not called")
+	})
+}
+
+func TestRoute_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		err        error
+		wantCode   int
+		wantStatus string
+		wantMsg    string
+	}{
+		{
+			name:       "not found",
+			err:        status.Error(codes.NotFound, "assignment not found"),
+			wantCode:   http.StatusNotFound,
+			wantStatus: "NOT_FOUND",
+			wantMsg:    "assignment not found",
+		},
+		{
+			name:       "permission denied",
+			err:        status.Error(codes.PermissionDenied, "assignment belongs to another teacher"),
+			wantCode:   http.StatusForbidden,
+			wantStatus: "PERMISSION_DENIED",
+			wantMsg:    "assignment belongs to another teacher",
+		},
+		{
+			name:       "failed precondition",
+			err:        status.Error(codes.FailedPrecondition, "assignment is already published"),
+			wantCode:   http.StatusBadRequest,
+			wantStatus: "FAILED_PRECONDITION",
+			wantMsg:    "assignment is already published",
+		},
+		{
+			name:       "not a status",
+			err:        errors.New("connection to db lost"),
+			wantCode:   http.StatusInternalServerError,
+			wantStatus: "INTERNAL",
+			wantMsg:    "internal error",
+		},
+		{
+			name:       "unknown route",
+			path:       "/v1/unknown",
+			wantCode:   http.StatusNotFound,
+			wantStatus: "NOT_FOUND",
+			wantMsg:    "route not found",
+		},
+		{
+			name:       "method not allowed",
+			method:     http.MethodPut,
+			wantCode:   http.StatusMethodNotAllowed,
+			wantStatus: "UNIMPLEMENTED",
+			wantMsg:    "method not allowed",
+		},
+		{
+			name:       "invalid token",
+			token:      "expired",
+			wantCode:   http.StatusUnauthorized,
+			wantStatus: "UNAUTHENTICATED",
+			wantMsg:    "invalid or expired access token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, path, token := http.MethodGet, "/v1/assignments/a-1", testToken
+			if tt.method != "" {
+				method = tt.method
+			}
+			if tt.path != "" {
+				path = tt.path
+			}
+			if tt.token != "" {
+				token = tt.token
+			}
+
+			router := newTestRouter(t, &fakeTasks{err: tt.err}, &fakeNotifications{})
+
+			r := httptest.NewRequest(method, path, nil)
+			r.Header.Set("Authorization", "Bearer "+token)
+
+			w := serve(t, router, r)
+			require.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+			var res errorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			assert.Equal(t, tt.wantCode, res.Error.Code)
+			assert.Equal(t, tt.wantStatus, res.Error.Status)
+			assert.Equal(t, tt.wantMsg, res.Error.Message)
+		})
+	}
+}
+
+func TestRoute_Public(t *testing.T) {
+	router := newTestRouter(t, &fakeTasks{}, &fakeNotifications{})
+
+	w := serve(t, router, httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code, "document is served without the token")
+
+	w = serve(t, router, httptest.NewRequest(http.MethodGet, "/v1/assignments/a-1", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "other routes require the token")
+}
@@ -0,0 +1,128 @@
+// Package ical writes the calendars in the iCalendar format (RFC 5545),
+// which the calendar apps subscribe to by url.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// maxLineLen is the length of the content line in octets
+	// after which it is folded.
+	maxLineLen = 75
+	timeFormat = "20060102T150405Z"
+)
+
+// Calendar is the published calendar of the events.
+type Calendar struct {
+	// ProdID identifies the product which has created the calendar.
+	ProdID string
+	// Name is the name of the calendar shown by the calendar apps.
+	Name string
+	// RefreshInterval is how often the calendar apps should fetch the calendar again.
+	RefreshInterval time.Duration
+	Events          []Event
+}
+
+// Event is the event of the calendar which has no duration.
+type Event struct {
+	// UID identifies the event across the fetches of the calendar,
+	// so the calendar apps update the event instead of adding the new one.
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	// Stamp is the time the event was last changed,
+	// which the calendar apps use to detect the changes.
+	Stamp time.Time
+}
+
+// Write writes the calendar to w.
+func (c Calendar) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	line := func(name, value string) {
+		writeLine(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", c.ProdID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escape(c.Name))
+	}
+	if c.RefreshInterval > 0 {
+		line("REFRESH-INTERVAL;VALUE=DURATION", duration(c.RefreshInterval))
+		line("X-PUBLISHED-TTL", duration(c.RefreshInterval))
+	}
+
+	for _, event := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", escape(event.UID))
+		line("DTSTAMP", formatTime(event.Stamp))
+		line("LAST-MODIFIED", formatTime(event.Stamp))
+		line("DTSTART", formatTime(event.Start))
+		line("SUMMARY", escape(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION", escape(event.Description))
+		}
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+
+	return bw.Flush()
+}
+
+// writeLine writes the content line ended with CRLF.
+// The line longer than maxLineLen octets is folded
+// without splitting the multi-byte characters.
+func writeLine(w *bufio.Writer, line string) {
+	limit := maxLineLen
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// the space of the continuation line counts towards its length
+		limit = maxLineLen - 1
+	}
+
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+var escaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// escape escapes the text value.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+// duration formats the duration as the whole minutes, like "PT90M".
+func duration(d time.Duration) string {
+	return "PT" + strconv.FormatInt(max(int64(d/time.Minute), 1), 10) + "M"
+}
@@ -0,0 +1,87 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	due := time.Date(2026, 3, 1, 18, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	stamp := time.Date(2026, 2, 20, 9, 30, 0, 0, time.UTC)
+
+	cal := Calendar{
+		ProdID:          "-//Test//EN",
+		Name:            "Assignments",
+		RefreshInterval: time.Hour,
+		Events: []Event{
+			{
+				UID:         "sa-1-due@test",
+				Summary:     "Due: Essay, part 1; draft",
+				Description: "Line one\nLine two",
+				Start:       due,
+				Stamp:       stamp,
+			},
+		},
+	}
+
+	var b strings.Builder
+	require.NoError(t, cal.Write(&b))
+
+	want := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"PRODID:-//Test//EN\r\n" +
+		"CALSCALE:GREGORIAN\r\n" +
+		"METHOD:PUBLISH\r\n" +
+		"X-WR-CALNAME:Assignments\r\n" +
+		"REFRESH-INTERVAL;VALUE=DURATION:PT60M\r\n" +
+		"X-PUBLISHED-TTL:PT60M\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:sa-1-due@test\r\n" +
+		"DTSTAMP:20260220T093000Z\r\n" +
+		"LAST-MODIFIED:20260220T093000Z\r\n" +
+		"DTSTART:20260301T150000Z\r\n" +
+		"SUMMARY:Due: Essay\\, part 1\\; draft\r\n" +
+		"DESCRIPTION:Line one\\nLine two\r\n" +
+		"TRANSP:TRANSPARENT\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	assert.Equal(t, want, b.String())
+}
+
+func TestWriteFoldsLongLines(t *testing.T) {
+	cal := Calendar{
+		ProdID: "-//Test//EN",
+		Events: []Event{
+			{
+				UID:     "uid",
+				Summary: strings.Repeat("Сочинение ", 20),
+			},
+		},
+	}
+
+	var b strings.Builder
+	require.NoError(t, cal.Write(&b))
+
+	var summary strings.Builder
+	inSummary := false
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), maxLineLen)
+
+		switch {
+		case strings.HasPrefix(line, "SUMMARY:"):
+			inSummary = true
+			summary.WriteString(line)
+		case inSummary && strings.HasPrefix(line, " "):
+			summary.WriteString(line[1:])
+		default:
+			inSummary = false
+		}
+	}
+
+	assert.Equal(t, "SUMMARY:"+strings.Repeat("Сочинение ", 20), summary.String())
+}
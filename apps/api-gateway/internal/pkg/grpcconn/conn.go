@@ -10,6 +10,7 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 
 	grpclog "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
@@ -44,7 +45,7 @@ func New[T any](
 		grpclog.WithLogOnEvents(grpclog.PayloadSent, grpclog.PayloadReceived),
 	}
 
-	var credentialsOpts grpc.DialOption
+	credentialsOpts := grpc.WithTransportCredentials(credentials.NewTLS(nil))
 
 	if cfg.Insecure {
 		credentialsOpts = grpc.WithTransportCredentials(insecure.NewCredentials())
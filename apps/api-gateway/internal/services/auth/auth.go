@@ -1 +1,193 @@
 package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"api-gateway/internal/clients/sso"
+
+	ssov1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/sso/v1"
+)
+
+// maxCacheSize bounds the number of the verified tokens kept in the cache.
+const maxCacheSize = 10000
+
+var ErrInvalidToken = sso.ErrInvalidToken
+
+// Identity is the caller identified by the access token.
+type Identity struct {
+	UserID int64
+	Email  string
+	Role   string
+	Scope  []string
+	// AppID is the app the token was issued for.
+	AppID     int32
+	ExpiresAt time.Time
+}
+
+type TokenVerifier interface {
+	VerifyToken(
+		ctx context.Context,
+		token string,
+	) (*ssov1.IntrospectResponse, error)
+}
+
+// Auth identifies the callers of the gateway by their access tokens.
+//
+// The tokens are signed with the secrets of the apps, which only SSO knows,
+// so they are verified by the introspection in SSO. The verified tokens
+// are cached for the cacheTTL, the revoked token is rejected
+// at most cacheTTL after the logout.
+type Auth struct {
+	log      *slog.Logger
+	verifier TokenVerifier
+	cacheTTL time.Duration
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]cacheEntry
+}
+
+type cacheEntry struct {
+	identity  Identity
+	expiresAt time.Time
+}
+
+// New returns a new instance of Auth service.
+func New(
+	log *slog.Logger,
+	verifier TokenVerifier,
+	cacheTTL time.Duration,
+) *Auth {
+	return &Auth{
+		log:      log,
+		verifier: verifier,
+		cacheTTL: cacheTTL,
+		now:      time.Now,
+		cache:    make(map[[sha256.Size]byte]cacheEntry),
+	}
+}
+
+// Authenticate returns the identity of the owner of the access token.
+//
+// If the token is malformed, expired or revoked, returns ErrInvalidToken.
+func (a *Auth) Authenticate(ctx context.Context, token string) (Identity, error) {
+	const op = "services.auth.Authenticate"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	// the tokens are not kept in memory as is
+	key := sha256.Sum256([]byte(token))
+
+	if identity, ok := a.cached(key); ok {
+		return identity, nil
+	}
+
+	res, err := a.verifier.VerifyToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			log.Debug("invalid token", slog.Any("error", err))
+
+			return Identity{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to verify token", slog.Any("error", err))
+
+		return Identity{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	identity := Identity{
+		UserID:    res.GetUserId(),
+		Email:     res.GetEmail(),
+		Role:      res.GetRole(),
+		Scope:     res.GetScope(),
+		AppID:     res.GetAppId(),
+		ExpiresAt: res.GetExpiresAt().AsTime(),
+	}
+
+	if !identity.ExpiresAt.After(a.now()) {
+		return Identity{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	a.store(key, identity)
+
+	return identity, nil
+}
+
+// cached returns the identity of the verified token
+// if it is still in the cache.
+func (a *Auth) cached(key [sha256.Size]byte) (Identity, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.cache[key]
+	if !ok {
+		return Identity{}, false
+	}
+
+	if !entry.expiresAt.After(a.now()) {
+		delete(a.cache, key)
+
+		return Identity{}, false
+	}
+
+	return entry.identity, true
+}
+
+// store caches the identity of the verified token until the cacheTTL passes,
+// but not longer than the token lives.
+func (a *Auth) store(key [sha256.Size]byte, identity Identity) {
+	if a.cacheTTL <= 0 {
+		return
+	}
+
+	now := a.now()
+
+	expiresAt := now.Add(a.cacheTTL)
+	if identity.ExpiresAt.Before(expiresAt) {
+		expiresAt = identity.ExpiresAt
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.cache) >= maxCacheSize {
+		for k, entry := range a.cache {
+			if !entry.expiresAt.After(now) {
+				delete(a.cache, k)
+			}
+		}
+	}
+
+	// every entry is still fresh, the cache is started over
+	// rather than grown without a bound
+	if len(a.cache) >= maxCacheSize {
+		clear(a.cache)
+	}
+
+	a.cache[key] = cacheEntry{
+		identity:  identity,
+		expiresAt: expiresAt,
+	}
+}
+
+type contextKey struct{}
+
+// WithIdentity returns the context of the request of the identified caller.
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// IdentityFromContext returns the identity of the caller of the request.
+// It returns false for the requests of the public routes.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(contextKey{}).(Identity)
+	return identity, ok
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	ssov1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/sso/v1"
+)
+
+type fakeVerifier struct {
+	responses map[string]*ssov1.IntrospectResponse
+	err       error
+	calls     int
+}
+
+func (v *fakeVerifier) VerifyToken(
+	_ context.Context,
+	token string,
+) (*ssov1.IntrospectResponse, error) {
+	v.calls++
+
+	if v.err != nil {
+		return nil, v.err
+	}
+
+	res, ok := v.responses[token]
+	if !ok {
+		return nil, fmt.Errorf("verify: %w", ErrInvalidToken)
+	}
+
+	return res, nil
+}
+
+func newTestAuth(verifier TokenVerifier, cacheTTL time.Duration, now *time.Time) *Auth {
+	a := New(slog.New(slog.NewTextHandler(io.Discard, nil)), verifier, cacheTTL)
+	a.now = func() time.Time { return *now }
+
+	return a
+}
+
+func TestAuthenticate(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	verifier := &fakeVerifier{
+		responses: map[string]*ssov1.IntrospectResponse{
+			"teacher": {
+				Active:    true,
+				UserId:    7,
+				Email:     "teacher@example.com",
+				Role:      "teacher",
+				Scope:     []string{"tasks:write"},
+				ExpiresAt: timestamppb.New(now.Add(time.Hour)),
+			},
+		},
+	}
+
+	a := newTestAuth(verifier, time.Minute, &now)
+	ctx := context.Background()
+
+	t.Run("valid token is cached", func(t *testing.T) {
+		identity, err := a.Authenticate(ctx, "teacher")
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), identity.UserID)
+		assert.Equal(t, "teacher", identity.Role)
+		assert.Equal(t, []string{"tasks:write"}, identity.Scope)
+
+		_, err = a.Authenticate(ctx, "teacher")
+		require.NoError(t, err)
+		assert.Equal(t, 1, verifier.calls)
+	})
+
+	t.Run("cache expires", func(t *testing.T) {
+		now = now.Add(time.Minute)
+
+		_, err := a.Authenticate(ctx, "teacher")
+		require.NoError(t, err)
+		assert.Equal(t, 2, verifier.calls)
+	})
+
+	t.Run("cache does not outlive token", func(t *testing.T) {
+		verifier.calls = 0
+		verifier.responses["expiring"] = &ssov1.IntrospectResponse{
+			Active:    true,
+			UserId:    8,
+			Role:      "student",
+			ExpiresAt: timestamppb.New(now.Add(10 * time.Second)),
+		}
+
+		_, err := a.Authenticate(ctx, "expiring")
+		require.NoError(t, err)
+
+		now = now.Add(10 * time.Second)
+
+		_, err = a.Authenticate(ctx, "expiring")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		assert.Equal(t, 2, verifier.calls)
+	})
+
+	t.Run("invalid token is not cached", func(t *testing.T) {
+		verifier.calls = 0
+
+		_, err := a.Authenticate(ctx, "unknown")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+
+		_, err = a.Authenticate(ctx, "unknown")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		assert.Equal(t, 2, verifier.calls)
+	})
+}
+
+func TestAuthenticate_VerifierFailure(t *testing.T) {
+	now := time.Now()
+
+	a := newTestAuth(&fakeVerifier{err: errors.New("connection refused")}, time.Minute, &now)
+
+	_, err := a.Authenticate(context.Background(), "teacher")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestIdentityFromContext(t *testing.T) {
+	_, ok := IdentityFromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := WithIdentity(context.Background(), Identity{UserID: 3, Role: "student"})
+
+	identity, ok := IdentityFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, int64(3), identity.UserID)
+}
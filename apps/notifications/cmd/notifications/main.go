@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"notifications/internal/app"
+	"notifications/internal/config"
+)
+
+const (
+	envLocal = "local"
+	envDev   = "dev"
+	envProd  = "prod"
+)
+
+func main() {
+	cfg := config.MustLoad()
+
+	log := setupLogger(cfg.Env)
+
+	log.Info("starting application")
+
+	connString := fmt.Sprintf(
+		"postgres://%s:%s@%s:%d/%s?sslmode=%s",
+		cfg.Database.User,
+		cfg.Database.Password,
+		cfg.Database.Host,
+		cfg.Database.Port,
+		cfg.Database.Name,
+		cfg.Database.SSLMode,
+	)
+
+	application := app.New(
+		log,
+		cfg.GRPC.Port,
+		connString,
+		cfg.Scheduler,
+		cfg.Events,
+		cfg.SMTP,
+		cfg.Webhook,
+	)
+
+	go application.GRPCServer.MustRun()
+	application.Scheduler.Run()
+	application.MustRunConsumer()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+
+	sysSign := <-stop
+
+	log.Info("stopping application", slog.String("signal", sysSign.String()))
+
+	application.Consumer.Stop()
+	application.SSOConsumer.Stop()
+	application.WebhookConsumer.Stop()
+	application.SSOWebhookConsumer.Stop()
+	application.GRPCServer.Stop()
+	application.Scheduler.Stop()
+
+	log.Info("application stopped")
+}
+
+// setupLogger creates a new logger instance based on the environment.
+func setupLogger(env string) *slog.Logger {
+	var log *slog.Logger
+	switch env {
+	case envLocal:
+		log = slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		)
+	case envDev:
+		log = slog.New(
+			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		)
+	case envProd:
+		log = slog.New(
+			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		)
+	}
+
+	return log
+}
@@ -0,0 +1,208 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	grpcapp "notifications/internal/app/grpc"
+	"notifications/internal/config"
+	"notifications/internal/domain/models"
+	"notifications/internal/events/sso"
+	"notifications/internal/events/tasks"
+	webhookevents "notifications/internal/events/webhook"
+	"notifications/internal/sender/email"
+	"notifications/internal/sender/webhook"
+	"notifications/internal/services/notification"
+	webhookservice "notifications/internal/services/webhook"
+	"notifications/internal/storage/postgres"
+
+	"github.com/Kaptoshka/creative-learning-platform/libs/platform/broker/nats"
+	schedulerapp "github.com/Kaptoshka/creative-learning-platform/libs/platform/scheduler"
+)
+
+const (
+	// subjects matches the subjects of every event of the tasks service.
+	subjects = "tasks.events.>"
+	// ssoSubjects matches the subjects of every event of the SSO service.
+	ssoSubjects = "sso.events.>"
+)
+
+type App struct {
+	GRPCServer         *grpcapp.App
+	Scheduler          *schedulerapp.App
+	Consumer           *nats.Consumer
+	SSOConsumer        *nats.Consumer
+	WebhookConsumer    *nats.Consumer
+	SSOWebhookConsumer *nats.Consumer
+	handler            *tasks.Handler
+	ssoHandler         *sso.Handler
+	webhookHandler     *webhookevents.Handler
+}
+
+// New creates a new instance of the App struct.
+func New(
+	log *slog.Logger,
+	grpcPort int,
+	connString string,
+	schedulerCfg config.SchedulerConfig,
+	eventsCfg config.EventsConfig,
+	smtpCfg config.SMTPConfig,
+	webhookCfg config.WebhookConfig,
+) *App {
+	client, err := postgres.New(connString)
+	if err != nil {
+		log.Error("failed to connect to database", slog.Any("error", err))
+
+		return nil
+	}
+
+	webhookSender := webhook.New(webhookCfg.Timeout, webhookCfg.AllowPrivateNetworks)
+
+	notificationService := notification.New(
+		log,
+		client.NotificationStorage,
+		client.NotificationStorage,
+		client.PreferenceStorage,
+		client.PreferenceStorage,
+		client.ReminderStorage,
+		map[models.Channel]notification.Sender{
+			models.ChannelEmail: email.New(email.Config{
+				Host: smtpCfg.Host,
+				Port: smtpCfg.Port,
+				From: smtpCfg.From,
+			}),
+			models.ChannelWebhook: webhookSender,
+		},
+	)
+
+	webhookService := webhookservice.New(
+		log,
+		client.WebhookStorage,
+		client.WebhookStorage,
+		webhookSender,
+		webhookevents.EventTypes,
+		webhookCfg.Retention,
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	consumer, err := nats.NewConsumer(
+		ctx,
+		log,
+		eventsCfg.NATSURL,
+		eventsCfg.Stream,
+		eventsCfg.Consumer,
+		subjects,
+		client.ParkedEventStorage,
+	)
+	if err != nil {
+		log.Error("failed to create consumer", slog.Any("error", err))
+
+		return nil
+	}
+
+	ssoConsumer, err := nats.NewConsumer(
+		ctx,
+		log,
+		eventsCfg.NATSURL,
+		eventsCfg.SSOStream,
+		eventsCfg.Consumer,
+		ssoSubjects,
+		client.ParkedEventStorage,
+	)
+	if err != nil {
+		log.Error("failed to create sso consumer", slog.Any("error", err))
+
+		return nil
+	}
+
+	webhookConsumer, err := nats.NewConsumer(
+		ctx,
+		log,
+		eventsCfg.NATSURL,
+		eventsCfg.Stream,
+		eventsCfg.WebhookConsumer,
+		subjects,
+		client.ParkedEventStorage,
+	)
+	if err != nil {
+		log.Error("failed to create webhook consumer", slog.Any("error", err))
+
+		return nil
+	}
+
+	ssoWebhookConsumer, err := nats.NewConsumer(
+		ctx,
+		log,
+		eventsCfg.NATSURL,
+		eventsCfg.SSOStream,
+		eventsCfg.WebhookConsumer,
+		ssoSubjects,
+		client.ParkedEventStorage,
+	)
+	if err != nil {
+		log.Error("failed to create sso webhook consumer", slog.Any("error", err))
+
+		return nil
+	}
+
+	grpcApp := grpcapp.New(log, notificationService, webhookService, grpcPort)
+
+	scheduler := schedulerapp.New(
+		log,
+		schedulerapp.Job{
+			Name:     "send_due_reminders",
+			Interval: schedulerCfg.ReminderInterval,
+			Run:      notificationService.SendReminders,
+		},
+		schedulerapp.Job{
+			Name:     "deliver_notifications",
+			Interval: schedulerCfg.DeliveryInterval,
+			Run:      notificationService.DeliverNotifications,
+		},
+		schedulerapp.Job{
+			Name:     "deliver_webhooks",
+			Interval: schedulerCfg.WebhookInterval,
+			Run:      webhookService.DeliverWebhooks,
+		},
+		schedulerapp.Job{
+			Name:     "purge_webhook_deliveries",
+			Interval: schedulerCfg.PurgeInterval,
+			Run:      webhookService.PurgeDeliveries,
+		},
+	)
+
+	return &App{
+		GRPCServer:         grpcApp,
+		Scheduler:          scheduler,
+		Consumer:           consumer,
+		SSOConsumer:        ssoConsumer,
+		WebhookConsumer:    webhookConsumer,
+		SSOWebhookConsumer: ssoWebhookConsumer,
+		handler:            tasks.New(log, notificationService),
+		ssoHandler:         sso.New(log, notificationService),
+		webhookHandler:     webhookevents.New(log, webhookService),
+	}
+}
+
+// MustRunConsumer starts handling the events of the tasks and SSO services,
+// including posting them to the webhooks, and panics if error occurs.
+func (a *App) MustRunConsumer() {
+	if err := a.Consumer.Run(a.handler.Handle); err != nil {
+		panic(err)
+	}
+
+	if err := a.SSOConsumer.Run(a.ssoHandler.Handle); err != nil {
+		panic(err)
+	}
+
+	if err := a.WebhookConsumer.Run(a.webhookHandler.HandleTasks); err != nil {
+		panic(err)
+	}
+
+	if err := a.SSOWebhookConsumer.Run(a.webhookHandler.HandleSSO); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,83 @@
+package grpcapp
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+
+	"notifications/internal/auth"
+	notificationsgrpc "notifications/internal/grpc/notifications"
+
+	"google.golang.org/grpc"
+
+	"github.com/Kaptoshka/creative-learning-platform/libs/platform/identity"
+)
+
+type App struct {
+	log        *slog.Logger
+	gRPCServer *grpc.Server
+	port       int
+}
+
+// New creates a new instance of the gRPC app struct.
+func New(
+	log *slog.Logger,
+	notificationService notificationsgrpc.Notifications,
+	webhookService notificationsgrpc.Webhooks,
+	port int,
+) *App {
+	gRPCServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(identity.UnaryServerInterceptor(auth.WithIdentity)),
+		grpc.ChainStreamInterceptor(identity.StreamServerInterceptor(auth.WithIdentity)),
+	)
+
+	notificationsgrpc.Register(gRPCServer, notificationService, webhookService)
+
+	return &App{
+		log:        log,
+		gRPCServer: gRPCServer,
+		port:       port,
+	}
+}
+
+// MustRun run gRPC server and panic if error occurs
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		a.log.Error("failed to run app", "error", err)
+		panic(err)
+	}
+}
+
+// Run runs gRPC server
+func (a *App) Run() error {
+	const op = "grpcapp.Run"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("port", a.port),
+	)
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", a.port))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("gRPC server is running")
+	if err := a.gRPCServer.Serve(l); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Stop stops gRPC server
+func (a *App) Stop() {
+	const op = "grpcapp.Stop"
+
+	a.log.With(
+		slog.String("op", op),
+		slog.Int("port", a.port),
+	)
+
+	a.gRPCServer.GracefulStop()
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Kaptoshka/creative-learning-platform/libs/platform/identity"
+)
+
+type contextKey string
+
+const (
+	contextKeyUserID contextKey = "user_id"
+	contextKeyRole   contextKey = "role"
+	contextKeyApp    contextKey = "app_id"
+)
+
+const (
+	RoleStudent     = "student"
+	RoleTeacher     = "teacher"
+	RoleHeadTeacher = "head_teacher"
+	RoleAdmin       = "admin"
+	RoleDev         = "dev"
+)
+
+func GetUserID(ctx context.Context) (int64, error) {
+	val, ok := ctx.Value(contextKeyUserID).(int64)
+	if !ok {
+		return 0, errors.New("user id not found in context")
+	}
+	return val, nil
+}
+
+func GetUserRole(ctx context.Context) string {
+	val, ok := ctx.Value(contextKeyRole).(string)
+	if !ok {
+		return ""
+	}
+	return val
+}
+
+func WithUser(ctx context.Context, userID int64, role string) context.Context {
+	ctx = context.WithValue(ctx, contextKeyUserID, userID)
+	ctx = context.WithValue(ctx, contextKeyRole, role)
+	return ctx
+}
+
+// GetAppID returns the app the user has signed in to.
+// If the app is unknown, it returns 0.
+func GetAppID(ctx context.Context) int32 {
+	val, ok := ctx.Value(contextKeyApp).(int32)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+func WithApp(ctx context.Context, appID int32) context.Context {
+	return context.WithValue(ctx, contextKeyApp, appID)
+}
+
+// WithIdentity puts the caller identified by the api-gateway into the context.
+func WithIdentity(ctx context.Context, caller identity.Identity) context.Context {
+	ctx = WithUser(ctx, caller.UserID, caller.Role)
+	if caller.AppID > 0 {
+		ctx = WithApp(ctx, caller.AppID)
+	}
+	return ctx
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+type Config struct {
+	Env       string          `yaml:"env" env-default:"local"`
+	Database  Database        `yaml:"database"`
+	GRPC      GRPCConfig      `yaml:"grpc"`
+	Scheduler SchedulerConfig `yaml:"scheduler"`
+	Events    EventsConfig    `yaml:"events"`
+	SMTP      SMTPConfig      `yaml:"smtp"`
+	Webhook   WebhookConfig   `yaml:"webhook"`
+}
+
+type GRPCConfig struct {
+	Port    int           `yaml:"port"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type SchedulerConfig struct {
+	// ReminderInterval is how often the due reminders are sent.
+	ReminderInterval time.Duration `yaml:"reminder_interval" env-default:"1m"`
+	DeliveryInterval time.Duration `yaml:"delivery_interval" env-default:"5s"`
+	// WebhookInterval is how often the pending webhook deliveries are posted.
+	WebhookInterval time.Duration `yaml:"webhook_interval" env-default:"5s"`
+	// PurgeInterval is how often the old webhook deliveries are deleted.
+	PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
+}
+
+type EventsConfig struct {
+	NATSURL string `yaml:"nats_url" env-default:"nats://localhost:4222"`
+	// Stream is the JetStream stream of the events of the tasks service.
+	Stream string `yaml:"stream" env-default:"TASKS_EVENTS"`
+	// SSOStream is the JetStream stream of the events of the SSO service.
+	SSOStream string `yaml:"sso_stream" env-default:"SSO_EVENTS"`
+	// Consumer is the name of the durable consumers of the streams.
+	Consumer string `yaml:"consumer" env-default:"notifications"`
+	// WebhookConsumer is the name of the durable consumers of the streams
+	// which post the events to the webhooks.
+	WebhookConsumer string `yaml:"webhook_consumer" env-default:"notifications-webhooks"`
+}
+
+type SMTPConfig struct {
+	Host string `yaml:"host" env-default:"localhost"`
+	Port int    `yaml:"port" env-default:"1025"`
+	From string `yaml:"from" env-default:"no-reply@creative-learning.local"`
+}
+
+type WebhookConfig struct {
+	Timeout time.Duration `yaml:"timeout" env-default:"10s"`
+	// Retention is how long the delivered webhook deliveries are kept.
+	Retention time.Duration `yaml:"retention" env-default:"720h"`
+	// AllowPrivateNetworks lets the webhooks reach the loopback and the private addresses,
+	// it is meant for the local environment only.
+	AllowPrivateNetworks bool `yaml:"allow_private_networks" env-default:"false"`
+}
+
+type Database struct {
+	Host     string `yaml:"host" env-required:"true"`
+	Port     int    `yaml:"port" env-required:"true"`
+	User     string `yaml:"user" env-required:"true"`
+	Password string `yaml:"password" env-required:"true"`
+	Name     string `yaml:"name" env-required:"true"`
+	SSLMode  string `yaml:"sslmode" env-default:"disable"`
+}
+
+// MustLoad retrive path to config
+// if there is no config path provided, it will panic.
+func MustLoad() *Config {
+	path := fetchConfigPath()
+	if path == "" {
+		panic("config path is empty")
+	}
+
+	return MustLoadByPath(path)
+}
+
+// MustLoadByPath loads the configuration from the specified path.
+// If config cannot be loaded, it will panic.
+func MustLoadByPath(path string) *Config {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		panic("config file does not exist" + path)
+	}
+
+	var cfg Config
+
+	if err := cleanenv.ReadConfig(path, &cfg); err != nil {
+		panic("failed to load config: " + err.Error())
+	}
+
+	return &cfg
+}
+
+// fetchConfigPath fetches config path from command line flag or environment variable
+// Priority: flag > env > default
+// Default value: is empty string
+func fetchConfigPath() string {
+	var res string
+
+	// --config="path/to/config.yaml"
+	flag.StringVar(&res, "config", "", "path to config file")
+	flag.Parse()
+
+	if res == "" {
+		res = os.Getenv("CONFIG_PATH")
+	}
+	return res
+}
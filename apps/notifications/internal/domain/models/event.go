@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// StudentAssignment is the assignment handed out to the student.
+type StudentAssignment struct {
+	ID           string
+	AssignmentID string
+	StudentID    int64
+	Title        string
+	DueDate      time.Time
+}
+
+// Feedback is the published grade of the submission or its return for rework.
+type Feedback struct {
+	// EventID is the id of the event the feedback is published in.
+	EventID             string
+	SubmissionID        string
+	AssignmentID        string
+	StudentAssignmentID string
+	AssignmentTitle     string
+	StudentID           int64
+	// Returned is set if the submission is returned to the student for rework.
+	Returned bool
+	// Score is nil if the submission is not scored.
+	Score       *float64
+	PublishedAt time.Time
+}
+
+// User is the user registered in the platform.
+type User struct {
+	ID           int64
+	Email        string
+	FirstName    string
+	RegisteredAt time.Time
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+type NotificationKind string
+
+const (
+	KindDueReminder        NotificationKind = "due_reminder"
+	KindFeedbackPublished  NotificationKind = "feedback_published"
+	KindSubmissionReturned NotificationKind = "submission_returned"
+	KindWelcome            NotificationKind = "welcome"
+)
+
+// Notification is the message to the user about the event in the platform.
+type Notification struct {
+	ID     string
+	UserID int64
+	Kind   NotificationKind
+	// DedupeKey identifies the occurrence the notification is about,
+	// the notification with the same key is never created twice.
+	DedupeKey    string
+	Title        string
+	Body         string
+	AssignmentID string
+	SubmissionID string
+	// InInbox is set if the notification is shown in the in-app inbox.
+	InInbox   bool
+	ReadAt    time.Time
+	CreatedAt time.Time
+}
+
+// InboxCursor is the position in the inbox after the notification
+// created at CreatedAt with the ID. The zero cursor is the start of the inbox.
+type InboxCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending DeliveryStatus = "pending"
+	DeliverySent    DeliveryStatus = "sent"
+	DeliveryFailed  DeliveryStatus = "failed"
+)
+
+// Delivery is the sending of the notification by email or webhook.
+type Delivery struct {
+	Notification Notification
+	Channel      Channel
+	// Recipient is the email address or the webhook url.
+	Recipient string
+	Status    DeliveryStatus
+	Attempts  int
+}
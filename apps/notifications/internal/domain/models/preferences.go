@@ -0,0 +1,39 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+type Channel string
+
+const (
+	ChannelInbox   Channel = "inbox"
+	ChannelEmail   Channel = "email"
+	ChannelWebhook Channel = "webhook"
+)
+
+// Preferences are the channels the user gets the notifications by.
+type Preferences struct {
+	UserID   int64
+	Channels []Channel
+	// Email is the address the notifications are sent to by email.
+	Email string
+	// WebhookURL is the url the notifications are posted to.
+	WebhookURL string
+	UpdatedAt  time.Time
+}
+
+// DefaultPreferences returns the preferences of the user
+// who has never changed them: the notifications are kept in the inbox only.
+func DefaultPreferences(userID int64) Preferences {
+	return Preferences{
+		UserID:   userID,
+		Channels: []Channel{ChannelInbox},
+	}
+}
+
+// Enabled reports whether the user gets the notifications by the channel.
+func (p Preferences) Enabled(channel Channel) bool {
+	return slices.Contains(p.Channels, channel)
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Reminder is the notice to the student that the due date of the assignment is near.
+type Reminder struct {
+	StudentAssignmentID string
+	// Lead is how long before the due date the reminder is sent.
+	Lead         time.Duration
+	AssignmentID string
+	StudentID    int64
+	Title        string
+	DueDate      time.Time
+	SendAt       time.Time
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// WebhookSubscription is the url the events of the chosen types are posted to
+// on behalf of the integration registered as the app in SSO.
+type WebhookSubscription struct {
+	ID         string
+	AppID      int32
+	URL        string
+	EventTypes []string
+	// Secret is the key of the HMAC-SHA256 signature of the deliveries.
+	Secret    string
+	CreatedBy int64
+	CreatedAt time.Time
+}
+
+// WebhookEvent is the domain event of the platform posted to the webhooks.
+type WebhookEvent struct {
+	ID   string
+	Type string
+	// AppID is the app the event belongs to, only its webhooks get the event.
+	AppID int32
+	// Payload is the JSON body posted to the url.
+	Payload    []byte
+	OccurredAt time.Time
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookPending   WebhookDeliveryStatus = "pending"
+	WebhookDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDead is the delivery which has exhausted its attempts,
+	// it stays in the dead-letter list until it is redelivered.
+	WebhookDead WebhookDeliveryStatus = "dead"
+)
+
+// WebhookDelivery is the posting of the event to the webhook.
+type WebhookDelivery struct {
+	ID             string
+	SubscriptionID string
+	EventID        string
+	EventType      string
+	Payload        []byte
+	Status         WebhookDeliveryStatus
+	Attempts       int
+	LastError      string
+	LastStatusCode int
+	CreatedAt      time.Time
+	DeliveredAt    time.Time
+	// URL and Secret are the ones of the subscription at the time of the claim.
+	URL    string
+	Secret string
+}
@@ -0,0 +1,100 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"notifications/internal/domain/models"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	ssoeventsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/sso/events/v1"
+)
+
+type Notifications interface {
+	NotifyWelcome(
+		ctx context.Context,
+		user models.User,
+	) error
+}
+
+// Handler handles the domain events of the SSO service.
+type Handler struct {
+	log           *slog.Logger
+	notifications Notifications
+}
+
+// New creates a new Handler instance.
+func New(log *slog.Logger, notifications Notifications) *Handler {
+	return &Handler{
+		log:           log,
+		notifications: notifications,
+	}
+}
+
+// Handle handles the serialized event of the SSO service.
+// The events may be delivered more than once, so every handling is idempotent.
+// The events of the unknown types and the malformed events are skipped,
+// any other error means the event has to be delivered again.
+func (h *Handler) Handle(ctx context.Context, data []byte) error {
+	const op = "events.sso.Handle"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	var event ssoeventsv1.Event
+	if err := proto.Unmarshal(data, &event); err != nil {
+		log.Error("malformed event", slog.Any("error", err))
+
+		return nil
+	}
+
+	log = log.With(
+		slog.String("event_id", event.GetId()),
+		slog.String("type", string(event.GetPayload().MessageName())),
+	)
+
+	payload, err := event.GetPayload().UnmarshalNew()
+	if err != nil {
+		if errors.Is(err, protoregistry.NotFound) {
+			log.Debug("unknown event skipped")
+
+			return nil
+		}
+
+		log.Error("malformed event", slog.Any("error", err))
+
+		return nil
+	}
+
+	switch p := payload.(type) {
+	case *ssoeventsv1.UserRegistered:
+		userID, err := strconv.ParseInt(p.GetUserId(), 10, 64)
+		if err != nil {
+			log.Error("malformed event", slog.Any("error", fmt.Errorf("invalid user id %q", p.GetUserId())))
+
+			return nil
+		}
+
+		err = h.notifications.NotifyWelcome(ctx, models.User{
+			ID:           userID,
+			Email:        p.GetEmail(),
+			FirstName:    p.GetFirstName(),
+			RegisteredAt: p.GetRegisteredAt().AsTime(),
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	default:
+		return nil
+	}
+
+	log.Debug("event handled")
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"notifications/internal/domain/models"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	eventsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/events/v1"
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+type Notifications interface {
+	ScheduleReminders(
+		ctx context.Context,
+		assignments []models.StudentAssignment,
+		publishedAt time.Time,
+	) error
+	RescheduleReminders(
+		ctx context.Context,
+		assignments []models.StudentAssignment,
+		updatedAt time.Time,
+	) error
+	SuspendReminders(
+		ctx context.Context,
+		assignmentID string,
+		suspended bool,
+	) error
+	CancelReminders(
+		ctx context.Context,
+		studentAssignmentID string,
+		submittedAt time.Time,
+	) error
+	NotifyFeedback(
+		ctx context.Context,
+		feedback models.Feedback,
+	) error
+}
+
+// Handler handles the domain events of the tasks service.
+type Handler struct {
+	log           *slog.Logger
+	notifications Notifications
+}
+
+// New creates a new Handler instance.
+func New(log *slog.Logger, notifications Notifications) *Handler {
+	return &Handler{
+		log:           log,
+		notifications: notifications,
+	}
+}
+
+// Handle handles the serialized event of the tasks service.
+// The events may be delivered more than once, so every handling is idempotent.
+// The events of the unknown types and the malformed events are skipped,
+// any other error means the event has to be delivered again.
+func (h *Handler) Handle(ctx context.Context, data []byte) error {
+	const op = "events.tasks.Handle"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	var event eventsv1.Event
+	if err := proto.Unmarshal(data, &event); err != nil {
+		log.Error("malformed event", slog.Any("error", err))
+
+		return nil
+	}
+
+	log = log.With(
+		slog.String("event_id", event.GetId()),
+		slog.String("type", string(event.GetPayload().MessageName())),
+	)
+
+	payload, err := event.GetPayload().UnmarshalNew()
+	if err != nil {
+		if errors.Is(err, protoregistry.NotFound) {
+			log.Debug("unknown event skipped")
+
+			return nil
+		}
+
+		log.Error("malformed event", slog.Any("error", err))
+
+		return nil
+	}
+
+	if err := h.handle(ctx, &event, payload); err != nil {
+		var malformed malformedError
+		if errors.As(err, &malformed) {
+			log.Error("malformed event", slog.Any("error", err))
+
+			return nil
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Debug("event handled")
+
+	return nil
+}
+
+func (h *Handler) handle(ctx context.Context, event *eventsv1.Event, payload proto.Message) error {
+	switch p := payload.(type) {
+	case *eventsv1.AssignmentPublished:
+		assignments, err := studentAssignments(p.GetAssignmentId(), p.GetTitle(), p.GetStudents())
+		if err != nil {
+			return err
+		}
+
+		return h.notifications.ScheduleReminders(ctx, assignments, p.GetPublishedAt().AsTime())
+	case *eventsv1.AssignmentUpdated:
+		assignments, err := studentAssignments(p.GetAssignmentId(), p.GetTitle(), p.GetStudents())
+		if err != nil {
+			return err
+		}
+
+		return h.notifications.RescheduleReminders(ctx, assignments, event.GetOccurredAt().AsTime())
+	case *eventsv1.AssignmentDeleted:
+		return h.notifications.SuspendReminders(ctx, p.GetAssignmentId(), true)
+	case *eventsv1.AssignmentRestored:
+		return h.notifications.SuspendReminders(ctx, p.GetAssignmentId(), false)
+	case *eventsv1.SubmissionSubmitted:
+		return h.notifications.CancelReminders(
+			ctx,
+			p.GetStudentAssignmentId(),
+			p.GetSubmittedAt().AsTime(),
+		)
+	case *eventsv1.FeedbackPublished:
+		studentID, err := parseID(p.GetStudentId())
+		if err != nil {
+			return err
+		}
+
+		return h.notifications.NotifyFeedback(ctx, models.Feedback{
+			EventID:             event.GetId(),
+			SubmissionID:        p.GetSubmissionId(),
+			AssignmentID:        p.GetAssignmentId(),
+			StudentAssignmentID: p.GetStudentAssignmentId(),
+			AssignmentTitle:     p.GetAssignmentTitle(),
+			StudentID:           studentID,
+			Returned:            p.GetStatus() == tasksv1.SubmissionStatus_SUBMISSION_STATUS_RETURNED,
+			Score:               p.Score,
+			PublishedAt:         p.GetPublishedAt().AsTime(),
+		})
+	default:
+		return nil
+	}
+}
+
+// studentAssignments converts the student assignments given in the event
+// of the assignment, each with its own due date.
+func studentAssignments(
+	assignmentID string,
+	title string,
+	students []*eventsv1.StudentAssignment,
+) ([]models.StudentAssignment, error) {
+	assignments := make([]models.StudentAssignment, 0, len(students))
+	for _, student := range students {
+		studentID, err := parseID(student.GetStudentId())
+		if err != nil {
+			return nil, err
+		}
+
+		assignments = append(assignments, models.StudentAssignment{
+			ID:           student.GetStudentAssignmentId(),
+			AssignmentID: assignmentID,
+			StudentID:    studentID,
+			Title:        title,
+			DueDate:      student.GetDueDate().AsTime(),
+		})
+	}
+
+	return assignments, nil
+}
+
+// malformedError is the event which cannot be handled however many times it is delivered.
+type malformedError struct {
+	err error
+}
+
+func (e malformedError) Error() string {
+	return e.err.Error()
+}
+
+// parseID parses the id of the user given in the event.
+func parseID(id string) (int64, error) {
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, malformedError{err: fmt.Errorf("invalid user id %q", id)}
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"notifications/internal/domain/models"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	ssoeventsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/sso/events/v1"
+	eventsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/events/v1"
+)
+
+// EventTypes are the types of the events the webhooks can subscribe to,
+// which are the full names of the payloads of the events.
+var EventTypes = []string{
+	eventType(&eventsv1.AssignmentCreated{}),
+	eventType(&eventsv1.AssignmentPublished{}),
+	eventType(&eventsv1.AssignmentUpdated{}),
+	eventType(&eventsv1.AssignmentDeleted{}),
+	eventType(&eventsv1.AssignmentRestored{}),
+	eventType(&eventsv1.SubmissionSubmitted{}),
+	eventType(&eventsv1.FeedbackPublished{}),
+	eventType(&eventsv1.RegradeRequested{}),
+	eventType(&eventsv1.RegradeResolved{}),
+	eventType(&ssoeventsv1.UserRegistered{}),
+}
+
+type Webhooks interface {
+	Dispatch(
+		ctx context.Context,
+		event models.WebhookEvent,
+	) error
+}
+
+// Handler posts the domain events of the tasks and SSO services to the webhooks.
+type Handler struct {
+	log      *slog.Logger
+	webhooks Webhooks
+}
+
+// New creates a new Handler instance.
+func New(log *slog.Logger, webhooks Webhooks) *Handler {
+	return &Handler{
+		log:      log,
+		webhooks: webhooks,
+	}
+}
+
+// payload is the body of the delivery posted to the webhook.
+type payload struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// HandleTasks handles the serialized event of the tasks service.
+func (h *Handler) HandleTasks(ctx context.Context, data []byte) error {
+	const op = "events.webhook.HandleTasks"
+
+	var event eventsv1.Event
+	if err := proto.Unmarshal(data, &event); err != nil {
+		h.log.Error("malformed event", slog.String("op", op), slog.Any("error", err))
+
+		return nil
+	}
+
+	if err := h.handle(ctx, event.GetId(), event.GetAppId(), event.GetOccurredAt(), event.GetPayload()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// HandleSSO handles the serialized event of the SSO service.
+func (h *Handler) HandleSSO(ctx context.Context, data []byte) error {
+	const op = "events.webhook.HandleSSO"
+
+	var event ssoeventsv1.Event
+	if err := proto.Unmarshal(data, &event); err != nil {
+		h.log.Error("malformed event", slog.String("op", op), slog.Any("error", err))
+
+		return nil
+	}
+
+	if err := h.handle(ctx, event.GetId(), event.GetAppId(), event.GetOccurredAt(), event.GetPayload()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// handle dispatches the event to the webhooks of the app as JSON.
+// The events may be delivered more than once, so every handling is idempotent.
+// The events the webhooks can not subscribe to and the malformed events are skipped,
+// any other error means the event has to be delivered again.
+func (h *Handler) handle(
+	ctx context.Context,
+	id string,
+	appID int32,
+	occurredAt *timestamppb.Timestamp,
+	raw *anypb.Any,
+) error {
+	const op = "events.webhook.handle"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("event_id", id),
+		slog.Int("app_id", int(appID)),
+		slog.String("type", string(raw.MessageName())),
+	)
+
+	if !slices.Contains(EventTypes, string(raw.MessageName())) {
+		log.Debug("event skipped")
+
+		return nil
+	}
+
+	message, err := raw.UnmarshalNew()
+	if err != nil {
+		if errors.Is(err, protoregistry.NotFound) {
+			log.Debug("unknown event skipped")
+
+			return nil
+		}
+
+		log.Error("malformed event", slog.Any("error", err))
+
+		return nil
+	}
+
+	data, err := protojson.Marshal(message)
+	if err != nil {
+		log.Error("failed to marshal event", slog.Any("error", err))
+
+		return nil
+	}
+
+	body, err := json.Marshal(payload{
+		ID:         id,
+		Type:       eventType(message),
+		OccurredAt: occurredAt.AsTime(),
+		Data:       data,
+	})
+	if err != nil {
+		log.Error("failed to marshal event", slog.Any("error", err))
+
+		return nil
+	}
+
+	err = h.webhooks.Dispatch(ctx, models.WebhookEvent{
+		ID:         id,
+		Type:       eventType(message),
+		AppID:      appID,
+		Payload:    body,
+		OccurredAt: occurredAt.AsTime(),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Debug("event dispatched")
+
+	return nil
+}
+
+func eventType(message proto.Message) string {
+	return string(message.ProtoReflect().Descriptor().FullName())
+}
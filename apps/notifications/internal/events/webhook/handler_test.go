@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"notifications/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	ssoeventsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/sso/events/v1"
+	eventsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/events/v1"
+)
+
+// fakeWebhooks records the dispatched events.
+type fakeWebhooks struct {
+	events []models.WebhookEvent
+}
+
+func (f *fakeWebhooks) Dispatch(_ context.Context, event models.WebhookEvent) error {
+	f.events = append(f.events, event)
+	return nil
+}
+
+func marshal(t *testing.T, event proto.Message) []byte {
+	t.Helper()
+
+	data, err := proto.Marshal(event)
+	require.NoError(t, err)
+
+	return data
+}
+
+func anyOf(t *testing.T, message proto.Message) *anypb.Any {
+	t.Helper()
+
+	body, err := anypb.New(message)
+	require.NoError(t, err)
+
+	return body
+}
+
+func TestHandle_App(t *testing.T) {
+	webhooks := &fakeWebhooks{}
+	h := New(slog.New(slog.NewTextHandler(io.Discard, nil)), webhooks)
+	ctx := context.Background()
+
+	err := h.HandleTasks(ctx, marshal(t, &eventsv1.Event{
+		Id:         "tasks-event",
+		OccurredAt: timestamppb.Now(),
+		Payload:    anyOf(t, &eventsv1.AssignmentDeleted{AssignmentId: "assignment"}),
+		AppId:      1,
+	}))
+	require.NoError(t, err)
+
+	err = h.HandleSSO(ctx, marshal(t, &ssoeventsv1.Event{
+		Id:         "sso-event",
+		OccurredAt: timestamppb.Now(),
+		Payload:    anyOf(t, &ssoeventsv1.UserRegistered{UserId: "1"}),
+		AppId:      2,
+	}))
+	require.NoError(t, err)
+
+	require.Len(t, webhooks.events, 2)
+	assert.Equal(t, "tasks-event", webhooks.events[0].ID)
+	assert.Equal(t, int32(1), webhooks.events[0].AppID)
+	assert.Equal(t, "sso-event", webhooks.events[1].ID)
+	assert.Equal(t, int32(2), webhooks.events[1].AppID)
+}
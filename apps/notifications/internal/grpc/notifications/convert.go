@@ -0,0 +1,207 @@
+package notifications
+
+import (
+	"encoding/base64"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"notifications/internal/domain/models"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	notificationsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/notifications/v1"
+)
+
+const maxWebhookURLLen = 2048
+
+func toPreferences(preferences models.Preferences) *notificationsv1.Preferences {
+	res := &notificationsv1.Preferences{
+		Channels:   make([]notificationsv1.Channel, 0, len(preferences.Channels)),
+		Email:      preferences.Email,
+		WebhookUrl: preferences.WebhookURL,
+	}
+
+	for _, channel := range preferences.Channels {
+		res.Channels = append(res.Channels, toChannel(channel))
+	}
+
+	if !preferences.UpdatedAt.IsZero() {
+		res.UpdatedAt = timestamppb.New(preferences.UpdatedAt)
+	}
+
+	return res
+}
+
+// fromPreferences validates the preferences of the request and converts them.
+// The email and the webhook url are required if their channels are chosen.
+func fromPreferences(req *notificationsv1.Preferences) (models.Preferences, error) {
+	preferences := models.Preferences{
+		Email:      req.GetEmail(),
+		WebhookURL: req.GetWebhookUrl(),
+	}
+
+	for _, channel := range req.GetChannels() {
+		c, ok := fromChannel(channel)
+		if !ok {
+			return models.Preferences{}, status.Error(codes.InvalidArgument, "invalid channel")
+		}
+
+		if !preferences.Enabled(c) {
+			preferences.Channels = append(preferences.Channels, c)
+		}
+	}
+
+	if preferences.Email != "" || preferences.Enabled(models.ChannelEmail) {
+		addr, err := mail.ParseAddress(preferences.Email)
+		if err != nil || addr.Name != "" {
+			return models.Preferences{}, status.Error(codes.InvalidArgument, "invalid email")
+		}
+	}
+
+	if preferences.WebhookURL != "" || preferences.Enabled(models.ChannelWebhook) {
+		if !validWebhookURL(preferences.WebhookURL) {
+			return models.Preferences{}, status.Error(codes.InvalidArgument, "invalid webhook_url")
+		}
+	}
+
+	return preferences, nil
+}
+
+// validWebhookURL reports whether the url is the absolute http or https url.
+func validWebhookURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		len(rawURL) <= maxWebhookURLLen
+}
+
+func toChannel(channel models.Channel) notificationsv1.Channel {
+	switch channel {
+	case models.ChannelInbox:
+		return notificationsv1.Channel_CHANNEL_INBOX
+	case models.ChannelEmail:
+		return notificationsv1.Channel_CHANNEL_EMAIL
+	case models.ChannelWebhook:
+		return notificationsv1.Channel_CHANNEL_WEBHOOK
+	default:
+		return notificationsv1.Channel_CHANNEL_UNSPECIFIED
+	}
+}
+
+func fromChannel(channel notificationsv1.Channel) (models.Channel, bool) {
+	switch channel {
+	case notificationsv1.Channel_CHANNEL_INBOX:
+		return models.ChannelInbox, true
+	case notificationsv1.Channel_CHANNEL_EMAIL:
+		return models.ChannelEmail, true
+	case notificationsv1.Channel_CHANNEL_WEBHOOK:
+		return models.ChannelWebhook, true
+	default:
+		return "", false
+	}
+}
+
+func toNotification(notification models.Notification) *notificationsv1.Notification {
+	res := &notificationsv1.Notification{
+		Id:           notification.ID,
+		Kind:         toNotificationKind(notification.Kind),
+		Title:        notification.Title,
+		Body:         notification.Body,
+		AssignmentId: notification.AssignmentID,
+		SubmissionId: notification.SubmissionID,
+		CreatedAt:    timestamppb.New(notification.CreatedAt),
+	}
+
+	if !notification.ReadAt.IsZero() {
+		res.ReadAt = timestamppb.New(notification.ReadAt)
+	}
+
+	return res
+}
+
+func toNotificationKind(kind models.NotificationKind) notificationsv1.NotificationKind {
+	switch kind {
+	case models.KindDueReminder:
+		return notificationsv1.NotificationKind_NOTIFICATION_KIND_DUE_REMINDER
+	case models.KindFeedbackPublished:
+		return notificationsv1.NotificationKind_NOTIFICATION_KIND_FEEDBACK_PUBLISHED
+	case models.KindSubmissionReturned:
+		return notificationsv1.NotificationKind_NOTIFICATION_KIND_SUBMISSION_RETURNED
+	case models.KindWelcome:
+		return notificationsv1.NotificationKind_NOTIFICATION_KIND_WELCOME
+	default:
+		return notificationsv1.NotificationKind_NOTIFICATION_KIND_UNSPECIFIED
+	}
+}
+
+// toPageToken encodes the cursor of the inbox as the opaque page token.
+func toPageToken(cursor models.InboxCursor) string {
+	raw := strconv.FormatInt(cursor.CreatedAt.UnixMicro(), 10) + "/" + cursor.ID
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// fromPageToken decodes the page token of the request.
+// The empty token is the start of the inbox.
+func fromPageToken(token string) (models.InboxCursor, error) {
+	if token == "" {
+		return models.InboxCursor{}, nil
+	}
+
+	invalid := status.Error(codes.InvalidArgument, "invalid page_token")
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return models.InboxCursor{}, invalid
+	}
+
+	micros, id, ok := strings.Cut(string(raw), "/")
+	if !ok {
+		return models.InboxCursor{}, invalid
+	}
+
+	createdAt, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return models.InboxCursor{}, invalid
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		return models.InboxCursor{}, invalid
+	}
+
+	return models.InboxCursor{
+		CreatedAt: time.UnixMicro(createdAt).UTC(),
+		ID:        id,
+	}, nil
+}
+
+func toWebhook(subscription models.WebhookSubscription) *notificationsv1.Webhook {
+	return &notificationsv1.Webhook{
+		Id:         subscription.ID,
+		AppId:      subscription.AppID,
+		Url:        subscription.URL,
+		EventTypes: subscription.EventTypes,
+		CreatedBy:  subscription.CreatedBy,
+		CreatedAt:  timestamppb.New(subscription.CreatedAt),
+	}
+}
+
+func toWebhookDelivery(delivery models.WebhookDelivery) *notificationsv1.WebhookDelivery {
+	return &notificationsv1.WebhookDelivery{
+		Id:             delivery.ID,
+		WebhookId:      delivery.SubscriptionID,
+		EventId:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Payload:        string(delivery.Payload),
+		Attempts:       int32(delivery.Attempts),
+		LastError:      delivery.LastError,
+		LastStatusCode: int32(delivery.LastStatusCode),
+		CreatedAt:      timestamppb.New(delivery.CreatedAt),
+	}
+}
@@ -0,0 +1,166 @@
+package notifications
+
+import (
+	"context"
+
+	"notifications/internal/domain/models"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	notificationsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/notifications/v1"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	// maxMarkReadIDs limits the number of notifications marked as read at once.
+	maxMarkReadIDs = 500
+)
+
+// ListNotifications returns the page of the inbox of the calling user, the newest first.
+func (s *serverAPI) ListNotifications(
+	ctx context.Context,
+	req *notificationsv1.ListNotificationsRequest,
+) (*notificationsv1.ListNotificationsResponse, error) {
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int(req.GetPageSize())
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	cursor, err := fromPageToken(req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	notifications, err := s.notifications.ListNotifications(ctx, userID, cursor, req.GetUnreadOnly(), limit)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list notifications")
+	}
+
+	res := &notificationsv1.ListNotificationsResponse{
+		Notifications: make([]*notificationsv1.Notification, 0, len(notifications)),
+	}
+	for _, notification := range notifications {
+		res.Notifications = append(res.Notifications, toNotification(notification))
+	}
+
+	if len(notifications) == limit {
+		last := notifications[len(notifications)-1]
+		res.NextPageToken = toPageToken(models.InboxCursor{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+		})
+	}
+
+	return res, nil
+}
+
+// MarkRead marks the notifications of the calling user as read.
+func (s *serverAPI) MarkRead(
+	ctx context.Context,
+	req *notificationsv1.MarkReadRequest,
+) (*emptypb.Empty, error) {
+	if len(req.GetIds()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "ids are required")
+	}
+
+	if len(req.GetIds()) > maxMarkReadIDs {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d ids are allowed", maxMarkReadIDs)
+	}
+
+	for _, id := range req.GetIds() {
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid id")
+		}
+	}
+
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.notifications.MarkRead(ctx, userID, req.GetIds()); err != nil {
+		return nil, status.Error(codes.Internal, "failed to mark notifications as read")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// MarkAllRead marks every notification in the inbox of the calling user as read.
+func (s *serverAPI) MarkAllRead(
+	ctx context.Context,
+	req *notificationsv1.MarkAllReadRequest,
+) (*emptypb.Empty, error) {
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.notifications.MarkAllRead(ctx, userID); err != nil {
+		return nil, status.Error(codes.Internal, "failed to mark notifications as read")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// UnreadCount returns the number of the unread notifications of the calling user.
+func (s *serverAPI) UnreadCount(
+	ctx context.Context,
+	req *notificationsv1.UnreadCountRequest,
+) (*notificationsv1.UnreadCountResponse, error) {
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.notifications.UnreadCount(ctx, userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to count unread notifications")
+	}
+
+	return &notificationsv1.UnreadCountResponse{
+		Count: count,
+	}, nil
+}
+
+// SubscribeNotifications sends the new notifications of the calling user
+// as they arrive, until the client cancels the call.
+func (s *serverAPI) SubscribeNotifications(
+	req *notificationsv1.SubscribeNotificationsRequest,
+	stream notificationsv1.Notifications_SubscribeNotificationsServer,
+) error {
+	ctx := stream.Context()
+
+	userID, err := user(ctx)
+	if err != nil {
+		return err
+	}
+
+	var sendErr error
+	err = s.notifications.WatchNotifications(ctx, userID, func(notification models.Notification) error {
+		sendErr = stream.Send(&notificationsv1.SubscribeNotificationsResponse{
+			Notification: toNotification(notification),
+		})
+
+		return sendErr
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	if err != nil {
+		return status.Error(codes.Internal, "failed to watch notifications")
+	}
+
+	return nil
+}
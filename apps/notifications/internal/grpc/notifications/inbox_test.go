@@ -0,0 +1,338 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"notifications/internal/auth"
+	"notifications/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	notificationsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/notifications/v1"
+)
+
+// fakeInbox keeps the inbox in memory and pages it the way the postgres storage does.
+// The methods the tests do not call are left to the embedded interface.
+type fakeInbox struct {
+	Notifications
+
+	notifications []models.Notification
+	// limit is the limit of the last listing.
+	limit int
+}
+
+func (f *fakeInbox) ListNotifications(
+	_ context.Context,
+	userID int64,
+	cursor models.InboxCursor,
+	unreadOnly bool,
+	limit int,
+) ([]models.Notification, error) {
+	f.limit = limit
+
+	inbox := make([]models.Notification, 0, len(f.notifications))
+	for _, notification := range f.notifications {
+		if notification.UserID != userID || unreadOnly && !notification.ReadAt.IsZero() {
+			continue
+		}
+
+		// (created_at, id) < (cursor.CreatedAt, cursor.ID)
+		if !cursor.CreatedAt.IsZero() && (notification.CreatedAt.After(cursor.CreatedAt) ||
+			notification.CreatedAt.Equal(cursor.CreatedAt) && notification.ID >= cursor.ID) {
+			continue
+		}
+
+		inbox = append(inbox, notification)
+	}
+
+	sort.Slice(inbox, func(i, j int) bool {
+		if !inbox[i].CreatedAt.Equal(inbox[j].CreatedAt) {
+			return inbox[i].CreatedAt.After(inbox[j].CreatedAt)
+		}
+		return inbox[i].ID > inbox[j].ID
+	})
+
+	return inbox[:min(limit, len(inbox))], nil
+}
+
+func (f *fakeInbox) MarkAllRead(_ context.Context, userID int64) error {
+	for i := range f.notifications {
+		if f.notifications[i].UserID == userID && f.notifications[i].ReadAt.IsZero() {
+			f.notifications[i].ReadAt = time.Now().UTC()
+		}
+	}
+
+	return nil
+}
+
+// WatchNotifications sends the inbox of the user and waits for ctx to be done.
+func (f *fakeInbox) WatchNotifications(
+	ctx context.Context,
+	userID int64,
+	send func(models.Notification) error,
+) error {
+	for _, notification := range f.notifications {
+		if notification.UserID != userID {
+			continue
+		}
+
+		if err := send(notification); err != nil {
+			return err
+		}
+	}
+
+	<-ctx.Done()
+
+	return nil
+}
+
+// fakeSubscription records the notifications sent to the subscriber.
+type fakeSubscription struct {
+	notificationsv1.Notifications_SubscribeNotificationsServer
+
+	ctx  context.Context
+	sent []*notificationsv1.Notification
+	// sendErr is returned by Send, as when the client has gone.
+	sendErr error
+}
+
+func (f *fakeSubscription) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeSubscription) Send(res *notificationsv1.SubscribeNotificationsResponse) error {
+	if f.sendErr != nil {
+		return f.sendErr
+	}
+
+	f.sent = append(f.sent, res.GetNotification())
+
+	return nil
+}
+
+func userContext(userID int64) context.Context {
+	return auth.WithUser(context.Background(), userID, auth.RoleStudent)
+}
+
+// newInbox returns the inbox of the user 1 with n notifications,
+// created a minute apart except the two newest ones, which are created at once,
+// and the notification of the user 2.
+func newInbox(n int) *fakeInbox {
+	createdAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	inbox := &fakeInbox{}
+	for i := range n {
+		if i < n-1 {
+			createdAt = createdAt.Add(time.Minute)
+		}
+
+		inbox.notifications = append(inbox.notifications, models.Notification{
+			ID:        fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1),
+			UserID:    1,
+			Kind:      models.KindDueReminder,
+			InInbox:   true,
+			CreatedAt: createdAt,
+		})
+	}
+
+	inbox.notifications = append(inbox.notifications, models.Notification{
+		ID:        "00000000-0000-0000-0000-100000000000",
+		UserID:    2,
+		Kind:      models.KindWelcome,
+		InInbox:   true,
+		CreatedAt: createdAt,
+	})
+
+	return inbox
+}
+
+// listAll lists the inbox of the user page by page
+// and returns the ids and the number of the requests made.
+func listAll(t *testing.T, s *serverAPI, ctx context.Context, pageSize int32) ([]string, int) {
+	t.Helper()
+
+	var (
+		ids   []string
+		token string
+	)
+	for requests := 1; ; requests++ {
+		res, err := s.ListNotifications(ctx, &notificationsv1.ListNotificationsRequest{
+			PageSize:  pageSize,
+			PageToken: token,
+		})
+		require.NoError(t, err)
+
+		for _, notification := range res.GetNotifications() {
+			ids = append(ids, notification.GetId())
+		}
+
+		if res.GetNextPageToken() == "" {
+			return ids, requests
+		}
+		require.NotEmpty(t, res.GetNotifications(), "empty page has no next page")
+
+		token = res.GetNextPageToken()
+	}
+}
+
+func TestListNotifications_Cursor(t *testing.T) {
+	t.Run("pages follow each other", func(t *testing.T) {
+		s := &serverAPI{notifications: newInbox(5)}
+
+		ids, requests := listAll(t, s, userContext(1), 2)
+		assert.Equal(t, []string{
+			"00000000-0000-0000-0000-000000000005",
+			"00000000-0000-0000-0000-000000000004",
+			"00000000-0000-0000-0000-000000000003",
+			"00000000-0000-0000-0000-000000000002",
+			"00000000-0000-0000-0000-000000000001",
+		}, ids, "notifications created at once are neither skipped nor repeated")
+		assert.Equal(t, 3, requests)
+	})
+
+	t.Run("last page is full", func(t *testing.T) {
+		s := &serverAPI{notifications: newInbox(4)}
+
+		ids, requests := listAll(t, s, userContext(1), 2)
+		assert.Len(t, ids, 4)
+		assert.Equal(t, 3, requests, "full page is followed by the empty one")
+	})
+
+	t.Run("page is not full", func(t *testing.T) {
+		s := &serverAPI{notifications: newInbox(3)}
+
+		res, err := s.ListNotifications(userContext(1), &notificationsv1.ListNotificationsRequest{PageSize: 5})
+		require.NoError(t, err)
+		assert.Len(t, res.GetNotifications(), 3)
+		assert.Empty(t, res.GetNextPageToken())
+	})
+
+	t.Run("page size", func(t *testing.T) {
+		inbox := newInbox(1)
+		s := &serverAPI{notifications: inbox}
+
+		for _, tt := range []struct {
+			pageSize int32
+			limit    int
+		}{
+			{0, defaultPageSize},
+			{-1, defaultPageSize},
+			{7, 7},
+			{maxPageSize + 1, maxPageSize},
+		} {
+			_, err := s.ListNotifications(userContext(1), &notificationsv1.ListNotificationsRequest{
+				PageSize: tt.pageSize,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.limit, inbox.limit, tt.pageSize)
+		}
+	})
+
+	t.Run("invalid page token", func(t *testing.T) {
+		s := &serverAPI{notifications: newInbox(1)}
+
+		for _, token := range []string{
+			"not base64!",
+			toPageToken(models.InboxCursor{CreatedAt: time.Now(), ID: "not-uuid"}),
+			"MTIz",
+		} {
+			_, err := s.ListNotifications(userContext(1), &notificationsv1.ListNotificationsRequest{
+				PageToken: token,
+			})
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), token)
+		}
+	})
+
+	t.Run("only own inbox", func(t *testing.T) {
+		s := &serverAPI{notifications: newInbox(2)}
+
+		res, err := s.ListNotifications(userContext(2), &notificationsv1.ListNotificationsRequest{})
+		require.NoError(t, err)
+		require.Len(t, res.GetNotifications(), 1)
+		assert.Equal(t, "00000000-0000-0000-0000-100000000000", res.GetNotifications()[0].GetId())
+
+		_, err = s.ListNotifications(context.Background(), &notificationsv1.ListNotificationsRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestMarkAllRead(t *testing.T) {
+	inbox := newInbox(3)
+	s := &serverAPI{notifications: inbox}
+
+	_, err := s.MarkAllRead(context.Background(), &notificationsv1.MarkAllReadRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = s.MarkAllRead(userContext(1), &notificationsv1.MarkAllReadRequest{})
+	require.NoError(t, err)
+
+	res, err := s.ListNotifications(userContext(1), &notificationsv1.ListNotificationsRequest{UnreadOnly: true})
+	require.NoError(t, err)
+	assert.Empty(t, res.GetNotifications())
+
+	res, err = s.ListNotifications(userContext(1), &notificationsv1.ListNotificationsRequest{})
+	require.NoError(t, err)
+	require.Len(t, res.GetNotifications(), 3)
+	for _, notification := range res.GetNotifications() {
+		assert.NotNil(t, notification.GetReadAt())
+	}
+
+	res, err = s.ListNotifications(userContext(2), &notificationsv1.ListNotificationsRequest{UnreadOnly: true})
+	require.NoError(t, err)
+	assert.Len(t, res.GetNotifications(), 1, "inbox of another user is left unread")
+}
+
+func TestSubscribeNotifications(t *testing.T) {
+	t.Run("delivered until cancelled", func(t *testing.T) {
+		s := &serverAPI{notifications: newInbox(2)}
+
+		ctx, cancel := context.WithCancel(userContext(1))
+		stream := &fakeSubscription{ctx: ctx}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- s.SubscribeNotifications(&notificationsv1.SubscribeNotificationsRequest{}, stream)
+		}()
+
+		cancel()
+
+		select {
+		case err := <-done:
+			require.NoError(t, err, "cancelled subscription ends without the error")
+		case <-time.After(time.Second):
+			t.Fatal("subscription is not ended by the cancellation")
+		}
+
+		require.Len(t, stream.sent, 2)
+		assert.Equal(t, "00000000-0000-0000-0000-000000000001", stream.sent[0].GetId())
+		assert.Equal(t, notificationsv1.NotificationKind_NOTIFICATION_KIND_DUE_REMINDER, stream.sent[0].GetKind())
+	})
+
+	t.Run("client has gone", func(t *testing.T) {
+		s := &serverAPI{notifications: newInbox(2)}
+
+		sendErr := errors.New("transport is closing")
+		stream := &fakeSubscription{ctx: userContext(1), sendErr: sendErr}
+
+		err := s.SubscribeNotifications(&notificationsv1.SubscribeNotificationsRequest{}, stream)
+		assert.ErrorIs(t, err, sendErr)
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		s := &serverAPI{notifications: newInbox(1)}
+
+		err := s.SubscribeNotifications(
+			&notificationsv1.SubscribeNotificationsRequest{},
+			&fakeSubscription{ctx: context.Background()},
+		)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
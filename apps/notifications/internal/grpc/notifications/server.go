@@ -0,0 +1,184 @@
+package notifications
+
+import (
+	"context"
+
+	"notifications/internal/auth"
+	"notifications/internal/domain/models"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	notificationsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/notifications/v1"
+)
+
+type Notifications interface {
+	Preferences(
+		ctx context.Context,
+		userID int64,
+	) (models.Preferences, error)
+	UpdatePreferences(
+		ctx context.Context,
+		preferences models.Preferences,
+	) (models.Preferences, error)
+	ListNotifications(
+		ctx context.Context,
+		userID int64,
+		cursor models.InboxCursor,
+		unreadOnly bool,
+		limit int,
+	) ([]models.Notification, error)
+	MarkRead(
+		ctx context.Context,
+		userID int64,
+		ids []string,
+	) error
+	MarkAllRead(
+		ctx context.Context,
+		userID int64,
+	) error
+	UnreadCount(
+		ctx context.Context,
+		userID int64,
+	) (int64, error)
+	WatchNotifications(
+		ctx context.Context,
+		userID int64,
+		send func(models.Notification) error,
+	) error
+}
+
+type Webhooks interface {
+	CreateWebhook(
+		ctx context.Context,
+		subscription models.WebhookSubscription,
+	) (models.WebhookSubscription, error)
+	Webhooks(
+		ctx context.Context,
+		appID int32,
+	) ([]models.WebhookSubscription, error)
+	DeleteWebhook(
+		ctx context.Context,
+		appID int32,
+		id string,
+	) error
+	DeadDeliveries(
+		ctx context.Context,
+		appID int32,
+		webhookID string,
+		limit int,
+		offset int,
+	) ([]models.WebhookDelivery, error)
+	RedeliverWebhook(
+		ctx context.Context,
+		appID int32,
+		webhookID string,
+		deliveryID string,
+	) error
+}
+
+type serverAPI struct {
+	notificationsv1.UnimplementedNotificationsServer
+	notifications Notifications
+	webhooks      Webhooks
+}
+
+func Register(gRPC *grpc.Server, notifications Notifications, webhooks Webhooks) {
+	notificationsv1.RegisterNotificationsServer(gRPC, &serverAPI{
+		notifications: notifications,
+		webhooks:      webhooks,
+	})
+}
+
+// GetPreferences returns the channel preferences of the calling user.
+func (s *serverAPI) GetPreferences(
+	ctx context.Context,
+	req *notificationsv1.GetPreferencesRequest,
+) (*notificationsv1.GetPreferencesResponse, error) {
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences, err := s.notifications.Preferences(ctx, userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get preferences")
+	}
+
+	return &notificationsv1.GetPreferencesResponse{
+		Preferences: toPreferences(preferences),
+	}, nil
+}
+
+// UpdatePreferences replaces the channel preferences of the calling user.
+func (s *serverAPI) UpdatePreferences(
+	ctx context.Context,
+	req *notificationsv1.UpdatePreferencesRequest,
+) (*notificationsv1.UpdatePreferencesResponse, error) {
+	if req.GetPreferences() == nil {
+		return nil, status.Error(codes.InvalidArgument, "preferences are required")
+	}
+
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences, err := fromPreferences(req.GetPreferences())
+	if err != nil {
+		return nil, err
+	}
+
+	preferences.UserID = userID
+
+	preferences, err = s.notifications.UpdatePreferences(ctx, preferences)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to update preferences")
+	}
+
+	return &notificationsv1.UpdatePreferencesResponse{
+		Preferences: toPreferences(preferences),
+	}, nil
+}
+
+// user returns the id of the caller.
+func user(ctx context.Context) (int64, error) {
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return 0, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+
+	return userID, nil
+}
+
+// admin returns the id of the caller.
+// If the caller is not an admin, it returns an error.
+func admin(ctx context.Context) (int64, error) {
+	userID, err := user(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if auth.GetUserRole(ctx) != auth.RoleAdmin {
+		return 0, status.Error(codes.PermissionDenied, "admin role is required")
+	}
+
+	return userID, nil
+}
+
+// appAdmin returns the id of the caller and of the app the caller has signed in to.
+// If the caller is not an admin, it returns an error.
+func appAdmin(ctx context.Context) (int64, int32, error) {
+	userID, err := admin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	appID := auth.GetAppID(ctx)
+	if appID <= 0 {
+		return 0, 0, status.Error(codes.PermissionDenied, "app is required")
+	}
+
+	return userID, appID, nil
+}
@@ -0,0 +1,217 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"notifications/internal/domain/models"
+	"notifications/internal/services/webhook"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	notificationsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/notifications/v1"
+)
+
+// maxEventTypes limits the number of the event types of the webhook.
+const maxEventTypes = 50
+
+// CreateWebhook subscribes the url of the app of the caller to the events of the types.
+// The secret of the signature is returned only once.
+func (s *serverAPI) CreateWebhook(
+	ctx context.Context,
+	req *notificationsv1.CreateWebhookRequest,
+) (*notificationsv1.CreateWebhookResponse, error) {
+	if req.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	if !validWebhookURL(req.GetUrl()) {
+		return nil, status.Error(codes.InvalidArgument, "invalid url")
+	}
+
+	if len(req.GetEventTypes()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "event_types are required")
+	}
+
+	if len(req.GetEventTypes()) > maxEventTypes {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d event_types are allowed", maxEventTypes)
+	}
+
+	userID, appID, err := appAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GetAppId() != appID {
+		return nil, status.Error(codes.PermissionDenied, "webhooks of another app cannot be managed")
+	}
+
+	subscription, err := s.webhooks.CreateWebhook(ctx, models.WebhookSubscription{
+		AppID:      req.GetAppId(),
+		URL:        req.GetUrl(),
+		EventTypes: req.GetEventTypes(),
+		CreatedBy:  userID,
+	})
+	if err != nil {
+		if errors.Is(err, webhook.ErrUnknownEventType) || errors.Is(err, webhook.ErrEventTypesRequired) {
+			return nil, status.Error(codes.InvalidArgument, "invalid event_types")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to create webhook")
+	}
+
+	return &notificationsv1.CreateWebhookResponse{
+		Webhook: toWebhook(subscription),
+		Secret:  subscription.Secret,
+	}, nil
+}
+
+// ListWebhooks returns the webhooks of the app of the caller.
+func (s *serverAPI) ListWebhooks(
+	ctx context.Context,
+	req *notificationsv1.ListWebhooksRequest,
+) (*notificationsv1.ListWebhooksResponse, error) {
+	if req.GetAppId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	}
+
+	_, appID, err := appAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GetAppId() != appID {
+		return nil, status.Error(codes.PermissionDenied, "webhooks of another app cannot be managed")
+	}
+
+	subscriptions, err := s.webhooks.Webhooks(ctx, appID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list webhooks")
+	}
+
+	res := &notificationsv1.ListWebhooksResponse{
+		Webhooks: make([]*notificationsv1.Webhook, 0, len(subscriptions)),
+	}
+	for _, subscription := range subscriptions {
+		res.Webhooks = append(res.Webhooks, toWebhook(subscription))
+	}
+
+	return res, nil
+}
+
+// DeleteWebhook deletes the webhook with its deliveries.
+func (s *serverAPI) DeleteWebhook(
+	ctx context.Context,
+	req *notificationsv1.DeleteWebhookRequest,
+) (*emptypb.Empty, error) {
+	if _, err := uuid.Parse(req.GetId()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid id")
+	}
+
+	_, appID, err := appAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.webhooks.DeleteWebhook(ctx, appID, req.GetId()); err != nil {
+		if errors.Is(err, webhook.ErrWebhookNotFound) {
+			return nil, status.Error(codes.NotFound, "webhook not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to delete webhook")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ListDeadWebhookDeliveries returns the page of the dead-letter list of the webhook.
+func (s *serverAPI) ListDeadWebhookDeliveries(
+	ctx context.Context,
+	req *notificationsv1.ListDeadWebhookDeliveriesRequest,
+) (*notificationsv1.ListDeadWebhookDeliveriesResponse, error) {
+	if _, err := uuid.Parse(req.GetWebhookId()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid webhook_id")
+	}
+
+	limit := int(req.GetPageSize())
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	var offset int
+	if req.GetPageToken() != "" {
+		var err error
+
+		offset, err = strconv.Atoi(req.GetPageToken())
+		if err != nil || offset < 0 {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+	}
+
+	_, appID, err := appAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.webhooks.DeadDeliveries(ctx, appID, req.GetWebhookId(), limit, offset)
+	if err != nil {
+		if errors.Is(err, webhook.ErrWebhookNotFound) {
+			return nil, status.Error(codes.NotFound, "webhook not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to list dead deliveries")
+	}
+
+	res := &notificationsv1.ListDeadWebhookDeliveriesResponse{
+		Deliveries: make([]*notificationsv1.WebhookDelivery, 0, len(deliveries)),
+	}
+	for _, delivery := range deliveries {
+		res.Deliveries = append(res.Deliveries, toWebhookDelivery(delivery))
+	}
+
+	if len(deliveries) == limit {
+		res.NextPageToken = strconv.Itoa(offset + limit)
+	}
+
+	return res, nil
+}
+
+// RedeliverWebhook posts the dead delivery of the webhook again.
+func (s *serverAPI) RedeliverWebhook(
+	ctx context.Context,
+	req *notificationsv1.RedeliverWebhookRequest,
+) (*emptypb.Empty, error) {
+	if _, err := uuid.Parse(req.GetWebhookId()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid webhook_id")
+	}
+
+	if _, err := uuid.Parse(req.GetDeliveryId()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid delivery_id")
+	}
+
+	_, appID, err := appAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.webhooks.RedeliverWebhook(ctx, appID, req.GetWebhookId(), req.GetDeliveryId()); err != nil {
+		if errors.Is(err, webhook.ErrWebhookNotFound) {
+			return nil, status.Error(codes.NotFound, "webhook not found")
+		}
+
+		if errors.Is(err, webhook.ErrDeliveryNotFound) {
+			return nil, status.Error(codes.NotFound, "dead delivery not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to redeliver webhook")
+	}
+
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,108 @@
+package notifications
+
+import (
+	"context"
+	"testing"
+
+	"notifications/internal/auth"
+	"notifications/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	notificationsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/notifications/v1"
+)
+
+// fakeWebhooks records the app of the last call.
+type fakeWebhooks struct {
+	appID int32
+}
+
+func (f *fakeWebhooks) CreateWebhook(
+	_ context.Context,
+	subscription models.WebhookSubscription,
+) (models.WebhookSubscription, error) {
+	f.appID = subscription.AppID
+	subscription.ID = "webhook"
+	return subscription, nil
+}
+
+func (f *fakeWebhooks) Webhooks(_ context.Context, appID int32) ([]models.WebhookSubscription, error) {
+	f.appID = appID
+	return nil, nil
+}
+
+func (f *fakeWebhooks) DeleteWebhook(_ context.Context, appID int32, _ string) error {
+	f.appID = appID
+	return nil
+}
+
+func (f *fakeWebhooks) DeadDeliveries(
+	_ context.Context,
+	appID int32,
+	_ string,
+	_ int,
+	_ int,
+) ([]models.WebhookDelivery, error) {
+	f.appID = appID
+	return nil, nil
+}
+
+func (f *fakeWebhooks) RedeliverWebhook(_ context.Context, appID int32, _ string, _ string) error {
+	f.appID = appID
+	return nil
+}
+
+func adminContext(appID int32) context.Context {
+	return auth.WithApp(auth.WithUser(context.Background(), 1, auth.RoleAdmin), appID)
+}
+
+func TestCreateWebhook_App(t *testing.T) {
+	req := &notificationsv1.CreateWebhookRequest{
+		AppId:      1,
+		Url:        "https://example.com/hooks",
+		EventTypes: []string{"tasks.events.v1.AssignmentPublished"},
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		code codes.Code
+	}{
+		{name: "own app", ctx: adminContext(1), code: codes.OK},
+		{name: "another app", ctx: adminContext(2), code: codes.PermissionDenied},
+		{name: "no app", ctx: auth.WithUser(context.Background(), 1, auth.RoleAdmin), code: codes.PermissionDenied},
+		{name: "not admin", ctx: auth.WithApp(auth.WithUser(context.Background(), 1, auth.RoleTeacher), 1), code: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webhooks := &fakeWebhooks{}
+			s := &serverAPI{webhooks: webhooks}
+
+			_, err := s.CreateWebhook(tt.ctx, req)
+			require.Equal(t, tt.code, status.Code(err))
+
+			if tt.code == codes.OK {
+				assert.Equal(t, int32(1), webhooks.appID)
+			} else {
+				assert.Zero(t, webhooks.appID)
+			}
+		})
+	}
+}
+
+func TestListWebhooks_App(t *testing.T) {
+	webhooks := &fakeWebhooks{}
+	s := &serverAPI{webhooks: webhooks}
+
+	_, err := s.ListWebhooks(adminContext(1), &notificationsv1.ListWebhooksRequest{AppId: 2})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Zero(t, webhooks.appID)
+
+	_, err = s.ListWebhooks(adminContext(1), &notificationsv1.ListWebhooksRequest{AppId: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), webhooks.appID)
+}
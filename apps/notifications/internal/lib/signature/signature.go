@@ -0,0 +1,61 @@
+// Package signature signs the webhook deliveries with HMAC-SHA256,
+// so the receiver can check they are sent by the platform and not replayed.
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// secretSize is the size of the random secret in bytes.
+	secretSize = 32
+	prefix     = "sha256="
+)
+
+// NewSecret generates the random secret of the webhook, like "whsec_3f9a...".
+func NewSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// Sign returns the signature of the body sent at the time, like "sha256=9c1e...".
+//
+// The signed message is the unix timestamp and the body joined with the dot,
+// the receiver rejects the deliveries with the old timestamps to prevent replays.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return prefix + hex.EncodeToString(mac(secret, timestamp, body))
+}
+
+// Verify reports whether the signature is the one of the body sent at the time.
+func Verify(secret string, timestamp time.Time, body []byte, signature string) bool {
+	hexSum, ok := strings.CutPrefix(signature, prefix)
+	if !ok {
+		return false
+	}
+
+	sum, err := hex.DecodeString(hexSum)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(sum, mac(secret, timestamp, body))
+}
+
+func mac(secret string, timestamp time.Time, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	h.Write([]byte("."))
+	h.Write(body)
+
+	return h.Sum(nil)
+}
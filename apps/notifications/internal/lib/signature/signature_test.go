@@ -0,0 +1,38 @@
+package signature
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+
+	other, err := NewSecret()
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"1"}`)
+
+	sig := Sign(secret, now, body)
+
+	assert.Regexp(t, `^sha256=[0-9a-f]{64}$`, sig)
+	assert.True(t, Verify(secret, now, body, sig))
+	assert.False(t, Verify(other, now, body, sig), "signature must depend on the secret")
+	assert.False(t, Verify(secret, now.Add(time.Second), body, sig), "signature must depend on the timestamp")
+	assert.False(t, Verify(secret, now, []byte(`{"id":"2"}`), sig), "signature must depend on the body")
+	assert.False(t, Verify(secret, now, body, sig[len("sha256="):]), "signature must have the prefix")
+}
+
+func TestSignKnownValue(t *testing.T) {
+	// echo -n '1700000000.hello' | openssl dgst -sha256 -hmac secret
+	assert.Equal(
+		t,
+		"sha256=47b1df0ab12338b2685470b0d2b37033add7c3b2bc8172f313e77413f1bb78c8",
+		Sign("secret", time.Unix(1700000000, 0), []byte("hello")),
+	)
+}
@@ -0,0 +1,121 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"notifications/internal/domain/models"
+)
+
+type Config struct {
+	Host string
+	Port int
+	// From is the address the notifications are sent from.
+	From string
+}
+
+// Sender sends the notifications by email through the SMTP server.
+// Authentication and TLS are not used: the server is expected to be the relay
+// in the private network, or the local stand-in like Mailpit during development.
+type Sender struct {
+	host   string
+	addr   string
+	from   string
+	dialer net.Dialer
+	now    func() time.Time
+}
+
+// New creates a new Sender instance.
+// That used to send the emails through the SMTP server given in the config.
+func New(cfg Config) *Sender {
+	return &Sender{
+		host: cfg.Host,
+		addr: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		from: cfg.From,
+		now:  time.Now,
+	}
+}
+
+// Send sends the notification to the email address.
+func (s *Sender) Send(
+	ctx context.Context,
+	recipient string,
+	notification models.Notification,
+) error {
+	const op = "sender.email.Send"
+
+	conn, err := s.dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer c.Close()
+
+	if err := c.Mail(s.from); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := c.Rcpt(recipient); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if _, err := w.Write(message(s.from, recipient, notification, s.now())); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := c.Quit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// message builds the plain text email with the notification.
+// The subject is encoded as RFC 2047 words and the body is quoted-printable,
+// so non-ASCII titles and bodies survive 7-bit relays.
+func message(from string, to string, notification models.Notification, date time.Time) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", notification.Title))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@notifications>\r\n", notification.ID)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	buf.WriteString("\r\n")
+
+	w := quotedprintable.NewWriter(&buf)
+	w.Write([]byte(notification.Body))
+	w.Close()
+
+	return buf.Bytes()
+}
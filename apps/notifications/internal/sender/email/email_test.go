@@ -0,0 +1,49 @@
+package email
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"testing"
+	"time"
+
+	"notifications/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessage(t *testing.T) {
+	date := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	raw := message(
+		"no-reply@example.com",
+		"student@example.com",
+		models.Notification{
+			ID:    "0b9e5d2c-6f0e-4d4f-9d6c-2f1f1a8f2c11",
+			Title: "Срок сдачи: Эссе",
+			Body:  "Задание «Эссе» нужно сдать до 20.10.2026 12:00 UTC.",
+		},
+		date,
+	)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Срок сдачи: Эссе", subject)
+
+	assert.Equal(t, "student@example.com", msg.Header.Get("To"))
+	assert.Equal(t, "<0b9e5d2c-6f0e-4d4f-9d6c-2f1f1a8f2c11@notifications>", msg.Header.Get("Message-Id"))
+
+	sent, err := msg.Header.Date()
+	require.NoError(t, err)
+	assert.True(t, date.Equal(sent))
+
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	require.NoError(t, err)
+	assert.Equal(t, "Задание «Эссе» нужно сдать до 20.10.2026 12:00 UTC.", string(body))
+}
@@ -0,0 +1,155 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"notifications/internal/domain/models"
+)
+
+// ErrForbiddenDestination is returned when the url resolves to the address
+// of the private network, so the webhooks cannot reach the internal services.
+var ErrForbiddenDestination = errors.New("forbidden webhook destination")
+
+// Sender posts the notifications as JSON to the webhook urls of the users
+// and the events to the webhooks of the integrations.
+type Sender struct {
+	client *http.Client
+}
+
+// New creates a new Sender instance.
+// That used to post the notifications, waiting for the response up to the timeout.
+//
+// The address is checked when the connection is dialed, after the host is resolved,
+// so neither the url, the redirect nor the DNS record changed later can point
+// the webhook to the loopback, private or link-local address,
+// unless allowPrivate is set, e.g. in the local environment.
+func New(timeout time.Duration, allowPrivate bool) *Sender {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = checkDestination
+	}
+
+	return &Sender{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: timeout,
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+// checkDestination refuses to connect to the address which is not public.
+func checkDestination(_ string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenDestination, address)
+	}
+
+	if !public(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenDestination, addrPort.Addr())
+	}
+
+	return nil
+}
+
+// public reports whether the address is routed on the internet.
+func public(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// sharedAddressSpace is the range of the carrier-grade NAT, RFC 6598.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+type payload struct {
+	ID           string    `json:"id"`
+	Kind         string    `json:"kind"`
+	Title        string    `json:"title"`
+	Body         string    `json:"body"`
+	AssignmentID string    `json:"assignment_id,omitempty"`
+	SubmissionID string    `json:"submission_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Send posts the notification to the url.
+// Any response status except 2xx is the failure.
+func (s *Sender) Send(
+	ctx context.Context,
+	recipient string,
+	notification models.Notification,
+) error {
+	const op = "sender.webhook.Send"
+
+	body, err := json.Marshal(payload{
+		ID:           notification.ID,
+		Kind:         string(notification.Kind),
+		Title:        notification.Title,
+		Body:         notification.Body,
+		AssignmentID: notification.AssignmentID,
+		SubmissionID: notification.SubmissionID,
+		CreatedAt:    notification.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	header := make(http.Header)
+	header.Set("X-Notification-Id", notification.ID)
+
+	if _, err := s.Post(ctx, recipient, body, header); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Post posts the JSON body with the headers to the url.
+// It returns the status code of the response,
+// any status except 2xx is the failure.
+func (s *Sender) Post(
+	ctx context.Context,
+	url string,
+	body []byte,
+	header http.Header,
+) (int, error) {
+	const op = "sender.webhook.Post"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("%s: unexpected status %s", op, resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublic(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.0.0.5", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.want, public(netip.MustParseAddr(tt.addr)))
+		})
+	}
+}
+
+func TestPost_Destination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	t.Run("private address is refused", func(t *testing.T) {
+		_, err := New(time.Second, false).Post(ctx, server.URL, []byte("{}"), nil)
+		assert.ErrorContains(t, err, ErrForbiddenDestination.Error())
+	})
+
+	t.Run("host resolved to private address is refused", func(t *testing.T) {
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		_, err = New(time.Second, false).Post(ctx, "http://localhost:"+u.Port(), []byte("{}"), nil)
+		assert.ErrorContains(t, err, ErrForbiddenDestination.Error())
+	})
+
+	t.Run("private address is allowed locally", func(t *testing.T) {
+		code, err := New(time.Second, true).Post(ctx, server.URL, []byte("{}"), nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, code)
+	})
+}
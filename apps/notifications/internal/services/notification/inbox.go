@@ -0,0 +1,241 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"notifications/internal/domain/models"
+)
+
+const (
+	// watchBatchSize is the number of notifications fetched for the subscription at once.
+	watchBatchSize = 100
+	// watchPollInterval is how often the subscription checks the inbox
+	// for the notifications created by the other instances of the service.
+	watchPollInterval = 5 * time.Second
+	// watchOverlap is how far back the subscription checks the inbox again,
+	// so the notification committed after the newer one is not missed.
+	watchOverlap = 5 * time.Second
+)
+
+// ListNotifications returns up to limit notifications of the inbox of the user
+// following the cursor, the newest first.
+func (s *NotificationService) ListNotifications(
+	ctx context.Context,
+	userID int64,
+	cursor models.InboxCursor,
+	unreadOnly bool,
+	limit int,
+) ([]models.Notification, error) {
+	const op = "services.notification.ListNotifications"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Debug("listing notifications")
+
+	notifications, err := s.notificationProvider.ListNotifications(ctx, userID, cursor, unreadOnly, limit)
+	if err != nil {
+		log.Error("failed to list notifications", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return notifications, nil
+}
+
+// MarkRead marks the notifications of the user as read.
+// The notifications of other users and the ones already read are skipped.
+func (s *NotificationService) MarkRead(
+	ctx context.Context,
+	userID int64,
+	ids []string,
+) error {
+	const op = "services.notification.MarkRead"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	if err := s.notificationSaver.MarkRead(ctx, userID, ids, time.Now().UTC()); err != nil {
+		log.Error("failed to mark notifications as read", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// MarkAllRead marks every notification in the inbox of the user as read.
+func (s *NotificationService) MarkAllRead(
+	ctx context.Context,
+	userID int64,
+) error {
+	const op = "services.notification.MarkAllRead"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	if err := s.notificationSaver.MarkAllRead(ctx, userID, time.Now().UTC()); err != nil {
+		log.Error("failed to mark all notifications as read", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UnreadCount returns the number of the notifications in the inbox
+// of the user which are not read yet.
+func (s *NotificationService) UnreadCount(
+	ctx context.Context,
+	userID int64,
+) (int64, error) {
+	const op = "services.notification.UnreadCount"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	count, err := s.notificationProvider.UnreadCount(ctx, userID)
+	if err != nil {
+		log.Error("failed to count unread notifications", slog.Any("error", err))
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// WatchNotifications calls send for every notification which arrives
+// in the inbox of the user until ctx is done or send fails.
+//
+// The subscription is woken as soon as the notification is created
+// by this instance of the service, and checks the inbox periodically
+// for the ones created by the others. The notifications created shortly
+// before the subscription may be sent too, so the one created between
+// the listing of the inbox and the subscription is not missed.
+func (s *NotificationService) WatchNotifications(
+	ctx context.Context,
+	userID int64,
+	send func(models.Notification) error,
+) error {
+	const op = "services.notification.WatchNotifications"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	wake, unsubscribe := s.watchers.subscribe(userID)
+	defer unsubscribe()
+
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	since := time.Now().UTC().Add(-watchOverlap)
+	// sent are the creation times of the notifications sent since since,
+	// which are fetched again as the checks of the inbox overlap.
+	sent := make(map[string]time.Time)
+
+	for {
+		notifications, err := s.notificationProvider.NotificationsSince(ctx, userID, since, watchBatchSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			log.Error("failed to fetch notifications", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		latest := since
+		for _, notification := range notifications {
+			latest = notification.CreatedAt
+
+			if _, ok := sent[notification.ID]; ok {
+				continue
+			}
+
+			if err := send(notification); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+
+			sent[notification.ID] = notification.CreatedAt
+		}
+
+		if len(notifications) == watchBatchSize && latest.After(since) {
+			since = latest
+
+			continue
+		}
+
+		if latest.Add(-watchOverlap).After(since) {
+			since = latest.Add(-watchOverlap)
+		}
+		for id, createdAt := range sent {
+			if createdAt.Before(since) {
+				delete(sent, id)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchers wakes the subscriptions of the users when their inbox changes.
+type watchers struct {
+	mu   sync.Mutex
+	subs map[int64]map[chan struct{}]struct{}
+}
+
+func newWatchers() *watchers {
+	return &watchers{subs: make(map[int64]map[chan struct{}]struct{})}
+}
+
+// subscribe returns the channel which receives the value when the inbox
+// of the user changes, and the function which removes the subscription.
+// The changes made while the previous one is not received yet are merged.
+func (w *watchers) subscribe(userID int64) (<-chan struct{}, func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	if w.subs[userID] == nil {
+		w.subs[userID] = make(map[chan struct{}]struct{})
+	}
+	w.subs[userID][ch] = struct{}{}
+
+	return ch, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		delete(w.subs[userID], ch)
+		if len(w.subs[userID]) == 0 {
+			delete(w.subs, userID)
+		}
+	}
+}
+
+// wake wakes every subscription of the user without blocking.
+func (w *watchers) wake(userID int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for ch := range w.subs[userID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
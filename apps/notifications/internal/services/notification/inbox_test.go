@@ -0,0 +1,120 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"notifications/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (f *fakeStorage) NotificationsSince(
+	_ context.Context,
+	userID int64,
+	since time.Time,
+	limit int,
+) ([]models.Notification, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var inbox []models.Notification
+	for _, notification := range f.notifications {
+		if notification.UserID == userID && notification.InInbox && !notification.CreatedAt.Before(since) {
+			inbox = append(inbox, notification)
+		}
+	}
+
+	sort.Slice(inbox, func(i, j int) bool {
+		return inbox[i].CreatedAt.Before(inbox[j].CreatedAt)
+	})
+
+	return inbox[:min(limit, len(inbox))], nil
+}
+
+// watch starts watching the inbox of the user and returns the channels
+// which receive the notifications sent and the result of the watching.
+func watch(
+	ctx context.Context,
+	s *NotificationService,
+	userID int64,
+) (<-chan models.Notification, <-chan error) {
+	sent := make(chan models.Notification, 10)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- s.WatchNotifications(ctx, userID, func(notification models.Notification) error {
+			sent <- notification
+			return nil
+		})
+	}()
+
+	return sent, done
+}
+
+func TestWatchNotifications(t *testing.T) {
+	t.Run("delivered once until cancelled", func(t *testing.T) {
+		s, _ := newTestService()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sent, done := watch(ctx, s, 1)
+
+		require.NoError(t, s.NotifyWelcome(ctx, models.User{ID: 2}))
+		require.NoError(t, s.NotifyWelcome(ctx, models.User{ID: 1, FirstName: "Ann"}))
+
+		select {
+		case notification := <-sent:
+			assert.Equal(t, int64(1), notification.UserID, "inbox of another user is not sent")
+			assert.Equal(t, models.KindWelcome, notification.Kind)
+		case <-time.After(time.Second):
+			t.Fatal("notification is not sent to the subscription")
+		}
+
+		// the inbox is checked again from before the first notification,
+		// which has to be skipped as already sent
+		require.NoError(t, s.NotifyFeedback(ctx, models.Feedback{
+			EventID:         "event",
+			StudentID:       1,
+			AssignmentTitle: "Essay",
+		}))
+
+		select {
+		case notification := <-sent:
+			assert.Equal(t, models.KindFeedbackPublished, notification.Kind)
+		case <-time.After(time.Second):
+			t.Fatal("notification is not sent to the subscription")
+		}
+
+		cancel()
+
+		select {
+		case err := <-done:
+			require.NoError(t, err, "cancelled subscription ends without the error")
+		case <-time.After(time.Second):
+			t.Fatal("subscription is not ended by the cancellation")
+		}
+
+		assert.Empty(t, sent, "notification is sent once")
+		assert.Empty(t, s.watchers.subs, "subscription is removed")
+	})
+
+	t.Run("send fails", func(t *testing.T) {
+		s, _ := newTestService()
+		ctx := context.Background()
+
+		require.NoError(t, s.NotifyWelcome(ctx, models.User{ID: 1}))
+
+		sendErr := errors.New("transport is closing")
+		err := s.WatchNotifications(ctx, 1, func(models.Notification) error {
+			return sendErr
+		})
+		assert.ErrorIs(t, err, sendErr)
+		assert.Empty(t, s.watchers.subs, "subscription is removed")
+	})
+}
@@ -0,0 +1,638 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"notifications/internal/domain/models"
+	"notifications/internal/storage"
+)
+
+const (
+	// reminderBatchSize is the number of reminders claimed by the worker at once.
+	reminderBatchSize = 100
+	// reminderLease is the time the claimed reminder is held by the worker.
+	reminderLease = 5 * time.Minute
+
+	// deliveryBatchSize is the number of deliveries claimed by the worker at once.
+	deliveryBatchSize = 50
+	// deliveryLease is the time the claimed delivery is held by the worker.
+	deliveryLease = 5 * time.Minute
+	// sendTimeout limits the time of sending the single notification.
+	sendTimeout = 30 * time.Second
+	// maxDeliveryAttempts is the number of attempts after which the delivery fails.
+	maxDeliveryAttempts = 8
+	// minRetryDelay is the delay after the first failed attempt,
+	// which is doubled on every next attempt.
+	minRetryDelay = 30 * time.Second
+
+	dateFormat = "02 Jan 2006 15:04 MST"
+)
+
+// reminderLeads are how long before the due date the reminders are sent.
+var reminderLeads = []time.Duration{24 * time.Hour, time.Hour}
+
+type NotificationService struct {
+	log                  *slog.Logger
+	notificationSaver    NotificationSaver
+	notificationProvider NotificationProvider
+	preferenceSaver      PreferenceSaver
+	preferenceProvider   PreferenceProvider
+	reminderSaver        ReminderSaver
+	senders              map[models.Channel]Sender
+	watchers             *watchers
+}
+
+type NotificationSaver interface {
+	SaveNotification(
+		ctx context.Context,
+		notification models.Notification,
+		deliveries []models.Delivery,
+	) error
+	ClaimDeliveries(
+		ctx context.Context,
+		now time.Time,
+		lockedUntil time.Time,
+		limit int,
+	) ([]models.Delivery, error)
+	MarkDelivered(
+		ctx context.Context,
+		notificationID string,
+		channel models.Channel,
+		sentAt time.Time,
+	) error
+	MarkDeliveryFailed(
+		ctx context.Context,
+		notificationID string,
+		channel models.Channel,
+		nextAttemptAt time.Time,
+		reason string,
+		final bool,
+	) error
+	MarkRead(ctx context.Context, userID int64, ids []string, readAt time.Time) error
+	MarkAllRead(ctx context.Context, userID int64, readAt time.Time) error
+}
+
+type NotificationProvider interface {
+	ListNotifications(
+		ctx context.Context,
+		userID int64,
+		cursor models.InboxCursor,
+		unreadOnly bool,
+		limit int,
+	) ([]models.Notification, error)
+	NotificationsSince(
+		ctx context.Context,
+		userID int64,
+		since time.Time,
+		limit int,
+	) ([]models.Notification, error)
+	UnreadCount(ctx context.Context, userID int64) (int64, error)
+}
+
+type PreferenceSaver interface {
+	SavePreferences(ctx context.Context, preferences models.Preferences) error
+}
+
+type PreferenceProvider interface {
+	Preferences(ctx context.Context, userID int64) (models.Preferences, error)
+}
+
+type ReminderSaver interface {
+	ScheduleReminders(ctx context.Context, reminders []models.Reminder) error
+	RescheduleReminders(ctx context.Context, reminders []models.Reminder, updatedAt time.Time) error
+	SuspendReminders(ctx context.Context, assignmentID string, suspended bool) error
+	CancelReminders(ctx context.Context, studentAssignmentID string, cancelledAt time.Time) error
+	ResumeReminders(ctx context.Context, studentAssignmentID string) error
+	ClaimReminders(
+		ctx context.Context,
+		now time.Time,
+		lockedUntil time.Time,
+		limit int,
+	) ([]models.Reminder, error)
+	MarkReminderSent(
+		ctx context.Context,
+		studentAssignmentID string,
+		lead time.Duration,
+		sentAt time.Time,
+	) error
+}
+
+// Sender sends the notification by the channel.
+type Sender interface {
+	// Send sends the notification to the recipient,
+	// which is the email address or the webhook url.
+	Send(ctx context.Context, recipient string, notification models.Notification) error
+}
+
+// New returns a new instance of NotificationService.
+func New(
+	log *slog.Logger,
+	notificationSaver NotificationSaver,
+	notificationProvider NotificationProvider,
+	preferenceSaver PreferenceSaver,
+	preferenceProvider PreferenceProvider,
+	reminderSaver ReminderSaver,
+	senders map[models.Channel]Sender,
+) *NotificationService {
+	return &NotificationService{
+		log:                  log,
+		notificationSaver:    notificationSaver,
+		notificationProvider: notificationProvider,
+		preferenceSaver:      preferenceSaver,
+		preferenceProvider:   preferenceProvider,
+		reminderSaver:        reminderSaver,
+		senders:              senders,
+		watchers:             newWatchers(),
+	}
+}
+
+// Preferences returns the channel preferences of the user,
+// or the defaults if the user has never changed them.
+func (s *NotificationService) Preferences(
+	ctx context.Context,
+	userID int64,
+) (models.Preferences, error) {
+	const op = "services.notification.Preferences"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Debug("fetching preferences")
+
+	preferences, err := s.preferenceProvider.Preferences(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrPreferencesNotFound) {
+			return models.DefaultPreferences(userID), nil
+		}
+
+		log.Error("failed to get preferences", slog.Any("error", err))
+
+		return models.Preferences{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return preferences, nil
+}
+
+// UpdatePreferences replaces the channel preferences of the user.
+// The new preferences apply to the notifications created after the update.
+func (s *NotificationService) UpdatePreferences(
+	ctx context.Context,
+	preferences models.Preferences,
+) (models.Preferences, error) {
+	const op = "services.notification.UpdatePreferences"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Debug("updating preferences")
+
+	preferences.UpdatedAt = time.Now().UTC()
+
+	if err := s.preferenceSaver.SavePreferences(ctx, preferences); err != nil {
+		log.Error("failed to save preferences", slog.Any("error", err))
+
+		return models.Preferences{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("preferences updated")
+
+	return preferences, nil
+}
+
+// ScheduleReminders schedules the due date reminders of the assignments
+// handed out to the students. The reminders which would have been sent
+// before the assignment was published are skipped.
+func (s *NotificationService) ScheduleReminders(
+	ctx context.Context,
+	assignments []models.StudentAssignment,
+	publishedAt time.Time,
+) error {
+	const op = "services.notification.ScheduleReminders"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	var reminders []models.Reminder
+	for _, assignment := range assignments {
+		for _, lead := range reminderLeads {
+			sendAt := assignment.DueDate.Add(-lead)
+			if !sendAt.After(publishedAt) {
+				continue
+			}
+
+			reminders = append(reminders, models.Reminder{
+				StudentAssignmentID: assignment.ID,
+				Lead:                lead,
+				AssignmentID:        assignment.AssignmentID,
+				StudentID:           assignment.StudentID,
+				Title:               assignment.Title,
+				DueDate:             assignment.DueDate,
+				SendAt:              sendAt,
+			})
+		}
+	}
+
+	if len(reminders) == 0 {
+		return nil
+	}
+
+	if err := s.reminderSaver.ScheduleReminders(ctx, reminders); err != nil {
+		log.Error("failed to schedule reminders", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Debug("reminders scheduled", slog.Int("count", len(reminders)))
+
+	return nil
+}
+
+// RescheduleReminders moves the reminders of the assignment to the due dates
+// of the students, which differ once the due date of the student is extended.
+// The reminders already sent are not sent again, and the missing reminders
+// are scheduled only if they were still ahead when the assignment was updated.
+func (s *NotificationService) RescheduleReminders(
+	ctx context.Context,
+	assignments []models.StudentAssignment,
+	updatedAt time.Time,
+) error {
+	const op = "services.notification.RescheduleReminders"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	reminders := make([]models.Reminder, 0, len(assignments)*len(reminderLeads))
+	for _, assignment := range assignments {
+		for _, lead := range reminderLeads {
+			reminders = append(reminders, models.Reminder{
+				StudentAssignmentID: assignment.ID,
+				Lead:                lead,
+				AssignmentID:        assignment.AssignmentID,
+				StudentID:           assignment.StudentID,
+				Title:               assignment.Title,
+				DueDate:             assignment.DueDate,
+				SendAt:              assignment.DueDate.Add(-lead),
+			})
+		}
+	}
+
+	if len(reminders) == 0 {
+		return nil
+	}
+
+	if err := s.reminderSaver.RescheduleReminders(ctx, reminders, updatedAt); err != nil {
+		log.Error("failed to reschedule reminders", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Debug("reminders rescheduled", slog.Int("count", len(reminders)))
+
+	return nil
+}
+
+// SuspendReminders stops sending the reminders of the assignment
+// while it is in the trash, or resumes it once the assignment is restored.
+func (s *NotificationService) SuspendReminders(
+	ctx context.Context,
+	assignmentID string,
+	suspended bool,
+) error {
+	const op = "services.notification.SuspendReminders"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
+	)
+
+	if err := s.reminderSaver.SuspendReminders(ctx, assignmentID, suspended); err != nil {
+		log.Error("failed to suspend reminders", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// CancelReminders stops sending the reminders to the student
+// who has submitted the assignment.
+func (s *NotificationService) CancelReminders(
+	ctx context.Context,
+	studentAssignmentID string,
+	submittedAt time.Time,
+) error {
+	const op = "services.notification.CancelReminders"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("student_assignment_id", studentAssignmentID),
+	)
+
+	if err := s.reminderSaver.CancelReminders(ctx, studentAssignmentID, submittedAt); err != nil {
+		log.Error("failed to cancel reminders", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// NotifyFeedback notifies the student that the submission is graded
+// or returned for rework. If the submission is returned, the cancelled
+// reminders of the assignment are resumed, as the student has to submit it again.
+func (s *NotificationService) NotifyFeedback(
+	ctx context.Context,
+	feedback models.Feedback,
+) error {
+	const op = "services.notification.NotifyFeedback"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", feedback.SubmissionID),
+	)
+
+	notification := models.Notification{
+		UserID:       feedback.StudentID,
+		Kind:         models.KindFeedbackPublished,
+		DedupeKey:    "feedback:" + feedback.EventID,
+		Title:        fmt.Sprintf("New feedback on %q", feedback.AssignmentTitle),
+		Body:         fmt.Sprintf("Your teacher has graded %q.", feedback.AssignmentTitle),
+		AssignmentID: feedback.AssignmentID,
+		SubmissionID: feedback.SubmissionID,
+	}
+
+	if feedback.Score != nil {
+		notification.Body += " Score: " + strconv.FormatFloat(*feedback.Score, 'f', -1, 64) + "."
+	}
+
+	if feedback.Returned {
+		notification.Kind = models.KindSubmissionReturned
+		notification.Title = fmt.Sprintf("%q is returned for rework", feedback.AssignmentTitle)
+		notification.Body = fmt.Sprintf(
+			"Your teacher has returned %q for rework. Read the feedback and submit it again.",
+			feedback.AssignmentTitle,
+		)
+
+		if err := s.reminderSaver.ResumeReminders(ctx, feedback.StudentAssignmentID); err != nil {
+			log.Error("failed to resume reminders", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := s.notify(ctx, notification); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// NotifyWelcome welcomes the user who has registered in the platform.
+func (s *NotificationService) NotifyWelcome(
+	ctx context.Context,
+	user models.User,
+) error {
+	const op = "services.notification.NotifyWelcome"
+
+	body := "Your account is ready. The assignments, grades and reminders will appear here."
+	if user.FirstName != "" {
+		body = fmt.Sprintf("Hello, %s! %s", user.FirstName, body)
+	}
+
+	err := s.notify(ctx, models.Notification{
+		UserID:    user.ID,
+		Kind:      models.KindWelcome,
+		DedupeKey: "welcome:" + strconv.FormatInt(user.ID, 10),
+		Title:     "Welcome to the Creative Learning Platform",
+		Body:      body,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// notify saves the notification for the channels chosen by the user.
+// The notification with the dedupe key which has already been used is skipped,
+// so the notification is never sent twice.
+func (s *NotificationService) notify(
+	ctx context.Context,
+	notification models.Notification,
+) error {
+	const op = "services.notification.notify"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("kind", string(notification.Kind)),
+		slog.String("dedupe_key", notification.DedupeKey),
+	)
+
+	preferences, err := s.Preferences(ctx, notification.UserID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	notification.InInbox = preferences.Enabled(models.ChannelInbox)
+	notification.CreatedAt = time.Now().UTC()
+
+	var deliveries []models.Delivery
+	if preferences.Enabled(models.ChannelEmail) && preferences.Email != "" {
+		deliveries = append(deliveries, models.Delivery{
+			Channel:   models.ChannelEmail,
+			Recipient: preferences.Email,
+		})
+	}
+	if preferences.Enabled(models.ChannelWebhook) && preferences.WebhookURL != "" {
+		deliveries = append(deliveries, models.Delivery{
+			Channel:   models.ChannelWebhook,
+			Recipient: preferences.WebhookURL,
+		})
+	}
+
+	if err := s.notificationSaver.SaveNotification(ctx, notification, deliveries); err != nil {
+		if errors.Is(err, storage.ErrNotificationExists) {
+			log.Debug("notification already exists")
+
+			return nil
+		}
+
+		log.Error("failed to save notification", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("notification created", slog.Int("deliveries", len(deliveries)))
+
+	if notification.InInbox {
+		s.watchers.wake(notification.UserID)
+	}
+
+	return nil
+}
+
+// SendReminders notifies the students about the near due dates.
+// It is run periodically by the scheduler as the background worker.
+func (s *NotificationService) SendReminders(ctx context.Context) error {
+	const op = "services.notification.SendReminders"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	for {
+		now := time.Now().UTC()
+
+		reminders, err := s.reminderSaver.ClaimReminders(ctx, now, now.Add(reminderLease), reminderBatchSize)
+		if err != nil {
+			log.Error("failed to claim reminders", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, reminder := range reminders {
+			err := s.notify(ctx, models.Notification{
+				UserID: reminder.StudentID,
+				Kind:   models.KindDueReminder,
+				DedupeKey: fmt.Sprintf(
+					"reminder:%s:%d:%d",
+					reminder.StudentAssignmentID,
+					int(reminder.Lead/time.Minute),
+					reminder.DueDate.Unix(),
+				),
+				Title:        fmt.Sprintf("%q is due soon", reminder.Title),
+				Body:         fmt.Sprintf("%q is due on %s.", reminder.Title, reminder.DueDate.Format(dateFormat)),
+				AssignmentID: reminder.AssignmentID,
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+
+			err = s.reminderSaver.MarkReminderSent(ctx, reminder.StudentAssignmentID, reminder.Lead, now)
+			if err != nil {
+				log.Error("failed to mark reminder as sent", slog.Any("error", err))
+
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		if len(reminders) < reminderBatchSize {
+			return nil
+		}
+	}
+}
+
+// DeliverNotifications sends the notifications by email and webhook.
+// It is run periodically by the scheduler as the background worker.
+// The failed delivery is retried with the exponential backoff
+// until maxDeliveryAttempts.
+func (s *NotificationService) DeliverNotifications(ctx context.Context) error {
+	const op = "services.notification.DeliverNotifications"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	for {
+		now := time.Now().UTC()
+
+		deliveries, err := s.notificationSaver.ClaimDeliveries(ctx, now, now.Add(deliveryLease), deliveryBatchSize)
+		if err != nil {
+			log.Error("failed to claim deliveries", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, delivery := range deliveries {
+			if err := s.deliver(ctx, delivery); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		if len(deliveries) < deliveryBatchSize {
+			return nil
+		}
+	}
+}
+
+// deliver sends the notification by the channel of the delivery and records the outcome.
+func (s *NotificationService) deliver(ctx context.Context, delivery models.Delivery) error {
+	const op = "services.notification.deliver"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("notification_id", delivery.Notification.ID),
+		slog.String("channel", string(delivery.Channel)),
+	)
+
+	sender, ok := s.senders[delivery.Channel]
+	if !ok {
+		log.Error("no sender for channel")
+
+		err := s.notificationSaver.MarkDeliveryFailed(
+			ctx,
+			delivery.Notification.ID,
+			delivery.Channel,
+			time.Now().UTC(),
+			"unsupported channel",
+			true,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return nil
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	if err := sender.Send(sendCtx, delivery.Recipient, delivery.Notification); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s: %w", op, ctx.Err())
+		}
+
+		final := delivery.Attempts >= maxDeliveryAttempts
+		delay := minRetryDelay << (delivery.Attempts - 1)
+
+		log.Warn(
+			"failed to send notification",
+			slog.Any("error", err),
+			slog.Int("attempts", delivery.Attempts),
+			slog.Bool("final", final),
+		)
+
+		err = s.notificationSaver.MarkDeliveryFailed(
+			ctx,
+			delivery.Notification.ID,
+			delivery.Channel,
+			time.Now().UTC().Add(delay),
+			err.Error(),
+			final,
+		)
+		if err != nil {
+			log.Error("failed to mark delivery as failed", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return nil
+	}
+
+	if err := s.notificationSaver.MarkDelivered(ctx, delivery.Notification.ID, delivery.Channel, time.Now().UTC()); err != nil {
+		log.Error("failed to mark delivery as sent", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
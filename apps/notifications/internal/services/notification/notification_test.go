@@ -0,0 +1,299 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"notifications/internal/domain/models"
+	"notifications/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reminderKey identifies the reminder the way the primary key of the reminders table does.
+type reminderKey struct {
+	studentAssignmentID string
+	lead                time.Duration
+}
+
+type fakeReminder struct {
+	models.Reminder
+	sentAt      time.Time
+	lockedUntil time.Time
+}
+
+// fakeStorage keeps the notifications and the reminders in memory
+// and claims the reminders the way the postgres storage does.
+// The methods the tests do not call are left to the embedded interfaces.
+type fakeStorage struct {
+	NotificationSaver
+	NotificationProvider
+	PreferenceSaver
+	ReminderSaver
+
+	// mu guards notifications, which are read by the subscriptions of the inbox.
+	mu            sync.Mutex
+	notifications []models.Notification
+	reminders     map[reminderKey]*fakeReminder
+	// markErr is returned by the next call of MarkReminderSent.
+	markErr error
+}
+
+func (f *fakeStorage) SaveNotification(
+	_ context.Context,
+	notification models.Notification,
+	_ []models.Delivery,
+) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, saved := range f.notifications {
+		if saved.DedupeKey == notification.DedupeKey {
+			return storage.ErrNotificationExists
+		}
+	}
+
+	notification.ID = fmt.Sprintf("notification-%d", len(f.notifications)+1)
+	f.notifications = append(f.notifications, notification)
+
+	return nil
+}
+
+func (f *fakeStorage) Preferences(_ context.Context, _ int64) (models.Preferences, error) {
+	return models.Preferences{}, storage.ErrPreferencesNotFound
+}
+
+func (f *fakeStorage) ScheduleReminders(_ context.Context, reminders []models.Reminder) error {
+	for _, reminder := range reminders {
+		key := reminderKey{reminder.StudentAssignmentID, reminder.Lead}
+		if _, ok := f.reminders[key]; !ok {
+			f.reminders[key] = &fakeReminder{Reminder: reminder}
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeStorage) RescheduleReminders(
+	_ context.Context,
+	reminders []models.Reminder,
+	updatedAt time.Time,
+) error {
+	for _, reminder := range reminders {
+		key := reminderKey{reminder.StudentAssignmentID, reminder.Lead}
+		if saved, ok := f.reminders[key]; ok {
+			saved.Title = reminder.Title
+			saved.DueDate = reminder.DueDate
+			saved.SendAt = reminder.SendAt
+
+			continue
+		}
+
+		if reminder.SendAt.After(updatedAt) {
+			f.reminders[key] = &fakeReminder{Reminder: reminder}
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeStorage) ClaimReminders(
+	_ context.Context,
+	now time.Time,
+	lockedUntil time.Time,
+	_ int,
+) ([]models.Reminder, error) {
+	var claimed []models.Reminder
+	for _, r := range f.reminders {
+		if !r.sentAt.IsZero() || r.SendAt.After(now) || !r.DueDate.After(now) || r.lockedUntil.After(now) {
+			continue
+		}
+
+		shorterDue := false
+		for _, s := range f.reminders {
+			if s.StudentAssignmentID == r.StudentAssignmentID && s.Lead < r.Lead && !s.SendAt.After(now) {
+				shorterDue = true
+			}
+		}
+		if shorterDue {
+			continue
+		}
+
+		r.lockedUntil = lockedUntil
+		claimed = append(claimed, r.Reminder)
+	}
+
+	return claimed, nil
+}
+
+func (f *fakeStorage) MarkReminderSent(
+	_ context.Context,
+	studentAssignmentID string,
+	lead time.Duration,
+	sentAt time.Time,
+) error {
+	if err := f.markErr; err != nil {
+		f.markErr = nil
+		return err
+	}
+
+	f.reminders[reminderKey{studentAssignmentID, lead}].sentAt = sentAt
+
+	return nil
+}
+
+// expireLeases makes the claimed reminders claimable again,
+// as if the worker which has claimed them has stopped.
+func (f *fakeStorage) expireLeases() {
+	for _, r := range f.reminders {
+		r.lockedUntil = time.Time{}
+	}
+}
+
+func (f *fakeStorage) reminder(studentAssignmentID string, lead time.Duration) *fakeReminder {
+	return f.reminders[reminderKey{studentAssignmentID, lead}]
+}
+
+func newTestService() (*NotificationService, *fakeStorage) {
+	st := &fakeStorage{reminders: make(map[reminderKey]*fakeReminder)}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return New(log, st, st, st, st, st, nil), st
+}
+
+func studentAssignment(id string, studentID int64, dueDate time.Time) models.StudentAssignment {
+	return models.StudentAssignment{
+		ID:           id,
+		AssignmentID: "assignment",
+		StudentID:    studentID,
+		Title:        "Essay",
+		DueDate:      dueDate,
+	}
+}
+
+func TestSendReminders_DuplicateClaim(t *testing.T) {
+	s, st := newTestService()
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	dueDate := now.Add(30 * time.Minute).Truncate(time.Second)
+
+	err := s.ScheduleReminders(ctx, []models.StudentAssignment{
+		studentAssignment("student-assignment", 1, dueDate),
+	}, now.Add(-48*time.Hour))
+	require.NoError(t, err)
+
+	// the worker stops after the notification is saved
+	// but before the reminder is marked as sent
+	st.markErr = errors.New("connection reset")
+	require.Error(t, s.SendReminders(ctx))
+	require.Len(t, st.notifications, 1)
+
+	st.expireLeases()
+	require.NoError(t, s.SendReminders(ctx))
+
+	require.Len(t, st.notifications, 1, "reminder claimed again is not sent twice")
+	assert.Equal(t, fmt.Sprintf("reminder:student-assignment:60:%d", dueDate.Unix()), st.notifications[0].DedupeKey)
+	assert.Equal(t, models.KindDueReminder, st.notifications[0].Kind)
+	assert.False(t, st.reminder("student-assignment", time.Hour).sentAt.IsZero())
+	assert.True(t, st.reminder("student-assignment", 24*time.Hour).sentAt.IsZero(),
+		"reminder superseded by the shorter one is not sent")
+}
+
+func TestRescheduleReminders_DueDateMoved(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("closer", func(t *testing.T) {
+		s, st := newTestService()
+
+		now := time.Now().UTC()
+		publishedAt := now.Add(-time.Hour)
+
+		err := s.ScheduleReminders(ctx, []models.StudentAssignment{
+			studentAssignment("student-assignment", 1, now.Add(72*time.Hour)),
+		}, publishedAt)
+		require.NoError(t, err)
+		require.NoError(t, s.SendReminders(ctx))
+		require.Empty(t, st.notifications)
+
+		dueDate := now.Add(30 * time.Minute).Truncate(time.Second)
+		err = s.RescheduleReminders(ctx, []models.StudentAssignment{
+			studentAssignment("student-assignment", 1, dueDate),
+		}, now)
+		require.NoError(t, err)
+
+		assert.Equal(t, dueDate.Add(-24*time.Hour), st.reminder("student-assignment", 24*time.Hour).SendAt)
+		assert.Equal(t, dueDate.Add(-time.Hour), st.reminder("student-assignment", time.Hour).SendAt)
+
+		require.NoError(t, s.SendReminders(ctx))
+		require.Len(t, st.notifications, 1, "only the shorter reminder is sent")
+		assert.Equal(t, fmt.Sprintf("reminder:student-assignment:60:%d", dueDate.Unix()), st.notifications[0].DedupeKey)
+	})
+
+	t.Run("later", func(t *testing.T) {
+		s, st := newTestService()
+
+		now := time.Now().UTC()
+
+		// the 24h reminder is skipped, as the assignment is published less than a day before the due date
+		err := s.ScheduleReminders(ctx, []models.StudentAssignment{
+			studentAssignment("student-assignment", 1, now.Add(2*time.Hour)),
+		}, now)
+		require.NoError(t, err)
+		require.Nil(t, st.reminder("student-assignment", 24*time.Hour))
+
+		dueDate := now.Add(72 * time.Hour)
+		err = s.RescheduleReminders(ctx, []models.StudentAssignment{
+			studentAssignment("student-assignment", 1, dueDate),
+		}, now)
+		require.NoError(t, err)
+
+		for _, lead := range []time.Duration{24 * time.Hour, time.Hour} {
+			reminder := st.reminder("student-assignment", lead)
+			require.NotNil(t, reminder, lead.String())
+			assert.Equal(t, dueDate, reminder.DueDate)
+			assert.Equal(t, dueDate.Add(-lead), reminder.SendAt)
+		}
+	})
+}
+
+func TestRescheduleReminders_Extension(t *testing.T) {
+	s, st := newTestService()
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	dueDate := now.Add(30 * time.Minute).Truncate(time.Second)
+
+	err := s.ScheduleReminders(ctx, []models.StudentAssignment{
+		studentAssignment("first", 1, dueDate),
+		studentAssignment("second", 2, dueDate),
+	}, now.Add(-48*time.Hour))
+	require.NoError(t, err)
+
+	// the due date of the second student is extended by two days
+	extendedDate := dueDate.Add(48 * time.Hour)
+	err = s.RescheduleReminders(ctx, []models.StudentAssignment{
+		studentAssignment("first", 1, dueDate),
+		studentAssignment("second", 2, extendedDate),
+	}, now)
+	require.NoError(t, err)
+
+	for _, lead := range []time.Duration{24 * time.Hour, time.Hour} {
+		assert.Equal(t, dueDate.Add(-lead), st.reminder("first", lead).SendAt, lead.String())
+		assert.Equal(t, extendedDate.Add(-lead), st.reminder("second", lead).SendAt, lead.String())
+	}
+
+	require.NoError(t, s.SendReminders(ctx))
+
+	require.Len(t, st.notifications, 1, "extended student is not reminded of the old due date")
+	assert.Equal(t, int64(1), st.notifications[0].UserID)
+	assert.Equal(t, fmt.Sprintf("reminder:first:60:%d", dueDate.Unix()), st.notifications[0].DedupeKey)
+}
@@ -0,0 +1,494 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"notifications/internal/domain/models"
+	"notifications/internal/lib/signature"
+	"notifications/internal/storage"
+)
+
+const (
+	// deliveryBatchSize is the number of deliveries claimed by the worker at once.
+	deliveryBatchSize = 50
+	// deliveryLease is the time the claimed delivery is held by the worker.
+	deliveryLease = 5 * time.Minute
+	// postTimeout limits the time of posting the single delivery.
+	postTimeout = 30 * time.Second
+	// maxDeliveryAttempts is the number of attempts after which
+	// the delivery is moved to the dead-letter list.
+	maxDeliveryAttempts = 10
+	// minRetryDelay is the delay after the first failed attempt,
+	// which is doubled on every next attempt.
+	minRetryDelay = 30 * time.Second
+	// maxRetryDelay limits the delay between the attempts.
+	maxRetryDelay = 6 * time.Hour
+	// purgeBatchSize is the number of deliveries deleted at once.
+	purgeBatchSize = 1000
+	// maxErrorLen limits the length of the error of the attempt kept with the delivery.
+	maxErrorLen = 1024
+)
+
+var (
+	ErrWebhookNotFound    = errors.New("webhook not found")
+	ErrDeliveryNotFound   = errors.New("delivery not found")
+	ErrUnknownEventType   = errors.New("unknown event type")
+	ErrEventTypesRequired = errors.New("event types are required")
+)
+
+type WebhookService struct {
+	log             *slog.Logger
+	webhookSaver    WebhookSaver
+	webhookProvider WebhookProvider
+	poster          Poster
+	eventTypes      []string
+	retention       time.Duration
+}
+
+type WebhookSaver interface {
+	SaveWebhook(ctx context.Context, subscription models.WebhookSubscription) (string, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	SaveWebhookDeliveries(ctx context.Context, event models.WebhookEvent, now time.Time) (int64, error)
+	ClaimWebhookDeliveries(
+		ctx context.Context,
+		now time.Time,
+		lockedUntil time.Time,
+		limit int,
+	) ([]models.WebhookDelivery, error)
+	MarkWebhookDelivered(ctx context.Context, id string, statusCode int, deliveredAt time.Time) error
+	MarkWebhookFailed(
+		ctx context.Context,
+		id string,
+		statusCode int,
+		nextAttemptAt time.Time,
+		reason string,
+		final bool,
+	) error
+	RedeliverWebhook(ctx context.Context, subscriptionID string, id string, now time.Time) error
+	PurgeWebhookDeliveries(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+type WebhookProvider interface {
+	Webhook(ctx context.Context, id string) (models.WebhookSubscription, error)
+	Webhooks(ctx context.Context, appID int32) ([]models.WebhookSubscription, error)
+	DeadWebhookDeliveries(
+		ctx context.Context,
+		subscriptionID string,
+		limit int,
+		offset int,
+	) ([]models.WebhookDelivery, error)
+}
+
+// Poster posts the deliveries to the urls of the webhooks.
+type Poster interface {
+	// Post posts the JSON body with the headers to the url
+	// and returns the status code of the response.
+	Post(ctx context.Context, url string, body []byte, header http.Header) (int, error)
+}
+
+// New returns a new instance of WebhookService.
+// The eventTypes are the types of the events the webhooks can subscribe to,
+// the delivered deliveries are kept for the retention.
+func New(
+	log *slog.Logger,
+	webhookSaver WebhookSaver,
+	webhookProvider WebhookProvider,
+	poster Poster,
+	eventTypes []string,
+	retention time.Duration,
+) *WebhookService {
+	return &WebhookService{
+		log:             log,
+		webhookSaver:    webhookSaver,
+		webhookProvider: webhookProvider,
+		poster:          poster,
+		eventTypes:      eventTypes,
+		retention:       retention,
+	}
+}
+
+// CreateWebhook subscribes the url of the app to the events of the types
+// and returns the webhook with the generated secret of its signature.
+func (s *WebhookService) CreateWebhook(
+	ctx context.Context,
+	subscription models.WebhookSubscription,
+) (models.WebhookSubscription, error) {
+	const op = "services.webhook.CreateWebhook"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int("app_id", int(subscription.AppID)),
+	)
+
+	if len(subscription.EventTypes) == 0 {
+		return models.WebhookSubscription{}, fmt.Errorf("%s: %w", op, ErrEventTypesRequired)
+	}
+
+	var eventTypes []string
+	for _, eventType := range subscription.EventTypes {
+		if !slices.Contains(s.eventTypes, eventType) {
+			return models.WebhookSubscription{}, fmt.Errorf("%s: %w: %s", op, ErrUnknownEventType, eventType)
+		}
+
+		if !slices.Contains(eventTypes, eventType) {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+
+	secret, err := signature.NewSecret()
+	if err != nil {
+		log.Error("failed to generate secret", slog.Any("error", err))
+
+		return models.WebhookSubscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	subscription.EventTypes = eventTypes
+	subscription.Secret = secret
+	subscription.CreatedAt = time.Now().UTC()
+
+	id, err := s.webhookSaver.SaveWebhook(ctx, subscription)
+	if err != nil {
+		log.Error("failed to save webhook", slog.Any("error", err))
+
+		return models.WebhookSubscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	subscription.ID = id
+
+	log.Info("webhook created", slog.String("webhook_id", id))
+
+	return subscription, nil
+}
+
+// Webhooks returns the webhooks of the app.
+func (s *WebhookService) Webhooks(
+	ctx context.Context,
+	appID int32,
+) ([]models.WebhookSubscription, error) {
+	const op = "services.webhook.Webhooks"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int("app_id", int(appID)),
+	)
+
+	subscriptions, err := s.webhookProvider.Webhooks(ctx, appID)
+	if err != nil {
+		log.Error("failed to list webhooks", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return subscriptions, nil
+}
+
+// DeleteWebhook deletes the webhook of the app with its pending and dead deliveries.
+func (s *WebhookService) DeleteWebhook(
+	ctx context.Context,
+	appID int32,
+	id string,
+) error {
+	const op = "services.webhook.DeleteWebhook"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("webhook_id", id),
+	)
+
+	if err := s.checkApp(ctx, appID, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.webhookSaver.DeleteWebhook(ctx, id); err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrWebhookNotFound)
+		}
+
+		log.Error("failed to delete webhook", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("webhook deleted")
+
+	return nil
+}
+
+// DeadDeliveries returns up to limit deliveries of the webhook of the app
+// from the dead-letter list after skipping offset of them.
+func (s *WebhookService) DeadDeliveries(
+	ctx context.Context,
+	appID int32,
+	webhookID string,
+	limit int,
+	offset int,
+) ([]models.WebhookDelivery, error) {
+	const op = "services.webhook.DeadDeliveries"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("webhook_id", webhookID),
+	)
+
+	if err := s.checkApp(ctx, appID, webhookID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	deliveries, err := s.webhookProvider.DeadWebhookDeliveries(ctx, webhookID, limit, offset)
+	if err != nil {
+		log.Error("failed to list dead deliveries", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// RedeliverWebhook moves the delivery of the webhook of the app from the dead-letter list
+// back to the pending ones, so it is posted again with the fresh attempts.
+func (s *WebhookService) RedeliverWebhook(
+	ctx context.Context,
+	appID int32,
+	webhookID string,
+	deliveryID string,
+) error {
+	const op = "services.webhook.RedeliverWebhook"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("webhook_id", webhookID),
+		slog.String("delivery_id", deliveryID),
+	)
+
+	if err := s.checkApp(ctx, appID, webhookID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.webhookSaver.RedeliverWebhook(ctx, webhookID, deliveryID, time.Now().UTC()); err != nil {
+		if errors.Is(err, storage.ErrDeliveryNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrDeliveryNotFound)
+		}
+
+		log.Error("failed to redeliver webhook", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("delivery scheduled for redelivery")
+
+	return nil
+}
+
+// checkApp checks that the webhook belongs to the app.
+// The webhook of another app is reported as missing, so its existence is not revealed.
+func (s *WebhookService) checkApp(ctx context.Context, appID int32, webhookID string) error {
+	const op = "services.webhook.checkApp"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("webhook_id", webhookID),
+	)
+
+	subscription, err := s.webhookProvider.Webhook(ctx, webhookID)
+	if err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrWebhookNotFound)
+		}
+
+		log.Error("failed to get webhook", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if subscription.AppID != appID {
+		log.Warn("webhook belongs to another app", slog.Int("app_id", int(appID)))
+
+		return fmt.Errorf("%s: %w", op, ErrWebhookNotFound)
+	}
+
+	return nil
+}
+
+// Dispatch saves the deliveries of the event to the webhooks subscribed to its type.
+// The event delivered again by the broker is not posted twice.
+func (s *WebhookService) Dispatch(
+	ctx context.Context,
+	event models.WebhookEvent,
+) error {
+	const op = "services.webhook.Dispatch"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("event_id", event.ID),
+		slog.String("type", event.Type),
+	)
+
+	saved, err := s.webhookSaver.SaveWebhookDeliveries(ctx, event, time.Now().UTC())
+	if err != nil {
+		log.Error("failed to save deliveries", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if saved > 0 {
+		log.Debug("deliveries saved", slog.Int64("count", saved))
+	}
+
+	return nil
+}
+
+// DeliverWebhooks posts the pending deliveries to the webhooks.
+// It is run periodically by the scheduler as the background worker.
+// The failed delivery is retried with the exponential backoff
+// until maxDeliveryAttempts and then moved to the dead-letter list.
+func (s *WebhookService) DeliverWebhooks(ctx context.Context) error {
+	const op = "services.webhook.DeliverWebhooks"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	for {
+		now := time.Now().UTC()
+
+		deliveries, err := s.webhookSaver.ClaimWebhookDeliveries(ctx, now, now.Add(deliveryLease), deliveryBatchSize)
+		if err != nil {
+			log.Error("failed to claim deliveries", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, delivery := range deliveries {
+			if err := s.deliver(ctx, delivery); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		if len(deliveries) < deliveryBatchSize {
+			return nil
+		}
+	}
+}
+
+// deliver posts the signed delivery to the url of its webhook and records the outcome.
+//
+// The receiver verifies the delivery by computing HMAC-SHA256
+// of "<X-Webhook-Timestamp>.<body>" with the secret of the webhook
+// and comparing it with X-Webhook-Signature.
+func (s *WebhookService) deliver(ctx context.Context, delivery models.WebhookDelivery) error {
+	const op = "services.webhook.deliver"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("delivery_id", delivery.ID),
+		slog.String("webhook_id", delivery.SubscriptionID),
+	)
+
+	timestamp := time.Now().UTC()
+
+	header := make(http.Header)
+	header.Set("X-Webhook-Id", delivery.ID)
+	header.Set("X-Webhook-Event", delivery.EventType)
+	header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp.Unix(), 10))
+	header.Set("X-Webhook-Signature", signature.Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	postCtx, cancel := context.WithTimeout(ctx, postTimeout)
+	defer cancel()
+
+	statusCode, err := s.poster.Post(postCtx, delivery.URL, delivery.Payload, header)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s: %w", op, ctx.Err())
+		}
+
+		final := delivery.Attempts >= maxDeliveryAttempts
+
+		log.Warn(
+			"failed to post delivery",
+			slog.Any("error", err),
+			slog.Int("attempts", delivery.Attempts),
+			slog.Bool("final", final),
+		)
+
+		reason := err.Error()
+		if len(reason) > maxErrorLen {
+			reason = reason[:maxErrorLen]
+		}
+
+		err = s.webhookSaver.MarkWebhookFailed(
+			ctx,
+			delivery.ID,
+			statusCode,
+			time.Now().UTC().Add(retryDelay(delivery.Attempts)),
+			reason,
+			final,
+		)
+		if err != nil {
+			log.Error("failed to mark delivery as failed", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return nil
+	}
+
+	if err := s.webhookSaver.MarkWebhookDelivered(ctx, delivery.ID, statusCode, time.Now().UTC()); err != nil {
+		log.Error("failed to mark delivery as delivered", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// PurgeDeliveries deletes the deliveries posted longer than the retention ago.
+// It is run periodically by the scheduler as the background worker.
+func (s *WebhookService) PurgeDeliveries(ctx context.Context) error {
+	const op = "services.webhook.PurgeDeliveries"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	before := time.Now().UTC().Add(-s.retention)
+
+	var total int64
+	for {
+		purged, err := s.webhookSaver.PurgeWebhookDeliveries(ctx, before, purgeBatchSize)
+		if err != nil {
+			log.Error("failed to purge deliveries", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		total += purged
+
+		if purged < purgeBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Info("deliveries purged", slog.Int64("count", total))
+	}
+
+	return nil
+}
+
+// retryDelay returns the delay after the failed attempt,
+// which doubles with every attempt up to maxRetryDelay.
+func retryDelay(attempts int) time.Duration {
+	delay := minRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRetryDelay)
+}
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"notifications/internal/domain/models"
+	"notifications/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStorage keeps the webhooks in memory. The methods the tests
+// do not use are left to the embedded interfaces.
+type fakeStorage struct {
+	WebhookSaver
+	WebhookProvider
+	webhooks    map[string]models.WebhookSubscription
+	redelivered []string
+	// deliveries are the ids of the webhooks the events are saved for.
+	deliveries []string
+}
+
+func (f *fakeStorage) Webhook(_ context.Context, id string) (models.WebhookSubscription, error) {
+	subscription, ok := f.webhooks[id]
+	if !ok {
+		return models.WebhookSubscription{}, storage.ErrWebhookNotFound
+	}
+	return subscription, nil
+}
+
+func (f *fakeStorage) DeleteWebhook(_ context.Context, id string) error {
+	if _, ok := f.webhooks[id]; !ok {
+		return storage.ErrWebhookNotFound
+	}
+	delete(f.webhooks, id)
+	return nil
+}
+
+func (f *fakeStorage) SaveWebhookDeliveries(
+	_ context.Context,
+	event models.WebhookEvent,
+	_ time.Time,
+) (int64, error) {
+	var saved int64
+	for id, subscription := range f.webhooks {
+		if subscription.AppID == event.AppID && slices.Contains(subscription.EventTypes, event.Type) {
+			f.deliveries = append(f.deliveries, id)
+			saved++
+		}
+	}
+
+	return saved, nil
+}
+
+func (f *fakeStorage) DeadWebhookDeliveries(
+	_ context.Context,
+	webhookID string,
+	_ int,
+	_ int,
+) ([]models.WebhookDelivery, error) {
+	return []models.WebhookDelivery{{ID: "delivery", SubscriptionID: webhookID}}, nil
+}
+
+func (f *fakeStorage) RedeliverWebhook(_ context.Context, _ string, id string, _ time.Time) error {
+	f.redelivered = append(f.redelivered, id)
+	return nil
+}
+
+const eventType = "tasks.events.v1.AssignmentPublished"
+
+func newTestService() (*WebhookService, *fakeStorage) {
+	st := &fakeStorage{
+		webhooks: map[string]models.WebhookSubscription{
+			"webhook": {ID: "webhook", AppID: 1, EventTypes: []string{eventType}},
+			"other":   {ID: "other", AppID: 2, EventTypes: []string{eventType}},
+		},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return New(log, st, st, nil, nil, time.Hour), st
+}
+
+func TestWebhook_AnotherApp(t *testing.T) {
+	s, st := newTestService()
+	ctx := context.Background()
+
+	err := s.DeleteWebhook(ctx, 2, "webhook")
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+	assert.Contains(t, st.webhooks, "webhook")
+
+	_, err = s.DeadDeliveries(ctx, 2, "webhook", 10, 0)
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+
+	err = s.RedeliverWebhook(ctx, 2, "webhook", "delivery")
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+	assert.Empty(t, st.redelivered)
+}
+
+func TestWebhook_SameApp(t *testing.T) {
+	s, st := newTestService()
+	ctx := context.Background()
+
+	deliveries, err := s.DeadDeliveries(ctx, 1, "webhook", 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+
+	require.NoError(t, s.RedeliverWebhook(ctx, 1, "webhook", "delivery"))
+	assert.Equal(t, []string{"delivery"}, st.redelivered)
+
+	require.NoError(t, s.DeleteWebhook(ctx, 1, "webhook"))
+	assert.NotContains(t, st.webhooks, "webhook")
+
+	err = s.DeleteWebhook(ctx, 1, "webhook")
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+}
+
+func TestDispatch_App(t *testing.T) {
+	s, st := newTestService()
+	ctx := context.Background()
+
+	for _, appID := range []int32{1, 2} {
+		err := s.Dispatch(ctx, models.WebhookEvent{ID: "event", Type: eventType, AppID: appID})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"webhook", "other"}, st.deliveries, "event is delivered only to the webhooks of its app")
+
+	st.deliveries = nil
+
+	require.NoError(t, s.Dispatch(ctx, models.WebhookEvent{ID: "event", Type: eventType}))
+	assert.Empty(t, st.deliveries, "event of no app is delivered to no webhook")
+}
@@ -0,0 +1,393 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"notifications/internal/domain/models"
+	"notifications/internal/storage"
+)
+
+type NotificationRepo struct {
+	db *sql.DB
+}
+
+// New creates a new NotificationRepo instance.
+// That used to interact with the notifications and deliveries tables.
+func New(db *sql.DB) *NotificationRepo {
+	return &NotificationRepo{db: db}
+}
+
+// SaveNotification saves the notification with its pending deliveries.
+// If the notification with the same dedupe key exists,
+// nothing is saved and it returns storage.ErrNotificationExists.
+func (r *NotificationRepo) SaveNotification(
+	ctx context.Context,
+	notification models.Notification,
+	deliveries []models.Delivery,
+) error {
+	const op = "storage.postgres.SaveNotification"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var id string
+	err = tx.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO notifications
+		(
+			id, user_id, kind, dedupe_key, title, body,
+			assignment_id, submission_id, in_inbox, created_at
+		)
+		VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (dedupe_key) DO NOTHING
+		RETURNING id
+		`,
+		notification.UserID,
+		notification.Kind,
+		notification.DedupeKey,
+		notification.Title,
+		notification.Body,
+		notification.AssignmentID,
+		notification.SubmissionID,
+		notification.InInbox,
+		notification.CreatedAt,
+	).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrNotificationExists)
+		}
+
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	for _, delivery := range deliveries {
+		_, err = tx.ExecContext(
+			ctx,
+			`
+			INSERT INTO deliveries (notification_id, channel, recipient, status, next_attempt_at)
+			VALUES ($1, $2, $3, $4, $5)
+			`,
+			id,
+			delivery.Channel,
+			delivery.Recipient,
+			models.DeliveryPending,
+			notification.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// ClaimDeliveries locks up to limit pending deliveries due to be sent
+// until lockedUntil and returns them with their notifications.
+//
+// Rows are locked with SKIP LOCKED, so several instances of the service
+// can send the notifications at the same time without sending them twice.
+func (r *NotificationRepo) ClaimDeliveries(
+	ctx context.Context,
+	now time.Time,
+	lockedUntil time.Time,
+	limit int,
+) ([]models.Delivery, error) {
+	const op = "storage.postgres.ClaimDeliveries"
+
+	query := `
+		UPDATE deliveries d
+		SET attempts = d.attempts + 1, next_attempt_at = $2
+		FROM notifications n
+		WHERE n.id = d.notification_id AND (d.notification_id, d.channel) IN (
+			SELECT notification_id, channel
+			FROM deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING
+			d.channel, d.recipient, d.status, d.attempts,
+			n.id, n.user_id, n.kind, n.dedupe_key, n.title, n.body,
+			n.assignment_id, n.submission_id, n.in_inbox, n.created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now, lockedUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.Delivery
+	for rows.Next() {
+		var delivery models.Delivery
+		if err := rows.Scan(
+			&delivery.Channel,
+			&delivery.Recipient,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.Notification.ID,
+			&delivery.Notification.UserID,
+			&delivery.Notification.Kind,
+			&delivery.Notification.DedupeKey,
+			&delivery.Notification.Title,
+			&delivery.Notification.Body,
+			&delivery.Notification.AssignmentID,
+			&delivery.Notification.SubmissionID,
+			&delivery.Notification.InInbox,
+			&delivery.Notification.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// MarkDelivered marks the delivery as sent.
+func (r *NotificationRepo) MarkDelivered(
+	ctx context.Context,
+	notificationID string,
+	channel models.Channel,
+	sentAt time.Time,
+) error {
+	const op = "storage.postgres.MarkDelivered"
+
+	query := `
+		UPDATE deliveries
+		SET status = $3, sent_at = $4, last_error = ''
+		WHERE notification_id = $1 AND channel = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, notificationID, channel, models.DeliverySent, sentAt)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// MarkDeliveryFailed records the failed attempt to send the notification
+// and postpones the next attempt. If the failure is final, the delivery is not retried.
+func (r *NotificationRepo) MarkDeliveryFailed(
+	ctx context.Context,
+	notificationID string,
+	channel models.Channel,
+	nextAttemptAt time.Time,
+	reason string,
+	final bool,
+) error {
+	const op = "storage.postgres.MarkDeliveryFailed"
+
+	status := models.DeliveryPending
+	if final {
+		status = models.DeliveryFailed
+	}
+
+	query := `
+		UPDATE deliveries
+		SET status = $3, next_attempt_at = $4, last_error = $5
+		WHERE notification_id = $1 AND channel = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, notificationID, channel, status, nextAttemptAt, reason)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// ListNotifications returns up to limit notifications of the inbox of the user
+// following the cursor, the newest first.
+func (r *NotificationRepo) ListNotifications(
+	ctx context.Context,
+	userID int64,
+	cursor models.InboxCursor,
+	unreadOnly bool,
+	limit int,
+) ([]models.Notification, error) {
+	const op = "storage.postgres.ListNotifications"
+
+	query := `
+		SELECT ` + inboxColumns + `
+		FROM notifications
+		WHERE user_id = $1 AND in_inbox
+			AND (NOT $2 OR read_at IS NULL)
+			AND ($3::TIMESTAMP IS NULL OR (created_at, id) < ($3, $4::UUID))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $5
+	`
+
+	var (
+		createdAt sql.NullTime
+		id        sql.NullString
+	)
+	if !cursor.CreatedAt.IsZero() {
+		createdAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		id = sql.NullString{String: cursor.ID, Valid: true}
+	}
+
+	notifications, err := r.queryInbox(ctx, query, userID, unreadOnly, createdAt, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return notifications, nil
+}
+
+// NotificationsSince returns up to limit notifications of the inbox of the user
+// created at or after since, the oldest first.
+func (r *NotificationRepo) NotificationsSince(
+	ctx context.Context,
+	userID int64,
+	since time.Time,
+	limit int,
+) ([]models.Notification, error) {
+	const op = "storage.postgres.NotificationsSince"
+
+	query := `
+		SELECT ` + inboxColumns + `
+		FROM notifications
+		WHERE user_id = $1 AND in_inbox AND created_at >= $2
+		ORDER BY created_at, id
+		LIMIT $3
+	`
+
+	notifications, err := r.queryInbox(ctx, query, userID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return notifications, nil
+}
+
+// MarkRead marks the notifications of the user as read.
+// The notifications of other users and the ones already read are skipped.
+func (r *NotificationRepo) MarkRead(
+	ctx context.Context,
+	userID int64,
+	ids []string,
+	readAt time.Time,
+) error {
+	const op = "storage.postgres.MarkRead"
+
+	query := `
+		UPDATE notifications
+		SET read_at = $3
+		WHERE user_id = $1 AND id = ANY($2::UUID[]) AND in_inbox AND read_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, ids, readAt); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// MarkAllRead marks every notification in the inbox of the user as read.
+func (r *NotificationRepo) MarkAllRead(
+	ctx context.Context,
+	userID int64,
+	readAt time.Time,
+) error {
+	const op = "storage.postgres.MarkAllRead"
+
+	query := `
+		UPDATE notifications
+		SET read_at = $2
+		WHERE user_id = $1 AND in_inbox AND read_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, readAt); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// UnreadCount returns the number of the notifications in the inbox
+// of the user which are not read yet.
+func (r *NotificationRepo) UnreadCount(
+	ctx context.Context,
+	userID int64,
+) (int64, error) {
+	const op = "storage.postgres.UnreadCount"
+
+	query := `
+		SELECT COUNT(*)
+		FROM notifications
+		WHERE user_id = $1 AND in_inbox AND read_at IS NULL
+	`
+
+	var count int64
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return count, nil
+}
+
+// inboxColumns are the columns of the notification scanned by queryInbox.
+const inboxColumns = `id, user_id, kind, title, body, assignment_id, submission_id, read_at, created_at`
+
+func (r *NotificationRepo) queryInbox(
+	ctx context.Context,
+	query string,
+	args ...any,
+) ([]models.Notification, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		var (
+			notification models.Notification
+			readAt       sql.NullTime
+		)
+		if err := rows.Scan(
+			&notification.ID,
+			&notification.UserID,
+			&notification.Kind,
+			&notification.Title,
+			&notification.Body,
+			&notification.AssignmentID,
+			&notification.SubmissionID,
+			&readAt,
+			&notification.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		notification.InInbox = true
+		notification.ReadAt = readAt.Time
+		notifications = append(notifications, notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
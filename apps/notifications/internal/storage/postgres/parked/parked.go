@@ -0,0 +1,41 @@
+package parked
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type ParkedEventRepo struct {
+	db *sql.DB
+}
+
+// New creates a new ParkedEventRepo instance.
+// That used to interact with the parked_events table.
+func New(db *sql.DB) *ParkedEventRepo {
+	return &ParkedEventRepo{db: db}
+}
+
+// ParkEvent saves the event which the consumer has failed to handle on every delivery.
+func (r *ParkedEventRepo) ParkEvent(
+	ctx context.Context,
+	consumer string,
+	subject string,
+	data []byte,
+	reason string,
+	parkedAt time.Time,
+) error {
+	const op = "storage.postgres.ParkEvent"
+
+	query := `
+		INSERT INTO parked_events (consumer, subject, data, reason, parked_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, consumer, subject, data, reason, parkedAt); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
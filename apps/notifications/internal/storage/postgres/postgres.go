@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"notifications/internal/storage"
+	"notifications/internal/storage/postgres/notification"
+	"notifications/internal/storage/postgres/parked"
+	"notifications/internal/storage/postgres/preference"
+	"notifications/internal/storage/postgres/reminder"
+	"notifications/internal/storage/postgres/webhook"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+type Storage struct {
+	db *sql.DB
+	storage.PreferenceStorage
+	storage.NotificationStorage
+	storage.ReminderStorage
+	storage.WebhookStorage
+	storage.ParkedEventStorage
+}
+
+func New(connString string) (*Storage, error) {
+	const op = "storage.postgres.New"
+
+	db, err := sql.Open("pgx", connString)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	err = db.Ping()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return &Storage{
+		db:                  db,
+		PreferenceStorage:   preference.New(db),
+		NotificationStorage: notification.New(db),
+		ReminderStorage:     reminder.New(db),
+		WebhookStorage:      webhook.New(db),
+		ParkedEventStorage:  parked.New(db),
+	}, nil
+}
+
+func (s *Storage) Close() error {
+	const op = "storage.postgres.Close"
+
+	err := s.db.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package preference
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"notifications/internal/domain/models"
+	"notifications/internal/storage"
+)
+
+type PreferenceRepo struct {
+	db *sql.DB
+}
+
+// New creates a new PreferenceRepo instance.
+// That used to interact with the preferences table.
+func New(db *sql.DB) *PreferenceRepo {
+	return &PreferenceRepo{db: db}
+}
+
+// Preferences returns the channel preferences of the user.
+// If the user has never saved them, it returns storage.ErrPreferencesNotFound.
+func (r *PreferenceRepo) Preferences(
+	ctx context.Context,
+	userID int64,
+) (models.Preferences, error) {
+	const op = "storage.postgres.Preferences"
+
+	query := `
+		SELECT inbox, email, email_address, webhook, webhook_url, updated_at
+		FROM preferences
+		WHERE user_id = $1
+	`
+
+	var (
+		preferences models.Preferences
+		inbox       bool
+		email       bool
+		webhook     bool
+	)
+
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&inbox,
+		&email,
+		&preferences.Email,
+		&webhook,
+		&preferences.WebhookURL,
+		&preferences.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Preferences{}, fmt.Errorf("%s: %w", op, storage.ErrPreferencesNotFound)
+		}
+
+		return models.Preferences{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	preferences.UserID = userID
+
+	for _, channel := range []struct {
+		channel models.Channel
+		enabled bool
+	}{
+		{channel: models.ChannelInbox, enabled: inbox},
+		{channel: models.ChannelEmail, enabled: email},
+		{channel: models.ChannelWebhook, enabled: webhook},
+	} {
+		if channel.enabled {
+			preferences.Channels = append(preferences.Channels, channel.channel)
+		}
+	}
+
+	return preferences, nil
+}
+
+// SavePreferences saves the channel preferences of the user, replacing the previous ones.
+func (r *PreferenceRepo) SavePreferences(
+	ctx context.Context,
+	preferences models.Preferences,
+) error {
+	const op = "storage.postgres.SavePreferences"
+
+	query := `
+		INSERT INTO preferences
+		(user_id, inbox, email, email_address, webhook, webhook_url, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE SET
+			inbox = EXCLUDED.inbox,
+			email = EXCLUDED.email,
+			email_address = EXCLUDED.email_address,
+			webhook = EXCLUDED.webhook,
+			webhook_url = EXCLUDED.webhook_url,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		preferences.UserID,
+		preferences.Enabled(models.ChannelInbox),
+		preferences.Enabled(models.ChannelEmail),
+		preferences.Email,
+		preferences.Enabled(models.ChannelWebhook),
+		preferences.WebhookURL,
+		preferences.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
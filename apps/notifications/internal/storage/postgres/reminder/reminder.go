@@ -0,0 +1,296 @@
+package reminder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"notifications/internal/domain/models"
+)
+
+type ReminderRepo struct {
+	db *sql.DB
+}
+
+// New creates a new ReminderRepo instance.
+// That used to interact with the reminders table.
+func New(db *sql.DB) *ReminderRepo {
+	return &ReminderRepo{db: db}
+}
+
+// ScheduleReminders saves the reminders.
+// The reminders which are already scheduled are kept, so scheduling is idempotent.
+func (r *ReminderRepo) ScheduleReminders(
+	ctx context.Context,
+	reminders []models.Reminder,
+) error {
+	const op = "storage.postgres.ScheduleReminders"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	for _, reminder := range reminders {
+		_, err = tx.ExecContext(
+			ctx,
+			`
+			INSERT INTO reminders
+			(student_assignment_id, lead_minutes, assignment_id, student_id, title, due_date, send_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (student_assignment_id, lead_minutes) DO NOTHING
+			`,
+			reminder.StudentAssignmentID,
+			int(reminder.Lead/time.Minute),
+			reminder.AssignmentID,
+			reminder.StudentID,
+			reminder.Title,
+			reminder.DueDate,
+			reminder.SendAt,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// RescheduleReminders moves the reminders to their new due dates.
+// The time the reminders were sent is kept, so the reminder is not sent twice,
+// and the reminders which are not scheduled yet, e.g. skipped on publishing,
+// are saved only if they are sent after updatedAt.
+func (r *ReminderRepo) RescheduleReminders(
+	ctx context.Context,
+	reminders []models.Reminder,
+	updatedAt time.Time,
+) error {
+	const op = "storage.postgres.RescheduleReminders"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	for _, reminder := range reminders {
+		res, err := tx.ExecContext(
+			ctx,
+			`
+			UPDATE reminders
+			SET title = $3, due_date = $4, send_at = $5
+			WHERE student_assignment_id = $1 AND lead_minutes = $2
+			`,
+			reminder.StudentAssignmentID,
+			int(reminder.Lead/time.Minute),
+			reminder.Title,
+			reminder.DueDate,
+			reminder.SendAt,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+
+		updated, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+
+		if updated > 0 || !reminder.SendAt.After(updatedAt) {
+			continue
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`
+			INSERT INTO reminders
+			(student_assignment_id, lead_minutes, assignment_id, student_id, title, due_date, send_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (student_assignment_id, lead_minutes) DO NOTHING
+			`,
+			reminder.StudentAssignmentID,
+			int(reminder.Lead/time.Minute),
+			reminder.AssignmentID,
+			reminder.StudentID,
+			reminder.Title,
+			reminder.DueDate,
+			reminder.SendAt,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// SuspendReminders stops or resumes sending the reminders of the assignment
+// while it is in the trash.
+func (r *ReminderRepo) SuspendReminders(
+	ctx context.Context,
+	assignmentID string,
+	suspended bool,
+) error {
+	const op = "storage.postgres.SuspendReminders"
+
+	query := `
+		UPDATE reminders
+		SET suspended = $2
+		WHERE assignment_id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, assignmentID, suspended); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// CancelReminders stops sending the reminders of the student assignment
+// once the student has submitted it.
+func (r *ReminderRepo) CancelReminders(
+	ctx context.Context,
+	studentAssignmentID string,
+	cancelledAt time.Time,
+) error {
+	const op = "storage.postgres.CancelReminders"
+
+	query := `
+		UPDATE reminders
+		SET cancelled_at = $2
+		WHERE student_assignment_id = $1 AND cancelled_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, studentAssignmentID, cancelledAt); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// ResumeReminders resumes sending the cancelled reminders of the student assignment
+// once the submission is returned to the student for rework.
+func (r *ReminderRepo) ResumeReminders(
+	ctx context.Context,
+	studentAssignmentID string,
+) error {
+	const op = "storage.postgres.ResumeReminders"
+
+	query := `
+		UPDATE reminders
+		SET cancelled_at = NULL
+		WHERE student_assignment_id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, studentAssignmentID); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// ClaimReminders locks up to limit reminders due to be sent until lockedUntil.
+// The reminder is skipped if the due date has passed or if the shorter reminder
+// of the same student assignment is also due, e.g. when the due date was moved closer.
+//
+// Rows are locked with SKIP LOCKED, so several instances of the service
+// can send the reminders at the same time without sending them twice.
+func (r *ReminderRepo) ClaimReminders(
+	ctx context.Context,
+	now time.Time,
+	lockedUntil time.Time,
+	limit int,
+) ([]models.Reminder, error) {
+	const op = "storage.postgres.ClaimReminders"
+
+	query := `
+		UPDATE reminders
+		SET locked_until = $2
+		WHERE (student_assignment_id, lead_minutes) IN (
+			SELECT r.student_assignment_id, r.lead_minutes
+			FROM reminders r
+			WHERE r.sent_at IS NULL AND r.cancelled_at IS NULL AND NOT r.suspended
+				AND r.send_at <= $1 AND r.due_date > $1
+				AND (r.locked_until IS NULL OR r.locked_until <= $1)
+				AND NOT EXISTS (
+					SELECT 1
+					FROM reminders s
+					WHERE s.student_assignment_id = r.student_assignment_id
+						AND s.lead_minutes < r.lead_minutes AND s.send_at <= $1
+				)
+			ORDER BY r.send_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING
+			student_assignment_id, lead_minutes, assignment_id, student_id,
+			title, due_date, send_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now, lockedUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var reminders []models.Reminder
+	for rows.Next() {
+		var (
+			reminder    models.Reminder
+			leadMinutes int
+		)
+		if err := rows.Scan(
+			&reminder.StudentAssignmentID,
+			&leadMinutes,
+			&reminder.AssignmentID,
+			&reminder.StudentID,
+			&reminder.Title,
+			&reminder.DueDate,
+			&reminder.SendAt,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		reminder.Lead = time.Duration(leadMinutes) * time.Minute
+		reminders = append(reminders, reminder)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return reminders, nil
+}
+
+// MarkReminderSent marks the reminder as sent.
+func (r *ReminderRepo) MarkReminderSent(
+	ctx context.Context,
+	studentAssignmentID string,
+	lead time.Duration,
+	sentAt time.Time,
+) error {
+	const op = "storage.postgres.MarkReminderSent"
+
+	query := `
+		UPDATE reminders
+		SET sent_at = $3
+		WHERE student_assignment_id = $1 AND lead_minutes = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, studentAssignmentID, int(lead/time.Minute), sentAt)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
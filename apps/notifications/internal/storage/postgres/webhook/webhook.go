@@ -0,0 +1,448 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"notifications/internal/domain/models"
+	"notifications/internal/storage"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type WebhookRepo struct {
+	db *sql.DB
+}
+
+// New creates a new WebhookRepo instance.
+// That used to interact with the webhook_subscriptions and webhook_deliveries tables.
+func New(db *sql.DB) *WebhookRepo {
+	return &WebhookRepo{db: db}
+}
+
+const webhookColumns = `id, app_id, url, event_types, secret, created_by, created_at`
+
+// SaveWebhook saves the webhook and returns its id.
+func (r *WebhookRepo) SaveWebhook(
+	ctx context.Context,
+	subscription models.WebhookSubscription,
+) (string, error) {
+	const op = "storage.postgres.SaveWebhook"
+
+	query := `
+		INSERT INTO webhook_subscriptions
+		(id, app_id, url, event_types, secret, created_by, created_at)
+		VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	var id string
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		subscription.AppID,
+		subscription.URL,
+		subscription.EventTypes,
+		subscription.Secret,
+		subscription.CreatedBy,
+		subscription.CreatedAt,
+	).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	return id, nil
+}
+
+// Webhook returns the webhook by its id.
+func (r *WebhookRepo) Webhook(
+	ctx context.Context,
+	id string,
+) (models.WebhookSubscription, error) {
+	const op = "storage.postgres.Webhook"
+
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhook_subscriptions
+		WHERE id = $1
+	`
+
+	subscription, err := scanWebhook(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.WebhookSubscription{}, fmt.Errorf("%s: %w", op, storage.ErrWebhookNotFound)
+		}
+
+		return models.WebhookSubscription{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return subscription, nil
+}
+
+// Webhooks returns the webhooks of the app, the oldest first.
+func (r *WebhookRepo) Webhooks(
+	ctx context.Context,
+	appID int32,
+) ([]models.WebhookSubscription, error) {
+	const op = "storage.postgres.Webhooks"
+
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhook_subscriptions
+		WHERE app_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, appID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var subscriptions []models.WebhookSubscription
+	for rows.Next() {
+		subscription, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return subscriptions, nil
+}
+
+// DeleteWebhook deletes the webhook with its deliveries.
+func (r *WebhookRepo) DeleteWebhook(
+	ctx context.Context,
+	id string,
+) error {
+	const op = "storage.postgres.DeleteWebhook"
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrWebhookNotFound)
+	}
+
+	return nil
+}
+
+// SaveWebhookDeliveries saves the pending delivery of the event to every webhook
+// of the app of the event subscribed to its type and returns the number of the deliveries saved.
+// The event already saved for the webhook is skipped.
+func (r *WebhookRepo) SaveWebhookDeliveries(
+	ctx context.Context,
+	event models.WebhookEvent,
+	now time.Time,
+) (int64, error) {
+	const op = "storage.postgres.SaveWebhookDeliveries"
+
+	query := `
+		INSERT INTO webhook_deliveries
+		(id, subscription_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+		SELECT gen_random_uuid(), id, $1, $2, $3, $4, $5, $5
+		FROM webhook_subscriptions
+		WHERE app_id = $6 AND $2 = ANY(event_types)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+	`
+
+	res, err := r.db.ExecContext(
+		ctx,
+		query,
+		event.ID,
+		event.Type,
+		event.Payload,
+		models.WebhookPending,
+		now,
+		event.AppID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	saved, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return saved, nil
+}
+
+// ClaimWebhookDeliveries locks up to limit pending deliveries due to be posted
+// until lockedUntil and returns them with the url and the secret of their webhooks.
+//
+// Rows are locked with SKIP LOCKED, so several instances of the service
+// can post the deliveries at the same time without posting them twice.
+func (r *WebhookRepo) ClaimWebhookDeliveries(
+	ctx context.Context,
+	now time.Time,
+	lockedUntil time.Time,
+	limit int,
+) ([]models.WebhookDelivery, error) {
+	const op = "storage.postgres.ClaimWebhookDeliveries"
+
+	query := `
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1, next_attempt_at = $2
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id AND d.id IN (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING
+			d.id, d.subscription_id, d.event_id, d.event_type, d.payload,
+			d.status, d.attempts, d.last_error, d.last_status_code, d.created_at,
+			s.url, s.secret
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now, lockedUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		if err := rows.Scan(
+			&delivery.ID,
+			&delivery.SubscriptionID,
+			&delivery.EventID,
+			&delivery.EventType,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.LastError,
+			&delivery.LastStatusCode,
+			&delivery.CreatedAt,
+			&delivery.URL,
+			&delivery.Secret,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// MarkWebhookDelivered marks the delivery as posted.
+func (r *WebhookRepo) MarkWebhookDelivered(
+	ctx context.Context,
+	id string,
+	statusCode int,
+	deliveredAt time.Time,
+) error {
+	const op = "storage.postgres.MarkWebhookDelivered"
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, delivered_at = $3, last_status_code = $4, last_error = ''
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, models.WebhookDelivered, deliveredAt, statusCode)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// MarkWebhookFailed records the failed attempt to post the delivery
+// and postpones the next attempt. If the failure is final,
+// the delivery is moved to the dead-letter list.
+func (r *WebhookRepo) MarkWebhookFailed(
+	ctx context.Context,
+	id string,
+	statusCode int,
+	nextAttemptAt time.Time,
+	reason string,
+	final bool,
+) error {
+	const op = "storage.postgres.MarkWebhookFailed"
+
+	status := models.WebhookPending
+	if final {
+		status = models.WebhookDead
+	}
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, next_attempt_at = $3, last_error = $4, last_status_code = $5
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, id, status, nextAttemptAt, reason, statusCode)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// DeadWebhookDeliveries returns up to limit deliveries of the webhook
+// from the dead-letter list after skipping offset of them, the oldest first.
+func (r *WebhookRepo) DeadWebhookDeliveries(
+	ctx context.Context,
+	subscriptionID string,
+	limit int,
+	offset int,
+) ([]models.WebhookDelivery, error) {
+	const op = "storage.postgres.DeadWebhookDeliveries"
+
+	query := `
+		SELECT
+			id, subscription_id, event_id, event_type, payload,
+			status, attempts, last_error, last_status_code, created_at
+		FROM webhook_deliveries
+		WHERE subscription_id = $1 AND status = 'dead'
+		ORDER BY created_at, id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, subscriptionID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		if err := rows.Scan(
+			&delivery.ID,
+			&delivery.SubscriptionID,
+			&delivery.EventID,
+			&delivery.EventType,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.LastError,
+			&delivery.LastStatusCode,
+			&delivery.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// RedeliverWebhook moves the delivery of the webhook from the dead-letter list
+// back to the pending ones with the fresh attempts, due at now.
+// If the delivery is not in the dead-letter list of the webhook,
+// it returns storage.ErrDeliveryNotFound.
+func (r *WebhookRepo) RedeliverWebhook(
+	ctx context.Context,
+	subscriptionID string,
+	id string,
+	now time.Time,
+) error {
+	const op = "storage.postgres.RedeliverWebhook"
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $3, attempts = 0, next_attempt_at = $4
+		WHERE id = $1 AND subscription_id = $2 AND status = 'dead'
+	`
+
+	res, err := r.db.ExecContext(ctx, query, id, subscriptionID, models.WebhookPending, now)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrDeliveryNotFound)
+	}
+
+	return nil
+}
+
+// PurgeWebhookDeliveries deletes up to limit deliveries posted before
+// and returns the number of the deliveries deleted.
+// The dead-letter list is kept until the webhook is deleted.
+func (r *WebhookRepo) PurgeWebhookDeliveries(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) (int64, error) {
+	const op = "storage.postgres.PurgeWebhookDeliveries"
+
+	query := `
+		DELETE FROM webhook_deliveries
+		WHERE id IN (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = 'delivered' AND delivered_at < $1
+			LIMIT $2
+		)
+	`
+
+	res, err := r.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	purged, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return purged, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanWebhook scans the row of webhookColumns.
+func scanWebhook(row scanner) (models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := row.Scan(
+		&subscription.ID,
+		&subscription.AppID,
+		&subscription.URL,
+		// the arrays are not supported by database/sql
+		pgtype.NewMap().SQLScanner(&subscription.EventTypes),
+		&subscription.Secret,
+		&subscription.CreatedBy,
+		&subscription.CreatedAt,
+	)
+
+	return subscription, err
+}
@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"notifications/internal/domain/models"
+)
+
+var (
+	ErrPreferencesNotFound = errors.New("preferences not found")
+	ErrNotificationExists  = errors.New("notification already exists")
+	ErrWebhookNotFound     = errors.New("webhook not found")
+	ErrDeliveryNotFound    = errors.New("delivery not found")
+)
+
+type PreferenceStorage interface {
+	Preferences(
+		ctx context.Context,
+		userID int64,
+	) (models.Preferences, error)
+	SavePreferences(
+		ctx context.Context,
+		preferences models.Preferences,
+	) error
+}
+
+type NotificationStorage interface {
+	SaveNotification(
+		ctx context.Context,
+		notification models.Notification,
+		deliveries []models.Delivery,
+	) error
+	ClaimDeliveries(
+		ctx context.Context,
+		now time.Time,
+		lockedUntil time.Time,
+		limit int,
+	) ([]models.Delivery, error)
+	MarkDelivered(
+		ctx context.Context,
+		notificationID string,
+		channel models.Channel,
+		sentAt time.Time,
+	) error
+	MarkDeliveryFailed(
+		ctx context.Context,
+		notificationID string,
+		channel models.Channel,
+		nextAttemptAt time.Time,
+		reason string,
+		final bool,
+	) error
+	ListNotifications(
+		ctx context.Context,
+		userID int64,
+		cursor models.InboxCursor,
+		unreadOnly bool,
+		limit int,
+	) ([]models.Notification, error)
+	NotificationsSince(
+		ctx context.Context,
+		userID int64,
+		since time.Time,
+		limit int,
+	) ([]models.Notification, error)
+	MarkRead(
+		ctx context.Context,
+		userID int64,
+		ids []string,
+		readAt time.Time,
+	) error
+	MarkAllRead(
+		ctx context.Context,
+		userID int64,
+		readAt time.Time,
+	) error
+	UnreadCount(
+		ctx context.Context,
+		userID int64,
+	) (int64, error)
+}
+
+type ReminderStorage interface {
+	ScheduleReminders(
+		ctx context.Context,
+		reminders []models.Reminder,
+	) error
+	RescheduleReminders(
+		ctx context.Context,
+		reminders []models.Reminder,
+		updatedAt time.Time,
+	) error
+	SuspendReminders(
+		ctx context.Context,
+		assignmentID string,
+		suspended bool,
+	) error
+	CancelReminders(
+		ctx context.Context,
+		studentAssignmentID string,
+		cancelledAt time.Time,
+	) error
+	ResumeReminders(
+		ctx context.Context,
+		studentAssignmentID string,
+	) error
+	ClaimReminders(
+		ctx context.Context,
+		now time.Time,
+		lockedUntil time.Time,
+		limit int,
+	) ([]models.Reminder, error)
+	MarkReminderSent(
+		ctx context.Context,
+		studentAssignmentID string,
+		lead time.Duration,
+		sentAt time.Time,
+	) error
+}
+
+type WebhookStorage interface {
+	SaveWebhook(
+		ctx context.Context,
+		subscription models.WebhookSubscription,
+	) (string, error)
+	Webhook(
+		ctx context.Context,
+		id string,
+	) (models.WebhookSubscription, error)
+	Webhooks(
+		ctx context.Context,
+		appID int32,
+	) ([]models.WebhookSubscription, error)
+	DeleteWebhook(
+		ctx context.Context,
+		id string,
+	) error
+	SaveWebhookDeliveries(
+		ctx context.Context,
+		event models.WebhookEvent,
+		now time.Time,
+	) (int64, error)
+	ClaimWebhookDeliveries(
+		ctx context.Context,
+		now time.Time,
+		lockedUntil time.Time,
+		limit int,
+	) ([]models.WebhookDelivery, error)
+	MarkWebhookDelivered(
+		ctx context.Context,
+		id string,
+		statusCode int,
+		deliveredAt time.Time,
+	) error
+	MarkWebhookFailed(
+		ctx context.Context,
+		id string,
+		statusCode int,
+		nextAttemptAt time.Time,
+		reason string,
+		final bool,
+	) error
+	DeadWebhookDeliveries(
+		ctx context.Context,
+		subscriptionID string,
+		limit int,
+		offset int,
+	) ([]models.WebhookDelivery, error)
+	RedeliverWebhook(
+		ctx context.Context,
+		subscriptionID string,
+		id string,
+		now time.Time,
+	) error
+	PurgeWebhookDeliveries(
+		ctx context.Context,
+		before time.Time,
+		limit int,
+	) (int64, error)
+}
+
+type ParkedEventStorage interface {
+	ParkEvent(
+		ctx context.Context,
+		consumer string,
+		subject string,
+		data []byte,
+		reason string,
+		parkedAt time.Time,
+	) error
+}
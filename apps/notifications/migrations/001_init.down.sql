@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_reminders_pending;
+DROP INDEX IF EXISTS idx_reminders_assignment_id;
+DROP TABLE IF EXISTS reminders;
+
+DROP INDEX IF EXISTS idx_deliveries_pending;
+DROP TABLE IF EXISTS deliveries;
+
+DROP INDEX IF EXISTS idx_notifications_inbox;
+DROP TABLE IF EXISTS notifications;
+
+DROP TABLE IF EXISTS preferences;
@@ -0,0 +1,68 @@
+CREATE TABLE IF NOT EXISTS preferences (
+    user_id BIGINT PRIMARY KEY,
+    inbox BOOLEAN NOT NULL DEFAULT TRUE,
+    email BOOLEAN NOT NULL DEFAULT FALSE,
+    email_address VARCHAR(255) NOT NULL DEFAULT '',
+    webhook BOOLEAN NOT NULL DEFAULT FALSE,
+    webhook_url TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    kind VARCHAR(60) NOT NULL,
+    -- the same notification is never created twice,
+    -- e.g. when the event is delivered again by the broker
+    dedupe_key VARCHAR(255) NOT NULL UNIQUE,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    assignment_id VARCHAR(255) NOT NULL DEFAULT '',
+    submission_id VARCHAR(255) NOT NULL DEFAULT '',
+    -- whether the notification is shown in the in-app inbox
+    in_inbox BOOLEAN NOT NULL,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_inbox
+    ON notifications(user_id, created_at DESC) WHERE in_inbox;
+
+-- deliveries of the notifications by email and webhook
+CREATE TABLE IF NOT EXISTS deliveries (
+    notification_id UUID NOT NULL REFERENCES notifications(id) ON DELETE CASCADE,
+    channel VARCHAR(60) NOT NULL,
+    -- the email address or the webhook url taken from the preferences
+    recipient TEXT NOT NULL,
+    status VARCHAR(60) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    -- the worker skips the delivery until this time: while it is being sent
+    -- by another instance or after the failed attempt
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMP,
+    PRIMARY KEY (notification_id, channel)
+);
+CREATE INDEX IF NOT EXISTS idx_deliveries_pending
+    ON deliveries(next_attempt_at) WHERE status = 'pending';
+
+-- reminders of the due dates of the student assignments
+CREATE TABLE IF NOT EXISTS reminders (
+    student_assignment_id VARCHAR(255) NOT NULL,
+    -- how long before the due date the reminder is sent
+    lead_minutes INTEGER NOT NULL,
+    assignment_id VARCHAR(255) NOT NULL,
+    student_id BIGINT NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    due_date TIMESTAMP NOT NULL,
+    send_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    sent_at TIMESTAMP,
+    -- set when the student submits the assignment
+    cancelled_at TIMESTAMP,
+    -- set while the assignment is in the trash
+    suspended BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (student_assignment_id, lead_minutes)
+);
+CREATE INDEX IF NOT EXISTS idx_reminders_assignment_id ON reminders(assignment_id);
+CREATE INDEX IF NOT EXISTS idx_reminders_pending
+    ON reminders(send_at) WHERE sent_at IS NULL AND cancelled_at IS NULL;
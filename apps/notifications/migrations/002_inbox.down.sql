@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_notifications_unread;
+
+DROP INDEX IF EXISTS idx_notifications_inbox;
+CREATE INDEX IF NOT EXISTS idx_notifications_inbox
+    ON notifications(user_id, created_at DESC) WHERE in_inbox;
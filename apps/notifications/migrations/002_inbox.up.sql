@@ -0,0 +1,8 @@
+-- the inbox is paged by (created_at, id)
+DROP INDEX IF EXISTS idx_notifications_inbox;
+CREATE INDEX IF NOT EXISTS idx_notifications_inbox
+    ON notifications(user_id, created_at DESC, id DESC) WHERE in_inbox;
+
+-- unread notifications of the inbox, used for the unread count
+CREATE INDEX IF NOT EXISTS idx_notifications_unread
+    ON notifications(user_id) WHERE in_inbox AND read_at IS NULL;
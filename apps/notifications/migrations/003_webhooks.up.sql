@@ -0,0 +1,40 @@
+-- webhooks of the integrations registered as apps in sso
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id UUID PRIMARY KEY,
+    app_id INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    -- full names of the events, e.g. tasks.events.v1.AssignmentPublished
+    event_types TEXT[] NOT NULL,
+    -- key of the HMAC-SHA256 signature of the deliveries
+    secret VARCHAR(255) NOT NULL,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_app_id ON webhook_subscriptions(app_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_event_types
+    ON webhook_subscriptions USING GIN (event_types);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(255) NOT NULL,
+    -- the body posted to the url
+    payload BYTEA NOT NULL,
+    -- pending, delivered or dead once the attempts are exhausted
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    last_status_code INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    delivered_at TIMESTAMP,
+    -- the event delivered again by the broker is not posted twice
+    UNIQUE (subscription_id, event_id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending
+    ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_dead
+    ON webhook_deliveries(subscription_id, created_at) WHERE status = 'dead';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_delivered_at
+    ON webhook_deliveries(delivered_at) WHERE status = 'delivered';
@@ -0,0 +1,13 @@
+-- events which have failed every delivery by the broker,
+-- kept to be inspected and handled again by hand
+CREATE TABLE IF NOT EXISTS parked_events (
+    id BIGSERIAL PRIMARY KEY,
+    -- the durable consumer which has failed to handle the event
+    consumer VARCHAR(255) NOT NULL,
+    -- full name of the event, e.g. tasks.events.v1.AssignmentUpdated
+    subject VARCHAR(255) NOT NULL,
+    data BYTEA NOT NULL,
+    reason TEXT NOT NULL,
+    parked_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_parked_events_parked_at ON parked_events(parked_at);
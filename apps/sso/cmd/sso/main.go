@@ -34,9 +34,10 @@ func main() {
 		cfg.Database.SSLMode,
 	)
 
-	application := app.New(log, cfg.GRPC.Port, connString, cfg.TokenTTL)
+	application := app.New(log, cfg.GRPC.Port, connString, cfg.TokenTTL, cfg.Events)
 
 	go application.GRPCServer.MustRun()
+	application.Scheduler.Run()
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
@@ -46,6 +47,11 @@ func main() {
 	log.Info("stopping application", slog.String("signal", sysSign.String()))
 
 	application.GRPCServer.Stop()
+	application.Scheduler.Stop()
+
+	if err := application.Broker.Close(); err != nil {
+		log.Error("failed to close broker", slog.Any("error", err))
+	}
 
 	log.Info("application stopped")
 }
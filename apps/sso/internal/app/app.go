@@ -1,17 +1,44 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"time"
 
 	grpcapp "sso/internal/app/grpc"
+	"sso/internal/config"
+	"sso/internal/domain/models"
 	"sso/internal/services/auth"
 	"sso/internal/storage"
 	"sso/internal/storage/postgres"
+
+	"github.com/Kaptoshka/creative-learning-platform/libs/platform/broker/memory"
+	"github.com/Kaptoshka/creative-learning-platform/libs/platform/broker/nats"
+	"github.com/Kaptoshka/creative-learning-platform/libs/platform/outbox"
+	schedulerapp "github.com/Kaptoshka/creative-learning-platform/libs/platform/scheduler"
+)
+
+const (
+	// streamName is the JetStream stream keeping the events of the SSO service.
+	streamName = "SSO_EVENTS"
+	// subjects matches the subjects of every event, which are the full names of the payloads.
+	subjects = "sso.events.>"
+
+	// revokedTokensPurgeInterval is how often the revoked tokens which have expired are deleted.
+	revokedTokensPurgeInterval = time.Hour
 )
 
 type App struct {
 	GRPCServer *grpcapp.App
+	Scheduler  *schedulerapp.App
+	Broker     Broker
+}
+
+// Broker is the message broker the domain events are published to.
+type Broker interface {
+	outbox.Publisher[models.OutboxEvent]
+	Close() error
 }
 
 type AuthUserStorageAdapter struct {
@@ -25,22 +52,81 @@ func New(
 	grpcPort int,
 	connString string,
 	tokenTTL time.Duration,
+	eventsCfg config.EventsConfig,
 ) *App {
 	client, err := postgres.New(connString)
 	if err != nil {
 		return nil
 	}
 
+	broker, err := newBroker(eventsCfg)
+	if err != nil {
+		log.Error("failed to create broker", slog.Any("error", err))
+
+		return nil
+	}
+
 	userStorageAdapter := &AuthUserStorageAdapter{
 		UserStorage: client.UserStorage,
 		RoleStorage: client.RoleStorage,
 	}
 
-	authService := auth.New(log, userStorageAdapter, userStorageAdapter, client.AppStorage, tokenTTL)
+	authService := auth.New(
+		log,
+		userStorageAdapter,
+		userStorageAdapter,
+		client.AppStorage,
+		client.TokenStorage,
+		tokenTTL,
+	)
+
+	outboxService := outbox.New[models.OutboxEvent](log, client.OutboxStorage, client.OutboxStorage, broker)
 
 	grpcApp := grpcapp.New(log, authService, grpcPort)
 
+	scheduler := schedulerapp.New(
+		log,
+		schedulerapp.Job{
+			Name:     "relay_outbox_events",
+			Interval: eventsCfg.RelayInterval,
+			Run:      outboxService.Relay,
+		},
+		schedulerapp.Job{
+			Name:     "purge_published_events",
+			Interval: eventsCfg.PurgeInterval,
+			Run: func(ctx context.Context) error {
+				return outboxService.PurgePublished(ctx, eventsCfg.Retention)
+			},
+		},
+		schedulerapp.Job{
+			Name:     "purge_revoked_tokens",
+			Interval: revokedTokensPurgeInterval,
+			Run:      authService.PurgeRevokedTokens,
+		},
+	)
+
 	return &App{
 		GRPCServer: grpcApp,
+		Scheduler:  scheduler,
+		Broker:     broker,
+	}
+}
+
+// newBroker creates the message broker of the domain events chosen in the config.
+func newBroker(cfg config.EventsConfig) (Broker, error) {
+	switch cfg.Broker {
+	case "memory":
+		return memory.New[models.OutboxEvent](), nil
+	case "nats":
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		return nats.New[models.OutboxEvent](ctx, cfg.NATSURL, "sso", nats.Stream{
+			Name:      streamName,
+			Subjects:  subjects,
+			Retention: cfg.Retention,
+		})
+	default:
+		return nil, fmt.Errorf("unknown events broker %q", cfg.Broker)
 	}
 }
@@ -13,6 +13,7 @@ type Config struct {
 	Database Database      `yaml:"database"`
 	TokenTTL time.Duration `yaml:"token_ttl" env-required:"true"`
 	GRPC     GRPCConfig    `yaml:"grpc"`
+	Events   EventsConfig  `yaml:"events"`
 }
 
 type GRPCConfig struct {
@@ -20,6 +21,17 @@ type GRPCConfig struct {
 	Timeout time.Duration `yaml:"timeout"`
 }
 
+type EventsConfig struct {
+	// Broker is the message broker the domain events are published to: memory or nats.
+	Broker  string `yaml:"broker" env-default:"memory"`
+	NATSURL string `yaml:"nats_url" env-default:"nats://localhost:4222"`
+	// RelayInterval is how often the events written to the outbox are published.
+	RelayInterval time.Duration `yaml:"relay_interval" env-default:"1s"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
+	// Retention is how long the published events are kept in the outbox and in the stream.
+	Retention time.Duration `yaml:"retention" env-default:"168h"`
+}
+
 type Database struct {
 	Host     string `yaml:"host" env-required:"true"`
 	Port     int    `yaml:"port" env-required:"true"`
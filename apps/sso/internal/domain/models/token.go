@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// TokenClaims are the verified claims of the access token.
+type TokenClaims struct {
+	// ID is the unique id of the token, it is used to revoke the token on logout.
+	ID        string
+	UserID    int64
+	Email     string
+	Role      string
+	Scope     []string
+	AppID     int
+	ExpiresAt time.Time
+}
@@ -8,7 +8,8 @@ const (
 )
 
 const (
-	RoleAdmin   = "admin"
-	RoleStudent = "student"
-	RoleTeacher = "teacher"
+	RoleAdmin       = "admin"
+	RoleStudent     = "student"
+	RoleTeacher     = "teacher"
+	RoleHeadTeacher = "head_teacher"
 )
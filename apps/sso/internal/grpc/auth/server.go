@@ -3,12 +3,16 @@ package auth
 import (
 	"context"
 	"errors"
+	"strings"
 
+	"sso/internal/domain/models"
 	"sso/internal/services/auth"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	ssov1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/sso/v1"
 )
@@ -31,7 +35,16 @@ type Auth interface {
 		firstName string,
 		lastName string,
 		middleName string,
+		appID int,
 	) (userID int64, err error)
+	Logout(
+		ctx context.Context,
+		token string,
+	) error
+	Introspect(
+		ctx context.Context,
+		token string,
+	) (models.TokenClaims, error)
 }
 
 type serverAPI struct {
@@ -82,6 +95,7 @@ func (s *serverAPI) Register(
 		req.GetFirstName(),
 		req.GetLastName(),
 		req.GetMiddleName(),
+		int(req.GetAppId()),
 	)
 
 	if err != nil {
@@ -89,6 +103,10 @@ func (s *serverAPI) Register(
 			return nil, status.Error(codes.AlreadyExists, "user already exists")
 		}
 
+		if errors.Is(err, auth.ErrInvalidAppID) {
+			return nil, status.Error(codes.InvalidArgument, "invalid app_id")
+		}
+
 		return nil, status.Error(codes.Internal, "internal error")
 	}
 
@@ -97,6 +115,86 @@ func (s *serverAPI) Register(
 	}, nil
 }
 
+// Logout implements logout of the user in SSO.
+// It revokes the token from the request or, if it is empty,
+// the bearer token of the request metadata.
+func (s *serverAPI) Logout(
+	ctx context.Context,
+	req *ssov1.LogoutRequest,
+) (*ssov1.LogoutResponse, error) {
+	token := req.GetToken()
+	if token == "" {
+		token = bearerToken(ctx)
+	}
+
+	if token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	if err := s.auth.Logout(ctx, token); err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to logout")
+	}
+
+	return &ssov1.LogoutResponse{
+		Success: true,
+	}, nil
+}
+
+// Introspect implements verification of the access token in SSO.
+// The invalid token is not an error, it is reported as inactive.
+func (s *serverAPI) Introspect(
+	ctx context.Context,
+	req *ssov1.IntrospectRequest,
+) (*ssov1.IntrospectResponse, error) {
+	if req.GetToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	claims, err := s.auth.Introspect(ctx, req.GetToken())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return &ssov1.IntrospectResponse{Active: false}, nil
+		}
+
+		return nil, status.Error(codes.Internal, "failed to introspect token")
+	}
+
+	return &ssov1.IntrospectResponse{
+		Active:    true,
+		UserId:    claims.UserID,
+		Email:     claims.Email,
+		Role:      claims.Role,
+		Scope:     claims.Scope,
+		AppId:     int32(claims.AppID),
+		ExpiresAt: timestamppb.New(claims.ExpiresAt),
+	}, nil
+}
+
+// bearerToken returns the bearer token of the authorization metadata
+// or the empty string if there is none.
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ""
+	}
+
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
 // validateLogin validates the login request
 // Email, password and AppId must be provided.
 // If not it returns an error.
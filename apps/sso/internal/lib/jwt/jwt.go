@@ -1,14 +1,24 @@
 package jwt
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 
 	"sso/internal/domain/models"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 )
 
+// ErrInvalidToken is returned when the token is malformed,
+// has the wrong signature or has expired.
+var ErrInvalidToken = errors.New("invalid token")
+
+// SecretFunc returns the secret of the app the token was issued for.
+type SecretFunc func(appID int) (string, error)
+
 // GenerateNewToken generates a new JWT token
 // for the given user, app, duration, role, and permission scope.
 func GenerateNewToken(
@@ -22,8 +32,10 @@ func GenerateNewToken(
 
 	claims := token.Claims.(jwt.MapClaims)
 
+	claims["jti"] = uuid.NewString()
 	claims["sub"] = user.ID
 	claims["email"] = user.Email
+	claims["app_id"] = app.ID
 	claims["iat"] = time.Now().Unix()
 	claims["exp"] = time.Now().Add(duration).Unix()
 	claims["role"] = role
@@ -36,3 +48,68 @@ func GenerateNewToken(
 
 	return tokenString, nil
 }
+
+// ParseToken verifies the signature and the expiration of the token
+// with the secret of the app the token was issued for and returns its claims.
+//
+// If the token is not valid, returns ErrInvalidToken.
+// The errors of the secret function are returned as is.
+func ParseToken(tokenString string, secret SecretFunc) (models.TokenClaims, error) {
+	var secretErr error
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			return nil, errors.New("unexpected claims")
+		}
+
+		appID, ok := claims["app_id"].(float64)
+		if !ok {
+			return nil, errors.New("app_id claim is missing")
+		}
+
+		key, err := secret(int(appID))
+		if err != nil {
+			secretErr = err
+
+			return nil, err
+		}
+
+		return []byte(key), nil
+	})
+	if secretErr != nil {
+		return models.TokenClaims{}, secretErr
+	}
+	if err != nil || !token.Valid {
+		return models.TokenClaims{}, ErrInvalidToken
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+
+	id, _ := claims["jti"].(string)
+	userID, _ := claims["sub"].(float64)
+	email, _ := claims["email"].(string)
+	appID, _ := claims["app_id"].(float64)
+	role, _ := claims["role"].(string)
+	scope, _ := claims["scope"].(string)
+
+	// the expiration is required, the library accepts the tokens without it
+	exp, ok := claims["exp"].(float64)
+	if !ok || id == "" || userID == 0 {
+		return models.TokenClaims{}, ErrInvalidToken
+	}
+
+	return models.TokenClaims{
+		ID:        id,
+		UserID:    int64(userID),
+		Email:     email,
+		Role:      role,
+		Scope:     strings.Fields(scope),
+		AppID:     int(appID),
+		ExpiresAt: time.Unix(int64(exp), 0).UTC(),
+	}, nil
+}
@@ -0,0 +1,84 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"sso/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseToken(t *testing.T) {
+	user := models.User{ID: 42, Email: "student@example.com"}
+	app := models.App{ID: 1, Name: "test", Secret: "test-secret"}
+
+	secret := func(appID int) (string, error) {
+		if appID != app.ID {
+			return "", errors.New("unknown app")
+		}
+		return app.Secret, nil
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		token, err := GenerateNewToken(user, app, time.Hour, "student", []string{"tasks:read", "tasks:solve"})
+		require.NoError(t, err)
+
+		claims, err := ParseToken(token, secret)
+		require.NoError(t, err)
+
+		assert.NotEmpty(t, claims.ID)
+		assert.Equal(t, int64(42), claims.UserID)
+		assert.Equal(t, "student@example.com", claims.Email)
+		assert.Equal(t, "student", claims.Role)
+		assert.Equal(t, []string{"tasks:read", "tasks:solve"}, claims.Scope)
+		assert.Equal(t, 1, claims.AppID)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), claims.ExpiresAt, 5*time.Second)
+	})
+
+	t.Run("unique ids", func(t *testing.T) {
+		first, err := GenerateNewToken(user, app, time.Hour, "student", nil)
+		require.NoError(t, err)
+		second, err := GenerateNewToken(user, app, time.Hour, "student", nil)
+		require.NoError(t, err)
+
+		firstClaims, err := ParseToken(first, secret)
+		require.NoError(t, err)
+		secondClaims, err := ParseToken(second, secret)
+		require.NoError(t, err)
+
+		assert.NotEqual(t, firstClaims.ID, secondClaims.ID)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		token, err := GenerateNewToken(user, app, -time.Minute, "student", nil)
+		require.NoError(t, err)
+
+		_, err = ParseToken(token, secret)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		token, err := GenerateNewToken(user, models.App{ID: 1, Secret: "other-secret"}, time.Hour, "student", nil)
+		require.NoError(t, err)
+
+		_, err = ParseToken(token, secret)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := ParseToken("not.a.token", secret)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("secret error", func(t *testing.T) {
+		token, err := GenerateNewToken(user, models.App{ID: 2, Secret: "test-secret"}, time.Hour, "student", nil)
+		require.NoError(t, err)
+
+		_, err = ParseToken(token, secret)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrInvalidToken)
+	})
+}
@@ -20,6 +20,7 @@ type Auth struct {
 	userSaver    UserSaver
 	userProvider UserProvider
 	appProvider  AppProvider
+	tokenRevoker TokenRevoker
 	tokenTTL     time.Duration
 }
 
@@ -31,6 +32,7 @@ type UserSaver interface {
 		firstName string,
 		lastName string,
 		middleName string,
+		appID int,
 	) (int64, error)
 	LinkUserRole(
 		ctx context.Context,
@@ -50,10 +52,22 @@ type AppProvider interface {
 	App(ctx context.Context, appID int) (models.App, error)
 }
 
+type TokenRevoker interface {
+	RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+	TokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	PurgeRevokedTokens(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// purgeBatchSize limits the number of revoked tokens deleted at once.
+const purgeBatchSize = 1000
+
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrInvalidAppID       = errors.New("invalid app id")
 	ErrUserExists         = storage.ErrUserExists
+	// ErrInvalidToken is returned for the token which is malformed,
+	// expired or revoked.
+	ErrInvalidToken = errors.New("invalid token")
 )
 
 // New returns a new instance of Auth service
@@ -62,6 +76,7 @@ func New(
 	userSaver UserSaver,
 	userProvider UserProvider,
 	appProvider AppProvider,
+	tokenRevoker TokenRevoker,
 	tokenTTL time.Duration,
 ) *Auth {
 	return &Auth{
@@ -69,6 +84,7 @@ func New(
 		userSaver:    userSaver,
 		userProvider: userProvider,
 		appProvider:  appProvider,
+		tokenRevoker: tokenRevoker,
 		tokenTTL:     tokenTTL,
 	}
 }
@@ -161,6 +177,9 @@ func (a *Auth) Login(
 }
 
 // RegisterNewUser service layer function that implements user registration
+//
+// The appID is the app the user signs up through, or zero if none.
+// If the app does not exist, returns ErrInvalidAppID.
 func (a *Auth) RegisterNewUser(
 	ctx context.Context,
 	email string,
@@ -168,6 +187,7 @@ func (a *Auth) RegisterNewUser(
 	firstName string,
 	lastName string,
 	middleName string,
+	appID int,
 ) (int64, error) {
 	const op = "services.auth.RegisterNewUser"
 
@@ -177,6 +197,20 @@ func (a *Auth) RegisterNewUser(
 
 	log.Debug("registering new user")
 
+	if appID != 0 {
+		if _, err := a.appProvider.App(ctx, appID); err != nil {
+			if errors.Is(err, storage.ErrAppNotFound) {
+				log.Warn("app not found", slog.Any("error", err))
+
+				return 0, fmt.Errorf("%s: %w", op, ErrInvalidAppID)
+			}
+
+			log.Error("failed to get app", slog.Any("error", err))
+
+			return 0, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
 	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		log.Error("failed to generate password hash", slog.Any("error", err))
@@ -187,7 +221,7 @@ func (a *Auth) RegisterNewUser(
 	log.Debug("password hash generated")
 
 	id, err := a.userSaver.SaveUser(
-		ctx, email, passHash, firstName, lastName, middleName,
+		ctx, email, passHash, firstName, lastName, middleName, appID,
 	)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserExists) {
@@ -225,3 +259,141 @@ func (a *Auth) RegisterNewUser(
 
 	return id, nil
 }
+
+// Logout revokes the token, so it is no longer accepted
+// even though it has not expired yet.
+//
+// If the token is not valid, returns ErrInvalidToken.
+func (a *Auth) Logout(ctx context.Context, token string) error {
+	const op = "services.auth.Logout"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	log.Debug("attempting to logout user")
+
+	claims, err := a.verifyToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			log.Warn("invalid token", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to verify token", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := a.tokenRevoker.RevokeToken(ctx, claims.ID, claims.ExpiresAt); err != nil {
+		log.Error("failed to revoke token", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	log.Info("user logged out", slog.Int64("user_id", claims.UserID))
+
+	return nil
+}
+
+// Introspect verifies the token and returns its claims.
+// It is used by the other services to identify the caller.
+//
+// If the token is malformed, expired or revoked, returns ErrInvalidToken.
+func (a *Auth) Introspect(ctx context.Context, token string) (models.TokenClaims, error) {
+	const op = "services.auth.Introspect"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	claims, err := a.verifyToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			log.Debug("invalid token", slog.Any("error", err))
+
+			return models.TokenClaims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to verify token", slog.Any("error", err))
+
+		return models.TokenClaims{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	revoked, err := a.tokenRevoker.TokenRevoked(ctx, claims.ID)
+	if err != nil {
+		log.Error("failed to check token revocation", slog.Any("error", err))
+
+		return models.TokenClaims{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if revoked {
+		log.Debug("token revoked")
+
+		return models.TokenClaims{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+// PurgeRevokedTokens permanently deletes the revoked tokens which have expired,
+// they are rejected by the expiration anyway.
+// It is run periodically by the scheduler.
+func (a *Auth) PurgeRevokedTokens(ctx context.Context) error {
+	const op = "services.auth.PurgeRevokedTokens"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	before := time.Now().UTC()
+
+	var total int64
+	for {
+		purged, err := a.tokenRevoker.PurgeRevokedTokens(ctx, before, purgeBatchSize)
+		if err != nil {
+			log.Error("failed to purge revoked tokens", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		total += purged
+
+		if purged < purgeBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Info("expired revoked tokens purged", slog.Int64("count", total))
+	}
+
+	return nil
+}
+
+// verifyToken verifies the token with the secret of the app it was issued for.
+// If the token is not valid or the app is unknown, returns ErrInvalidToken.
+func (a *Auth) verifyToken(ctx context.Context, token string) (models.TokenClaims, error) {
+	claims, err := jwt.ParseToken(token, func(appID int) (string, error) {
+		app, err := a.appProvider.App(ctx, appID)
+		if err != nil {
+			if errors.Is(err, storage.ErrAppNotFound) {
+				return "", ErrInvalidToken
+			}
+
+			return "", err
+		}
+
+		return app.Secret, nil
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrInvalidToken) {
+			return models.TokenClaims{}, ErrInvalidToken
+		}
+
+		return models.TokenClaims{}, err
+	}
+
+	return claims, nil
+}
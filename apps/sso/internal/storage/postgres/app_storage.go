@@ -46,7 +46,7 @@ func (s *PostgresAppStorage) App(
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return models.App{}, fmt.Errorf("%s: %v", op, storage.ErrAppNotFound)
+			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
 		}
 
 		return models.App{}, fmt.Errorf("%s: %v", op, err)
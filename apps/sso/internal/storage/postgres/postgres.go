@@ -14,6 +14,8 @@ type Storage struct {
 	storage.UserStorage
 	storage.RoleStorage
 	storage.AppStorage
+	storage.OutboxStorage
+	storage.TokenStorage
 }
 
 // New creates a new instance of PostgreSQL storage
@@ -32,10 +34,12 @@ func New(connString string) (*Storage, error) {
 	}
 
 	return &Storage{
-		db:          db,
-		UserStorage: NewUserStorage(db),
-		RoleStorage: NewRoleStorage(db),
-		AppStorage:  NewAppStorage(db),
+		db:            db,
+		UserStorage:   NewUserStorage(db),
+		RoleStorage:   NewRoleStorage(db),
+		AppStorage:    NewAppStorage(db),
+		OutboxStorage: NewOutboxStorage(db),
+		TokenStorage:  NewTokenStorage(db),
 	}, nil
 }
 
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type PostgresTokenStorage struct {
+	db *sql.DB
+}
+
+// NewTokenStorage creates a new instance of PostgresTokenStorage.
+// That used to interact with the revoked_tokens table.
+func NewTokenStorage(db *sql.DB) *PostgresTokenStorage {
+	return &PostgresTokenStorage{
+		db: db,
+	}
+}
+
+// RevokeToken saves the token as revoked until it expires.
+// Revoking the token which is already revoked is not an error.
+func (s *PostgresTokenStorage) RevokeToken(
+	ctx context.Context,
+	tokenID string,
+	expiresAt time.Time,
+) error {
+	const op = "storage.postgres.RevokeToken"
+
+	query := `
+		INSERT INTO revoked_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	_, err := s.db.ExecContext(ctx, query, tokenID, expiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// TokenRevoked reports whether the token with the given ID is revoked.
+func (s *PostgresTokenStorage) TokenRevoked(
+	ctx context.Context,
+	tokenID string,
+) (bool, error) {
+	const op = "storage.postgres.TokenRevoked"
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM revoked_tokens
+			WHERE jti = $1
+		)
+	`
+
+	var revoked bool
+
+	err := s.db.QueryRowContext(ctx, query, tokenID).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return revoked, nil
+}
+
+// PurgeRevokedTokens permanently deletes up to limit revoked tokens
+// which expired before the given time.
+// It returns the number of deleted tokens.
+func (s *PostgresTokenStorage) PurgeRevokedTokens(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) (int64, error) {
+	const op = "storage.postgres.PurgeRevokedTokens"
+
+	query := `
+		DELETE FROM revoked_tokens
+		WHERE jti IN (
+			SELECT jti
+			FROM revoked_tokens
+			WHERE expires_at < $1
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+
+	res, err := s.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return affected, nil
+}
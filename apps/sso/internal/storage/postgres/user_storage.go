@@ -5,11 +5,16 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"sso/internal/domain/models"
 	"sso/internal/storage"
 
 	pgConn "github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	ssoeventsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/sso/events/v1"
 )
 
 type PostgresUserStorage struct {
@@ -25,6 +30,8 @@ func NewUserStorage(db *sql.DB) *PostgresUserStorage {
 }
 
 // SaveUser saves a new user to the database.
+// The registration is announced with the UserRegistered event
+// to the app the user has signed up through.
 func (s *PostgresUserStorage) SaveUser(
 	ctx context.Context,
 	email string,
@@ -32,18 +39,28 @@ func (s *PostgresUserStorage) SaveUser(
 	firstName string,
 	lastName string,
 	middleName string,
+	appID int,
 ) (int64, error) {
 	const op = "storage.postgres.SaveUser"
 
-	var id int64
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var (
+		id        int64
+		createdAt time.Time
+	)
 
 	query := `
 		INSERT INTO users
 		(email, pass_hash, first_name, last_name, middle_name)
 		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id
+		RETURNING id, created_at
 		`
-	err := s.db.QueryRowContext(ctx, query, email, passHash, firstName, lastName, middleName).Scan(&id)
+	err = tx.QueryRowContext(ctx, query, email, passHash, firstName, lastName, middleName).Scan(&id, &createdAt)
 	if err != nil {
 		var pgErr *pgConn.PgError
 
@@ -54,6 +71,24 @@ func (s *PostgresUserStorage) SaveUser(
 		return 0, fmt.Errorf("%s: %v", op, err)
 	}
 
+	userID := strconv.FormatInt(id, 10)
+
+	err = insertEvent(ctx, tx, userID, int32(appID), createdAt, &ssoeventsv1.UserRegistered{
+		UserId:       userID,
+		Email:        email,
+		FirstName:    firstName,
+		LastName:     lastName,
+		MiddleName:   middleName,
+		RegisteredAt: timestamppb.New(createdAt),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
 	return id, nil
 }
 
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"errors"
+	"time"
 
 	"sso/internal/domain/models"
 )
@@ -22,6 +23,7 @@ type UserStorage interface {
 		firstName string,
 		lastName string,
 		middleName string,
+		appID int,
 	) (int64, error)
 	User(
 		ctx context.Context,
@@ -55,3 +57,45 @@ type AppStorage interface {
 		appID int,
 	) (models.App, error)
 }
+
+type OutboxStorage interface {
+	ClaimEvents(
+		ctx context.Context,
+		now time.Time,
+		lockedUntil time.Time,
+		limit int,
+	) ([]models.OutboxEvent, error)
+	MarkPublished(
+		ctx context.Context,
+		eventID string,
+		publishedAt time.Time,
+	) error
+	MarkFailed(
+		ctx context.Context,
+		eventID string,
+		nextAttemptAt time.Time,
+		reason string,
+	) error
+	PurgePublished(
+		ctx context.Context,
+		before time.Time,
+		limit int,
+	) (int64, error)
+}
+
+type TokenStorage interface {
+	RevokeToken(
+		ctx context.Context,
+		tokenID string,
+		expiresAt time.Time,
+	) error
+	TokenRevoked(
+		ctx context.Context,
+		tokenID string,
+	) (bool, error)
+	PurgeRevokedTokens(
+		ctx context.Context,
+		before time.Time,
+		limit int,
+	) (int64, error)
+}
@@ -0,0 +1 @@
+DELETE FROM roles WHERE role = 'head_teacher';
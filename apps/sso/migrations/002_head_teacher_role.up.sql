@@ -0,0 +1,2 @@
+-- the head teacher moderates the grades of other teachers
+INSERT INTO roles (role) VALUES ('head_teacher') ON CONFLICT (role) DO NOTHING;
@@ -0,0 +1,21 @@
+-- domain events written in the same transaction as the changes they describe
+-- and published to the broker by the relay
+CREATE TABLE IF NOT EXISTS outbox (
+    id UUID PRIMARY KEY,
+    -- the order in which the events were written
+    seq BIGSERIAL NOT NULL,
+    event_type VARCHAR(255) NOT NULL,
+    aggregate_id VARCHAR(255) NOT NULL,
+    payload BYTEA NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    -- the relay skips the event until this time: while it is being published
+    -- by another instance or after the failed attempt
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    published_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending
+    ON outbox(aggregate_id, seq) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_published_at
+    ON outbox(published_at) WHERE published_at IS NOT NULL;
@@ -0,0 +1,7 @@
+-- the tokens revoked on logout, they are kept only until they expire
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti VARCHAR(255) PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at
+    ON revoked_tokens(expires_at);
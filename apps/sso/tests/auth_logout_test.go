@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"sso/tests/suite"
+
+	ssov1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/sso/v1"
+	"github.com/brianvoe/gofakeit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestLogout_RevokesToken(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	email := gofakeit.Email()
+	pass := randomFakePassword()
+
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:     email,
+		Password:  pass,
+		FirstName: gofakeit.FirstName(),
+		LastName:  gofakeit.LastName(),
+	})
+	require.NoError(t, err)
+
+	respLogin, err := st.AuthClient.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: pass,
+		AppId:    appID,
+	})
+	require.NoError(t, err)
+
+	token := respLogin.GetToken()
+
+	respIntrospect, err := st.AuthClient.Introspect(ctx, &ssov1.IntrospectRequest{Token: token})
+	require.NoError(t, err)
+	require.True(t, respIntrospect.GetActive())
+	assert.Equal(t, respReg.GetUserId(), respIntrospect.GetUserId())
+	assert.Equal(t, email, respIntrospect.GetEmail())
+	assert.Equal(t, "student", respIntrospect.GetRole())
+	assert.Equal(t, int32(appID), respIntrospect.GetAppId())
+	assert.WithinDuration(t, time.Now().Add(st.Cfg.TokenTTL), respIntrospect.GetExpiresAt().AsTime(), 10*time.Second)
+
+	// the token is taken from the metadata when it is not in the request
+	logoutCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+
+	respLogout, err := st.AuthClient.Logout(logoutCtx, &ssov1.LogoutRequest{})
+	require.NoError(t, err)
+	assert.True(t, respLogout.GetSuccess())
+
+	respIntrospect, err = st.AuthClient.Introspect(ctx, &ssov1.IntrospectRequest{Token: token})
+	require.NoError(t, err)
+	assert.False(t, respIntrospect.GetActive())
+	assert.Empty(t, respIntrospect.GetUserId())
+
+	// logout is idempotent
+	respLogout, err = st.AuthClient.Logout(ctx, &ssov1.LogoutRequest{Token: token})
+	require.NoError(t, err)
+	assert.True(t, respLogout.GetSuccess())
+}
+
+func TestIntrospect_InvalidToken(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	respIntrospect, err := st.AuthClient.Introspect(ctx, &ssov1.IntrospectRequest{Token: "not.a.token"})
+	require.NoError(t, err)
+	assert.False(t, respIntrospect.GetActive())
+
+	_, err = st.AuthClient.Introspect(ctx, &ssov1.IntrospectRequest{})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "token is required")
+}
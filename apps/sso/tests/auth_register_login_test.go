@@ -259,6 +259,30 @@ func TestLogin_FailCases(t *testing.T) {
 	}
 }
 
+func TestRegister_App(t *testing.T) {
+	ctx, st := suite.New(t)
+
+	respReg, err := st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:     gofakeit.Email(),
+		Password:  randomFakePassword(),
+		FirstName: gofakeit.FirstName(),
+		LastName:  gofakeit.LastName(),
+		AppId:     appID,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, respReg.GetUserId())
+
+	_, err = st.AuthClient.Register(ctx, &ssov1.RegisterRequest{
+		Email:     gofakeit.Email(),
+		Password:  randomFakePassword(),
+		FirstName: gofakeit.FirstName(),
+		LastName:  gofakeit.LastName(),
+		AppId:     appID + 1000,
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid app_id")
+}
+
 func randomFakePassword() string {
 	return gofakeit.Password(true, true, true, true, false, passDefaultLen)
 }
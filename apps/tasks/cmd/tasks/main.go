@@ -1 +1,86 @@
 package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"tasks/internal/app"
+	"tasks/internal/config"
+)
+
+const (
+	envLocal = "local"
+	envDev   = "dev"
+	envProd  = "prod"
+)
+
+func main() {
+	cfg := config.MustLoad()
+
+	log := setupLogger(cfg.Env)
+
+	log.Info("starting application")
+
+	connString := fmt.Sprintf(
+		"postgres://%s:%s@%s:%d/%s?sslmode=%s",
+		cfg.Database.User,
+		cfg.Database.Password,
+		cfg.Database.Host,
+		cfg.Database.Port,
+		cfg.Database.Name,
+		cfg.Database.SSLMode,
+	)
+
+	application := app.New(
+		log,
+		cfg.GRPC.Port,
+		connString,
+		cfg.Scheduler,
+		cfg.Attachments,
+		cfg.Grading,
+		cfg.Events,
+	)
+
+	go application.GRPCServer.MustRun()
+	application.Scheduler.Run()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+
+	sysSign := <-stop
+
+	log.Info("stopping application", slog.String("signal", sysSign.String()))
+
+	application.GRPCServer.Stop()
+	application.Scheduler.Stop()
+
+	if err := application.Broker.Close(); err != nil {
+		log.Error("failed to close broker", slog.Any("error", err))
+	}
+
+	log.Info("application stopped")
+}
+
+// setupLogger creates a new logger instance based on the environment.
+func setupLogger(env string) *slog.Logger {
+	var log *slog.Logger
+	switch env {
+	case envLocal:
+		log = slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		)
+	case envDev:
+		log = slog.New(
+			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		)
+	case envProd:
+		log = slog.New(
+			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		)
+	}
+
+	return log
+}
@@ -1,34 +1,318 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
+	"time"
 
 	grpcapp "tasks/internal/app/grpc"
+	"tasks/internal/config"
+	"tasks/internal/domain/models"
+	"tasks/internal/services/activity"
 	"tasks/internal/services/assignment"
+	"tasks/internal/services/attachment"
+	"tasks/internal/services/calendar"
+	"tasks/internal/services/comment"
+	"tasks/internal/services/course"
+	"tasks/internal/services/grading"
+	"tasks/internal/services/moderation"
+	"tasks/internal/services/peerreview"
+	"tasks/internal/services/search"
+	"tasks/internal/services/similarity"
 	"tasks/internal/services/submission"
+	"tasks/internal/services/template"
+	"tasks/internal/storage"
+	"tasks/internal/storage/blob/local"
+	"tasks/internal/storage/blob/s3"
 	"tasks/internal/storage/postgres"
+
+	"github.com/Kaptoshka/creative-learning-platform/libs/platform/broker/memory"
+	"github.com/Kaptoshka/creative-learning-platform/libs/platform/broker/nats"
+	"github.com/Kaptoshka/creative-learning-platform/libs/platform/outbox"
+	schedulerapp "github.com/Kaptoshka/creative-learning-platform/libs/platform/scheduler"
+)
+
+const (
+	// streamName is the JetStream stream keeping the events of the tasks service.
+	streamName = "TASKS_EVENTS"
+	// subjects matches the subjects of every event, which are the full names of the payloads.
+	subjects = "tasks.events.>"
 )
 
 type App struct {
 	GRPCServer *grpcapp.App
+	Scheduler  *schedulerapp.App
+	Broker     Broker
+}
+
+// Broker is the message broker the domain events are published to.
+type Broker interface {
+	outbox.Publisher[models.OutboxEvent]
+	Close() error
 }
 
+// New creates a new instance of the App struct.
 func New(
 	log *slog.Logger,
 	grpcPort int,
 	connString string,
+	schedulerCfg config.SchedulerConfig,
+	attachmentsCfg config.AttachmentsConfig,
+	gradingCfg config.GradingConfig,
+	eventsCfg config.EventsConfig,
 ) *App {
 	client, err := postgres.New(connString)
 	if err != nil {
 		return nil
 	}
 
-	assignmentService := assignment.New(log, client.AssignmentStorage)
-	submissionService := submission.New(log, client.SubmissionStorage)
+	blobs, err := newBlobStore(attachmentsCfg)
+	if err != nil {
+		log.Error("failed to create blob store", slog.Any("error", err))
+
+		return nil
+	}
+
+	broker, err := newBroker(eventsCfg)
+	if err != nil {
+		log.Error("failed to create broker", slog.Any("error", err))
+
+		return nil
+	}
+
+	assignmentService := assignment.New(
+		log,
+		client.AssignmentStorage,
+		client.AssignmentStorage,
+		client.TemplateStorage,
+		client.TemplateStorage,
+		client.WidgetStorage,
+	)
+	submissionService := submission.New(
+		log,
+		client.SubmissionStorage,
+		client.SubmissionStorage,
+		client.AttachmentStorage,
+		client.AssignmentStorage,
+	)
+	attachmentService := attachment.New(
+		log,
+		client.AttachmentStorage,
+		client.AttachmentStorage,
+		client.SubmissionStorage,
+		client.PeerReviewStorage,
+		blobs,
+		attachmentsCfg.MaxFileSize,
+		attachmentsCfg.StudentQuota,
+		attachmentsCfg.PreviewBaseURL,
+	)
+	templateService := template.New(
+		log,
+		client.TemplateStorage,
+		client.TemplateStorage,
+		client.WidgetStorage,
+	)
+
+	courseService := course.New(
+		log,
+		client.CourseStorage,
+		client.CourseStorage,
+		client.TemplateStorage,
+		client.RubricStorage,
+		client.WidgetStorage,
+	)
+
+	similarityService := similarity.New(
+		log,
+		client.SimilarityStorage,
+		client.SimilarityStorage,
+		client.AssignmentStorage,
+	)
 
-	grpcApp := grpcapp.New(log, assignmentService, submissionService, grpcPort)
+	peerReviewService := peerreview.New(
+		log,
+		client.PeerReviewStorage,
+		client.PeerReviewStorage,
+		client.AssignmentStorage,
+		client.SubmissionStorage,
+	)
+
+	commentService := comment.New(
+		log,
+		client.CommentStorage,
+		client.CommentStorage,
+		client.SubmissionStorage,
+		client.AttachmentStorage,
+	)
+
+	gradingService := grading.New(
+		log,
+		client.GradingStorage,
+		client.GradingStorage,
+		client.SubmissionStorage,
+		client.ModerationStorage,
+		gradingCfg.RegradeWindow,
+	)
+
+	moderationService := moderation.New(
+		log,
+		client.ModerationStorage,
+		client.ModerationStorage,
+		client.AssignmentStorage,
+	)
+
+	searchService := search.New(
+		log,
+		client.SearchStorage,
+	)
+
+	activityService := activity.New(
+		log,
+		client.ActivityStorage,
+		client.ActivityStorage,
+		client.AssignmentStorage,
+	)
+
+	calendarService := calendar.New(
+		log,
+		client.CalendarStorage,
+		client.CalendarStorage,
+	)
+
+	outboxService := outbox.New[models.OutboxEvent](
+		log,
+		client.OutboxStorage,
+		client.OutboxStorage,
+		broker,
+	)
+
+	grpcApp := grpcapp.New(
+		log,
+		assignmentService,
+		submissionService,
+		attachmentService,
+		templateService,
+		courseService,
+		similarityService,
+		peerReviewService,
+		commentService,
+		gradingService,
+		moderationService,
+		searchService,
+		activityService,
+		calendarService,
+		grpcPort,
+	)
+
+	scheduler := schedulerapp.New(
+		log,
+		schedulerapp.Job{
+			Name:     "publish_scheduled_assignments",
+			Interval: schedulerCfg.PublishInterval,
+			Run:      assignmentService.PublishScheduled,
+		},
+		schedulerapp.Job{
+			Name:     "purge_deleted_assignments",
+			Interval: schedulerCfg.PurgeInterval,
+			Run: func(ctx context.Context) error {
+				return assignmentService.PurgeDeleted(ctx, schedulerCfg.TrashRetention)
+			},
+		},
+		schedulerapp.Job{
+			Name:     "purge_deleted_submissions",
+			Interval: schedulerCfg.PurgeInterval,
+			Run: func(ctx context.Context) error {
+				return submissionService.PurgeDeleted(ctx, schedulerCfg.TrashRetention)
+			},
+		},
+		schedulerapp.Job{
+			Name:     "purge_orphaned_attachments",
+			Interval: schedulerCfg.PurgeInterval,
+			Run:      attachmentService.PurgeOrphaned,
+		},
+		schedulerapp.Job{
+			Name:     "generate_attachment_previews",
+			Interval: schedulerCfg.PreviewInterval,
+			Run:      attachmentService.GeneratePreviews,
+		},
+		schedulerapp.Job{
+			Name:     "check_submission_similarity",
+			Interval: schedulerCfg.SimilarityInterval,
+			Run:      similarityService.CheckSimilarity,
+		},
+		schedulerapp.Job{
+			Name:     "assign_peer_reviewers",
+			Interval: schedulerCfg.PeerReviewInterval,
+			Run:      peerReviewService.AssignReviewers,
+		},
+		schedulerapp.Job{
+			Name:     "record_time_limits",
+			Interval: schedulerCfg.TimeLimitInterval,
+			Run:      activityService.RecordTimeLimits,
+		},
+		schedulerapp.Job{
+			Name:     "purge_submission_activity",
+			Interval: schedulerCfg.PurgeInterval,
+			Run: func(ctx context.Context) error {
+				return activityService.PurgeActivity(ctx, schedulerCfg.ActivityRetention)
+			},
+		},
+		schedulerapp.Job{
+			Name:     "relay_outbox_events",
+			Interval: schedulerCfg.OutboxInterval,
+			Run:      outboxService.Relay,
+		},
+		schedulerapp.Job{
+			Name:     "purge_published_events",
+			Interval: schedulerCfg.PurgeInterval,
+			Run: func(ctx context.Context) error {
+				return outboxService.PurgePublished(ctx, eventsCfg.Retention)
+			},
+		},
+	)
 
 	return &App{
 		GRPCServer: grpcApp,
+		Scheduler:  scheduler,
+		Broker:     broker,
+	}
+}
+
+// newBlobStore creates the blob store of the attachments chosen in the config.
+func newBlobStore(cfg config.AttachmentsConfig) (storage.BlobStore, error) {
+	switch cfg.Storage {
+	case "local":
+		return local.New(cfg.Path)
+	case "s3":
+		return s3.New(s3.Config{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			Timeout:   cfg.S3.Timeout,
+		})
+	default:
+		return nil, fmt.Errorf("unknown attachments storage %q", cfg.Storage)
+	}
+}
+
+// newBroker creates the message broker of the domain events chosen in the config.
+func newBroker(cfg config.EventsConfig) (Broker, error) {
+	switch cfg.Broker {
+	case "memory":
+		return memory.New[models.OutboxEvent](), nil
+	case "nats":
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		return nats.New[models.OutboxEvent](ctx, cfg.NATSURL, "tasks", nats.Stream{
+			Name:      streamName,
+			Subjects:  subjects,
+			Retention: cfg.Retention,
+		})
+	default:
+		return nil, fmt.Errorf("unknown events broker %q", cfg.Broker)
 	}
 }
@@ -1,11 +1,16 @@
 package grpcapp
 
 import (
+	"fmt"
 	"log/slog"
+	"net"
 
+	"tasks/internal/auth"
 	tasksgrpc "tasks/internal/grpc/tasks"
 
 	"google.golang.org/grpc"
+
+	"github.com/Kaptoshka/creative-learning-platform/libs/platform/identity"
 )
 
 type App struct {
@@ -14,15 +19,45 @@ type App struct {
 	port       int
 }
 
+// New creates a new instance of the gRPC app struct.
 func New(
 	log *slog.Logger,
 	assignmentService tasksgrpc.Assignments,
 	submissionService tasksgrpc.Submissions,
+	attachmentService tasksgrpc.Attachments,
+	templateService tasksgrpc.Templates,
+	courseService tasksgrpc.Courses,
+	similarityService tasksgrpc.Similarity,
+	peerReviewService tasksgrpc.PeerReviews,
+	commentService tasksgrpc.Comments,
+	gradingService tasksgrpc.Grading,
+	moderationService tasksgrpc.Moderation,
+	searchService tasksgrpc.Search,
+	activityService tasksgrpc.Activity,
+	calendarService tasksgrpc.Calendar,
 	port int,
 ) *App {
-	gRPCServer := grpc.NewServer()
+	gRPCServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(identity.UnaryServerInterceptor(auth.WithIdentity)),
+		grpc.ChainStreamInterceptor(identity.StreamServerInterceptor(auth.WithIdentity)),
+	)
 
-	tasksgrpc.Register(gRPCServer, assignmentService, submissionService)
+	tasksgrpc.Register(
+		gRPCServer,
+		assignmentService,
+		submissionService,
+		attachmentService,
+		templateService,
+		courseService,
+		similarityService,
+		peerReviewService,
+		commentService,
+		gradingService,
+		moderationService,
+		searchService,
+		activityService,
+		calendarService,
+	)
 
 	return &App{
 		log:        log,
@@ -30,3 +65,45 @@ func New(
 		port:       port,
 	}
 }
+
+// MustRun run gRPC server and panic if error occurs
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		a.log.Error("failed to run app", "error", err)
+		panic(err)
+	}
+}
+
+// Run runs gRPC server
+func (a *App) Run() error {
+	const op = "grpcapp.Run"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("port", a.port),
+	)
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", a.port))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("gRPC server is running")
+	if err := a.gRPCServer.Serve(l); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Stop stops gRPC server
+func (a *App) Stop() {
+	const op = "grpcapp.Stop"
+
+	a.log.With(
+		slog.String("op", op),
+		slog.Int("port", a.port),
+	)
+
+	a.gRPCServer.GracefulStop()
+}
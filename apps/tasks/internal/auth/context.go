@@ -3,6 +3,8 @@ package auth
 import (
 	"context"
 	"errors"
+
+	"github.com/Kaptoshka/creative-learning-platform/libs/platform/identity"
 )
 
 type contextKey string
@@ -10,13 +12,15 @@ type contextKey string
 const (
 	contextKeyUserID contextKey = "user_id"
 	contextKeyRole   contextKey = "role"
+	contextKeySchool contextKey = "school_id"
 )
 
 const (
-	RoleStudent = "student"
-	RoleTeacher = "teacher"
-	RoleAdmin   = "admin"
-	RoleDev     = "dev"
+	RoleStudent     = "student"
+	RoleTeacher     = "teacher"
+	RoleHeadTeacher = "head_teacher"
+	RoleAdmin       = "admin"
+	RoleDev         = "dev"
 )
 
 func GetUserID(ctx context.Context) (int64, error) {
@@ -35,8 +39,35 @@ func GetUserRole(ctx context.Context) string {
 	return val
 }
 
+// GetSchoolID returns the school of the user.
+// If the user is not attached to any school, it returns 0.
+func GetSchoolID(ctx context.Context) int64 {
+	val, ok := ctx.Value(contextKeySchool).(int64)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+func WithSchool(ctx context.Context, schoolID int64) context.Context {
+	return context.WithValue(ctx, contextKeySchool, schoolID)
+}
+
 func WithUser(ctx context.Context, userID int64, role string) context.Context {
 	ctx = context.WithValue(ctx, contextKeyUserID, userID)
 	ctx = context.WithValue(ctx, contextKeyRole, role)
 	return ctx
 }
+
+// WithIdentity puts the caller identified by the api-gateway into the context.
+//
+// The school of the user is the app the user has signed in to,
+// as every school is registered in sso as the app of its own.
+func WithIdentity(ctx context.Context, caller identity.Identity) context.Context {
+	ctx = WithUser(ctx, caller.UserID, caller.Role)
+	if caller.AppID > 0 {
+		ctx = WithSchool(ctx, int64(caller.AppID))
+	}
+
+	return ctx
+}
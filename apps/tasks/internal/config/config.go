@@ -9,10 +9,14 @@ import (
 )
 
 type Config struct {
-	Env      string   `yaml:"env" env-default:"local"`
-	Database Database `yaml:"database"`
-	TokenTTL time.Duration
-	GRPC     GRPCConfig `yaml:"grpc"`
+	Env         string   `yaml:"env" env-default:"local"`
+	Database    Database `yaml:"database"`
+	TokenTTL    time.Duration
+	GRPC        GRPCConfig        `yaml:"grpc"`
+	Scheduler   SchedulerConfig   `yaml:"scheduler"`
+	Attachments AttachmentsConfig `yaml:"attachments"`
+	Grading     GradingConfig     `yaml:"grading"`
+	Events      EventsConfig      `yaml:"events"`
 }
 
 type GRPCConfig struct {
@@ -20,6 +24,58 @@ type GRPCConfig struct {
 	Timeout time.Duration `yaml:"timeout"`
 }
 
+type SchedulerConfig struct {
+	PublishInterval time.Duration `yaml:"publish_interval" env-default:"30s"`
+	PurgeInterval   time.Duration `yaml:"purge_interval" env-default:"1h"`
+	PreviewInterval time.Duration `yaml:"preview_interval" env-default:"5s"`
+	// SimilarityInterval is how often the new submissions are compared with the others.
+	SimilarityInterval time.Duration `yaml:"similarity_interval" env-default:"10m"`
+	PeerReviewInterval time.Duration `yaml:"peer_review_interval" env-default:"1m"`
+	// OutboxInterval is how often the events written to the outbox are published.
+	OutboxInterval time.Duration `yaml:"outbox_interval" env-default:"1s"`
+	// TimeLimitInterval is how often the passed due dates and cutoff dates are recorded
+	// in the live activity of the assignments.
+	TimeLimitInterval time.Duration `yaml:"time_limit_interval" env-default:"15s"`
+	// ActivityRetention is how long the live activity is kept for the watchers to resume.
+	ActivityRetention time.Duration `yaml:"activity_retention" env-default:"24h"`
+	// TrashRetention is how long deleted assignments and submissions can be restored.
+	TrashRetention time.Duration `yaml:"trash_retention" env-default:"720h"`
+}
+
+type AttachmentsConfig struct {
+	// Storage is the blob store of the attachments: local or s3.
+	Storage      string   `yaml:"storage" env-default:"local"`
+	Path         string   `yaml:"path" env-default:"./data/attachments"`
+	S3           S3Config `yaml:"s3"`
+	MaxFileSize  int64    `yaml:"max_file_size" env-default:"104857600"`
+	StudentQuota int64    `yaml:"student_quota" env-default:"1073741824"`
+	// PreviewBaseURL is the prefix of the thumbnail and preview URLs served by the gateway.
+	PreviewBaseURL string `yaml:"preview_base_url" env-default:"/v1"`
+}
+
+type GradingConfig struct {
+	// RegradeWindow is how long after the feedback is published the student can dispute the grade.
+	RegradeWindow time.Duration `yaml:"regrade_window" env-default:"168h"`
+}
+
+type EventsConfig struct {
+	// Broker is the message broker the domain events are published to: memory or nats.
+	Broker  string `yaml:"broker" env-default:"memory"`
+	NATSURL string `yaml:"nats_url" env-default:"nats://localhost:4222"`
+	// Retention is how long the published events are kept in the outbox and in the stream.
+	Retention time.Duration `yaml:"retention" env-default:"168h"`
+}
+
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region" env-default:"us-east-1"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	// Timeout bounds the upload and the download of the single attachment.
+	Timeout time.Duration `yaml:"timeout" env-default:"5m"`
+}
+
 type Database struct {
 	Host     string `yaml:"host" env-required:"true"`
 	Port     int    `yaml:"port" env-required:"true"`
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+type ActivityKind string
+
+const (
+	ActivityStarted   ActivityKind = "started"
+	ActivityAutosaved ActivityKind = "autosaved"
+	ActivitySubmitted ActivityKind = "submitted"
+	// ActivityDuePassed and ActivityCutoffPassed are recorded when the time limit
+	// of the student assignment passes while the work is not submitted.
+	ActivityDuePassed    ActivityKind = "due_date_passed"
+	ActivityCutoffPassed ActivityKind = "cutoff_passed"
+)
+
+// Activity is the step of the work of the student on the assignment,
+// watched live by the teacher.
+type Activity struct {
+	// ID is the number of the activity in the sequence of the assignment,
+	// the activities of the assignment are committed in the order of their ids.
+	ID                  int64
+	AssignmentID        string
+	StudentAssignmentID string
+	// SubmissionID is empty if the time limit passed before the work was started.
+	SubmissionID string
+	StudentID    int64
+	// StudentPseudonym replaces StudentID while the assignment is anonymous.
+	StudentPseudonym string
+	Kind             ActivityKind
+	// Revision is the revision of the submission after the autosave or the submission.
+	Revision  int64
+	IsLate    bool
+	CreatedAt time.Time
+}
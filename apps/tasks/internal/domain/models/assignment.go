@@ -1,4 +1,112 @@
 package models
 
+import "time"
+
+// Assignment is the template given by the teacher to the students.
+// It becomes visible to the students only after it is published.
 type Assignment struct {
+	ID          string
+	TemplateID  string
+	CreatorID   int64
+	Title       string
+	Widget      Widget
+	StudentIDs  []int64
+	DueDate     time.Time
+	CutoffDate  time.Time
+	PublishAt   time.Time
+	PublishedAt time.Time
+	PeerReview  PeerReviewSettings
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   time.Time
+
+	// SchoolID is the school the assignment is created in, zero if none.
+	SchoolID int64
+
+	// Anonymous assignments hide the students from the teacher
+	// until the identities are revealed.
+	Anonymous            bool
+	AnonymityKey         []byte
+	IdentitiesRevealedAt time.Time
+	// StudentPseudonyms replace StudentIDs while the students are hidden.
+	StudentPseudonyms []string
+}
+
+// Published reports whether the assignment is visible to the students.
+func (a *Assignment) Published() bool {
+	return !a.PublishedAt.IsZero()
+}
+
+// Deleted reports whether the assignment is in the trash.
+func (a *Assignment) Deleted() bool {
+	return !a.DeletedAt.IsZero()
+}
+
+// Anonymized reports whether the students are hidden from the teacher.
+func (a *Assignment) Anonymized() bool {
+	return a.Anonymous && a.IdentitiesRevealedAt.IsZero()
+}
+
+// IdentityReveal is the audit record of revealing the students of the anonymous assignment.
+type IdentityReveal struct {
+	ID           string
+	AssignmentID string
+	TeacherID    int64
+	Reason       string
+	RevealedAt   time.Time
+}
+
+// StudentAssignment is the assignment materialized for a single student.
+type StudentAssignment struct {
+	ID         string
+	Assignment Assignment
+	StudentID  int64
+	Status     SubmissionStatus
+	DueDate    time.Time
+	CutoffDate time.Time
+	// ExtendedAt is set when the dates were moved for this student alone.
+	ExtendedAt time.Time
+	Submission *Submission
+	Feedback   string
+}
+
+// Reschedule returns the dates of the student assignment after the update
+// of the assignment dates. The extended student assignment keeps its dates
+// unless the new ones are later, so the update does not take the extension back.
+// The cutoff date is never before the due date, which may happen
+// when the update moves only the due date past the extended cutoff date.
+func (a *StudentAssignment) Reschedule(update AssignmentUpdate) (time.Time, time.Time) {
+	dueDate, cutoffDate := a.DueDate, a.CutoffDate
+
+	if update.DueDate != nil && (a.ExtendedAt.IsZero() || update.DueDate.After(dueDate)) {
+		dueDate = *update.DueDate
+	}
+
+	if update.CutoffDate != nil && (a.ExtendedAt.IsZero() || update.CutoffDate.After(cutoffDate)) {
+		cutoffDate = *update.CutoffDate
+	}
+
+	if cutoffDate.Before(dueDate) {
+		cutoffDate = dueDate
+	}
+
+	return dueDate, cutoffDate
+}
+
+// Extension moves the dates of the assignment for a single student.
+type Extension struct {
+	AssignmentID string
+	StudentID    int64
+	DueDate      time.Time
+	CutoffDate   time.Time
+	ExtendedAt   time.Time
+}
+
+// AssignmentUpdate contains the fields of the assignment to change.
+// Nil fields are left untouched.
+type AssignmentUpdate struct {
+	Title      *string
+	DueDate    *time.Time
+	CutoffDate *time.Time
+	PublishAt  *time.Time
 }
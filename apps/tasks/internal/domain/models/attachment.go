@@ -0,0 +1,95 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AttachmentRefKey is the key of the json object which references
+// the attachment from the payload of the submission version:
+//
+//	{"$attachment": "<attachment id>"}
+const AttachmentRefKey = "$attachment"
+
+// Attachment is the file uploaded by the student to the submission.
+// The content is kept in the blob store under StorageKey.
+type Attachment struct {
+	ID           string
+	SubmissionID string
+	OwnerID      int64
+	// TeacherID is the creator of the assignment, who can download the attachment as well.
+	TeacherID   int64
+	Filename    string
+	ContentType string
+	Size        int64
+	Checksum    string
+	StorageKey  string
+	CreatedAt   time.Time
+	// Preview is nil if the renditions are not generated for the content type.
+	Preview *AttachmentPreview
+}
+
+type PreviewStatus string
+
+const (
+	PreviewPending    PreviewStatus = "pending"
+	PreviewProcessing PreviewStatus = "processing"
+	PreviewReady      PreviewStatus = "ready"
+	PreviewFailed     PreviewStatus = "failed"
+)
+
+// AttachmentPreview describes the renditions of the image attachment
+// generated in background: the small thumbnail and the larger preview.
+type AttachmentPreview struct {
+	Status       PreviewStatus
+	ThumbnailKey string
+	PreviewKey   string
+	Attempts     int
+	Error        string
+	// URLs are set only when the renditions are ready.
+	ThumbnailURL string
+	PreviewURL   string
+}
+
+// Rendition selects the content of the attachment to download.
+type Rendition int
+
+const (
+	RenditionOriginal Rendition = iota
+	RenditionThumbnail
+	RenditionPreview
+)
+
+// AttachmentRefs returns the ids of the attachments referenced from the payload.
+func AttachmentRefs(payload json.RawMessage) ([]string, error) {
+	if len(payload) == 0 {
+		return nil, nil
+	}
+
+	var v any
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return nil, err
+	}
+
+	var refs []string
+	collectRefs(v, &refs)
+
+	return refs, nil
+}
+
+func collectRefs(v any, refs *[]string) {
+	switch v := v.(type) {
+	case map[string]any:
+		if id, ok := v[AttachmentRefKey].(string); ok {
+			*refs = append(*refs, id)
+		}
+
+		for _, child := range v {
+			collectRefs(child, refs)
+		}
+	case []any:
+		for _, child := range v {
+			collectRefs(child, refs)
+		}
+	}
+}
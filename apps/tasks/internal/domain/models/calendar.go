@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// CalendarEntry is the assignment handed out to the student
+// shown in the calendar feed at its due and cutoff dates.
+type CalendarEntry struct {
+	StudentAssignmentID string
+	AssignmentID        string
+	Title               string
+	Status              SubmissionStatus
+	DueDate             time.Time
+	CutoffDate          time.Time
+	// UpdatedAt changes when the dates of the student are moved,
+	// so the calendar apps replace the outdated events.
+	UpdatedAt time.Time
+}
@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// CommentAuthor is the role of the participant of the comment thread.
+// Only the roles are shown to the participants, so the student
+// stays hidden from the teacher in the anonymous grading.
+type CommentAuthor string
+
+const (
+	CommentByStudent CommentAuthor = "student"
+	CommentByTeacher CommentAuthor = "teacher"
+)
+
+// CommentThread is the discussion of the submission version
+// between the student and the teacher.
+type CommentThread struct {
+	ID            string
+	SubmissionID  string
+	VersionID     string
+	VersionNumber int
+	// Anchor is nil if the thread is about the version as a whole.
+	Anchor     *CommentAnchor
+	Comments   []Comment
+	ResolvedBy CommentAuthor
+	ResolvedAt time.Time
+	CreatedAt  time.Time
+}
+
+// Resolved reports whether the discussion is finished.
+func (t *CommentThread) Resolved() bool {
+	return !t.ResolvedAt.IsZero()
+}
+
+// CommentAnchor is the location in the submission version the thread is about,
+// either the value of the payload or the region of the image attachment.
+type CommentAnchor struct {
+	// Pointer is the JSON pointer (RFC 6901) to the value of the payload.
+	Pointer string       `json:"pointer,omitempty"`
+	Region  *ImageRegion `json:"region,omitempty"`
+}
+
+// ImageRegion is the rectangle on the image attachment.
+// The coordinates are relative to the size of the image, from 0 to 1,
+// so the region does not depend on the rendition shown.
+type ImageRegion struct {
+	AttachmentID string  `json:"attachment_id"`
+	X            float64 `json:"x"`
+	Y            float64 `json:"y"`
+	Width        float64 `json:"width"`
+	Height       float64 `json:"height"`
+}
+
+// Valid reports whether the region is not empty and lies within the image.
+func (r *ImageRegion) Valid() bool {
+	return r.X >= 0 && r.Y >= 0 && r.Width > 0 && r.Height > 0 &&
+		r.X+r.Width <= 1 && r.Y+r.Height <= 1
+}
+
+type Comment struct {
+	ID       string
+	ThreadID string
+	AuthorID int64
+	Author   CommentAuthor
+	Body     string
+	// Revisions are the previous bodies of the edited comment, the oldest first.
+	Revisions []CommentRevision
+	CreatedAt time.Time
+	EditedAt  time.Time
+}
+
+// CommentRevision is the body of the comment before the edit.
+type CommentRevision struct {
+	Body      string
+	CreatedAt time.Time
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type Course struct {
+	ID          string
+	CreatorID   int64
+	SchoolID    int64
+	Title       string
+	Description string
+	Sections    []CourseSection
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// CourseSection groups the templates of the course in the order
+// they are given to the class.
+type CourseSection struct {
+	ID          string
+	Title       string
+	Position    int
+	TemplateIDs []string
+}
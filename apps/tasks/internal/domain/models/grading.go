@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Feedback is the grade of the submission version given by the teacher.
+// The draft feedback is seen only by the teacher until it is published.
+type Feedback struct {
+	ID           string
+	SubmissionID string
+	VersionID    string
+	GraderID     int64
+	Text         string
+	// Score is nil if the submission is not scored.
+	Score       *float64
+	Published   bool
+	PublishedAt time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type RegradeStatus string
+
+const (
+	RegradeOpen     RegradeStatus = "open"
+	RegradeAccepted RegradeStatus = "accepted"
+	RegradeRejected RegradeStatus = "rejected"
+)
+
+// RegradeRequest is the dispute of the published grade by the student.
+type RegradeRequest struct {
+	ID              string
+	SubmissionID    string
+	AssignmentID    string
+	AssignmentTitle string
+	StudentID       int64
+	TeacherID       int64
+	Reason          string
+	Status          RegradeStatus
+	// Resolution is the answer of the teacher.
+	Resolution string
+	OldScore   *float64
+	NewScore   *float64
+	CreatedAt  time.Time
+	ResolvedAt time.Time
+}
+
+type SubmissionEventKind string
+
+const (
+	EventFeedbackPublished SubmissionEventKind = "feedback_published"
+	EventRegradeRequested  SubmissionEventKind = "regrade_requested"
+	EventRegradeResolved   SubmissionEventKind = "regrade_resolved"
+)
+
+// SubmissionEvent is the record of the history of the submission.
+// The fields set depend on the kind: the status and the score of the
+// published feedback, the reason or the resolution of the regrade.
+type SubmissionEvent struct {
+	ID           string
+	SubmissionID string
+	Kind         SubmissionEventKind
+	ActorID      int64
+	Status       SubmissionStatus
+	Score        *float64
+	Comment      string
+	RegradeID    string
+	CreatedAt    time.Time
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ModerationDecision string
+
+const (
+	ModerationPending  ModerationDecision = "pending"
+	ModerationAgreed   ModerationDecision = "agreed"
+	ModerationAdjusted ModerationDecision = "adjusted"
+)
+
+// Moderation is the second marking of the random sample of the graded
+// submissions of the assignment by the head teacher. The feedback on the
+// assignment cannot be published until the moderation is completed.
+type Moderation struct {
+	AssignmentID    string
+	AssignmentTitle string
+	RequestedBy     int64
+	ModeratorID     int64
+	Items           []ModerationItem
+	CreatedAt       time.Time
+	// CompletedAt is set when the last item of the sample is moderated.
+	CompletedAt time.Time
+}
+
+// Completed reports whether every item of the sample is moderated.
+func (m *Moderation) Completed() bool {
+	return !m.CompletedAt.IsZero()
+}
+
+// Item returns the item of the sample on the submission version.
+func (m *Moderation) Item(submissionID string, versionID string) (ModerationItem, bool) {
+	for _, item := range m.Items {
+		if item.SubmissionID == submissionID && item.VersionID == versionID {
+			return item, true
+		}
+	}
+
+	return ModerationItem{}, false
+}
+
+// ModerationItem is the graded submission of the sample.
+// The author of the submission is not shown to the moderator.
+type ModerationItem struct {
+	ID           string
+	AssignmentID string
+	SubmissionID string
+	VersionID    string
+	Payload      json.RawMessage
+	// FeedbackID is the id of the draft feedback of the teacher.
+	FeedbackID    string
+	FeedbackText  string
+	OriginalScore *float64
+	Decision      ModerationDecision
+	// AdjustedScore replaces the score of the teacher if the decision is adjusted.
+	AdjustedScore *float64
+	Comment       string
+	ModeratedAt   time.Time
+}
+
+// Score returns the score of the item agreed by the moderator.
+func (i *ModerationItem) Score() *float64 {
+	if i.Decision == ModerationAdjusted {
+		return i.AdjustedScore
+	}
+
+	return i.OriginalScore
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// OutboxEvent is the domain event waiting in the outbox to be published.
+// It is written in the same transaction as the change it describes.
+type OutboxEvent struct {
+	ID string
+	// Seq is the order in which the events were written.
+	Seq int64
+	// Type is the full name of the payload of the event,
+	// it is used as the subject of the message in the broker.
+	Type        string
+	AggregateID string
+	// Payload is the serialized envelope of the event.
+	Payload   []byte
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// MessageID returns the id the broker drops the duplicates of the event by.
+func (e OutboxEvent) MessageID() string {
+	return e.ID
+}
+
+// Subject returns the subject of the message in the broker.
+func (e OutboxEvent) Subject() string {
+	return e.Type
+}
+
+// Data returns the payload of the event.
+func (e OutboxEvent) Data() []byte {
+	return e.Payload
+}
+
+// AggregateKey returns the id of the aggregate the event is about.
+func (e OutboxEvent) AggregateKey() string {
+	return e.AggregateID
+}
+
+// FailedAttempts returns the number of the failed attempts to publish the event.
+func (e OutboxEvent) FailedAttempts() int {
+	return e.Attempts
+}
@@ -0,0 +1,91 @@
+package models
+
+import "time"
+
+// MaxPeerReviewers limits the number of reviewers of every submission.
+const MaxPeerReviewers = 10
+
+// PeerReviewSettings enable the peer review of the assignment.
+// After the due date every submitted submission is assigned to
+// ReviewersCount other students, who score it by the criteria.
+type PeerReviewSettings struct {
+	ReviewersCount int
+	// DueDate closes the reviews, zero means they are never closed.
+	DueDate  time.Time
+	Criteria []RubricCriterion
+	// AssignedAt is set once the reviewers are assigned.
+	AssignedAt time.Time
+}
+
+// Enabled reports whether the submissions of the assignment are peer reviewed.
+func (s *PeerReviewSettings) Enabled() bool {
+	return s.ReviewersCount > 0
+}
+
+// MaxScore returns the sum of the maximal scores of the criteria.
+func (s *PeerReviewSettings) MaxScore() float64 {
+	var res float64
+	for _, c := range s.Criteria {
+		res += c.MaxScore
+	}
+
+	return res
+}
+
+type PeerReviewStatus string
+
+const (
+	PeerReviewPending   PeerReviewStatus = "pending"
+	PeerReviewSubmitted PeerReviewStatus = "submitted"
+)
+
+// PeerReview is the review of the submission by another student.
+// Neither the reviewer nor the author is disclosed to each other.
+type PeerReview struct {
+	ID              string
+	AssignmentID    string
+	AssignmentTitle string
+	SubmissionID    string
+	ReviewerID      int64
+	Status          PeerReviewStatus
+	DueDate         time.Time
+	Criteria        []RubricCriterion
+	Scores          []CriterionScore
+	Comment         string
+	CreatedAt       time.Time
+	SubmittedAt     time.Time
+}
+
+// Closed reports whether the review can no longer be submitted at the given time.
+func (r *PeerReview) Closed(now time.Time) bool {
+	return !r.DueDate.IsZero() && now.After(r.DueDate)
+}
+
+// Score returns the total score of the review.
+func (r *PeerReview) Score() float64 {
+	var res float64
+	for _, s := range r.Scores {
+		res += s.Score
+	}
+
+	return res
+}
+
+type CriterionScore struct {
+	CriterionID string  `json:"criterion_id"`
+	Score       float64 `json:"score"`
+}
+
+// PeerReviewSummary aggregates the peer reviews of the submission for the teacher.
+// Score and criteria scores are the averages of the submitted reviews.
+type PeerReviewSummary struct {
+	SubmissionID     string
+	StudentID        int64
+	StudentPseudonym string
+	ReviewsAssigned  int
+	ReviewsSubmitted int
+	Score            float64
+	MaxScore         float64
+	Criteria         []CriterionScore
+	TeacherFeedback  string
+}
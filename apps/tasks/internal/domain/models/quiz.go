@@ -0,0 +1,31 @@
+package models
+
+const WidgetTypeQuiz = "quiz"
+
+type QuestionType string
+
+const (
+	QuestionSingleChoice   QuestionType = "single_choice"
+	QuestionMultipleChoice QuestionType = "multiple_choice"
+	QuestionTextEntry      QuestionType = "text_entry"
+	QuestionOpenText       QuestionType = "open_text"
+)
+
+// QuizConfig is the widget config of the quiz widget.
+type QuizConfig struct {
+	Questions []QuizQuestion `json:"questions"`
+}
+
+type QuizQuestion struct {
+	ID      string       `json:"id"`
+	Type    QuestionType `json:"type"`
+	Title   string       `json:"title,omitempty"`
+	Prompt  string       `json:"prompt"`
+	Choices []QuizChoice `json:"choices,omitempty"`
+	Correct []string     `json:"correct,omitempty"`
+}
+
+type QuizChoice struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
@@ -0,0 +1,15 @@
+package models
+
+type Rubric struct {
+	ID         string
+	TemplateID string
+	Title      string
+	Criteria   []RubricCriterion
+}
+
+type RubricCriterion struct {
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	Description string  `json:"description,omitempty"`
+	MaxScore    float64 `json:"max_score"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+type SearchKind string
+
+const (
+	SearchAssignment SearchKind = "assignment"
+	SearchTemplate   SearchKind = "template"
+)
+
+// SearchQuery is the full-text query over the assignments of the teacher
+// and the templates visible to the teacher.
+type SearchQuery struct {
+	Text string
+	// Kind limits the results to the assignments or the templates,
+	// the empty kind searches both.
+	Kind       SearchKind
+	WidgetType string
+	// CreatorID limits the results to the ones created by the user, if set.
+	CreatorID int64
+}
+
+// SearchResult is the assignment or the template matching the query.
+type SearchResult struct {
+	Kind       SearchKind
+	ID         string
+	Title      string
+	WidgetType string
+	CreatorID  int64
+	// Rank is the relevance of the result, the greater the better.
+	Rank      float64
+	UpdatedAt time.Time
+}
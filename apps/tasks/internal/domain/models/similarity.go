@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// SimilarityReport lists the pairs of similar submissions of the assignment.
+// CheckedAt is zero if the submissions have not been compared yet.
+type SimilarityReport struct {
+	AssignmentID string
+	CheckedAt    time.Time
+	Pairs        []SimilarPair
+}
+
+// SimilarPair is the pair of submissions sharing the fragments of text.
+// Score is the share of the smaller submission found in the other one, from 0 to 1.
+type SimilarPair struct {
+	SubmissionID      string
+	StudentID         int64
+	OtherSubmissionID string
+	OtherStudentID    int64
+	// Pseudonyms replace the student ids in the anonymous grading.
+	StudentPseudonym      string
+	OtherStudentPseudonym string
+	Score                 float64
+	Fragments             []MatchedFragment
+}
+
+// MatchedFragment is the common fragment as written in each of the submissions.
+type MatchedFragment struct {
+	Text      string `json:"text"`
+	OtherText string `json:"other_text"`
+}
@@ -1,4 +1,61 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
+type SubmissionStatus string
+
+const (
+	StatusNotStarted SubmissionStatus = "not_started"
+	StatusInProgress SubmissionStatus = "in_progress"
+	StatusSubmitted  SubmissionStatus = "submitted"
+	StatusGraded     SubmissionStatus = "graded"
+	StatusReturned   SubmissionStatus = "returned"
+)
+
+// Submission is the work of the student on the student assignment.
+// AssignmentID is the id of the student assignment.
 type Submission struct {
+	ID           string
+	AssignmentID string
+	StudentID    int64
+	// StudentPseudonym replaces StudentID in the anonymous grading.
+	StudentPseudonym string
+	TeacherID        int64
+	Status           SubmissionStatus
+	Revision         int64
+	CurrentVersion   *SubmissionVersion
+	DueDate          time.Time
+	CutoffDate       time.Time
+	StartedAt        time.Time
+	SubmittedAt      time.Time
+	UpdatedAt        time.Time
+	DeletedAt        time.Time
+}
+
+// Editable reports whether the student can change the submission.
+func (s *Submission) Editable() bool {
+	return s.Status == StatusInProgress || s.Status == StatusReturned
+}
+
+// Graded reports whether the feedback on the submission is published.
+func (s *Submission) Graded() bool {
+	return s.Status == StatusGraded || s.Status == StatusReturned
+}
+
+// Deleted reports whether the submission is in the trash.
+func (s *Submission) Deleted() bool {
+	return !s.DeletedAt.IsZero()
+}
+
+type SubmissionVersion struct {
+	ID           string
+	SubmissionID string
+	Number       int
+	Payload      json.RawMessage
+	IsLate       bool
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
 }
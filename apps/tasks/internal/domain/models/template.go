@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Visibility string
+
+const (
+	VisibilityPrivate Visibility = "private"
+	VisibilitySchool  Visibility = "school"
+	VisibilityPublic  Visibility = "public"
+)
+
+type TemplateScope int
+
+const (
+	TemplateScopeMine TemplateScope = iota
+	TemplateScopeSchool
+	TemplateScopePublic
+)
+
+type Widget struct {
+	ID      int64
+	Type    string
+	Version int
+	Config  json.RawMessage
+}
+
+type Template struct {
+	ID         string
+	CreatorID  int64
+	SchoolID   int64
+	Title      string
+	Widget     Widget
+	Visibility Visibility
+	ClonedFrom string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// VisibleTo reports whether the template can be seen
+// by the user with the given id from the given school.
+// The school 0 is unknown and is never shared with anyone.
+func (t *Template) VisibleTo(userID int64, schoolID int64) bool {
+	switch {
+	case t.CreatorID == userID:
+		return true
+	case t.Visibility == VisibilityPublic:
+		return true
+	case t.Visibility == VisibilitySchool:
+		return t.SchoolID != 0 && t.SchoolID == schoolID
+	default:
+		return false
+	}
+}
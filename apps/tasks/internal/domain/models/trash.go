@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type TrashKind int
+
+const (
+	TrashAssignment TrashKind = iota
+	TrashSubmission
+)
+
+// TrashItem is the soft deleted entity which can still be restored
+// until it is purged by the retention job.
+type TrashItem struct {
+	ID        string
+	Kind      TrashKind
+	Title     string
+	DeletedAt time.Time
+}
@@ -0,0 +1,100 @@
+package tasks
+
+import (
+	"errors"
+	"strconv"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/services/activity"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// WatchAssignment sends the activity of the students on the assignment
+// of the calling teacher as it occurs, until the client cancels the call.
+// The students of the anonymous assignment are shown as pseudonyms.
+func (s *serverAPI) WatchAssignment(
+	req *tasksv1.WatchAssignmentRequest,
+	stream tasksv1.Tasks_WatchAssignmentServer,
+) error {
+	if req.GetAssignmentId() == "" {
+		return status.Error(codes.InvalidArgument, "assignment_id is required")
+	}
+
+	var afterID int64
+	if req.GetLastEventId() != "" {
+		var err error
+
+		afterID, err = strconv.ParseInt(req.GetLastEventId(), 10, 64)
+		if err != nil || afterID <= 0 {
+			return status.Error(codes.InvalidArgument, "invalid last_event_id")
+		}
+	}
+
+	ctx := stream.Context()
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return err
+	}
+
+	var sendErr error
+	err = s.activity.WatchAssignment(ctx, req.GetAssignmentId(), userID, afterID, func(a models.Activity) error {
+		sendErr = stream.Send(&tasksv1.WatchAssignmentResponse{
+			Activity: toSubmissionActivity(a),
+		})
+
+		return sendErr
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, activity.ErrAssignmentNotFound):
+			return status.Error(codes.NotFound, "assignment not found")
+		case errors.Is(err, activity.ErrAccessDenied):
+			return status.Error(codes.PermissionDenied, "assignment belongs to another teacher")
+		case errors.Is(err, activity.ErrActivityExpired):
+			return status.Error(codes.OutOfRange, "last_event_id is no longer kept")
+		}
+
+		return status.Error(codes.Internal, "failed to watch assignment")
+	}
+
+	return nil
+}
+
+func toSubmissionActivity(activity models.Activity) *tasksv1.SubmissionActivity {
+	return &tasksv1.SubmissionActivity{
+		Id:                  strconv.FormatInt(activity.ID, 10),
+		Kind:                toSubmissionActivityKind(activity.Kind),
+		StudentAssignmentId: activity.StudentAssignmentID,
+		SubmissionId:        activity.SubmissionID,
+		StudentId:           toStudentID(activity.StudentID, activity.StudentPseudonym),
+		Revision:            activity.Revision,
+		IsLate:              activity.IsLate,
+		OccurredAt:          timestamppb.New(activity.CreatedAt),
+	}
+}
+
+func toSubmissionActivityKind(kind models.ActivityKind) tasksv1.SubmissionActivityKind {
+	switch kind {
+	case models.ActivityStarted:
+		return tasksv1.SubmissionActivityKind_SUBMISSION_ACTIVITY_KIND_STARTED
+	case models.ActivityAutosaved:
+		return tasksv1.SubmissionActivityKind_SUBMISSION_ACTIVITY_KIND_AUTOSAVED
+	case models.ActivitySubmitted:
+		return tasksv1.SubmissionActivityKind_SUBMISSION_ACTIVITY_KIND_SUBMITTED
+	case models.ActivityDuePassed:
+		return tasksv1.SubmissionActivityKind_SUBMISSION_ACTIVITY_KIND_DUE_DATE_PASSED
+	case models.ActivityCutoffPassed:
+		return tasksv1.SubmissionActivityKind_SUBMISSION_ACTIVITY_KIND_CUTOFF_PASSED
+	default:
+		return tasksv1.SubmissionActivityKind_SUBMISSION_ACTIVITY_KIND_UNSPECIFIED
+	}
+}
@@ -0,0 +1,516 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/services/assignment"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// CreateAssignment gives the template or the widget to the students,
+// immediately or at the given publish time.
+func (s *serverAPI) CreateAssignment(
+	ctx context.Context,
+	req *tasksv1.CreateAssignmentRequest,
+) (*tasksv1.CreateAssignmentResponse, error) {
+	if err := validateCreateAssignment(req); err != nil {
+		return nil, err
+	}
+
+	userID, schoolID, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	studentIDs, err := fromUserIDs(req.GetStudentIds())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid student_ids")
+	}
+
+	a := models.Assignment{
+		TemplateID: req.GetTemplateId(),
+		CreatorID:  userID,
+		Title:      req.GetTitle(),
+		StudentIDs: studentIDs,
+		DueDate:    req.GetDueDate().AsTime(),
+		PeerReview: fromPeerReviewSettings(req.GetPeerReview()),
+		Anonymous:  req.GetAnonymous(),
+	}
+
+	if req.GetTemplateId() == "" {
+		a.Widget, err = fromWidget(req.GetWidget())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid widget")
+		}
+	}
+
+	if req.GetCutoffDate() != nil {
+		a.CutoffDate = req.GetCutoffDate().AsTime()
+	}
+
+	if req.GetPublishAt() != nil {
+		a.PublishAt = req.GetPublishAt().AsTime()
+	}
+
+	id, err := s.assignments.CreateAssignment(ctx, a, schoolID)
+	if err != nil {
+		switch {
+		case errors.Is(err, assignment.ErrTemplateNotFound):
+			return nil, status.Error(codes.NotFound, "template not found")
+		case errors.Is(err, assignment.ErrWidgetNotFound):
+			return nil, status.Error(codes.InvalidArgument, "widget not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to create assignment")
+	}
+
+	return &tasksv1.CreateAssignmentResponse{
+		Id: id,
+	}, nil
+}
+
+// UpdateAssignment changes the title, dates or publish time of the assignment.
+func (s *serverAPI) UpdateAssignment(
+	ctx context.Context,
+	req *tasksv1.UpdateAssignmentRequest,
+) (*emptypb.Empty, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	if req.GetWidget() != nil {
+		return nil, status.Error(codes.InvalidArgument, "widget of assignment cannot be changed")
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var update models.AssignmentUpdate
+
+	if title := req.GetTitle(); title != "" {
+		update.Title = &title
+	}
+
+	if req.GetDueDate() != nil {
+		dueDate := req.GetDueDate().AsTime()
+		update.DueDate = &dueDate
+	}
+
+	if req.GetCutoffDate() != nil {
+		cutoffDate := req.GetCutoffDate().AsTime()
+		update.CutoffDate = &cutoffDate
+	}
+
+	if req.GetPublishAt() != nil {
+		publishAt := req.GetPublishAt().AsTime()
+		update.PublishAt = &publishAt
+	}
+
+	err = s.assignments.UpdateAssignment(ctx, req.GetId(), userID, update)
+	if err != nil {
+		switch {
+		case errors.Is(err, assignment.ErrAssignmentNotFound):
+			return nil, status.Error(codes.NotFound, "assignment not found")
+		case errors.Is(err, assignment.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "assignment belongs to another teacher")
+		case errors.Is(err, assignment.ErrAlreadyPublished):
+			return nil, status.Error(codes.FailedPrecondition, "assignment is already published")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to update assignment")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ExtendStudentAssignment moves the dates of the assignment for a single student.
+func (s *serverAPI) ExtendStudentAssignment(
+	ctx context.Context,
+	req *tasksv1.ExtendStudentAssignmentRequest,
+) (*emptypb.Empty, error) {
+	if err := validateExtendStudentAssignment(req); err != nil {
+		return nil, err
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var cutoffDate time.Time
+	if req.GetCutoffDate() != nil {
+		cutoffDate = req.GetCutoffDate().AsTime()
+	}
+
+	err = s.assignments.ExtendStudentAssignment(
+		ctx,
+		req.GetAssignmentId(),
+		userID,
+		req.GetStudentId(),
+		req.GetDueDate().AsTime(),
+		cutoffDate,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, assignment.ErrAssignmentNotFound):
+			return nil, status.Error(codes.NotFound, "assignment not found")
+		case errors.Is(err, assignment.ErrStudentNotFound):
+			return nil, status.Error(codes.NotFound, "student of assignment not found")
+		case errors.Is(err, assignment.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "assignment belongs to another teacher")
+		case errors.Is(err, assignment.ErrNotPublished):
+			return nil, status.Error(codes.FailedPrecondition, "assignment is not published")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to extend assignment")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// DeleteAssignment moves the assignment to the trash.
+func (s *serverAPI) DeleteAssignment(
+	ctx context.Context,
+	req *tasksv1.DeleteAssignmentRequest,
+) (*emptypb.Empty, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.assignments.DeleteAssignment(ctx, req.GetId(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, assignment.ErrAssignmentNotFound):
+			return nil, status.Error(codes.NotFound, "assignment not found")
+		case errors.Is(err, assignment.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "assignment belongs to another teacher")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to delete assignment")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// RestoreAssignment moves the assignment out of the trash.
+func (s *serverAPI) RestoreAssignment(
+	ctx context.Context,
+	req *tasksv1.RestoreAssignmentRequest,
+) (*emptypb.Empty, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.assignments.RestoreAssignment(ctx, req.GetId(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, assignment.ErrAssignmentNotFound):
+			return nil, status.Error(codes.NotFound, "assignment not found in trash")
+		case errors.Is(err, assignment.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "assignment belongs to another teacher")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to restore assignment")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// GetTeacherAssignment returns the assignment created by the caller.
+func (s *serverAPI) GetTeacherAssignment(
+	ctx context.Context,
+	req *tasksv1.GetTeacherAssignmentRequest,
+) (*tasksv1.GetTeacherAssignmentResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := s.assignments.Assignment(ctx, req.GetId(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, assignment.ErrAssignmentNotFound):
+			return nil, status.Error(codes.NotFound, "assignment not found")
+		case errors.Is(err, assignment.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "assignment belongs to another teacher")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to get assignment")
+	}
+
+	res, err := toAssignment(a)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get assignment")
+	}
+
+	return &tasksv1.GetTeacherAssignmentResponse{
+		Assignment: res,
+	}, nil
+}
+
+// RevealStudentIdentities reveals the students of the anonymous assignment
+// of the caller. The reveal is recorded in the audit log with its reason.
+func (s *serverAPI) RevealStudentIdentities(
+	ctx context.Context,
+	req *tasksv1.RevealStudentIdentitiesRequest,
+) (*emptypb.Empty, error) {
+	if req.GetAssignmentId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "assignment_id is required")
+	}
+
+	if strings.TrimSpace(req.GetReason()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "reason is required")
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.assignments.RevealIdentities(ctx, req.GetAssignmentId(), userID, req.GetReason())
+	if err != nil {
+		switch {
+		case errors.Is(err, assignment.ErrAssignmentNotFound):
+			return nil, status.Error(codes.NotFound, "assignment not found")
+		case errors.Is(err, assignment.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "assignment belongs to another teacher")
+		case errors.Is(err, assignment.ErrNotAnonymous):
+			return nil, status.Error(codes.FailedPrecondition, "assignment is not anonymous")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to reveal identities")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ListIdentityReveals returns the audit log of the reveals of the caller's assignment.
+func (s *serverAPI) ListIdentityReveals(
+	ctx context.Context,
+	req *tasksv1.ListIdentityRevealsRequest,
+) (*tasksv1.ListIdentityRevealsResponse, error) {
+	if req.GetAssignmentId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "assignment_id is required")
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reveals, err := s.assignments.IdentityReveals(ctx, req.GetAssignmentId(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, assignment.ErrAssignmentNotFound):
+			return nil, status.Error(codes.NotFound, "assignment not found")
+		case errors.Is(err, assignment.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "assignment belongs to another teacher")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to list identity reveals")
+	}
+
+	resp := &tasksv1.ListIdentityRevealsResponse{
+		Reveals: make([]*tasksv1.IdentityReveal, 0, len(reveals)),
+	}
+
+	for _, reveal := range reveals {
+		resp.Reveals = append(resp.Reveals, toIdentityReveal(reveal))
+	}
+
+	return resp, nil
+}
+
+// ListAssignments lists the published assignments of the calling student.
+func (s *serverAPI) ListAssignments(
+	ctx context.Context,
+	req *tasksv1.ListAssignmentsRequest,
+) (*tasksv1.ListAssignmentsResponse, error) {
+	filter, err := pageFilter(req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	studentID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, err := s.assignments.StudentAssignments(ctx, studentID, filter)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list assignments")
+	}
+
+	resp := &tasksv1.ListAssignmentsResponse{
+		Items:         make([]*tasksv1.StudentAssignmentItem, 0, len(assignments)),
+		NextPageToken: nextPageToken(filter, len(assignments)),
+	}
+
+	for _, a := range assignments {
+		resp.Items = append(resp.Items, &tasksv1.StudentAssignmentItem{
+			AssignmentId: a.ID,
+			Title:        a.Assignment.Title,
+			Status:       toSubmissionStatus(a.Status),
+			Feedback:     a.Feedback,
+		})
+	}
+
+	return resp, nil
+}
+
+// GetStudentAssignment returns the published assignment of the calling student.
+func (s *serverAPI) GetStudentAssignment(
+	ctx context.Context,
+	req *tasksv1.GetStudentAssignmentRequest,
+) (*tasksv1.GetStudentAssignmentResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	studentID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := s.assignments.StudentAssignment(ctx, req.GetId(), studentID)
+	if err != nil {
+		if errors.Is(err, assignment.ErrAssignmentNotFound) {
+			return nil, status.Error(codes.NotFound, "assignment not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to get assignment")
+	}
+
+	res, err := toStudentAssignment(a)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get assignment")
+	}
+
+	return &tasksv1.GetStudentAssignmentResponse{
+		Assignment: res,
+	}, nil
+}
+
+// validateCreateAssignment validates the create assignment request
+// Due date and either template_id or widget with title must be provided.
+// If not it returns an error.
+func validateCreateAssignment(req *tasksv1.CreateAssignmentRequest) error {
+	if req.GetDueDate() == nil {
+		return status.Error(codes.InvalidArgument, "due_date is required")
+	}
+
+	if req.GetTemplateId() == "" {
+		if req.GetWidget().GetType() == "" {
+			return status.Error(codes.InvalidArgument, "template_id or widget is required")
+		}
+
+		if req.GetTitle() == "" {
+			return status.Error(codes.InvalidArgument, "title is required")
+		}
+	}
+
+	if len(req.GetStudentIds()) == 0 {
+		return status.Error(codes.InvalidArgument, "student_ids are required")
+	}
+
+	if req.GetCutoffDate() != nil && req.GetCutoffDate().AsTime().Before(req.GetDueDate().AsTime()) {
+		return status.Error(codes.InvalidArgument, "cutoff_date must not be before due_date")
+	}
+
+	if req.GetPeerReview() != nil {
+		return validatePeerReviewSettings(req.GetPeerReview(), req.GetDueDate().AsTime())
+	}
+
+	return nil
+}
+
+func validateExtendStudentAssignment(req *tasksv1.ExtendStudentAssignmentRequest) error {
+	if req.GetAssignmentId() == "" {
+		return status.Error(codes.InvalidArgument, "assignment_id is required")
+	}
+
+	if req.GetStudentId() == "" {
+		return status.Error(codes.InvalidArgument, "student_id is required")
+	}
+
+	if req.GetDueDate() == nil {
+		return status.Error(codes.InvalidArgument, "due_date is required")
+	}
+
+	if req.GetCutoffDate() != nil && req.GetCutoffDate().AsTime().Before(req.GetDueDate().AsTime()) {
+		return status.Error(codes.InvalidArgument, "cutoff_date must not be before due_date")
+	}
+
+	return nil
+}
+
+func validatePeerReviewSettings(settings *tasksv1.PeerReviewSettings, dueDate time.Time) error {
+	count := settings.GetReviewersCount()
+	if count < 0 || count > models.MaxPeerReviewers {
+		return status.Errorf(
+			codes.InvalidArgument,
+			"peer_review.reviewers_count must be between 0 and %d",
+			models.MaxPeerReviewers,
+		)
+	}
+
+	if count == 0 {
+		return nil
+	}
+
+	if settings.GetDueDate() != nil && !settings.GetDueDate().AsTime().After(dueDate) {
+		return status.Error(codes.InvalidArgument, "peer_review.due_date must be after due_date")
+	}
+
+	if len(settings.GetCriteria()) == 0 {
+		return status.Error(codes.InvalidArgument, "peer_review.criteria are required")
+	}
+
+	ids := make(map[string]struct{}, len(settings.GetCriteria()))
+	for _, c := range settings.GetCriteria() {
+		if c.GetTitle() == "" {
+			return status.Error(codes.InvalidArgument, "title of criterion is required")
+		}
+
+		if c.GetMaxScore() <= 0 {
+			return status.Error(codes.InvalidArgument, "max_score of criterion must be positive")
+		}
+
+		if c.GetId() == "" {
+			continue
+		}
+
+		if _, ok := ids[c.GetId()]; ok {
+			return status.Error(codes.InvalidArgument, "ids of criteria must be unique")
+		}
+
+		ids[c.GetId()] = struct{}{}
+	}
+
+	return nil
+}
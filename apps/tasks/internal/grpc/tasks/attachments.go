@@ -0,0 +1,210 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"tasks/internal/services/attachment"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// chunkSize is the size of the content in one message of the download stream.
+const chunkSize = 64 * 1024
+
+var errDuplicateInfo = errors.New("duplicate attachment info")
+
+// UploadAttachment receives the file attached to the submission of the calling student.
+func (s *serverAPI) UploadAttachment(stream tasksv1.Tasks_UploadAttachmentServer) error {
+	ctx := stream.Context()
+
+	studentID, err := user(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := stream.Recv()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return status.Error(codes.InvalidArgument, "info is required")
+		}
+
+		return err
+	}
+
+	info := req.GetInfo()
+	if info == nil {
+		return status.Error(codes.InvalidArgument, "first message must contain info")
+	}
+
+	if info.GetSubmissionId() == "" {
+		return status.Error(codes.InvalidArgument, "submission_id is required")
+	}
+
+	if info.GetFilename() == "" {
+		return status.Error(codes.InvalidArgument, "filename is required")
+	}
+
+	res, err := s.attachments.UploadAttachment(
+		ctx,
+		studentID,
+		info.GetSubmissionId(),
+		info.GetFilename(),
+		&uploadReader{stream: stream},
+	)
+	if err != nil {
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+
+		switch {
+		case errors.Is(err, errDuplicateInfo):
+			return status.Error(codes.InvalidArgument, "info must be sent only once")
+		case errors.Is(err, attachment.ErrSubmissionNotFound):
+			return status.Error(codes.NotFound, "submission not found")
+		case errors.Is(err, attachment.ErrNotEditable):
+			return status.Error(codes.FailedPrecondition, "submission is not editable")
+		case errors.Is(err, attachment.ErrQuotaExceeded):
+			return status.Error(codes.ResourceExhausted, "attachment quota exceeded")
+		case errors.Is(err, attachment.ErrFileTooLarge):
+			return status.Error(codes.InvalidArgument, "file is too large")
+		case errors.Is(err, attachment.ErrEmptyFile):
+			return status.Error(codes.InvalidArgument, "file is empty")
+		case errors.Is(err, attachment.ErrUnsupportedContent):
+			return status.Error(codes.InvalidArgument, "content type is not supported")
+		}
+
+		return status.Error(codes.Internal, "failed to upload attachment")
+	}
+
+	return stream.SendAndClose(&tasksv1.UploadAttachmentResponse{
+		Attachment: toAttachment(res),
+	})
+}
+
+// DownloadAttachment sends the attachment and then its content in chunks.
+func (s *serverAPI) DownloadAttachment(
+	req *tasksv1.DownloadAttachmentRequest,
+	stream tasksv1.Tasks_DownloadAttachmentServer,
+) error {
+	if req.GetAttachmentId() == "" {
+		return status.Error(codes.InvalidArgument, "attachment_id is required")
+	}
+
+	ctx := stream.Context()
+
+	userID, err := user(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, r, err := s.attachments.DownloadAttachment(
+		ctx,
+		req.GetAttachmentId(),
+		userID,
+		fromRendition(req.GetRendition()),
+		req.GetOffset(),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, attachment.ErrAttachmentNotFound):
+			return status.Error(codes.NotFound, "attachment not found")
+		case errors.Is(err, attachment.ErrPreviewNotReady):
+			return status.Error(codes.FailedPrecondition, "preview is not ready")
+		case errors.Is(err, attachment.ErrInvalidOffset):
+			return status.Error(codes.OutOfRange, "offset is out of range")
+		}
+
+		return status.Error(codes.Internal, "failed to download attachment")
+	}
+	defer r.Close()
+
+	err = stream.Send(&tasksv1.DownloadAttachmentResponse{
+		Data: &tasksv1.DownloadAttachmentResponse_Attachment{Attachment: toAttachment(res)},
+	})
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			sendErr := stream.Send(&tasksv1.DownloadAttachmentResponse{
+				Data: &tasksv1.DownloadAttachmentResponse_Chunk{Chunk: buf[:n]},
+			})
+			if sendErr != nil {
+				return sendErr
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return status.Error(codes.Internal, "failed to read attachment")
+		}
+	}
+}
+
+// ListAttachments returns the attachments of the submission with their preview URLs.
+func (s *serverAPI) ListAttachments(
+	ctx context.Context,
+	req *tasksv1.ListAttachmentsRequest,
+) (*tasksv1.ListAttachmentsResponse, error) {
+	if req.GetSubmissionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "submission_id is required")
+	}
+
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.attachments.SubmissionAttachments(ctx, req.GetSubmissionId(), userID)
+	if err != nil {
+		if errors.Is(err, attachment.ErrSubmissionNotFound) {
+			return nil, status.Error(codes.NotFound, "submission not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to list attachments")
+	}
+
+	attachments := make([]*tasksv1.Attachment, 0, len(res))
+	for _, a := range res {
+		attachments = append(attachments, toAttachment(a))
+	}
+
+	return &tasksv1.ListAttachmentsResponse{Attachments: attachments}, nil
+}
+
+// uploadReader reads the content of the file from the chunks of the upload stream.
+type uploadReader struct {
+	stream tasksv1.Tasks_UploadAttachmentServer
+	buf    []byte
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+
+		if req.GetInfo() != nil {
+			return 0, errDuplicateInfo
+		}
+
+		r.buf = req.GetChunk()
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+
+	return n, nil
+}
@@ -0,0 +1,101 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/services/calendar"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// maxCalendarTokenLen limits the length of the token of the calendar feed.
+const maxCalendarTokenLen = 128
+
+// CreateCalendarFeed creates the feed of the due dates of the calling user
+// and returns its token, revoking the previous one.
+func (s *serverAPI) CreateCalendarFeed(
+	ctx context.Context,
+	req *tasksv1.CreateCalendarFeedRequest,
+) (*tasksv1.CreateCalendarFeedResponse, error) {
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.calendar.CreateCalendarFeed(ctx, userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to create calendar feed")
+	}
+
+	return &tasksv1.CreateCalendarFeedResponse{
+		Token: token,
+	}, nil
+}
+
+// RevokeCalendarFeed revokes the feed of the calling user.
+func (s *serverAPI) RevokeCalendarFeed(
+	ctx context.Context,
+	req *tasksv1.RevokeCalendarFeedRequest,
+) (*emptypb.Empty, error) {
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.calendar.RevokeCalendarFeed(ctx, userID); err != nil {
+		if errors.Is(err, calendar.ErrCalendarFeedNotFound) {
+			return nil, status.Error(codes.NotFound, "calendar feed not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to revoke calendar feed")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// GetCalendarFeed returns the assignments of the feed with the token.
+// The token is the only credential, so the call is not bound to the caller.
+func (s *serverAPI) GetCalendarFeed(
+	ctx context.Context,
+	req *tasksv1.GetCalendarFeedRequest,
+) (*tasksv1.GetCalendarFeedResponse, error) {
+	if req.GetToken() == "" || len(req.GetToken()) > maxCalendarTokenLen {
+		return nil, status.Error(codes.InvalidArgument, "invalid token")
+	}
+
+	entries, err := s.calendar.CalendarFeed(ctx, req.GetToken())
+	if err != nil {
+		if errors.Is(err, calendar.ErrCalendarFeedNotFound) {
+			return nil, status.Error(codes.NotFound, "calendar feed not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to get calendar feed")
+	}
+
+	res := &tasksv1.GetCalendarFeedResponse{
+		Entries: make([]*tasksv1.CalendarEntry, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		res.Entries = append(res.Entries, toCalendarEntry(entry))
+	}
+
+	return res, nil
+}
+
+func toCalendarEntry(entry models.CalendarEntry) *tasksv1.CalendarEntry {
+	return &tasksv1.CalendarEntry{
+		StudentAssignmentId: entry.StudentAssignmentID,
+		AssignmentId:        entry.AssignmentID,
+		Title:               entry.Title,
+		Status:              toSubmissionStatus(entry.Status),
+		DueDate:             toTimestamp(entry.DueDate),
+		CutoffDate:          toTimestamp(entry.CutoffDate),
+		UpdatedAt:           toTimestamp(entry.UpdatedAt),
+	}
+}
@@ -0,0 +1,209 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"tasks/internal/services/comment"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// maxCommentLen limits the length of the comment in characters.
+const maxCommentLen = 10000
+
+// ListCommentThreads lists the comment threads of the submission
+// of the calling student or of the calling teacher's assignment.
+func (s *serverAPI) ListCommentThreads(
+	ctx context.Context,
+	req *tasksv1.ListCommentThreadsRequest,
+) (*tasksv1.ListCommentThreadsResponse, error) {
+	if req.GetSubmissionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "submission_id is required")
+	}
+
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	threads, err := s.comments.Threads(ctx, req.GetSubmissionId(), req.GetVersionId(), userID)
+	if err != nil {
+		if errors.Is(err, comment.ErrSubmissionNotFound) {
+			return nil, status.Error(codes.NotFound, "submission not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to list comment threads")
+	}
+
+	resp := &tasksv1.ListCommentThreadsResponse{
+		Threads: make([]*tasksv1.CommentThread, 0, len(threads)),
+	}
+
+	for _, thread := range threads {
+		resp.Threads = append(resp.Threads, toCommentThread(thread, userID))
+	}
+
+	return resp, nil
+}
+
+// StartCommentThread starts the discussion of the submission version,
+// optionally anchored to the value of the payload or the region of the image.
+func (s *serverAPI) StartCommentThread(
+	ctx context.Context,
+	req *tasksv1.StartCommentThreadRequest,
+) (*tasksv1.StartCommentThreadResponse, error) {
+	if req.GetSubmissionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "submission_id is required")
+	}
+
+	if req.GetVersionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "version_id is required")
+	}
+
+	if err := validateCommentBody(req.GetBody()); err != nil {
+		return nil, err
+	}
+
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	thread, err := s.comments.StartThread(
+		ctx,
+		req.GetSubmissionId(),
+		req.GetVersionId(),
+		userID,
+		fromCommentAnchor(req.GetAnchor()),
+		req.GetBody(),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, comment.ErrSubmissionNotFound):
+			return nil, status.Error(codes.NotFound, "submission not found")
+		case errors.Is(err, comment.ErrVersionNotFound):
+			return nil, status.Error(codes.NotFound, "submission version not found")
+		case errors.Is(err, comment.ErrInvalidAnchor):
+			return nil, status.Error(codes.InvalidArgument, "anchor does not match the submission version")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to start comment thread")
+	}
+
+	return &tasksv1.StartCommentThreadResponse{
+		Thread: toCommentThread(thread, userID),
+	}, nil
+}
+
+// ReplyToCommentThread adds the comment of the caller to the thread.
+func (s *serverAPI) ReplyToCommentThread(
+	ctx context.Context,
+	req *tasksv1.ReplyToCommentThreadRequest,
+) (*tasksv1.ReplyToCommentThreadResponse, error) {
+	if req.GetThreadId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "thread_id is required")
+	}
+
+	if err := validateCommentBody(req.GetBody()); err != nil {
+		return nil, err
+	}
+
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.comments.Reply(ctx, req.GetThreadId(), userID, req.GetBody())
+	if err != nil {
+		if errors.Is(err, comment.ErrThreadNotFound) {
+			return nil, status.Error(codes.NotFound, "comment thread not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to reply to comment thread")
+	}
+
+	return &tasksv1.ReplyToCommentThreadResponse{
+		Comment: toComment(c, userID),
+	}, nil
+}
+
+// EditComment changes the comment of the caller, keeping the previous text in the history.
+func (s *serverAPI) EditComment(
+	ctx context.Context,
+	req *tasksv1.EditCommentRequest,
+) (*tasksv1.EditCommentResponse, error) {
+	if req.GetCommentId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "comment_id is required")
+	}
+
+	if err := validateCommentBody(req.GetBody()); err != nil {
+		return nil, err
+	}
+
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.comments.EditComment(ctx, req.GetCommentId(), userID, req.GetBody())
+	if err != nil {
+		switch {
+		case errors.Is(err, comment.ErrCommentNotFound):
+			return nil, status.Error(codes.NotFound, "comment not found")
+		case errors.Is(err, comment.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "comment belongs to another user")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to edit comment")
+	}
+
+	return &tasksv1.EditCommentResponse{
+		Comment: toComment(c, userID),
+	}, nil
+}
+
+// ResolveCommentThread resolves or reopens the comment thread.
+func (s *serverAPI) ResolveCommentThread(
+	ctx context.Context,
+	req *tasksv1.ResolveCommentThreadRequest,
+) (*tasksv1.ResolveCommentThreadResponse, error) {
+	if req.GetThreadId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "thread_id is required")
+	}
+
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	thread, err := s.comments.ResolveThread(ctx, req.GetThreadId(), userID, req.GetResolved())
+	if err != nil {
+		if errors.Is(err, comment.ErrThreadNotFound) {
+			return nil, status.Error(codes.NotFound, "comment thread not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to resolve comment thread")
+	}
+
+	return &tasksv1.ResolveCommentThreadResponse{
+		Thread: toCommentThread(thread, userID),
+	}, nil
+}
+
+func validateCommentBody(body string) error {
+	if strings.TrimSpace(body) == "" {
+		return status.Error(codes.InvalidArgument, "body is required")
+	}
+
+	if utf8.RuneCountInString(body) > maxCommentLen {
+		return status.Error(codes.InvalidArgument, "body is too long")
+	}
+
+	return nil
+}
@@ -0,0 +1,753 @@
+package tasks
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/lib/qti"
+
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// toStruct converts raw JSON object to the protobuf struct.
+func toStruct(raw json.RawMessage) (*structpb.Struct, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var s structpb.Struct
+	if err := s.UnmarshalJSON(raw); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// fromStruct converts the protobuf struct to raw JSON object.
+func fromStruct(s *structpb.Struct) (json.RawMessage, error) {
+	if s == nil {
+		return json.RawMessage("{}"), nil
+	}
+
+	return s.MarshalJSON()
+}
+
+func toWidget(widget models.Widget) (*tasksv1.AssignmentWidget, error) {
+	config, err := toStruct(widget.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tasksv1.AssignmentWidget{
+		Type:    widget.Type,
+		Version: strconv.Itoa(widget.Version),
+		Config:  config,
+	}, nil
+}
+
+func toTemplate(template models.Template) (*tasksv1.AssignmentTemplate, error) {
+	widget, err := toWidget(template.Widget)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tasksv1.AssignmentTemplate{
+		Id:         template.ID,
+		CreatorId:  strconv.FormatInt(template.CreatorID, 10),
+		Title:      template.Title,
+		Widget:     widget,
+		Visibility: toVisibility(template.Visibility),
+		ClonedFrom: template.ClonedFrom,
+		CreatedAt:  timestamppb.New(template.CreatedAt),
+		UpdatedAt:  timestamppb.New(template.UpdatedAt),
+	}, nil
+}
+
+func toVisibility(visibility models.Visibility) tasksv1.TemplateVisibility {
+	switch visibility {
+	case models.VisibilityPrivate:
+		return tasksv1.TemplateVisibility_TEMPLATE_VISIBILITY_PRIVATE
+	case models.VisibilitySchool:
+		return tasksv1.TemplateVisibility_TEMPLATE_VISIBILITY_SCHOOL
+	case models.VisibilityPublic:
+		return tasksv1.TemplateVisibility_TEMPLATE_VISIBILITY_PUBLIC
+	default:
+		return tasksv1.TemplateVisibility_TEMPLATE_VISIBILITY_UNSPECIFIED
+	}
+}
+
+func fromVisibility(visibility tasksv1.TemplateVisibility) (models.Visibility, bool) {
+	switch visibility {
+	case tasksv1.TemplateVisibility_TEMPLATE_VISIBILITY_PRIVATE:
+		return models.VisibilityPrivate, true
+	case tasksv1.TemplateVisibility_TEMPLATE_VISIBILITY_SCHOOL:
+		return models.VisibilitySchool, true
+	case tasksv1.TemplateVisibility_TEMPLATE_VISIBILITY_PUBLIC:
+		return models.VisibilityPublic, true
+	default:
+		return "", false
+	}
+}
+
+func fromTemplateScope(scope tasksv1.TemplateScope) (models.TemplateScope, bool) {
+	switch scope {
+	case tasksv1.TemplateScope_TEMPLATE_SCOPE_MINE:
+		return models.TemplateScopeMine, true
+	case tasksv1.TemplateScope_TEMPLATE_SCOPE_SCHOOL:
+		return models.TemplateScopeSchool, true
+	case tasksv1.TemplateScope_TEMPLATE_SCOPE_PUBLIC:
+		return models.TemplateScopePublic, true
+	default:
+		return 0, false
+	}
+}
+
+func toQTIVersion(version qti.Version) tasksv1.QTIVersion {
+	switch version {
+	case qti.Version21:
+		return tasksv1.QTIVersion_QTI_VERSION_2_1
+	case qti.Version30:
+		return tasksv1.QTIVersion_QTI_VERSION_3_0
+	default:
+		return tasksv1.QTIVersion_QTI_VERSION_UNSPECIFIED
+	}
+}
+
+func fromQTIVersion(version tasksv1.QTIVersion) (qti.Version, bool) {
+	switch version {
+	case tasksv1.QTIVersion_QTI_VERSION_2_1:
+		return qti.Version21, true
+	case tasksv1.QTIVersion_QTI_VERSION_3_0:
+		return qti.Version30, true
+	default:
+		return "", false
+	}
+}
+
+func toUnsupportedQTIItems(items []qti.Unsupported) []*tasksv1.UnsupportedQTIItem {
+	res := make([]*tasksv1.UnsupportedQTIItem, 0, len(items))
+	for _, item := range items {
+		res = append(res, &tasksv1.UnsupportedQTIItem{
+			Identifier:  item.Identifier,
+			Interaction: item.Interaction,
+			Reason:      item.Reason,
+		})
+	}
+
+	return res
+}
+
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	return timestamppb.New(t)
+}
+
+func toAssignment(assignment models.Assignment) (*tasksv1.Assignment, error) {
+	widget, err := toWidget(assignment.Widget)
+	if err != nil {
+		return nil, err
+	}
+
+	studentIDs := assignment.StudentPseudonyms
+	if studentIDs == nil {
+		studentIDs = make([]string, 0, len(assignment.StudentIDs))
+		for _, id := range assignment.StudentIDs {
+			studentIDs = append(studentIDs, strconv.FormatInt(id, 10))
+		}
+	}
+
+	return &tasksv1.Assignment{
+		Id:          assignment.ID,
+		CreatorId:   strconv.FormatInt(assignment.CreatorID, 10),
+		Title:       assignment.Title,
+		TemplateId:  assignment.TemplateID,
+		StudentIds:  studentIDs,
+		Widget:      widget,
+		DueDate:     toTimestamp(assignment.DueDate),
+		CutoffDate:  toTimestamp(assignment.CutoffDate),
+		PublishAt:   toTimestamp(assignment.PublishAt),
+		PublishedAt: toTimestamp(assignment.PublishedAt),
+		PeerReview:  toPeerReviewSettings(assignment.PeerReview),
+		Anonymous:   assignment.Anonymous,
+
+		IdentitiesRevealedAt: toTimestamp(assignment.IdentitiesRevealedAt),
+	}, nil
+}
+
+// toStudentID returns the id of the student,
+// or the pseudonym if the student is hidden in the anonymous grading.
+func toStudentID(studentID int64, pseudonym string) string {
+	if pseudonym != "" {
+		return pseudonym
+	}
+
+	return strconv.FormatInt(studentID, 10)
+}
+
+// toStudentAssignment converts the assignment as it is seen by the student.
+// The id of the assignment is the id of the student assignment.
+func toStudentAssignment(assignment models.StudentAssignment) (*tasksv1.StudentAssignment, error) {
+	widget, err := toWidget(assignment.Assignment.Widget)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &tasksv1.StudentAssignment{
+		Assignment: &tasksv1.Assignment{
+			Id:         assignment.ID,
+			CreatorId:  strconv.FormatInt(assignment.Assignment.CreatorID, 10),
+			StudentId:  strconv.FormatInt(assignment.StudentID, 10),
+			Title:      assignment.Assignment.Title,
+			Widget:     widget,
+			DueDate:    toTimestamp(assignment.DueDate),
+			CutoffDate: toTimestamp(assignment.CutoffDate),
+		},
+		Feedback: assignment.Feedback,
+	}
+
+	if assignment.Submission != nil {
+		res.Submission, err = toSubmission(*assignment.Submission)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func toSubmission(submission models.Submission) (*tasksv1.Submission, error) {
+	res := &tasksv1.Submission{
+		Id:           submission.ID,
+		AssignmentId: submission.AssignmentID,
+		StudentId:    toStudentID(submission.StudentID, submission.StudentPseudonym),
+		Status:       toSubmissionStatus(submission.Status),
+		StartedAt:    toTimestamp(submission.StartedAt),
+		UpdatedAt:    toTimestamp(submission.UpdatedAt),
+		Revision:     submission.Revision,
+		SubmittedAt:  toTimestamp(submission.SubmittedAt),
+	}
+
+	if submission.CurrentVersion != nil {
+		var err error
+
+		res.CurrentVersion, err = toSubmissionVersion(*submission.CurrentVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func toSubmissionVersion(version models.SubmissionVersion) (*tasksv1.SubmissionVersion, error) {
+	payload, err := toStruct(version.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tasksv1.SubmissionVersion{
+		Id:            version.ID,
+		VersionNumber: int32(version.Number),
+		Payload:       payload,
+		IsLate:        version.IsLate,
+		CreatedAt:     toTimestamp(version.CreatedAt),
+		UpdatedAt:     toTimestamp(version.UpdatedAt),
+	}, nil
+}
+
+func toSubmissionStatus(status models.SubmissionStatus) tasksv1.SubmissionStatus {
+	switch status {
+	case models.StatusNotStarted:
+		return tasksv1.SubmissionStatus_SUBMISSION_STATUS_NOT_STARTED
+	case models.StatusInProgress:
+		return tasksv1.SubmissionStatus_SUBMISSION_STATUS_IN_PROGRESS
+	case models.StatusSubmitted:
+		return tasksv1.SubmissionStatus_SUBMISSION_STATUS_SUBMITTED
+	case models.StatusGraded:
+		return tasksv1.SubmissionStatus_SUBMISSION_STATUS_GRADED
+	case models.StatusReturned:
+		return tasksv1.SubmissionStatus_SUBMISSION_STATUS_RETURNED
+	default:
+		return tasksv1.SubmissionStatus_SUBMISSION_STATUS_UNSPECIFIED
+	}
+}
+
+// fromWidget converts the widget of the request.
+// Version of the widget is a number, so the invalid version is reported.
+func fromWidget(widget *tasksv1.AssignmentWidget) (models.Widget, error) {
+	version, err := strconv.Atoi(widget.GetVersion())
+	if err != nil {
+		return models.Widget{}, err
+	}
+
+	config, err := fromStruct(widget.GetConfig())
+	if err != nil {
+		return models.Widget{}, err
+	}
+
+	return models.Widget{
+		Type:    widget.GetType(),
+		Version: version,
+		Config:  config,
+	}, nil
+}
+
+func fromUserIDs(ids []string) ([]int64, error) {
+	res := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		userID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, userID)
+	}
+
+	return res, nil
+}
+
+func toTrashItem(item models.TrashItem) *tasksv1.TrashItem {
+	kind := tasksv1.TrashItemKind_TRASH_ITEM_KIND_ASSIGNMENT
+	if item.Kind == models.TrashSubmission {
+		kind = tasksv1.TrashItemKind_TRASH_ITEM_KIND_SUBMISSION
+	}
+
+	return &tasksv1.TrashItem{
+		Id:        item.ID,
+		Kind:      kind,
+		Title:     item.Title,
+		DeletedAt: toTimestamp(item.DeletedAt),
+	}
+}
+
+func toAttachment(attachment models.Attachment) *tasksv1.Attachment {
+	res := &tasksv1.Attachment{
+		Id:           attachment.ID,
+		SubmissionId: attachment.SubmissionID,
+		Filename:     attachment.Filename,
+		ContentType:  attachment.ContentType,
+		Size:         attachment.Size,
+		Checksum:     attachment.Checksum,
+		CreatedAt:    toTimestamp(attachment.CreatedAt),
+	}
+
+	if attachment.Preview != nil {
+		res.PreviewStatus = toPreviewStatus(attachment.Preview.Status)
+		res.ThumbnailUrl = attachment.Preview.ThumbnailURL
+		res.PreviewUrl = attachment.Preview.PreviewURL
+	}
+
+	return res
+}
+
+func toPreviewStatus(status models.PreviewStatus) tasksv1.PreviewStatus {
+	switch status {
+	case models.PreviewPending:
+		return tasksv1.PreviewStatus_PREVIEW_STATUS_PENDING
+	case models.PreviewProcessing:
+		return tasksv1.PreviewStatus_PREVIEW_STATUS_PROCESSING
+	case models.PreviewReady:
+		return tasksv1.PreviewStatus_PREVIEW_STATUS_READY
+	case models.PreviewFailed:
+		return tasksv1.PreviewStatus_PREVIEW_STATUS_FAILED
+	default:
+		return tasksv1.PreviewStatus_PREVIEW_STATUS_UNSPECIFIED
+	}
+}
+
+func fromRendition(rendition tasksv1.AttachmentRendition) models.Rendition {
+	switch rendition {
+	case tasksv1.AttachmentRendition_ATTACHMENT_RENDITION_THUMBNAIL:
+		return models.RenditionThumbnail
+	case tasksv1.AttachmentRendition_ATTACHMENT_RENDITION_PREVIEW:
+		return models.RenditionPreview
+	default:
+		return models.RenditionOriginal
+	}
+}
+
+func toSimilarPair(pair models.SimilarPair) *tasksv1.SimilarPair {
+	fragments := make([]*tasksv1.MatchedFragment, 0, len(pair.Fragments))
+	for _, f := range pair.Fragments {
+		fragments = append(fragments, &tasksv1.MatchedFragment{
+			Text:      f.Text,
+			OtherText: f.OtherText,
+		})
+	}
+
+	return &tasksv1.SimilarPair{
+		SubmissionId:      pair.SubmissionID,
+		StudentId:         pair.StudentID,
+		OtherSubmissionId: pair.OtherSubmissionID,
+		OtherStudentId:    pair.OtherStudentID,
+		Score:             pair.Score,
+		Fragments:         fragments,
+
+		StudentPseudonym:      pair.StudentPseudonym,
+		OtherStudentPseudonym: pair.OtherStudentPseudonym,
+	}
+}
+
+func toIdentityReveal(reveal models.IdentityReveal) *tasksv1.IdentityReveal {
+	return &tasksv1.IdentityReveal{
+		TeacherId:  strconv.FormatInt(reveal.TeacherID, 10),
+		Reason:     reveal.Reason,
+		RevealedAt: toTimestamp(reveal.RevealedAt),
+	}
+}
+
+func toPeerReviewSettings(settings models.PeerReviewSettings) *tasksv1.PeerReviewSettings {
+	if !settings.Enabled() {
+		return nil
+	}
+
+	return &tasksv1.PeerReviewSettings{
+		ReviewersCount: int32(settings.ReviewersCount),
+		DueDate:        toTimestamp(settings.DueDate),
+		Criteria:       toRubricCriteria(settings.Criteria),
+		AssignedAt:     toTimestamp(settings.AssignedAt),
+	}
+}
+
+func fromPeerReviewSettings(settings *tasksv1.PeerReviewSettings) models.PeerReviewSettings {
+	if settings == nil {
+		return models.PeerReviewSettings{}
+	}
+
+	res := models.PeerReviewSettings{
+		ReviewersCount: int(settings.GetReviewersCount()),
+		Criteria:       make([]models.RubricCriterion, 0, len(settings.GetCriteria())),
+	}
+
+	if settings.GetDueDate() != nil {
+		res.DueDate = settings.GetDueDate().AsTime()
+	}
+
+	for _, c := range settings.GetCriteria() {
+		res.Criteria = append(res.Criteria, models.RubricCriterion{
+			ID:          c.GetId(),
+			Title:       c.GetTitle(),
+			Description: c.GetDescription(),
+			MaxScore:    c.GetMaxScore(),
+		})
+	}
+
+	return res
+}
+
+func toRubricCriteria(criteria []models.RubricCriterion) []*tasksv1.RubricCriterion {
+	res := make([]*tasksv1.RubricCriterion, 0, len(criteria))
+	for _, c := range criteria {
+		res = append(res, &tasksv1.RubricCriterion{
+			Id:          c.ID,
+			Title:       c.Title,
+			Description: c.Description,
+			MaxScore:    c.MaxScore,
+		})
+	}
+
+	return res
+}
+
+func toCriterionScores(scores []models.CriterionScore) []*tasksv1.CriterionScore {
+	res := make([]*tasksv1.CriterionScore, 0, len(scores))
+	for _, s := range scores {
+		res = append(res, &tasksv1.CriterionScore{
+			CriterionId: s.CriterionID,
+			Score:       s.Score,
+		})
+	}
+
+	return res
+}
+
+func fromCriterionScores(scores []*tasksv1.CriterionScore) []models.CriterionScore {
+	res := make([]models.CriterionScore, 0, len(scores))
+	for _, s := range scores {
+		res = append(res, models.CriterionScore{
+			CriterionID: s.GetCriterionId(),
+			Score:       s.GetScore(),
+		})
+	}
+
+	return res
+}
+
+// toPeerReview converts the review without the reviewer and the author.
+func toPeerReview(review models.PeerReview) *tasksv1.PeerReview {
+	status := tasksv1.PeerReviewStatus_PEER_REVIEW_STATUS_PENDING
+	if review.Status == models.PeerReviewSubmitted {
+		status = tasksv1.PeerReviewStatus_PEER_REVIEW_STATUS_SUBMITTED
+	}
+
+	return &tasksv1.PeerReview{
+		Id:              review.ID,
+		AssignmentId:    review.AssignmentID,
+		AssignmentTitle: review.AssignmentTitle,
+		Status:          status,
+		DueDate:         toTimestamp(review.DueDate),
+		Criteria:        toRubricCriteria(review.Criteria),
+		Scores:          toCriterionScores(review.Scores),
+		Comment:         review.Comment,
+		SubmittedAt:     toTimestamp(review.SubmittedAt),
+	}
+}
+
+func toPeerReviewSummary(summary models.PeerReviewSummary) *tasksv1.PeerReviewSummary {
+	return &tasksv1.PeerReviewSummary{
+		SubmissionId:     summary.SubmissionID,
+		StudentId:        toStudentID(summary.StudentID, summary.StudentPseudonym),
+		ReviewsAssigned:  int32(summary.ReviewsAssigned),
+		ReviewsSubmitted: int32(summary.ReviewsSubmitted),
+		Score:            summary.Score,
+		MaxScore:         summary.MaxScore,
+		Criteria:         toCriterionScores(summary.Criteria),
+		TeacherFeedback:  summary.TeacherFeedback,
+	}
+}
+
+// toCommentThread converts the thread as it is seen by the user,
+// whose own comments are marked as mine.
+func toCommentThread(thread models.CommentThread, userID int64) *tasksv1.CommentThread {
+	comments := make([]*tasksv1.Comment, 0, len(thread.Comments))
+	for _, c := range thread.Comments {
+		comments = append(comments, toComment(c, userID))
+	}
+
+	return &tasksv1.CommentThread{
+		Id:            thread.ID,
+		SubmissionId:  thread.SubmissionID,
+		VersionId:     thread.VersionID,
+		VersionNumber: int32(thread.VersionNumber),
+		Anchor:        toCommentAnchor(thread.Anchor),
+		Comments:      comments,
+		Resolved:      thread.Resolved(),
+		ResolvedBy:    toCommentAuthorRole(thread.ResolvedBy),
+		ResolvedAt:    toTimestamp(thread.ResolvedAt),
+		CreatedAt:     toTimestamp(thread.CreatedAt),
+	}
+}
+
+func toComment(comment models.Comment, userID int64) *tasksv1.Comment {
+	revisions := make([]*tasksv1.CommentRevision, 0, len(comment.Revisions))
+	for _, r := range comment.Revisions {
+		revisions = append(revisions, &tasksv1.CommentRevision{
+			Body:      r.Body,
+			CreatedAt: toTimestamp(r.CreatedAt),
+		})
+	}
+
+	return &tasksv1.Comment{
+		Id:         comment.ID,
+		ThreadId:   comment.ThreadID,
+		AuthorRole: toCommentAuthorRole(comment.Author),
+		Mine:       comment.AuthorID == userID,
+		Body:       comment.Body,
+		CreatedAt:  toTimestamp(comment.CreatedAt),
+		EditedAt:   toTimestamp(comment.EditedAt),
+		Revisions:  revisions,
+	}
+}
+
+func toCommentAuthorRole(author models.CommentAuthor) tasksv1.CommentAuthorRole {
+	switch author {
+	case models.CommentByStudent:
+		return tasksv1.CommentAuthorRole_COMMENT_AUTHOR_ROLE_STUDENT
+	case models.CommentByTeacher:
+		return tasksv1.CommentAuthorRole_COMMENT_AUTHOR_ROLE_TEACHER
+	default:
+		return tasksv1.CommentAuthorRole_COMMENT_AUTHOR_ROLE_UNSPECIFIED
+	}
+}
+
+func toCommentAnchor(anchor *models.CommentAnchor) *tasksv1.CommentAnchor {
+	switch {
+	case anchor == nil:
+		return nil
+	case anchor.Region != nil:
+		return &tasksv1.CommentAnchor{
+			Target: &tasksv1.CommentAnchor_ImageRegion{
+				ImageRegion: &tasksv1.ImageRegion{
+					AttachmentId: anchor.Region.AttachmentID,
+					X:            anchor.Region.X,
+					Y:            anchor.Region.Y,
+					Width:        anchor.Region.Width,
+					Height:       anchor.Region.Height,
+				},
+			},
+		}
+	default:
+		return &tasksv1.CommentAnchor{
+			Target: &tasksv1.CommentAnchor_JsonPointer{
+				JsonPointer: anchor.Pointer,
+			},
+		}
+	}
+}
+
+// fromCommentAnchor returns nil if the anchor has no target.
+func fromCommentAnchor(anchor *tasksv1.CommentAnchor) *models.CommentAnchor {
+	switch target := anchor.GetTarget().(type) {
+	case *tasksv1.CommentAnchor_JsonPointer:
+		return &models.CommentAnchor{
+			Pointer: target.JsonPointer,
+		}
+	case *tasksv1.CommentAnchor_ImageRegion:
+		return &models.CommentAnchor{
+			Region: &models.ImageRegion{
+				AttachmentID: target.ImageRegion.GetAttachmentId(),
+				X:            target.ImageRegion.GetX(),
+				Y:            target.ImageRegion.GetY(),
+				Width:        target.ImageRegion.GetWidth(),
+				Height:       target.ImageRegion.GetHeight(),
+			},
+		}
+	default:
+		return nil
+	}
+}
+
+func toRegradeRequest(request models.RegradeRequest) *tasksv1.RegradeRequest {
+	return &tasksv1.RegradeRequest{
+		Id:              request.ID,
+		SubmissionId:    request.SubmissionID,
+		AssignmentId:    request.AssignmentID,
+		AssignmentTitle: request.AssignmentTitle,
+		Reason:          request.Reason,
+		Status:          toRegradeStatus(request.Status),
+		Resolution:      request.Resolution,
+		OldScore:        request.OldScore,
+		NewScore:        request.NewScore,
+		CreatedAt:       toTimestamp(request.CreatedAt),
+		ResolvedAt:      toTimestamp(request.ResolvedAt),
+	}
+}
+
+func toRegradeStatus(status models.RegradeStatus) tasksv1.RegradeStatus {
+	switch status {
+	case models.RegradeOpen:
+		return tasksv1.RegradeStatus_REGRADE_STATUS_OPEN
+	case models.RegradeAccepted:
+		return tasksv1.RegradeStatus_REGRADE_STATUS_ACCEPTED
+	case models.RegradeRejected:
+		return tasksv1.RegradeStatus_REGRADE_STATUS_REJECTED
+	default:
+		return tasksv1.RegradeStatus_REGRADE_STATUS_UNSPECIFIED
+	}
+}
+
+func toSubmissionEvent(event models.SubmissionEvent) *tasksv1.SubmissionEvent {
+	return &tasksv1.SubmissionEvent{
+		Id:        event.ID,
+		Kind:      toSubmissionEventKind(event.Kind),
+		Status:    toSubmissionStatus(event.Status),
+		Score:     event.Score,
+		Comment:   event.Comment,
+		RegradeId: event.RegradeID,
+		CreatedAt: toTimestamp(event.CreatedAt),
+	}
+}
+
+func toSubmissionEventKind(kind models.SubmissionEventKind) tasksv1.SubmissionEventKind {
+	switch kind {
+	case models.EventFeedbackPublished:
+		return tasksv1.SubmissionEventKind_SUBMISSION_EVENT_KIND_FEEDBACK_PUBLISHED
+	case models.EventRegradeRequested:
+		return tasksv1.SubmissionEventKind_SUBMISSION_EVENT_KIND_REGRADE_REQUESTED
+	case models.EventRegradeResolved:
+		return tasksv1.SubmissionEventKind_SUBMISSION_EVENT_KIND_REGRADE_RESOLVED
+	default:
+		return tasksv1.SubmissionEventKind_SUBMISSION_EVENT_KIND_UNSPECIFIED
+	}
+}
+
+func toModeration(moderation models.Moderation) (*tasksv1.Moderation, error) {
+	res := &tasksv1.Moderation{
+		AssignmentId:    moderation.AssignmentID,
+		AssignmentTitle: moderation.AssignmentTitle,
+		RequestedBy:     strconv.FormatInt(moderation.RequestedBy, 10),
+		ModeratorId:     strconv.FormatInt(moderation.ModeratorID, 10),
+		Items:           make([]*tasksv1.ModerationItem, 0, len(moderation.Items)),
+		CreatedAt:       toTimestamp(moderation.CreatedAt),
+		CompletedAt:     toTimestamp(moderation.CompletedAt),
+	}
+
+	for _, item := range moderation.Items {
+		payload, err := toStruct(item.Payload)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Items = append(res.Items, &tasksv1.ModerationItem{
+			Id:            item.ID,
+			SubmissionId:  item.SubmissionID,
+			VersionId:     item.VersionID,
+			Payload:       payload,
+			Feedback:      item.FeedbackText,
+			Score:         item.OriginalScore,
+			Decision:      toModerationDecision(item.Decision),
+			AdjustedScore: item.AdjustedScore,
+			Comment:       item.Comment,
+			ModeratedAt:   toTimestamp(item.ModeratedAt),
+		})
+	}
+
+	return res, nil
+}
+
+func toModerationDecision(decision models.ModerationDecision) tasksv1.ModerationDecision {
+	switch decision {
+	case models.ModerationPending:
+		return tasksv1.ModerationDecision_MODERATION_DECISION_PENDING
+	case models.ModerationAgreed:
+		return tasksv1.ModerationDecision_MODERATION_DECISION_AGREED
+	case models.ModerationAdjusted:
+		return tasksv1.ModerationDecision_MODERATION_DECISION_ADJUSTED
+	default:
+		return tasksv1.ModerationDecision_MODERATION_DECISION_UNSPECIFIED
+	}
+}
+
+func toSearchResult(result models.SearchResult) *tasksv1.SearchResult {
+	return &tasksv1.SearchResult{
+		Kind:       toSearchResultKind(result.Kind),
+		Id:         result.ID,
+		Title:      result.Title,
+		WidgetType: result.WidgetType,
+		CreatorId:  strconv.FormatInt(result.CreatorID, 10),
+		Rank:       result.Rank,
+		UpdatedAt:  toTimestamp(result.UpdatedAt),
+	}
+}
+
+func toSearchResultKind(kind models.SearchKind) tasksv1.SearchResultKind {
+	switch kind {
+	case models.SearchAssignment:
+		return tasksv1.SearchResultKind_SEARCH_RESULT_KIND_ASSIGNMENT
+	case models.SearchTemplate:
+		return tasksv1.SearchResultKind_SEARCH_RESULT_KIND_TEMPLATE
+	default:
+		return tasksv1.SearchResultKind_SEARCH_RESULT_KIND_UNSPECIFIED
+	}
+}
+
+func fromSearchResultKind(kind tasksv1.SearchResultKind) (models.SearchKind, bool) {
+	switch kind {
+	case tasksv1.SearchResultKind_SEARCH_RESULT_KIND_UNSPECIFIED:
+		return "", true
+	case tasksv1.SearchResultKind_SEARCH_RESULT_KIND_ASSIGNMENT:
+		return models.SearchAssignment, true
+	case tasksv1.SearchResultKind_SEARCH_RESULT_KIND_TEMPLATE:
+		return models.SearchTemplate, true
+	default:
+		return "", false
+	}
+}
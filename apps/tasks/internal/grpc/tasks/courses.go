@@ -0,0 +1,179 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+
+	"tasks/internal/lib/bundle"
+	"tasks/internal/services/course"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// CreateCourse creates the empty course of the caller.
+func (s *serverAPI) CreateCourse(
+	ctx context.Context,
+	req *tasksv1.CreateCourseRequest,
+) (*tasksv1.CreateCourseResponse, error) {
+	if req.GetTitle() == "" {
+		return nil, status.Error(codes.InvalidArgument, "title is required")
+	}
+
+	userID, schoolID, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	courseID, err := s.courses.CreateCourse(ctx, userID, schoolID, req.GetTitle(), req.GetDescription())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to create course")
+	}
+
+	return &tasksv1.CreateCourseResponse{
+		CourseId: courseID,
+	}, nil
+}
+
+// AddCourseSection appends the section with the templates to the course of the caller.
+func (s *serverAPI) AddCourseSection(
+	ctx context.Context,
+	req *tasksv1.AddCourseSectionRequest,
+) (*tasksv1.AddCourseSectionResponse, error) {
+	if err := validateAddCourseSection(req); err != nil {
+		return nil, err
+	}
+
+	userID, schoolID, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sectionID, err := s.courses.AddCourseSection(
+		ctx,
+		req.GetCourseId(),
+		userID,
+		schoolID,
+		req.GetTitle(),
+		req.GetTemplateIds(),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, course.ErrCourseNotFound):
+			return nil, status.Error(codes.NotFound, "course not found")
+		case errors.Is(err, course.ErrTemplateNotFound):
+			return nil, status.Error(codes.NotFound, "template not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to add course section")
+	}
+
+	return &tasksv1.AddCourseSectionResponse{
+		SectionId: sectionID,
+	}, nil
+}
+
+// ExportCourse packs the course of the caller to the portable bundle.
+func (s *serverAPI) ExportCourse(
+	ctx context.Context,
+	req *tasksv1.ExportCourseRequest,
+) (*tasksv1.ExportCourseResponse, error) {
+	if req.GetCourseId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "course_id is required")
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.courses.ExportCourse(ctx, req.GetCourseId(), userID)
+	if err != nil {
+		if errors.Is(err, course.ErrCourseNotFound) {
+			return nil, status.Error(codes.NotFound, "course not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to export course")
+	}
+
+	return &tasksv1.ExportCourseResponse{
+		Bundle:        data,
+		FormatVersion: bundle.Version,
+	}, nil
+}
+
+// ImportCourse validates the bundle and, if requested, creates the course from it.
+func (s *serverAPI) ImportCourse(
+	ctx context.Context,
+	req *tasksv1.ImportCourseRequest,
+) (*tasksv1.ImportCourseResponse, error) {
+	if len(req.GetBundle()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "bundle is required")
+	}
+
+	userID, schoolID, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.courses.ImportCourse(ctx, userID, schoolID, req.GetBundle(), req.GetApply())
+	if err != nil {
+		if errors.Is(err, course.ErrInvalidBundle) {
+			return nil, status.Error(codes.InvalidArgument, "invalid course bundle")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to import course")
+	}
+
+	resp := &tasksv1.ImportCourseResponse{
+		Applied:  res.Applied,
+		CourseId: res.CourseID,
+		Mappings: make([]*tasksv1.ImportMapping, 0, len(res.Mappings)),
+		Problems: make([]*tasksv1.BundleProblem, 0, len(res.Problems)),
+	}
+
+	for _, m := range res.Mappings {
+		resp.Mappings = append(resp.Mappings, &tasksv1.ImportMapping{
+			Kind:     m.Kind,
+			SourceId: m.SourceID,
+			TargetId: m.TargetID,
+		})
+	}
+
+	for _, p := range res.Problems {
+		resp.Problems = append(resp.Problems, &tasksv1.BundleProblem{
+			Ref:    p.Ref,
+			Reason: p.Reason,
+		})
+	}
+
+	return resp, nil
+}
+
+// validateAddCourseSection validates the request of the new section.
+// The course, the title and the distinct templates must be provided.
+func validateAddCourseSection(req *tasksv1.AddCourseSectionRequest) error {
+	if req.GetCourseId() == "" {
+		return status.Error(codes.InvalidArgument, "course_id is required")
+	}
+
+	if req.GetTitle() == "" {
+		return status.Error(codes.InvalidArgument, "title is required")
+	}
+
+	listed := make(map[string]bool, len(req.GetTemplateIds()))
+	for _, templateID := range req.GetTemplateIds() {
+		if templateID == "" {
+			return status.Error(codes.InvalidArgument, "template_ids must not be empty")
+		}
+
+		if listed[templateID] {
+			return status.Error(codes.InvalidArgument, "template_ids must be distinct")
+		}
+		listed[templateID] = true
+	}
+
+	return nil
+}
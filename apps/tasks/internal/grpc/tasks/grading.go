@@ -0,0 +1,278 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/services/grading"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// ProvideFeedback saves the draft feedback of the calling teacher on the submission.
+func (s *serverAPI) ProvideFeedback(
+	ctx context.Context,
+	req *tasksv1.ProvideFeedbackRequest,
+) (*emptypb.Empty, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	if req.Score != nil && !validScore(req.GetScore()) {
+		return nil, status.Error(codes.InvalidArgument, "score must be a non-negative number")
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.grading.ProvideFeedback(ctx, req.GetId(), userID, req.GetFeedback(), req.Score)
+	if err != nil {
+		switch {
+		case errors.Is(err, grading.ErrSubmissionNotFound):
+			return nil, status.Error(codes.NotFound, "submission not found")
+		case errors.Is(err, grading.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "submission belongs to another teacher")
+		case errors.Is(err, grading.ErrNotSubmitted):
+			return nil, status.Error(codes.FailedPrecondition, "submission is not submitted")
+		case errors.Is(err, grading.ErrModerated):
+			return nil, status.Error(codes.FailedPrecondition, "grade is moderated and cannot be changed")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to provide feedback")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ReturnSubmission publishes the feedback on the submission version to the student.
+func (s *serverAPI) ReturnSubmission(
+	ctx context.Context,
+	req *tasksv1.ReturnSubmissionRequest,
+) (*emptypb.Empty, error) {
+	if req.GetSubmissionVersionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "submission_version_id is required")
+	}
+
+	var st models.SubmissionStatus
+	switch req.GetStatus() {
+	case tasksv1.SubmissionStatus_SUBMISSION_STATUS_GRADED:
+		st = models.StatusGraded
+	case tasksv1.SubmissionStatus_SUBMISSION_STATUS_RETURNED:
+		st = models.StatusReturned
+	default:
+		return nil, status.Error(codes.InvalidArgument, "status must be GRADED or RETURNED")
+	}
+
+	if req.Score != nil && !validScore(req.GetScore()) {
+		return nil, status.Error(codes.InvalidArgument, "score must be a non-negative number")
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.grading.ReturnSubmission(ctx, req.GetSubmissionVersionId(), userID, st, req.GetFeedback(), req.Score)
+	if err != nil {
+		switch {
+		case errors.Is(err, grading.ErrVersionNotFound):
+			return nil, status.Error(codes.NotFound, "submission version not found")
+		case errors.Is(err, grading.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "submission belongs to another teacher")
+		case errors.Is(err, grading.ErrNotSubmitted):
+			return nil, status.Error(codes.FailedPrecondition, "submission is not submitted")
+		case errors.Is(err, grading.ErrNotCurrentVersion):
+			return nil, status.Error(codes.FailedPrecondition, "only the current version can be returned")
+		case errors.Is(err, grading.ErrModerationPending):
+			return nil, status.Error(codes.FailedPrecondition, "moderation of the assignment is not completed")
+		case errors.Is(err, grading.ErrModerated):
+			return nil, status.Error(codes.FailedPrecondition, "moderated score cannot be changed")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to return submission")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// RequestRegrade disputes the grade of the submission of the calling student.
+func (s *serverAPI) RequestRegrade(
+	ctx context.Context,
+	req *tasksv1.RequestRegradeRequest,
+) (*tasksv1.RequestRegradeResponse, error) {
+	if req.GetSubmissionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "submission_id is required")
+	}
+
+	if strings.TrimSpace(req.GetReason()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "reason is required")
+	}
+
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := s.grading.RequestRegrade(ctx, req.GetSubmissionId(), userID, req.GetReason())
+	if err != nil {
+		switch {
+		case errors.Is(err, grading.ErrSubmissionNotFound):
+			return nil, status.Error(codes.NotFound, "submission not found")
+		case errors.Is(err, grading.ErrNotGraded):
+			return nil, status.Error(codes.FailedPrecondition, "submission is not graded")
+		case errors.Is(err, grading.ErrRegradeWindow):
+			return nil, status.Error(codes.FailedPrecondition, "regrade window has closed")
+		case errors.Is(err, grading.ErrRegradeAlreadyOpen):
+			return nil, status.Error(codes.AlreadyExists, "regrade request is already open")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to request regrade")
+	}
+
+	return &tasksv1.RequestRegradeResponse{
+		Regrade: toRegradeRequest(request),
+	}, nil
+}
+
+// ResolveRegrade accepts or rejects the regrade request to the calling teacher.
+func (s *serverAPI) ResolveRegrade(
+	ctx context.Context,
+	req *tasksv1.ResolveRegradeRequest,
+) (*tasksv1.ResolveRegradeResponse, error) {
+	if req.GetRegradeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "regrade_id is required")
+	}
+
+	var outcome models.RegradeStatus
+	switch req.GetOutcome() {
+	case tasksv1.RegradeStatus_REGRADE_STATUS_ACCEPTED:
+		outcome = models.RegradeAccepted
+	case tasksv1.RegradeStatus_REGRADE_STATUS_REJECTED:
+		outcome = models.RegradeRejected
+	default:
+		return nil, status.Error(codes.InvalidArgument, "outcome must be ACCEPTED or REJECTED")
+	}
+
+	if req.NewScore != nil {
+		if outcome != models.RegradeAccepted {
+			return nil, status.Error(codes.InvalidArgument, "new_score can be set only if accepted")
+		}
+
+		if !validScore(req.GetNewScore()) {
+			return nil, status.Error(codes.InvalidArgument, "new_score must be a non-negative number")
+		}
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := s.grading.ResolveRegrade(
+		ctx,
+		req.GetRegradeId(),
+		userID,
+		outcome,
+		req.GetResolution(),
+		req.NewScore,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, grading.ErrRegradeNotFound):
+			return nil, status.Error(codes.NotFound, "regrade request not found")
+		case errors.Is(err, grading.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "regrade request belongs to another teacher")
+		case errors.Is(err, grading.ErrRegradeResolved):
+			return nil, status.Error(codes.FailedPrecondition, "regrade request is already resolved")
+		case errors.Is(err, grading.ErrModerated):
+			return nil, status.Error(codes.FailedPrecondition, "moderated score cannot be changed")
+		case errors.Is(err, grading.ErrNotGraded):
+			return nil, status.Error(codes.FailedPrecondition, "submission is not graded")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to resolve regrade")
+	}
+
+	return &tasksv1.ResolveRegradeResponse{
+		Regrade: toRegradeRequest(request),
+	}, nil
+}
+
+// ListOpenRegrades lists the open regrade requests to the calling teacher, the oldest first.
+func (s *serverAPI) ListOpenRegrades(
+	ctx context.Context,
+	req *tasksv1.ListOpenRegradesRequest,
+) (*tasksv1.ListOpenRegradesResponse, error) {
+	filter, err := pageFilter(req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := s.grading.OpenRegrades(ctx, userID, filter)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list regrade requests")
+	}
+
+	resp := &tasksv1.ListOpenRegradesResponse{
+		Regrades:      make([]*tasksv1.RegradeRequest, 0, len(requests)),
+		NextPageToken: nextPageToken(filter, len(requests)),
+	}
+
+	for _, request := range requests {
+		resp.Regrades = append(resp.Regrades, toRegradeRequest(request))
+	}
+
+	return resp, nil
+}
+
+// GetSubmissionHistory returns the history of the grading of the submission.
+func (s *serverAPI) GetSubmissionHistory(
+	ctx context.Context,
+	req *tasksv1.GetSubmissionHistoryRequest,
+) (*tasksv1.GetSubmissionHistoryResponse, error) {
+	if req.GetSubmissionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "submission_id is required")
+	}
+
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.grading.History(ctx, req.GetSubmissionId(), userID)
+	if err != nil {
+		if errors.Is(err, grading.ErrSubmissionNotFound) {
+			return nil, status.Error(codes.NotFound, "submission not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to get submission history")
+	}
+
+	resp := &tasksv1.GetSubmissionHistoryResponse{
+		Events: make([]*tasksv1.SubmissionEvent, 0, len(events)),
+	}
+
+	for _, event := range events {
+		resp.Events = append(resp.Events, toSubmissionEvent(event))
+	}
+
+	return resp, nil
+}
+
+func validScore(score float64) bool {
+	return score >= 0 && !math.IsInf(score, 0) && !math.IsNaN(score)
+}
@@ -0,0 +1,221 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"unicode/utf8"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/services/moderation"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// RequestModeration assigns the random sample of the graded submissions
+// of the calling teacher's assignment to the head teacher.
+func (s *serverAPI) RequestModeration(
+	ctx context.Context,
+	req *tasksv1.RequestModerationRequest,
+) (*tasksv1.RequestModerationResponse, error) {
+	if req.GetAssignmentId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "assignment_id is required")
+	}
+
+	moderatorID, err := strconv.ParseInt(req.GetModeratorId(), 10, 64)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid moderator_id")
+	}
+
+	if req.GetSampleSize() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "sample_size must be positive")
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := s.moderation.RequestModeration(
+		ctx,
+		req.GetAssignmentId(),
+		userID,
+		moderatorID,
+		int(req.GetSampleSize()),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, moderation.ErrAssignmentNotFound):
+			return nil, status.Error(codes.NotFound, "assignment not found")
+		case errors.Is(err, moderation.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "assignment belongs to another teacher")
+		case errors.Is(err, moderation.ErrSelfModeration):
+			return nil, status.Error(codes.InvalidArgument, "teacher cannot moderate own grades")
+		case errors.Is(err, moderation.ErrNothingToModerate):
+			return nil, status.Error(codes.FailedPrecondition, "assignment has no graded submissions")
+		case errors.Is(err, moderation.ErrModerationAlreadyExists):
+			return nil, status.Error(codes.AlreadyExists, "assignment is already moderated")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to request moderation")
+	}
+
+	res, err := toModeration(m)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to request moderation")
+	}
+
+	return &tasksv1.RequestModerationResponse{
+		Moderation: res,
+	}, nil
+}
+
+// GetModeration returns the moderation of the assignment
+// to its teacher or to the moderator.
+func (s *serverAPI) GetModeration(
+	ctx context.Context,
+	req *tasksv1.GetModerationRequest,
+) (*tasksv1.GetModerationResponse, error) {
+	if req.GetAssignmentId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "assignment_id is required")
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := s.moderation.Moderation(ctx, req.GetAssignmentId(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, moderation.ErrModerationNotFound),
+			errors.Is(err, moderation.ErrAssignmentNotFound):
+			return nil, status.Error(codes.NotFound, "moderation not found")
+		case errors.Is(err, moderation.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "assignment belongs to another teacher")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to get moderation")
+	}
+
+	res, err := toModeration(m)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get moderation")
+	}
+
+	return &tasksv1.GetModerationResponse{
+		Moderation: res,
+	}, nil
+}
+
+// ListPendingModerations lists the moderations waiting for the calling head teacher.
+func (s *serverAPI) ListPendingModerations(
+	ctx context.Context,
+	req *tasksv1.ListPendingModerationsRequest,
+) (*tasksv1.ListPendingModerationsResponse, error) {
+	filter, err := pageFilter(req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := headTeacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	moderations, err := s.moderation.PendingModerations(ctx, userID, filter)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list moderations")
+	}
+
+	resp := &tasksv1.ListPendingModerationsResponse{
+		Moderations:   make([]*tasksv1.Moderation, 0, len(moderations)),
+		NextPageToken: nextPageToken(filter, len(moderations)),
+	}
+
+	for _, m := range moderations {
+		res, err := toModeration(m)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to list moderations")
+		}
+
+		resp.Moderations = append(resp.Moderations, res)
+	}
+
+	return resp, nil
+}
+
+// RecordModerationDecision records the agreement of the calling head teacher
+// with the grade or the adjusted score of the submission of the sample.
+func (s *serverAPI) RecordModerationDecision(
+	ctx context.Context,
+	req *tasksv1.RecordModerationDecisionRequest,
+) (*tasksv1.RecordModerationDecisionResponse, error) {
+	if req.GetItemId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "item_id is required")
+	}
+
+	var decision models.ModerationDecision
+	switch req.GetDecision() {
+	case tasksv1.ModerationDecision_MODERATION_DECISION_AGREED:
+		decision = models.ModerationAgreed
+	case tasksv1.ModerationDecision_MODERATION_DECISION_ADJUSTED:
+		decision = models.ModerationAdjusted
+	default:
+		return nil, status.Error(codes.InvalidArgument, "decision must be AGREED or ADJUSTED")
+	}
+
+	if decision == models.ModerationAdjusted && req.AdjustedScore == nil {
+		return nil, status.Error(codes.InvalidArgument, "adjusted_score is required if adjusted")
+	}
+
+	if req.AdjustedScore != nil {
+		if decision != models.ModerationAdjusted {
+			return nil, status.Error(codes.InvalidArgument, "adjusted_score can be set only if adjusted")
+		}
+
+		if !validScore(req.GetAdjustedScore()) {
+			return nil, status.Error(codes.InvalidArgument, "adjusted_score must be a non-negative number")
+		}
+	}
+
+	if utf8.RuneCountInString(req.GetComment()) > maxCommentLen {
+		return nil, status.Error(codes.InvalidArgument, "comment is too long")
+	}
+
+	userID, err := headTeacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := s.moderation.RecordDecision(
+		ctx,
+		req.GetItemId(),
+		userID,
+		decision,
+		req.AdjustedScore,
+		req.GetComment(),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, moderation.ErrItemNotFound):
+			return nil, status.Error(codes.NotFound, "moderation item not found")
+		case errors.Is(err, moderation.ErrAlreadyModerated):
+			return nil, status.Error(codes.FailedPrecondition, "moderation item is already moderated")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to record moderation decision")
+	}
+
+	res, err := toModeration(m)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to record moderation decision")
+	}
+
+	return &tasksv1.RecordModerationDecisionResponse{
+		Moderation: res,
+	}, nil
+}
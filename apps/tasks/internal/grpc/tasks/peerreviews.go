@@ -0,0 +1,196 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+
+	"tasks/internal/services/peerreview"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// ListPeerReviewSummaries returns the aggregated peer scores of the submissions
+// of the calling teacher's assignment.
+func (s *serverAPI) ListPeerReviewSummaries(
+	ctx context.Context,
+	req *tasksv1.ListPeerReviewSummariesRequest,
+) (*tasksv1.ListPeerReviewSummariesResponse, error) {
+	if req.GetAssignmentId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "assignment_id is required")
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := s.peerReviews.Summaries(ctx, req.GetAssignmentId(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, peerreview.ErrAssignmentNotFound):
+			return nil, status.Error(codes.NotFound, "assignment not found")
+		case errors.Is(err, peerreview.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "access to assignment denied")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to list peer review summaries")
+	}
+
+	resp := &tasksv1.ListPeerReviewSummariesResponse{
+		Summaries: make([]*tasksv1.PeerReviewSummary, 0, len(summaries)),
+	}
+
+	for _, summary := range summaries {
+		resp.Summaries = append(resp.Summaries, toPeerReviewSummary(summary))
+	}
+
+	return resp, nil
+}
+
+// ListPeerReviews lists the reviews assigned to the calling student.
+func (s *serverAPI) ListPeerReviews(
+	ctx context.Context,
+	req *tasksv1.ListPeerReviewsRequest,
+) (*tasksv1.ListPeerReviewsResponse, error) {
+	filter, err := pageFilter(req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := s.peerReviews.ReviewerReviews(ctx, userID, filter)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list peer reviews")
+	}
+
+	resp := &tasksv1.ListPeerReviewsResponse{
+		Reviews:       make([]*tasksv1.PeerReview, 0, len(reviews)),
+		NextPageToken: nextPageToken(filter, len(reviews)),
+	}
+
+	for _, review := range reviews {
+		resp.Reviews = append(resp.Reviews, toPeerReview(review))
+	}
+
+	return resp, nil
+}
+
+// GetPeerReview returns the review assigned to the calling student
+// together with the anonymous work to review.
+func (s *serverAPI) GetPeerReview(
+	ctx context.Context,
+	req *tasksv1.GetPeerReviewRequest,
+) (*tasksv1.GetPeerReviewResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	review, version, err := s.peerReviews.PeerReview(ctx, req.GetId(), userID)
+	if err != nil {
+		if errors.Is(err, peerreview.ErrPeerReviewNotFound) {
+			return nil, status.Error(codes.NotFound, "peer review not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to get peer review")
+	}
+
+	v, err := toSubmissionVersion(version)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to convert submission")
+	}
+
+	return &tasksv1.GetPeerReviewResponse{
+		Review:       toPeerReview(review),
+		Version:      v,
+		SubmissionId: review.SubmissionID,
+	}, nil
+}
+
+// SubmitPeerReview saves the scores of the review by the calling student.
+func (s *serverAPI) SubmitPeerReview(
+	ctx context.Context,
+	req *tasksv1.SubmitPeerReviewRequest,
+) (*tasksv1.SubmitPeerReviewResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	if len(req.GetScores()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "scores are required")
+	}
+
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	review, err := s.peerReviews.SubmitPeerReview(
+		ctx,
+		req.GetId(),
+		userID,
+		fromCriterionScores(req.GetScores()),
+		req.GetComment(),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, peerreview.ErrPeerReviewNotFound):
+			return nil, status.Error(codes.NotFound, "peer review not found")
+		case errors.Is(err, peerreview.ErrReviewClosed):
+			return nil, status.Error(codes.FailedPrecondition, "peer review is closed")
+		case errors.Is(err, peerreview.ErrInvalidScores):
+			return nil, status.Error(codes.InvalidArgument, "scores do not match the criteria")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to submit peer review")
+	}
+
+	return &tasksv1.SubmitPeerReviewResponse{
+		Review: toPeerReview(review),
+	}, nil
+}
+
+// ListReceivedPeerReviews lists the submitted reviews of the calling student's submission.
+func (s *serverAPI) ListReceivedPeerReviews(
+	ctx context.Context,
+	req *tasksv1.ListReceivedPeerReviewsRequest,
+) (*tasksv1.ListReceivedPeerReviewsResponse, error) {
+	if req.GetSubmissionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "submission_id is required")
+	}
+
+	userID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := s.peerReviews.ReceivedReviews(ctx, req.GetSubmissionId(), userID)
+	if err != nil {
+		if errors.Is(err, peerreview.ErrSubmissionNotFound) {
+			return nil, status.Error(codes.NotFound, "submission not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to list peer reviews")
+	}
+
+	resp := &tasksv1.ListReceivedPeerReviewsResponse{
+		Reviews: make([]*tasksv1.PeerReview, 0, len(reviews)),
+	}
+
+	for _, review := range reviews {
+		resp.Reviews = append(resp.Reviews, toPeerReview(review))
+	}
+
+	return resp, nil
+}
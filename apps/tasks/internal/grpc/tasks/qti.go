@@ -0,0 +1,83 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+
+	"tasks/internal/services/template"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// ImportQTI creates a quiz template from the QTI package.
+func (s *serverAPI) ImportQTI(
+	ctx context.Context,
+	req *tasksv1.ImportQTIRequest,
+) (*tasksv1.ImportQTIResponse, error) {
+	if len(req.GetContent()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "content is required")
+	}
+
+	userID, schoolID, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.templates.ImportQTI(ctx, userID, schoolID, req.GetTitle(), req.GetContent())
+	if err != nil {
+		switch {
+		case errors.Is(err, template.ErrInvalidPackage):
+			return nil, status.Error(codes.InvalidArgument, "invalid qti package")
+		case errors.Is(err, template.ErrNothingToImport):
+			return nil, status.Error(codes.InvalidArgument, "qti package has no items supported by quiz widget")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to import qti package")
+	}
+
+	return &tasksv1.ImportQTIResponse{
+		TemplateId:  res.TemplateID,
+		Version:     toQTIVersion(res.Version),
+		Unsupported: toUnsupportedQTIItems(res.Unsupported),
+	}, nil
+}
+
+// ExportQTI packs the quiz template to the QTI package.
+func (s *serverAPI) ExportQTI(
+	ctx context.Context,
+	req *tasksv1.ExportQTIRequest,
+) (*tasksv1.ExportQTIResponse, error) {
+	if req.GetTemplateId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "template_id is required")
+	}
+
+	version, ok := fromQTIVersion(req.GetVersion())
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "version is required")
+	}
+
+	userID, schoolID, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	content, unsupported, err := s.templates.ExportQTI(ctx, req.GetTemplateId(), userID, schoolID, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, template.ErrTemplateNotFound):
+			return nil, status.Error(codes.NotFound, "template not found")
+		case errors.Is(err, template.ErrNotQuiz):
+			return nil, status.Error(codes.FailedPrecondition, "only quiz templates can be exported to qti")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to export qti package")
+	}
+
+	return &tasksv1.ExportQTIResponse{
+		Content:     content,
+		Unsupported: toUnsupportedQTIItems(unsupported),
+	}, nil
+}
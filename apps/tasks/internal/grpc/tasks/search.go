@@ -0,0 +1,80 @@
+package tasks
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"tasks/internal/domain/models"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// maxQueryLen limits the length of the search query in characters.
+const maxQueryLen = 256
+
+// SearchAssignments finds the calling teacher's assignments and the templates
+// of the library visible to the teacher by the words of their titles,
+// widget types and texts of the widget configs.
+func (s *serverAPI) SearchAssignments(
+	ctx context.Context,
+	req *tasksv1.SearchAssignmentsRequest,
+) (*tasksv1.SearchAssignmentsResponse, error) {
+	if strings.TrimSpace(req.GetQuery()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+
+	if utf8.RuneCountInString(req.GetQuery()) > maxQueryLen {
+		return nil, status.Error(codes.InvalidArgument, "query is too long")
+	}
+
+	kind, ok := fromSearchResultKind(req.GetKind())
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "invalid kind")
+	}
+
+	query := models.SearchQuery{
+		Text:       req.GetQuery(),
+		Kind:       kind,
+		WidgetType: req.GetWidgetType(),
+	}
+
+	if req.GetCreatorId() != "" {
+		var err error
+
+		query.CreatorID, err = strconv.ParseInt(req.GetCreatorId(), 10, 64)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid creator_id")
+		}
+	}
+
+	filter, err := pageFilter(req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	userID, schoolID, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.search.Search(ctx, userID, schoolID, query, filter)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to search assignments")
+	}
+
+	resp := &tasksv1.SearchAssignmentsResponse{
+		Results:       make([]*tasksv1.SearchResult, 0, len(results)),
+		NextPageToken: nextPageToken(filter, len(results)),
+	}
+
+	for _, result := range results {
+		resp.Results = append(resp.Results, toSearchResult(result))
+	}
+
+	return resp, nil
+}
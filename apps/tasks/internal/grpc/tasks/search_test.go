@@ -0,0 +1,245 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"tasks/internal/auth"
+	"tasks/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+	"github.com/Kaptoshka/creative-learning-platform/libs/platform/identity"
+)
+
+// fakeSearch records the last search and returns the given results.
+type fakeSearch struct {
+	userID   int64
+	schoolID int64
+	query    models.SearchQuery
+	filter   models.Filter
+	results  []models.SearchResult
+	err      error
+	calls    int
+}
+
+func (f *fakeSearch) Search(
+	_ context.Context,
+	userID int64,
+	schoolID int64,
+	query models.SearchQuery,
+	filter models.Filter,
+) ([]models.SearchResult, error) {
+	f.calls++
+	f.userID, f.schoolID, f.query, f.filter = userID, schoolID, query, filter
+	return f.results, f.err
+}
+
+func teacherContext(userID int64) context.Context {
+	return auth.WithUser(context.Background(), userID, auth.RoleTeacher)
+}
+
+func TestSearchAssignments_Filters(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        *tasksv1.SearchAssignmentsRequest
+		wantQuery  models.SearchQuery
+		wantFilter models.Filter
+	}{
+		{
+			name:       "no filters",
+			req:        &tasksv1.SearchAssignmentsRequest{Query: "дроби"},
+			wantQuery:  models.SearchQuery{Text: "дроби"},
+			wantFilter: models.Filter{Limit: defaultPageSize},
+		},
+		{
+			name: "assignments only",
+			req: &tasksv1.SearchAssignmentsRequest{
+				Query: "fractions",
+				Kind:  tasksv1.SearchResultKind_SEARCH_RESULT_KIND_ASSIGNMENT,
+			},
+			wantQuery:  models.SearchQuery{Text: "fractions", Kind: models.SearchAssignment},
+			wantFilter: models.Filter{Limit: defaultPageSize},
+		},
+		{
+			name: "templates of widget and creator",
+			req: &tasksv1.SearchAssignmentsRequest{
+				Query:      "fractions",
+				Kind:       tasksv1.SearchResultKind_SEARCH_RESULT_KIND_TEMPLATE,
+				WidgetType: "quiz",
+				CreatorId:  "42",
+			},
+			wantQuery: models.SearchQuery{
+				Text:       "fractions",
+				Kind:       models.SearchTemplate,
+				WidgetType: "quiz",
+				CreatorID:  42,
+			},
+			wantFilter: models.Filter{Limit: defaultPageSize},
+		},
+		{
+			name: "page",
+			req: &tasksv1.SearchAssignmentsRequest{
+				Query:     "fractions",
+				PageSize:  maxPageSize + 1,
+				PageToken: "200",
+			},
+			wantQuery:  models.SearchQuery{Text: "fractions"},
+			wantFilter: models.Filter{Limit: maxPageSize, Offset: 200},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			search := &fakeSearch{}
+			s := &serverAPI{search: search}
+
+			_, err := s.SearchAssignments(teacherContext(7), tt.req)
+			require.NoError(t, err)
+
+			assert.Equal(t, int64(7), search.userID)
+			assert.Equal(t, tt.wantQuery, search.query)
+			assert.Equal(t, tt.wantFilter, search.filter)
+		})
+	}
+}
+
+func TestSearchAssignments_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		req  *tasksv1.SearchAssignmentsRequest
+		code codes.Code
+	}{
+		{
+			name: "empty query",
+			ctx:  teacherContext(7),
+			req:  &tasksv1.SearchAssignmentsRequest{Query: "  "},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "long query",
+			ctx:  teacherContext(7),
+			req:  &tasksv1.SearchAssignmentsRequest{Query: strings.Repeat("я", maxQueryLen+1)},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "unknown kind",
+			ctx:  teacherContext(7),
+			req:  &tasksv1.SearchAssignmentsRequest{Query: "quiz", Kind: 42},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "invalid creator",
+			ctx:  teacherContext(7),
+			req:  &tasksv1.SearchAssignmentsRequest{Query: "quiz", CreatorId: "me"},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "invalid page token",
+			ctx:  teacherContext(7),
+			req:  &tasksv1.SearchAssignmentsRequest{Query: "quiz", PageToken: "-1"},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "student",
+			ctx:  auth.WithUser(context.Background(), 7, auth.RoleStudent),
+			req:  &tasksv1.SearchAssignmentsRequest{Query: "quiz"},
+			code: codes.PermissionDenied,
+		},
+		{
+			name: "unauthenticated",
+			ctx:  context.Background(),
+			req:  &tasksv1.SearchAssignmentsRequest{Query: "quiz"},
+			code: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			search := &fakeSearch{}
+			s := &serverAPI{search: search}
+
+			_, err := s.SearchAssignments(tt.ctx, tt.req)
+			assert.Equal(t, tt.code, status.Code(err))
+			assert.Zero(t, search.calls)
+		})
+	}
+}
+
+func TestSearchAssignments_Results(t *testing.T) {
+	search := &fakeSearch{
+		results: []models.SearchResult{
+			{Kind: models.SearchAssignment, ID: "a-1", Title: "Fractions", WidgetType: "quiz", CreatorID: 7},
+			{Kind: models.SearchTemplate, ID: "t-1", Title: "Fractions drill", WidgetType: "quiz", CreatorID: 8},
+		},
+	}
+	s := &serverAPI{search: search}
+
+	t.Run("full page has next token", func(t *testing.T) {
+		resp, err := s.SearchAssignments(teacherContext(7), &tasksv1.SearchAssignmentsRequest{
+			Query:    "fractions",
+			PageSize: 2,
+		})
+		require.NoError(t, err)
+
+		require.Len(t, resp.GetResults(), 2)
+		assert.Equal(t, tasksv1.SearchResultKind_SEARCH_RESULT_KIND_ASSIGNMENT, resp.GetResults()[0].GetKind())
+		assert.Equal(t, tasksv1.SearchResultKind_SEARCH_RESULT_KIND_TEMPLATE, resp.GetResults()[1].GetKind())
+		assert.Equal(t, "2", resp.GetNextPageToken())
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		resp, err := s.SearchAssignments(teacherContext(7), &tasksv1.SearchAssignmentsRequest{
+			Query:    "fractions",
+			PageSize: 3,
+		})
+		require.NoError(t, err)
+		assert.Empty(t, resp.GetNextPageToken())
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		search.err = errors.New("connection refused")
+
+		_, err := s.SearchAssignments(teacherContext(7), &tasksv1.SearchAssignmentsRequest{Query: "fractions"})
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+}
+
+func TestSearchAssignments_School(t *testing.T) {
+	search := &fakeSearch{}
+	s := &serverAPI{search: search}
+	interceptor := identity.UnaryServerInterceptor(auth.WithIdentity)
+
+	call := func(md metadata.MD) {
+		t.Helper()
+
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+			return s.SearchAssignments(ctx, &tasksv1.SearchAssignmentsRequest{Query: "fractions"})
+		})
+		require.NoError(t, err)
+	}
+
+	call(metadata.Pairs(
+		identity.MetadataUserID, "7",
+		identity.MetadataRole, auth.RoleTeacher,
+		identity.MetadataAppID, "4",
+	))
+	assert.Equal(t, int64(7), search.userID)
+	assert.Equal(t, int64(4), search.schoolID, "school is the app the teacher has signed in to")
+
+	call(metadata.Pairs(
+		identity.MetadataUserID, "7",
+		identity.MetadataRole, auth.RoleTeacher,
+	))
+	assert.Zero(t, search.schoolID, "school is unknown without the app")
+}
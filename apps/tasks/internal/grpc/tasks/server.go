@@ -1,30 +1,528 @@
 package tasks
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"tasks/internal/auth"
+	"tasks/internal/domain/models"
+	"tasks/internal/lib/qti"
+	"tasks/internal/services/course"
+	"tasks/internal/services/template"
+
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
 )
 
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
 type Assignments interface {
+	CreateAssignment(
+		ctx context.Context,
+		assignment models.Assignment,
+		schoolID int64,
+	) (id string, err error)
+	UpdateAssignment(
+		ctx context.Context,
+		assignmentID string,
+		userID int64,
+		update models.AssignmentUpdate,
+	) error
+	ExtendStudentAssignment(
+		ctx context.Context,
+		assignmentID string,
+		userID int64,
+		student string,
+		dueDate time.Time,
+		cutoffDate time.Time,
+	) error
+	Assignment(
+		ctx context.Context,
+		assignmentID string,
+		userID int64,
+	) (models.Assignment, error)
+	StudentAssignments(
+		ctx context.Context,
+		studentID int64,
+		filter models.Filter,
+	) ([]models.StudentAssignment, error)
+	StudentAssignment(
+		ctx context.Context,
+		studentAssignmentID string,
+		studentID int64,
+	) (models.StudentAssignment, error)
+	DeleteAssignment(
+		ctx context.Context,
+		assignmentID string,
+		userID int64,
+	) error
+	RestoreAssignment(
+		ctx context.Context,
+		assignmentID string,
+		userID int64,
+	) error
+	DeletedAssignments(
+		ctx context.Context,
+		userID int64,
+		filter models.Filter,
+	) ([]models.TrashItem, error)
+	RevealIdentities(
+		ctx context.Context,
+		assignmentID string,
+		userID int64,
+		reason string,
+	) error
+	IdentityReveals(
+		ctx context.Context,
+		assignmentID string,
+		userID int64,
+	) ([]models.IdentityReveal, error)
 }
 
 type Submissions interface {
+	StartSubmission(
+		ctx context.Context,
+		studentAssignmentID string,
+		studentID int64,
+	) (id string, err error)
+	UpdateSubmission(
+		ctx context.Context,
+		submissionID string,
+		studentID int64,
+		expectedRevision int64,
+		payload json.RawMessage,
+	) (models.Submission, error)
+	SubmitSubmission(
+		ctx context.Context,
+		submissionID string,
+		studentID int64,
+		expectedRevision int64,
+		payload json.RawMessage,
+	) (models.Submission, error)
+	DeleteSubmission(
+		ctx context.Context,
+		submissionID string,
+		studentID int64,
+	) error
+	RestoreSubmission(
+		ctx context.Context,
+		submissionID string,
+		studentID int64,
+	) error
+	DeletedSubmissions(
+		ctx context.Context,
+		studentID int64,
+		filter models.Filter,
+	) ([]models.TrashItem, error)
+	AssignmentSubmissions(
+		ctx context.Context,
+		assignmentID string,
+		teacherID int64,
+		filter models.Filter,
+	) ([]models.Submission, error)
+}
+
+type Attachments interface {
+	UploadAttachment(
+		ctx context.Context,
+		studentID int64,
+		submissionID string,
+		filename string,
+		r io.Reader,
+	) (models.Attachment, error)
+	DownloadAttachment(
+		ctx context.Context,
+		attachmentID string,
+		userID int64,
+		rendition models.Rendition,
+		offset int64,
+	) (models.Attachment, io.ReadCloser, error)
+	SubmissionAttachments(
+		ctx context.Context,
+		submissionID string,
+		userID int64,
+	) ([]models.Attachment, error)
+}
+
+type Templates interface {
+	CloneTemplate(
+		ctx context.Context,
+		templateID string,
+		userID int64,
+		schoolID int64,
+		title string,
+	) (id string, err error)
+	Templates(
+		ctx context.Context,
+		scope models.TemplateScope,
+		userID int64,
+		schoolID int64,
+		filter models.Filter,
+	) ([]models.Template, error)
+	ShareTemplate(
+		ctx context.Context,
+		templateID string,
+		userID int64,
+		visibility models.Visibility,
+	) error
+	ImportQTI(
+		ctx context.Context,
+		userID int64,
+		schoolID int64,
+		title string,
+		data []byte,
+	) (template.QTIImport, error)
+	ExportQTI(
+		ctx context.Context,
+		templateID string,
+		userID int64,
+		schoolID int64,
+		version qti.Version,
+	) ([]byte, []qti.Unsupported, error)
+}
+
+type Courses interface {
+	CreateCourse(
+		ctx context.Context,
+		userID int64,
+		schoolID int64,
+		title string,
+		description string,
+	) (string, error)
+	AddCourseSection(
+		ctx context.Context,
+		courseID string,
+		userID int64,
+		schoolID int64,
+		title string,
+		templateIDs []string,
+	) (string, error)
+	ExportCourse(
+		ctx context.Context,
+		courseID string,
+		userID int64,
+	) ([]byte, error)
+	ImportCourse(
+		ctx context.Context,
+		userID int64,
+		schoolID int64,
+		data []byte,
+		apply bool,
+	) (course.ImportResult, error)
+}
+
+type Similarity interface {
+	SimilarityReport(
+		ctx context.Context,
+		assignmentID string,
+		teacherID int64,
+		minScore float64,
+	) (models.SimilarityReport, error)
+}
+
+type PeerReviews interface {
+	ReviewerReviews(
+		ctx context.Context,
+		reviewerID int64,
+		filter models.Filter,
+	) ([]models.PeerReview, error)
+	PeerReview(
+		ctx context.Context,
+		reviewID string,
+		reviewerID int64,
+	) (models.PeerReview, models.SubmissionVersion, error)
+	SubmitPeerReview(
+		ctx context.Context,
+		reviewID string,
+		reviewerID int64,
+		scores []models.CriterionScore,
+		comment string,
+	) (models.PeerReview, error)
+	ReceivedReviews(
+		ctx context.Context,
+		submissionID string,
+		studentID int64,
+	) ([]models.PeerReview, error)
+	Summaries(
+		ctx context.Context,
+		assignmentID string,
+		teacherID int64,
+	) ([]models.PeerReviewSummary, error)
+}
+
+type Comments interface {
+	StartThread(
+		ctx context.Context,
+		submissionID string,
+		versionID string,
+		userID int64,
+		anchor *models.CommentAnchor,
+		body string,
+	) (models.CommentThread, error)
+	Reply(
+		ctx context.Context,
+		threadID string,
+		userID int64,
+		body string,
+	) (models.Comment, error)
+	EditComment(
+		ctx context.Context,
+		commentID string,
+		userID int64,
+		body string,
+	) (models.Comment, error)
+	ResolveThread(
+		ctx context.Context,
+		threadID string,
+		userID int64,
+		resolved bool,
+	) (models.CommentThread, error)
+	Threads(
+		ctx context.Context,
+		submissionID string,
+		versionID string,
+		userID int64,
+	) ([]models.CommentThread, error)
+}
+
+type Grading interface {
+	ProvideFeedback(
+		ctx context.Context,
+		submissionID string,
+		teacherID int64,
+		text string,
+		score *float64,
+	) error
+	ReturnSubmission(
+		ctx context.Context,
+		versionID string,
+		teacherID int64,
+		status models.SubmissionStatus,
+		text string,
+		score *float64,
+	) error
+	RequestRegrade(
+		ctx context.Context,
+		submissionID string,
+		studentID int64,
+		reason string,
+	) (models.RegradeRequest, error)
+	ResolveRegrade(
+		ctx context.Context,
+		requestID string,
+		teacherID int64,
+		status models.RegradeStatus,
+		resolution string,
+		newScore *float64,
+	) (models.RegradeRequest, error)
+	OpenRegrades(
+		ctx context.Context,
+		teacherID int64,
+		filter models.Filter,
+	) ([]models.RegradeRequest, error)
+	History(
+		ctx context.Context,
+		submissionID string,
+		userID int64,
+	) ([]models.SubmissionEvent, error)
+}
+
+type Moderation interface {
+	RequestModeration(
+		ctx context.Context,
+		assignmentID string,
+		teacherID int64,
+		moderatorID int64,
+		sampleSize int,
+	) (models.Moderation, error)
+	Moderation(
+		ctx context.Context,
+		assignmentID string,
+		userID int64,
+	) (models.Moderation, error)
+	PendingModerations(
+		ctx context.Context,
+		moderatorID int64,
+		filter models.Filter,
+	) ([]models.Moderation, error)
+	RecordDecision(
+		ctx context.Context,
+		itemID string,
+		moderatorID int64,
+		decision models.ModerationDecision,
+		adjustedScore *float64,
+		comment string,
+	) (models.Moderation, error)
+}
+
+type Search interface {
+	Search(
+		ctx context.Context,
+		userID int64,
+		schoolID int64,
+		query models.SearchQuery,
+		filter models.Filter,
+	) ([]models.SearchResult, error)
+}
+
+type Activity interface {
+	WatchAssignment(
+		ctx context.Context,
+		assignmentID string,
+		teacherID int64,
+		afterID int64,
+		send func(models.Activity) error,
+	) error
+}
+
+type Calendar interface {
+	CreateCalendarFeed(
+		ctx context.Context,
+		userID int64,
+	) (token string, err error)
+	RevokeCalendarFeed(
+		ctx context.Context,
+		userID int64,
+	) error
+	CalendarFeed(
+		ctx context.Context,
+		token string,
+	) ([]models.CalendarEntry, error)
 }
 
 type serverAPI struct {
 	tasksv1.UnimplementedTasksServer
 	assignments Assignments
 	submissions Submissions
+	attachments Attachments
+	templates   Templates
+	courses     Courses
+	similarity  Similarity
+	peerReviews PeerReviews
+	comments    Comments
+	grading     Grading
+	moderation  Moderation
+	search      Search
+	activity    Activity
+	calendar    Calendar
 }
 
 func Register(
 	gRPC *grpc.Server,
 	assignments Assignments,
 	submissions Submissions,
+	attachments Attachments,
+	templates Templates,
+	courses Courses,
+	similarity Similarity,
+	peerReviews PeerReviews,
+	comments Comments,
+	grading Grading,
+	moderation Moderation,
+	search Search,
+	activity Activity,
+	calendar Calendar,
 ) {
 	tasksv1.RegisterTasksServer(gRPC, &serverAPI{
 		assignments: assignments,
 		submissions: submissions,
+		attachments: attachments,
+		templates:   templates,
+		courses:     courses,
+		similarity:  similarity,
+		peerReviews: peerReviews,
+		comments:    comments,
+		grading:     grading,
+		moderation:  moderation,
+		search:      search,
+		activity:    activity,
+		calendar:    calendar,
 	})
 }
+
+// teacher returns the id and the school of the caller.
+// If the caller is not a teacher, it returns an error.
+func teacher(ctx context.Context) (int64, int64, error) {
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return 0, 0, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+
+	switch auth.GetUserRole(ctx) {
+	case auth.RoleTeacher, auth.RoleHeadTeacher, auth.RoleAdmin:
+		return userID, auth.GetSchoolID(ctx), nil
+	default:
+		return 0, 0, status.Error(codes.PermissionDenied, "teacher role is required")
+	}
+}
+
+// headTeacher returns the id of the caller.
+// If the caller is not a head teacher, it returns an error.
+func headTeacher(ctx context.Context) (int64, error) {
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return 0, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+
+	switch auth.GetUserRole(ctx) {
+	case auth.RoleHeadTeacher, auth.RoleAdmin:
+		return userID, nil
+	default:
+		return 0, status.Error(codes.PermissionDenied, "head teacher role is required")
+	}
+}
+
+// user returns the id of the authenticated caller.
+func user(ctx context.Context) (int64, error) {
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		return 0, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+
+	return userID, nil
+}
+
+// pageFilter converts page size and page token of the request to the filter.
+func pageFilter(pageSize int32, pageToken string) (models.Filter, error) {
+	limit := int(pageSize)
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	var offset int
+	if pageToken != "" {
+		var err error
+
+		offset, err = strconv.Atoi(pageToken)
+		if err != nil || offset < 0 {
+			return models.Filter{}, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+	}
+
+	return models.Filter{
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+// nextPageToken returns the token of the page following the given one.
+// If the page is not full, there are no more pages and it returns empty string.
+func nextPageToken(filter models.Filter, count int) string {
+	if count < filter.Limit {
+		return ""
+	}
+
+	return strconv.Itoa(filter.Offset + count)
+}
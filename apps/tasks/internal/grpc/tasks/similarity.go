@@ -0,0 +1,55 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+
+	"tasks/internal/services/similarity"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// SimilarityReport lists the pairs of similar submissions of the teacher's assignment.
+func (s *serverAPI) SimilarityReport(
+	ctx context.Context,
+	req *tasksv1.SimilarityReportRequest,
+) (*tasksv1.SimilarityReportResponse, error) {
+	if req.GetAssignmentId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "assignment_id is required")
+	}
+
+	if req.GetMinScore() < 0 || req.GetMinScore() > 1 {
+		return nil, status.Error(codes.InvalidArgument, "min_score must be between 0 and 1")
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.similarity.SimilarityReport(ctx, req.GetAssignmentId(), userID, req.GetMinScore())
+	if err != nil {
+		switch {
+		case errors.Is(err, similarity.ErrAssignmentNotFound):
+			return nil, status.Error(codes.NotFound, "assignment not found")
+		case errors.Is(err, similarity.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "access to assignment denied")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to get similarity report")
+	}
+
+	resp := &tasksv1.SimilarityReportResponse{
+		CheckedAt: toTimestamp(report.CheckedAt),
+		Pairs:     make([]*tasksv1.SimilarPair, 0, len(report.Pairs)),
+	}
+
+	for _, pair := range report.Pairs {
+		resp.Pairs = append(resp.Pairs, toSimilarPair(pair))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,269 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/services/submission"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// StartAssignment starts the work of the calling student on the assignment.
+func (s *serverAPI) StartAssignment(
+	ctx context.Context,
+	req *tasksv1.StartAssignmentRequest,
+) (*tasksv1.StartAssignmentResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	studentID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.submissions.StartSubmission(ctx, req.GetId(), studentID)
+	if err != nil {
+		if errors.Is(err, submission.ErrAssignmentNotFound) {
+			return nil, status.Error(codes.NotFound, "assignment not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to start assignment")
+	}
+
+	return &tasksv1.StartAssignmentResponse{
+		Id: id,
+	}, nil
+}
+
+// UpdateSubmission autosaves the payload of the submission.
+func (s *serverAPI) UpdateSubmission(
+	ctx context.Context,
+	req *tasksv1.UpdateSubmissionRequest,
+) (*tasksv1.UpdateSubmissionResponse, error) {
+	if req.GetSubmissionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "submission_id is required")
+	}
+
+	if req.GetPayload() == nil {
+		return nil, status.Error(codes.InvalidArgument, "payload is required")
+	}
+
+	studentID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := fromStruct(req.GetPayload())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid payload")
+	}
+
+	res, err := s.submissions.UpdateSubmission(
+		ctx,
+		req.GetSubmissionId(),
+		studentID,
+		req.GetExpectedRevision(),
+		payload,
+	)
+	if err != nil {
+		return nil, saveError(res, err, "failed to update submission")
+	}
+
+	sub, err := toSubmission(res)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to update submission")
+	}
+
+	return &tasksv1.UpdateSubmissionResponse{
+		Submission: sub,
+	}, nil
+}
+
+// SubmitAssignment hands the submission of the calling student in for grading.
+func (s *serverAPI) SubmitAssignment(
+	ctx context.Context,
+	req *tasksv1.SubmitAssignmentRequest,
+) (*tasksv1.SubmitAssignmentResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	studentID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload json.RawMessage
+	if req.GetPayload() != nil {
+		payload, err = fromStruct(req.GetPayload())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid payload")
+		}
+	}
+
+	res, err := s.submissions.SubmitSubmission(
+		ctx,
+		req.GetId(),
+		studentID,
+		req.GetExpectedRevision(),
+		payload,
+	)
+	if err != nil {
+		return nil, saveError(res, err, "failed to submit assignment")
+	}
+
+	sub, err := toSubmission(res)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to submit assignment")
+	}
+
+	return &tasksv1.SubmitAssignmentResponse{
+		Id:         res.ID,
+		Submission: sub,
+	}, nil
+}
+
+// saveError maps the error of saving the submission to the status.
+// On conflict the current submission is attached to the details of the status,
+// so the client can merge its changes without another request.
+func saveError(current models.Submission, err error, msg string) error {
+	switch {
+	case errors.Is(err, submission.ErrRevisionConflict):
+		st := status.New(codes.Aborted, "submission was changed concurrently")
+
+		sub, convErr := toSubmission(current)
+		if convErr != nil {
+			return st.Err()
+		}
+
+		if detailed, detailsErr := st.WithDetails(sub); detailsErr == nil {
+			st = detailed
+		}
+
+		return st.Err()
+	case errors.Is(err, submission.ErrSubmissionNotFound):
+		return status.Error(codes.NotFound, "submission not found")
+	case errors.Is(err, submission.ErrNotEditable):
+		return status.Error(codes.FailedPrecondition, "submission is not editable")
+	case errors.Is(err, submission.ErrPastCutoff):
+		return status.Error(codes.FailedPrecondition, "cutoff date of assignment has passed")
+	case errors.Is(err, submission.ErrEmptySubmission):
+		return status.Error(codes.FailedPrecondition, "submission has no saved version")
+	case errors.Is(err, submission.ErrInvalidAttachment):
+		return status.Error(codes.InvalidArgument, "payload references unknown attachment")
+	}
+
+	return status.Error(codes.Internal, msg)
+}
+
+// DeleteSubmission moves the submission of the calling student to the trash.
+func (s *serverAPI) DeleteSubmission(
+	ctx context.Context,
+	req *tasksv1.DeleteSubmissionRequest,
+) (*emptypb.Empty, error) {
+	if req.GetSubmissionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "submission_id is required")
+	}
+
+	studentID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.submissions.DeleteSubmission(ctx, req.GetSubmissionId(), studentID)
+	if err != nil {
+		if errors.Is(err, submission.ErrSubmissionNotFound) {
+			return nil, status.Error(codes.NotFound, "submission not found")
+		}
+		if errors.Is(err, submission.ErrGraded) {
+			return nil, status.Error(codes.FailedPrecondition, "graded submission cannot be deleted")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to delete submission")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// RestoreSubmission moves the submission of the calling student out of the trash.
+func (s *serverAPI) RestoreSubmission(
+	ctx context.Context,
+	req *tasksv1.RestoreSubmissionRequest,
+) (*emptypb.Empty, error) {
+	if req.GetSubmissionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "submission_id is required")
+	}
+
+	studentID, err := user(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.submissions.RestoreSubmission(ctx, req.GetSubmissionId(), studentID)
+	if err != nil {
+		if errors.Is(err, submission.ErrSubmissionNotFound) {
+			return nil, status.Error(codes.NotFound, "submission not found in trash")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to restore submission")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ListAssignmentSubmissions lists the submissions of the caller's assignment.
+// The students of the anonymous assignment are shown as pseudonyms.
+func (s *serverAPI) ListAssignmentSubmissions(
+	ctx context.Context,
+	req *tasksv1.ListAssignmentSubmissionsRequest,
+) (*tasksv1.ListAssignmentSubmissionsResponse, error) {
+	if req.GetAssignmentId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "assignment_id is required")
+	}
+
+	filter, err := pageFilter(req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	submissions, err := s.submissions.AssignmentSubmissions(ctx, req.GetAssignmentId(), userID, filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, submission.ErrAssignmentNotFound):
+			return nil, status.Error(codes.NotFound, "assignment not found")
+		case errors.Is(err, submission.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "assignment belongs to another teacher")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to list submissions")
+	}
+
+	resp := &tasksv1.ListAssignmentSubmissionsResponse{
+		Submissions:   make([]*tasksv1.Submission, 0, len(submissions)),
+		NextPageToken: nextPageToken(filter, len(submissions)),
+	}
+
+	for _, sub := range submissions {
+		res, err := toSubmission(sub)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to list submissions")
+		}
+
+		resp.Submissions = append(resp.Submissions, res)
+	}
+
+	return resp, nil
+}
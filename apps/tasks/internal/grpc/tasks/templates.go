@@ -0,0 +1,126 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+
+	"tasks/internal/services/template"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// CloneTemplate copies a template from the library into the caller's templates.
+func (s *serverAPI) CloneTemplate(
+	ctx context.Context,
+	req *tasksv1.CloneTemplateRequest,
+) (*tasksv1.CloneTemplateResponse, error) {
+	if req.GetTemplateId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "template_id is required")
+	}
+
+	userID, schoolID, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.templates.CloneTemplate(
+		ctx,
+		req.GetTemplateId(),
+		userID,
+		schoolID,
+		req.GetTitle(),
+	)
+	if err != nil {
+		if errors.Is(err, template.ErrTemplateNotFound) {
+			return nil, status.Error(codes.NotFound, "template not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to clone template")
+	}
+
+	return &tasksv1.CloneTemplateResponse{
+		Id: id,
+	}, nil
+}
+
+// ListTemplates lists templates of the caller, shared with the caller's school or public.
+func (s *serverAPI) ListTemplates(
+	ctx context.Context,
+	req *tasksv1.ListTemplatesRequest,
+) (*tasksv1.ListTemplatesResponse, error) {
+	scope, ok := fromTemplateScope(req.GetScope())
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "scope is required")
+	}
+
+	filter, err := pageFilter(req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	userID, schoolID, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := s.templates.Templates(ctx, scope, userID, schoolID, filter)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list templates")
+	}
+
+	resp := &tasksv1.ListTemplatesResponse{
+		Templates:     make([]*tasksv1.AssignmentTemplate, 0, len(templates)),
+		NextPageToken: nextPageToken(filter, len(templates)),
+	}
+
+	for _, t := range templates {
+		item, err := toTemplate(t)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to list templates")
+		}
+
+		resp.Templates = append(resp.Templates, item)
+	}
+
+	return resp, nil
+}
+
+// ShareTemplate changes who can see and clone the template.
+func (s *serverAPI) ShareTemplate(
+	ctx context.Context,
+	req *tasksv1.ShareTemplateRequest,
+) (*emptypb.Empty, error) {
+	if req.GetTemplateId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "template_id is required")
+	}
+
+	visibility, ok := fromVisibility(req.GetVisibility())
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "visibility is required")
+	}
+
+	userID, _, err := teacher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.templates.ShareTemplate(ctx, req.GetTemplateId(), userID, visibility)
+	if err != nil {
+		switch {
+		case errors.Is(err, template.ErrTemplateNotFound):
+			return nil, status.Error(codes.NotFound, "template not found")
+		case errors.Is(err, template.ErrAccessDenied):
+			return nil, status.Error(codes.PermissionDenied, "only creator can share template")
+		case errors.Is(err, template.ErrSchoolUnknown):
+			return nil, status.Error(codes.FailedPrecondition, "school is unknown, template cannot be shared with school")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to share template")
+	}
+
+	return &emptypb.Empty{}, nil
+}
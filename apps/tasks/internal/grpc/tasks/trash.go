@@ -0,0 +1,62 @@
+package tasks
+
+import (
+	"context"
+
+	"tasks/internal/domain/models"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+// ListTrash lists the deleted assignments of the calling teacher
+// or the deleted submissions of the calling student.
+func (s *serverAPI) ListTrash(
+	ctx context.Context,
+	req *tasksv1.ListTrashRequest,
+) (*tasksv1.ListTrashResponse, error) {
+	filter, err := pageFilter(req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	var items []models.TrashItem
+
+	switch req.GetKind() {
+	case tasksv1.TrashItemKind_TRASH_ITEM_KIND_ASSIGNMENT:
+		userID, _, err := teacher(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		items, err = s.assignments.DeletedAssignments(ctx, userID, filter)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to list trash")
+		}
+	case tasksv1.TrashItemKind_TRASH_ITEM_KIND_SUBMISSION:
+		studentID, err := user(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		items, err = s.submissions.DeletedSubmissions(ctx, studentID, filter)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to list trash")
+		}
+	default:
+		return nil, status.Error(codes.InvalidArgument, "kind is required")
+	}
+
+	resp := &tasksv1.ListTrashResponse{
+		Items:         make([]*tasksv1.TrashItem, 0, len(items)),
+		NextPageToken: nextPageToken(filter, len(items)),
+	}
+
+	for _, item := range items {
+		resp.Items = append(resp.Items, toTrashItem(item))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,221 @@
+package bundle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"tasks/internal/domain/models"
+)
+
+const (
+	// Format identifies the course bundle among other json documents.
+	Format = "creative-learning-platform/course-bundle"
+	// Version is the version of the bundle layout.
+	// It must be increased on every incompatible change of the layout.
+	Version = 1
+)
+
+var (
+	ErrUnknownFormat      = errors.New("unknown bundle format")
+	ErrUnsupportedVersion = errors.New("unsupported bundle version")
+)
+
+// Bundle is the portable description of the course.
+// It contains only the content created by the teacher and never contains student data.
+//
+// Entities reference each other by refs, which are the ids in the source environment.
+// Refs are remapped to new ids when the bundle is imported.
+type Bundle struct {
+	Format     string     `json:"format"`
+	Version    int        `json:"version"`
+	ExportedAt time.Time  `json:"exported_at"`
+	Widgets    []Widget   `json:"widgets"`
+	Course     Course     `json:"course"`
+	Templates  []Template `json:"templates"`
+}
+
+// Widget references the widget by type and version,
+// ids of widgets differ between environments.
+type Widget struct {
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+}
+
+type Course struct {
+	Ref         string    `json:"ref"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Sections    []Section `json:"sections"`
+}
+
+type Section struct {
+	Ref       string   `json:"ref"`
+	Title     string   `json:"title"`
+	Templates []string `json:"templates"`
+}
+
+type Template struct {
+	Ref     string          `json:"ref"`
+	Title   string          `json:"title"`
+	Widget  Widget          `json:"widget"`
+	Config  json.RawMessage `json:"config"`
+	Rubrics []Rubric        `json:"rubrics,omitempty"`
+}
+
+type Rubric struct {
+	Ref      string                   `json:"ref"`
+	Title    string                   `json:"title"`
+	Criteria []models.RubricCriterion `json:"criteria"`
+}
+
+// Problem describes why the bundle cannot be imported.
+type Problem struct {
+	Ref    string
+	Reason string
+}
+
+// New creates the bundle of the course with the given templates and rubrics.
+func New(
+	course models.Course,
+	templates []models.Template,
+	rubrics []models.Rubric,
+) *Bundle {
+	b := &Bundle{
+		Format:     Format,
+		Version:    Version,
+		ExportedAt: time.Now().UTC(),
+		Course: Course{
+			Ref:         course.ID,
+			Title:       course.Title,
+			Description: course.Description,
+			Sections:    make([]Section, 0, len(course.Sections)),
+		},
+		Templates: make([]Template, 0, len(templates)),
+	}
+
+	for _, section := range course.Sections {
+		b.Course.Sections = append(b.Course.Sections, Section{
+			Ref:       section.ID,
+			Title:     section.Title,
+			Templates: section.TemplateIDs,
+		})
+	}
+
+	rubricsByTemplate := make(map[string][]Rubric)
+	for _, rubric := range rubrics {
+		rubricsByTemplate[rubric.TemplateID] = append(rubricsByTemplate[rubric.TemplateID], Rubric{
+			Ref:      rubric.ID,
+			Title:    rubric.Title,
+			Criteria: rubric.Criteria,
+		})
+	}
+
+	widgets := make(map[Widget]bool)
+	for _, template := range templates {
+		widget := Widget{
+			Type:    template.Widget.Type,
+			Version: template.Widget.Version,
+		}
+
+		if !widgets[widget] {
+			widgets[widget] = true
+			b.Widgets = append(b.Widgets, widget)
+		}
+
+		b.Templates = append(b.Templates, Template{
+			Ref:     template.ID,
+			Title:   template.Title,
+			Widget:  widget,
+			Config:  template.Widget.Config,
+			Rubrics: rubricsByTemplate[template.ID],
+		})
+	}
+
+	return b
+}
+
+// Encode returns the json representation of the bundle.
+func (b *Bundle) Encode() ([]byte, error) {
+	return json.MarshalIndent(b, "", "  ")
+}
+
+// Decode parses the bundle and checks that its format and version are supported.
+func Decode(data []byte) (*Bundle, error) {
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+	}
+
+	if b.Format != Format {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, b.Format)
+	}
+
+	if b.Version < 1 || b.Version > Version {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, b.Version)
+	}
+
+	return &b, nil
+}
+
+// Validate checks that refs of the bundle are unique and
+// that sections reference only templates included in the bundle.
+func (b *Bundle) Validate() []Problem {
+	var problems []Problem
+
+	if b.Course.Title == "" {
+		problems = append(problems, Problem{Ref: b.Course.Ref, Reason: "course title is empty"})
+	}
+
+	refs := make(map[string]bool)
+	unique := func(ref string, kind string) {
+		switch {
+		case ref == "":
+			problems = append(problems, Problem{Reason: kind + " has empty ref"})
+		case refs[ref]:
+			problems = append(problems, Problem{Ref: ref, Reason: "duplicate ref"})
+		default:
+			refs[ref] = true
+		}
+	}
+
+	unique(b.Course.Ref, "course")
+
+	templates := make(map[string]bool, len(b.Templates))
+	for _, template := range b.Templates {
+		unique(template.Ref, "template")
+		templates[template.Ref] = true
+
+		if template.Widget.Type == "" {
+			problems = append(problems, Problem{Ref: template.Ref, Reason: "template has no widget"})
+		}
+
+		for _, rubric := range template.Rubrics {
+			unique(rubric.Ref, "rubric")
+		}
+	}
+
+	for _, section := range b.Course.Sections {
+		unique(section.Ref, "section")
+
+		listed := make(map[string]bool, len(section.Templates))
+		for _, ref := range section.Templates {
+			switch {
+			case !templates[ref]:
+				problems = append(problems, Problem{
+					Ref:    section.Ref,
+					Reason: "section references template " + ref + " missing in bundle",
+				})
+			case listed[ref]:
+				problems = append(problems, Problem{
+					Ref:    section.Ref,
+					Reason: "section lists template " + ref + " twice",
+				})
+			}
+			listed[ref] = true
+		}
+	}
+
+	return problems
+}
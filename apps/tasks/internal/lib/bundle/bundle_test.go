@@ -0,0 +1,94 @@
+package bundle
+
+import (
+	"encoding/json"
+	"testing"
+
+	"tasks/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	course := models.Course{
+		ID:    "course-1",
+		Title: "Drawing",
+		Sections: []models.CourseSection{
+			{ID: "section-1", Title: "Basics", TemplateIDs: []string{"template-1", "template-2"}},
+		},
+	}
+	templates := []models.Template{
+		{ID: "template-1", Title: "Lines", Widget: models.Widget{Type: "canvas", Version: 2, Config: json.RawMessage(`{"size":1}`)}},
+		{ID: "template-2", Title: "Shapes", Widget: models.Widget{Type: "canvas", Version: 2, Config: json.RawMessage(`{}`)}},
+	}
+	rubrics := []models.Rubric{
+		{ID: "rubric-1", TemplateID: "template-2", Title: "Accuracy", Criteria: []models.RubricCriterion{{ID: "c1", Title: "Lines", MaxScore: 5}}},
+	}
+
+	data, err := New(course, templates, rubrics).Encode()
+	require.NoError(t, err)
+
+	b, err := Decode(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, Format, b.Format)
+	assert.Equal(t, Version, b.Version)
+	assert.Equal(t, []Widget{{Type: "canvas", Version: 2}}, b.Widgets)
+	assert.Equal(t, "Drawing", b.Course.Title)
+	assert.Equal(t, []string{"template-1", "template-2"}, b.Course.Sections[0].Templates)
+	require.Len(t, b.Templates, 2)
+	assert.JSONEq(t, `{"size":1}`, string(b.Templates[0].Config))
+	assert.Empty(t, b.Templates[0].Rubrics)
+	require.Len(t, b.Templates[1].Rubrics, 1)
+	assert.Equal(t, "rubric-1", b.Templates[1].Rubrics[0].Ref)
+	assert.Empty(t, b.Validate())
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"not json", `course`, ErrUnknownFormat},
+		{"other format", `{"format":"other","version":1}`, ErrUnknownFormat},
+		{"no format", `{"version":1}`, ErrUnknownFormat},
+		{"future version", `{"format":"` + Format + `","version":2}`, ErrUnsupportedVersion},
+		{"zero version", `{"format":"` + Format + `","version":0}`, ErrUnsupportedVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.data))
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	b := &Bundle{
+		Format:  Format,
+		Version: Version,
+		Course: Course{
+			Ref: "course",
+			Sections: []Section{
+				{Ref: "section", Templates: []string{"template", "template", "missing"}},
+				{Ref: "template"},
+			},
+		},
+		Templates: []Template{
+			{Ref: "template", Widget: Widget{Type: "quiz", Version: 1}, Rubrics: []Rubric{{Ref: ""}}},
+			{Ref: "no-widget"},
+		},
+	}
+
+	assert.ElementsMatch(t, []Problem{
+		{Ref: "course", Reason: "course title is empty"},
+		{Reason: "rubric has empty ref"},
+		{Ref: "no-widget", Reason: "template has no widget"},
+		{Ref: "section", Reason: "section lists template template twice"},
+		{Ref: "section", Reason: "section references template missing missing in bundle"},
+		{Ref: "template", Reason: "duplicate ref"},
+	}, b.Validate())
+}
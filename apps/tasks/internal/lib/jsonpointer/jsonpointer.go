@@ -0,0 +1,93 @@
+// Package jsonpointer resolves the JSON pointers (RFC 6901)
+// used to anchor the comments to the parts of the submission payload.
+package jsonpointer
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrSyntax = errors.New("invalid json pointer")
+
+// Parse splits the pointer into the unescaped reference tokens.
+// The empty pointer refers to the whole document and has no tokens.
+func Parse(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if pointer[0] != '/' {
+		return nil, ErrSyntax
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, ErrSyntax
+			}
+		}
+
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	return tokens, nil
+}
+
+// Exists reports whether the pointer refers to a value of the JSON document.
+func Exists(doc []byte, pointer string) (bool, error) {
+	tokens, err := Parse(pointer)
+	if err != nil {
+		return false, err
+	}
+
+	var value any
+	if err := json.Unmarshal(doc, &value); err != nil {
+		return false, err
+	}
+
+	for _, token := range tokens {
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[token]
+			if !ok {
+				return false, nil
+			}
+
+			value = next
+		case []any:
+			i, ok := index(token)
+			if !ok || i >= len(v) {
+				return false, nil
+			}
+
+			value = v[i]
+		default:
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// index parses the array index, which has no leading zeros or sign.
+func index(token string) (int, bool) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, false
+	}
+
+	for _, c := range token {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+	}
+
+	i, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, false
+	}
+
+	return i, true
+}
@@ -0,0 +1,51 @@
+package jsonpointer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tokens, err := Parse("/blocks/0/a~1b/m~0n")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"blocks", "0", "a/b", "m~n"}, tokens)
+
+	tokens, err = Parse("")
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+
+	for _, pointer := range []string{"blocks", "/a~", "/a~2"} {
+		_, err := Parse(pointer)
+		assert.ErrorIs(t, err, ErrSyntax, pointer)
+	}
+}
+
+func TestExists(t *testing.T) {
+	doc := []byte(`{"title": "Осень", "blocks": [{"text": "Листья"}, {"a/b": 1}], "": true}`)
+
+	tests := []struct {
+		pointer string
+		exists  bool
+	}{
+		{pointer: "", exists: true},
+		{pointer: "/title", exists: true},
+		{pointer: "/blocks/0/text", exists: true},
+		{pointer: "/blocks/1/a~1b", exists: true},
+		{pointer: "/", exists: true},
+		{pointer: "/blocks/2", exists: false},
+		{pointer: "/blocks/01", exists: false},
+		{pointer: "/blocks/-", exists: false},
+		{pointer: "/title/0", exists: false},
+		{pointer: "/missing", exists: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			exists, err := Exists(doc, tt.pointer)
+			require.NoError(t, err)
+			assert.Equal(t, tt.exists, exists)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+// Package pseudonym derives the stable pseudonyms of the students
+// used to hide their identities in anonymous grading.
+package pseudonym
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"strconv"
+)
+
+const (
+	// KeySize is the size of the key of the assignment in bytes.
+	KeySize = 32
+	// codeLen is the number of base32 characters in the pseudonym,
+	// 30 bits are enough to avoid collisions within the class.
+	codeLen = 6
+	prefix  = "anon-"
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewKey generates the random key of the assignment.
+func NewKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// Of returns the pseudonym of the student, like "anon-K7F3QX".
+//
+// The pseudonym is the keyed hash of the student id, so it is stable
+// within the assignment, differs between assignments and cannot be
+// reversed without the key, even though the ids are small numbers.
+func Of(key []byte, studentID int64) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strconv.FormatInt(studentID, 10)))
+
+	return prefix + encoding.EncodeToString(mac.Sum(nil))[:codeLen]
+}
@@ -0,0 +1,23 @@
+package pseudonym
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOf(t *testing.T) {
+	key, err := NewKey()
+	require.NoError(t, err)
+
+	other, err := NewKey()
+	require.NoError(t, err)
+
+	p := Of(key, 42)
+
+	assert.Regexp(t, `^anon-[A-Z2-7]{6}$`, p)
+	assert.Equal(t, p, Of(key, 42), "pseudonym must be stable")
+	assert.NotEqual(t, p, Of(key, 43), "students must have different pseudonyms")
+	assert.NotEqual(t, p, Of(other, 42), "pseudonyms must differ between assignments")
+}
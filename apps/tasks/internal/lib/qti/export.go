@@ -0,0 +1,383 @@
+package qti
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"tasks/internal/domain/models"
+)
+
+const responseIdentifier = "RESPONSE"
+
+var htmlElements = map[string]bool{
+	"p": true,
+}
+
+type spec struct {
+	itemNamespace     string
+	manifestNamespace string
+	itemType          string
+	schema            string
+	schemaVersion     string
+	matchCorrect      string
+}
+
+var specs = map[Version]spec{
+	Version21: {
+		itemNamespace:     "http://www.imsglobal.org/xsd/imsqti_v2p1",
+		manifestNamespace: "http://www.imsglobal.org/xsd/imscp_v1p1",
+		itemType:          "imsqti_item_xmlv2p1",
+		schema:            "QTIv2.1 Package",
+		schemaVersion:     "1.0.0",
+		matchCorrect:      "http://www.imsglobal.org/question/qti_v2p1/rptemplates/match_correct",
+	},
+	Version30: {
+		itemNamespace:     "http://www.imsglobal.org/xsd/imsqti_v3p0",
+		manifestNamespace: "http://www.imsglobal.org/xsd/qti/qtiv3p0/imscp_v1p1",
+		itemType:          "imsqti_item_xmlv3p0",
+		schema:            "QTI Package",
+		schemaVersion:     "3.0.0",
+		matchCorrect:      "https://purl.imsglobal.org/spec/qti/v3p0/rptemplates/match_correct.xml",
+	},
+}
+
+// Export packs the questions of the quiz widget to the zipped QTI content package
+// of the given version, one assessment item per question.
+//
+// Questions that cannot be represented in QTI are reported in the returned slice.
+func Export(
+	identifier string,
+	questions []models.QuizQuestion,
+	version Version,
+) ([]byte, []Unsupported, error) {
+	s, ok := specs[version]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown qti version %q", version)
+	}
+
+	var (
+		buf         bytes.Buffer
+		unsupported []Unsupported
+		resources   []*node
+	)
+
+	zw := zip.NewWriter(&buf)
+
+	// the item ids name the files of the package as well
+	itemIDs := newIdentifiers()
+
+	for i, question := range questions {
+		itemID := itemIDs.issue(question.ID, fmt.Sprintf("ITEM_%d", i+1))
+
+		item, reason := buildItem(s, itemID, question)
+		if reason != "" {
+			unsupported = append(unsupported, Unsupported{
+				Identifier:  question.ID,
+				Interaction: string(question.Type),
+				Reason:      reason,
+			})
+
+			continue
+		}
+
+		href := "items/" + itemID + ".xml"
+		if err := writeFile(zw, href, item, version); err != nil {
+			return nil, nil, err
+		}
+
+		resources = append(resources, el("resource",
+			attrs("identifier", itemID, "type", s.itemType, "href", href),
+			el("file", attrs("href", href)),
+		))
+	}
+
+	manifest := el("manifest",
+		attrs("xmlns", s.manifestNamespace, "identifier", sanitizeIdentifier(identifier, "MANIFEST")),
+		el("metadata", nil,
+			textEl("schema", s.schema),
+			textEl("schemaversion", s.schemaVersion),
+		),
+		el("organizations", nil),
+		el("resources", nil, resources...),
+	)
+
+	// The manifest is the same in both versions and must not be converted to kebab-case.
+	if err := writeFile(zw, manifestName, manifest, Version21); err != nil {
+		return nil, nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	return buf.Bytes(), unsupported, nil
+}
+
+// buildItem builds the assessment item for the question.
+// If the question cannot be exported, it returns the reason.
+func buildItem(s spec, itemID string, question models.QuizQuestion) (*node, string) {
+	var (
+		cardinality = "single"
+		baseType    = "identifier"
+		interaction *node
+		correct     = question.Correct
+	)
+
+	switch question.Type {
+	case models.QuestionSingleChoice, models.QuestionMultipleChoice:
+		if len(question.Choices) == 0 {
+			return nil, "choice question has no choices"
+		}
+
+		maxChoices := "1"
+		if question.Type == models.QuestionMultipleChoice {
+			cardinality = "multiple"
+			maxChoices = "0"
+		}
+
+		interaction = el("choiceInteraction",
+			attrs("responseIdentifier", responseIdentifier, "shuffle", "false", "maxChoices", maxChoices),
+			textEl("prompt", question.Prompt),
+		)
+
+		// the correct response refers to the choices by their identifiers
+		choiceIDs := newIdentifiers()
+		byID := make(map[string]string, len(question.Choices))
+		for i, choice := range question.Choices {
+			choiceID := choiceIDs.issue(choice.ID, fmt.Sprintf("CHOICE_%d", i+1))
+			if _, ok := byID[choice.ID]; !ok {
+				byID[choice.ID] = choiceID
+			}
+
+			interaction.children = append(interaction.children,
+				textEl("simpleChoice", choice.Text, "identifier", choiceID),
+			)
+		}
+
+		correct = make([]string, 0, len(question.Correct))
+		for _, value := range question.Correct {
+			choiceID, ok := byID[value]
+			if !ok {
+				return nil, "correct response refers to unknown choice"
+			}
+			correct = append(correct, choiceID)
+		}
+	case models.QuestionTextEntry:
+		baseType = "string"
+		// text entry is an inline interaction and has to be placed inside a block.
+		interaction = el("p", nil,
+			&node{data: question.Prompt + " "},
+			el("textEntryInteraction", attrs("responseIdentifier", responseIdentifier)),
+		)
+	case models.QuestionOpenText:
+		baseType = "string"
+		interaction = el("extendedTextInteraction",
+			attrs("responseIdentifier", responseIdentifier),
+			textEl("prompt", question.Prompt),
+		)
+	default:
+		return nil, "question type is not supported by qti export"
+	}
+
+	declaration := el("responseDeclaration",
+		attrs("identifier", responseIdentifier, "cardinality", cardinality, "baseType", baseType),
+	)
+	if len(correct) > 0 {
+		response := el("correctResponse", nil)
+		for _, value := range correct {
+			response.children = append(response.children, textEl("value", value))
+		}
+		declaration.children = append(declaration.children, response)
+	}
+
+	title := question.Title
+	if title == "" {
+		title = itemID
+	}
+
+	item := el("assessmentItem",
+		attrs(
+			"xmlns", s.itemNamespace,
+			"identifier", itemID,
+			"title", title,
+			"adaptive", "false",
+			"timeDependent", "false",
+		),
+		declaration,
+		el("outcomeDeclaration", attrs("identifier", "SCORE", "cardinality", "single", "baseType", "float")),
+		el("itemBody", nil, interaction),
+	)
+
+	if len(correct) > 0 {
+		item.children = append(item.children,
+			el("responseProcessing", attrs("template", s.matchCorrect)),
+		)
+	}
+
+	return item, ""
+}
+
+func writeFile(zw *zip.Writer, name string, root *node, version Version) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	if err := encode(encoder, root, version); err != nil {
+		return err
+	}
+
+	return encoder.Flush()
+}
+
+func encode(encoder *xml.Encoder, n *node, version Version) error {
+	if n.name == "" {
+		return encoder.EncodeToken(xml.CharData(n.data))
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: elementName(n.name, version)}}
+	for _, key := range n.keys() {
+		start.Attr = append(start.Attr, xml.Attr{
+			Name:  xml.Name{Local: kebab(key, version)},
+			Value: n.attrs[key],
+		})
+	}
+
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for _, child := range n.children {
+		if err := encode(encoder, child, version); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// elementName converts QTI 2.1 element name to the spelling of the given version.
+// Elements of QTI 3.0 items are kebab-case with qti- prefix, except of the html ones.
+func elementName(name string, version Version) string {
+	if version != Version30 || htmlElements[name] {
+		return name
+	}
+
+	return "qti-" + kebab(name, version)
+}
+
+// kebab converts QTI 2.1 camelCase name to kebab-case for QTI 3.0.
+func kebab(name string, version Version) string {
+	if version != Version30 {
+		return name
+	}
+
+	var sb strings.Builder
+	for _, r := range name {
+		if unicode.IsUpper(r) {
+			sb.WriteByte('-')
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+// identifiers issues the identifiers which are unique within the scope,
+// e.g. the items of the package or the choices of the item.
+type identifiers struct {
+	issued map[string]bool
+}
+
+func newIdentifiers() *identifiers {
+	return &identifiers{issued: make(map[string]bool)}
+}
+
+// issue returns the valid xml identifier made from the id, or from the fallback
+// if the id is empty. The distinct ids may be sanitized to the same identifier,
+// e.g. "a b" and "a_b", so the identifier issued before gets a numeric suffix.
+func (ids *identifiers) issue(id string, fallback string) string {
+	base := sanitizeIdentifier(id, fallback)
+
+	identifier := base
+	for n := 2; ids.issued[identifier]; n++ {
+		identifier = fmt.Sprintf("%s_%d", base, n)
+	}
+	ids.issued[identifier] = true
+
+	return identifier
+}
+
+// sanitizeIdentifier makes the valid xml identifier from the id,
+// the empty id is replaced with the fallback.
+func sanitizeIdentifier(id string, fallback string) string {
+	if id == "" {
+		return fallback
+	}
+
+	var sb strings.Builder
+	for i, r := range id {
+		switch {
+		case unicode.IsLetter(r), r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		case i == 0 && unicode.IsDigit(r):
+			sb.WriteString("ID_")
+		default:
+			r = '_'
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+func el(name string, attrs map[string]string, children ...*node) *node {
+	return &node{
+		name:     name,
+		attrs:    attrs,
+		children: children,
+	}
+}
+
+func textEl(name string, text string, keyValues ...string) *node {
+	return el(name, attrs(keyValues...), &node{data: text})
+}
+
+func attrs(keyValues ...string) map[string]string {
+	m := make(map[string]string, len(keyValues)/2)
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		m[keyValues[i]] = keyValues[i+1]
+	}
+
+	return m
+}
+
+// keys returns the attribute names in stable order, namespace goes first.
+func (n *node) keys() []string {
+	keys := make([]string, 0, len(n.attrs))
+	for key := range n.attrs {
+		if key != "xmlns" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	if _, ok := n.attrs["xmlns"]; ok {
+		keys = append([]string{"xmlns"}, keys...)
+	}
+
+	return keys
+}
@@ -0,0 +1,391 @@
+package qti
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"tasks/internal/domain/models"
+)
+
+type Version string
+
+const (
+	Version21 Version = "2.1"
+	Version30 Version = "3.0"
+)
+
+const manifestName = "imsmanifest.xml"
+
+// maxFileSize limits the size of a single unpacked file of the package.
+const maxFileSize = 10 << 20
+
+var (
+	ErrInvalidPackage = errors.New("invalid qti package")
+	ErrNoManifest     = errors.New("qti package has no imsmanifest.xml")
+)
+
+// Package is the content of an imported QTI package.
+type Package struct {
+	Version     Version
+	Questions   []models.QuizQuestion
+	Unsupported []Unsupported
+}
+
+// Unsupported describes an item that could not be converted
+// between QTI and the quiz widget.
+type Unsupported struct {
+	Identifier  string
+	Interaction string
+	Reason      string
+}
+
+// Import reads the zipped QTI 2.1 or 3.0 content package
+// and converts its items to the questions of the quiz widget.
+//
+// Items that cannot be represented by the quiz widget are not dropped,
+// they are reported in Package.Unsupported.
+func Import(data []byte) (*Package, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPackage, err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[path.Clean(f.Name)] = f
+	}
+
+	manifestFile, ok := files[manifestName]
+	if !ok {
+		return nil, ErrNoManifest
+	}
+
+	manifest, err := readNode(manifestFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidPackage, manifestName, err)
+	}
+
+	pkg := &Package{Version: Version21}
+
+	for _, resource := range manifest.findAll("resource") {
+		resourceType := resource.attrs["type"]
+		if !strings.HasPrefix(resourceType, "imsqti_item") {
+			continue
+		}
+
+		if strings.Contains(resourceType, "v3p0") {
+			pkg.Version = Version30
+		}
+
+		href := path.Clean(resource.attrs["href"])
+
+		f, ok := files[href]
+		if !ok {
+			pkg.Unsupported = append(pkg.Unsupported, Unsupported{
+				Identifier: resource.attrs["identifier"],
+				Reason:     "item file " + href + " is missing in package",
+			})
+
+			continue
+		}
+
+		item, err := readNode(f)
+		if err != nil {
+			pkg.Unsupported = append(pkg.Unsupported, Unsupported{
+				Identifier: resource.attrs["identifier"],
+				Reason:     "item file " + href + " is not valid xml",
+			})
+
+			continue
+		}
+
+		question, unsupported := parseItem(item)
+		if unsupported != nil {
+			pkg.Unsupported = append(pkg.Unsupported, *unsupported)
+
+			continue
+		}
+
+		pkg.Questions = append(pkg.Questions, question)
+	}
+
+	return pkg, nil
+}
+
+// parseItem converts the assessment item to the quiz question.
+// If the item cannot be converted, it returns the reason.
+func parseItem(item *node) (models.QuizQuestion, *Unsupported) {
+	identifier := item.attrs["identifier"]
+
+	if item.name != "assessmentItem" {
+		return models.QuizQuestion{}, &Unsupported{
+			Identifier: identifier,
+			Reason:     "root element is not an assessment item",
+		}
+	}
+
+	body := item.find("itemBody")
+	if body == nil {
+		return models.QuizQuestion{}, &Unsupported{
+			Identifier: identifier,
+			Reason:     "item has no body",
+		}
+	}
+
+	interactions := body.findAllFunc(isInteraction)
+
+	switch len(interactions) {
+	case 0:
+		return models.QuizQuestion{}, &Unsupported{
+			Identifier: identifier,
+			Reason:     "item has no interactions",
+		}
+	case 1:
+	default:
+		names := make([]string, 0, len(interactions))
+		for _, interaction := range interactions {
+			names = append(names, interaction.name)
+		}
+
+		return models.QuizQuestion{}, &Unsupported{
+			Identifier:  identifier,
+			Interaction: strings.Join(names, ","),
+			Reason:      "items with several interactions are not supported",
+		}
+	}
+
+	interaction := interactions[0]
+
+	question := models.QuizQuestion{
+		ID:    identifier,
+		Title: item.attrs["title"],
+	}
+
+	if prompt := interaction.find("prompt"); prompt != nil {
+		question.Prompt = prompt.text(nil)
+	} else {
+		question.Prompt = body.text(isInteraction)
+	}
+
+	declaration := responseDeclaration(item, interaction.attrs["responseIdentifier"])
+
+	switch interaction.name {
+	case "choiceInteraction":
+		question.Type = models.QuestionSingleChoice
+		if declaration != nil && declaration.attrs["cardinality"] == "multiple" {
+			question.Type = models.QuestionMultipleChoice
+		}
+
+		for _, choice := range interaction.findAll("simpleChoice") {
+			question.Choices = append(question.Choices, models.QuizChoice{
+				ID:   choice.attrs["identifier"],
+				Text: choice.text(nil),
+			})
+		}
+
+		if len(question.Choices) == 0 {
+			return models.QuizQuestion{}, &Unsupported{
+				Identifier:  identifier,
+				Interaction: interaction.name,
+				Reason:      "choice interaction has no simple choices",
+			}
+		}
+	case "textEntryInteraction":
+		question.Type = models.QuestionTextEntry
+	case "extendedTextInteraction":
+		question.Type = models.QuestionOpenText
+	default:
+		return models.QuizQuestion{}, &Unsupported{
+			Identifier:  identifier,
+			Interaction: interaction.name,
+			Reason:      "interaction is not supported by quiz widget",
+		}
+	}
+
+	if declaration != nil {
+		if correct := declaration.find("correctResponse"); correct != nil {
+			for _, value := range correct.findAll("value") {
+				question.Correct = append(question.Correct, value.text(nil))
+			}
+		}
+	}
+
+	return question, nil
+}
+
+func responseDeclaration(item *node, identifier string) *node {
+	for _, declaration := range item.findAll("responseDeclaration") {
+		if declaration.attrs["identifier"] == identifier {
+			return declaration
+		}
+	}
+
+	return nil
+}
+
+func isInteraction(n *node) bool {
+	return strings.HasSuffix(n.name, "Interaction")
+}
+
+func readNode(f *zip.File) (*node, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return parse(io.LimitReader(rc, maxFileSize))
+}
+
+// node is a simplified xml element.
+// Names of QTI 3.0 elements and attributes are normalized to
+// QTI 2.1 spelling, so qti-choice-interaction becomes choiceInteraction.
+// Text nodes are represented by nodes with empty name.
+type node struct {
+	name     string
+	attrs    map[string]string
+	children []*node
+	data     string
+}
+
+func parse(r io.Reader) (*node, error) {
+	decoder := xml.NewDecoder(r)
+
+	var (
+		root  *node
+		stack []*node
+	)
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			n := &node{
+				name:  normalize(t.Name.Local),
+				attrs: make(map[string]string, len(t.Attr)),
+			}
+			for _, attr := range t.Attr {
+				n.attrs[normalize(attr.Name.Local)] = attr.Value
+			}
+
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, &node{data: string(t)})
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("document is empty")
+	}
+
+	return root, nil
+}
+
+// normalize converts QTI 3.0 kebab-case name to QTI 2.1 camelCase name.
+func normalize(name string) string {
+	name = strings.TrimPrefix(name, "qti-")
+	if !strings.Contains(name, "-") {
+		return name
+	}
+
+	parts := strings.Split(name, "-")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+
+	return strings.Join(parts, "")
+}
+
+// find returns the first descendant with the given name.
+func (n *node) find(name string) *node {
+	for _, child := range n.children {
+		if child.name == name {
+			return child
+		}
+		if found := child.find(name); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// findAll returns all descendants with the given name in document order.
+func (n *node) findAll(name string) []*node {
+	return n.findAllFunc(func(child *node) bool {
+		return child.name == name
+	})
+}
+
+func (n *node) findAllFunc(match func(*node) bool) []*node {
+	var found []*node
+	for _, child := range n.children {
+		if child.name == "" {
+			continue
+		}
+		if match(child) {
+			found = append(found, child)
+
+			continue
+		}
+
+		found = append(found, child.findAllFunc(match)...)
+	}
+
+	return found
+}
+
+// text returns the whitespace collapsed text of the node.
+// Subtrees matched by skip are not included.
+func (n *node) text(skip func(*node) bool) string {
+	var sb strings.Builder
+	n.writeText(&sb, skip)
+
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func (n *node) writeText(sb *strings.Builder, skip func(*node) bool) {
+	for _, child := range n.children {
+		if child.name == "" {
+			sb.WriteString(child.data)
+
+			continue
+		}
+		if skip != nil && skip(child) {
+			continue
+		}
+
+		sb.WriteByte(' ')
+		child.writeText(sb, skip)
+		sb.WriteByte(' ')
+	}
+}
@@ -0,0 +1,194 @@
+package qti
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"tasks/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const item30 = `<?xml version="1.0" encoding="UTF-8"?>
+<qti-assessment-item xmlns="http://www.imsglobal.org/xsd/imsqti_v3p0" identifier="Q1" title="Colors">
+  <qti-response-declaration identifier="RESPONSE" cardinality="multiple" base-type="identifier">
+    <qti-correct-response>
+      <qti-value>A</qti-value>
+      <qti-value>C</qti-value>
+    </qti-correct-response>
+  </qti-response-declaration>
+  <qti-item-body>
+    <qti-choice-interaction response-identifier="RESPONSE" max-choices="0">
+      <qti-prompt>Which colors are <em>primary</em>?</qti-prompt>
+      <qti-simple-choice identifier="A">Red</qti-simple-choice>
+      <qti-simple-choice identifier="B">Green</qti-simple-choice>
+      <qti-simple-choice identifier="C">Blue</qti-simple-choice>
+    </qti-choice-interaction>
+  </qti-item-body>
+</qti-assessment-item>`
+
+const itemHotspot = `<?xml version="1.0" encoding="UTF-8"?>
+<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="Q2" title="Map">
+  <itemBody>
+    <hotspotInteraction responseIdentifier="RESPONSE" maxChoices="1"/>
+  </itemBody>
+</assessmentItem>`
+
+const manifest = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest xmlns="http://www.imsglobal.org/xsd/qti/qtiv3p0/imscp_v1p1" identifier="M">
+  <resources>
+    <resource identifier="Q1" type="imsqti_item_xmlv3p0" href="items/q1.xml"/>
+    <resource identifier="Q2" type="imsqti_item_xmlv3p0" href="items/q2.xml"/>
+  </resources>
+</manifest>`
+
+func TestImport_ReportsUnsupportedItems(t *testing.T) {
+	pkg, err := Import(zipFiles(t, map[string]string{
+		"imsmanifest.xml": manifest,
+		"items/q1.xml":    item30,
+		"items/q2.xml":    itemHotspot,
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, Version30, pkg.Version)
+	require.Len(t, pkg.Questions, 1)
+	assert.Equal(t, models.QuizQuestion{
+		ID:     "Q1",
+		Type:   models.QuestionMultipleChoice,
+		Title:  "Colors",
+		Prompt: "Which colors are primary ?",
+		Choices: []models.QuizChoice{
+			{ID: "A", Text: "Red"},
+			{ID: "B", Text: "Green"},
+			{ID: "C", Text: "Blue"},
+		},
+		Correct: []string{"A", "C"},
+	}, pkg.Questions[0])
+
+	require.Len(t, pkg.Unsupported, 1)
+	assert.Equal(t, "Q2", pkg.Unsupported[0].Identifier)
+	assert.Equal(t, "hotspotInteraction", pkg.Unsupported[0].Interaction)
+}
+
+func TestImport_NoManifest(t *testing.T) {
+	_, err := Import(zipFiles(t, map[string]string{
+		"items/q1.xml": item30,
+	}))
+	assert.ErrorIs(t, err, ErrNoManifest)
+}
+
+func TestExport_RoundTrip(t *testing.T) {
+	questions := []models.QuizQuestion{
+		{
+			ID:     "capital",
+			Type:   models.QuestionSingleChoice,
+			Title:  "Capital",
+			Prompt: "Capital of France?",
+			Choices: []models.QuizChoice{
+				{ID: "A", Text: "Paris"},
+				{ID: "B", Text: "Rome"},
+			},
+			Correct: []string{"A"},
+		},
+		{
+			ID:      "word",
+			Type:    models.QuestionTextEntry,
+			Title:   "Word",
+			Prompt:  "Translate cat:",
+			Correct: []string{"кошка"},
+		},
+		{
+			ID:     "essay",
+			Type:   models.QuestionOpenText,
+			Title:  "Essay",
+			Prompt: "Describe your drawing.",
+		},
+		{
+			ID:   "drawing",
+			Type: "canvas",
+		},
+	}
+
+	for _, version := range []Version{Version21, Version30} {
+		t.Run(string(version), func(t *testing.T) {
+			data, unsupported, err := Export("template", questions, version)
+			require.NoError(t, err)
+
+			require.Len(t, unsupported, 1)
+			assert.Equal(t, "drawing", unsupported[0].Identifier)
+
+			pkg, err := Import(data)
+			require.NoError(t, err)
+
+			assert.Equal(t, version, pkg.Version)
+			assert.Empty(t, pkg.Unsupported)
+			assert.Equal(t, questions[:3], pkg.Questions)
+		})
+	}
+}
+
+func TestExport_CollidingIdentifiers(t *testing.T) {
+	questions := []models.QuizQuestion{
+		{
+			ID:     "a b",
+			Type:   models.QuestionSingleChoice,
+			Prompt: "First?",
+			Choices: []models.QuizChoice{
+				{ID: "x y", Text: "One"},
+				{ID: "x_y", Text: "Two"},
+			},
+			Correct: []string{"x_y"},
+		},
+		{
+			ID:     "a_b",
+			Type:   models.QuestionOpenText,
+			Prompt: "Second?",
+		},
+	}
+
+	data, unsupported, err := Export("template", questions, Version30)
+	require.NoError(t, err)
+	assert.Empty(t, unsupported)
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	names := make(map[string]bool)
+	for _, f := range zr.File {
+		assert.False(t, names[f.Name], "duplicate entry %s", f.Name)
+		names[f.Name] = true
+	}
+	assert.Len(t, names, 3)
+
+	pkg, err := Import(data)
+	require.NoError(t, err)
+	require.Len(t, pkg.Questions, 2)
+
+	assert.Equal(t, "a_b", pkg.Questions[0].ID)
+	assert.Equal(t, "a_b_2", pkg.Questions[1].ID)
+
+	assert.Equal(t, []models.QuizChoice{
+		{ID: "x_y", Text: "One"},
+		{ID: "x_y_2", Text: "Two"},
+	}, pkg.Questions[0].Choices)
+	assert.Equal(t, []string{"x_y_2"}, pkg.Questions[0].Correct)
+}
+
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
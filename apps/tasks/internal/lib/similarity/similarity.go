@@ -0,0 +1,325 @@
+// Package similarity finds the common fragments of the texts using winnowing.
+//
+// The text is split into the words, every k consecutive words (the shingle)
+// are hashed and the minimal hash of every window of w shingles is kept as
+// the fingerprint of the text. Any fragment of at least k+w-1 common words is
+// guaranteed to produce the common fingerprint, while the number of the kept
+// hashes is about 2/(w+1) of all shingles.
+//
+// See Schleimer, Wilkerson, Aiken "Winnowing: Local Algorithms for Document Fingerprinting".
+package similarity
+
+import (
+	"bytes"
+	"encoding/json"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// shingleSize is the number of the words hashed together.
+	shingleSize = 5
+	// windowSize is the number of the consecutive shingles a fingerprint is selected from.
+	windowSize = 4
+	// maxFragments limits the number of the fragments returned for a pair.
+	maxFragments = 20
+	// maxFragmentLen limits the length of the fragment text in bytes.
+	maxFragmentLen = 500
+)
+
+// attachmentRefKey is the key of the attachment reference in the payload.
+// Attachment ids are random and never copied, so they are not compared.
+const attachmentRefKey = "$attachment"
+
+// Document is the fingerprinted text.
+type Document struct {
+	text   string
+	words  []span
+	prints []fingerprint
+}
+
+// Fragment is the fragment of the text found in both documents.
+type Fragment struct {
+	// Text is the fragment as written in the first document.
+	Text string
+	// OtherText is the fragment as written in the second document.
+	OtherText string
+}
+
+// Result is the outcome of the comparison of two documents.
+type Result struct {
+	// Score is the share of the fingerprints of the smaller document
+	// found in the other one, from 0 to 1.
+	Score     float64
+	Fragments []Fragment
+}
+
+type span struct {
+	start int
+	end   int
+}
+
+// fingerprint is the selected hash of the shingle starting at the word pos.
+type fingerprint struct {
+	hash uint64
+	pos  int
+}
+
+// Text extracts the text of the JSON payload of the submission:
+// all string values in the order of their appearance, one per line.
+// Object keys and attachment references are skipped.
+func Text(payload []byte) string {
+	var b strings.Builder
+
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+
+	// The malformed payload is compared by its valid part.
+	_ = writeText(dec, &b)
+
+	return b.String()
+}
+
+// writeText writes the strings of the next JSON value read from dec to w.
+// If w is nil, the value is skipped.
+func writeText(dec *json.Decoder, w *strings.Builder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch v := tok.(type) {
+	case json.Delim:
+		object := v == '{'
+
+		for dec.More() {
+			valueW := w
+			if object {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+
+				if key == attachmentRefKey {
+					valueW = nil
+				}
+			}
+
+			if err := writeText(dec, valueW); err != nil {
+				return err
+			}
+		}
+
+		// closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	case string:
+		if w != nil {
+			w.WriteString(v)
+			w.WriteByte('\n')
+		}
+	}
+
+	return nil
+}
+
+// Fingerprint splits the text into the words and selects its fingerprints.
+func Fingerprint(text string) Document {
+	doc := Document{
+		text:  text,
+		words: words(text),
+	}
+
+	if len(doc.words) < shingleSize {
+		return doc
+	}
+
+	hashes := make([]uint64, len(doc.words)-shingleSize+1)
+	for i := range hashes {
+		h := fnv.New64a()
+		for _, w := range doc.words[i : i+shingleSize] {
+			h.Write([]byte(strings.ToLower(text[w.start:w.end])))
+			h.Write([]byte{0})
+		}
+
+		hashes[i] = h.Sum64()
+	}
+
+	doc.prints = winnow(hashes)
+
+	return doc
+}
+
+// Empty reports whether the document is too short to be compared.
+func (d Document) Empty() bool {
+	return len(d.prints) == 0
+}
+
+// Compare compares two documents and returns the common fragments.
+func Compare(a, b Document) Result {
+	if a.Empty() || b.Empty() {
+		return Result{}
+	}
+
+	positions := make(map[uint64]int, len(b.prints))
+	for _, p := range b.prints {
+		if _, ok := positions[p.hash]; !ok {
+			positions[p.hash] = p.pos
+		}
+	}
+
+	type match struct {
+		a int
+		b int
+	}
+
+	var (
+		matches []match
+		seen    = make(map[uint64]struct{})
+	)
+	for _, p := range a.prints {
+		pos, ok := positions[p.hash]
+		if !ok {
+			continue
+		}
+
+		matches = append(matches, match{a: p.pos, b: pos})
+		seen[p.hash] = struct{}{}
+	}
+
+	if len(matches) == 0 {
+		return Result{}
+	}
+
+	smaller := min(distinct(a.prints), distinct(b.prints))
+	res := Result{
+		Score: float64(len(seen)) / float64(smaller),
+	}
+
+	// Consecutive matches covering overlapping shingles in both documents
+	// are merged into one fragment.
+	first, last := matches[0], matches[0]
+	flush := func() {
+		if len(res.Fragments) < maxFragments {
+			res.Fragments = append(res.Fragments, Fragment{
+				Text:      a.fragment(first.a, last.a),
+				OtherText: b.fragment(first.b, last.b),
+			})
+		}
+	}
+
+	for _, m := range matches[1:] {
+		if m.a <= last.a+shingleSize && m.b > last.b && m.b <= last.b+shingleSize {
+			last = m
+
+			continue
+		}
+
+		flush()
+		first, last = m, m
+	}
+	flush()
+
+	return res
+}
+
+// fragment returns the text from the shingle at the word from to the end of the shingle at the word to.
+func (d Document) fragment(from, to int) string {
+	start := d.words[from].start
+	end := d.words[min(to+shingleSize, len(d.words))-1].end
+
+	text := d.text[start:end]
+	if len(text) > maxFragmentLen {
+		cut := maxFragmentLen
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+
+		text = text[:cut] + "…"
+	}
+
+	return text
+}
+
+// winnow selects the minimal hash of every window of windowSize hashes.
+// On ties the rightmost hash is selected, and the same hash is recorded only once.
+func winnow(hashes []uint64) []fingerprint {
+	if len(hashes) <= windowSize {
+		minPos := 0
+		for i, h := range hashes {
+			if h <= hashes[minPos] {
+				minPos = i
+			}
+		}
+
+		return []fingerprint{{hash: hashes[minPos], pos: minPos}}
+	}
+
+	var (
+		prints []fingerprint
+		last   = -1
+	)
+	for start := 0; start+windowSize <= len(hashes); start++ {
+		minPos := start
+		for i := start; i < start+windowSize; i++ {
+			if hashes[i] <= hashes[minPos] {
+				minPos = i
+			}
+		}
+
+		if minPos != last {
+			prints = append(prints, fingerprint{hash: hashes[minPos], pos: minPos})
+			last = minPos
+		}
+	}
+
+	return prints
+}
+
+// words returns the positions of the words of the text.
+// The word is the run of letters and digits, so the punctuation,
+// the formatting and the case do not affect the comparison.
+func words(text string) []span {
+	var (
+		res   []span
+		start = -1
+	)
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			res = append(res, span{start: start, end: i})
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		res = append(res, span{start: start, end: len(text)})
+	}
+
+	return res
+}
+
+func distinct(prints []fingerprint) int {
+	hashes := make([]uint64, len(prints))
+	for i, p := range prints {
+		hashes[i] = p.hash
+	}
+
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+
+	n := 0
+	for i, h := range hashes {
+		if i == 0 || h != hashes[i-1] {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,104 @@
+package similarity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const essay = `Осень пришла в наш город незаметно. Листья на старых клёнах
+пожелтели за одну ночь, и утром дворник долго сметал их с тротуара.
+Дети шли в школу и собирали самые красивые листья в букеты.`
+
+func TestText(t *testing.T) {
+	payload := `{
+		"title": "Осень",
+		"blocks": [
+			{"type": "text", "text": "Листья пожелтели"},
+			{"type": "image", "image": {"$attachment": "6f1c9a52-4d0e-4a43-9c7b-0f0c2a1f1b9e"}},
+			{"score": 5, "done": true}
+		]
+	}`
+
+	assert.Equal(t, "Осень\ntext\nЛистья пожелтели\nimage\n", Text([]byte(payload)))
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name      string
+		a         string
+		b         string
+		minScore  float64
+		maxScore  float64
+		fragments int
+	}{
+		{
+			name:      "identical",
+			a:         essay,
+			b:         essay,
+			minScore:  1,
+			maxScore:  1,
+			fragments: 1,
+		},
+		{
+			name:      "formatting and case are ignored",
+			a:         essay,
+			b:         strings.ToUpper(strings.NewReplacer("\n", " ", ",", " —", ".", "!\n\n").Replace(essay)),
+			minScore:  1,
+			maxScore:  1,
+			fragments: 1,
+		},
+		{
+			name:      "copied paragraph",
+			a:         essay,
+			b:         "Я люблю лето больше всего на свете, потому что можно купаться. " + strings.SplitN(essay, "\n", 2)[1] + " А зимой мы катаемся на лыжах в ближайшем лесу каждое воскресенье.",
+			minScore:  0.5,
+			maxScore:  1,
+			fragments: 1,
+		},
+		{
+			name:      "different",
+			a:         essay,
+			b:         "func main() {\n\tfor i := 0; i < 10; i++ {\n\t\tfmt.Println(i * i)\n\t}\n}",
+			minScore:  0,
+			maxScore:  0,
+			fragments: 0,
+		},
+		{
+			name:      "too short",
+			a:         "Осень пришла",
+			b:         "Осень пришла",
+			minScore:  0,
+			maxScore:  0,
+			fragments: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Compare(Fingerprint(tt.a), Fingerprint(tt.b))
+
+			assert.GreaterOrEqual(t, res.Score, tt.minScore)
+			assert.LessOrEqual(t, res.Score, tt.maxScore)
+			require.Len(t, res.Fragments, tt.fragments)
+
+			for _, f := range res.Fragments {
+				assert.Contains(t, tt.a, f.Text)
+				assert.Contains(t, tt.b, f.OtherText)
+			}
+		})
+	}
+}
+
+func TestCompareFragment(t *testing.T) {
+	code := "def total(items):\n    result = 0\n    for item in items:\n        result += item.price * item.count\n    return result\n"
+	other := "# my solution\ndef total(goods):\n    s = 0\n    for item in items:\n        result += item.price * item.count\n    return result\n"
+
+	res := Compare(Fingerprint(code), Fingerprint(other))
+
+	require.Len(t, res.Fragments, 1)
+	assert.Contains(t, res.Fragments[0].Text, "for item in items:\n        result += item.price * item.count")
+	assert.Equal(t, res.Fragments[0].Text, res.Fragments[0].OtherText)
+}
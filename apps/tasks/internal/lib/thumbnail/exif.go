@@ -0,0 +1,98 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	jpegSOI  = 0xD8
+	jpegSOS  = 0xDA
+	jpegAPP1 = 0xE1
+
+	tagOrientation = 0x0112
+	typeShort      = 3
+)
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// orientation returns the EXIF orientation (1-8) of the JPEG image.
+// If the image has no EXIF or the orientation cannot be read, it returns 1.
+func orientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != jpegSOI {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+
+		marker := data[i+1]
+		if marker == jpegSOS {
+			return 1
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return 1
+		}
+
+		segment := data[i+4 : end]
+		if marker == jpegAPP1 && bytes.HasPrefix(segment, exifHeader) {
+			return tiffOrientation(segment[len(exifHeader):])
+		}
+
+		i = end
+	}
+
+	return 1
+}
+
+// tiffOrientation reads the orientation tag from the first IFD of the TIFF structure.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+
+		if order.Uint16(tiff[entry:]) != tagOrientation {
+			continue
+		}
+
+		if order.Uint16(tiff[entry+2:]) != typeShort {
+			return 1
+		}
+
+		value := int(order.Uint16(tiff[entry+8:]))
+		if value < 1 || value > 8 {
+			return 1
+		}
+
+		return value
+	}
+
+	return 1
+}
@@ -0,0 +1,201 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+const (
+	// maxPixels protects from decompression bombs.
+	maxPixels   = 50_000_000
+	jpegQuality = 85
+)
+
+var (
+	ErrUnsupported = errors.New("image format is not supported")
+	ErrTooLarge    = errors.New("image is too large")
+)
+
+// Image is the generated rendition of the image.
+type Image struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Supported reports whether the rendition can be generated for the content type.
+func Supported(contentType string) bool {
+	switch contentType {
+	case "image/png", "image/jpeg", "image/gif":
+		return true
+	default:
+		return false
+	}
+}
+
+// ContentType returns the content type of the rendition
+// generated for the image of the given content type.
+func ContentType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return "image/jpeg"
+	}
+
+	return "image/png"
+}
+
+// Generate decodes the PNG, JPEG or GIF image, applies its EXIF orientation
+// and scales it down to fit into maxSize x maxSize.
+//
+// The image is re-encoded, so no metadata of the source is kept:
+// JPEG images stay JPEG, the others are encoded as PNG to keep transparency.
+// Only the first frame of the animated GIF is used.
+func Generate(data []byte, contentType string, maxSize int) (Image, error) {
+	if !Supported(contentType) {
+		return Image{}, ErrUnsupported
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+
+	if cfg.Width*cfg.Height > maxPixels {
+		return Image{}, ErrTooLarge
+	}
+
+	var src image.Image
+	switch contentType {
+	case "image/png":
+		src, err = png.Decode(bytes.NewReader(data))
+	case "image/jpeg":
+		src, err = jpeg.Decode(bytes.NewReader(data))
+	case "image/gif":
+		src, err = gif.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return Image{}, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+
+	o := 1
+	if contentType == "image/jpeg" {
+		o = orientation(data)
+	}
+
+	dst := resize(orient(src, o), maxSize)
+
+	var buf bytes.Buffer
+	res := Image{
+		Width:  dst.Bounds().Dx(),
+		Height: dst.Bounds().Dy(),
+	}
+
+	res.ContentType = ContentType(contentType)
+	if res.ContentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return Image{}, err
+	}
+
+	res.Data = buf.Bytes()
+
+	return res, nil
+}
+
+// orient returns the image as it must be displayed according to the EXIF orientation.
+func orient(src image.Image, orientation int) *image.NRGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sx, sy := x, y
+
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+
+			dst.Set(x, y, src.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+
+	return dst
+}
+
+// resize scales the image down to fit into maxSize x maxSize keeping the aspect ratio.
+// Every pixel of the result is the average of the source pixels it covers.
+func resize(src *image.NRGBA, maxSize int) *image.NRGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if w <= maxSize && h <= maxSize {
+		return src
+	}
+
+	dw, dh := maxSize, h*maxSize/w
+	if h > w {
+		dw, dh = w*maxSize/h, maxSize
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := src.NRGBAAt(sx, sy)
+					// colors are weighted by alpha, so transparent pixels do not darken the edges
+					r += int(c.R) * int(c.A)
+					g += int(c.G) * int(c.A)
+					b += int(c.B) * int(c.A)
+					a += int(c.A)
+					n++
+				}
+			}
+
+			if a == 0 {
+				continue
+			}
+
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / a),
+				G: uint8(g / a),
+				B: uint8(b / a),
+				A: uint8(a / n),
+			})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,127 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withOrientation inserts the EXIF segment with the orientation right after SOI of the JPEG.
+func withOrientation(t *testing.T, data []byte, orientation uint16) []byte {
+	t.Helper()
+
+	tiff := make([]byte, 26)
+	copy(tiff, "II")
+	binary.LittleEndian.PutUint16(tiff[2:], 42)
+	binary.LittleEndian.PutUint32(tiff[4:], 8)
+	binary.LittleEndian.PutUint16(tiff[8:], 1)
+	binary.LittleEndian.PutUint16(tiff[10:], tagOrientation)
+	binary.LittleEndian.PutUint16(tiff[12:], typeShort)
+	binary.LittleEndian.PutUint32(tiff[14:], 1)
+	binary.LittleEndian.PutUint16(tiff[18:], orientation)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	var buf bytes.Buffer
+	buf.Write(data[:2])
+	buf.Write([]byte{0xFF, jpegAPP1})
+	require.NoError(t, binary.Write(&buf, binary.BigEndian, uint16(len(segment)+2)))
+	buf.Write(segment)
+	buf.Write(data[2:])
+
+	return buf.Bytes()
+}
+
+func TestGenerateAppliesOrientation(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 20 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, src, &jpeg.Options{Quality: 100}))
+
+	data := withOrientation(t, buf.Bytes(), 6)
+	require.Equal(t, 6, orientation(data))
+
+	res, err := Generate(data, "image/jpeg", 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, "image/jpeg", res.ContentType)
+	assert.Equal(t, 5, res.Width)
+	assert.Equal(t, 10, res.Height)
+
+	// metadata of the source is not copied to the rendition
+	assert.Equal(t, 1, orientation(res.Data))
+	assert.False(t, bytes.Contains(res.Data, []byte("Exif")))
+
+	img, err := jpeg.Decode(bytes.NewReader(res.Data))
+	require.NoError(t, err)
+
+	// rotated clockwise, the left red half of the source is on the top
+	r, _, b, _ := img.At(2, 1).RGBA()
+	assert.Greater(t, r, b)
+	r, _, b, _ = img.At(2, 8).RGBA()
+	assert.Greater(t, b, r)
+}
+
+func TestGenerateKeepsTransparency(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	src.SetNRGBA(0, 0, color.NRGBA{G: 255, A: 255})
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, src))
+
+	res, err := Generate(buf.Bytes(), "image/png", 4)
+	require.NoError(t, err)
+
+	assert.Equal(t, "image/png", res.ContentType)
+
+	img, err := png.Decode(bytes.NewReader(res.Data))
+	require.NoError(t, err)
+
+	_, g, _, a := img.At(0, 0).RGBA()
+	assert.NotZero(t, g)
+	assert.Less(t, a, uint32(0xFFFF))
+
+	_, _, _, a = img.At(3, 3).RGBA()
+	assert.Zero(t, a)
+}
+
+func TestGenerateGIFAsPNG(t *testing.T) {
+	src := image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{color.Black, color.White})
+
+	var buf bytes.Buffer
+	require.NoError(t, gif.Encode(&buf, src, nil))
+
+	res, err := Generate(buf.Bytes(), "image/gif", 4)
+	require.NoError(t, err)
+
+	assert.Equal(t, "image/png", res.ContentType)
+	assert.Equal(t, ContentType("image/gif"), res.ContentType)
+
+	_, err = png.Decode(bytes.NewReader(res.Data))
+	assert.NoError(t, err)
+}
+
+func TestGenerateUnsupported(t *testing.T) {
+	_, err := Generate([]byte("not an image"), "image/png", 10)
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	_, err = Generate([]byte("%PDF-1.4"), "application/pdf", 10)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
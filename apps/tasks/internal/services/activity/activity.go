@@ -0,0 +1,276 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/lib/pseudonym"
+	"tasks/internal/storage"
+)
+
+const (
+	// watchBatchSize is the number of activities read for the watcher at once.
+	watchBatchSize = 100
+	// watchPollInterval is how often the watcher checks for the new activities.
+	watchPollInterval = time.Second
+	// timeLimitLookback is how far back the time limits are looked up,
+	// so the ones passed while the scheduler was down are recorded too.
+	timeLimitLookback = time.Hour
+	// purgeBatchSize limits the number of activities deleted at once.
+	purgeBatchSize = 1000
+)
+
+var (
+	ErrAssignmentNotFound = storage.ErrAssignmentNotFound
+	ErrAccessDenied       = errors.New("access to assignment denied")
+	ErrActivityExpired    = errors.New("activity is no longer kept")
+)
+
+type ActivityService struct {
+	log                *slog.Logger
+	activitySaver      ActivitySaver
+	activityProvider   ActivityProvider
+	assignmentProvider AssignmentProvider
+}
+
+type ActivitySaver interface {
+	RecordTimeLimits(ctx context.Context, since time.Time, now time.Time) (int64, error)
+	PurgeActivity(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+type ActivityProvider interface {
+	Activities(
+		ctx context.Context,
+		assignmentID string,
+		afterID int64,
+		limit int,
+	) ([]models.Activity, error)
+	HasActivity(ctx context.Context, assignmentID string, activityID int64) (bool, error)
+	LastActivityID(ctx context.Context, assignmentID string) (int64, error)
+}
+
+type AssignmentProvider interface {
+	Assignment(ctx context.Context, assignmentID string) (models.Assignment, error)
+}
+
+// New returns a new instance of ActivityService.
+func New(
+	log *slog.Logger,
+	activitySaver ActivitySaver,
+	activityProvider ActivityProvider,
+	assignmentProvider AssignmentProvider,
+) *ActivityService {
+	return &ActivityService{
+		log:                log,
+		activitySaver:      activitySaver,
+		activityProvider:   activityProvider,
+		assignmentProvider: assignmentProvider,
+	}
+}
+
+// WatchAssignment calls send for every activity of the students on the assignment
+// created by the teacher until ctx is done or send fails.
+//
+// If afterID is set, the activities following it are sent first, so the watcher
+// reconnecting with the id of the last activity it has seen misses none.
+// If the activity is no longer kept, it returns ErrActivityExpired.
+// Otherwise only the activities occurring after the call are sent.
+//
+// The activities are read only as fast as send returns, so the slow watcher
+// lags behind instead of buffering them. While the watcher lags, the autosaves
+// followed by the newer activity of the same submission are skipped.
+// If the assignment is anonymous, the students are replaced with their pseudonyms.
+func (s *ActivityService) WatchAssignment(
+	ctx context.Context,
+	assignmentID string,
+	teacherID int64,
+	afterID int64,
+	send func(models.Activity) error,
+) error {
+	const op = "services.activity.WatchAssignment"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
+	)
+
+	assignment, err := s.assignmentProvider.Assignment(ctx, assignmentID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAssignmentNotFound) {
+			log.Warn("assignment not found", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+		}
+
+		log.Error("failed to get assignment", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if assignment.CreatorID != teacherID {
+		log.Warn("assignment belongs to another teacher")
+
+		return fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	}
+
+	if assignment.Deleted() {
+		log.Warn("assignment is in the trash")
+
+		return fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+	}
+
+	if afterID > 0 {
+		ok, err := s.activityProvider.HasActivity(ctx, assignmentID, afterID)
+		if err != nil {
+			log.Error("failed to check activity", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if !ok {
+			log.Warn("activity is no longer kept", slog.Int64("after_id", afterID))
+
+			return fmt.Errorf("%s: %w", op, ErrActivityExpired)
+		}
+	} else {
+		afterID, err = s.activityProvider.LastActivityID(ctx, assignmentID)
+		if err != nil {
+			log.Error("failed to get last activity", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	log.Debug("watching assignment", slog.Int64("after_id", afterID))
+
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	for {
+		activities, err := s.activityProvider.Activities(ctx, assignmentID, afterID, watchBatchSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			log.Error("failed to get activities", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		lagging := len(activities) == watchBatchSize
+		if len(activities) > 0 {
+			afterID = activities[len(activities)-1].ID
+		}
+
+		if lagging {
+			activities = coalesce(activities)
+		}
+
+		for _, activity := range activities {
+			if assignment.Anonymized() {
+				activity.StudentPseudonym = pseudonym.Of(assignment.AnonymityKey, activity.StudentID)
+				activity.StudentID = 0
+			}
+
+			if err := send(activity); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		if lagging {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// coalesce drops the autosaves followed by the newer activity of the same submission.
+func coalesce(activities []models.Activity) []models.Activity {
+	latest := make(map[string]int, len(activities))
+	for i, activity := range activities {
+		if activity.SubmissionID != "" {
+			latest[activity.SubmissionID] = i
+		}
+	}
+
+	res := activities[:0]
+	for i, activity := range activities {
+		if activity.Kind == models.ActivityAutosaved && latest[activity.SubmissionID] != i {
+			continue
+		}
+
+		res = append(res, activity)
+	}
+
+	return res
+}
+
+// RecordTimeLimits records the due dates and the cutoff dates which have passed
+// while the students have not submitted their work.
+// It is run periodically by the scheduler.
+func (s *ActivityService) RecordTimeLimits(ctx context.Context) error {
+	const op = "services.activity.RecordTimeLimits"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	now := time.Now().UTC()
+
+	recorded, err := s.activitySaver.RecordTimeLimits(ctx, now.Add(-timeLimitLookback), now)
+	if err != nil {
+		log.Error("failed to record time limits", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if recorded > 0 {
+		log.Debug("time limits recorded", slog.Int64("count", recorded))
+	}
+
+	return nil
+}
+
+// PurgeActivity permanently deletes the activities which occurred longer than retention ago.
+// It is run periodically by the scheduler.
+func (s *ActivityService) PurgeActivity(ctx context.Context, retention time.Duration) error {
+	const op = "services.activity.PurgeActivity"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	before := time.Now().UTC().Add(-retention)
+
+	var total int64
+	for {
+		purged, err := s.activitySaver.PurgeActivity(ctx, before, purgeBatchSize)
+		if err != nil {
+			log.Error("failed to purge activity", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		total += purged
+
+		if purged < purgeBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Info("activity purged", slog.Int64("count", total))
+	}
+
+	return nil
+}
@@ -0,0 +1,290 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/lib/pseudonym"
+	"tasks/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errStop stops the watcher once the test has seen the activities it expects.
+var errStop = errors.New("stop watching")
+
+// fakeStorage keeps the activities of the assignment in the order of their ids.
+// The pending activities are committed on the next read, as if they were
+// written while the watcher was waiting.
+type fakeStorage struct {
+	assignment models.Assignment
+	activities []models.Activity
+	pending    []models.Activity
+	reads      []int64
+}
+
+func (f *fakeStorage) Assignment(_ context.Context, assignmentID string) (models.Assignment, error) {
+	if assignmentID != f.assignment.ID {
+		return models.Assignment{}, storage.ErrAssignmentNotFound
+	}
+	return f.assignment, nil
+}
+
+func (f *fakeStorage) Activities(
+	_ context.Context,
+	_ string,
+	afterID int64,
+	limit int,
+) ([]models.Activity, error) {
+	f.activities = append(f.activities, f.pending...)
+	f.pending = nil
+	f.reads = append(f.reads, afterID)
+
+	var res []models.Activity
+	for _, activity := range f.activities {
+		if activity.ID > afterID && len(res) < limit {
+			res = append(res, activity)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeStorage) HasActivity(_ context.Context, _ string, activityID int64) (bool, error) {
+	for _, activity := range f.activities {
+		if activity.ID == activityID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeStorage) LastActivityID(_ context.Context, _ string) (int64, error) {
+	if len(f.activities) == 0 {
+		return 0, nil
+	}
+	return f.activities[len(f.activities)-1].ID, nil
+}
+
+func (f *fakeStorage) RecordTimeLimits(_ context.Context, _ time.Time, _ time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeStorage) PurgeActivity(_ context.Context, _ time.Time, _ int) (int64, error) {
+	return 0, nil
+}
+
+func newTestService(activities ...models.Activity) (*ActivityService, *fakeStorage) {
+	st := &fakeStorage{
+		assignment: models.Assignment{ID: "assignment", CreatorID: 1},
+		activities: activities,
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return New(log, st, st, st), st
+}
+
+func autosaved(id int64, submissionID string) models.Activity {
+	return models.Activity{ID: id, SubmissionID: submissionID, StudentID: 10, Kind: models.ActivityAutosaved}
+}
+
+func submitted(id int64, submissionID string) models.Activity {
+	return models.Activity{ID: id, SubmissionID: submissionID, StudentID: 10, Kind: models.ActivitySubmitted}
+}
+
+// watch watches the assignment until the activity with the last id is sent.
+func watch(
+	t *testing.T,
+	s *ActivityService,
+	teacherID int64,
+	afterID int64,
+	lastID int64,
+) ([]models.Activity, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var sent []models.Activity
+	err := s.WatchAssignment(ctx, "assignment", teacherID, afterID, func(activity models.Activity) error {
+		sent = append(sent, activity)
+		if activity.ID == lastID {
+			return errStop
+		}
+		return nil
+	})
+	if errors.Is(err, errStop) {
+		err = nil
+	}
+
+	return sent, err
+}
+
+func ids(activities []models.Activity) []int64 {
+	res := make([]int64, 0, len(activities))
+	for _, activity := range activities {
+		res = append(res, activity.ID)
+	}
+	return res
+}
+
+func TestWatchAssignment_Resume(t *testing.T) {
+	s, _ := newTestService(
+		models.Activity{ID: 1, Kind: models.ActivityStarted},
+		autosaved(2, "s-1"),
+		submitted(3, "s-1"),
+	)
+
+	sent, err := watch(t, s, 1, 1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 3}, ids(sent), "activities after the last seen one are sent")
+}
+
+func TestWatchAssignment_FromNow(t *testing.T) {
+	s, st := newTestService(
+		models.Activity{ID: 1, Kind: models.ActivityStarted},
+		autosaved(2, "s-1"),
+	)
+	st.pending = []models.Activity{submitted(3, "s-1")}
+
+	sent, err := watch(t, s, 1, 0, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3}, ids(sent), "only activities after the call are sent")
+	assert.Equal(t, []int64{2}, st.reads)
+}
+
+func TestWatchAssignment_Lagging(t *testing.T) {
+	activities := []models.Activity{{ID: 1, Kind: models.ActivityStarted}}
+	for id := int64(2); id <= watchBatchSize; id++ {
+		activities = append(activities, autosaved(id, "s-1"))
+	}
+	activities = append(activities,
+		submitted(watchBatchSize+1, "s-1"),
+		autosaved(watchBatchSize+2, "s-2"),
+	)
+
+	s, st := newTestService(activities...)
+
+	sent, err := watch(t, s, 1, 1, watchBatchSize+2)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{watchBatchSize + 1, watchBatchSize + 2}, ids(sent),
+		"autosaves followed by the submission are dropped while lagging")
+	assert.Equal(t, []int64{1, watchBatchSize + 1}, st.reads,
+		"the next batch follows the last read activity")
+}
+
+func TestWatchAssignment_NotLagging(t *testing.T) {
+	s, _ := newTestService(
+		models.Activity{ID: 1, Kind: models.ActivityStarted},
+		autosaved(2, "s-1"),
+		autosaved(3, "s-1"),
+		submitted(4, "s-1"),
+	)
+
+	sent, err := watch(t, s, 1, 1, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 3, 4}, ids(sent), "every autosave is sent while the watcher keeps up")
+}
+
+func TestWatchAssignment_Anonymous(t *testing.T) {
+	s, st := newTestService(
+		models.Activity{ID: 1, Kind: models.ActivityStarted},
+		submitted(2, "s-1"),
+	)
+	st.assignment.Anonymous = true
+	st.assignment.AnonymityKey = []byte("key")
+
+	sent, err := watch(t, s, 1, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, sent, 1)
+	assert.Zero(t, sent[0].StudentID)
+	assert.Equal(t, pseudonym.Of([]byte("key"), 10), sent[0].StudentPseudonym)
+}
+
+func TestWatchAssignment_Errors(t *testing.T) {
+	t.Run("expired activity", func(t *testing.T) {
+		s, _ := newTestService(models.Activity{ID: 5, Kind: models.ActivityStarted})
+
+		_, err := watch(t, s, 1, 4, 5)
+		assert.ErrorIs(t, err, ErrActivityExpired)
+	})
+
+	t.Run("assignment of another teacher", func(t *testing.T) {
+		s, _ := newTestService()
+
+		_, err := watch(t, s, 2, 0, 1)
+		assert.ErrorIs(t, err, ErrAccessDenied)
+	})
+
+	t.Run("deleted assignment", func(t *testing.T) {
+		s, st := newTestService()
+		st.assignment.DeletedAt = time.Now()
+
+		_, err := watch(t, s, 1, 0, 1)
+		assert.ErrorIs(t, err, ErrAssignmentNotFound)
+	})
+
+	t.Run("failed send", func(t *testing.T) {
+		s, _ := newTestService(models.Activity{ID: 1}, submitted(2, "s-1"))
+		errSend := errors.New("stream closed")
+
+		err := s.WatchAssignment(context.Background(), "assignment", 1, 1, func(models.Activity) error {
+			return errSend
+		})
+		assert.ErrorIs(t, err, errSend)
+	})
+}
+
+func TestCoalesce(t *testing.T) {
+	tests := []struct {
+		name       string
+		activities []models.Activity
+		want       []int64
+	}{
+		{
+			name:       "autosaves followed by the autosave",
+			activities: []models.Activity{autosaved(1, "s-1"), autosaved(2, "s-1"), autosaved(3, "s-1")},
+			want:       []int64{3},
+		},
+		{
+			name:       "autosaves followed by the submission",
+			activities: []models.Activity{autosaved(1, "s-1"), submitted(2, "s-1")},
+			want:       []int64{2},
+		},
+		{
+			name: "other submissions are kept",
+			activities: []models.Activity{
+				autosaved(1, "s-1"),
+				autosaved(2, "s-2"),
+				autosaved(3, "s-1"),
+			},
+			want: []int64{2, 3},
+		},
+		{
+			name: "other kinds are kept",
+			activities: []models.Activity{
+				{ID: 1, SubmissionID: "s-1", Kind: models.ActivityStarted},
+				autosaved(2, "s-1"),
+				submitted(3, "s-1"),
+				{ID: 4, StudentAssignmentID: "sa-2", Kind: models.ActivityDuePassed},
+			},
+			want: []int64{1, 3, 4},
+		},
+		{
+			name: "none",
+			want: []int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ids(coalesce(tt.activities)))
+		})
+	}
+}
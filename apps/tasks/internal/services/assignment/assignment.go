@@ -5,46 +5,132 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
+	"strconv"
+	"time"
 
 	"tasks/internal/domain/models"
+	"tasks/internal/lib/pseudonym"
 	"tasks/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// publishBatchSize limits the number of assignments published in one transaction.
+	publishBatchSize = 100
+	// purgeBatchSize limits the number of assignments purged in one statement.
+	purgeBatchSize = 100
 )
 
 type AssignmentService struct {
 	log                *slog.Logger
 	assignmentSaver    AssignmentSaver
 	assignmentProvider AssignmentProvider
+	templateSaver      TemplateSaver
+	templateProvider   TemplateProvider
+	widgetProvider     WidgetProvider
 }
 
 type AssignmentSaver interface {
 	SaveAssignment(
 		ctx context.Context,
-		assignment *models.Assignment,
+		assignment models.Assignment,
+	) (string, error)
+	UpdateAssignment(
+		ctx context.Context,
+		assignmentID string,
+		update models.AssignmentUpdate,
+	) error
+	PublishDue(
+		ctx context.Context,
+		now time.Time,
+		limit int,
+	) ([]string, error)
+	DeleteAssignment(ctx context.Context, assignmentID string) error
+	RestoreAssignment(ctx context.Context, assignmentID string) error
+	PurgeAssignments(
+		ctx context.Context,
+		before time.Time,
+		limit int,
 	) (int64, error)
-	UpdateAssignment() error
+	RevealIdentities(ctx context.Context, reveal models.IdentityReveal) error
+	ExtendStudentAssignment(ctx context.Context, extension models.Extension) error
 }
 
 type AssignmentProvider interface {
-	AssignmentByID(ctx context.Context, id int64) (*models.Assignment, error)
-	AssignmentsByTeacherID(ctx context.Context, teacherID int64) ([]*models.Assignment, error)
+	Assignment(ctx context.Context, assignmentID string) (models.Assignment, error)
+	StudentAssignments(
+		ctx context.Context,
+		studentID int64,
+		filter models.Filter,
+	) ([]models.StudentAssignment, error)
+	StudentAssignment(
+		ctx context.Context,
+		studentAssignmentID string,
+		studentID int64,
+	) (models.StudentAssignment, error)
+	DeletedAssignments(
+		ctx context.Context,
+		creatorID int64,
+		filter models.Filter,
+	) ([]models.TrashItem, error)
+	IdentityReveals(ctx context.Context, assignmentID string) ([]models.IdentityReveal, error)
 }
 
+type TemplateSaver interface {
+	SaveTemplate(ctx context.Context, template models.Template) (string, error)
+}
+
+type TemplateProvider interface {
+	Template(ctx context.Context, templateID string) (models.Template, error)
+}
+
+type WidgetProvider interface {
+	Widget(ctx context.Context, widgetType string, version int) (models.Widget, error)
+}
+
+var (
+	ErrAssignmentNotFound = storage.ErrAssignmentNotFound
+	ErrTemplateNotFound   = storage.ErrTemplateNotFound
+	ErrWidgetNotFound     = storage.ErrWidgetNotFound
+	ErrAccessDenied       = errors.New("access to assignment denied")
+	ErrAlreadyPublished   = errors.New("assignment is already published")
+	ErrNotAnonymous       = errors.New("assignment is not anonymous")
+	ErrNotPublished       = errors.New("assignment is not published")
+	ErrStudentNotFound    = errors.New("student not found")
+)
+
+// New returns a new instance of AssignmentService.
 func New(
 	log *slog.Logger,
-	assignmentProvider AssignmentProvider,
 	assignmentSaver AssignmentSaver,
+	assignmentProvider AssignmentProvider,
+	templateSaver TemplateSaver,
+	templateProvider TemplateProvider,
+	widgetProvider WidgetProvider,
 ) *AssignmentService {
 	return &AssignmentService{
 		log:                log,
-		assignmentProvider: assignmentProvider,
 		assignmentSaver:    assignmentSaver,
+		assignmentProvider: assignmentProvider,
+		templateSaver:      templateSaver,
+		templateProvider:   templateProvider,
+		widgetProvider:     widgetProvider,
 	}
 }
 
+// CreateAssignment gives the template to the students.
+//
+// If assignment has no template, the private template is created from its widget.
+// If publish time is not set, the assignment is published immediately,
+// otherwise it stays hidden from the students until the scheduler publishes it.
+// If the peer review is enabled, the reviewers are assigned after the due date.
 func (s *AssignmentService) CreateAssignment(
 	ctx context.Context,
-	assignment *models.Assignment,
-) (int64, error) {
+	assignment models.Assignment,
+	schoolID int64,
+) (string, error) {
 	const op = "services.assignment.CreateAssignment"
 
 	log := s.log.With(
@@ -53,45 +139,635 @@ func (s *AssignmentService) CreateAssignment(
 
 	log.Debug("creating assignment")
 
-	assignmentID, err := s.assignmentSaver.SaveAssignment(
-		ctx,
-		assignment,
-	)
+	if assignment.TemplateID != "" {
+		template, err := s.templateProvider.Template(ctx, assignment.TemplateID)
+		if err != nil {
+			if errors.Is(err, storage.ErrTemplateNotFound) {
+				log.Warn("template not found", slog.Any("error", err))
+
+				return "", fmt.Errorf("%s: %w", op, ErrTemplateNotFound)
+			}
+
+			log.Error("failed to get template", slog.Any("error", err))
+
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		if !template.VisibleTo(assignment.CreatorID, schoolID) {
+			log.Warn("template is not visible to user")
+
+			return "", fmt.Errorf("%s: %w", op, ErrTemplateNotFound)
+		}
+
+		if assignment.Title == "" {
+			assignment.Title = template.Title
+		}
+	} else {
+		widget, err := s.widgetProvider.Widget(ctx, assignment.Widget.Type, assignment.Widget.Version)
+		if err != nil {
+			if errors.Is(err, storage.ErrWidgetNotFound) {
+				log.Warn("widget not found", slog.Any("error", err))
+
+				return "", fmt.Errorf("%s: %w", op, ErrWidgetNotFound)
+			}
+
+			log.Error("failed to get widget", slog.Any("error", err))
+
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		widget.Config = assignment.Widget.Config
+
+		templateID, err := s.templateSaver.SaveTemplate(ctx, models.Template{
+			CreatorID:  assignment.CreatorID,
+			SchoolID:   schoolID,
+			Title:      assignment.Title,
+			Widget:     widget,
+			Visibility: models.VisibilityPrivate,
+		})
+		if err != nil {
+			log.Error("failed to save template", slog.Any("error", err))
+
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		assignment.TemplateID = templateID
+	}
+
+	assignment.SchoolID = schoolID
+
+	if assignment.PublishAt.IsZero() {
+		assignment.PublishAt = time.Now().UTC()
+	}
+
+	if assignment.CutoffDate.IsZero() {
+		assignment.CutoffDate = assignment.DueDate
+	}
+
+	if assignment.Anonymous {
+		key, err := pseudonym.NewKey()
+		if err != nil {
+			log.Error("failed to generate anonymity key", slog.Any("error", err))
+
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		assignment.AnonymityKey = key
+	}
+
+	// Criteria are referenced by the scores of the peer reviews.
+	for i := range assignment.PeerReview.Criteria {
+		if assignment.PeerReview.Criteria[i].ID == "" {
+			assignment.PeerReview.Criteria[i].ID = uuid.NewString()
+		}
+	}
+
+	assignmentID, err := s.assignmentSaver.SaveAssignment(ctx, assignment)
 	if err != nil {
 		if errors.Is(err, storage.ErrAssignmentAlreadyExists) {
 			log.Warn("assignment already exists")
 
-			return 0, fmt.Errorf("%s: %w", op, storage.ErrAssignmentAlreadyExists)
+			return "", fmt.Errorf("%s: %w", op, storage.ErrAssignmentAlreadyExists)
 		}
 
-		log.Error("failed to save assignment")
+		log.Error("failed to save assignment", slog.Any("error", err))
 
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	log.Debug("assignment created")
+	log.Info(
+		"assignment created",
+		slog.String("assignment_id", assignmentID),
+		slog.Time("publish_at", assignment.PublishAt),
+		slog.Bool("anonymous", assignment.Anonymous),
+	)
 
 	return assignmentID, nil
 }
 
+// UpdateAssignment changes the assignment of the teacher.
+// Publish time can be changed only until the assignment is published.
+func (s *AssignmentService) UpdateAssignment(
+	ctx context.Context,
+	assignmentID string,
+	userID int64,
+	update models.AssignmentUpdate,
+) error {
+	const op = "services.assignment.UpdateAssignment"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
+	)
+
+	log.Debug("updating assignment")
+
+	assignment, err := s.Assignment(ctx, assignmentID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if update.PublishAt != nil && assignment.Published() {
+		log.Warn("assignment is already published")
+
+		return fmt.Errorf("%s: %w", op, ErrAlreadyPublished)
+	}
+
+	if err := s.assignmentSaver.UpdateAssignment(ctx, assignmentID, update); err != nil {
+		log.Error("failed to update assignment", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("assignment updated")
+
+	return nil
+}
+
+// ExtendStudentAssignment moves the dates of the published assignment of the teacher
+// for a single student. The student of the anonymous assignment is given by the pseudonym
+// until the identities are revealed. If the cutoff date is not set, it is the due date.
+// The later updates of the assignment dates do not take the extension back.
+func (s *AssignmentService) ExtendStudentAssignment(
+	ctx context.Context,
+	assignmentID string,
+	userID int64,
+	student string,
+	dueDate time.Time,
+	cutoffDate time.Time,
+) error {
+	const op = "services.assignment.ExtendStudentAssignment"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
+	)
+
+	log.Debug("extending student assignment")
+
+	assignment, err := s.teacherAssignment(ctx, assignmentID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !assignment.Published() {
+		log.Warn("assignment is not published")
+
+		return fmt.Errorf("%s: %w", op, ErrNotPublished)
+	}
+
+	studentID, ok := studentOf(assignment, student)
+	if !ok {
+		log.Warn("student not found")
+
+		return fmt.Errorf("%s: %w", op, ErrStudentNotFound)
+	}
+
+	if cutoffDate.IsZero() {
+		cutoffDate = dueDate
+	}
+
+	extension := models.Extension{
+		AssignmentID: assignmentID,
+		StudentID:    studentID,
+		DueDate:      dueDate,
+		CutoffDate:   cutoffDate,
+		ExtendedAt:   time.Now().UTC(),
+	}
+
+	if err := s.assignmentSaver.ExtendStudentAssignment(ctx, extension); err != nil {
+		if errors.Is(err, storage.ErrAssignmentNotFound) {
+			log.Warn("student assignment not found", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, ErrStudentNotFound)
+		}
+
+		log.Error("failed to extend student assignment", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("student assignment extended", slog.Time("due_date", dueDate))
+
+	return nil
+}
+
+// studentOf returns the student of the assignment given by the id,
+// or by the pseudonym while the students are hidden from the teacher.
+func studentOf(assignment models.Assignment, student string) (int64, bool) {
+	for _, studentID := range assignment.StudentIDs {
+		if assignment.Anonymized() {
+			if pseudonym.Of(assignment.AnonymityKey, studentID) == student {
+				return studentID, true
+			}
+
+			continue
+		}
+
+		if strconv.FormatInt(studentID, 10) == student {
+			return studentID, true
+		}
+	}
+
+	return 0, false
+}
+
+// Assignment returns the assignment created by the teacher.
+// The students of the anonymous assignment are replaced with their pseudonyms
+// until the identities are revealed.
 func (s *AssignmentService) Assignment(
 	ctx context.Context,
-	assignmentID int64,
+	assignmentID string,
+	userID int64,
 ) (models.Assignment, error) {
 	const op = "services.assignment.Assignment"
 
 	log := s.log.With(
 		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
 	)
 
 	log.Debug("fetching assignment")
 
-	assignment, err := s.assignmentProvider.AssignmentByID(ctx, assignmentID)
+	assignment, err := s.teacherAssignment(ctx, assignmentID, userID)
 	if err != nil {
 		return models.Assignment{}, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if assignment.Anonymized() {
+		assignment.StudentPseudonyms = make([]string, len(assignment.StudentIDs))
+		for i, studentID := range assignment.StudentIDs {
+			assignment.StudentPseudonyms[i] = pseudonym.Of(assignment.AnonymityKey, studentID)
+		}
+
+		// The order of the ids would give the students away.
+		sort.Strings(assignment.StudentPseudonyms)
+		assignment.StudentIDs = nil
+	}
+
+	assignment.AnonymityKey = nil
+
 	log.Debug("assignment fetched")
 
-	return *assignment, nil
+	return assignment, nil
+}
+
+// RevealIdentities reveals the students of the anonymous assignment to the teacher.
+// The reveal cannot be undone and is recorded in the audit log with its reason,
+// repeated reveals are recorded as well.
+func (s *AssignmentService) RevealIdentities(
+	ctx context.Context,
+	assignmentID string,
+	userID int64,
+	reason string,
+) error {
+	const op = "services.assignment.RevealIdentities"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
+	)
+
+	log.Debug("revealing identities")
+
+	assignment, err := s.Assignment(ctx, assignmentID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !assignment.Anonymous {
+		log.Warn("assignment is not anonymous")
+
+		return fmt.Errorf("%s: %w", op, ErrNotAnonymous)
+	}
+
+	reveal := models.IdentityReveal{
+		AssignmentID: assignmentID,
+		TeacherID:    userID,
+		Reason:       reason,
+		RevealedAt:   time.Now().UTC(),
+	}
+
+	if err := s.assignmentSaver.RevealIdentities(ctx, reveal); err != nil {
+		if errors.Is(err, storage.ErrAssignmentNotFound) {
+			log.Warn("assignment not found", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+		}
+
+		log.Error("failed to reveal identities", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info(
+		"identities revealed",
+		slog.Int64("teacher_id", userID),
+		slog.String("reason", reason),
+	)
+
+	return nil
+}
+
+// IdentityReveals returns the audit log of the reveals of the anonymous assignment.
+func (s *AssignmentService) IdentityReveals(
+	ctx context.Context,
+	assignmentID string,
+	userID int64,
+) ([]models.IdentityReveal, error) {
+	const op = "services.assignment.IdentityReveals"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
+	)
+
+	log.Debug("fetching identity reveals")
+
+	if _, err := s.Assignment(ctx, assignmentID, userID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	reveals, err := s.assignmentProvider.IdentityReveals(ctx, assignmentID)
+	if err != nil {
+		log.Error("failed to get identity reveals", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return reveals, nil
+}
+
+// DeleteAssignment moves the assignment of the teacher to the trash.
+// The work of the students is kept until the assignment is purged,
+// so the assignment can be restored with RestoreAssignment.
+func (s *AssignmentService) DeleteAssignment(
+	ctx context.Context,
+	assignmentID string,
+	userID int64,
+) error {
+	const op = "services.assignment.DeleteAssignment"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
+	)
+
+	log.Debug("deleting assignment")
+
+	if _, err := s.Assignment(ctx, assignmentID, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.assignmentSaver.DeleteAssignment(ctx, assignmentID); err != nil {
+		if errors.Is(err, storage.ErrAssignmentNotFound) {
+			log.Warn("assignment not found", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+		}
+
+		log.Error("failed to delete assignment", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("assignment moved to trash")
+
+	return nil
+}
+
+// RestoreAssignment moves the assignment of the teacher out of the trash.
+// If the assignment is not in the trash, returns ErrAssignmentNotFound.
+func (s *AssignmentService) RestoreAssignment(
+	ctx context.Context,
+	assignmentID string,
+	userID int64,
+) error {
+	const op = "services.assignment.RestoreAssignment"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
+	)
+
+	log.Debug("restoring assignment")
+
+	assignment, err := s.assignmentProvider.Assignment(ctx, assignmentID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAssignmentNotFound) {
+			log.Warn("assignment not found", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+		}
+
+		log.Error("failed to get assignment", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if assignment.CreatorID != userID {
+		log.Warn("assignment belongs to another teacher")
+
+		return fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	}
+
+	if err := s.assignmentSaver.RestoreAssignment(ctx, assignmentID); err != nil {
+		if errors.Is(err, storage.ErrAssignmentNotFound) {
+			log.Warn("assignment is not in the trash", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+		}
+
+		log.Error("failed to restore assignment", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("assignment restored")
+
+	return nil
+}
+
+// DeletedAssignments returns the page of assignments of the teacher in the trash.
+func (s *AssignmentService) DeletedAssignments(
+	ctx context.Context,
+	userID int64,
+	filter models.Filter,
+) ([]models.TrashItem, error) {
+	const op = "services.assignment.DeletedAssignments"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Debug("fetching deleted assignments")
+
+	items, err := s.assignmentProvider.DeletedAssignments(ctx, userID, filter)
+	if err != nil {
+		log.Error("failed to get deleted assignments", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return items, nil
+}
+
+// PurgeDeleted permanently deletes the assignments which are in the trash
+// for longer than the retention period, together with the work of the students.
+// It is run periodically by the scheduler.
+func (s *AssignmentService) PurgeDeleted(ctx context.Context, retention time.Duration) error {
+	const op = "services.assignment.PurgeDeleted"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	before := time.Now().UTC().Add(-retention)
+
+	var total int64
+	for {
+		purged, err := s.assignmentSaver.PurgeAssignments(ctx, before, purgeBatchSize)
+		if err != nil {
+			log.Error("failed to purge assignments", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		total += purged
+
+		if purged < purgeBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Info("assignments purged", slog.Int64("count", total))
+	}
+
+	return nil
+}
+
+// StudentAssignments returns the page of published assignments of the student.
+func (s *AssignmentService) StudentAssignments(
+	ctx context.Context,
+	studentID int64,
+	filter models.Filter,
+) ([]models.StudentAssignment, error) {
+	const op = "services.assignment.StudentAssignments"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Debug("fetching student assignments")
+
+	assignments, err := s.assignmentProvider.StudentAssignments(ctx, studentID, filter)
+	if err != nil {
+		log.Error("failed to get student assignments", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return assignments, nil
+}
+
+// StudentAssignment returns the published assignment of the student.
+// Assignments which are not published yet are reported as not found.
+func (s *AssignmentService) StudentAssignment(
+	ctx context.Context,
+	studentAssignmentID string,
+	studentID int64,
+) (models.StudentAssignment, error) {
+	const op = "services.assignment.StudentAssignment"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("student_assignment_id", studentAssignmentID),
+	)
+
+	log.Debug("fetching student assignment")
+
+	assignment, err := s.assignmentProvider.StudentAssignment(ctx, studentAssignmentID, studentID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAssignmentNotFound) {
+			log.Warn("student assignment not found", slog.Any("error", err))
+
+			return models.StudentAssignment{}, fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+		}
+
+		log.Error("failed to get student assignment", slog.Any("error", err))
+
+		return models.StudentAssignment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return assignment, nil
+}
+
+// PublishScheduled publishes all the assignments whose publish time has come.
+// It is run periodically by the scheduler, and since the state is kept in the
+// database, assignments missed while the service was down are published on start.
+func (s *AssignmentService) PublishScheduled(ctx context.Context) error {
+	const op = "services.assignment.PublishScheduled"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	for {
+		ids, err := s.assignmentSaver.PublishDue(ctx, time.Now().UTC(), publishBatchSize)
+		if err != nil {
+			log.Error("failed to publish assignments", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, id := range ids {
+			log.Info("assignment published", slog.String("assignment_id", id))
+		}
+
+		if len(ids) < publishBatchSize {
+			return nil
+		}
+	}
+}
+
+// teacherAssignment returns the assignment created by the teacher
+// with its students and the anonymity key.
+func (s *AssignmentService) teacherAssignment(
+	ctx context.Context,
+	assignmentID string,
+	userID int64,
+) (models.Assignment, error) {
+	const op = "services.assignment.teacherAssignment"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
+	)
+
+	assignment, err := s.assignmentProvider.Assignment(ctx, assignmentID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAssignmentNotFound) {
+			log.Warn("assignment not found", slog.Any("error", err))
+
+			return models.Assignment{}, fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+		}
+
+		log.Error("failed to get assignment", slog.Any("error", err))
+
+		return models.Assignment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if assignment.CreatorID != userID {
+		log.Warn("assignment belongs to another teacher")
+
+		return models.Assignment{}, fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	}
+
+	if assignment.Deleted() {
+		log.Warn("assignment is in the trash")
+
+		return models.Assignment{}, fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+	}
+
+	return assignment, nil
 }
@@ -0,0 +1,350 @@
+package assignment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"testing"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/lib/pseudonym"
+	"tasks/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStorage keeps the assignments in memory
+// and publishes them the way the postgres storage does.
+// The methods the tests do not call are left to the embedded interfaces.
+type fakeStorage struct {
+	AssignmentSaver
+	AssignmentProvider
+	TemplateProvider
+
+	assignments map[string]models.Assignment
+	publishErr  error
+	batches     []int
+	extensions  []models.Extension
+}
+
+func (f *fakeStorage) SaveAssignment(_ context.Context, assignment models.Assignment) (string, error) {
+	if assignment.ID == "" {
+		assignment.ID = fmt.Sprintf("assignment-%d", len(f.assignments)+1)
+	}
+	f.assignments[assignment.ID] = assignment
+	return assignment.ID, nil
+}
+
+func (f *fakeStorage) UpdateAssignment(
+	_ context.Context,
+	assignmentID string,
+	update models.AssignmentUpdate,
+) error {
+	assignment, ok := f.assignments[assignmentID]
+	if !ok {
+		return storage.ErrAssignmentNotFound
+	}
+	if update.PublishAt != nil {
+		assignment.PublishAt = *update.PublishAt
+	}
+	if update.DueDate != nil {
+		assignment.DueDate = *update.DueDate
+	}
+	f.assignments[assignmentID] = assignment
+	return nil
+}
+
+func (f *fakeStorage) PublishDue(_ context.Context, now time.Time, limit int) ([]string, error) {
+	if f.publishErr != nil {
+		return nil, f.publishErr
+	}
+
+	var due []models.Assignment
+	for _, assignment := range f.assignments {
+		if !assignment.Published() && !assignment.Deleted() && !assignment.PublishAt.After(now) {
+			due = append(due, assignment)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].PublishAt.Before(due[j].PublishAt)
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	ids := make([]string, 0, len(due))
+	for _, assignment := range due {
+		assignment.PublishedAt = now
+		f.assignments[assignment.ID] = assignment
+		ids = append(ids, assignment.ID)
+	}
+
+	f.batches = append(f.batches, len(ids))
+
+	return ids, nil
+}
+
+func (f *fakeStorage) published(assignmentID string) bool {
+	assignment := f.assignments[assignmentID]
+	return assignment.Published()
+}
+
+func (f *fakeStorage) ExtendStudentAssignment(_ context.Context, extension models.Extension) error {
+	f.extensions = append(f.extensions, extension)
+	return nil
+}
+
+func (f *fakeStorage) Assignment(_ context.Context, assignmentID string) (models.Assignment, error) {
+	assignment, ok := f.assignments[assignmentID]
+	if !ok {
+		return models.Assignment{}, storage.ErrAssignmentNotFound
+	}
+	return assignment, nil
+}
+
+func (f *fakeStorage) SaveTemplate(_ context.Context, _ models.Template) (string, error) {
+	return "template", nil
+}
+
+func (f *fakeStorage) Widget(_ context.Context, widgetType string, version int) (models.Widget, error) {
+	return models.Widget{Type: widgetType, Version: version}, nil
+}
+
+func newTestService(assignments ...models.Assignment) (*AssignmentService, *fakeStorage) {
+	st := &fakeStorage{assignments: make(map[string]models.Assignment)}
+	for _, assignment := range assignments {
+		st.assignments[assignment.ID] = assignment
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return New(log, st, st, st, st, st), st
+}
+
+func TestPublishScheduled(t *testing.T) {
+	now := time.Now().UTC()
+
+	s, st := newTestService(
+		models.Assignment{ID: "due", CreatorID: 1, PublishAt: now.Add(-time.Minute)},
+		models.Assignment{ID: "missed", CreatorID: 1, PublishAt: now.Add(-24 * time.Hour)},
+		models.Assignment{ID: "scheduled", CreatorID: 1, PublishAt: now.Add(time.Hour)},
+		models.Assignment{
+			ID:        "deleted",
+			CreatorID: 1,
+			PublishAt: now.Add(-time.Minute),
+			DeletedAt: now,
+		},
+		models.Assignment{
+			ID:          "published",
+			CreatorID:   1,
+			PublishAt:   now.Add(-48 * time.Hour),
+			PublishedAt: now.Add(-48 * time.Hour),
+		},
+	)
+
+	require.NoError(t, s.PublishScheduled(context.Background()))
+
+	assert.True(t, st.published("due"))
+	assert.True(t, st.published("missed"), "assignments missed while down are published")
+	assert.False(t, st.published("scheduled"))
+	assert.False(t, st.published("deleted"))
+	assert.Equal(
+		t,
+		now.Add(-48*time.Hour),
+		st.assignments["published"].PublishedAt,
+		"published assignments are not published again",
+	)
+
+	require.NoError(t, s.PublishScheduled(context.Background()))
+	assert.Equal(t, []int{2, 0}, st.batches)
+}
+
+func TestPublishScheduled_Batches(t *testing.T) {
+	now := time.Now().UTC()
+
+	assignments := make([]models.Assignment, 2*publishBatchSize+1)
+	for i := range assignments {
+		assignments[i] = models.Assignment{
+			ID:        fmt.Sprintf("assignment-%d", i),
+			CreatorID: 1,
+			PublishAt: now.Add(-time.Duration(i+1) * time.Second),
+		}
+	}
+
+	s, st := newTestService(assignments...)
+
+	require.NoError(t, s.PublishScheduled(context.Background()))
+
+	assert.Equal(t, []int{publishBatchSize, publishBatchSize, 1}, st.batches)
+	for id := range st.assignments {
+		assert.True(t, st.published(id), id)
+	}
+}
+
+func TestPublishScheduled_FullLastBatch(t *testing.T) {
+	now := time.Now().UTC()
+
+	assignments := make([]models.Assignment, publishBatchSize)
+	for i := range assignments {
+		assignments[i] = models.Assignment{
+			ID:        fmt.Sprintf("assignment-%d", i),
+			CreatorID: 1,
+			PublishAt: now.Add(-time.Minute),
+		}
+	}
+
+	s, st := newTestService(assignments...)
+
+	require.NoError(t, s.PublishScheduled(context.Background()))
+
+	// the full batch may be followed by more assignments, so one more is requested
+	assert.Equal(t, []int{publishBatchSize, 0}, st.batches)
+}
+
+func TestPublishScheduled_Error(t *testing.T) {
+	s, st := newTestService()
+	st.publishErr = errors.New("connection refused")
+
+	err := s.PublishScheduled(context.Background())
+	assert.ErrorIs(t, err, st.publishErr)
+}
+
+func TestUpdateAssignment_PublishAt(t *testing.T) {
+	now := time.Now().UTC()
+	later := now.Add(time.Hour)
+
+	s, st := newTestService(
+		models.Assignment{ID: "scheduled", CreatorID: 1, PublishAt: now.Add(time.Minute)},
+		models.Assignment{ID: "published", CreatorID: 1, PublishAt: now, PublishedAt: now},
+	)
+
+	ctx := context.Background()
+
+	t.Run("scheduled assignment is rescheduled", func(t *testing.T) {
+		err := s.UpdateAssignment(ctx, "scheduled", 1, models.AssignmentUpdate{PublishAt: &later})
+		require.NoError(t, err)
+		assert.Equal(t, later, st.assignments["scheduled"].PublishAt)
+
+		require.NoError(t, s.PublishScheduled(ctx))
+		assert.False(t, st.published("scheduled"))
+	})
+
+	t.Run("published assignment is not rescheduled", func(t *testing.T) {
+		err := s.UpdateAssignment(ctx, "published", 1, models.AssignmentUpdate{PublishAt: &later})
+		assert.ErrorIs(t, err, ErrAlreadyPublished)
+		assert.Equal(t, now, st.assignments["published"].PublishAt)
+	})
+
+	t.Run("assignment of another teacher", func(t *testing.T) {
+		err := s.UpdateAssignment(ctx, "scheduled", 2, models.AssignmentUpdate{PublishAt: &now})
+		assert.ErrorIs(t, err, ErrAccessDenied)
+	})
+}
+
+func TestCreateAssignment_PublishAt(t *testing.T) {
+	s, st := newTestService()
+
+	ctx := context.Background()
+	widget := models.Widget{Type: "quiz", Version: 1}
+
+	immediateID, err := s.CreateAssignment(ctx, models.Assignment{CreatorID: 1, Widget: widget}, 0)
+	require.NoError(t, err)
+
+	scheduledID, err := s.CreateAssignment(ctx, models.Assignment{
+		CreatorID: 1,
+		Widget:    widget,
+		PublishAt: time.Now().UTC().Add(time.Hour),
+	}, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, s.PublishScheduled(ctx))
+
+	assert.True(t, st.published(immediateID), "assignment without publish time is published at once")
+	assert.False(t, st.published(scheduledID))
+}
+
+func TestExtendStudentAssignment(t *testing.T) {
+	now := time.Now().UTC()
+	dueDate := now.Add(72 * time.Hour)
+
+	newAssignment := func() models.Assignment {
+		return models.Assignment{
+			ID:          "assignment",
+			CreatorID:   1,
+			StudentIDs:  []int64{10, 11},
+			PublishedAt: now.Add(-time.Hour),
+		}
+	}
+
+	t.Run("student", func(t *testing.T) {
+		s, st := newTestService(newAssignment())
+
+		require.NoError(t, s.ExtendStudentAssignment(context.Background(), "assignment", 1, "11", dueDate, time.Time{}))
+
+		require.Len(t, st.extensions, 1)
+		assert.Equal(t, int64(11), st.extensions[0].StudentID)
+		assert.Equal(t, dueDate, st.extensions[0].DueDate)
+		assert.Equal(t, dueDate, st.extensions[0].CutoffDate, "cutoff date defaults to the due date")
+	})
+
+	t.Run("anonymous student", func(t *testing.T) {
+		assignment := newAssignment()
+		assignment.Anonymous = true
+		assignment.AnonymityKey = []byte("key")
+
+		s, st := newTestService(assignment)
+
+		err := s.ExtendStudentAssignment(context.Background(), "assignment", 1, "11", dueDate, time.Time{})
+		assert.ErrorIs(t, err, ErrStudentNotFound, "ids of hidden students are not accepted")
+
+		student := pseudonym.Of([]byte("key"), 11)
+		require.NoError(t, s.ExtendStudentAssignment(context.Background(), "assignment", 1, student, dueDate, time.Time{}))
+
+		require.Len(t, st.extensions, 1)
+		assert.Equal(t, int64(11), st.extensions[0].StudentID)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		unpublished := newAssignment()
+		unpublished.ID = "unpublished"
+		unpublished.PublishedAt = time.Time{}
+
+		s, st := newTestService(newAssignment(), unpublished)
+
+		tests := []struct {
+			name         string
+			assignmentID string
+			userID       int64
+			student      string
+			want         error
+		}{
+			{"another teacher", "assignment", 2, "10", ErrAccessDenied},
+			{"unknown student", "assignment", 1, "12", ErrStudentNotFound},
+			{"unpublished", "unpublished", 1, "10", ErrNotPublished},
+			{"missing", "missing", 1, "10", ErrAssignmentNotFound},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := s.ExtendStudentAssignment(
+					context.Background(),
+					tt.assignmentID,
+					tt.userID,
+					tt.student,
+					dueDate,
+					time.Time{},
+				)
+				assert.ErrorIs(t, err, tt.want)
+			})
+		}
+
+		assert.Empty(t, st.extensions)
+	})
+}
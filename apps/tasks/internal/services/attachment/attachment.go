@@ -0,0 +1,658 @@
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/lib/thumbnail"
+	"tasks/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// sniffLen is the number of bytes used to detect the content type.
+	sniffLen = 512
+	// purgeBatchSize limits the number of orphaned attachments deleted at once.
+	purgeBatchSize = 100
+	maxFilenameLen = 255
+
+	// previewBatchSize is the number of previews claimed by the worker at once.
+	previewBatchSize = 10
+	// previewLease is the time the claimed preview is held by the worker.
+	previewLease = 5 * time.Minute
+	// maxPreviewAttempts is the number of attempts after which the preview fails.
+	maxPreviewAttempts = 3
+
+	thumbnailSize = 256
+	previewSize   = 1280
+)
+
+// allowedTypes are the prefixes of the content types accepted for upload.
+// The type is detected from the content, the one claimed by the client is ignored.
+var allowedTypes = []string{
+	"image/",
+	"audio/",
+	"video/",
+	"application/ogg",
+	"application/pdf",
+	"text/plain",
+}
+
+type AttachmentService struct {
+	log                *slog.Logger
+	attachmentSaver    AttachmentSaver
+	attachmentProvider AttachmentProvider
+	submissionProvider SubmissionProvider
+	reviewProvider     ReviewProvider
+	blobs              storage.BlobStore
+	maxFileSize        int64
+	quota              int64
+	previewBaseURL     string
+}
+
+type AttachmentSaver interface {
+	SaveAttachment(
+		ctx context.Context,
+		attachment models.Attachment,
+		quota int64,
+	) (string, error)
+	DeleteAttachment(ctx context.Context, attachmentID string) error
+	ClaimPreviews(
+		ctx context.Context,
+		now time.Time,
+		lockedUntil time.Time,
+		limit int,
+	) ([]models.Attachment, error)
+	SavePreview(
+		ctx context.Context,
+		attachmentID string,
+		preview models.AttachmentPreview,
+	) error
+}
+
+type AttachmentProvider interface {
+	Attachment(ctx context.Context, attachmentID string) (models.Attachment, error)
+	SubmissionAttachments(ctx context.Context, submissionID string) ([]models.Attachment, error)
+	UsedQuota(ctx context.Context, ownerID int64) (int64, error)
+	OrphanedAttachments(ctx context.Context, limit int) ([]models.Attachment, error)
+}
+
+type SubmissionProvider interface {
+	Submission(ctx context.Context, submissionID string) (models.Submission, error)
+}
+
+type ReviewProvider interface {
+	IsReviewer(ctx context.Context, submissionID string, userID int64) (bool, error)
+}
+
+var (
+	ErrAttachmentNotFound = storage.ErrAttachmentNotFound
+	ErrSubmissionNotFound = storage.ErrSubmissionNotFound
+	ErrQuotaExceeded      = storage.ErrQuotaExceeded
+	ErrNotEditable        = errors.New("submission is not editable")
+	ErrFileTooLarge       = errors.New("file is too large")
+	ErrEmptyFile          = errors.New("file is empty")
+	ErrUnsupportedContent = errors.New("content type is not supported")
+	ErrInvalidOffset      = errors.New("offset is out of range")
+	ErrPreviewNotReady    = errors.New("preview is not ready")
+)
+
+// New returns a new instance of AttachmentService.
+// Files are limited to maxFileSize bytes and attachments of every student to quota bytes.
+// Preview URLs are built as <previewBaseURL>/attachments/<id>?rendition=thumbnail|preview.
+func New(
+	log *slog.Logger,
+	attachmentSaver AttachmentSaver,
+	attachmentProvider AttachmentProvider,
+	submissionProvider SubmissionProvider,
+	reviewProvider ReviewProvider,
+	blobs storage.BlobStore,
+	maxFileSize int64,
+	quota int64,
+	previewBaseURL string,
+) *AttachmentService {
+	return &AttachmentService{
+		log:                log,
+		attachmentSaver:    attachmentSaver,
+		attachmentProvider: attachmentProvider,
+		submissionProvider: submissionProvider,
+		reviewProvider:     reviewProvider,
+		blobs:              blobs,
+		maxFileSize:        maxFileSize,
+		quota:              quota,
+		previewBaseURL:     strings.TrimSuffix(previewBaseURL, "/"),
+	}
+}
+
+// UploadAttachment stores the file read from r as the attachment of the submission.
+//
+// The file is spooled to the temporary file first, so its size and checksum are known
+// before it is put to the blob store, and its content type is detected from the content.
+// The attachment is referenced from the payload as {"$attachment": "<id>"}.
+// Thumbnail and preview of the image are generated later by GeneratePreviews.
+func (s *AttachmentService) UploadAttachment(
+	ctx context.Context,
+	studentID int64,
+	submissionID string,
+	filename string,
+	r io.Reader,
+) (models.Attachment, error) {
+	const op = "services.attachment.UploadAttachment"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
+	)
+
+	log.Debug("uploading attachment")
+
+	submission, err := s.submissionProvider.Submission(ctx, submissionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSubmissionNotFound) {
+			log.Warn("submission not found", slog.Any("error", err))
+
+			return models.Attachment{}, fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+		}
+
+		log.Error("failed to get submission", slog.Any("error", err))
+
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if submission.StudentID != studentID || submission.Deleted() {
+		log.Warn("submission belongs to another student or is deleted")
+
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+	}
+
+	if !submission.Editable() {
+		log.Warn("submission is not editable")
+
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, ErrNotEditable)
+	}
+
+	used, err := s.attachmentProvider.UsedQuota(ctx, studentID)
+	if err != nil {
+		log.Error("failed to get used quota", slog.Any("error", err))
+
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	remaining := s.quota - used
+	if remaining <= 0 {
+		log.Warn("quota exceeded", slog.Int64("used", used))
+
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, ErrQuotaExceeded)
+	}
+
+	tmp, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		log.Error("failed to create temporary file", slog.Any("error", err))
+
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(r, min(s.maxFileSize, remaining)+1))
+	if err != nil {
+		log.Error("failed to receive file", slog.Any("error", err))
+
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch {
+	case size == 0:
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, ErrEmptyFile)
+	case size > s.maxFileSize:
+		log.Warn("file is too large")
+
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, ErrFileTooLarge)
+	case size > remaining:
+		log.Warn("quota exceeded", slog.Int64("used", used), slog.Int64("size", size))
+
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, ErrQuotaExceeded)
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := tmp.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		log.Error("failed to read file", slog.Any("error", err))
+
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	contentType := http.DetectContentType(head[:n])
+	if !allowed(contentType) {
+		log.Warn("content type is not supported", slog.String("content_type", contentType))
+
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, ErrUnsupportedContent)
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id := uuid.NewString()
+	attachment := models.Attachment{
+		ID:           id,
+		SubmissionID: submissionID,
+		OwnerID:      studentID,
+		Filename:     cleanFilename(filename),
+		ContentType:  contentType,
+		Size:         size,
+		Checksum:     hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:   id,
+	}
+
+	if thumbnail.Supported(contentType) {
+		attachment.Preview = &models.AttachmentPreview{Status: models.PreviewPending}
+	}
+
+	if err := s.blobs.Put(ctx, attachment.StorageKey, tmp, size, contentType); err != nil {
+		log.Error("failed to put blob", slog.Any("error", err))
+
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := s.attachmentSaver.SaveAttachment(ctx, attachment, s.quota); err != nil {
+		if err := s.blobs.Delete(ctx, attachment.StorageKey); err != nil {
+			log.Error("failed to delete blob", slog.Any("error", err))
+		}
+
+		if errors.Is(err, storage.ErrQuotaExceeded) {
+			log.Warn("quota exceeded by concurrent upload")
+
+			return models.Attachment{}, fmt.Errorf("%s: %w", op, ErrQuotaExceeded)
+		}
+
+		log.Error("failed to save attachment", slog.Any("error", err))
+
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info(
+		"attachment uploaded",
+		slog.String("attachment_id", id),
+		slog.String("content_type", contentType),
+		slog.Int64("size", size),
+	)
+
+	return attachment, nil
+}
+
+// DownloadAttachment returns the attachment and the content of its rendition
+// starting from the offset. Attachments can be downloaded only by the student,
+// the teacher of the assignment and the peer reviewers of the submission.
+// The caller must close the returned reader.
+func (s *AttachmentService) DownloadAttachment(
+	ctx context.Context,
+	attachmentID string,
+	userID int64,
+	rendition models.Rendition,
+	offset int64,
+) (models.Attachment, io.ReadCloser, error) {
+	const op = "services.attachment.DownloadAttachment"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("attachment_id", attachmentID),
+	)
+
+	log.Debug("downloading attachment")
+
+	attachment, err := s.attachmentProvider.Attachment(ctx, attachmentID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAttachmentNotFound) {
+			log.Warn("attachment not found", slog.Any("error", err))
+
+			return models.Attachment{}, nil, fmt.Errorf("%s: %w", op, ErrAttachmentNotFound)
+		}
+
+		log.Error("failed to get attachment", slog.Any("error", err))
+
+		return models.Attachment{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ok, err := s.accessible(ctx, attachment.SubmissionID, userID, attachment.OwnerID, attachment.TeacherID)
+	if err != nil {
+		log.Error("failed to check access", slog.Any("error", err))
+
+		return models.Attachment{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !ok {
+		log.Warn("attachment is not accessible to user")
+
+		return models.Attachment{}, nil, fmt.Errorf("%s: %w", op, ErrAttachmentNotFound)
+	}
+
+	key := attachment.StorageKey
+	if rendition != models.RenditionOriginal {
+		if attachment.Preview == nil || attachment.Preview.Status != models.PreviewReady {
+			return models.Attachment{}, nil, fmt.Errorf("%s: %w", op, ErrPreviewNotReady)
+		}
+
+		key = attachment.Preview.ThumbnailKey
+		if rendition == models.RenditionPreview {
+			key = attachment.Preview.PreviewKey
+		}
+	}
+
+	if offset < 0 || (rendition == models.RenditionOriginal && offset > attachment.Size) {
+		return models.Attachment{}, nil, fmt.Errorf("%s: %w", op, ErrInvalidOffset)
+	}
+
+	r, err := s.blobs.Open(ctx, key, offset)
+	if err != nil {
+		log.Error("failed to open blob", slog.Any("error", err))
+
+		return models.Attachment{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.setURLs(&attachment)
+
+	// the rendition is the re-encoded image, e.g. the GIF is previewed as PNG,
+	// its size is known only to the blob store
+	if rendition != models.RenditionOriginal {
+		attachment.ContentType = thumbnail.ContentType(attachment.ContentType)
+		attachment.Size = 0
+	}
+
+	return attachment, r, nil
+}
+
+// SubmissionAttachments returns the attachments of the submission with their preview URLs.
+// They are available to the student, the teacher and the peer reviewers.
+func (s *AttachmentService) SubmissionAttachments(
+	ctx context.Context,
+	submissionID string,
+	userID int64,
+) ([]models.Attachment, error) {
+	const op = "services.attachment.SubmissionAttachments"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
+	)
+
+	log.Debug("fetching attachments")
+
+	submission, err := s.submissionProvider.Submission(ctx, submissionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSubmissionNotFound) {
+			log.Warn("submission not found", slog.Any("error", err))
+
+			return nil, fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+		}
+
+		log.Error("failed to get submission", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ok, err := s.accessible(ctx, submissionID, userID, submission.StudentID, submission.TeacherID)
+	if err != nil {
+		log.Error("failed to check access", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !ok {
+		log.Warn("submission is not accessible to user")
+
+		return nil, fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+	}
+
+	attachments, err := s.attachmentProvider.SubmissionAttachments(ctx, submissionID)
+	if err != nil {
+		log.Error("failed to get attachments", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range attachments {
+		s.setURLs(&attachments[i])
+	}
+
+	return attachments, nil
+}
+
+// GeneratePreviews generates thumbnails and previews of the uploaded images.
+// It is run periodically by the scheduler as the background worker.
+func (s *AttachmentService) GeneratePreviews(ctx context.Context) error {
+	const op = "services.attachment.GeneratePreviews"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	for {
+		now := time.Now().UTC()
+
+		attachments, err := s.attachmentSaver.ClaimPreviews(ctx, now, now.Add(previewLease), previewBatchSize)
+		if err != nil {
+			log.Error("failed to claim previews", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, attachment := range attachments {
+			preview := s.generatePreview(ctx, attachment)
+
+			if err := s.attachmentSaver.SavePreview(ctx, attachment.ID, preview); err != nil {
+				log.Error("failed to save preview", slog.Any("error", err))
+
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		if len(attachments) < previewBatchSize {
+			return nil
+		}
+	}
+}
+
+// generatePreview generates the renditions of the attachment and returns the outcome.
+// Failed attempts are retried until maxPreviewAttempts,
+// images that cannot be decoded are failed at once.
+func (s *AttachmentService) generatePreview(
+	ctx context.Context,
+	attachment models.Attachment,
+) models.AttachmentPreview {
+	const op = "services.attachment.generatePreview"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("attachment_id", attachment.ID),
+	)
+
+	preview := *attachment.Preview
+
+	fail := func(err error, permanent bool) models.AttachmentPreview {
+		preview.Error = err.Error()
+		preview.Status = models.PreviewPending
+
+		if permanent || preview.Attempts >= maxPreviewAttempts {
+			log.Warn("preview failed", slog.Any("error", err))
+
+			preview.Status = models.PreviewFailed
+		}
+
+		return preview
+	}
+
+	r, err := s.blobs.Open(ctx, attachment.StorageKey, 0)
+	if err != nil {
+		return fail(err, errors.Is(err, storage.ErrBlobNotFound))
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(io.LimitReader(r, s.maxFileSize))
+	if err != nil {
+		return fail(err, false)
+	}
+
+	renditions := []struct {
+		size int
+		key  *string
+	}{
+		{size: thumbnailSize, key: &preview.ThumbnailKey},
+		{size: previewSize, key: &preview.PreviewKey},
+	}
+
+	for i, rendition := range renditions {
+		img, err := thumbnail.Generate(data, attachment.ContentType, rendition.size)
+		if err != nil {
+			return fail(err, errors.Is(err, thumbnail.ErrUnsupported) || errors.Is(err, thumbnail.ErrTooLarge))
+		}
+
+		key := attachment.StorageKey + "-thumbnail"
+		if i > 0 {
+			key = attachment.StorageKey + "-preview"
+		}
+
+		err = s.blobs.Put(ctx, key, bytes.NewReader(img.Data), int64(len(img.Data)), img.ContentType)
+		if err != nil {
+			return fail(err, false)
+		}
+
+		*rendition.key = key
+	}
+
+	log.Info("preview generated")
+
+	preview.Status = models.PreviewReady
+	preview.Error = ""
+
+	return preview
+}
+
+// accessible reports whether the attachments of the submission are accessible to the user:
+// the student, the teacher or the peer reviewer of the submission.
+func (s *AttachmentService) accessible(
+	ctx context.Context,
+	submissionID string,
+	userID int64,
+	studentID int64,
+	teacherID int64,
+) (bool, error) {
+	if userID == studentID || userID == teacherID {
+		return true, nil
+	}
+
+	if submissionID == "" {
+		return false, nil
+	}
+
+	return s.reviewProvider.IsReviewer(ctx, submissionID, userID)
+}
+
+// setURLs sets the URLs of the ready renditions of the attachment.
+func (s *AttachmentService) setURLs(attachment *models.Attachment) {
+	if attachment.Preview == nil || attachment.Preview.Status != models.PreviewReady {
+		return
+	}
+
+	base := s.previewBaseURL + "/attachments/" + attachment.ID
+	attachment.Preview.ThumbnailURL = base + "?rendition=thumbnail"
+	attachment.Preview.PreviewURL = base + "?rendition=preview"
+}
+
+// PurgeOrphaned deletes the blobs of the attachments whose submissions were purged.
+// It is run periodically by the scheduler.
+func (s *AttachmentService) PurgeOrphaned(ctx context.Context) error {
+	const op = "services.attachment.PurgeOrphaned"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	var total int
+	for {
+		attachments, err := s.attachmentProvider.OrphanedAttachments(ctx, purgeBatchSize)
+		if err != nil {
+			log.Error("failed to get orphaned attachments", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, attachment := range attachments {
+			keys := []string{attachment.StorageKey}
+			if attachment.Preview != nil {
+				keys = append(keys, attachment.Preview.ThumbnailKey, attachment.Preview.PreviewKey)
+			}
+
+			for _, key := range keys {
+				if key == "" {
+					continue
+				}
+
+				if err := s.blobs.Delete(ctx, key); err != nil {
+					log.Error("failed to delete blob", slog.Any("error", err))
+
+					return fmt.Errorf("%s: %w", op, err)
+				}
+			}
+
+			if err := s.attachmentSaver.DeleteAttachment(ctx, attachment.ID); err != nil {
+				log.Error("failed to delete attachment", slog.Any("error", err))
+
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		total += len(attachments)
+
+		if len(attachments) < purgeBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Info("orphaned attachments purged", slog.Int("count", total))
+	}
+
+	return nil
+}
+
+func allowed(contentType string) bool {
+	for _, prefix := range allowedTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cleanFilename drops the directories from the name given by the client and limits its length.
+func cleanFilename(filename string) string {
+	filename = filepath.Base(strings.ReplaceAll(filename, `\`, "/"))
+	if filename == "." || filename == "/" {
+		return "attachment"
+	}
+
+	for len(filename) > maxFilenameLen {
+		_, size := utf8.DecodeLastRuneInString(filename)
+		filename = filename[:len(filename)-size]
+	}
+
+	return filename
+}
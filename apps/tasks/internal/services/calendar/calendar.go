@@ -0,0 +1,164 @@
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+)
+
+const (
+	// tokenSize is the size of the random token of the feed in bytes.
+	tokenSize = 32
+	// feedLookback is how long the assignments stay in the feed after their cutoff date.
+	feedLookback = 30 * 24 * time.Hour
+	// maxFeedEntries limits the number of the assignments in the feed.
+	maxFeedEntries = 500
+)
+
+var ErrCalendarFeedNotFound = errors.New("calendar feed not found")
+
+type CalendarService struct {
+	log              *slog.Logger
+	calendarSaver    CalendarSaver
+	calendarProvider CalendarProvider
+}
+
+type CalendarSaver interface {
+	SaveCalendarFeed(ctx context.Context, userID int64, tokenHash []byte, createdAt time.Time) error
+	DeleteCalendarFeed(ctx context.Context, userID int64) error
+}
+
+type CalendarProvider interface {
+	CalendarFeedUser(ctx context.Context, tokenHash []byte) (int64, error)
+	CalendarEntries(
+		ctx context.Context,
+		studentID int64,
+		since time.Time,
+		limit int,
+	) ([]models.CalendarEntry, error)
+}
+
+// New returns a new instance of CalendarService.
+func New(
+	log *slog.Logger,
+	calendarSaver CalendarSaver,
+	calendarProvider CalendarProvider,
+) *CalendarService {
+	return &CalendarService{
+		log:              log,
+		calendarSaver:    calendarSaver,
+		calendarProvider: calendarProvider,
+	}
+}
+
+// CreateCalendarFeed creates the secret feed of the due dates of the user
+// and returns its token. Only the hash of the token is kept,
+// so creating the feed again is the only way to get the new token,
+// which revokes the previous one.
+func (s *CalendarService) CreateCalendarFeed(
+	ctx context.Context,
+	userID int64,
+) (string, error) {
+	const op = "services.calendar.CreateCalendarFeed"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	raw := make([]byte, tokenSize)
+	if _, err := rand.Read(raw); err != nil {
+		log.Error("failed to generate token", slog.Any("error", err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	if err := s.calendarSaver.SaveCalendarFeed(ctx, userID, hashToken(token), time.Now().UTC()); err != nil {
+		log.Error("failed to save calendar feed", slog.Any("error", err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("calendar feed created")
+
+	return token, nil
+}
+
+// RevokeCalendarFeed deletes the feed of the user, so its token stops working.
+func (s *CalendarService) RevokeCalendarFeed(
+	ctx context.Context,
+	userID int64,
+) error {
+	const op = "services.calendar.RevokeCalendarFeed"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	if err := s.calendarSaver.DeleteCalendarFeed(ctx, userID); err != nil {
+		if errors.Is(err, storage.ErrCalendarFeedNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrCalendarFeedNotFound)
+		}
+
+		log.Error("failed to delete calendar feed", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("calendar feed revoked")
+
+	return nil
+}
+
+// CalendarFeed returns the assignments of the user the feed with the token belongs to.
+// The dates are the current ones of the student, so the feed follows
+// the rescheduled assignments as soon as they are changed.
+// The assignments stay in the feed for feedLookback after their cutoff date.
+func (s *CalendarService) CalendarFeed(
+	ctx context.Context,
+	token string,
+) ([]models.CalendarEntry, error) {
+	const op = "services.calendar.CalendarFeed"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	userID, err := s.calendarProvider.CalendarFeedUser(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, storage.ErrCalendarFeedNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrCalendarFeedNotFound)
+		}
+
+		log.Error("failed to get calendar feed", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	since := time.Now().UTC().Add(-feedLookback)
+
+	entries, err := s.calendarProvider.CalendarEntries(ctx, userID, since, maxFeedEntries)
+	if err != nil {
+		log.Error("failed to list calendar entries", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
+
+// hashToken returns the hash of the token kept in the storage.
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+
+	return sum[:]
+}
@@ -0,0 +1,222 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"sort"
+	"testing"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStorage keeps the feeds and the student assignments of a single assignment.
+// Its dates are moved the way the assignment storage moves them.
+type fakeStorage struct {
+	feeds       map[int64][]byte
+	assignments []models.StudentAssignment
+}
+
+func (f *fakeStorage) SaveCalendarFeed(_ context.Context, userID int64, tokenHash []byte, _ time.Time) error {
+	f.feeds[userID] = tokenHash
+	return nil
+}
+
+func (f *fakeStorage) DeleteCalendarFeed(_ context.Context, userID int64) error {
+	if _, ok := f.feeds[userID]; !ok {
+		return storage.ErrCalendarFeedNotFound
+	}
+	delete(f.feeds, userID)
+	return nil
+}
+
+func (f *fakeStorage) CalendarFeedUser(_ context.Context, tokenHash []byte) (int64, error) {
+	for userID, hash := range f.feeds {
+		if bytes.Equal(hash, tokenHash) {
+			return userID, nil
+		}
+	}
+	return 0, storage.ErrCalendarFeedNotFound
+}
+
+func (f *fakeStorage) CalendarEntries(
+	_ context.Context,
+	studentID int64,
+	since time.Time,
+	limit int,
+) ([]models.CalendarEntry, error) {
+	var entries []models.CalendarEntry
+	for _, assignment := range f.assignments {
+		if assignment.StudentID != studentID || assignment.CutoffDate.Before(since) {
+			continue
+		}
+
+		entries = append(entries, models.CalendarEntry{
+			StudentAssignmentID: assignment.ID,
+			AssignmentID:        assignment.Assignment.ID,
+			Title:               assignment.Assignment.Title,
+			DueDate:             assignment.DueDate,
+			CutoffDate:          assignment.CutoffDate,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].DueDate.Before(entries[j].DueDate)
+	})
+
+	return entries[:min(limit, len(entries))], nil
+}
+
+// updateAssignment moves the dates of the whole assignment.
+func (f *fakeStorage) updateAssignment(update models.AssignmentUpdate) {
+	for i := range f.assignments {
+		f.assignments[i].DueDate, f.assignments[i].CutoffDate = f.assignments[i].Reschedule(update)
+	}
+}
+
+// extend moves the dates of the assignment for the student.
+func (f *fakeStorage) extend(studentID int64, dueDate time.Time, cutoffDate time.Time) {
+	for i := range f.assignments {
+		if f.assignments[i].StudentID == studentID {
+			f.assignments[i].DueDate = dueDate
+			f.assignments[i].CutoffDate = cutoffDate
+			f.assignments[i].ExtendedAt = time.Now().UTC()
+		}
+	}
+}
+
+func newTestService(assignments ...models.StudentAssignment) (*CalendarService, *fakeStorage) {
+	st := &fakeStorage{
+		feeds:       make(map[int64][]byte),
+		assignments: assignments,
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return New(log, st, st), st
+}
+
+func studentAssignment(id string, studentID int64, dueDate time.Time) models.StudentAssignment {
+	return models.StudentAssignment{
+		ID:         id,
+		Assignment: models.Assignment{ID: "assignment", Title: "Essay"},
+		StudentID:  studentID,
+		DueDate:    dueDate,
+		CutoffDate: dueDate.Add(24 * time.Hour),
+	}
+}
+
+// feedDates returns the due and cutoff dates in the feed with the token.
+func feedDates(t *testing.T, s *CalendarService, token string) (time.Time, time.Time) {
+	t.Helper()
+
+	entries, err := s.CalendarFeed(context.Background(), token)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	return entries[0].DueDate, entries[0].CutoffDate
+}
+
+func TestCalendarFeed_Dates(t *testing.T) {
+	ctx := context.Background()
+	day := 24 * time.Hour
+	dueDate := time.Now().UTC().Truncate(time.Hour).Add(7 * day)
+
+	s, st := newTestService(
+		studentAssignment("sa-1", 10, dueDate),
+		studentAssignment("sa-2", 11, dueDate),
+	)
+
+	extended, err := s.CreateCalendarFeed(ctx, 10)
+	require.NoError(t, err)
+	other, err := s.CreateCalendarFeed(ctx, 11)
+	require.NoError(t, err)
+
+	st.extend(10, dueDate.Add(3*day), dueDate.Add(4*day))
+
+	due, cutoff := feedDates(t, s, extended)
+	assert.Equal(t, dueDate.Add(3*day), due, "extension is in the feed of the student")
+	assert.Equal(t, dueDate.Add(4*day), cutoff)
+
+	due, _ = feedDates(t, s, other)
+	assert.Equal(t, dueDate, due, "extension is not in the feeds of other students")
+
+	earlier := dueDate.Add(day)
+	earlierCutoff := dueDate.Add(2 * day)
+	st.updateAssignment(models.AssignmentUpdate{DueDate: &earlier, CutoffDate: &earlierCutoff})
+
+	due, cutoff = feedDates(t, s, extended)
+	assert.Equal(t, dueDate.Add(3*day), due, "update of the assignment does not take the extension back")
+	assert.Equal(t, dueDate.Add(4*day), cutoff)
+
+	due, cutoff = feedDates(t, s, other)
+	assert.Equal(t, earlier, due)
+	assert.Equal(t, earlierCutoff, cutoff)
+
+	later := dueDate.Add(5 * day)
+	laterCutoff := dueDate.Add(6 * day)
+	st.updateAssignment(models.AssignmentUpdate{DueDate: &later, CutoffDate: &laterCutoff})
+
+	due, cutoff = feedDates(t, s, extended)
+	assert.Equal(t, later, due, "dates later than the extension are applied")
+	assert.Equal(t, laterCutoff, cutoff)
+}
+
+func TestCalendarFeed_ExtendedCutoff(t *testing.T) {
+	ctx := context.Background()
+	day := 24 * time.Hour
+	dueDate := time.Now().UTC().Truncate(time.Hour).Add(7 * day)
+
+	s, st := newTestService(studentAssignment("sa-1", 10, dueDate))
+
+	token, err := s.CreateCalendarFeed(ctx, 10)
+	require.NoError(t, err)
+
+	st.extend(10, dueDate.Add(3*day), dueDate.Add(4*day))
+
+	later := dueDate.Add(5 * day)
+	st.updateAssignment(models.AssignmentUpdate{DueDate: &later})
+
+	due, cutoff := feedDates(t, s, token)
+	assert.Equal(t, later, due)
+	assert.Equal(t, later, cutoff, "cutoff date is moved to the due date past it")
+
+	laterCutoff := dueDate.Add(6 * day)
+	st.updateAssignment(models.AssignmentUpdate{CutoffDate: &laterCutoff})
+
+	due, cutoff = feedDates(t, s, token)
+	assert.Equal(t, later, due)
+	assert.Equal(t, laterCutoff, cutoff)
+}
+
+func TestCalendarFeed_Tokens(t *testing.T) {
+	ctx := context.Background()
+
+	s, _ := newTestService(studentAssignment("sa-1", 10, time.Now().UTC().Add(time.Hour)))
+
+	revoked, err := s.CreateCalendarFeed(ctx, 10)
+	require.NoError(t, err)
+
+	token, err := s.CreateCalendarFeed(ctx, 10)
+	require.NoError(t, err)
+	assert.NotEqual(t, revoked, token)
+
+	_, err = s.CalendarFeed(ctx, revoked)
+	assert.ErrorIs(t, err, ErrCalendarFeedNotFound, "new feed revokes the previous token")
+
+	_, err = s.CalendarFeed(ctx, token)
+	require.NoError(t, err)
+
+	require.NoError(t, s.RevokeCalendarFeed(ctx, 10))
+
+	_, err = s.CalendarFeed(ctx, token)
+	assert.ErrorIs(t, err, ErrCalendarFeedNotFound)
+
+	assert.ErrorIs(t, s.RevokeCalendarFeed(ctx, 10), ErrCalendarFeedNotFound)
+}
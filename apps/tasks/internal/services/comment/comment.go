@@ -0,0 +1,502 @@
+package comment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/lib/jsonpointer"
+	"tasks/internal/storage"
+)
+
+type CommentService struct {
+	log                *slog.Logger
+	commentSaver       CommentSaver
+	commentProvider    CommentProvider
+	submissionProvider SubmissionProvider
+	attachmentProvider AttachmentProvider
+}
+
+type CommentSaver interface {
+	SaveThread(ctx context.Context, thread models.CommentThread) (string, error)
+	SaveComment(ctx context.Context, comment models.Comment) (string, error)
+	UpdateComment(
+		ctx context.Context,
+		commentID string,
+		body string,
+		editedAt time.Time,
+	) error
+	ResolveThread(
+		ctx context.Context,
+		threadID string,
+		resolvedBy models.CommentAuthor,
+		resolvedAt time.Time,
+	) error
+}
+
+type CommentProvider interface {
+	Thread(ctx context.Context, threadID string) (models.CommentThread, error)
+	Threads(
+		ctx context.Context,
+		submissionID string,
+		versionID string,
+	) ([]models.CommentThread, error)
+	Comment(ctx context.Context, commentID string) (models.Comment, error)
+}
+
+type SubmissionProvider interface {
+	Submission(ctx context.Context, submissionID string) (models.Submission, error)
+	SubmissionVersion(ctx context.Context, versionID string) (models.SubmissionVersion, error)
+}
+
+type AttachmentProvider interface {
+	SubmissionAttachments(ctx context.Context, submissionID string) ([]models.Attachment, error)
+}
+
+var (
+	ErrSubmissionNotFound = storage.ErrSubmissionNotFound
+	ErrVersionNotFound    = storage.ErrVersionNotFound
+	ErrThreadNotFound     = storage.ErrThreadNotFound
+	ErrCommentNotFound    = storage.ErrCommentNotFound
+	ErrAccessDenied       = errors.New("comment belongs to another user")
+	ErrInvalidAnchor      = errors.New("anchor does not match the submission version")
+)
+
+// New returns a new instance of CommentService.
+func New(
+	log *slog.Logger,
+	commentSaver CommentSaver,
+	commentProvider CommentProvider,
+	submissionProvider SubmissionProvider,
+	attachmentProvider AttachmentProvider,
+) *CommentService {
+	return &CommentService{
+		log:                log,
+		commentSaver:       commentSaver,
+		commentProvider:    commentProvider,
+		submissionProvider: submissionProvider,
+		attachmentProvider: attachmentProvider,
+	}
+}
+
+// StartThread starts the discussion of the submission version with the first comment.
+// Both the student and the teacher of the submission can start the thread.
+func (s *CommentService) StartThread(
+	ctx context.Context,
+	submissionID string,
+	versionID string,
+	userID int64,
+	anchor *models.CommentAnchor,
+	body string,
+) (models.CommentThread, error) {
+	const op = "services.comment.StartThread"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
+		slog.String("version_id", versionID),
+	)
+
+	log.Debug("starting comment thread")
+
+	author, err := s.participant(ctx, submissionID, userID)
+	if err != nil {
+		return models.CommentThread{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	version, err := s.submissionProvider.SubmissionVersion(ctx, versionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrVersionNotFound) {
+			log.Warn("version not found", slog.Any("error", err))
+
+			return models.CommentThread{}, fmt.Errorf("%s: %w", op, ErrVersionNotFound)
+		}
+
+		log.Error("failed to get version", slog.Any("error", err))
+
+		return models.CommentThread{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if version.SubmissionID != submissionID {
+		log.Warn("version belongs to another submission")
+
+		return models.CommentThread{}, fmt.Errorf("%s: %w", op, ErrVersionNotFound)
+	}
+
+	if anchor != nil {
+		if err := s.checkAnchor(ctx, version, anchor); err != nil {
+			return models.CommentThread{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	now := time.Now().UTC()
+
+	threadID, err := s.commentSaver.SaveThread(ctx, models.CommentThread{
+		SubmissionID: submissionID,
+		VersionID:    versionID,
+		Anchor:       anchor,
+		CreatedAt:    now,
+		Comments: []models.Comment{
+			{
+				AuthorID:  userID,
+				Author:    author,
+				Body:      body,
+				CreatedAt: now,
+			},
+		},
+	})
+	if err != nil {
+		log.Error("failed to save comment thread", slog.Any("error", err))
+
+		return models.CommentThread{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("comment thread started", slog.String("thread_id", threadID))
+
+	thread, err := s.commentProvider.Thread(ctx, threadID)
+	if err != nil {
+		log.Error("failed to get comment thread", slog.Any("error", err))
+
+		return models.CommentThread{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return thread, nil
+}
+
+// Reply adds the comment to the thread.
+// Replying to the resolved thread does not reopen it.
+func (s *CommentService) Reply(
+	ctx context.Context,
+	threadID string,
+	userID int64,
+	body string,
+) (models.Comment, error) {
+	const op = "services.comment.Reply"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("thread_id", threadID),
+	)
+
+	log.Debug("replying to comment thread")
+
+	thread, author, err := s.thread(ctx, threadID, userID)
+	if err != nil {
+		return models.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	comment := models.Comment{
+		ThreadID:  thread.ID,
+		AuthorID:  userID,
+		Author:    author,
+		Body:      body,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	comment.ID, err = s.commentSaver.SaveComment(ctx, comment)
+	if err != nil {
+		if errors.Is(err, storage.ErrThreadNotFound) {
+			log.Warn("comment thread not found", slog.Any("error", err))
+
+			return models.Comment{}, fmt.Errorf("%s: %w", op, ErrThreadNotFound)
+		}
+
+		log.Error("failed to save comment", slog.Any("error", err))
+
+		return models.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("comment added", slog.String("comment_id", comment.ID))
+
+	return comment, nil
+}
+
+// EditComment changes the body of the comment of the user.
+// The previous body is kept in the edit history.
+func (s *CommentService) EditComment(
+	ctx context.Context,
+	commentID string,
+	userID int64,
+	body string,
+) (models.Comment, error) {
+	const op = "services.comment.EditComment"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("comment_id", commentID),
+	)
+
+	log.Debug("editing comment")
+
+	comment, err := s.commentProvider.Comment(ctx, commentID)
+	if err != nil {
+		if errors.Is(err, storage.ErrCommentNotFound) {
+			log.Warn("comment not found", slog.Any("error", err))
+
+			return models.Comment{}, fmt.Errorf("%s: %w", op, ErrCommentNotFound)
+		}
+
+		log.Error("failed to get comment", slog.Any("error", err))
+
+		return models.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, _, err := s.thread(ctx, comment.ThreadID, userID); err != nil {
+		if errors.Is(err, ErrThreadNotFound) {
+			return models.Comment{}, fmt.Errorf("%s: %w", op, ErrCommentNotFound)
+		}
+
+		return models.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if comment.AuthorID != userID {
+		log.Warn("comment belongs to another user")
+
+		return models.Comment{}, fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	}
+
+	if comment.Body == body {
+		return comment, nil
+	}
+
+	if err := s.commentSaver.UpdateComment(ctx, commentID, body, time.Now().UTC()); err != nil {
+		if errors.Is(err, storage.ErrCommentNotFound) {
+			log.Warn("comment not found", slog.Any("error", err))
+
+			return models.Comment{}, fmt.Errorf("%s: %w", op, ErrCommentNotFound)
+		}
+
+		log.Error("failed to update comment", slog.Any("error", err))
+
+		return models.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("comment edited")
+
+	comment, err = s.commentProvider.Comment(ctx, commentID)
+	if err != nil {
+		log.Error("failed to get comment", slog.Any("error", err))
+
+		return models.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return comment, nil
+}
+
+// ResolveThread marks the thread as resolved or reopens it.
+// Either participant can resolve and reopen the thread.
+func (s *CommentService) ResolveThread(
+	ctx context.Context,
+	threadID string,
+	userID int64,
+	resolved bool,
+) (models.CommentThread, error) {
+	const op = "services.comment.ResolveThread"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("thread_id", threadID),
+		slog.Bool("resolved", resolved),
+	)
+
+	log.Debug("resolving comment thread")
+
+	thread, author, err := s.thread(ctx, threadID, userID)
+	if err != nil {
+		return models.CommentThread{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if thread.Resolved() == resolved {
+		return thread, nil
+	}
+
+	var resolvedAt time.Time
+	if resolved {
+		resolvedAt = time.Now().UTC()
+	} else {
+		author = ""
+	}
+
+	if err := s.commentSaver.ResolveThread(ctx, threadID, author, resolvedAt); err != nil {
+		if errors.Is(err, storage.ErrThreadNotFound) {
+			log.Warn("comment thread not found", slog.Any("error", err))
+
+			return models.CommentThread{}, fmt.Errorf("%s: %w", op, ErrThreadNotFound)
+		}
+
+		log.Error("failed to resolve comment thread", slog.Any("error", err))
+
+		return models.CommentThread{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("comment thread resolution changed")
+
+	thread.ResolvedBy = author
+	thread.ResolvedAt = resolvedAt
+
+	return thread, nil
+}
+
+// Threads returns the threads of the submission, or of its version if versionID is not empty.
+func (s *CommentService) Threads(
+	ctx context.Context,
+	submissionID string,
+	versionID string,
+	userID int64,
+) ([]models.CommentThread, error) {
+	const op = "services.comment.Threads"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
+	)
+
+	log.Debug("fetching comment threads")
+
+	if _, err := s.participant(ctx, submissionID, userID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	threads, err := s.commentProvider.Threads(ctx, submissionID, versionID)
+	if err != nil {
+		log.Error("failed to get comment threads", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return threads, nil
+}
+
+// thread returns the thread and the role of the user in it.
+// Threads the user does not participate in are reported as not found.
+func (s *CommentService) thread(
+	ctx context.Context,
+	threadID string,
+	userID int64,
+) (models.CommentThread, models.CommentAuthor, error) {
+	const op = "services.comment.thread"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("thread_id", threadID),
+	)
+
+	thread, err := s.commentProvider.Thread(ctx, threadID)
+	if err != nil {
+		if errors.Is(err, storage.ErrThreadNotFound) {
+			log.Warn("comment thread not found", slog.Any("error", err))
+
+			return models.CommentThread{}, "", fmt.Errorf("%s: %w", op, ErrThreadNotFound)
+		}
+
+		log.Error("failed to get comment thread", slog.Any("error", err))
+
+		return models.CommentThread{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	author, err := s.participant(ctx, thread.SubmissionID, userID)
+	if err != nil {
+		if errors.Is(err, ErrSubmissionNotFound) {
+			return models.CommentThread{}, "", fmt.Errorf("%s: %w", op, ErrThreadNotFound)
+		}
+
+		return models.CommentThread{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return thread, author, nil
+}
+
+// participant returns the role of the user in the discussion of the submission:
+// the student who owns it or the teacher of the assignment.
+// Submissions of others and the submissions in the trash are reported as not found.
+func (s *CommentService) participant(
+	ctx context.Context,
+	submissionID string,
+	userID int64,
+) (models.CommentAuthor, error) {
+	const op = "services.comment.participant"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
+	)
+
+	submission, err := s.submissionProvider.Submission(ctx, submissionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSubmissionNotFound) {
+			log.Warn("submission not found", slog.Any("error", err))
+
+			return "", fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+		}
+
+		log.Error("failed to get submission", slog.Any("error", err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch {
+	case submission.Deleted():
+		log.Warn("submission is in the trash")
+	case submission.StudentID == userID:
+		return models.CommentByStudent, nil
+	case submission.TeacherID == userID:
+		return models.CommentByTeacher, nil
+	default:
+		log.Warn("user does not participate in submission")
+	}
+
+	return "", fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+}
+
+// checkAnchor checks that the anchor refers to the existing part of the version:
+// the value of the payload or the region of the image attached to the submission.
+func (s *CommentService) checkAnchor(
+	ctx context.Context,
+	version models.SubmissionVersion,
+	anchor *models.CommentAnchor,
+) error {
+	const op = "services.comment.checkAnchor"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("version_id", version.ID),
+	)
+
+	if anchor.Region != nil {
+		if anchor.Pointer != "" || !anchor.Region.Valid() {
+			log.Warn("invalid image region")
+
+			return fmt.Errorf("%s: %w", op, ErrInvalidAnchor)
+		}
+
+		attachments, err := s.attachmentProvider.SubmissionAttachments(ctx, version.SubmissionID)
+		if err != nil {
+			log.Error("failed to get attachments", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, a := range attachments {
+			if a.ID == anchor.Region.AttachmentID && strings.HasPrefix(a.ContentType, "image/") {
+				return nil
+			}
+		}
+
+		log.Warn("region refers to unknown image", slog.String("attachment_id", anchor.Region.AttachmentID))
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidAnchor)
+	}
+
+	exists, err := jsonpointer.Exists(version.Payload, anchor.Pointer)
+	if err != nil || !exists {
+		log.Warn("pointer does not refer to payload", slog.String("pointer", anchor.Pointer))
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidAnchor)
+	}
+
+	return nil
+}
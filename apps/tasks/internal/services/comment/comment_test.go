@@ -0,0 +1,248 @@
+package comment
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	studentID  = 1
+	teacherID  = 10
+	outsiderID = 20
+)
+
+// fakeStorage keeps the comments in memory
+// and edits them the way the postgres storage does.
+// The methods the tests do not call are left to the embedded interfaces.
+type fakeStorage struct {
+	CommentSaver
+	CommentProvider
+	SubmissionProvider
+	AttachmentProvider
+
+	submissions map[string]models.Submission
+	threads     map[string]models.CommentThread
+	comments    map[string]models.Comment
+	// resolutions counts the changes of the resolution of the threads.
+	resolutions int
+}
+
+func (f *fakeStorage) UpdateComment(
+	_ context.Context,
+	commentID string,
+	body string,
+	editedAt time.Time,
+) error {
+	comment, ok := f.comments[commentID]
+	if !ok {
+		return storage.ErrCommentNotFound
+	}
+
+	revisedAt := comment.CreatedAt
+	if !comment.EditedAt.IsZero() {
+		revisedAt = comment.EditedAt
+	}
+
+	comment.Revisions = append(comment.Revisions, models.CommentRevision{
+		Body:      comment.Body,
+		CreatedAt: revisedAt,
+	})
+	comment.Body = body
+	comment.EditedAt = editedAt
+	f.comments[commentID] = comment
+
+	return nil
+}
+
+func (f *fakeStorage) ResolveThread(
+	_ context.Context,
+	threadID string,
+	resolvedBy models.CommentAuthor,
+	resolvedAt time.Time,
+) error {
+	thread, ok := f.threads[threadID]
+	if !ok {
+		return storage.ErrThreadNotFound
+	}
+
+	thread.ResolvedBy = resolvedBy
+	thread.ResolvedAt = resolvedAt
+	f.threads[threadID] = thread
+	f.resolutions++
+
+	return nil
+}
+
+func (f *fakeStorage) Thread(_ context.Context, threadID string) (models.CommentThread, error) {
+	thread, ok := f.threads[threadID]
+	if !ok {
+		return models.CommentThread{}, storage.ErrThreadNotFound
+	}
+	return thread, nil
+}
+
+func (f *fakeStorage) Comment(_ context.Context, commentID string) (models.Comment, error) {
+	comment, ok := f.comments[commentID]
+	if !ok {
+		return models.Comment{}, storage.ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+func (f *fakeStorage) Submission(_ context.Context, submissionID string) (models.Submission, error) {
+	submission, ok := f.submissions[submissionID]
+	if !ok {
+		return models.Submission{}, storage.ErrSubmissionNotFound
+	}
+	return submission, nil
+}
+
+// newTestService returns the service with the thread "thread" on the submission
+// of the student, started by the comment "comment" of the student.
+func newTestService() (*CommentService, *fakeStorage) {
+	st := &fakeStorage{
+		submissions: map[string]models.Submission{
+			"submission": {ID: "submission", StudentID: studentID, TeacherID: teacherID},
+		},
+		threads: map[string]models.CommentThread{
+			"thread": {ID: "thread", SubmissionID: "submission", VersionID: "version"},
+		},
+		comments: map[string]models.Comment{
+			"comment": {
+				ID:        "comment",
+				ThreadID:  "thread",
+				AuthorID:  studentID,
+				Author:    models.CommentByStudent,
+				Body:      "Is the second answer right?",
+				CreatedAt: time.Now().UTC().Add(-time.Hour),
+			},
+		},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return New(log, st, st, st, st), st
+}
+
+func TestEditComment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("edits are kept in history", func(t *testing.T) {
+		s, st := newTestService()
+		createdAt := st.comments["comment"].CreatedAt
+
+		first, err := s.EditComment(ctx, "comment", studentID, "Is the third answer right?")
+		require.NoError(t, err)
+		assert.Equal(t, "Is the third answer right?", first.Body)
+		assert.False(t, first.EditedAt.IsZero())
+		assert.Equal(t, []models.CommentRevision{
+			{Body: "Is the second answer right?", CreatedAt: createdAt},
+		}, first.Revisions)
+
+		second, err := s.EditComment(ctx, "comment", studentID, "Are the answers right?")
+		require.NoError(t, err)
+		assert.Equal(t, "Are the answers right?", second.Body)
+		assert.Equal(t, []models.CommentRevision{
+			{Body: "Is the second answer right?", CreatedAt: createdAt},
+			{Body: "Is the third answer right?", CreatedAt: first.EditedAt},
+		}, second.Revisions, "revisions are the oldest first")
+	})
+
+	t.Run("same body is not a revision", func(t *testing.T) {
+		s, st := newTestService()
+
+		comment, err := s.EditComment(ctx, "comment", studentID, "Is the second answer right?")
+		require.NoError(t, err)
+		assert.Empty(t, comment.Revisions)
+		assert.True(t, st.comments["comment"].EditedAt.IsZero())
+	})
+
+	t.Run("comment of another participant", func(t *testing.T) {
+		s, st := newTestService()
+
+		_, err := s.EditComment(ctx, "comment", teacherID, "Yes, it is.")
+		assert.ErrorIs(t, err, ErrAccessDenied)
+		assert.Equal(t, "Is the second answer right?", st.comments["comment"].Body)
+	})
+
+	t.Run("hidden from others", func(t *testing.T) {
+		s, st := newTestService()
+
+		_, err := s.EditComment(ctx, "comment", outsiderID, "Spam")
+		assert.ErrorIs(t, err, ErrCommentNotFound, "outsider does not learn the comment exists")
+
+		submission := st.submissions["submission"]
+		submission.DeletedAt = time.Now().UTC()
+		st.submissions["submission"] = submission
+
+		_, err = s.EditComment(ctx, "comment", studentID, "Edited in the trash")
+		assert.ErrorIs(t, err, ErrCommentNotFound, "comments of submission in trash are frozen")
+
+		_, err = s.EditComment(ctx, "unknown", studentID, "Edited")
+		assert.ErrorIs(t, err, ErrCommentNotFound)
+
+		assert.Equal(t, "Is the second answer right?", st.comments["comment"].Body)
+	})
+}
+
+func TestResolveThread(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("either participant resolves and reopens", func(t *testing.T) {
+		s, st := newTestService()
+
+		thread, err := s.ResolveThread(ctx, "thread", studentID, true)
+		require.NoError(t, err)
+		assert.True(t, thread.Resolved())
+		assert.Equal(t, models.CommentByStudent, thread.ResolvedBy)
+		assert.Equal(t, models.CommentByStudent, st.threads["thread"].ResolvedBy)
+
+		thread, err = s.ResolveThread(ctx, "thread", teacherID, false)
+		require.NoError(t, err)
+		assert.False(t, thread.Resolved())
+		assert.Empty(t, thread.ResolvedBy)
+		assert.True(t, st.threads["thread"].ResolvedAt.IsZero())
+
+		thread, err = s.ResolveThread(ctx, "thread", teacherID, true)
+		require.NoError(t, err)
+		assert.Equal(t, models.CommentByTeacher, thread.ResolvedBy)
+	})
+
+	t.Run("unchanged resolution is not saved", func(t *testing.T) {
+		s, st := newTestService()
+
+		_, err := s.ResolveThread(ctx, "thread", studentID, false)
+		require.NoError(t, err)
+		assert.Zero(t, st.resolutions)
+
+		_, err = s.ResolveThread(ctx, "thread", studentID, true)
+		require.NoError(t, err)
+		_, err = s.ResolveThread(ctx, "thread", teacherID, true)
+		require.NoError(t, err)
+		assert.Equal(t, 1, st.resolutions)
+		assert.Equal(t, models.CommentByStudent, st.threads["thread"].ResolvedBy,
+			"resolving the resolved thread keeps who resolved it")
+	})
+
+	t.Run("hidden from others", func(t *testing.T) {
+		s, st := newTestService()
+
+		_, err := s.ResolveThread(ctx, "thread", outsiderID, true)
+		assert.ErrorIs(t, err, ErrThreadNotFound)
+
+		_, err = s.ResolveThread(ctx, "unknown", studentID, true)
+		assert.ErrorIs(t, err, ErrThreadNotFound)
+
+		assert.True(t, st.threads["thread"].ResolvedAt.IsZero())
+		assert.Zero(t, st.resolutions)
+	})
+}
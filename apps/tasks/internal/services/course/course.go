@@ -0,0 +1,439 @@
+package course
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/lib/bundle"
+	"tasks/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+type CourseService struct {
+	log              *slog.Logger
+	courseSaver      CourseSaver
+	courseProvider   CourseProvider
+	templateProvider TemplateProvider
+	rubricProvider   RubricProvider
+	widgetProvider   WidgetProvider
+}
+
+type CourseSaver interface {
+	SaveCourse(
+		ctx context.Context,
+		course models.Course,
+		templates []models.Template,
+		rubrics []models.Rubric,
+		commit bool,
+	) error
+	AddCourseSection(
+		ctx context.Context,
+		courseID string,
+		section models.CourseSection,
+	) error
+}
+
+type CourseProvider interface {
+	Course(ctx context.Context, courseID string) (models.Course, error)
+}
+
+type TemplateProvider interface {
+	TemplatesByIDs(ctx context.Context, templateIDs []string) ([]models.Template, error)
+}
+
+type RubricProvider interface {
+	RubricsByTemplateIDs(ctx context.Context, templateIDs []string) ([]models.Rubric, error)
+}
+
+type WidgetProvider interface {
+	Widget(ctx context.Context, widgetType string, version int) (models.Widget, error)
+}
+
+var (
+	ErrCourseNotFound   = storage.ErrCourseNotFound
+	ErrTemplateNotFound = storage.ErrTemplateNotFound
+	ErrInvalidBundle    = errors.New("invalid course bundle")
+)
+
+const (
+	KindCourse   = "course"
+	KindSection  = "section"
+	KindTemplate = "template"
+	KindRubric   = "rubric"
+)
+
+// Mapping links the entity of the bundle to the entity created by the import.
+type Mapping struct {
+	Kind     string
+	SourceID string
+	TargetID string
+}
+
+// ImportResult describes the outcome of the course import.
+type ImportResult struct {
+	Applied  bool
+	CourseID string
+	Mappings []Mapping
+	Problems []bundle.Problem
+}
+
+// New returns a new instance of CourseService.
+func New(
+	log *slog.Logger,
+	courseSaver CourseSaver,
+	courseProvider CourseProvider,
+	templateProvider TemplateProvider,
+	rubricProvider RubricProvider,
+	widgetProvider WidgetProvider,
+) *CourseService {
+	return &CourseService{
+		log:              log,
+		courseSaver:      courseSaver,
+		courseProvider:   courseProvider,
+		templateProvider: templateProvider,
+		rubricProvider:   rubricProvider,
+		widgetProvider:   widgetProvider,
+	}
+}
+
+// CreateCourse creates the empty course owned by the user.
+// The sections are added to it by AddCourseSection.
+func (s *CourseService) CreateCourse(
+	ctx context.Context,
+	userID int64,
+	schoolID int64,
+	title string,
+	description string,
+) (string, error) {
+	const op = "services.course.CreateCourse"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Debug("creating course")
+
+	course := models.Course{
+		ID:          uuid.NewString(),
+		CreatorID:   userID,
+		SchoolID:    schoolID,
+		Title:       title,
+		Description: description,
+	}
+
+	if err := s.courseSaver.SaveCourse(ctx, course, nil, nil, true); err != nil {
+		log.Error("failed to save course", slog.Any("error", err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("course created", slog.String("course_id", course.ID))
+
+	return course.ID, nil
+}
+
+// AddCourseSection appends the section with the templates of the library
+// to the course of the user. The templates are referenced, not copied.
+//
+// If the course does not exist or belongs to another user, returns ErrCourseNotFound.
+// If some template does not exist or is not visible to the user, returns ErrTemplateNotFound.
+func (s *CourseService) AddCourseSection(
+	ctx context.Context,
+	courseID string,
+	userID int64,
+	schoolID int64,
+	title string,
+	templateIDs []string,
+) (string, error) {
+	const op = "services.course.AddCourseSection"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("course_id", courseID),
+	)
+
+	log.Debug("adding course section")
+
+	course, err := s.courseProvider.Course(ctx, courseID)
+	if err != nil {
+		if errors.Is(err, storage.ErrCourseNotFound) {
+			log.Warn("course not found", slog.Any("error", err))
+
+			return "", fmt.Errorf("%s: %w", op, ErrCourseNotFound)
+		}
+
+		log.Error("failed to get course", slog.Any("error", err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if course.CreatorID != userID {
+		log.Warn("course belongs to another user")
+
+		return "", fmt.Errorf("%s: %w", op, ErrCourseNotFound)
+	}
+
+	templates, err := s.templateProvider.TemplatesByIDs(ctx, templateIDs)
+	if err != nil {
+		log.Error("failed to get templates", slog.Any("error", err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	visible := make(map[string]bool, len(templates))
+	for _, template := range templates {
+		visible[template.ID] = template.VisibleTo(userID, schoolID)
+	}
+
+	for _, templateID := range templateIDs {
+		if !visible[templateID] {
+			log.Warn("template is not visible to user", slog.String("template_id", templateID))
+
+			return "", fmt.Errorf("%s: %w", op, ErrTemplateNotFound)
+		}
+	}
+
+	section := models.CourseSection{
+		ID:          uuid.NewString(),
+		Title:       title,
+		TemplateIDs: templateIDs,
+	}
+
+	if err := s.courseSaver.AddCourseSection(ctx, courseID, section); err != nil {
+		if errors.Is(err, storage.ErrCourseNotFound) {
+			log.Warn("course not found", slog.Any("error", err))
+
+			return "", fmt.Errorf("%s: %w", op, ErrCourseNotFound)
+		}
+
+		log.Error("failed to add course section", slog.Any("error", err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("course section added", slog.String("section_id", section.ID))
+
+	return section.ID, nil
+}
+
+// ExportCourse packs the course of the user to the portable bundle.
+//
+// The bundle contains templates, widget references, rubrics and sections of the course.
+// If the course does not exist or belongs to another user, returns ErrCourseNotFound.
+func (s *CourseService) ExportCourse(
+	ctx context.Context,
+	courseID string,
+	userID int64,
+) ([]byte, error) {
+	const op = "services.course.ExportCourse"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("course_id", courseID),
+	)
+
+	log.Debug("exporting course")
+
+	course, err := s.courseProvider.Course(ctx, courseID)
+	if err != nil {
+		if errors.Is(err, storage.ErrCourseNotFound) {
+			log.Warn("course not found", slog.Any("error", err))
+
+			return nil, fmt.Errorf("%s: %w", op, ErrCourseNotFound)
+		}
+
+		log.Error("failed to get course", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if course.CreatorID != userID {
+		log.Warn("course belongs to another user")
+
+		return nil, fmt.Errorf("%s: %w", op, ErrCourseNotFound)
+	}
+
+	var templateIDs []string
+	for _, section := range course.Sections {
+		templateIDs = append(templateIDs, section.TemplateIDs...)
+	}
+
+	templates, err := s.templateProvider.TemplatesByIDs(ctx, templateIDs)
+	if err != nil {
+		log.Error("failed to get course templates", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rubrics, err := s.rubricProvider.RubricsByTemplateIDs(ctx, templateIDs)
+	if err != nil {
+		log.Error("failed to get course rubrics", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	data, err := bundle.New(course, templates, rubrics).Encode()
+	if err != nil {
+		log.Error("failed to encode bundle", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info(
+		"course exported",
+		slog.Int("templates", len(templates)),
+		slog.Int("rubrics", len(rubrics)),
+	)
+
+	return data, nil
+}
+
+// ImportCourse creates the course owned by the user from the bundle.
+//
+// Ids of all the entities are remapped and widget versions are resolved in the
+// current environment. The import is always written in a transaction first,
+// if apply is false the transaction is rolled back and nothing is changed.
+// If the bundle cannot be imported, the problems are returned in ImportResult.Problems.
+func (s *CourseService) ImportCourse(
+	ctx context.Context,
+	userID int64,
+	schoolID int64,
+	data []byte,
+	apply bool,
+) (ImportResult, error) {
+	const op = "services.course.ImportCourse"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Bool("apply", apply),
+	)
+
+	log.Debug("importing course")
+
+	b, err := bundle.Decode(data)
+	if err != nil {
+		log.Warn("failed to decode bundle", slog.Any("error", err))
+
+		return ImportResult{}, fmt.Errorf("%s: %w: %v", op, ErrInvalidBundle, err)
+	}
+
+	problems := b.Validate()
+
+	widgets := make(map[bundle.Widget]models.Widget, len(b.Widgets))
+	for _, template := range b.Templates {
+		if _, ok := widgets[template.Widget]; ok {
+			continue
+		}
+
+		widget, err := s.widgetProvider.Widget(ctx, template.Widget.Type, template.Widget.Version)
+		if err != nil {
+			if errors.Is(err, storage.ErrWidgetNotFound) {
+				problems = append(problems, bundle.Problem{
+					Ref: template.Ref,
+					Reason: "widget " + template.Widget.Type +
+						" version " + strconv.Itoa(template.Widget.Version) + " does not exist",
+				})
+
+				continue
+			}
+
+			log.Error("failed to get widget", slog.Any("error", err))
+
+			return ImportResult{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		widgets[template.Widget] = widget
+	}
+
+	if len(problems) > 0 {
+		log.Warn("bundle cannot be imported", slog.Int("problems", len(problems)))
+
+		return ImportResult{Problems: problems}, nil
+	}
+
+	res := ImportResult{}
+	ids := make(map[string]string)
+	remap := func(kind string, ref string) string {
+		id := uuid.NewString()
+		ids[ref] = id
+		res.Mappings = append(res.Mappings, Mapping{Kind: kind, SourceID: ref, TargetID: id})
+
+		return id
+	}
+
+	course := models.Course{
+		ID:          remap(KindCourse, b.Course.Ref),
+		CreatorID:   userID,
+		SchoolID:    schoolID,
+		Title:       b.Course.Title,
+		Description: b.Course.Description,
+	}
+
+	var (
+		templates []models.Template
+		rubrics   []models.Rubric
+	)
+
+	for _, t := range b.Templates {
+		widget := widgets[t.Widget]
+		widget.Config = t.Config
+
+		template := models.Template{
+			ID:         remap(KindTemplate, t.Ref),
+			CreatorID:  userID,
+			SchoolID:   schoolID,
+			Title:      t.Title,
+			Widget:     widget,
+			Visibility: models.VisibilityPrivate,
+		}
+		templates = append(templates, template)
+
+		for _, r := range t.Rubrics {
+			rubrics = append(rubrics, models.Rubric{
+				ID:         remap(KindRubric, r.Ref),
+				TemplateID: template.ID,
+				Title:      r.Title,
+				Criteria:   r.Criteria,
+			})
+		}
+	}
+
+	for position, section := range b.Course.Sections {
+		templateIDs := make([]string, 0, len(section.Templates))
+		for _, ref := range section.Templates {
+			templateIDs = append(templateIDs, ids[ref])
+		}
+
+		course.Sections = append(course.Sections, models.CourseSection{
+			ID:          remap(KindSection, section.Ref),
+			Title:       section.Title,
+			Position:    position,
+			TemplateIDs: templateIDs,
+		})
+	}
+
+	if err := s.courseSaver.SaveCourse(ctx, course, templates, rubrics, apply); err != nil {
+		log.Error("failed to save course", slog.Any("error", err))
+
+		return ImportResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res.Applied = apply
+	if apply {
+		res.CourseID = course.ID
+	}
+
+	log.Info(
+		"course imported",
+		slog.String("course_id", course.ID),
+		slog.Int("templates", len(templates)),
+		slog.Int("rubrics", len(rubrics)),
+	)
+
+	return res, nil
+}
@@ -0,0 +1,154 @@
+package course
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/lib/bundle"
+	"tasks/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStorage struct {
+	courses   map[string]models.Course
+	templates map[string]models.Template
+}
+
+func (f *fakeStorage) SaveCourse(
+	_ context.Context,
+	course models.Course,
+	templates []models.Template,
+	_ []models.Rubric,
+	commit bool,
+) error {
+	if !commit {
+		return nil
+	}
+
+	f.courses[course.ID] = course
+	for _, template := range templates {
+		f.templates[template.ID] = template
+	}
+
+	return nil
+}
+
+func (f *fakeStorage) AddCourseSection(
+	_ context.Context,
+	courseID string,
+	section models.CourseSection,
+) error {
+	course, ok := f.courses[courseID]
+	if !ok {
+		return storage.ErrCourseNotFound
+	}
+
+	section.Position = len(course.Sections)
+	course.Sections = append(course.Sections, section)
+	f.courses[courseID] = course
+
+	return nil
+}
+
+func (f *fakeStorage) Course(_ context.Context, courseID string) (models.Course, error) {
+	course, ok := f.courses[courseID]
+	if !ok {
+		return models.Course{}, storage.ErrCourseNotFound
+	}
+
+	return course, nil
+}
+
+func (f *fakeStorage) TemplatesByIDs(_ context.Context, templateIDs []string) ([]models.Template, error) {
+	var templates []models.Template
+	for _, id := range templateIDs {
+		if template, ok := f.templates[id]; ok {
+			templates = append(templates, template)
+		}
+	}
+
+	return templates, nil
+}
+
+func (f *fakeStorage) RubricsByTemplateIDs(_ context.Context, _ []string) ([]models.Rubric, error) {
+	return nil, nil
+}
+
+func (f *fakeStorage) Widget(_ context.Context, widgetType string, version int) (models.Widget, error) {
+	return models.Widget{ID: 1, Type: widgetType, Version: version}, nil
+}
+
+func newTestService(templates ...models.Template) (*CourseService, *fakeStorage) {
+	st := &fakeStorage{
+		courses:   make(map[string]models.Course),
+		templates: make(map[string]models.Template),
+	}
+	for _, template := range templates {
+		st.templates[template.ID] = template
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return New(log, st, st, st, st, st), st
+}
+
+func TestAuthoredCourseExport(t *testing.T) {
+	const (
+		teacherID = 1
+		schoolID  = 10
+	)
+
+	quiz := models.Widget{Type: models.WidgetTypeQuiz, Version: 1, Config: json.RawMessage(`{"questions":[]}`)}
+
+	svc, _ := newTestService(
+		models.Template{ID: "own", CreatorID: teacherID, SchoolID: schoolID, Title: "Own", Widget: quiz},
+		models.Template{ID: "shared", CreatorID: 2, SchoolID: schoolID, Title: "Shared", Widget: quiz, Visibility: models.VisibilitySchool},
+		models.Template{ID: "private", CreatorID: 2, SchoolID: schoolID, Title: "Private", Widget: quiz},
+	)
+
+	ctx := context.Background()
+
+	courseID, err := svc.CreateCourse(ctx, teacherID, schoolID, "Drawing", "Autumn term")
+	require.NoError(t, err)
+
+	_, err = svc.AddCourseSection(ctx, courseID, teacherID, schoolID, "Week 1", []string{"own", "shared"})
+	require.NoError(t, err)
+
+	_, err = svc.AddCourseSection(ctx, courseID, teacherID, schoolID, "Week 2", []string{"private"})
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	_, err = svc.AddCourseSection(ctx, courseID, teacherID, schoolID, "Week 2", []string{"missing"})
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	_, err = svc.AddCourseSection(ctx, courseID, 3, schoolID, "Week 2", []string{"own"})
+	assert.ErrorIs(t, err, ErrCourseNotFound)
+
+	_, err = svc.AddCourseSection(ctx, "unknown", teacherID, schoolID, "Week 2", nil)
+	assert.ErrorIs(t, err, ErrCourseNotFound)
+
+	data, err := svc.ExportCourse(ctx, courseID, teacherID)
+	require.NoError(t, err)
+
+	b, err := bundle.Decode(data)
+	require.NoError(t, err)
+	assert.Empty(t, b.Validate())
+
+	assert.Equal(t, "Drawing", b.Course.Title)
+	require.Len(t, b.Course.Sections, 1)
+	assert.Equal(t, "Week 1", b.Course.Sections[0].Title)
+	assert.Equal(t, []string{"own", "shared"}, b.Course.Sections[0].Templates)
+	assert.Len(t, b.Templates, 2)
+
+	// the exported bundle is imported as the new course of another teacher
+	res, err := svc.ImportCourse(ctx, 4, 20, data, true)
+	require.NoError(t, err)
+	assert.Empty(t, res.Problems)
+	assert.True(t, res.Applied)
+	assert.NotEqual(t, courseID, res.CourseID)
+}
@@ -0,0 +1,599 @@
+package grading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+)
+
+type GradingService struct {
+	log                *slog.Logger
+	gradingSaver       GradingSaver
+	gradingProvider    GradingProvider
+	submissionProvider SubmissionProvider
+	moderationProvider ModerationProvider
+	regradeWindow      time.Duration
+}
+
+type GradingSaver interface {
+	SaveDraftFeedback(ctx context.Context, feedback models.Feedback) error
+	PublishFeedback(
+		ctx context.Context,
+		feedback models.Feedback,
+		status models.SubmissionStatus,
+	) error
+	SaveRegradeRequest(ctx context.Context, request models.RegradeRequest) (string, error)
+	ResolveRegrade(ctx context.Context, request models.RegradeRequest) error
+}
+
+type GradingProvider interface {
+	PublishedFeedback(ctx context.Context, submissionID string) (models.Feedback, error)
+	RegradeRequest(ctx context.Context, requestID string) (models.RegradeRequest, error)
+	OpenRegrades(
+		ctx context.Context,
+		teacherID int64,
+		filter models.Filter,
+	) ([]models.RegradeRequest, error)
+	SubmissionEvents(ctx context.Context, submissionID string) ([]models.SubmissionEvent, error)
+}
+
+type SubmissionProvider interface {
+	Submission(ctx context.Context, submissionID string) (models.Submission, error)
+	SubmissionVersion(ctx context.Context, versionID string) (models.SubmissionVersion, error)
+}
+
+type ModerationProvider interface {
+	SubmissionModeration(ctx context.Context, submissionID string) (models.Moderation, error)
+}
+
+var (
+	ErrSubmissionNotFound = storage.ErrSubmissionNotFound
+	ErrVersionNotFound    = storage.ErrVersionNotFound
+	ErrRegradeNotFound    = storage.ErrRegradeNotFound
+	ErrRegradeAlreadyOpen = storage.ErrRegradeAlreadyOpen
+	ErrAccessDenied       = errors.New("submission belongs to another teacher")
+	ErrNotSubmitted       = errors.New("submission is not submitted")
+	ErrNotCurrentVersion  = errors.New("version is not the current version of submission")
+	ErrNotGraded          = errors.New("submission is not graded")
+	ErrRegradeWindow      = errors.New("regrade window has closed")
+	ErrRegradeResolved    = errors.New("regrade request is already resolved")
+	ErrModerationPending  = errors.New("moderation of assignment is not completed")
+	ErrModerated          = errors.New("grade of submission is moderated")
+)
+
+// New returns a new instance of GradingService.
+func New(
+	log *slog.Logger,
+	gradingSaver GradingSaver,
+	gradingProvider GradingProvider,
+	submissionProvider SubmissionProvider,
+	moderationProvider ModerationProvider,
+	regradeWindow time.Duration,
+) *GradingService {
+	return &GradingService{
+		log:                log,
+		gradingSaver:       gradingSaver,
+		gradingProvider:    gradingProvider,
+		submissionProvider: submissionProvider,
+		moderationProvider: moderationProvider,
+		regradeWindow:      regradeWindow,
+	}
+}
+
+// ProvideFeedback saves the draft feedback on the current version of the submission.
+// The draft is seen only by the teacher until it is published with ReturnSubmission.
+// The draft moderated by the head teacher cannot be changed.
+func (s *GradingService) ProvideFeedback(
+	ctx context.Context,
+	submissionID string,
+	teacherID int64,
+	text string,
+	score *float64,
+) error {
+	const op = "services.grading.ProvideFeedback"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
+	)
+
+	log.Debug("saving draft feedback")
+
+	submission, err := s.teacherSubmission(ctx, submissionID, teacherID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if submission.SubmittedAt.IsZero() || submission.CurrentVersion == nil {
+		log.Warn("submission is not submitted")
+
+		return fmt.Errorf("%s: %w", op, ErrNotSubmitted)
+	}
+
+	moderation, moderated, err := s.moderation(ctx, submissionID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, ok := moderation.Item(submissionID, submission.CurrentVersion.ID); moderated && ok {
+		log.Warn("draft feedback is moderated")
+
+		return fmt.Errorf("%s: %w", op, ErrModerated)
+	}
+
+	err = s.gradingSaver.SaveDraftFeedback(ctx, models.Feedback{
+		SubmissionID: submissionID,
+		VersionID:    submission.CurrentVersion.ID,
+		GraderID:     teacherID,
+		Text:         text,
+		Score:        score,
+		UpdatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		log.Error("failed to save draft feedback", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("draft feedback saved")
+
+	return nil
+}
+
+// ReturnSubmission publishes the feedback on the current version of the submission
+// and sets its status: graded, or returned to the student for rework.
+// The empty text and the nil score are taken from the draft feedback, if any.
+// While the moderation of the assignment is not completed, the feedback
+// is not published, and the moderated score cannot be changed.
+func (s *GradingService) ReturnSubmission(
+	ctx context.Context,
+	versionID string,
+	teacherID int64,
+	status models.SubmissionStatus,
+	text string,
+	score *float64,
+) error {
+	const op = "services.grading.ReturnSubmission"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("version_id", versionID),
+		slog.String("status", string(status)),
+	)
+
+	log.Debug("returning submission")
+
+	version, err := s.submissionProvider.SubmissionVersion(ctx, versionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrVersionNotFound) {
+			log.Warn("version not found", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, ErrVersionNotFound)
+		}
+
+		log.Error("failed to get version", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	submission, err := s.teacherSubmission(ctx, version.SubmissionID, teacherID)
+	if err != nil {
+		if errors.Is(err, ErrSubmissionNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrVersionNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if submission.SubmittedAt.IsZero() {
+		log.Warn("submission is not submitted")
+
+		return fmt.Errorf("%s: %w", op, ErrNotSubmitted)
+	}
+
+	if submission.CurrentVersion == nil || submission.CurrentVersion.ID != versionID {
+		log.Warn("version is not current")
+
+		return fmt.Errorf("%s: %w", op, ErrNotCurrentVersion)
+	}
+
+	moderation, moderated, err := s.moderation(ctx, submission.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if moderated {
+		if !moderation.Completed() {
+			log.Warn("moderation is not completed")
+
+			return fmt.Errorf("%s: %w", op, ErrModerationPending)
+		}
+
+		item, ok := moderation.Item(submission.ID, versionID)
+		if ok && score != nil && (item.Score() == nil || *item.Score() != *score) {
+			log.Warn("moderated score is changed")
+
+			return fmt.Errorf("%s: %w", op, ErrModerated)
+		}
+	}
+
+	err = s.gradingSaver.PublishFeedback(ctx, models.Feedback{
+		SubmissionID: submission.ID,
+		VersionID:    versionID,
+		GraderID:     teacherID,
+		Text:         text,
+		Score:        score,
+		PublishedAt:  time.Now().UTC(),
+	}, status)
+	if err != nil {
+		if errors.Is(err, storage.ErrSubmissionNotFound) {
+			log.Warn("submission not found", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, ErrVersionNotFound)
+		}
+
+		log.Error("failed to publish feedback", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("submission returned", slog.String("submission_id", submission.ID))
+
+	return nil
+}
+
+// RequestRegrade disputes the published grade of the submission of the student.
+// The grade can be disputed only within the regrade window after it is published,
+// and the submission can have only one open request.
+func (s *GradingService) RequestRegrade(
+	ctx context.Context,
+	submissionID string,
+	studentID int64,
+	reason string,
+) (models.RegradeRequest, error) {
+	const op = "services.grading.RequestRegrade"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
+	)
+
+	log.Debug("requesting regrade")
+
+	submission, err := s.submission(ctx, submissionID)
+	if err != nil {
+		return models.RegradeRequest{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if submission.StudentID != studentID {
+		log.Warn("submission belongs to another student")
+
+		return models.RegradeRequest{}, fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+	}
+
+	if !submission.Graded() {
+		log.Warn("submission is not graded", slog.String("status", string(submission.Status)))
+
+		return models.RegradeRequest{}, fmt.Errorf("%s: %w", op, ErrNotGraded)
+	}
+
+	feedback, err := s.gradingProvider.PublishedFeedback(ctx, submissionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrFeedbackNotFound) {
+			log.Warn("feedback not found", slog.Any("error", err))
+
+			return models.RegradeRequest{}, fmt.Errorf("%s: %w", op, ErrNotGraded)
+		}
+
+		log.Error("failed to get feedback", slog.Any("error", err))
+
+		return models.RegradeRequest{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now().UTC()
+	if now.After(feedback.PublishedAt.Add(s.regradeWindow)) {
+		log.Warn("regrade window has closed", slog.Time("published_at", feedback.PublishedAt))
+
+		return models.RegradeRequest{}, fmt.Errorf("%s: %w", op, ErrRegradeWindow)
+	}
+
+	request := models.RegradeRequest{
+		SubmissionID: submissionID,
+		StudentID:    studentID,
+		TeacherID:    submission.TeacherID,
+		Reason:       reason,
+		Status:       models.RegradeOpen,
+		OldScore:     feedback.Score,
+		CreatedAt:    now,
+	}
+
+	request.ID, err = s.gradingSaver.SaveRegradeRequest(ctx, request)
+	if err != nil {
+		if errors.Is(err, storage.ErrRegradeAlreadyOpen) {
+			log.Warn("regrade request is already open")
+
+			return models.RegradeRequest{}, fmt.Errorf("%s: %w", op, ErrRegradeAlreadyOpen)
+		}
+
+		log.Error("failed to save regrade request", slog.Any("error", err))
+
+		return models.RegradeRequest{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("regrade requested", slog.String("regrade_id", request.ID))
+
+	return request, nil
+}
+
+// ResolveRegrade accepts or rejects the open regrade request to the teacher.
+// The accepted request can change the score of the published feedback,
+// unless the score is moderated by the head teacher.
+func (s *GradingService) ResolveRegrade(
+	ctx context.Context,
+	requestID string,
+	teacherID int64,
+	status models.RegradeStatus,
+	resolution string,
+	newScore *float64,
+) (models.RegradeRequest, error) {
+	const op = "services.grading.ResolveRegrade"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("regrade_id", requestID),
+		slog.String("status", string(status)),
+	)
+
+	log.Debug("resolving regrade")
+
+	request, err := s.gradingProvider.RegradeRequest(ctx, requestID)
+	if err != nil {
+		if errors.Is(err, storage.ErrRegradeNotFound) {
+			log.Warn("regrade request not found", slog.Any("error", err))
+
+			return models.RegradeRequest{}, fmt.Errorf("%s: %w", op, ErrRegradeNotFound)
+		}
+
+		log.Error("failed to get regrade request", slog.Any("error", err))
+
+		return models.RegradeRequest{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if request.TeacherID != teacherID {
+		log.Warn("regrade request belongs to another teacher")
+
+		return models.RegradeRequest{}, fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	}
+
+	if request.Status != models.RegradeOpen {
+		log.Warn("regrade request is already resolved")
+
+		return models.RegradeRequest{}, fmt.Errorf("%s: %w", op, ErrRegradeResolved)
+	}
+
+	if newScore != nil {
+		if err := s.checkModeratedScore(ctx, request.SubmissionID, *newScore); err != nil {
+			return models.RegradeRequest{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	request.Status = status
+	request.Resolution = resolution
+	request.NewScore = newScore
+	request.ResolvedAt = time.Now().UTC()
+
+	if err := s.gradingSaver.ResolveRegrade(ctx, request); err != nil {
+		if errors.Is(err, storage.ErrRegradeNotFound) {
+			log.Warn("regrade request is already resolved", slog.Any("error", err))
+
+			return models.RegradeRequest{}, fmt.Errorf("%s: %w", op, ErrRegradeResolved)
+		}
+
+		log.Error("failed to resolve regrade request", slog.Any("error", err))
+
+		return models.RegradeRequest{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("regrade resolved")
+
+	return request, nil
+}
+
+// OpenRegrades returns the page of the open regrade requests to the teacher.
+func (s *GradingService) OpenRegrades(
+	ctx context.Context,
+	teacherID int64,
+	filter models.Filter,
+) ([]models.RegradeRequest, error) {
+	const op = "services.grading.OpenRegrades"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Debug("fetching open regrade requests")
+
+	requests, err := s.gradingProvider.OpenRegrades(ctx, teacherID, filter)
+	if err != nil {
+		log.Error("failed to get open regrade requests", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return requests, nil
+}
+
+// History returns the history of the grading of the submission
+// to its student or to the teacher of the assignment.
+func (s *GradingService) History(
+	ctx context.Context,
+	submissionID string,
+	userID int64,
+) ([]models.SubmissionEvent, error) {
+	const op = "services.grading.History"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
+	)
+
+	log.Debug("fetching submission history")
+
+	submission, err := s.submission(ctx, submissionID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if submission.StudentID != userID && submission.TeacherID != userID {
+		log.Warn("user does not participate in submission")
+
+		return nil, fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+	}
+
+	events, err := s.gradingProvider.SubmissionEvents(ctx, submissionID)
+	if err != nil {
+		log.Error("failed to get submission history", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
+
+// teacherSubmission returns the submission of the teacher's assignment.
+func (s *GradingService) teacherSubmission(
+	ctx context.Context,
+	submissionID string,
+	teacherID int64,
+) (models.Submission, error) {
+	const op = "services.grading.teacherSubmission"
+
+	submission, err := s.submission(ctx, submissionID)
+	if err != nil {
+		return models.Submission{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if submission.TeacherID != teacherID {
+		s.log.Warn(
+			"submission belongs to another teacher",
+			slog.String("op", op),
+			slog.String("submission_id", submissionID),
+		)
+
+		return models.Submission{}, fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	}
+
+	return submission, nil
+}
+
+// moderation returns the moderation of the assignment of the submission.
+// If the assignment is not moderated, it returns false.
+func (s *GradingService) moderation(
+	ctx context.Context,
+	submissionID string,
+) (models.Moderation, bool, error) {
+	const op = "services.grading.moderation"
+
+	moderation, err := s.moderationProvider.SubmissionModeration(ctx, submissionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrModerationNotFound) {
+			return models.Moderation{}, false, nil
+		}
+
+		s.log.Error(
+			"failed to get moderation",
+			slog.String("op", op),
+			slog.String("submission_id", submissionID),
+			slog.Any("error", err),
+		)
+
+		return models.Moderation{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return moderation, true, nil
+}
+
+// checkModeratedScore returns ErrModerated if the published feedback
+// on the submission is moderated with another score.
+func (s *GradingService) checkModeratedScore(
+	ctx context.Context,
+	submissionID string,
+	score float64,
+) error {
+	const op = "services.grading.checkModeratedScore"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
+	)
+
+	moderation, moderated, err := s.moderation(ctx, submissionID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !moderated {
+		return nil
+	}
+
+	feedback, err := s.gradingProvider.PublishedFeedback(ctx, submissionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrFeedbackNotFound) {
+			log.Warn("feedback not found", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, ErrNotGraded)
+		}
+
+		log.Error("failed to get feedback", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	item, ok := moderation.Item(submissionID, feedback.VersionID)
+	if ok && (item.Score() == nil || *item.Score() != score) {
+		log.Warn("moderated score is changed")
+
+		return fmt.Errorf("%s: %w", op, ErrModerated)
+	}
+
+	return nil
+}
+
+// submission returns the submission which is not in the trash.
+func (s *GradingService) submission(
+	ctx context.Context,
+	submissionID string,
+) (models.Submission, error) {
+	const op = "services.grading.submission"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
+	)
+
+	submission, err := s.submissionProvider.Submission(ctx, submissionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSubmissionNotFound) {
+			log.Warn("submission not found", slog.Any("error", err))
+
+			return models.Submission{}, fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+		}
+
+		log.Error("failed to get submission", slog.Any("error", err))
+
+		return models.Submission{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if submission.Deleted() {
+		log.Warn("submission is in the trash")
+
+		return models.Submission{}, fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+	}
+
+	return submission, nil
+}
@@ -0,0 +1,444 @@
+package grading
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const regradeWindow = 7 * 24 * time.Hour
+
+// fakeStorage keeps the grading of the submissions in memory.
+// The methods the tests do not call are left to the embedded interface.
+type fakeStorage struct {
+	GradingProvider
+
+	submissions map[string]models.Submission
+	versions    map[string]models.SubmissionVersion
+	// feedbacks are the published feedbacks by the submission.
+	feedbacks   map[string]models.Feedback
+	drafts      map[string]models.Feedback
+	regrades    map[string]models.RegradeRequest
+	moderations map[string]models.Moderation
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{
+		submissions: make(map[string]models.Submission),
+		versions:    make(map[string]models.SubmissionVersion),
+		feedbacks:   make(map[string]models.Feedback),
+		drafts:      make(map[string]models.Feedback),
+		regrades:    make(map[string]models.RegradeRequest),
+		moderations: make(map[string]models.Moderation),
+	}
+}
+
+func (f *fakeStorage) SaveDraftFeedback(_ context.Context, feedback models.Feedback) error {
+	f.drafts[feedback.SubmissionID] = feedback
+	return nil
+}
+
+func (f *fakeStorage) PublishFeedback(
+	_ context.Context,
+	feedback models.Feedback,
+	status models.SubmissionStatus,
+) error {
+	submission, ok := f.submissions[feedback.SubmissionID]
+	if !ok {
+		return storage.ErrSubmissionNotFound
+	}
+	submission.Status = status
+	f.submissions[feedback.SubmissionID] = submission
+
+	feedback.Published = true
+	f.feedbacks[feedback.SubmissionID] = feedback
+	return nil
+}
+
+func (f *fakeStorage) SaveRegradeRequest(_ context.Context, request models.RegradeRequest) (string, error) {
+	for _, open := range f.regrades {
+		if open.SubmissionID == request.SubmissionID && open.Status == models.RegradeOpen {
+			return "", storage.ErrRegradeAlreadyOpen
+		}
+	}
+	request.ID = "regrade-" + request.SubmissionID
+	f.regrades[request.ID] = request
+	return request.ID, nil
+}
+
+func (f *fakeStorage) ResolveRegrade(_ context.Context, request models.RegradeRequest) error {
+	if f.regrades[request.ID].Status != models.RegradeOpen {
+		return storage.ErrRegradeNotFound
+	}
+	f.regrades[request.ID] = request
+
+	if request.NewScore != nil {
+		feedback := f.feedbacks[request.SubmissionID]
+		feedback.Score = request.NewScore
+		f.feedbacks[request.SubmissionID] = feedback
+	}
+	return nil
+}
+
+func (f *fakeStorage) PublishedFeedback(_ context.Context, submissionID string) (models.Feedback, error) {
+	feedback, ok := f.feedbacks[submissionID]
+	if !ok {
+		return models.Feedback{}, storage.ErrFeedbackNotFound
+	}
+	return feedback, nil
+}
+
+func (f *fakeStorage) RegradeRequest(_ context.Context, requestID string) (models.RegradeRequest, error) {
+	request, ok := f.regrades[requestID]
+	if !ok {
+		return models.RegradeRequest{}, storage.ErrRegradeNotFound
+	}
+	return request, nil
+}
+
+func (f *fakeStorage) Submission(_ context.Context, submissionID string) (models.Submission, error) {
+	submission, ok := f.submissions[submissionID]
+	if !ok {
+		return models.Submission{}, storage.ErrSubmissionNotFound
+	}
+	return submission, nil
+}
+
+func (f *fakeStorage) SubmissionVersion(_ context.Context, versionID string) (models.SubmissionVersion, error) {
+	version, ok := f.versions[versionID]
+	if !ok {
+		return models.SubmissionVersion{}, storage.ErrVersionNotFound
+	}
+	return version, nil
+}
+
+func (f *fakeStorage) SubmissionModeration(_ context.Context, submissionID string) (models.Moderation, error) {
+	moderation, ok := f.moderations[submissionID]
+	if !ok {
+		return models.Moderation{}, storage.ErrModerationNotFound
+	}
+	return moderation, nil
+}
+
+// addSubmission adds the submitted submission of the student 1
+// to the assignment of the teacher 10.
+func (f *fakeStorage) addSubmission(id string, status models.SubmissionStatus) {
+	version := models.SubmissionVersion{ID: id + "-v1", SubmissionID: id, Number: 1}
+
+	f.versions[version.ID] = version
+	f.submissions[id] = models.Submission{
+		ID:             id,
+		StudentID:      1,
+		TeacherID:      10,
+		Status:         status,
+		CurrentVersion: &version,
+		SubmittedAt:    time.Now().UTC().Add(-48 * time.Hour),
+	}
+}
+
+// publish publishes the feedback with the score on the submission at the given time.
+func (f *fakeStorage) publish(submissionID string, score float64, publishedAt time.Time) {
+	f.feedbacks[submissionID] = models.Feedback{
+		SubmissionID: submissionID,
+		VersionID:    submissionID + "-v1",
+		GraderID:     10,
+		Score:        &score,
+		Published:    true,
+		PublishedAt:  publishedAt,
+	}
+}
+
+func newTestService(st *fakeStorage) *GradingService {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return New(log, st, st, st, st, regradeWindow)
+}
+
+func score(v float64) *float64 {
+	return &v
+}
+
+func TestRequestRegrade_Window(t *testing.T) {
+	now := time.Now().UTC()
+
+	st := newFakeStorage()
+	st.addSubmission("recent", models.StatusGraded)
+	st.publish("recent", 70, now.Add(-time.Hour))
+	st.addSubmission("closing", models.StatusGraded)
+	st.publish("closing", 70, now.Add(-regradeWindow+time.Minute))
+	st.addSubmission("expired", models.StatusGraded)
+	st.publish("expired", 70, now.Add(-regradeWindow-time.Minute))
+
+	s := newTestService(st)
+	ctx := context.Background()
+
+	t.Run("within window", func(t *testing.T) {
+		for _, id := range []string{"recent", "closing"} {
+			request, err := s.RequestRegrade(ctx, id, 1, "the second task is solved")
+			require.NoError(t, err, id)
+			assert.Equal(t, models.RegradeOpen, request.Status)
+			assert.Equal(t, int64(10), request.TeacherID)
+			require.NotNil(t, request.OldScore)
+			assert.InDelta(t, 70.0, *request.OldScore, 0.001)
+		}
+	})
+
+	t.Run("window has closed", func(t *testing.T) {
+		_, err := s.RequestRegrade(ctx, "expired", 1, "too late")
+		assert.ErrorIs(t, err, ErrRegradeWindow)
+	})
+
+	t.Run("window starts at the latest publish", func(t *testing.T) {
+		st.publish("expired", 60, now.Add(-time.Minute))
+
+		request, err := s.RequestRegrade(ctx, "expired", 1, "regraded")
+		require.NoError(t, err)
+		assert.InDelta(t, 60.0, *request.OldScore, 0.001)
+	})
+
+	t.Run("only one open request", func(t *testing.T) {
+		_, err := s.RequestRegrade(ctx, "recent", 1, "once more")
+		assert.ErrorIs(t, err, ErrRegradeAlreadyOpen)
+	})
+}
+
+func TestRequestRegrade_Status(t *testing.T) {
+	now := time.Now().UTC()
+
+	st := newFakeStorage()
+	st.addSubmission("submitted", models.StatusSubmitted)
+	st.addSubmission("in-progress", models.StatusInProgress)
+	st.addSubmission("returned", models.StatusReturned)
+	st.publish("returned", 40, now.Add(-time.Hour))
+	st.addSubmission("unpublished", models.StatusGraded)
+	st.addSubmission("deleted", models.StatusGraded)
+	st.publish("deleted", 40, now.Add(-time.Hour))
+
+	deleted := st.submissions["deleted"]
+	deleted.DeletedAt = now
+	st.submissions["deleted"] = deleted
+
+	s := newTestService(st)
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		submissionID string
+		studentID    int64
+		wantErr      error
+	}{
+		{name: "submitted", submissionID: "submitted", studentID: 1, wantErr: ErrNotGraded},
+		{name: "in progress", submissionID: "in-progress", studentID: 1, wantErr: ErrNotGraded},
+		{name: "graded without feedback", submissionID: "unpublished", studentID: 1, wantErr: ErrNotGraded},
+		{name: "in trash", submissionID: "deleted", studentID: 1, wantErr: ErrSubmissionNotFound},
+		{name: "another student", submissionID: "returned", studentID: 2, wantErr: ErrSubmissionNotFound},
+		{name: "unknown", submissionID: "unknown", studentID: 1, wantErr: ErrSubmissionNotFound},
+		{name: "returned", submissionID: "returned", studentID: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.RequestRegrade(ctx, tt.submissionID, tt.studentID, "reason")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestResolveRegrade(t *testing.T) {
+	now := time.Now().UTC()
+
+	st := newFakeStorage()
+	st.addSubmission("submission", models.StatusGraded)
+	st.publish("submission", 70, now.Add(-time.Hour))
+
+	s := newTestService(st)
+	ctx := context.Background()
+
+	request, err := s.RequestRegrade(ctx, "submission", 1, "the second task is solved")
+	require.NoError(t, err)
+
+	t.Run("another teacher", func(t *testing.T) {
+		_, err := s.ResolveRegrade(ctx, request.ID, 11, models.RegradeAccepted, "", score(90))
+		assert.ErrorIs(t, err, ErrAccessDenied)
+	})
+
+	t.Run("accepted request changes score", func(t *testing.T) {
+		resolved, err := s.ResolveRegrade(ctx, request.ID, 10, models.RegradeAccepted, "indeed", score(90))
+		require.NoError(t, err)
+		assert.Equal(t, models.RegradeAccepted, resolved.Status)
+		assert.False(t, resolved.ResolvedAt.IsZero())
+		assert.InDelta(t, 90.0, *st.feedbacks["submission"].Score, 0.001)
+	})
+
+	t.Run("resolved request", func(t *testing.T) {
+		_, err := s.ResolveRegrade(ctx, request.ID, 10, models.RegradeRejected, "no", nil)
+		assert.ErrorIs(t, err, ErrRegradeResolved)
+	})
+
+	t.Run("unknown request", func(t *testing.T) {
+		_, err := s.ResolveRegrade(ctx, "unknown", 10, models.RegradeRejected, "no", nil)
+		assert.ErrorIs(t, err, ErrRegradeNotFound)
+	})
+}
+
+// moderate adds the submission to the sample of the moderation
+// with the decision of the head teacher.
+func (f *fakeStorage) moderate(submissionID string, original float64, adjusted *float64, completed bool) {
+	item := models.ModerationItem{
+		SubmissionID:  submissionID,
+		VersionID:     submissionID + "-v1",
+		OriginalScore: &original,
+		Decision:      models.ModerationPending,
+	}
+	if completed {
+		item.Decision = models.ModerationAgreed
+		if adjusted != nil {
+			item.Decision = models.ModerationAdjusted
+			item.AdjustedScore = adjusted
+		}
+	}
+
+	moderation := models.Moderation{AssignmentID: "assignment", Items: []models.ModerationItem{item}}
+	if completed {
+		moderation.CompletedAt = time.Now().UTC()
+	}
+
+	f.moderations[submissionID] = moderation
+}
+
+func TestProvideFeedback_Moderated(t *testing.T) {
+	st := newFakeStorage()
+	st.addSubmission("sampled", models.StatusSubmitted)
+	st.moderate("sampled", 70, nil, false)
+	st.addSubmission("free", models.StatusSubmitted)
+
+	s := newTestService(st)
+	ctx := context.Background()
+
+	err := s.ProvideFeedback(ctx, "sampled", 10, "changed", score(80))
+	assert.ErrorIs(t, err, ErrModerated)
+	assert.NotContains(t, st.drafts, "sampled")
+
+	require.NoError(t, s.ProvideFeedback(ctx, "free", 10, "good", score(80)))
+	assert.Contains(t, st.drafts, "free")
+}
+
+func TestReturnSubmission_Moderation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("moderation is not completed", func(t *testing.T) {
+		st := newFakeStorage()
+		st.addSubmission("sampled", models.StatusSubmitted)
+		st.moderate("sampled", 70, nil, false)
+
+		err := newTestService(st).ReturnSubmission(ctx, "sampled-v1", 10, models.StatusGraded, "", score(70))
+		assert.ErrorIs(t, err, ErrModerationPending)
+		assert.NotContains(t, st.feedbacks, "sampled")
+	})
+
+	t.Run("published once moderation is completed", func(t *testing.T) {
+		st := newFakeStorage()
+		st.addSubmission("sampled", models.StatusSubmitted)
+		st.moderate("sampled", 70, nil, false)
+		st.addSubmission("free", models.StatusSubmitted)
+		st.moderations["free"] = st.moderations["sampled"]
+
+		s := newTestService(st)
+
+		for _, id := range []string{"sampled", "free"} {
+			err := s.ReturnSubmission(ctx, id+"-v1", 10, models.StatusGraded, "", score(70))
+			assert.ErrorIs(t, err, ErrModerationPending, "feedback on the whole assignment waits")
+		}
+		assert.Empty(t, st.feedbacks)
+
+		st.moderate("sampled", 70, nil, true)
+		st.moderations["free"] = st.moderations["sampled"]
+
+		for _, id := range []string{"sampled", "free"} {
+			require.NoError(t, s.ReturnSubmission(ctx, id+"-v1", 10, models.StatusGraded, "", score(70)))
+			assert.Equal(t, models.StatusGraded, st.submissions[id].Status)
+			assert.Contains(t, st.feedbacks, id)
+		}
+	})
+
+	t.Run("adjusted score cannot be changed", func(t *testing.T) {
+		st := newFakeStorage()
+		st.addSubmission("sampled", models.StatusSubmitted)
+		st.moderate("sampled", 70, score(60), true)
+
+		s := newTestService(st)
+
+		err := s.ReturnSubmission(ctx, "sampled-v1", 10, models.StatusGraded, "", score(70))
+		assert.ErrorIs(t, err, ErrModerated)
+
+		require.NoError(t, s.ReturnSubmission(ctx, "sampled-v1", 10, models.StatusGraded, "", score(60)))
+		assert.Equal(t, models.StatusGraded, st.submissions["sampled"].Status)
+	})
+
+	t.Run("score of submission out of sample", func(t *testing.T) {
+		st := newFakeStorage()
+		st.addSubmission("sampled", models.StatusSubmitted)
+		st.moderate("sampled", 70, nil, true)
+		st.addSubmission("free", models.StatusSubmitted)
+		st.moderations["free"] = st.moderations["sampled"]
+
+		err := newTestService(st).ReturnSubmission(ctx, "free-v1", 10, models.StatusGraded, "", score(95))
+		require.NoError(t, err)
+		assert.InDelta(t, 95.0, *st.feedbacks["free"].Score, 0.001)
+	})
+}
+
+func TestResolveRegrade_Moderated(t *testing.T) {
+	now := time.Now().UTC()
+	ctx := context.Background()
+
+	newModerated := func() (*GradingService, *fakeStorage, string) {
+		st := newFakeStorage()
+		st.addSubmission("sampled", models.StatusGraded)
+		st.publish("sampled", 60, now.Add(-time.Hour))
+		st.moderate("sampled", 70, score(60), true)
+
+		s := newTestService(st)
+
+		request, err := s.RequestRegrade(ctx, "sampled", 1, "the second task is solved")
+		require.NoError(t, err)
+
+		return s, st, request.ID
+	}
+
+	t.Run("moderated score cannot be changed", func(t *testing.T) {
+		s, st, requestID := newModerated()
+
+		_, err := s.ResolveRegrade(ctx, requestID, 10, models.RegradeAccepted, "indeed", score(90))
+		assert.ErrorIs(t, err, ErrModerated)
+		assert.Equal(t, models.RegradeOpen, st.regrades[requestID].Status)
+		assert.InDelta(t, 60.0, *st.feedbacks["sampled"].Score, 0.001)
+	})
+
+	t.Run("moderated score is kept", func(t *testing.T) {
+		s, _, requestID := newModerated()
+
+		_, err := s.ResolveRegrade(ctx, requestID, 10, models.RegradeAccepted, "same", score(60))
+		assert.NoError(t, err)
+	})
+
+	t.Run("request can be rejected", func(t *testing.T) {
+		s, st, requestID := newModerated()
+
+		_, err := s.ResolveRegrade(ctx, requestID, 10, models.RegradeRejected, "moderated", nil)
+		require.NoError(t, err)
+		assert.Equal(t, models.RegradeRejected, st.regrades[requestID].Status)
+	})
+}
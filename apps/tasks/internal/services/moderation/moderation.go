@@ -0,0 +1,348 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+)
+
+type ModerationService struct {
+	log                *slog.Logger
+	moderationSaver    ModerationSaver
+	moderationProvider ModerationProvider
+	assignmentProvider AssignmentProvider
+}
+
+type ModerationSaver interface {
+	SaveModeration(ctx context.Context, moderation models.Moderation) error
+	RecordModeration(ctx context.Context, item models.ModerationItem) error
+}
+
+type ModerationProvider interface {
+	GradedSubmissions(ctx context.Context, assignmentID string) ([]models.ModerationItem, error)
+	Moderation(ctx context.Context, assignmentID string) (models.Moderation, error)
+	PendingModerations(
+		ctx context.Context,
+		moderatorID int64,
+		filter models.Filter,
+	) ([]models.Moderation, error)
+	ModerationItem(ctx context.Context, itemID string) (models.ModerationItem, error)
+}
+
+type AssignmentProvider interface {
+	Assignment(ctx context.Context, assignmentID string) (models.Assignment, error)
+}
+
+var (
+	ErrAssignmentNotFound      = storage.ErrAssignmentNotFound
+	ErrModerationNotFound      = storage.ErrModerationNotFound
+	ErrModerationAlreadyExists = storage.ErrModerationAlreadyExists
+	ErrItemNotFound            = storage.ErrModerationItemNotFound
+	ErrAccessDenied            = errors.New("access to assignment denied")
+	ErrSelfModeration          = errors.New("teacher cannot moderate own grades")
+	ErrNothingToModerate       = errors.New("assignment has no graded submissions")
+	ErrAlreadyModerated        = errors.New("moderation item is already moderated")
+)
+
+// New returns a new instance of ModerationService.
+func New(
+	log *slog.Logger,
+	moderationSaver ModerationSaver,
+	moderationProvider ModerationProvider,
+	assignmentProvider AssignmentProvider,
+) *ModerationService {
+	return &ModerationService{
+		log:                log,
+		moderationSaver:    moderationSaver,
+		moderationProvider: moderationProvider,
+		assignmentProvider: assignmentProvider,
+	}
+}
+
+// RequestModeration assigns the random sample of the graded submissions
+// of the teacher's assignment to the head teacher. The submission is graded
+// if the teacher has scored its current version in the draft feedback.
+// The feedback on the assignment is not published until the moderation is completed.
+func (s *ModerationService) RequestModeration(
+	ctx context.Context,
+	assignmentID string,
+	teacherID int64,
+	moderatorID int64,
+	sampleSize int,
+) (models.Moderation, error) {
+	const op = "services.moderation.RequestModeration"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
+	)
+
+	log.Debug("requesting moderation")
+
+	if _, err := s.assignment(ctx, assignmentID, teacherID); err != nil {
+		return models.Moderation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if moderatorID == teacherID {
+		log.Warn("teacher cannot moderate own grades")
+
+		return models.Moderation{}, fmt.Errorf("%s: %w", op, ErrSelfModeration)
+	}
+
+	items, err := s.moderationProvider.GradedSubmissions(ctx, assignmentID)
+	if err != nil {
+		log.Error("failed to get graded submissions", slog.Any("error", err))
+
+		return models.Moderation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(items) == 0 {
+		log.Warn("assignment has no graded submissions")
+
+		return models.Moderation{}, fmt.Errorf("%s: %w", op, ErrNothingToModerate)
+	}
+
+	err = s.moderationSaver.SaveModeration(ctx, models.Moderation{
+		AssignmentID: assignmentID,
+		RequestedBy:  teacherID,
+		ModeratorID:  moderatorID,
+		Items:        sample(items, sampleSize, rand.Shuffle),
+		CreatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrModerationAlreadyExists) {
+			log.Warn("moderation already exists")
+
+			return models.Moderation{}, fmt.Errorf("%s: %w", op, ErrModerationAlreadyExists)
+		}
+
+		log.Error("failed to save moderation", slog.Any("error", err))
+
+		return models.Moderation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	moderation, err := s.moderationProvider.Moderation(ctx, assignmentID)
+	if err != nil {
+		log.Error("failed to get moderation", slog.Any("error", err))
+
+		return models.Moderation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info(
+		"moderation requested",
+		slog.Int64("moderator_id", moderatorID),
+		slog.Int("sample", len(moderation.Items)),
+	)
+
+	return moderation, nil
+}
+
+// Moderation returns the moderation of the assignment
+// to the teacher of the assignment or to the moderator.
+func (s *ModerationService) Moderation(
+	ctx context.Context,
+	assignmentID string,
+	userID int64,
+) (models.Moderation, error) {
+	const op = "services.moderation.Moderation"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
+	)
+
+	log.Debug("fetching moderation")
+
+	moderation, err := s.moderationProvider.Moderation(ctx, assignmentID)
+	if err != nil {
+		if errors.Is(err, storage.ErrModerationNotFound) {
+			log.Warn("moderation not found", slog.Any("error", err))
+
+			return models.Moderation{}, fmt.Errorf("%s: %w", op, ErrModerationNotFound)
+		}
+
+		log.Error("failed to get moderation", slog.Any("error", err))
+
+		return models.Moderation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if moderation.ModeratorID == userID {
+		return moderation, nil
+	}
+
+	if _, err := s.assignment(ctx, assignmentID, userID); err != nil {
+		return models.Moderation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return moderation, nil
+}
+
+// PendingModerations returns the page of the moderations
+// which wait for the decisions of the moderator.
+func (s *ModerationService) PendingModerations(
+	ctx context.Context,
+	moderatorID int64,
+	filter models.Filter,
+) ([]models.Moderation, error) {
+	const op = "services.moderation.PendingModerations"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Debug("fetching pending moderations")
+
+	moderations, err := s.moderationProvider.PendingModerations(ctx, moderatorID, filter)
+	if err != nil {
+		log.Error("failed to get pending moderations", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return moderations, nil
+}
+
+// RecordDecision records the agreement of the moderator with the grade of the teacher
+// or the adjusted score which replaces it, and returns the updated moderation.
+// The decision on the item cannot be changed.
+func (s *ModerationService) RecordDecision(
+	ctx context.Context,
+	itemID string,
+	moderatorID int64,
+	decision models.ModerationDecision,
+	adjustedScore *float64,
+	comment string,
+) (models.Moderation, error) {
+	const op = "services.moderation.RecordDecision"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("item_id", itemID),
+		slog.String("decision", string(decision)),
+	)
+
+	log.Debug("recording moderation decision")
+
+	item, err := s.moderationProvider.ModerationItem(ctx, itemID)
+	if err != nil {
+		if errors.Is(err, storage.ErrModerationItemNotFound) {
+			log.Warn("moderation item not found", slog.Any("error", err))
+
+			return models.Moderation{}, fmt.Errorf("%s: %w", op, ErrItemNotFound)
+		}
+
+		log.Error("failed to get moderation item", slog.Any("error", err))
+
+		return models.Moderation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	moderation, err := s.moderationProvider.Moderation(ctx, item.AssignmentID)
+	if err != nil {
+		log.Error("failed to get moderation", slog.Any("error", err))
+
+		return models.Moderation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if moderation.ModeratorID != moderatorID {
+		log.Warn("moderation is assigned to another moderator")
+
+		return models.Moderation{}, fmt.Errorf("%s: %w", op, ErrItemNotFound)
+	}
+
+	if item.Decision != models.ModerationPending {
+		log.Warn("moderation item is already moderated")
+
+		return models.Moderation{}, fmt.Errorf("%s: %w", op, ErrAlreadyModerated)
+	}
+
+	if decision != models.ModerationAdjusted {
+		adjustedScore = nil
+	}
+
+	item.Decision = decision
+	item.AdjustedScore = adjustedScore
+	item.Comment = comment
+	item.ModeratedAt = time.Now().UTC()
+
+	if err := s.moderationSaver.RecordModeration(ctx, item); err != nil {
+		if errors.Is(err, storage.ErrModerationItemNotFound) {
+			log.Warn("moderation item is already moderated", slog.Any("error", err))
+
+			return models.Moderation{}, fmt.Errorf("%s: %w", op, ErrAlreadyModerated)
+		}
+
+		log.Error("failed to record moderation decision", slog.Any("error", err))
+
+		return models.Moderation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	moderation, err = s.moderationProvider.Moderation(ctx, item.AssignmentID)
+	if err != nil {
+		log.Error("failed to get moderation", slog.Any("error", err))
+
+		return models.Moderation{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("moderation decision recorded", slog.Bool("completed", moderation.Completed()))
+
+	return moderation, nil
+}
+
+// sample returns size items chosen at random, or all the items if there are fewer.
+// Every item is equally likely to be chosen, as the items are shuffled first.
+func sample(
+	items []models.ModerationItem,
+	size int,
+	shuffle func(n int, swap func(i, j int)),
+) []models.ModerationItem {
+	shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
+
+	return items[:min(size, len(items))]
+}
+
+// assignment returns the teacher's assignment which is not in the trash.
+func (s *ModerationService) assignment(
+	ctx context.Context,
+	assignmentID string,
+	teacherID int64,
+) (models.Assignment, error) {
+	const op = "services.moderation.assignment"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
+	)
+
+	assignment, err := s.assignmentProvider.Assignment(ctx, assignmentID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAssignmentNotFound) {
+			log.Warn("assignment not found", slog.Any("error", err))
+
+			return models.Assignment{}, fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+		}
+
+		log.Error("failed to get assignment", slog.Any("error", err))
+
+		return models.Assignment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if assignment.Deleted() {
+		log.Warn("assignment is in the trash")
+
+		return models.Assignment{}, fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+	}
+
+	if assignment.CreatorID != teacherID {
+		log.Warn("assignment belongs to another teacher")
+
+		return models.Assignment{}, fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	}
+
+	return assignment, nil
+}
@@ -0,0 +1,311 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"testing"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	teacherID   = 10
+	moderatorID = 30
+)
+
+// fakeStorage keeps the moderation of the assignment "assignment" in memory
+// and records the decisions the way the postgres storage does.
+// The methods the tests do not call are left to the embedded interface.
+type fakeStorage struct {
+	ModerationProvider
+
+	graded     []models.ModerationItem
+	moderation *models.Moderation
+}
+
+func (f *fakeStorage) SaveModeration(_ context.Context, moderation models.Moderation) error {
+	if f.moderation != nil {
+		return storage.ErrModerationAlreadyExists
+	}
+
+	for i := range moderation.Items {
+		moderation.Items[i].ID = fmt.Sprintf("item-%d", i+1)
+		moderation.Items[i].AssignmentID = moderation.AssignmentID
+		moderation.Items[i].Decision = models.ModerationPending
+	}
+	f.moderation = &moderation
+
+	return nil
+}
+
+func (f *fakeStorage) RecordModeration(_ context.Context, item models.ModerationItem) error {
+	completed := true
+	for i := range f.moderation.Items {
+		if f.moderation.Items[i].ID == item.ID {
+			f.moderation.Items[i] = item
+		}
+		if f.moderation.Items[i].Decision == models.ModerationPending {
+			completed = false
+		}
+	}
+
+	if completed {
+		f.moderation.CompletedAt = item.ModeratedAt
+	}
+
+	return nil
+}
+
+func (f *fakeStorage) GradedSubmissions(_ context.Context, _ string) ([]models.ModerationItem, error) {
+	graded := make([]models.ModerationItem, len(f.graded))
+	copy(graded, f.graded)
+	return graded, nil
+}
+
+func (f *fakeStorage) Moderation(_ context.Context, _ string) (models.Moderation, error) {
+	if f.moderation == nil {
+		return models.Moderation{}, storage.ErrModerationNotFound
+	}
+
+	moderation := *f.moderation
+	moderation.Items = append([]models.ModerationItem(nil), f.moderation.Items...)
+
+	return moderation, nil
+}
+
+func (f *fakeStorage) ModerationItem(_ context.Context, itemID string) (models.ModerationItem, error) {
+	if f.moderation != nil {
+		for _, item := range f.moderation.Items {
+			if item.ID == itemID {
+				return item, nil
+			}
+		}
+	}
+
+	return models.ModerationItem{}, storage.ErrModerationItemNotFound
+}
+
+func (f *fakeStorage) Assignment(_ context.Context, assignmentID string) (models.Assignment, error) {
+	if assignmentID != "assignment" {
+		return models.Assignment{}, storage.ErrAssignmentNotFound
+	}
+	return models.Assignment{ID: assignmentID, CreatorID: teacherID}, nil
+}
+
+// newItems returns the n graded submissions scored by the teacher.
+func newItems(n int) []models.ModerationItem {
+	items := make([]models.ModerationItem, n)
+	for i := range items {
+		score := float64(50 + i)
+		items[i] = models.ModerationItem{
+			SubmissionID:  fmt.Sprintf("submission-%d", i),
+			VersionID:     fmt.Sprintf("submission-%d-v1", i),
+			OriginalScore: &score,
+		}
+	}
+
+	return items
+}
+
+func newTestService(graded int) (*ModerationService, *fakeStorage) {
+	st := &fakeStorage{graded: newItems(graded)}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return New(log, st, st, st), st
+}
+
+func score(v float64) *float64 {
+	return &v
+}
+
+func TestSample(t *testing.T) {
+	t.Run("size", func(t *testing.T) {
+		for _, tt := range []struct{ n, size, want int }{
+			{10, 3, 3},
+			{10, 10, 10},
+			{3, 5, 3},
+			{1, 1, 1},
+		} {
+			r := rand.New(rand.NewPCG(uint64(tt.n), uint64(tt.size)))
+
+			chosen := sample(newItems(tt.n), tt.size, r.Shuffle)
+			require.Len(t, chosen, tt.want)
+
+			seen := make(map[string]bool, len(chosen))
+			for _, item := range chosen {
+				assert.False(t, seen[item.SubmissionID], "submission is sampled once")
+				seen[item.SubmissionID] = true
+			}
+		}
+	})
+
+	t.Run("every submission is equally likely", func(t *testing.T) {
+		const (
+			n      = 10
+			size   = 3
+			rounds = 10000
+		)
+
+		r := rand.New(rand.NewPCG(1, 2))
+		counts := make(map[string]int, n)
+		for range rounds {
+			for _, item := range sample(newItems(n), size, r.Shuffle) {
+				counts[item.SubmissionID]++
+			}
+		}
+
+		require.Len(t, counts, n)
+		for id, count := range counts {
+			// every submission is sampled in 30% of the rounds
+			assert.InDelta(t, rounds*size/n, count, rounds*size/n/10, id)
+		}
+	})
+}
+
+func TestRequestModeration(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sample of graded submissions", func(t *testing.T) {
+		s, st := newTestService(10)
+
+		moderation, err := s.RequestModeration(ctx, "assignment", teacherID, moderatorID, 3)
+		require.NoError(t, err)
+		assert.Equal(t, int64(moderatorID), moderation.ModeratorID)
+		assert.Len(t, moderation.Items, 3)
+		assert.False(t, moderation.Completed())
+
+		graded := make(map[string]bool, len(st.graded))
+		for _, item := range st.graded {
+			graded[item.SubmissionID] = true
+		}
+		for _, item := range moderation.Items {
+			assert.True(t, graded[item.SubmissionID])
+			assert.Equal(t, models.ModerationPending, item.Decision)
+		}
+	})
+
+	t.Run("sample is not larger than graded submissions", func(t *testing.T) {
+		s, _ := newTestService(2)
+
+		moderation, err := s.RequestModeration(ctx, "assignment", teacherID, moderatorID, 5)
+		require.NoError(t, err)
+		assert.Len(t, moderation.Items, 2)
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		s, st := newTestService(3)
+
+		_, err := s.RequestModeration(ctx, "assignment", teacherID, teacherID, 2)
+		assert.ErrorIs(t, err, ErrSelfModeration)
+
+		_, err = s.RequestModeration(ctx, "assignment", 11, moderatorID, 2)
+		assert.ErrorIs(t, err, ErrAccessDenied)
+
+		_, err = s.RequestModeration(ctx, "unknown", teacherID, moderatorID, 2)
+		assert.ErrorIs(t, err, ErrAssignmentNotFound)
+
+		assert.Nil(t, st.moderation)
+
+		empty, _ := newTestService(0)
+		_, err = empty.RequestModeration(ctx, "assignment", teacherID, moderatorID, 2)
+		assert.ErrorIs(t, err, ErrNothingToModerate)
+	})
+
+	t.Run("requested once", func(t *testing.T) {
+		s, _ := newTestService(3)
+
+		_, err := s.RequestModeration(ctx, "assignment", teacherID, moderatorID, 2)
+		require.NoError(t, err)
+
+		_, err = s.RequestModeration(ctx, "assignment", teacherID, moderatorID, 2)
+		assert.ErrorIs(t, err, ErrModerationAlreadyExists)
+	})
+}
+
+func TestRecordDecision(t *testing.T) {
+	ctx := context.Background()
+
+	newModeration := func(t *testing.T) (*ModerationService, *fakeStorage, []models.ModerationItem) {
+		t.Helper()
+
+		s, st := newTestService(2)
+
+		moderation, err := s.RequestModeration(ctx, "assignment", teacherID, moderatorID, 2)
+		require.NoError(t, err)
+
+		return s, st, moderation.Items
+	}
+
+	t.Run("agreed and adjusted", func(t *testing.T) {
+		s, _, items := newModeration(t)
+
+		moderation, err := s.RecordDecision(ctx, items[0].ID, moderatorID, models.ModerationAgreed, score(10), "fair")
+		require.NoError(t, err)
+		assert.False(t, moderation.Completed(), "other item waits for the decision")
+
+		agreed, ok := moderation.Item(items[0].SubmissionID, items[0].VersionID)
+		require.True(t, ok)
+		assert.Equal(t, models.ModerationAgreed, agreed.Decision)
+		assert.Nil(t, agreed.AdjustedScore, "score is adjusted only by the adjusted decision")
+		assert.Equal(t, items[0].OriginalScore, agreed.Score())
+		assert.Equal(t, "fair", agreed.Comment)
+
+		moderation, err = s.RecordDecision(ctx, items[1].ID, moderatorID, models.ModerationAdjusted, score(42), "too high")
+		require.NoError(t, err)
+		assert.True(t, moderation.Completed(), "moderation is completed by the last decision")
+
+		adjusted, ok := moderation.Item(items[1].SubmissionID, items[1].VersionID)
+		require.True(t, ok)
+		assert.Equal(t, models.ModerationAdjusted, adjusted.Decision)
+		require.NotNil(t, adjusted.Score())
+		assert.InDelta(t, 42.0, *adjusted.Score(), 0.001)
+		assert.Equal(t, items[1].OriginalScore, adjusted.OriginalScore, "score of the teacher is kept")
+	})
+
+	t.Run("decision cannot be changed", func(t *testing.T) {
+		s, st, items := newModeration(t)
+
+		_, err := s.RecordDecision(ctx, items[0].ID, moderatorID, models.ModerationAgreed, nil, "")
+		require.NoError(t, err)
+
+		_, err = s.RecordDecision(ctx, items[0].ID, moderatorID, models.ModerationAdjusted, score(0), "")
+		assert.ErrorIs(t, err, ErrAlreadyModerated)
+		assert.Equal(t, models.ModerationAgreed, st.moderation.Items[0].Decision)
+	})
+
+	t.Run("moderator of another moderation", func(t *testing.T) {
+		s, st, items := newModeration(t)
+
+		_, err := s.RecordDecision(ctx, items[0].ID, teacherID, models.ModerationAdjusted, score(100), "")
+		assert.ErrorIs(t, err, ErrItemNotFound)
+
+		_, err = s.RecordDecision(ctx, "unknown", moderatorID, models.ModerationAgreed, nil, "")
+		assert.ErrorIs(t, err, ErrItemNotFound)
+
+		assert.Equal(t, models.ModerationPending, st.moderation.Items[0].Decision)
+	})
+}
+
+func TestModeration_Access(t *testing.T) {
+	s, _ := newTestService(2)
+	ctx := context.Background()
+
+	_, err := s.RequestModeration(ctx, "assignment", teacherID, moderatorID, 1)
+	require.NoError(t, err)
+
+	for _, userID := range []int64{teacherID, moderatorID} {
+		_, err := s.Moderation(ctx, "assignment", userID)
+		assert.NoError(t, err)
+	}
+
+	_, err = s.Moderation(ctx, "assignment", 11)
+	assert.ErrorIs(t, err, ErrAccessDenied)
+}
@@ -0,0 +1,506 @@
+package peerreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/lib/pseudonym"
+	"tasks/internal/storage"
+)
+
+// assignBatchSize limits the number of assignments handled in one run.
+const assignBatchSize = 10
+
+type PeerReviewService struct {
+	log                *slog.Logger
+	reviewSaver        PeerReviewSaver
+	reviewProvider     PeerReviewProvider
+	assignmentProvider AssignmentProvider
+	submissionProvider SubmissionProvider
+}
+
+type PeerReviewSaver interface {
+	SavePeerReviews(
+		ctx context.Context,
+		assignmentID string,
+		reviews []models.PeerReview,
+		assignedAt time.Time,
+	) (bool, error)
+	SubmitPeerReview(
+		ctx context.Context,
+		reviewID string,
+		scores []models.CriterionScore,
+		comment string,
+		submittedAt time.Time,
+	) error
+}
+
+type PeerReviewProvider interface {
+	PeerReviewsDue(ctx context.Context, now time.Time, limit int) ([]models.Assignment, error)
+	ReviewableSubmissions(ctx context.Context, assignmentID string) ([]models.Submission, error)
+	PeerReview(ctx context.Context, reviewID string) (models.PeerReview, error)
+	ReviewerReviews(
+		ctx context.Context,
+		reviewerID int64,
+		filter models.Filter,
+	) ([]models.PeerReview, error)
+	SubmissionReviews(ctx context.Context, submissionID string) ([]models.PeerReview, error)
+	AssignmentReviews(ctx context.Context, assignmentID string) ([]models.PeerReview, error)
+	ReviewedSubmissions(ctx context.Context, assignmentID string) ([]models.PeerReviewSummary, error)
+}
+
+type AssignmentProvider interface {
+	Assignment(ctx context.Context, assignmentID string) (models.Assignment, error)
+}
+
+type SubmissionProvider interface {
+	Submission(ctx context.Context, submissionID string) (models.Submission, error)
+}
+
+var (
+	ErrPeerReviewNotFound = storage.ErrPeerReviewNotFound
+	ErrAssignmentNotFound = storage.ErrAssignmentNotFound
+	ErrSubmissionNotFound = storage.ErrSubmissionNotFound
+	ErrAccessDenied       = errors.New("access to assignment denied")
+	ErrReviewClosed       = errors.New("peer review is closed")
+	ErrInvalidScores      = errors.New("scores do not match the criteria")
+)
+
+// New returns a new instance of PeerReviewService.
+func New(
+	log *slog.Logger,
+	reviewSaver PeerReviewSaver,
+	reviewProvider PeerReviewProvider,
+	assignmentProvider AssignmentProvider,
+	submissionProvider SubmissionProvider,
+) *PeerReviewService {
+	return &PeerReviewService{
+		log:                log,
+		reviewSaver:        reviewSaver,
+		reviewProvider:     reviewProvider,
+		assignmentProvider: assignmentProvider,
+		submissionProvider: submissionProvider,
+	}
+}
+
+// AssignReviewers assigns the reviewers to the submissions of the assignments
+// whose due date has passed. It is run periodically by the scheduler.
+//
+// Submissions submitted after the reviewers are assigned are not reviewed.
+func (s *PeerReviewService) AssignReviewers(ctx context.Context) error {
+	const op = "services.peerreview.AssignReviewers"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	for {
+		assignments, err := s.reviewProvider.PeerReviewsDue(ctx, time.Now().UTC(), assignBatchSize)
+		if err != nil {
+			log.Error("failed to get assignments", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, assignment := range assignments {
+			submissions, err := s.reviewProvider.ReviewableSubmissions(ctx, assignment.ID)
+			if err != nil {
+				log.Error("failed to get submissions", slog.Any("error", err))
+
+				return fmt.Errorf("%s: %w", op, err)
+			}
+
+			reviews := assignReviewers(assignment.ID, submissions, assignment.PeerReview.ReviewersCount, rand.Shuffle)
+
+			ok, err := s.reviewSaver.SavePeerReviews(ctx, assignment.ID, reviews, time.Now().UTC())
+			if err != nil {
+				log.Error("failed to save peer reviews", slog.Any("error", err))
+
+				return fmt.Errorf("%s: %w", op, err)
+			}
+
+			if ok {
+				log.Info(
+					"reviewers assigned",
+					slog.String("assignment_id", assignment.ID),
+					slog.Int("submissions", len(submissions)),
+					slog.Int("reviews", len(reviews)),
+				)
+			}
+		}
+
+		if len(assignments) < assignBatchSize {
+			return nil
+		}
+	}
+}
+
+// assignReviewers assigns every submission to k authors of the other submissions.
+//
+// Submissions are shuffled and arranged in a circle, and the author of every
+// submission reviews the k submissions following it. So every submission gets
+// exactly k reviewers, every author reviews exactly k submissions and nobody
+// reviews their own work. If there are not enough submissions, k is reduced.
+func assignReviewers(
+	assignmentID string,
+	submissions []models.Submission,
+	k int,
+	shuffle func(n int, swap func(i, j int)),
+) []models.PeerReview {
+	n := len(submissions)
+	k = min(k, n-1)
+	if k <= 0 {
+		return nil
+	}
+
+	order := make([]models.Submission, n)
+	copy(order, submissions)
+	shuffle(n, func(i, j int) { order[i], order[j] = order[j], order[i] })
+
+	reviews := make([]models.PeerReview, 0, n*k)
+	for i, reviewer := range order {
+		for d := 1; d <= k; d++ {
+			reviews = append(reviews, models.PeerReview{
+				AssignmentID: assignmentID,
+				SubmissionID: order[(i+d)%n].ID,
+				ReviewerID:   reviewer.StudentID,
+			})
+		}
+	}
+
+	return reviews
+}
+
+// ReviewerReviews returns the page of the reviews assigned to the student.
+func (s *PeerReviewService) ReviewerReviews(
+	ctx context.Context,
+	reviewerID int64,
+	filter models.Filter,
+) ([]models.PeerReview, error) {
+	const op = "services.peerreview.ReviewerReviews"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	reviews, err := s.reviewProvider.ReviewerReviews(ctx, reviewerID, filter)
+	if err != nil {
+		log.Error("failed to get peer reviews", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return reviews, nil
+}
+
+// PeerReview returns the review assigned to the student
+// together with the reviewed version of the submission.
+func (s *PeerReviewService) PeerReview(
+	ctx context.Context,
+	reviewID string,
+	reviewerID int64,
+) (models.PeerReview, models.SubmissionVersion, error) {
+	const op = "services.peerreview.PeerReview"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("review_id", reviewID),
+	)
+
+	review, err := s.review(ctx, reviewID, reviewerID)
+	if err != nil {
+		return models.PeerReview{}, models.SubmissionVersion{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	submission, err := s.submissionProvider.Submission(ctx, review.SubmissionID)
+	if err != nil {
+		log.Error("failed to get submission", slog.Any("error", err))
+
+		return models.PeerReview{}, models.SubmissionVersion{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if submission.CurrentVersion == nil {
+		log.Error("reviewed submission has no version")
+
+		return models.PeerReview{}, models.SubmissionVersion{}, fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+	}
+
+	return review, *submission.CurrentVersion, nil
+}
+
+// SubmitPeerReview saves the scores of the review by the criteria of the assignment.
+// Every criterion must be scored once, from zero to its maximal score.
+func (s *PeerReviewService) SubmitPeerReview(
+	ctx context.Context,
+	reviewID string,
+	reviewerID int64,
+	scores []models.CriterionScore,
+	comment string,
+) (models.PeerReview, error) {
+	const op = "services.peerreview.SubmitPeerReview"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("review_id", reviewID),
+	)
+
+	review, err := s.review(ctx, reviewID, reviewerID)
+	if err != nil {
+		return models.PeerReview{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now().UTC()
+
+	if review.Closed(now) {
+		log.Warn("peer review is closed")
+
+		return models.PeerReview{}, fmt.Errorf("%s: %w", op, ErrReviewClosed)
+	}
+
+	if !validScores(review.Criteria, scores) {
+		log.Warn("invalid scores")
+
+		return models.PeerReview{}, fmt.Errorf("%s: %w", op, ErrInvalidScores)
+	}
+
+	if err := s.reviewSaver.SubmitPeerReview(ctx, reviewID, scores, comment, now); err != nil {
+		log.Error("failed to submit peer review", slog.Any("error", err))
+
+		return models.PeerReview{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("peer review submitted")
+
+	review.Status = models.PeerReviewSubmitted
+	review.Scores = scores
+	review.Comment = comment
+	review.SubmittedAt = now
+
+	return review, nil
+}
+
+// ReceivedReviews returns the submitted reviews of the student's submission.
+// The reviewers are not disclosed.
+func (s *PeerReviewService) ReceivedReviews(
+	ctx context.Context,
+	submissionID string,
+	studentID int64,
+) ([]models.PeerReview, error) {
+	const op = "services.peerreview.ReceivedReviews"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
+	)
+
+	submission, err := s.submissionProvider.Submission(ctx, submissionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSubmissionNotFound) {
+			log.Warn("submission not found", slog.Any("error", err))
+
+			return nil, fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+		}
+
+		log.Error("failed to get submission", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if submission.StudentID != studentID || submission.Deleted() {
+		log.Warn("submission is not accessible to student")
+
+		return nil, fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+	}
+
+	reviews, err := s.reviewProvider.SubmissionReviews(ctx, submissionID)
+	if err != nil {
+		log.Error("failed to get peer reviews", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res := make([]models.PeerReview, 0, len(reviews))
+	for _, review := range reviews {
+		if review.Status != models.PeerReviewSubmitted {
+			continue
+		}
+
+		review.ReviewerID = 0
+		res = append(res, review)
+	}
+
+	return res, nil
+}
+
+// Summaries returns the aggregated peer scores of the submissions
+// of the teacher's assignment together with the teacher's feedback.
+func (s *PeerReviewService) Summaries(
+	ctx context.Context,
+	assignmentID string,
+	teacherID int64,
+) ([]models.PeerReviewSummary, error) {
+	const op = "services.peerreview.Summaries"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
+	)
+
+	assignment, err := s.assignmentProvider.Assignment(ctx, assignmentID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAssignmentNotFound) {
+			log.Warn("assignment not found", slog.Any("error", err))
+
+			return nil, fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+		}
+
+		log.Error("failed to get assignment", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if assignment.Deleted() {
+		log.Warn("assignment is in the trash")
+
+		return nil, fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+	}
+
+	if assignment.CreatorID != teacherID {
+		log.Warn("assignment belongs to another teacher")
+
+		return nil, fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	}
+
+	summaries, err := s.reviewProvider.ReviewedSubmissions(ctx, assignmentID)
+	if err != nil {
+		log.Error("failed to get submissions", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	reviews, err := s.reviewProvider.AssignmentReviews(ctx, assignmentID)
+	if err != nil {
+		log.Error("failed to get peer reviews", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	bySubmission := make(map[string][]models.PeerReview)
+	for _, review := range reviews {
+		bySubmission[review.SubmissionID] = append(bySubmission[review.SubmissionID], review)
+	}
+
+	for i := range summaries {
+		summarize(&summaries[i], assignment.PeerReview, bySubmission[summaries[i].SubmissionID])
+
+		if assignment.Anonymized() {
+			summaries[i].StudentPseudonym = pseudonym.Of(assignment.AnonymityKey, summaries[i].StudentID)
+			summaries[i].StudentID = 0
+		}
+	}
+
+	return summaries, nil
+}
+
+// summarize sets the averages of the submitted reviews to the summary.
+func summarize(
+	summary *models.PeerReviewSummary,
+	settings models.PeerReviewSettings,
+	reviews []models.PeerReview,
+) {
+	summary.MaxScore = settings.MaxScore()
+	summary.ReviewsAssigned = len(reviews)
+
+	totals := make(map[string]float64, len(settings.Criteria))
+	for _, review := range reviews {
+		if review.Status != models.PeerReviewSubmitted {
+			continue
+		}
+
+		summary.ReviewsSubmitted++
+		summary.Score += review.Score()
+
+		for _, score := range review.Scores {
+			totals[score.CriterionID] += score.Score
+		}
+	}
+
+	if summary.ReviewsSubmitted == 0 {
+		return
+	}
+
+	n := float64(summary.ReviewsSubmitted)
+	summary.Score /= n
+
+	summary.Criteria = make([]models.CriterionScore, 0, len(settings.Criteria))
+	for _, criterion := range settings.Criteria {
+		summary.Criteria = append(summary.Criteria, models.CriterionScore{
+			CriterionID: criterion.ID,
+			Score:       totals[criterion.ID] / n,
+		})
+	}
+}
+
+// review returns the review assigned to the reviewer.
+// Reviews of other students are reported as not found.
+func (s *PeerReviewService) review(
+	ctx context.Context,
+	reviewID string,
+	reviewerID int64,
+) (models.PeerReview, error) {
+	const op = "services.peerreview.review"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("review_id", reviewID),
+	)
+
+	review, err := s.reviewProvider.PeerReview(ctx, reviewID)
+	if err != nil {
+		if errors.Is(err, storage.ErrPeerReviewNotFound) {
+			log.Warn("peer review not found", slog.Any("error", err))
+
+			return models.PeerReview{}, fmt.Errorf("%s: %w", op, ErrPeerReviewNotFound)
+		}
+
+		log.Error("failed to get peer review", slog.Any("error", err))
+
+		return models.PeerReview{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if review.ReviewerID != reviewerID {
+		log.Warn("peer review is assigned to another student")
+
+		return models.PeerReview{}, fmt.Errorf("%s: %w", op, ErrPeerReviewNotFound)
+	}
+
+	return review, nil
+}
+
+// validScores reports whether every criterion is scored exactly once within its range.
+func validScores(criteria []models.RubricCriterion, scores []models.CriterionScore) bool {
+	if len(scores) != len(criteria) {
+		return false
+	}
+
+	maxScores := make(map[string]float64, len(criteria))
+	for _, c := range criteria {
+		maxScores[c.ID] = c.MaxScore
+	}
+
+	for _, score := range scores {
+		maxScore, ok := maxScores[score.CriterionID]
+		if !ok || score.Score < 0 || score.Score > maxScore {
+			return false
+		}
+
+		delete(maxScores, score.CriterionID)
+	}
+
+	return true
+}
@@ -0,0 +1,105 @@
+package peerreview
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"tasks/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSubmissions returns the submissions of n students,
+// whose ids are of the student assignments, unlike the id of the assignment.
+func newSubmissions(n int) []models.Submission {
+	submissions := make([]models.Submission, n)
+	for i := range submissions {
+		submissions[i] = models.Submission{
+			ID:           fmt.Sprintf("submission-%d", i),
+			AssignmentID: fmt.Sprintf("student-assignment-%d", i),
+			StudentID:    int64(i + 1),
+		}
+	}
+
+	return submissions
+}
+
+func TestAssignReviewers(t *testing.T) {
+	for _, n := range []int{2, 3, 5, 30} {
+		for _, k := range []int{1, 2, 3} {
+			if k >= n {
+				continue
+			}
+
+			t.Run(fmt.Sprintf("n=%d k=%d", n, k), func(t *testing.T) {
+				submissions := newSubmissions(n)
+				authors := make(map[string]int64, n)
+				for _, submission := range submissions {
+					authors[submission.ID] = submission.StudentID
+				}
+
+				r := rand.New(rand.NewPCG(uint64(n), uint64(k)))
+				reviews := assignReviewers("assignment", submissions, k, r.Shuffle)
+				require.Len(t, reviews, n*k)
+
+				reviewers := make(map[string]map[int64]bool, n)
+				assigned := make(map[int64]int, n)
+				for _, review := range reviews {
+					assert.Equal(t, "assignment", review.AssignmentID)
+					assert.NotEqual(t, authors[review.SubmissionID], review.ReviewerID, "own work is reviewed")
+
+					if reviewers[review.SubmissionID] == nil {
+						reviewers[review.SubmissionID] = make(map[int64]bool)
+					}
+					assert.False(t, reviewers[review.SubmissionID][review.ReviewerID], "reviewer is assigned twice")
+					reviewers[review.SubmissionID][review.ReviewerID] = true
+
+					assigned[review.ReviewerID]++
+				}
+
+				for _, submission := range submissions {
+					assert.Len(t, reviewers[submission.ID], k, submission.ID)
+					assert.Equal(t, k, assigned[submission.StudentID], "reviews of student %d", submission.StudentID)
+				}
+			})
+		}
+	}
+}
+
+func TestAssignReviewers_NotEnoughSubmissions(t *testing.T) {
+	noShuffle := func(int, func(i, j int)) {}
+
+	t.Run("k is reduced to n-1", func(t *testing.T) {
+		for _, k := range []int{3, 4, 10} {
+			reviews := assignReviewers("assignment", newSubmissions(3), k, noShuffle)
+			require.Len(t, reviews, 3*2, "k=%d", k)
+
+			for _, review := range reviews {
+				assert.NotEqual(t, review.SubmissionID, fmt.Sprintf("submission-%d", review.ReviewerID-1))
+			}
+		}
+	})
+
+	t.Run("single submission is not reviewed", func(t *testing.T) {
+		assert.Empty(t, assignReviewers("assignment", newSubmissions(1), 2, noShuffle))
+	})
+
+	t.Run("no submissions", func(t *testing.T) {
+		assert.Empty(t, assignReviewers("assignment", nil, 2, noShuffle))
+	})
+
+	t.Run("reviews are disabled", func(t *testing.T) {
+		assert.Empty(t, assignReviewers("assignment", newSubmissions(5), 0, noShuffle))
+	})
+}
+
+func TestAssignReviewers_KeepsSubmissions(t *testing.T) {
+	submissions := newSubmissions(4)
+	reviews := assignReviewers("assignment", submissions, 1, rand.Shuffle)
+	require.Len(t, reviews, 4)
+
+	// the submissions of the caller are not reordered by the shuffle
+	assert.Equal(t, newSubmissions(4), submissions)
+}
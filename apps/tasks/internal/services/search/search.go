@@ -0,0 +1,63 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"tasks/internal/domain/models"
+)
+
+type SearchService struct {
+	log            *slog.Logger
+	searchProvider SearchProvider
+}
+
+type SearchProvider interface {
+	Search(
+		ctx context.Context,
+		userID int64,
+		schoolID int64,
+		query models.SearchQuery,
+		filter models.Filter,
+	) ([]models.SearchResult, error)
+}
+
+// New returns a new instance of SearchService.
+func New(
+	log *slog.Logger,
+	searchProvider SearchProvider,
+) *SearchService {
+	return &SearchService{
+		log:            log,
+		searchProvider: searchProvider,
+	}
+}
+
+// Search returns the page of the teacher's assignments and the templates
+// of the library visible to the teacher which match the query, the most relevant first.
+func (s *SearchService) Search(
+	ctx context.Context,
+	userID int64,
+	schoolID int64,
+	query models.SearchQuery,
+	filter models.Filter,
+) ([]models.SearchResult, error) {
+	const op = "services.search.Search"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("kind", string(query.Kind)),
+	)
+
+	log.Debug("searching assignments")
+
+	results, err := s.searchProvider.Search(ctx, userID, schoolID, query, filter)
+	if err != nil {
+		log.Error("failed to search assignments", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return results, nil
+}
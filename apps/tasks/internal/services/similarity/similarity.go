@@ -0,0 +1,231 @@
+package similarity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/lib/pseudonym"
+	"tasks/internal/lib/similarity"
+	"tasks/internal/storage"
+)
+
+const (
+	// checkBatchSize limits the number of assignments checked in one run.
+	checkBatchSize = 10
+	// reportScore is the minimal score of the pair to be reported.
+	// Short answers to the same question often share a few phrases,
+	// so the pairs with the smaller score are not stored at all.
+	reportScore = 0.3
+)
+
+type SimilarityService struct {
+	log                *slog.Logger
+	similaritySaver    SimilaritySaver
+	similarityProvider SimilarityProvider
+	assignmentProvider AssignmentProvider
+}
+
+type SimilaritySaver interface {
+	SaveSimilarity(
+		ctx context.Context,
+		assignmentID string,
+		pairs []models.SimilarPair,
+		checkedAt time.Time,
+	) error
+}
+
+type SimilarityProvider interface {
+	UncheckedAssignments(ctx context.Context, limit int) ([]string, error)
+	SubmittedSubmissions(ctx context.Context, assignmentID string) ([]models.Submission, error)
+	SimilarityReport(
+		ctx context.Context,
+		assignmentID string,
+		minScore float64,
+	) (models.SimilarityReport, error)
+}
+
+type AssignmentProvider interface {
+	Assignment(ctx context.Context, assignmentID string) (models.Assignment, error)
+}
+
+var (
+	ErrAssignmentNotFound = storage.ErrAssignmentNotFound
+	ErrAccessDenied       = errors.New("access to assignment denied")
+)
+
+// New returns a new instance of SimilarityService.
+func New(
+	log *slog.Logger,
+	similaritySaver SimilaritySaver,
+	similarityProvider SimilarityProvider,
+	assignmentProvider AssignmentProvider,
+) *SimilarityService {
+	return &SimilarityService{
+		log:                log,
+		similaritySaver:    similaritySaver,
+		similarityProvider: similarityProvider,
+		assignmentProvider: assignmentProvider,
+	}
+}
+
+// CheckSimilarity compares the submissions of the assignments
+// which got new submissions since their last check.
+// It is run periodically by the scheduler.
+func (s *SimilarityService) CheckSimilarity(ctx context.Context) error {
+	const op = "services.similarity.CheckSimilarity"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	for {
+		ids, err := s.similarityProvider.UncheckedAssignments(ctx, checkBatchSize)
+		if err != nil {
+			log.Error("failed to get unchecked assignments", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, id := range ids {
+			if err := s.checkAssignment(ctx, id); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		if len(ids) < checkBatchSize {
+			return nil
+		}
+	}
+}
+
+// checkAssignment compares every pair of the submitted submissions of the assignment.
+// The submission submitted earlier comes first in the pair.
+func (s *SimilarityService) checkAssignment(ctx context.Context, assignmentID string) error {
+	const op = "services.similarity.checkAssignment"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
+	)
+
+	// Submissions submitted while the check runs are compared by the next run.
+	checkedAt := time.Now().UTC()
+
+	submissions, err := s.similarityProvider.SubmittedSubmissions(ctx, assignmentID)
+	if err != nil {
+		log.Error("failed to get submissions", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	docs := make([]similarity.Document, len(submissions))
+	for i, submission := range submissions {
+		docs[i] = similarity.Fingerprint(similarity.Text(submission.CurrentVersion.Payload))
+	}
+
+	var pairs []models.SimilarPair
+	for i := range submissions {
+		for j := i + 1; j < len(submissions); j++ {
+			res := similarity.Compare(docs[i], docs[j])
+			if res.Score < reportScore {
+				continue
+			}
+
+			fragments := make([]models.MatchedFragment, 0, len(res.Fragments))
+			for _, f := range res.Fragments {
+				fragments = append(fragments, models.MatchedFragment{
+					Text:      f.Text,
+					OtherText: f.OtherText,
+				})
+			}
+
+			pairs = append(pairs, models.SimilarPair{
+				SubmissionID:      submissions[i].ID,
+				StudentID:         submissions[i].StudentID,
+				OtherSubmissionID: submissions[j].ID,
+				OtherStudentID:    submissions[j].StudentID,
+				Score:             res.Score,
+				Fragments:         fragments,
+			})
+		}
+	}
+
+	if err := s.similaritySaver.SaveSimilarity(ctx, assignmentID, pairs, checkedAt); err != nil {
+		log.Error("failed to save similarity", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info(
+		"assignment checked",
+		slog.Int("submissions", len(submissions)),
+		slog.Int("pairs", len(pairs)),
+	)
+
+	return nil
+}
+
+// SimilarityReport returns the pairs of similar submissions of the teacher's assignment
+// with the score of at least minScore.
+func (s *SimilarityService) SimilarityReport(
+	ctx context.Context,
+	assignmentID string,
+	teacherID int64,
+	minScore float64,
+) (models.SimilarityReport, error) {
+	const op = "services.similarity.SimilarityReport"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
+	)
+
+	log.Debug("fetching similarity report")
+
+	assignment, err := s.assignmentProvider.Assignment(ctx, assignmentID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAssignmentNotFound) {
+			log.Warn("assignment not found", slog.Any("error", err))
+
+			return models.SimilarityReport{}, fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+		}
+
+		log.Error("failed to get assignment", slog.Any("error", err))
+
+		return models.SimilarityReport{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if assignment.Deleted() {
+		log.Warn("assignment is in the trash")
+
+		return models.SimilarityReport{}, fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+	}
+
+	if assignment.CreatorID != teacherID {
+		log.Warn("assignment belongs to another teacher")
+
+		return models.SimilarityReport{}, fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	}
+
+	report, err := s.similarityProvider.SimilarityReport(ctx, assignmentID, max(minScore, reportScore))
+	if err != nil {
+		log.Error("failed to get similarity report", slog.Any("error", err))
+
+		return models.SimilarityReport{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if assignment.Anonymized() {
+		for i := range report.Pairs {
+			pair := &report.Pairs[i]
+			pair.StudentPseudonym = pseudonym.Of(assignment.AnonymityKey, pair.StudentID)
+			pair.OtherStudentPseudonym = pseudonym.Of(assignment.AnonymityKey, pair.OtherStudentID)
+			pair.StudentID, pair.OtherStudentID = 0, 0
+		}
+	}
+
+	return report, nil
+}
@@ -3,72 +3,623 @@ package submission
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"time"
+
 	"tasks/internal/domain/models"
+	"tasks/internal/lib/pseudonym"
+	"tasks/internal/storage"
+)
+
+const (
+	// purgeBatchSize limits the number of submissions purged in one statement.
+	purgeBatchSize = 100
+	// autosaveWindow is the age of the draft version after which
+	// the autosave creates the new version instead of overwriting the draft.
+	autosaveWindow = 10 * time.Minute
 )
 
 type SubmissionService struct {
 	log                *slog.Logger
 	submissionSaver    SubmissionSaver
 	submissionProvider SubmissionProvider
+	attachmentProvider AttachmentProvider
+	assignmentProvider AssignmentProvider
 }
 
 type SubmissionSaver interface {
-	SaveSubmission(
+	StartSubmission(
 		ctx context.Context,
-		assignmentID int64,
+		studentAssignmentID string,
 		studentID int64,
-		content json.RawMessage,
+		now time.Time,
+	) (string, error)
+	SaveDraft(
+		ctx context.Context,
+		submissionID string,
+		expectedRevision int64,
+		payload json.RawMessage,
+		isLate bool,
+		coalesceSince time.Time,
 	) (int64, error)
-	UpdateSubmission(
+	Submit(
 		ctx context.Context,
-		submissionID int64,
-		content json.RawMessage,
-	) error
+		submissionID string,
+		expectedRevision int64,
+		isLate bool,
+		now time.Time,
+	) (int64, error)
+	DeleteSubmission(ctx context.Context, submissionID string) error
+	RestoreSubmission(ctx context.Context, submissionID string) error
+	PurgeSubmissions(
+		ctx context.Context,
+		before time.Time,
+		limit int,
+	) (int64, error)
 }
 
 type SubmissionProvider interface {
-	SubmissionByAssignmentID(
-		ctx context.Context,
-		assignmentID int64,
-	) ([]models.Submission, error)
-	SubmissionsByAssignmentIDAndStudentID(
+	Submission(ctx context.Context, submissionID string) (models.Submission, error)
+	DeletedSubmissions(
 		ctx context.Context,
-		assignmentID int64,
 		studentID int64,
-	) (models.Submission, error)
+		filter models.Filter,
+	) ([]models.TrashItem, error)
+	AssignmentSubmissions(
+		ctx context.Context,
+		assignmentID string,
+		filter models.Filter,
+	) ([]models.Submission, error)
+}
+
+type AttachmentProvider interface {
+	SubmissionAttachments(ctx context.Context, submissionID string) ([]models.Attachment, error)
+}
+
+type AssignmentProvider interface {
+	Assignment(ctx context.Context, assignmentID string) (models.Assignment, error)
 }
 
+var (
+	ErrSubmissionNotFound = storage.ErrSubmissionNotFound
+	ErrAssignmentNotFound = storage.ErrAssignmentNotFound
+	ErrRevisionConflict   = storage.ErrRevisionConflict
+	ErrNotEditable        = errors.New("submission is not editable")
+	ErrPastCutoff         = errors.New("cutoff date of assignment has passed")
+	ErrEmptySubmission    = errors.New("submission has no saved version")
+	ErrInvalidAttachment  = errors.New("payload references unknown attachment")
+	ErrAccessDenied       = errors.New("access to assignment denied")
+	ErrGraded             = errors.New("submission is graded")
+)
+
+// New returns a new instance of SubmissionService.
 func New(
 	log *slog.Logger,
 	submissionSaver SubmissionSaver,
 	submissionProvider SubmissionProvider,
+	attachmentProvider AttachmentProvider,
+	assignmentProvider AssignmentProvider,
 ) *SubmissionService {
 	return &SubmissionService{
 		log:                log,
 		submissionSaver:    submissionSaver,
 		submissionProvider: submissionProvider,
+		attachmentProvider: attachmentProvider,
+		assignmentProvider: assignmentProvider,
+	}
+}
+
+// StartSubmission starts the work of the student on the student assignment.
+// If the work is already started, it returns the id of the active submission.
+func (s *SubmissionService) StartSubmission(
+	ctx context.Context,
+	studentAssignmentID string,
+	studentID int64,
+) (string, error) {
+	const op = "services.submission.StartSubmission"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("student_assignment_id", studentAssignmentID),
+	)
+
+	log.Debug("starting submission")
+
+	submissionID, err := s.submissionSaver.StartSubmission(
+		ctx, studentAssignmentID, studentID, time.Now().UTC(),
+	)
+	if err != nil {
+		if errors.Is(err, storage.ErrAssignmentNotFound) {
+			log.Warn("student assignment not found", slog.Any("error", err))
+
+			return "", fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+		}
+
+		log.Error("failed to start submission", slog.Any("error", err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("submission started", slog.String("submission_id", submissionID))
+
+	return submissionID, nil
+}
+
+// UpdateSubmission autosaves the payload of the submission.
+//
+// The expected revision must be the revision of the submission read by the client.
+// If the submission was changed since then, for example in another tab,
+// nothing is saved and ErrRevisionConflict is returned together with the current submission.
+// On success the updated submission is returned.
+func (s *SubmissionService) UpdateSubmission(
+	ctx context.Context,
+	submissionID string,
+	studentID int64,
+	expectedRevision int64,
+	payload json.RawMessage,
+) (models.Submission, error) {
+	const op = "services.submission.UpdateSubmission"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
+	)
+
+	log.Debug("updating submission")
+
+	submission, err := s.editable(ctx, submissionID, studentID)
+	if err != nil {
+		return models.Submission{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.checkAttachments(ctx, submissionID, payload); err != nil {
+		return models.Submission{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now().UTC()
+
+	_, err = s.submissionSaver.SaveDraft(
+		ctx,
+		submissionID,
+		expectedRevision,
+		payload,
+		now.After(submission.DueDate),
+		now.Add(-autosaveWindow),
+	)
+	if err != nil {
+		return s.saveFailed(ctx, log, op, submissionID, err)
+	}
+
+	submission, err = s.submission(ctx, submissionID, studentID)
+	if err != nil {
+		return models.Submission{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Debug("submission updated", slog.Int64("revision", submission.Revision))
+
+	return submission, nil
+}
+
+// SubmitSubmission hands the submission in for grading.
+// If the payload is given, it is saved as the final version first.
+// Conflicts are reported the same way as in UpdateSubmission.
+func (s *SubmissionService) SubmitSubmission(
+	ctx context.Context,
+	submissionID string,
+	studentID int64,
+	expectedRevision int64,
+	payload json.RawMessage,
+) (models.Submission, error) {
+	const op = "services.submission.SubmitSubmission"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
+	)
+
+	log.Debug("submitting submission")
+
+	submission, err := s.editable(ctx, submissionID, studentID)
+	if err != nil {
+		return models.Submission{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now().UTC()
+	isLate := now.After(submission.DueDate)
+
+	if payload != nil {
+		if err := s.checkAttachments(ctx, submissionID, payload); err != nil {
+			return models.Submission{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		expectedRevision, err = s.submissionSaver.SaveDraft(
+			ctx,
+			submissionID,
+			expectedRevision,
+			payload,
+			isLate,
+			now.Add(-autosaveWindow),
+		)
+		if err != nil {
+			return s.saveFailed(ctx, log, op, submissionID, err)
+		}
+	} else if submission.CurrentVersion == nil {
+		log.Warn("submission has no saved version")
+
+		return models.Submission{}, fmt.Errorf("%s: %w", op, ErrEmptySubmission)
+	}
+
+	_, err = s.submissionSaver.Submit(ctx, submissionID, expectedRevision, isLate, now)
+	if err != nil {
+		return s.saveFailed(ctx, log, op, submissionID, err)
+	}
+
+	submission, err = s.submission(ctx, submissionID, studentID)
+	if err != nil {
+		return models.Submission{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("submission submitted", slog.Bool("is_late", isLate))
+
+	return submission, nil
+}
+
+// DeleteSubmission moves the submission of the student to the trash.
+// The submission can be restored with RestoreSubmission until it is purged.
+// Graded submissions cannot be deleted, the purge would remove the feedback of the teacher.
+func (s *SubmissionService) DeleteSubmission(
+	ctx context.Context,
+	submissionID string,
+	studentID int64,
+) error {
+	const op = "services.submission.DeleteSubmission"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
+	)
+
+	log.Debug("deleting submission")
+
+	submission, err := s.submission(ctx, submissionID, studentID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if submission.Deleted() {
+		log.Warn("submission is already in the trash")
+
+		return fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+	}
+
+	if submission.Graded() {
+		log.Warn("submission is graded", slog.String("status", string(submission.Status)))
+
+		return fmt.Errorf("%s: %w", op, ErrGraded)
+	}
+
+	if err := s.submissionSaver.DeleteSubmission(ctx, submissionID); err != nil {
+		if errors.Is(err, storage.ErrSubmissionNotFound) {
+			log.Warn("submission not found", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+		}
+
+		log.Error("failed to delete submission", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("submission moved to trash")
+
+	return nil
+}
+
+// RestoreSubmission moves the submission of the student out of the trash.
+// If the submission is not in the trash, returns ErrSubmissionNotFound.
+func (s *SubmissionService) RestoreSubmission(
+	ctx context.Context,
+	submissionID string,
+	studentID int64,
+) error {
+	const op = "services.submission.RestoreSubmission"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
+	)
+
+	log.Debug("restoring submission")
+
+	if _, err := s.submission(ctx, submissionID, studentID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
+
+	if err := s.submissionSaver.RestoreSubmission(ctx, submissionID); err != nil {
+		if errors.Is(err, storage.ErrSubmissionNotFound) {
+			log.Warn("submission is not in the trash", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+		}
+
+		log.Error("failed to restore submission", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("submission restored")
+
+	return nil
+}
+
+// DeletedSubmissions returns the page of submissions of the student in the trash.
+func (s *SubmissionService) DeletedSubmissions(
+	ctx context.Context,
+	studentID int64,
+	filter models.Filter,
+) ([]models.TrashItem, error) {
+	const op = "services.submission.DeletedSubmissions"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Debug("fetching deleted submissions")
+
+	items, err := s.submissionProvider.DeletedSubmissions(ctx, studentID, filter)
+	if err != nil {
+		log.Error("failed to get deleted submissions", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return items, nil
+}
+
+// AssignmentSubmissions returns the page of the submissions of the assignment
+// created by the teacher. If the assignment is anonymous, the students
+// are replaced with their pseudonyms until the identities are revealed.
+func (s *SubmissionService) AssignmentSubmissions(
+	ctx context.Context,
+	assignmentID string,
+	teacherID int64,
+	filter models.Filter,
+) ([]models.Submission, error) {
+	const op = "services.submission.AssignmentSubmissions"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("assignment_id", assignmentID),
+	)
+
+	log.Debug("fetching assignment submissions")
+
+	assignment, err := s.assignmentProvider.Assignment(ctx, assignmentID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAssignmentNotFound) {
+			log.Warn("assignment not found", slog.Any("error", err))
+
+			return nil, fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+		}
+
+		log.Error("failed to get assignment", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if assignment.CreatorID != teacherID {
+		log.Warn("assignment belongs to another teacher")
+
+		return nil, fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	}
+
+	if assignment.Deleted() {
+		log.Warn("assignment is in the trash")
+
+		return nil, fmt.Errorf("%s: %w", op, ErrAssignmentNotFound)
+	}
+
+	submissions, err := s.submissionProvider.AssignmentSubmissions(ctx, assignmentID, filter)
+	if err != nil {
+		log.Error("failed to get assignment submissions", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if assignment.Anonymized() {
+		for i := range submissions {
+			submissions[i].StudentPseudonym = pseudonym.Of(assignment.AnonymityKey, submissions[i].StudentID)
+			submissions[i].StudentID = 0
+		}
+	}
+
+	return submissions, nil
+}
+
+// PurgeDeleted permanently deletes the submissions which are in the trash
+// for longer than the retention period. It is run periodically by the scheduler.
+func (s *SubmissionService) PurgeDeleted(ctx context.Context, retention time.Duration) error {
+	const op = "services.submission.PurgeDeleted"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	before := time.Now().UTC().Add(-retention)
+
+	var total int64
+	for {
+		purged, err := s.submissionSaver.PurgeSubmissions(ctx, before, purgeBatchSize)
+		if err != nil {
+			log.Error("failed to purge submissions", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		total += purged
+
+		if purged < purgeBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Info("submissions purged", slog.Int64("count", total))
+	}
+
+	return nil
 }
 
-func (s *SubmissionService) SubmissionByAssignmentIDAndStudentID(
+// editable returns the submission of the student which can still be changed.
+func (s *SubmissionService) editable(
 	ctx context.Context,
-	assignmentID int64,
+	submissionID string,
 	studentID int64,
 ) (models.Submission, error) {
-	const op = "services.submission.SubmissionByAssignmentIDAndStudentID"
+	const op = "services.submission.editable"
 
 	log := s.log.With(
 		slog.String("op", op),
+		slog.String("submission_id", submissionID),
 	)
 
-	log.Debug("fetching submission by assignment and student id's")
+	submission, err := s.submission(ctx, submissionID, studentID)
+	if err != nil {
+		return models.Submission{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if submission.Deleted() {
+		log.Warn("submission is in the trash")
+
+		return models.Submission{}, fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+	}
+
+	if !submission.Editable() {
+		log.Warn("submission is not editable", slog.String("status", string(submission.Status)))
+
+		return models.Submission{}, fmt.Errorf("%s: %w", op, ErrNotEditable)
+	}
+
+	if time.Now().UTC().After(submission.CutoffDate) {
+		log.Warn("cutoff date has passed")
+
+		return models.Submission{}, fmt.Errorf("%s: %w", op, ErrPastCutoff)
+	}
+
+	return submission, nil
+}
+
+// checkAttachments checks that the payload references
+// only the attachments uploaded to the submission.
+func (s *SubmissionService) checkAttachments(
+	ctx context.Context,
+	submissionID string,
+	payload json.RawMessage,
+) error {
+	const op = "services.submission.checkAttachments"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
+	)
+
+	refs, err := models.AttachmentRefs(payload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(refs) == 0 {
+		return nil
+	}
+
+	attachments, err := s.attachmentProvider.SubmissionAttachments(ctx, submissionID)
+	if err != nil {
+		log.Error("failed to get attachments", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	uploaded := make(map[string]bool, len(attachments))
+	for _, attachment := range attachments {
+		uploaded[attachment.ID] = true
+	}
+
+	for _, ref := range refs {
+		if !uploaded[ref] {
+			log.Warn("payload references unknown attachment", slog.String("attachment_id", ref))
+
+			return fmt.Errorf("%s: %w: %s", op, ErrInvalidAttachment, ref)
+		}
+	}
+
+	return nil
+}
+
+// saveFailed converts the error of saving the submission.
+// On revision conflict it returns the current submission along with ErrRevisionConflict.
+func (s *SubmissionService) saveFailed(
+	ctx context.Context,
+	log *slog.Logger,
+	op string,
+	submissionID string,
+	err error,
+) (models.Submission, error) {
+	switch {
+	case errors.Is(err, storage.ErrRevisionConflict):
+		log.Warn("submission was changed concurrently")
+
+		current, err := s.submissionProvider.Submission(ctx, submissionID)
+		if err != nil {
+			log.Error("failed to get current submission", slog.Any("error", err))
+
+			return models.Submission{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return current, fmt.Errorf("%s: %w", op, ErrRevisionConflict)
+	case errors.Is(err, storage.ErrSubmissionNotFound):
+		log.Warn("submission not found", slog.Any("error", err))
+
+		return models.Submission{}, fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+	}
+
+	log.Error("failed to save submission", slog.Any("error", err))
 
-	submission, err := s.submissionProvider.SubmissionsByAssignmentIDAndStudentID(
-		ctx, assignmentID, studentID,
+	return models.Submission{}, fmt.Errorf("%s: %w", op, err)
+}
+
+// submission returns the submission of the student, including the deleted one.
+// Submissions of other students are reported as not found.
+func (s *SubmissionService) submission(
+	ctx context.Context,
+	submissionID string,
+	studentID int64,
+) (models.Submission, error) {
+	const op = "services.submission.submission"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("submission_id", submissionID),
 	)
+
+	submission, err := s.submissionProvider.Submission(ctx, submissionID)
 	if err != nil {
-		return models.Submission{}, err
+		if errors.Is(err, storage.ErrSubmissionNotFound) {
+			log.Warn("submission not found", slog.Any("error", err))
+
+			return models.Submission{}, fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
+		}
+
+		log.Error("failed to get submission", slog.Any("error", err))
+
+		return models.Submission{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if submission.StudentID != studentID {
+		log.Warn("submission belongs to another student")
+
+		return models.Submission{}, fmt.Errorf("%s: %w", op, ErrSubmissionNotFound)
 	}
 
 	return submission, nil
@@ -0,0 +1,415 @@
+package submission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStorage keeps the submissions in memory
+// and changes them the way the postgres storage does.
+// The methods the tests do not call are left to the embedded interfaces.
+type fakeStorage struct {
+	SubmissionSaver
+	SubmissionProvider
+	AttachmentProvider
+	AssignmentProvider
+
+	submissions map[string]models.Submission
+	// versions counts the versions saved for the submission.
+	versions      map[string]int
+	coalesceSince time.Time
+}
+
+func (f *fakeStorage) SaveDraft(
+	_ context.Context,
+	submissionID string,
+	expectedRevision int64,
+	payload json.RawMessage,
+	isLate bool,
+	coalesceSince time.Time,
+) (int64, error) {
+	f.coalesceSince = coalesceSince
+
+	submission, ok := f.submissions[submissionID]
+	if !ok || submission.Deleted() {
+		return 0, storage.ErrSubmissionNotFound
+	}
+
+	if submission.Revision != expectedRevision {
+		return 0, storage.ErrRevisionConflict
+	}
+
+	version := submission.CurrentVersion
+	coalesce := submission.Status == models.StatusInProgress &&
+		version != nil &&
+		!version.CreatedAt.Before(coalesceSince)
+
+	if coalesce {
+		version = &models.SubmissionVersion{
+			ID:        version.ID,
+			Number:    version.Number,
+			CreatedAt: version.CreatedAt,
+		}
+	} else {
+		f.versions[submissionID]++
+		version = &models.SubmissionVersion{
+			ID:        fmt.Sprintf("%s-v%d", submissionID, f.versions[submissionID]),
+			Number:    f.versions[submissionID],
+			CreatedAt: time.Now().UTC(),
+		}
+	}
+
+	version.SubmissionID = submissionID
+	version.Payload = payload
+	version.IsLate = isLate
+	version.UpdatedAt = time.Now().UTC()
+
+	submission.CurrentVersion = version
+	submission.Status = models.StatusInProgress
+	submission.Revision++
+	f.submissions[submissionID] = submission
+
+	return submission.Revision, nil
+}
+
+func (f *fakeStorage) Submit(
+	_ context.Context,
+	submissionID string,
+	expectedRevision int64,
+	_ bool,
+	now time.Time,
+) (int64, error) {
+	submission, ok := f.submissions[submissionID]
+	if !ok || submission.Deleted() || submission.Revision != expectedRevision {
+		return 0, storage.ErrRevisionConflict
+	}
+
+	submission.Status = models.StatusSubmitted
+	submission.SubmittedAt = now
+	submission.Revision++
+	f.submissions[submissionID] = submission
+
+	return submission.Revision, nil
+}
+
+func (f *fakeStorage) DeleteSubmission(_ context.Context, submissionID string) error {
+	submission, ok := f.submissions[submissionID]
+	if !ok || submission.Deleted() || submission.Graded() {
+		return storage.ErrSubmissionNotFound
+	}
+	submission.DeletedAt = time.Now().UTC()
+	f.submissions[submissionID] = submission
+	return nil
+}
+
+func (f *fakeStorage) RestoreSubmission(_ context.Context, submissionID string) error {
+	submission, ok := f.submissions[submissionID]
+	if !ok || !submission.Deleted() {
+		return storage.ErrSubmissionNotFound
+	}
+	submission.DeletedAt = time.Time{}
+	f.submissions[submissionID] = submission
+	return nil
+}
+
+func (f *fakeStorage) PurgeSubmissions(_ context.Context, before time.Time, limit int) (int64, error) {
+	var purged int64
+	for id, submission := range f.submissions {
+		if purged == int64(limit) {
+			break
+		}
+		if submission.Deleted() && submission.DeletedAt.Before(before) && !submission.Graded() {
+			delete(f.submissions, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (f *fakeStorage) Submission(_ context.Context, submissionID string) (models.Submission, error) {
+	submission, ok := f.submissions[submissionID]
+	if !ok {
+		return models.Submission{}, storage.ErrSubmissionNotFound
+	}
+	return submission, nil
+}
+
+func newTestService(submissions ...models.Submission) (*SubmissionService, *fakeStorage) {
+	st := &fakeStorage{
+		submissions: make(map[string]models.Submission),
+		versions:    make(map[string]int),
+	}
+	for _, submission := range submissions {
+		st.submissions[submission.ID] = submission
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return New(log, st, st, st, st), st
+}
+
+func TestDeleteSubmission(t *testing.T) {
+	s, st := newTestService(
+		models.Submission{ID: "draft", StudentID: 1, Status: models.StatusInProgress},
+		models.Submission{ID: "submitted", StudentID: 1, Status: models.StatusSubmitted},
+		models.Submission{ID: "graded", StudentID: 1, Status: models.StatusGraded},
+		models.Submission{ID: "returned", StudentID: 1, Status: models.StatusReturned},
+	)
+
+	ctx := context.Background()
+
+	t.Run("submission is moved to trash", func(t *testing.T) {
+		for _, id := range []string{"draft", "submitted"} {
+			require.NoError(t, s.DeleteSubmission(ctx, id, 1))
+			assert.False(t, st.submissions[id].DeletedAt.IsZero(), id)
+		}
+	})
+
+	t.Run("submission in trash", func(t *testing.T) {
+		err := s.DeleteSubmission(ctx, "draft", 1)
+		assert.ErrorIs(t, err, ErrSubmissionNotFound)
+	})
+
+	t.Run("submission of another student", func(t *testing.T) {
+		err := s.DeleteSubmission(ctx, "graded", 2)
+		assert.ErrorIs(t, err, ErrSubmissionNotFound)
+	})
+
+	t.Run("graded submission is kept", func(t *testing.T) {
+		for _, id := range []string{"graded", "returned"} {
+			err := s.DeleteSubmission(ctx, id, 1)
+			assert.ErrorIs(t, err, ErrGraded, id)
+			assert.True(t, st.submissions[id].DeletedAt.IsZero(), id)
+		}
+	})
+}
+
+func TestRestoreSubmission(t *testing.T) {
+	deletedAt := time.Now().UTC().Add(-time.Hour)
+
+	s, st := newTestService(
+		models.Submission{ID: "deleted", StudentID: 1, Status: models.StatusInProgress, DeletedAt: deletedAt},
+		models.Submission{ID: "active", StudentID: 1, Status: models.StatusInProgress},
+	)
+
+	ctx := context.Background()
+
+	t.Run("submission of another student", func(t *testing.T) {
+		err := s.RestoreSubmission(ctx, "deleted", 2)
+		assert.ErrorIs(t, err, ErrSubmissionNotFound)
+		assert.Equal(t, deletedAt, st.submissions["deleted"].DeletedAt)
+	})
+
+	t.Run("submission is restored", func(t *testing.T) {
+		require.NoError(t, s.RestoreSubmission(ctx, "deleted", 1))
+		assert.True(t, st.submissions["deleted"].DeletedAt.IsZero())
+	})
+
+	t.Run("submission is not in trash", func(t *testing.T) {
+		err := s.RestoreSubmission(ctx, "active", 1)
+		assert.ErrorIs(t, err, ErrSubmissionNotFound)
+	})
+
+	t.Run("restored submission can be deleted again", func(t *testing.T) {
+		require.NoError(t, s.DeleteSubmission(ctx, "deleted", 1))
+		assert.False(t, st.submissions["deleted"].DeletedAt.IsZero())
+	})
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	const retention = 30 * 24 * time.Hour
+
+	now := time.Now().UTC()
+	expired := now.Add(-retention - time.Hour)
+
+	submissions := []models.Submission{
+		{ID: "recent", StudentID: 1, Status: models.StatusInProgress, DeletedAt: now.Add(-time.Hour)},
+		{ID: "active", StudentID: 1, Status: models.StatusInProgress},
+		// graded submissions moved to the trash before it was forbidden
+		{ID: "graded", StudentID: 1, Status: models.StatusGraded, DeletedAt: expired},
+		{ID: "returned", StudentID: 1, Status: models.StatusReturned, DeletedAt: expired},
+	}
+	for i := range 2*purgeBatchSize + 1 {
+		submissions = append(submissions, models.Submission{
+			ID:        fmt.Sprintf("expired-%d", i),
+			StudentID: 1,
+			Status:    models.StatusSubmitted,
+			DeletedAt: expired,
+		})
+	}
+
+	s, st := newTestService(submissions...)
+
+	require.NoError(t, s.PurgeDeleted(context.Background(), retention))
+
+	ids := make([]string, 0, len(st.submissions))
+	for id := range st.submissions {
+		ids = append(ids, id)
+	}
+	assert.ElementsMatch(t, []string{"recent", "active", "graded", "returned"}, ids)
+}
+
+func TestUpdateSubmission_Autosave(t *testing.T) {
+	now := time.Now().UTC()
+
+	s, st := newTestService(models.Submission{
+		ID:         "submission",
+		StudentID:  1,
+		Status:     models.StatusInProgress,
+		DueDate:    now.Add(time.Hour),
+		CutoffDate: now.Add(time.Hour),
+	})
+
+	ctx := context.Background()
+
+	first, err := s.UpdateSubmission(ctx, "submission", 1, 0, json.RawMessage(`{"text":"a"}`))
+	require.NoError(t, err)
+	require.NotNil(t, first.CurrentVersion)
+	assert.Equal(t, int64(1), first.Revision)
+	assert.Equal(t, 1, first.CurrentVersion.Number)
+	assert.WithinDuration(t, now.Add(-autosaveWindow), st.coalesceSince, time.Minute)
+
+	t.Run("autosaves within window are coalesced", func(t *testing.T) {
+		second, err := s.UpdateSubmission(ctx, "submission", 1, 1, json.RawMessage(`{"text":"ab"}`))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), second.Revision)
+		assert.Equal(t, first.CurrentVersion.ID, second.CurrentVersion.ID)
+		assert.JSONEq(t, `{"text":"ab"}`, string(second.CurrentVersion.Payload))
+		assert.Equal(t, 1, st.versions["submission"])
+	})
+
+	t.Run("stale revision is rejected with current submission", func(t *testing.T) {
+		current, err := s.UpdateSubmission(ctx, "submission", 1, 1, json.RawMessage(`{"text":"other tab"}`))
+		assert.ErrorIs(t, err, ErrRevisionConflict)
+		assert.Equal(t, int64(2), current.Revision)
+		require.NotNil(t, current.CurrentVersion)
+		assert.JSONEq(t, `{"text":"ab"}`, string(current.CurrentVersion.Payload))
+		assert.Equal(t, int64(2), st.submissions["submission"].Revision, "nothing is saved")
+	})
+
+	t.Run("autosave after window creates new version", func(t *testing.T) {
+		submission := st.submissions["submission"]
+		submission.CurrentVersion.CreatedAt = now.Add(-autosaveWindow - time.Minute)
+		st.submissions["submission"] = submission
+
+		third, err := s.UpdateSubmission(ctx, "submission", 1, 2, json.RawMessage(`{"text":"abc"}`))
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), third.Revision)
+		assert.Equal(t, 2, third.CurrentVersion.Number)
+		assert.NotEqual(t, first.CurrentVersion.ID, third.CurrentVersion.ID)
+	})
+}
+
+func TestUpdateSubmission_ReturnedStartsNewVersion(t *testing.T) {
+	now := time.Now().UTC()
+
+	s, st := newTestService(models.Submission{
+		ID:         "submission",
+		StudentID:  1,
+		Status:     models.StatusReturned,
+		Revision:   4,
+		DueDate:    now.Add(time.Hour),
+		CutoffDate: now.Add(time.Hour),
+		CurrentVersion: &models.SubmissionVersion{
+			ID:        "graded-version",
+			Number:    1,
+			Payload:   json.RawMessage(`{"text":"graded"}`),
+			CreatedAt: now,
+		},
+	})
+	st.versions["submission"] = 1
+
+	updated, err := s.UpdateSubmission(context.Background(), "submission", 1, 4, json.RawMessage(`{"text":"fixed"}`))
+	require.NoError(t, err)
+
+	// the graded version keeps the work the feedback was given on
+	assert.NotEqual(t, "graded-version", updated.CurrentVersion.ID)
+	assert.Equal(t, 2, updated.CurrentVersion.Number)
+	assert.Equal(t, models.StatusInProgress, updated.Status)
+}
+
+func TestUpdateSubmission_NotEditable(t *testing.T) {
+	now := time.Now().UTC()
+
+	s, _ := newTestService(
+		models.Submission{
+			ID:         "submitted",
+			StudentID:  1,
+			Status:     models.StatusSubmitted,
+			CutoffDate: now.Add(time.Hour),
+		},
+		models.Submission{
+			ID:         "closed",
+			StudentID:  1,
+			Status:     models.StatusInProgress,
+			DueDate:    now.Add(-2 * time.Hour),
+			CutoffDate: now.Add(-time.Hour),
+		},
+	)
+
+	ctx := context.Background()
+	payload := json.RawMessage(`{"text":"a"}`)
+
+	_, err := s.UpdateSubmission(ctx, "submitted", 1, 0, payload)
+	assert.ErrorIs(t, err, ErrNotEditable)
+
+	_, err = s.UpdateSubmission(ctx, "closed", 1, 0, payload)
+	assert.ErrorIs(t, err, ErrPastCutoff)
+
+	_, err = s.UpdateSubmission(ctx, "closed", 2, 0, payload)
+	assert.ErrorIs(t, err, ErrSubmissionNotFound)
+}
+
+func TestSubmitSubmission_Revision(t *testing.T) {
+	now := time.Now().UTC()
+
+	newSubmission := func() models.Submission {
+		return models.Submission{
+			ID:         "submission",
+			StudentID:  1,
+			Status:     models.StatusInProgress,
+			Revision:   1,
+			DueDate:    now.Add(time.Hour),
+			CutoffDate: now.Add(time.Hour),
+			CurrentVersion: &models.SubmissionVersion{
+				ID:        "version",
+				Number:    1,
+				Payload:   json.RawMessage(`{"text":"a"}`),
+				CreatedAt: now,
+			},
+		}
+	}
+
+	ctx := context.Background()
+
+	t.Run("stale revision is rejected", func(t *testing.T) {
+		s, st := newTestService(newSubmission())
+
+		current, err := s.SubmitSubmission(ctx, "submission", 1, 0, nil)
+		assert.ErrorIs(t, err, ErrRevisionConflict)
+		assert.Equal(t, int64(1), current.Revision)
+		assert.Equal(t, models.StatusInProgress, st.submissions["submission"].Status)
+	})
+
+	t.Run("payload is saved before submit", func(t *testing.T) {
+		s, _ := newTestService(newSubmission())
+
+		submitted, err := s.SubmitSubmission(ctx, "submission", 1, 1, json.RawMessage(`{"text":"final"}`))
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusSubmitted, submitted.Status)
+		assert.Equal(t, int64(3), submitted.Revision)
+		assert.Equal(t, "version", submitted.CurrentVersion.ID)
+		assert.JSONEq(t, `{"text":"final"}`, string(submitted.CurrentVersion.Payload))
+	})
+}
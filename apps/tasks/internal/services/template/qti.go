@@ -0,0 +1,175 @@
+package template
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/lib/qti"
+	"tasks/internal/storage"
+)
+
+const defaultImportTitle = "Imported quiz"
+
+// QTIImport is the result of the QTI package import.
+type QTIImport struct {
+	TemplateID  string
+	Version     qti.Version
+	Unsupported []qti.Unsupported
+}
+
+// ImportQTI creates a private quiz template from the zipped QTI package.
+//
+// Items which cannot be represented by the quiz widget are reported in QTIImport.Unsupported.
+// If none of the items are supported, returns ErrNothingToImport.
+func (s *TemplateService) ImportQTI(
+	ctx context.Context,
+	userID int64,
+	schoolID int64,
+	title string,
+	data []byte,
+) (QTIImport, error) {
+	const op = "services.template.ImportQTI"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Debug("importing qti package")
+
+	pkg, err := qti.Import(data)
+	if err != nil {
+		log.Warn("failed to read qti package", slog.Any("error", err))
+
+		return QTIImport{}, fmt.Errorf("%s: %w: %v", op, ErrInvalidPackage, err)
+	}
+
+	for _, item := range pkg.Unsupported {
+		log.Info(
+			"qti item is not supported",
+			slog.String("identifier", item.Identifier),
+			slog.String("interaction", item.Interaction),
+			slog.String("reason", item.Reason),
+		)
+	}
+
+	if len(pkg.Questions) == 0 {
+		log.Warn("qti package has no supported items")
+
+		return QTIImport{}, fmt.Errorf("%s: %w", op, ErrNothingToImport)
+	}
+
+	widget, err := s.widgetProvider.LatestWidget(ctx, models.WidgetTypeQuiz)
+	if err != nil {
+		log.Error("failed to get quiz widget", slog.Any("error", err))
+
+		return QTIImport{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	config, err := json.Marshal(models.QuizConfig{Questions: pkg.Questions})
+	if err != nil {
+		return QTIImport{}, fmt.Errorf("%s: %w", op, err)
+	}
+	widget.Config = config
+
+	if title == "" {
+		title = defaultImportTitle
+	}
+
+	id, err := s.templateSaver.SaveTemplate(ctx, models.Template{
+		CreatorID:  userID,
+		SchoolID:   schoolID,
+		Title:      title,
+		Widget:     widget,
+		Visibility: models.VisibilityPrivate,
+	})
+	if err != nil {
+		log.Error("failed to save template", slog.Any("error", err))
+
+		return QTIImport{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info(
+		"qti package imported",
+		slog.String("template_id", id),
+		slog.Int("imported", len(pkg.Questions)),
+		slog.Int("unsupported", len(pkg.Unsupported)),
+	)
+
+	return QTIImport{
+		TemplateID:  id,
+		Version:     pkg.Version,
+		Unsupported: pkg.Unsupported,
+	}, nil
+}
+
+// ExportQTI packs the quiz template to the zipped QTI package of the given version.
+//
+// Questions which cannot be represented in QTI are reported in the returned slice.
+// If template is not a quiz, returns ErrNotQuiz.
+func (s *TemplateService) ExportQTI(
+	ctx context.Context,
+	templateID string,
+	userID int64,
+	schoolID int64,
+	version qti.Version,
+) ([]byte, []qti.Unsupported, error) {
+	const op = "services.template.ExportQTI"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("template_id", templateID),
+	)
+
+	log.Debug("exporting template to qti")
+
+	template, err := s.templateProvider.Template(ctx, templateID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTemplateNotFound) {
+			log.Warn("template not found", slog.Any("error", err))
+
+			return nil, nil, fmt.Errorf("%s: %w", op, ErrTemplateNotFound)
+		}
+
+		log.Error("failed to get template", slog.Any("error", err))
+
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !template.VisibleTo(userID, schoolID) {
+		log.Warn("template is not visible to user")
+
+		return nil, nil, fmt.Errorf("%s: %w", op, ErrTemplateNotFound)
+	}
+
+	if template.Widget.Type != models.WidgetTypeQuiz {
+		log.Warn("template is not a quiz", slog.String("widget", template.Widget.Type))
+
+		return nil, nil, fmt.Errorf("%s: %w", op, ErrNotQuiz)
+	}
+
+	var config models.QuizConfig
+	if err := json.Unmarshal(template.Widget.Config, &config); err != nil {
+		log.Error("failed to decode quiz config", slog.Any("error", err))
+
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	data, unsupported, err := qti.Export(template.ID, config.Questions, version)
+	if err != nil {
+		log.Error("failed to export qti package", slog.Any("error", err))
+
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info(
+		"template exported to qti",
+		slog.Int("questions", len(config.Questions)),
+		slog.Int("unsupported", len(unsupported)),
+	)
+
+	return data, unsupported, nil
+}
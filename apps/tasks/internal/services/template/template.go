@@ -0,0 +1,214 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+)
+
+type TemplateService struct {
+	log              *slog.Logger
+	templateSaver    TemplateSaver
+	templateProvider TemplateProvider
+	widgetProvider   WidgetProvider
+}
+
+type TemplateSaver interface {
+	SaveTemplate(
+		ctx context.Context,
+		template models.Template,
+	) (string, error)
+	CloneTemplate(
+		ctx context.Context,
+		templateID string,
+		creatorID int64,
+		schoolID int64,
+		title string,
+	) (string, error)
+	UpdateTemplateVisibility(
+		ctx context.Context,
+		templateID string,
+		visibility models.Visibility,
+	) error
+}
+
+type TemplateProvider interface {
+	Template(ctx context.Context, templateID string) (models.Template, error)
+	Templates(
+		ctx context.Context,
+		scope models.TemplateScope,
+		userID int64,
+		schoolID int64,
+		filter models.Filter,
+	) ([]models.Template, error)
+}
+
+type WidgetProvider interface {
+	LatestWidget(ctx context.Context, widgetType string) (models.Widget, error)
+}
+
+var (
+	ErrTemplateNotFound = storage.ErrTemplateNotFound
+	ErrAccessDenied     = errors.New("access to template denied")
+	ErrNotQuiz          = errors.New("template is not a quiz")
+	ErrInvalidPackage   = errors.New("invalid qti package")
+	ErrNothingToImport  = errors.New("qti package has no supported items")
+	ErrSchoolUnknown    = errors.New("school of template is unknown")
+)
+
+// New returns a new instance of TemplateService.
+func New(
+	log *slog.Logger,
+	templateSaver TemplateSaver,
+	templateProvider TemplateProvider,
+	widgetProvider WidgetProvider,
+) *TemplateService {
+	return &TemplateService{
+		log:              log,
+		templateSaver:    templateSaver,
+		templateProvider: templateProvider,
+		widgetProvider:   widgetProvider,
+	}
+}
+
+// CloneTemplate copies a template visible to the user into the user's own library.
+//
+// If template does not exist or is not visible to the user, returns ErrTemplateNotFound.
+func (s *TemplateService) CloneTemplate(
+	ctx context.Context,
+	templateID string,
+	userID int64,
+	schoolID int64,
+	title string,
+) (string, error) {
+	const op = "services.template.CloneTemplate"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("template_id", templateID),
+	)
+
+	log.Debug("cloning template")
+
+	source, err := s.templateProvider.Template(ctx, templateID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTemplateNotFound) {
+			log.Warn("template not found", slog.Any("error", err))
+
+			return "", fmt.Errorf("%s: %w", op, ErrTemplateNotFound)
+		}
+
+		log.Error("failed to get template", slog.Any("error", err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Templates that the user cannot see are reported as missing
+	// so that private templates do not leak through their ids.
+	if !source.VisibleTo(userID, schoolID) {
+		log.Warn("template is not visible to user")
+
+		return "", fmt.Errorf("%s: %w", op, ErrTemplateNotFound)
+	}
+
+	id, err := s.templateSaver.CloneTemplate(ctx, templateID, userID, schoolID, title)
+	if err != nil {
+		log.Error("failed to clone template", slog.Any("error", err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("template cloned", slog.String("clone_id", id))
+
+	return id, nil
+}
+
+// Templates returns the page of templates from the given scope of the library.
+func (s *TemplateService) Templates(
+	ctx context.Context,
+	scope models.TemplateScope,
+	userID int64,
+	schoolID int64,
+	filter models.Filter,
+) ([]models.Template, error) {
+	const op = "services.template.Templates"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	log.Debug("fetching templates")
+
+	templates, err := s.templateProvider.Templates(ctx, scope, userID, schoolID, filter)
+	if err != nil {
+		log.Error("failed to get templates", slog.Any("error", err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Debug("templates fetched", slog.Int("count", len(templates)))
+
+	return templates, nil
+}
+
+// ShareTemplate changes the visibility of the template.
+// Only the creator of the template is allowed to share it.
+//
+// If the template is shared with the school, but the school
+// of its creator is unknown, returns ErrSchoolUnknown.
+func (s *TemplateService) ShareTemplate(
+	ctx context.Context,
+	templateID string,
+	userID int64,
+	visibility models.Visibility,
+) error {
+	const op = "services.template.ShareTemplate"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("template_id", templateID),
+	)
+
+	log.Debug("sharing template")
+
+	template, err := s.templateProvider.Template(ctx, templateID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTemplateNotFound) {
+			log.Warn("template not found", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, ErrTemplateNotFound)
+		}
+
+		log.Error("failed to get template", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if template.CreatorID != userID {
+		log.Warn("user is not the creator of template")
+
+		return fmt.Errorf("%s: %w", op, ErrAccessDenied)
+	}
+
+	// Otherwise the template would be shared with everyone
+	// whose school is unknown as well.
+	if visibility == models.VisibilitySchool && template.SchoolID == 0 {
+		log.Warn("school of template is unknown")
+
+		return fmt.Errorf("%s: %w", op, ErrSchoolUnknown)
+	}
+
+	if err := s.templateSaver.UpdateTemplateVisibility(ctx, templateID, visibility); err != nil {
+		log.Error("failed to update template visibility", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("template shared", slog.String("visibility", string(visibility)))
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package template
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStorage struct {
+	templates map[string]models.Template
+	clones    int
+}
+
+func (f *fakeStorage) SaveTemplate(_ context.Context, template models.Template) (string, error) {
+	f.templates[template.ID] = template
+	return template.ID, nil
+}
+
+func (f *fakeStorage) CloneTemplate(
+	_ context.Context,
+	_ string,
+	_ int64,
+	_ int64,
+	_ string,
+) (string, error) {
+	f.clones++
+	return "clone", nil
+}
+
+func (f *fakeStorage) UpdateTemplateVisibility(
+	_ context.Context,
+	templateID string,
+	visibility models.Visibility,
+) error {
+	template := f.templates[templateID]
+	template.Visibility = visibility
+	f.templates[templateID] = template
+	return nil
+}
+
+func (f *fakeStorage) Template(_ context.Context, templateID string) (models.Template, error) {
+	template, ok := f.templates[templateID]
+	if !ok {
+		return models.Template{}, storage.ErrTemplateNotFound
+	}
+	return template, nil
+}
+
+func (f *fakeStorage) Templates(
+	_ context.Context,
+	_ models.TemplateScope,
+	_ int64,
+	_ int64,
+	_ models.Filter,
+) ([]models.Template, error) {
+	return nil, nil
+}
+
+func (f *fakeStorage) LatestWidget(_ context.Context, widgetType string) (models.Widget, error) {
+	return models.Widget{Type: widgetType}, nil
+}
+
+func newTestService(templates ...models.Template) (*TemplateService, *fakeStorage) {
+	st := &fakeStorage{templates: make(map[string]models.Template)}
+	for _, template := range templates {
+		st.templates[template.ID] = template
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return New(log, st, st, st), st
+}
+
+func TestCloneTemplate_SchoolVisibility(t *testing.T) {
+	const (
+		creatorID = 1
+		schoolID  = 10
+	)
+
+	svc, st := newTestService(
+		models.Template{ID: "shared", CreatorID: creatorID, SchoolID: schoolID, Visibility: models.VisibilitySchool},
+		models.Template{ID: "unknown-school", CreatorID: creatorID, Visibility: models.VisibilitySchool},
+	)
+
+	ctx := context.Background()
+
+	t.Run("teacher of the same school", func(t *testing.T) {
+		_, err := svc.CloneTemplate(ctx, "shared", 2, schoolID, "copy")
+		require.NoError(t, err)
+	})
+
+	t.Run("teacher of another school", func(t *testing.T) {
+		_, err := svc.CloneTemplate(ctx, "shared", 3, 20, "copy")
+		assert.ErrorIs(t, err, ErrTemplateNotFound)
+	})
+
+	t.Run("teacher of unknown school", func(t *testing.T) {
+		_, err := svc.CloneTemplate(ctx, "shared", 3, 0, "copy")
+		assert.ErrorIs(t, err, ErrTemplateNotFound)
+	})
+
+	t.Run("template of unknown school", func(t *testing.T) {
+		_, err := svc.CloneTemplate(ctx, "unknown-school", 3, 0, "copy")
+		assert.ErrorIs(t, err, ErrTemplateNotFound)
+	})
+
+	assert.Equal(t, 1, st.clones)
+}
+
+func TestShareTemplate(t *testing.T) {
+	svc, st := newTestService(
+		models.Template{ID: "known", CreatorID: 1, SchoolID: 10, Visibility: models.VisibilityPrivate},
+		models.Template{ID: "unknown", CreatorID: 1, Visibility: models.VisibilityPrivate},
+	)
+
+	ctx := context.Background()
+
+	require.NoError(t, svc.ShareTemplate(ctx, "known", 1, models.VisibilitySchool))
+	assert.Equal(t, models.VisibilitySchool, st.templates["known"].Visibility)
+
+	err := svc.ShareTemplate(ctx, "known", 2, models.VisibilityPublic)
+	assert.ErrorIs(t, err, ErrAccessDenied)
+
+	err = svc.ShareTemplate(ctx, "unknown", 1, models.VisibilitySchool)
+	assert.ErrorIs(t, err, ErrSchoolUnknown)
+	assert.Equal(t, models.VisibilityPrivate, st.templates["unknown"].Visibility)
+
+	require.NoError(t, svc.ShareTemplate(ctx, "unknown", 1, models.VisibilityPublic))
+}
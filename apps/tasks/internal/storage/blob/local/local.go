@@ -0,0 +1,135 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tasks/internal/storage"
+)
+
+// Store keeps the blobs as files in the directory on the local filesystem.
+type Store struct {
+	root string
+}
+
+// New creates a new Store instance.
+// That used to keep the blobs under the root directory, which is created if missing.
+func New(root string) (*Store, error) {
+	const op = "storage.blob.local.New"
+
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return &Store{root: root}, nil
+}
+
+// Put writes the blob to the temporary file and renames it to the key,
+// so the partially written blob is never visible.
+func (s *Store) Put(
+	ctx context.Context,
+	key string,
+	r io.Reader,
+	size int64,
+	contentType string,
+) error {
+	const op = "storage.blob.local.Put"
+
+	path, err := s.path(key)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer os.Remove(f.Name())
+
+	written, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if written != size {
+		return fmt.Errorf("%s: written %d bytes instead of %d", op, written, size)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// Open opens the file of the blob and seeks to the offset.
+func (s *Store) Open(
+	ctx context.Context,
+	key string,
+	offset int64,
+) (io.ReadCloser, error) {
+	const op = "storage.blob.local.Open"
+
+	path, err := s.path(key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrBlobNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return f, nil
+}
+
+// Delete removes the file of the blob.
+func (s *Store) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	const op = "storage.blob.local.Delete"
+
+	path, err := s.path(key)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// path returns the path of the blob file.
+// Blobs are spread over subdirectories by the first characters of the key.
+func (s *Store) path(key string) (string, error) {
+	if len(key) < 3 || strings.ContainsAny(key, `/\`) || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+
+	return filepath.Join(s.root, key[:2], key), nil
+}
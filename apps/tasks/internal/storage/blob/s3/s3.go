@@ -0,0 +1,302 @@
+package s3
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tasks/internal/storage"
+)
+
+const (
+	algorithm       = "AWS4-HMAC-SHA256"
+	service         = "s3"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	timeFormat      = "20060102T150405Z"
+	dateFormat      = "20060102"
+	// defaultTimeout bounds the request when the config does not set the timeout.
+	defaultTimeout = 5 * time.Minute
+)
+
+type Config struct {
+	// Endpoint is the base url of the S3-compatible server, e.g. http://localhost:9000.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// Timeout bounds the whole request to the server, including the transfer of the blob,
+	// so it must be long enough for the largest attachment.
+	Timeout time.Duration
+}
+
+// Store keeps the blobs in the bucket of the S3-compatible object storage.
+// Objects are addressed in path style, which is supported by AWS and by MinIO-like servers,
+// and requests are signed with AWS Signature Version 4.
+type Store struct {
+	client    *http.Client
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	now       func() time.Time
+}
+
+// New creates a new Store instance.
+// That used to interact with the bucket given in the config.
+func New(cfg Config) (*Store, error) {
+	const op = "storage.blob.s3.New"
+
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("%s: invalid endpoint %q", op, cfg.Endpoint)
+	}
+
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("%s: bucket is required", op)
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	return &Store{
+		client:    &http.Client{Timeout: timeout},
+		endpoint:  endpoint,
+		region:    region,
+		bucket:    cfg.Bucket,
+		accessKey: cfg.AccessKey,
+		secretKey: cfg.SecretKey,
+		now:       time.Now,
+	}, nil
+}
+
+// Put uploads the blob as the object with the given key.
+// The payload is streamed unsigned, so the blob is not read twice.
+func (s *Store) Put(
+	ctx context.Context,
+	key string,
+	r io.Reader,
+	size int64,
+	contentType string,
+) error {
+	const op = "storage.blob.s3.Put"
+
+	req, err := s.request(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %v", op, responseError(resp))
+	}
+
+	return nil
+}
+
+// Open downloads the object with the given key starting from the offset.
+func (s *Store) Open(
+	ctx context.Context,
+	key string,
+	offset int64,
+) (io.ReadCloser, error) {
+	const op = "storage.blob.s3.Open"
+
+	req, err := s.request(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrBlobNotFound)
+	default:
+		defer resp.Body.Close()
+
+		return nil, fmt.Errorf("%s: %v", op, responseError(resp))
+	}
+}
+
+// Delete deletes the object with the given key.
+func (s *Store) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	const op = "storage.blob.s3.Delete"
+
+	req, err := s.request(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("%s: %v", op, responseError(resp))
+	}
+}
+
+func (s *Store) request(
+	ctx context.Context,
+	method string,
+	key string,
+	body io.Reader,
+) (*http.Request, error) {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+	u.RawPath = ""
+
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+func (s *Store) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	s.sign(req, s.now().UTC())
+
+	return s.client.Do(req)
+}
+
+// sign adds the AWS Signature Version 4 authorization header to the request.
+// Host, Range and all x-amz-* headers are signed,
+// the hash of the payload is taken from the x-amz-content-sha256 header.
+func (s *Store) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format(timeFormat)
+	date := now.Format(dateFormat)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "range" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	scope := date + "/" + s.region + "/" + service + "/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := algorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, s.accessKey, scope, signedHeaders, signature,
+	))
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+
+		for _, value := range values {
+			pairs = append(pairs, escape(key)+"="+escape(value))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+func escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+
+	return h.Sum(nil)
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,403 @@
+package activity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"tasks/internal/domain/models"
+)
+
+type ActivityRepo struct {
+	db *sql.DB
+}
+
+// New creates a new ActivityRepo instance.
+// That used to interact with the submission_activity table.
+func New(db *sql.DB) *ActivityRepo {
+	return &ActivityRepo{db: db}
+}
+
+// Insert records the activity in the transaction of the change it describes.
+//
+// The activity is inserted without the number, which is taken from the sequence
+// of the assignment by the reader once the transaction is committed, see number.
+// So the writers of the assignment never wait for each other, and the watcher
+// reading the activities after the last seen number never skips one committed later.
+func Insert(ctx context.Context, tx *sql.Tx, activity models.Activity) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`
+		INSERT INTO submission_activity
+		(
+			assignment_id, student_assignment_id, submission_id, student_id,
+			kind, revision, is_late, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`,
+		activity.AssignmentID,
+		activity.StudentAssignmentID,
+		sql.NullString{String: activity.SubmissionID, Valid: activity.SubmissionID != ""},
+		activity.StudentID,
+		activity.Kind,
+		activity.Revision,
+		activity.IsLate,
+		activity.CreatedAt,
+	)
+
+	return err
+}
+
+// number gives the next numbers of the sequence of the assignment
+// to the committed activities which have no number yet, in the order they were inserted.
+//
+// The sequence is locked only while the numbers are given, so the readers
+// of the assignment wait for each other instead of the writers,
+// and the activity is seen by the watcher a poll later than it is committed at most.
+// The activity committed after the numbers are given gets the greater number
+// the next time, so the numbers are still given in the order of the commits.
+func (r *ActivityRepo) number(ctx context.Context, assignmentID string) error {
+	// the sequence is not locked by the polls which find nothing to number
+	var pending bool
+	err := r.db.QueryRowContext(
+		ctx,
+		"SELECT EXISTS (SELECT 1 FROM submission_activity WHERE assignment_id = $1 AND seq IS NULL)",
+		assignmentID,
+	).Scan(&pending)
+	if err != nil || !pending {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	seq, err := reserve(ctx, tx, assignmentID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE submission_activity a
+		SET seq = $2 + p.n
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY id) AS n
+			FROM submission_activity
+			WHERE assignment_id = $1 AND seq IS NULL
+		) p
+		WHERE a.id = p.id
+	`
+
+	res, err := tx.ExecContext(ctx, query, assignmentID, seq)
+	if err != nil {
+		return err
+	}
+
+	numbered, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if numbered == 0 {
+		return nil
+	}
+
+	if err := advance(ctx, tx, assignmentID, seq+numbered); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// reserve locks the sequence of the activities of the assignment
+// until the end of the transaction and returns the last number taken.
+func reserve(ctx context.Context, tx *sql.Tx, assignmentID string) (int64, error) {
+	query := `
+		INSERT INTO activity_sequences (assignment_id, seq)
+		VALUES ($1, 0)
+		ON CONFLICT (assignment_id) DO UPDATE SET seq = activity_sequences.seq
+		RETURNING seq
+	`
+
+	var seq int64
+	if err := tx.QueryRowContext(ctx, query, assignmentID).Scan(&seq); err != nil {
+		return 0, err
+	}
+
+	return seq, nil
+}
+
+// advance moves the sequence of the activities of the assignment to the last number taken.
+func advance(ctx context.Context, tx *sql.Tx, assignmentID string, seq int64) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"UPDATE activity_sequences SET seq = $2 WHERE assignment_id = $1",
+		assignmentID,
+		seq,
+	)
+
+	return err
+}
+
+// Activities returns up to limit activities of the assignment
+// following the activity with the given number, in the order they were committed.
+func (r *ActivityRepo) Activities(
+	ctx context.Context,
+	assignmentID string,
+	afterID int64,
+	limit int,
+) ([]models.Activity, error) {
+	const op = "storage.postgres.Activities"
+
+	if err := r.number(ctx, assignmentID); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	query := `
+		SELECT
+			seq, assignment_id, student_assignment_id, COALESCE(submission_id::TEXT, ''),
+			student_id, kind, revision, is_late, created_at
+		FROM submission_activity
+		WHERE assignment_id = $1 AND seq > $2
+		ORDER BY seq
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, assignmentID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var activities []models.Activity
+	for rows.Next() {
+		var activity models.Activity
+		if err := rows.Scan(
+			&activity.ID,
+			&activity.AssignmentID,
+			&activity.StudentAssignmentID,
+			&activity.SubmissionID,
+			&activity.StudentID,
+			&activity.Kind,
+			&activity.Revision,
+			&activity.IsLate,
+			&activity.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		activities = append(activities, activity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return activities, nil
+}
+
+// HasActivity reports whether the activity of the assignment with the number is still kept.
+func (r *ActivityRepo) HasActivity(
+	ctx context.Context,
+	assignmentID string,
+	activityID int64,
+) (bool, error) {
+	const op = "storage.postgres.HasActivity"
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM submission_activity
+			WHERE assignment_id = $1 AND seq = $2
+		)
+	`
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, assignmentID, activityID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return exists, nil
+}
+
+// LastActivityID returns the number of the latest activity of the assignment,
+// or zero if the assignment has no activity.
+func (r *ActivityRepo) LastActivityID(
+	ctx context.Context,
+	assignmentID string,
+) (int64, error) {
+	const op = "storage.postgres.LastActivityID"
+
+	if err := r.number(ctx, assignmentID); err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	query := `
+		SELECT seq
+		FROM activity_sequences
+		WHERE assignment_id = $1
+	`
+
+	var seq int64
+	err := r.db.QueryRowContext(ctx, query, assignmentID).Scan(&seq)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return seq, nil
+}
+
+// RecordTimeLimits records the due dates and the cutoff dates
+// of the published student assignments which passed after since and until now
+// while the work was not submitted. Every time limit is recorded once.
+// It returns the number of recorded activities.
+func (r *ActivityRepo) RecordTimeLimits(
+	ctx context.Context,
+	since time.Time,
+	now time.Time,
+) (int64, error) {
+	const op = "storage.postgres.RecordTimeLimits"
+
+	query := `
+		SELECT DISTINCT sa.assignment_id
+		FROM student_assignments sa
+		INNER JOIN assignments a ON a.id = sa.assignment_id
+		WHERE a.published_at IS NOT NULL AND a.deleted_at IS NULL
+			AND sa.status IN ($3, $4, $5)
+			AND (
+				(sa.due_date > $1 AND sa.due_date <= $2)
+				OR (sa.cutoff_date > $1 AND sa.cutoff_date <= $2)
+			)
+	`
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		since,
+		now,
+		models.StatusNotStarted,
+		models.StatusInProgress,
+		models.StatusReturned,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var assignmentIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return 0, fmt.Errorf("%s: %v", op, err)
+		}
+
+		assignmentIDs = append(assignmentIDs, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	var total int64
+	for _, assignmentID := range assignmentIDs {
+		recorded, err := r.recordTimeLimits(ctx, assignmentID, since, now)
+		if err != nil {
+			return total, fmt.Errorf("%s: %v", op, err)
+		}
+
+		total += recorded
+	}
+
+	return total, nil
+}
+
+// recordTimeLimits records the time limits of the students of the assignment.
+func (r *ActivityRepo) recordTimeLimits(
+	ctx context.Context,
+	assignmentID string,
+	since time.Time,
+	now time.Time,
+) (int64, error) {
+	query := `
+		INSERT INTO submission_activity
+		(
+			assignment_id, student_assignment_id, submission_id, student_id,
+			kind, revision, is_late, created_at
+		)
+		SELECT
+			sa.assignment_id, sa.id, s.id, sa.student_id,
+			l.kind, COALESCE(s.revision, 0), FALSE, l.passed_at
+		FROM student_assignments sa
+		CROSS JOIN LATERAL (
+			VALUES ($7, sa.due_date), ($8, sa.cutoff_date)
+		) AS l(kind, passed_at)
+		LEFT JOIN LATERAL (
+			SELECT id, revision
+			FROM submissions
+			WHERE assignment_id = sa.id AND deleted_at IS NULL
+			ORDER BY started_at DESC
+			LIMIT 1
+		) s ON TRUE
+		WHERE sa.assignment_id = $1 AND l.passed_at > $2 AND l.passed_at <= $3
+			AND sa.status IN ($4, $5, $6)
+		ORDER BY l.passed_at, sa.id
+		ON CONFLICT (student_assignment_id, kind)
+			WHERE kind IN ('due_date_passed', 'cutoff_passed')
+			DO NOTHING
+	`
+
+	res, err := r.db.ExecContext(
+		ctx,
+		query,
+		assignmentID,
+		since,
+		now,
+		models.StatusNotStarted,
+		models.StatusInProgress,
+		models.StatusReturned,
+		models.ActivityDuePassed,
+		models.ActivityCutoffPassed,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// PurgeActivity permanently deletes up to limit activities
+// which occurred before the given time.
+// It returns the number of deleted activities.
+func (r *ActivityRepo) PurgeActivity(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) (int64, error) {
+	const op = "storage.postgres.PurgeActivity"
+
+	query := `
+		DELETE FROM submission_activity
+		WHERE id IN (
+			SELECT id
+			FROM submission_activity
+			WHERE created_at < $1
+			LIMIT $2
+		)
+	`
+
+	res, err := r.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return affected, nil
+}
@@ -4,11 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"tasks/internal/domain/models"
 	"tasks/internal/storage"
+	"tasks/internal/storage/postgres/outbox"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	eventsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/events/v1"
 )
 
 type AssignmentRepo struct {
@@ -21,133 +29,1085 @@ func New(db *sql.DB) *AssignmentRepo {
 	return &AssignmentRepo{db: db}
 }
 
+// SaveAssignment saves the assignment with its target students.
+// If the assignment is due to be published, it is published in the same transaction.
+// The assignment is announced with the AssignmentCreated event.
 func (r *AssignmentRepo) SaveAssignment(
 	ctx context.Context,
 	assignment models.Assignment,
-) (int64, error) {
-	const op = "storage.postgres.SaveASsignment"
+) (string, error) {
+	const op = "storage.postgres.SaveAssignment"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var criteria []byte
+	if assignment.PeerReview.Enabled() {
+		criteria, err = json.Marshal(assignment.PeerReview.Criteria)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", op, err)
+		}
+	}
 
 	query := `
 		INSERT INTO assignments
-		(teacher_id, title, content, created_at, updated_at, deadline)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		(
+			id, template_id, creator_id, title, due_date, cutoff_date, publish_at,
+			peer_reviewers_count, peer_review_due_date, peer_review_criteria,
+			anonymous, anonymity_key, school_id
+		)
+		VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id
 	`
-	res := r.db.QueryRowContext(
+
+	var id string
+	err = tx.QueryRowContext(
 		ctx,
 		query,
-		assignment.TeacherID,
+		assignment.TemplateID,
+		assignment.CreatorID,
 		assignment.Title,
-		assignment.Content,
-		time.Now().Unix(),
-		time.Now().Unix(),
-		assignment.Deadline.Unix(),
-	)
+		assignment.DueDate,
+		assignment.CutoffDate,
+		assignment.PublishAt,
+		assignment.PeerReview.ReviewersCount,
+		nullTime(assignment.PeerReview.DueDate),
+		criteria,
+		assignment.Anonymous,
+		assignment.AnonymityKey,
+		assignment.SchoolID,
+	).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	for _, studentID := range assignment.StudentIDs {
+		_, err = tx.ExecContext(
+			ctx,
+			`
+			INSERT INTO assignment_targets (assignment_id, student_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+			`,
+			id,
+			studentID,
+		)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	now := time.Now().UTC()
 
-	var id int64
-	err := res.Scan(&id)
+	studentIDs := make([]string, 0, len(assignment.StudentIDs))
+	for _, studentID := range assignment.StudentIDs {
+		studentIDs = append(studentIDs, strconv.FormatInt(studentID, 10))
+	}
+
+	err = outbox.Insert(ctx, tx, id, now, &eventsv1.AssignmentCreated{
+		AssignmentId: id,
+		CreatorId:    strconv.FormatInt(assignment.CreatorID, 10),
+		Title:        assignment.Title,
+		TemplateId:   assignment.TemplateID,
+		StudentIds:   studentIDs,
+		DueDate:      timestamppb.New(assignment.DueDate),
+		CutoffDate:   timestamppb.New(assignment.CutoffDate),
+		PublishAt:    timestamppb.New(assignment.PublishAt),
+	})
 	if err != nil {
-		return 0, err
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	if !assignment.PublishAt.After(now) {
+		if err := publish(ctx, tx, []string{id}, now); err != nil {
+			return "", fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
 	}
 
 	return id, nil
 }
 
+// UpdateAssignment changes the given fields of the assignment.
+// Dates of already materialized student assignments are updated as well,
+// the extended ones are only moved to the later dates.
 func (r *AssignmentRepo) UpdateAssignment(
 	ctx context.Context,
-	assignmentID int64,
-	updates map[string]any,
-	updateTargets bool,
-	studentIDs []int64,
+	assignmentID string,
+	update models.AssignmentUpdate,
 ) error {
-	tx, err := r.db.Begin()
+	const op = "storage.postgres.UpdateAssignment"
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return fmt.Errorf("%s: %v", op, err)
 	}
 	defer tx.Rollback()
 
-	if len(updates) > 0 {
-		query := "UPDATE assignments SET "
-		args := []any{}
-		argID := 1
+	query := `
+		UPDATE assignments
+		SET
+			title = COALESCE($2, title),
+			due_date = COALESCE($3, due_date),
+			cutoff_date = COALESCE($4, cutoff_date),
+			publish_at = COALESCE($5, publish_at),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING creator_id, title, due_date, cutoff_date, publish_at
+	`
+
+	var (
+		creatorID  int64
+		title      string
+		dueDate    time.Time
+		cutoffDate time.Time
+		publishAt  time.Time
+	)
 
-		i := 0
-		for col, val := range updates {
-			if i > 0 {
-				query += ", "
-			}
-			query += fmt.Sprintf("%s = %d", col, argID)
-			args = append(args, val)
-			argID++
-			i++
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		assignmentID,
+		update.Title,
+		update.DueDate,
+		update.CutoffDate,
+		update.PublishAt,
+	).Scan(&creatorID, &title, &dueDate, &cutoffDate, &publishAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrAssignmentNotFound)
 		}
 
-		query += fmt.Sprintf(" WHERE id = %d", argID)
-		args = append(args, assignmentID)
+		return fmt.Errorf("%s: %w", op, storage.ErrAssignmentUpdateFailed)
+	}
 
-		_, err := tx.ExecContext(ctx, query, args...)
-		if err != nil {
-			return storage.ErrAssignmentUpdateFailed
+	if update.DueDate != nil || update.CutoffDate != nil {
+		if err := rescheduleStudents(ctx, tx, assignmentID, update); err != nil {
+			return fmt.Errorf("%s: %v", op, err)
 		}
 	}
 
-	if updateTargets {
-		_, err = tx.ExecContext(
+	students, err := publishedStudents(ctx, tx, assignmentID)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	err = outbox.Insert(ctx, tx, assignmentID, time.Now().UTC(), &eventsv1.AssignmentUpdated{
+		AssignmentId: assignmentID,
+		CreatorId:    strconv.FormatInt(creatorID, 10),
+		Title:        title,
+		DueDate:      timestamppb.New(dueDate),
+		CutoffDate:   timestamppb.New(cutoffDate),
+		PublishAt:    timestamppb.New(publishAt),
+		Students:     students,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// rescheduleStudents moves the dates of the student assignments
+// after the update of the assignment dates, keeping the extensions.
+func rescheduleStudents(
+	ctx context.Context,
+	tx *sql.Tx,
+	assignmentID string,
+	update models.AssignmentUpdate,
+) error {
+	query := `
+		SELECT id, due_date, cutoff_date, extended_at
+		FROM student_assignments
+		WHERE assignment_id = $1
+		FOR UPDATE
+	`
+
+	rows, err := tx.QueryContext(ctx, query, assignmentID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var assignments []models.StudentAssignment
+	for rows.Next() {
+		var (
+			assignment models.StudentAssignment
+			extendedAt sql.NullTime
+		)
+		if err := rows.Scan(
+			&assignment.ID,
+			&assignment.DueDate,
+			&assignment.CutoffDate,
+			&extendedAt,
+		); err != nil {
+			return err
+		}
+
+		assignment.ExtendedAt = extendedAt.Time
+
+		assignments = append(assignments, assignment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, assignment := range assignments {
+		dueDate, cutoffDate := assignment.Reschedule(update)
+		if dueDate.Equal(assignment.DueDate) && cutoffDate.Equal(assignment.CutoffDate) {
+			continue
+		}
+
+		_, err := tx.ExecContext(
 			ctx,
-			"DELETE FROM assignment_targets WHERE assignment_id = $1",
-			assignmentID,
+			`
+			UPDATE student_assignments
+			SET due_date = $2, cutoff_date = $3, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1
+			`,
+			assignment.ID,
+			dueDate,
+			cutoffDate,
 		)
 		if err != nil {
 			return err
 		}
+	}
 
-		for _, studentID := range studentIDs {
-			_, err = tx.ExecContext(
-				ctx,
-				"INSERT INTO assignment_targets (assignment_id, student_id) VALUES ($1, $2)",
-				assignmentID,
-				studentID,
-			)
-			if err != nil {
-				return err
-			}
-		}
+	return nil
+}
+
+// ExtendStudentAssignment moves the dates of the published assignment
+// for a single student. The change is announced with the AssignmentUpdated event.
+func (r *AssignmentRepo) ExtendStudentAssignment(
+	ctx context.Context,
+	extension models.Extension,
+) error {
+	const op = "storage.postgres.ExtendStudentAssignment"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
 	}
+	defer tx.Rollback()
 
-	return tx.Commit()
+	query := `
+		UPDATE student_assignments
+		SET due_date = $3, cutoff_date = $4, extended_at = $5, updated_at = $5
+		WHERE assignment_id = $1 AND student_id = $2
+	`
+
+	res, err := tx.ExecContext(
+		ctx,
+		query,
+		extension.AssignmentID,
+		extension.StudentID,
+		extension.DueDate,
+		extension.CutoffDate,
+		extension.ExtendedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAssignmentNotFound)
+	}
+
+	query = `
+		SELECT creator_id, title, due_date, cutoff_date, publish_at
+		FROM assignments
+		WHERE id = $1
+	`
+
+	event := &eventsv1.AssignmentUpdated{AssignmentId: extension.AssignmentID}
+
+	var (
+		creatorID  int64
+		dueDate    time.Time
+		cutoffDate time.Time
+		publishAt  time.Time
+	)
+
+	err = tx.QueryRowContext(ctx, query, extension.AssignmentID).Scan(
+		&creatorID,
+		&event.Title,
+		&dueDate,
+		&cutoffDate,
+		&publishAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	event.CreatorId = strconv.FormatInt(creatorID, 10)
+	event.DueDate = timestamppb.New(dueDate)
+	event.CutoffDate = timestamppb.New(cutoffDate)
+	event.PublishAt = timestamppb.New(publishAt)
+
+	event.Students, err = publishedStudents(ctx, tx, extension.AssignmentID)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := outbox.Insert(ctx, tx, extension.AssignmentID, extension.ExtendedAt, event); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
 }
 
+// Assignment returns the assignment with the given ID.
 func (r *AssignmentRepo) Assignment(
 	ctx context.Context,
-	assignmentID int64,
+	assignmentID string,
 ) (models.Assignment, error) {
 	const op = "storage.postgres.Assignment"
 
 	query := `
-		SELECT *
-		FROM assignments
-		WHERE id = $1
+		SELECT
+			a.id, a.template_id, a.creator_id, a.title,
+			w.id, w.type, w.version, t.widget_config,
+			a.due_date, a.cutoff_date, a.publish_at, a.published_at,
+			a.peer_reviewers_count, a.peer_review_due_date, a.peer_review_criteria,
+			a.peer_reviews_assigned_at,
+			a.anonymous, a.anonymity_key, a.identities_revealed_at,
+			a.created_at, a.updated_at, a.deleted_at
+		FROM assignments a
+		INNER JOIN assignment_templates t ON t.id = a.template_id
+		INNER JOIN widgets w ON w.id = t.widget_id
+		WHERE a.id = $1
 	`
 
-	var assignment models.Assignment
-	var contentBytes []byte
+	var (
+		assignment       models.Assignment
+		config           []byte
+		publishedAt      sql.NullTime
+		reviewDueDate    sql.NullTime
+		reviewCriteria   []byte
+		reviewAssignedAt sql.NullTime
+		revealedAt       sql.NullTime
+		deletedAt        sql.NullTime
+	)
 
 	err := r.db.QueryRowContext(ctx, query, assignmentID).Scan(
 		&assignment.ID,
-		&assignment.TeacherID,
+		&assignment.TemplateID,
+		&assignment.CreatorID,
 		&assignment.Title,
-		&contentBytes,
+		&assignment.Widget.ID,
+		&assignment.Widget.Type,
+		&assignment.Widget.Version,
+		&config,
+		&assignment.DueDate,
+		&assignment.CutoffDate,
+		&assignment.PublishAt,
+		&publishedAt,
+		&assignment.PeerReview.ReviewersCount,
+		&reviewDueDate,
+		&reviewCriteria,
+		&reviewAssignedAt,
+		&assignment.Anonymous,
+		&assignment.AnonymityKey,
+		&revealedAt,
 		&assignment.CreatedAt,
 		&assignment.UpdatedAt,
-		&assignment.Deadline,
+		&deletedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Assignment{}, fmt.Errorf("%s: %w", op, storage.ErrAssignmentNotFound)
+		}
+
+		return models.Assignment{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	assignment.Widget.Config = config
+	assignment.PublishedAt = publishedAt.Time
+	assignment.DeletedAt = deletedAt.Time
+	assignment.PeerReview.DueDate = reviewDueDate.Time
+	assignment.PeerReview.AssignedAt = reviewAssignedAt.Time
+	assignment.IdentitiesRevealedAt = revealedAt.Time
+
+	if reviewCriteria != nil {
+		if err := json.Unmarshal(reviewCriteria, &assignment.PeerReview.Criteria); err != nil {
+			return models.Assignment{}, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		"SELECT student_id FROM assignment_targets WHERE assignment_id = $1 ORDER BY student_id",
+		assignmentID,
+	)
+	if err != nil {
+		return models.Assignment{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var studentID int64
+		if err := rows.Scan(&studentID); err != nil {
+			return models.Assignment{}, fmt.Errorf("%s: %v", op, err)
+		}
+
+		assignment.StudentIDs = append(assignment.StudentIDs, studentID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.Assignment{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return assignment, nil
+}
+
+const selectStudentAssignment = `
+	SELECT
+		sa.id, sa.student_id, sa.status, sa.due_date, sa.cutoff_date,
+		a.id, a.template_id, a.creator_id, a.title,
+		w.id, w.type, w.version, t.widget_config,
+		a.publish_at, a.published_at
+	FROM student_assignments sa
+	INNER JOIN assignments a ON a.id = sa.assignment_id
+	INNER JOIN assignment_templates t ON t.id = a.template_id
+	INNER JOIN widgets w ON w.id = t.widget_id
+`
+
+// StudentAssignments returns the page of published assignments of the student.
+func (r *AssignmentRepo) StudentAssignments(
+	ctx context.Context,
+	studentID int64,
+	filter models.Filter,
+) ([]models.StudentAssignment, error) {
+	const op = "storage.postgres.StudentAssignments"
+
+	query := selectStudentAssignment + `
+		WHERE sa.student_id = $1 AND a.published_at IS NOT NULL AND a.deleted_at IS NULL
+		ORDER BY sa.due_date, sa.id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, studentID, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var assignments []models.StudentAssignment
+	for rows.Next() {
+		assignment, err := scanStudentAssignment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		assignments = append(assignments, assignment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return assignments, nil
+}
+
+// StudentAssignment returns the published assignment of the student
+// together with the latest submission.
+// Assignments which are not published yet are reported as not found.
+func (r *AssignmentRepo) StudentAssignment(
+	ctx context.Context,
+	studentAssignmentID string,
+	studentID int64,
+) (models.StudentAssignment, error) {
+	const op = "storage.postgres.StudentAssignment"
+
+	query := selectStudentAssignment + `
+		WHERE sa.id = $1 AND sa.student_id = $2
+			AND a.published_at IS NOT NULL AND a.deleted_at IS NULL
+	`
+
+	assignment, err := scanStudentAssignment(r.db.QueryRowContext(ctx, query, studentAssignmentID, studentID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.StudentAssignment{}, fmt.Errorf("%s: %w", op, storage.ErrAssignmentNotFound)
+		}
+
+		return models.StudentAssignment{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	query = `
+		SELECT
+			s.id, s.status, s.revision, s.started_at, s.submitted_at,
+			v.id, v.version_number, v.payload, v.is_late, v.created_at, v.updated_at
+		FROM submissions s
+		LEFT JOIN submission_versions v ON v.id = s.current_version_id
+		WHERE s.assignment_id = $1 AND s.deleted_at IS NULL
+		ORDER BY s.started_at DESC
+		LIMIT 1
+	`
+
+	var (
+		submission  models.Submission
+		submittedAt sql.NullTime
+		versionID   sql.NullString
+		number      sql.NullInt64
+		payload     []byte
+		isLate      sql.NullBool
+		createdAt   sql.NullTime
+		updatedAt   sql.NullTime
+	)
+
+	err = r.db.QueryRowContext(ctx, query, studentAssignmentID).Scan(
+		&submission.ID,
+		&submission.Status,
+		&submission.Revision,
+		&submission.StartedAt,
+		&submittedAt,
+		&versionID,
+		&number,
+		&payload,
+		&isLate,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return assignment, nil
+		}
+
+		return models.StudentAssignment{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	submission.AssignmentID = studentAssignmentID
+	submission.StudentID = studentID
+	submission.SubmittedAt = submittedAt.Time
+	submission.DueDate = assignment.DueDate
+	submission.CutoffDate = assignment.CutoffDate
+
+	if versionID.Valid {
+		submission.CurrentVersion = &models.SubmissionVersion{
+			ID:           versionID.String,
+			SubmissionID: submission.ID,
+			Number:       int(number.Int64),
+			Payload:      payload,
+			IsLate:       isLate.Bool,
+			CreatedAt:    createdAt.Time,
+			UpdatedAt:    updatedAt.Time,
+		}
+		submission.UpdatedAt = updatedAt.Time
+	}
+
+	assignment.Submission = &submission
+
+	return assignment, nil
+}
+
+// DeleteAssignment moves the assignment to the trash.
+// Student assignments and submissions are kept, but hidden from the students.
+// The deletion is announced with the AssignmentDeleted event.
+func (r *AssignmentRepo) DeleteAssignment(
+	ctx context.Context,
+	assignmentID string,
+) error {
+	const op = "storage.postgres.DeleteAssignment"
+
+	query := `
+		UPDATE assignments
+		SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING creator_id
+	`
+
+	err := r.announce(ctx, query, assignmentID, func(creatorID int64) proto.Message {
+		return &eventsv1.AssignmentDeleted{
+			AssignmentId: assignmentID,
+			CreatorId:    strconv.FormatInt(creatorID, 10),
+		}
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrAssignmentNotFound)
+		}
+
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// RestoreAssignment moves the assignment out of the trash.
+// The restoration is announced with the AssignmentRestored event.
+func (r *AssignmentRepo) RestoreAssignment(
+	ctx context.Context,
+	assignmentID string,
+) error {
+	const op = "storage.postgres.RestoreAssignment"
+
+	query := `
+		UPDATE assignments
+		SET deleted_at = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING creator_id
+	`
+
+	err := r.announce(ctx, query, assignmentID, func(creatorID int64) proto.Message {
+		return &eventsv1.AssignmentRestored{
+			AssignmentId: assignmentID,
+			CreatorId:    strconv.FormatInt(creatorID, 10),
+		}
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrAssignmentNotFound)
+		}
+
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// announce executes the update of the single assignment returning its creator
+// and writes the event built from the creator to the outbox in the same transaction.
+// If no rows are affected, it returns sql.ErrNoRows.
+func (r *AssignmentRepo) announce(
+	ctx context.Context,
+	query string,
+	assignmentID string,
+	event func(creatorID int64) proto.Message,
+) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var creatorID int64
+	if err := tx.QueryRowContext(ctx, query, assignmentID).Scan(&creatorID); err != nil {
+		return err
+	}
+
+	if err := outbox.Insert(ctx, tx, assignmentID, time.Now().UTC(), event(creatorID)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeletedAssignments returns the page of assignments of the teacher in the trash,
+// the most recently deleted first.
+func (r *AssignmentRepo) DeletedAssignments(
+	ctx context.Context,
+	creatorID int64,
+	filter models.Filter,
+) ([]models.TrashItem, error) {
+	const op = "storage.postgres.DeletedAssignments"
+
+	query := `
+		SELECT id, title, deleted_at
+		FROM assignments
+		WHERE creator_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, creatorID, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var items []models.TrashItem
+	for rows.Next() {
+		item := models.TrashItem{Kind: models.TrashAssignment}
+		if err := rows.Scan(&item.ID, &item.Title, &item.DeletedAt); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return items, nil
+}
+
+// PurgeAssignments permanently deletes up to limit assignments
+// which were moved to the trash before the given time.
+// Student assignments, submissions and their versions are deleted by cascade.
+// It returns the number of deleted assignments.
+func (r *AssignmentRepo) PurgeAssignments(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) (int64, error) {
+	const op = "storage.postgres.PurgeAssignments"
+
+	query := `
+		DELETE FROM assignments
+		WHERE id IN (
+			SELECT id
+			FROM assignments
+			WHERE deleted_at < $1
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+
+	res, err := r.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return affected, nil
+}
+
+// RevealIdentities reveals the students of the anonymous assignment
+// and records the reveal in the audit log in the same transaction.
+// If the identities are already revealed, only the audit record is added.
+func (r *AssignmentRepo) RevealIdentities(
+	ctx context.Context,
+	reveal models.IdentityReveal,
+) error {
+	const op = "storage.postgres.RevealIdentities"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(
+		ctx,
+		`
+		UPDATE assignments
+		SET identities_revealed_at = COALESCE(identities_revealed_at, $2)
+		WHERE id = $1 AND anonymous AND deleted_at IS NULL
+		`,
+		reveal.AssignmentID,
+		reveal.RevealedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAssignmentNotFound)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`
+		INSERT INTO identity_reveals (id, assignment_id, teacher_id, reason, revealed_at)
+		VALUES (gen_random_uuid(), $1, $2, $3, $4)
+		`,
+		reveal.AssignmentID,
+		reveal.TeacherID,
+		reveal.Reason,
+		reveal.RevealedAt,
 	)
 	if err != nil {
-		return models.Assignment{}, err
+		return fmt.Errorf("%s: %v", op, err)
 	}
 
-	if err := json.Unmarshal(contentBytes, &assignment.Content); err != nil {
-		return models.Assignment{}, err
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
 	}
 
+	return nil
+}
+
+// IdentityReveals returns the audit log of the reveals of the assignment, the oldest first.
+func (r *AssignmentRepo) IdentityReveals(
+	ctx context.Context,
+	assignmentID string,
+) ([]models.IdentityReveal, error) {
+	const op = "storage.postgres.IdentityReveals"
+
+	query := `
+		SELECT id, assignment_id, teacher_id, reason, revealed_at
+		FROM identity_reveals
+		WHERE assignment_id = $1
+		ORDER BY revealed_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, assignmentID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var reveals []models.IdentityReveal
+	for rows.Next() {
+		var reveal models.IdentityReveal
+		if err := rows.Scan(
+			&reveal.ID,
+			&reveal.AssignmentID,
+			&reveal.TeacherID,
+			&reveal.Reason,
+			&reveal.RevealedAt,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		reveals = append(reveals, reveal)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return reveals, nil
+}
+
+// PublishDue publishes up to limit assignments whose publish time has come
+// and materializes student assignments for their targets.
+// It returns the IDs of the published assignments.
+//
+// Rows are locked with SKIP LOCKED, so several instances of the service
+// can run the scheduler at the same time without publishing twice.
+func (r *AssignmentRepo) PublishDue(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) ([]string, error) {
+	const op = "storage.postgres.PublishDue"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id
+		FROM assignments
+		WHERE published_at IS NULL AND publish_at <= $1 AND deleted_at IS NULL
+		ORDER BY publish_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := tx.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	if err := publish(ctx, tx, ids, now); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return ids, nil
+}
+
+// publish materializes student assignments of the given assignments
+// and marks them as published.
+// Student assignments that already exist are kept, so publishing is idempotent.
+// Every newly published assignment is announced with the AssignmentPublished event.
+func publish(ctx context.Context, tx *sql.Tx, ids []string, now time.Time) error {
+	query := `
+		INSERT INTO student_assignments
+		(id, template_id, assignment_id, student_id, due_date, cutoff_date, status)
+		SELECT
+			gen_random_uuid(), a.template_id, a.id, t.student_id,
+			a.due_date, a.cutoff_date, $2
+		FROM assignments a
+		INNER JOIN assignment_targets t ON t.assignment_id = a.id
+		WHERE a.id = ANY($1)
+		ON CONFLICT (assignment_id, student_id) DO NOTHING
+	`
+
+	if _, err := tx.ExecContext(ctx, query, ids, models.StatusNotStarted); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE assignments
+		SET published_at = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ANY($1) AND published_at IS NULL
+		RETURNING id, creator_id, title
+	`
+
+	rows, err := tx.QueryContext(ctx, query, ids, now)
+	if err != nil {
+		return err
+	}
+
+	var events []*eventsv1.AssignmentPublished
+	for rows.Next() {
+		var (
+			event     eventsv1.AssignmentPublished
+			creatorID int64
+		)
+		if err := rows.Scan(&event.AssignmentId, &creatorID, &event.Title); err != nil {
+			rows.Close()
+
+			return err
+		}
+
+		event.CreatorId = strconv.FormatInt(creatorID, 10)
+		event.PublishedAt = timestamppb.New(now)
+		events = append(events, &event)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		event.Students, err = publishedStudents(ctx, tx, event.AssignmentId)
+		if err != nil {
+			return err
+		}
+
+		if err := outbox.Insert(ctx, tx, event.AssignmentId, now, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// publishedStudents returns the student assignments of the assignment for its events.
+func publishedStudents(
+	ctx context.Context,
+	tx *sql.Tx,
+	assignmentID string,
+) ([]*eventsv1.StudentAssignment, error) {
+	query := `
+		SELECT id, student_id, due_date, cutoff_date
+		FROM student_assignments
+		WHERE assignment_id = $1
+		ORDER BY student_id
+	`
+
+	rows, err := tx.QueryContext(ctx, query, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var students []*eventsv1.StudentAssignment
+	for rows.Next() {
+		var (
+			id         string
+			studentID  int64
+			dueDate    time.Time
+			cutoffDate time.Time
+		)
+		if err := rows.Scan(&id, &studentID, &dueDate, &cutoffDate); err != nil {
+			return nil, err
+		}
+
+		students = append(students, &eventsv1.StudentAssignment{
+			StudentAssignmentId: id,
+			StudentId:           strconv.FormatInt(studentID, 10),
+			DueDate:             timestamppb.New(dueDate),
+			CutoffDate:          timestamppb.New(cutoffDate),
+		})
+	}
+
+	return students, rows.Err()
+}
+
+// nullTime converts the zero time to NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanStudentAssignment(row scanner) (models.StudentAssignment, error) {
+	var (
+		assignment  models.StudentAssignment
+		config      []byte
+		publishedAt sql.NullTime
+	)
+
+	err := row.Scan(
+		&assignment.ID,
+		&assignment.StudentID,
+		&assignment.Status,
+		&assignment.DueDate,
+		&assignment.CutoffDate,
+		&assignment.Assignment.ID,
+		&assignment.Assignment.TemplateID,
+		&assignment.Assignment.CreatorID,
+		&assignment.Assignment.Title,
+		&assignment.Assignment.Widget.ID,
+		&assignment.Assignment.Widget.Type,
+		&assignment.Assignment.Widget.Version,
+		&config,
+		&assignment.Assignment.PublishAt,
+		&publishedAt,
+	)
+	if err != nil {
+		return models.StudentAssignment{}, err
+	}
+
+	assignment.Assignment.Widget.Config = config
+	assignment.Assignment.PublishedAt = publishedAt.Time
+	assignment.Assignment.DueDate = assignment.DueDate
+	assignment.Assignment.CutoffDate = assignment.CutoffDate
+
 	return assignment, nil
 }
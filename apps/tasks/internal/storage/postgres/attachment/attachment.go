@@ -0,0 +1,371 @@
+package attachment
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+)
+
+type AttachmentRepo struct {
+	db *sql.DB
+}
+
+// New creates a new AttachmentRepo instance.
+// That used to interact with the attachments table.
+func New(db *sql.DB) *AttachmentRepo {
+	return &AttachmentRepo{db: db}
+}
+
+// SaveAttachment saves the attachment if the total size of the attachments
+// of its owner stays within the quota, otherwise returns storage.ErrQuotaExceeded.
+// If the attachment has the preview, it is queued for generation.
+func (r *AttachmentRepo) SaveAttachment(
+	ctx context.Context,
+	attachment models.Attachment,
+	quota int64,
+) (string, error) {
+	const op = "storage.postgres.SaveAttachment"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	// the lock serializes concurrent uploads of the owner, so the quota cannot be overrun
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", attachment.OwnerID); err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	var used int64
+	err = tx.QueryRowContext(
+		ctx,
+		"SELECT COALESCE(SUM(size), 0) FROM attachments WHERE owner_id = $1 AND submission_id IS NOT NULL",
+		attachment.OwnerID,
+	).Scan(&used)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	if used+attachment.Size > quota {
+		return "", fmt.Errorf("%s: %w", op, storage.ErrQuotaExceeded)
+	}
+
+	query := `
+		INSERT INTO attachments
+		(id, submission_id, owner_id, filename, content_type, size, checksum, storage_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err = tx.ExecContext(
+		ctx,
+		query,
+		attachment.ID,
+		attachment.SubmissionID,
+		attachment.OwnerID,
+		attachment.Filename,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.Checksum,
+		attachment.StorageKey,
+	)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	if attachment.Preview != nil {
+		_, err = tx.ExecContext(
+			ctx,
+			"INSERT INTO attachment_previews (attachment_id, status) VALUES ($1, $2)",
+			attachment.ID,
+			models.PreviewPending,
+		)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	return attachment.ID, nil
+}
+
+const selectAttachment = `
+	SELECT
+		at.id, COALESCE(at.submission_id::text, ''), at.owner_id, COALESCE(a.creator_id, 0),
+		at.filename, at.content_type, at.size, at.checksum, at.storage_key, at.created_at,
+		p.status, p.thumbnail_key, p.preview_key, COALESCE(p.attempts, 0), p.error
+	FROM attachments at
+	LEFT JOIN attachment_previews p ON p.attachment_id = at.id
+	LEFT JOIN submissions s ON s.id = at.submission_id
+	LEFT JOIN student_assignments sa ON sa.id = s.assignment_id
+	LEFT JOIN assignments a ON a.id = sa.assignment_id
+`
+
+// Attachment returns the attachment with the given ID.
+func (r *AttachmentRepo) Attachment(
+	ctx context.Context,
+	attachmentID string,
+) (models.Attachment, error) {
+	const op = "storage.postgres.Attachment"
+
+	query := selectAttachment + "WHERE at.id = $1 AND at.submission_id IS NOT NULL"
+
+	attachment, err := scanAttachment(r.db.QueryRowContext(ctx, query, attachmentID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Attachment{}, fmt.Errorf("%s: %w", op, storage.ErrAttachmentNotFound)
+		}
+
+		return models.Attachment{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return attachment, nil
+}
+
+// SubmissionAttachments returns the attachments uploaded to the submission.
+func (r *AttachmentRepo) SubmissionAttachments(
+	ctx context.Context,
+	submissionID string,
+) ([]models.Attachment, error) {
+	const op = "storage.postgres.SubmissionAttachments"
+
+	query := selectAttachment + "WHERE at.submission_id = $1 ORDER BY at.created_at"
+
+	attachments, err := r.query(ctx, query, submissionID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return attachments, nil
+}
+
+// UsedQuota returns the total size of the attachments of the owner.
+func (r *AttachmentRepo) UsedQuota(
+	ctx context.Context,
+	ownerID int64,
+) (int64, error) {
+	const op = "storage.postgres.UsedQuota"
+
+	query := `
+		SELECT COALESCE(SUM(size), 0)
+		FROM attachments
+		WHERE owner_id = $1 AND submission_id IS NOT NULL
+	`
+
+	var used int64
+	if err := r.db.QueryRowContext(ctx, query, ownerID).Scan(&used); err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return used, nil
+}
+
+// OrphanedAttachments returns up to limit attachments whose submissions were purged.
+func (r *AttachmentRepo) OrphanedAttachments(
+	ctx context.Context,
+	limit int,
+) ([]models.Attachment, error) {
+	const op = "storage.postgres.OrphanedAttachments"
+
+	query := selectAttachment + "WHERE at.submission_id IS NULL ORDER BY at.created_at LIMIT $1"
+
+	attachments, err := r.query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return attachments, nil
+}
+
+// ClaimPreviews leases up to limit queued previews to the worker until lockedUntil
+// and returns their attachments. Previews whose lease has expired are claimed again,
+// so the work of the crashed worker is not lost.
+func (r *AttachmentRepo) ClaimPreviews(
+	ctx context.Context,
+	now time.Time,
+	lockedUntil time.Time,
+	limit int,
+) ([]models.Attachment, error) {
+	const op = "storage.postgres.ClaimPreviews"
+
+	query := `
+		UPDATE attachment_previews
+		SET status = $4, attempts = attempts + 1, locked_until = $2, updated_at = $1
+		WHERE attachment_id IN (
+			SELECT attachment_id
+			FROM attachment_previews
+			WHERE status = $5 OR (status = $4 AND locked_until < $1)
+			ORDER BY created_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING attachment_id
+	`
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		now,
+		lockedUntil,
+		limit,
+		models.PreviewProcessing,
+		models.PreviewPending,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	attachments, err := r.query(ctx, selectAttachment+"WHERE at.id = ANY($1)", ids)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return attachments, nil
+}
+
+// SavePreview saves the outcome of the preview generation and releases its lease.
+func (r *AttachmentRepo) SavePreview(
+	ctx context.Context,
+	attachmentID string,
+	preview models.AttachmentPreview,
+) error {
+	const op = "storage.postgres.SavePreview"
+
+	query := `
+		UPDATE attachment_previews
+		SET
+			status = $2,
+			thumbnail_key = NULLIF($3, ''),
+			preview_key = NULLIF($4, ''),
+			error = NULLIF($5, ''),
+			locked_until = NULL,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE attachment_id = $1
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		attachmentID,
+		preview.Status,
+		preview.ThumbnailKey,
+		preview.PreviewKey,
+		preview.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// DeleteAttachment permanently deletes the attachment.
+func (r *AttachmentRepo) DeleteAttachment(
+	ctx context.Context,
+	attachmentID string,
+) error {
+	const op = "storage.postgres.DeleteAttachment"
+
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM attachments WHERE id = $1", attachmentID); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+func (r *AttachmentRepo) query(ctx context.Context, query string, args ...any) ([]models.Attachment, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAttachment(row scanner) (models.Attachment, error) {
+	var (
+		attachment    models.Attachment
+		previewStatus sql.NullString
+		thumbnailKey  sql.NullString
+		previewKey    sql.NullString
+		attempts      int
+		previewError  sql.NullString
+	)
+
+	err := row.Scan(
+		&attachment.ID,
+		&attachment.SubmissionID,
+		&attachment.OwnerID,
+		&attachment.TeacherID,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.Checksum,
+		&attachment.StorageKey,
+		&attachment.CreatedAt,
+		&previewStatus,
+		&thumbnailKey,
+		&previewKey,
+		&attempts,
+		&previewError,
+	)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+
+	if previewStatus.Valid {
+		attachment.Preview = &models.AttachmentPreview{
+			Status:       models.PreviewStatus(previewStatus.String),
+			ThumbnailKey: thumbnailKey.String,
+			PreviewKey:   previewKey.String,
+			Attempts:     attempts,
+			Error:        previewError.String,
+		}
+	}
+
+	return attachment, nil
+}
@@ -0,0 +1,151 @@
+package calendar
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+)
+
+type CalendarRepo struct {
+	db *sql.DB
+}
+
+// New creates a new CalendarRepo instance.
+// That used to interact with the calendar_feeds table.
+func New(db *sql.DB) *CalendarRepo {
+	return &CalendarRepo{db: db}
+}
+
+// SaveCalendarFeed saves the hash of the token of the feed of the user.
+// The previous token of the user is replaced, so it stops working.
+func (r *CalendarRepo) SaveCalendarFeed(
+	ctx context.Context,
+	userID int64,
+	tokenHash []byte,
+	createdAt time.Time,
+) error {
+	const op = "storage.postgres.SaveCalendarFeed"
+
+	query := `
+		INSERT INTO calendar_feeds (user_id, token_hash, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET token_hash = EXCLUDED.token_hash, created_at = EXCLUDED.created_at
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, tokenHash, createdAt); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// DeleteCalendarFeed deletes the feed of the user.
+func (r *CalendarRepo) DeleteCalendarFeed(
+	ctx context.Context,
+	userID int64,
+) error {
+	const op = "storage.postgres.DeleteCalendarFeed"
+
+	res, err := r.db.ExecContext(ctx, "DELETE FROM calendar_feeds WHERE user_id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrCalendarFeedNotFound)
+	}
+
+	return nil
+}
+
+// CalendarFeedUser returns the id of the user the feed with the token hash belongs to.
+func (r *CalendarRepo) CalendarFeedUser(
+	ctx context.Context,
+	tokenHash []byte,
+) (int64, error) {
+	const op = "storage.postgres.CalendarFeedUser"
+
+	var userID int64
+	err := r.db.QueryRowContext(
+		ctx,
+		"SELECT user_id FROM calendar_feeds WHERE token_hash = $1",
+		tokenHash,
+	).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrCalendarFeedNotFound)
+		}
+
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return userID, nil
+}
+
+// CalendarEntries returns up to limit published assignments of the student
+// which cutoff date is not before since, the earliest due first.
+func (r *CalendarRepo) CalendarEntries(
+	ctx context.Context,
+	studentID int64,
+	since time.Time,
+	limit int,
+) ([]models.CalendarEntry, error) {
+	const op = "storage.postgres.CalendarEntries"
+
+	query := `
+		SELECT
+			sa.id, a.id, a.title, sa.status, sa.due_date, sa.cutoff_date,
+			GREATEST(sa.updated_at, a.updated_at)
+		FROM student_assignments sa
+		INNER JOIN assignments a ON a.id = sa.assignment_id
+		WHERE sa.student_id = $1 AND sa.cutoff_date >= $2
+			AND a.published_at IS NOT NULL AND a.deleted_at IS NULL
+		ORDER BY sa.due_date, sa.id
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, studentID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var entries []models.CalendarEntry
+	for rows.Next() {
+		var (
+			entry     models.CalendarEntry
+			updatedAt sql.NullTime
+		)
+		if err := rows.Scan(
+			&entry.StudentAssignmentID,
+			&entry.AssignmentID,
+			&entry.Title,
+			&entry.Status,
+			&entry.DueDate,
+			&entry.CutoffDate,
+			&updatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		entry.UpdatedAt = updatedAt.Time
+
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,489 @@
+package comment
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+)
+
+type CommentRepo struct {
+	db *sql.DB
+}
+
+// New creates a new CommentRepo instance.
+// That used to interact with the comment_threads and comments tables.
+func New(db *sql.DB) *CommentRepo {
+	return &CommentRepo{db: db}
+}
+
+// SaveThread saves the thread together with its first comment
+// and returns the id of the thread.
+func (r *CommentRepo) SaveThread(
+	ctx context.Context,
+	thread models.CommentThread,
+) (string, error) {
+	const op = "storage.postgres.SaveThread"
+
+	var anchor []byte
+	if thread.Anchor != nil {
+		var err error
+
+		anchor, err = json.Marshal(thread.Anchor)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var threadID string
+	err = tx.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO comment_threads (id, submission_id, submission_version_id, anchor, created_at)
+		VALUES (gen_random_uuid(), $1, $2, $3, $4)
+		RETURNING id
+		`,
+		thread.SubmissionID,
+		thread.VersionID,
+		anchor,
+		thread.CreatedAt,
+	).Scan(&threadID)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	for _, comment := range thread.Comments {
+		comment.ThreadID = threadID
+		if _, err := insertComment(ctx, tx, comment); err != nil {
+			return "", fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	return threadID, nil
+}
+
+// SaveComment adds the reply to the thread and returns the id of the comment.
+func (r *CommentRepo) SaveComment(
+	ctx context.Context,
+	comment models.Comment,
+) (string, error) {
+	const op = "storage.postgres.SaveComment"
+
+	id, err := insertComment(ctx, r.db, comment)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrThreadNotFound)
+		}
+
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	return id, nil
+}
+
+// UpdateComment replaces the body of the comment
+// and keeps the previous body as the revision.
+func (r *CommentRepo) UpdateComment(
+	ctx context.Context,
+	commentID string,
+	body string,
+	editedAt time.Time,
+) error {
+	const op = "storage.postgres.UpdateComment"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(
+		ctx,
+		`
+		INSERT INTO comment_revisions (id, comment_id, body, created_at)
+		SELECT gen_random_uuid(), id, body, COALESCE(edited_at, created_at)
+		FROM comments
+		WHERE id = $1
+		`,
+		commentID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrCommentNotFound)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		"UPDATE comments SET body = $2, edited_at = $3 WHERE id = $1",
+		commentID,
+		body,
+		editedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// ResolveThread marks the thread as resolved by the participant.
+// The zero resolvedAt reopens the thread.
+func (r *CommentRepo) ResolveThread(
+	ctx context.Context,
+	threadID string,
+	resolvedBy models.CommentAuthor,
+	resolvedAt time.Time,
+) error {
+	const op = "storage.postgres.ResolveThread"
+
+	query := `
+		UPDATE comment_threads
+		SET resolved_by = NULLIF($2, ''), resolved_at = $3
+		WHERE id = $1
+	`
+
+	res, err := r.db.ExecContext(
+		ctx,
+		query,
+		threadID,
+		resolvedBy,
+		sql.NullTime{Time: resolvedAt, Valid: !resolvedAt.IsZero()},
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrThreadNotFound)
+	}
+
+	return nil
+}
+
+const selectThread = `
+	SELECT
+		t.id, t.submission_id, t.submission_version_id, v.version_number,
+		t.anchor, t.resolved_by, t.resolved_at, t.created_at
+	FROM comment_threads t
+	INNER JOIN submission_versions v ON v.id = t.submission_version_id
+`
+
+// Thread returns the thread with its comments.
+func (r *CommentRepo) Thread(
+	ctx context.Context,
+	threadID string,
+) (models.CommentThread, error) {
+	const op = "storage.postgres.Thread"
+
+	thread, err := scanThread(r.db.QueryRowContext(ctx, selectThread+"WHERE t.id = $1", threadID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CommentThread{}, fmt.Errorf("%s: %w", op, storage.ErrThreadNotFound)
+		}
+
+		return models.CommentThread{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	threads := []models.CommentThread{thread}
+	if err := r.loadComments(ctx, threads); err != nil {
+		return models.CommentThread{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return threads[0], nil
+}
+
+// Threads returns the threads of the submission with their comments,
+// the oldest first. If versionID is not empty, only the threads
+// on this version are returned.
+func (r *CommentRepo) Threads(
+	ctx context.Context,
+	submissionID string,
+	versionID string,
+) ([]models.CommentThread, error) {
+	const op = "storage.postgres.Threads"
+
+	query := selectThread + `
+		WHERE t.submission_id = $1 AND ($2 = '' OR t.submission_version_id::text = $2)
+		ORDER BY t.created_at, t.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, submissionID, versionID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var threads []models.CommentThread
+	for rows.Next() {
+		thread, err := scanThread(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		threads = append(threads, thread)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := r.loadComments(ctx, threads); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return threads, nil
+}
+
+// Comment returns the comment with its revisions.
+func (r *CommentRepo) Comment(
+	ctx context.Context,
+	commentID string,
+) (models.Comment, error) {
+	const op = "storage.postgres.Comment"
+
+	query := `
+		SELECT id, thread_id, author_id, author_role, body, created_at, edited_at
+		FROM comments
+		WHERE id = $1
+	`
+
+	comment, err := scanComment(r.db.QueryRowContext(ctx, query, commentID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Comment{}, fmt.Errorf("%s: %w", op, storage.ErrCommentNotFound)
+		}
+
+		return models.Comment{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	revisions, err := r.revisions(ctx, []string{commentID})
+	if err != nil {
+		return models.Comment{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	comment.Revisions = revisions[commentID]
+
+	return comment, nil
+}
+
+// loadComments sets the comments with their revisions to the threads.
+func (r *CommentRepo) loadComments(ctx context.Context, threads []models.CommentThread) error {
+	if len(threads) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(threads))
+	byID := make(map[string]*models.CommentThread, len(threads))
+	for i := range threads {
+		ids[i] = threads[i].ID
+		byID[threads[i].ID] = &threads[i]
+	}
+
+	query := `
+		SELECT id, thread_id, author_id, author_role, body, created_at, edited_at
+		FROM comments
+		WHERE thread_id = ANY($1)
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var (
+		comments   []models.Comment
+		commentIDs []string
+	)
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return err
+		}
+
+		comments = append(comments, comment)
+		commentIDs = append(commentIDs, comment.ID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	revisions, err := r.revisions(ctx, commentIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, comment := range comments {
+		comment.Revisions = revisions[comment.ID]
+
+		thread := byID[comment.ThreadID]
+		thread.Comments = append(thread.Comments, comment)
+	}
+
+	return nil
+}
+
+// revisions returns the revisions of the comments by the comment ids.
+func (r *CommentRepo) revisions(ctx context.Context, commentIDs []string) (map[string][]models.CommentRevision, error) {
+	res := make(map[string][]models.CommentRevision)
+	if len(commentIDs) == 0 {
+		return res, nil
+	}
+
+	query := `
+		SELECT comment_id, body, created_at
+		FROM comment_revisions
+		WHERE comment_id = ANY($1)
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, commentIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			commentID string
+			revision  models.CommentRevision
+		)
+		if err := rows.Scan(&commentID, &revision.Body, &revision.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		res[commentID] = append(res[commentID], revision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// insertComment inserts the comment into the existing thread.
+// If there is no such thread, it returns sql.ErrNoRows.
+func insertComment(ctx context.Context, q querier, comment models.Comment) (string, error) {
+	query := `
+		INSERT INTO comments (id, thread_id, author_id, author_role, body, created_at)
+		SELECT gen_random_uuid(), t.id, $2, $3, $4, $5
+		FROM comment_threads t
+		WHERE t.id = $1
+		RETURNING id
+	`
+
+	var id string
+	err := q.QueryRowContext(
+		ctx,
+		query,
+		comment.ThreadID,
+		comment.AuthorID,
+		comment.Author,
+		comment.Body,
+		comment.CreatedAt,
+	).Scan(&id)
+
+	return id, err
+}
+
+func scanThread(row scanner) (models.CommentThread, error) {
+	var (
+		thread     models.CommentThread
+		anchor     []byte
+		resolvedBy sql.NullString
+		resolvedAt sql.NullTime
+	)
+
+	err := row.Scan(
+		&thread.ID,
+		&thread.SubmissionID,
+		&thread.VersionID,
+		&thread.VersionNumber,
+		&anchor,
+		&resolvedBy,
+		&resolvedAt,
+		&thread.CreatedAt,
+	)
+	if err != nil {
+		return models.CommentThread{}, err
+	}
+
+	if anchor != nil {
+		thread.Anchor = &models.CommentAnchor{}
+		if err := json.Unmarshal(anchor, thread.Anchor); err != nil {
+			return models.CommentThread{}, err
+		}
+	}
+
+	thread.ResolvedBy = models.CommentAuthor(resolvedBy.String)
+	thread.ResolvedAt = resolvedAt.Time
+
+	return thread, nil
+}
+
+func scanComment(row scanner) (models.Comment, error) {
+	var (
+		comment  models.Comment
+		editedAt sql.NullTime
+	)
+
+	err := row.Scan(
+		&comment.ID,
+		&comment.ThreadID,
+		&comment.AuthorID,
+		&comment.Author,
+		&comment.Body,
+		&comment.CreatedAt,
+		&editedAt,
+	)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	comment.EditedAt = editedAt.Time
+
+	return comment, nil
+}
@@ -0,0 +1,312 @@
+package course
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+)
+
+type CourseRepo struct {
+	db *sql.DB
+}
+
+// New creates a new CourseRepo instance.
+// That used to interact with the courses and related tables.
+func New(db *sql.DB) *CourseRepo {
+	return &CourseRepo{db: db}
+}
+
+// Course returns the course with the given ID
+// together with its sections and templates in order.
+func (r *CourseRepo) Course(
+	ctx context.Context,
+	courseID string,
+) (models.Course, error) {
+	const op = "storage.postgres.Course"
+
+	query := `
+		SELECT id, creator_id, school_id, title, description, created_at, updated_at
+		FROM courses
+		WHERE id = $1
+	`
+
+	var course models.Course
+	err := r.db.QueryRowContext(ctx, query, courseID).Scan(
+		&course.ID,
+		&course.CreatorID,
+		&course.SchoolID,
+		&course.Title,
+		&course.Description,
+		&course.CreatedAt,
+		&course.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Course{}, fmt.Errorf("%s: %w", op, storage.ErrCourseNotFound)
+		}
+
+		return models.Course{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	query = `
+		SELECT s.id, s.title, s.position, i.template_id
+		FROM course_sections s
+		LEFT JOIN course_items i ON i.section_id = s.id
+		WHERE s.course_id = $1
+		ORDER BY s.position, i.position
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, courseID)
+	if err != nil {
+		return models.Course{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			section    models.CourseSection
+			templateID sql.NullString
+		)
+
+		if err := rows.Scan(
+			&section.ID,
+			&section.Title,
+			&section.Position,
+			&templateID,
+		); err != nil {
+			return models.Course{}, fmt.Errorf("%s: %v", op, err)
+		}
+
+		last := len(course.Sections) - 1
+		if last < 0 || course.Sections[last].ID != section.ID {
+			course.Sections = append(course.Sections, section)
+			last++
+		}
+
+		if templateID.Valid {
+			course.Sections[last].TemplateIDs = append(
+				course.Sections[last].TemplateIDs,
+				templateID.String,
+			)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.Course{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return course, nil
+}
+
+// SaveCourse saves the course with its sections, templates and rubrics
+// in a single transaction.
+// If commit is false, the transaction is rolled back after all the rows are written,
+// so the constraints of the database are checked without changing anything.
+func (r *CourseRepo) SaveCourse(
+	ctx context.Context,
+	course models.Course,
+	templates []models.Template,
+	rubrics []models.Rubric,
+	commit bool,
+) error {
+	const op = "storage.postgres.SaveCourse"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`
+		INSERT INTO courses
+		(id, creator_id, school_id, title, description)
+		VALUES ($1, $2, $3, $4, $5)
+		`,
+		course.ID,
+		course.CreatorID,
+		course.SchoolID,
+		course.Title,
+		course.Description,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	for _, template := range templates {
+		_, err = tx.ExecContext(
+			ctx,
+			`
+			INSERT INTO assignment_templates
+			(id, creator_id, school_id, title, widget_id, widget_config, visibility)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			`,
+			template.ID,
+			template.CreatorID,
+			template.SchoolID,
+			template.Title,
+			template.Widget.ID,
+			[]byte(template.Widget.Config),
+			template.Visibility,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	for _, rubric := range rubrics {
+		criteria, err := json.Marshal(rubric.Criteria)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`
+			INSERT INTO rubrics
+			(id, template_id, title, criteria)
+			VALUES ($1, $2, $3, $4)
+			`,
+			rubric.ID,
+			rubric.TemplateID,
+			rubric.Title,
+			criteria,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	for _, section := range course.Sections {
+		_, err = tx.ExecContext(
+			ctx,
+			`
+			INSERT INTO course_sections
+			(id, course_id, title, position)
+			VALUES ($1, $2, $3, $4)
+			`,
+			section.ID,
+			course.ID,
+			section.Title,
+			section.Position,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+
+		for position, templateID := range section.TemplateIDs {
+			_, err = tx.ExecContext(
+				ctx,
+				`
+				INSERT INTO course_items
+				(section_id, template_id, position)
+				VALUES ($1, $2, $3)
+				`,
+				section.ID,
+				templateID,
+				position,
+			)
+			if err != nil {
+				return fmt.Errorf("%s: %v", op, err)
+			}
+		}
+	}
+
+	if !commit {
+		return nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// AddCourseSection appends the section with its templates to the end of the course.
+// The position of the section is ignored, the section gets the next one.
+//
+// If the course does not exist, returns storage.ErrCourseNotFound.
+func (r *CourseRepo) AddCourseSection(
+	ctx context.Context,
+	courseID string,
+	section models.CourseSection,
+) error {
+	const op = "storage.postgres.AddCourseSection"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	// the course is locked, so the concurrent sections get distinct positions
+	var id string
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT id FROM courses WHERE id = $1 FOR UPDATE`,
+		courseID,
+	).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrCourseNotFound)
+		}
+
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`
+		INSERT INTO course_sections
+		(id, course_id, title, position)
+		SELECT $1, $2, $3, COALESCE(MAX(position) + 1, 0)
+		FROM course_sections
+		WHERE course_id = $2
+		`,
+		section.ID,
+		courseID,
+		section.Title,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	for position, templateID := range section.TemplateIDs {
+		_, err = tx.ExecContext(
+			ctx,
+			`
+			INSERT INTO course_items
+			(section_id, template_id, position)
+			VALUES ($1, $2, $3)
+			`,
+			section.ID,
+			templateID,
+			position,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE courses SET updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
+		courseID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
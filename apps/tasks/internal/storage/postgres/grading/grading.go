@@ -0,0 +1,656 @@
+package grading
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+	"tasks/internal/storage/postgres/outbox"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	eventsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/events/v1"
+	tasksv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/v1"
+)
+
+type GradingRepo struct {
+	db *sql.DB
+}
+
+// New creates a new GradingRepo instance.
+// That used to interact with the feedbacks, regrade_requests and submission_events tables.
+func New(db *sql.DB) *GradingRepo {
+	return &GradingRepo{db: db}
+}
+
+// SaveDraftFeedback saves the draft feedback of the version,
+// replacing the previous draft if any.
+func (r *GradingRepo) SaveDraftFeedback(
+	ctx context.Context,
+	feedback models.Feedback,
+) error {
+	const op = "storage.postgres.SaveDraftFeedback"
+
+	query := `
+		INSERT INTO feedbacks
+		(id, submission_version_id, grader_id, feedback, score, is_published, created_at, updated_at)
+		VALUES (gen_random_uuid(), $1, $2, $3, $4, FALSE, $5, $5)
+		ON CONFLICT (submission_version_id) WHERE NOT is_published
+		DO UPDATE SET
+			grader_id = EXCLUDED.grader_id,
+			feedback = EXCLUDED.feedback,
+			score = EXCLUDED.score,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		feedback.VersionID,
+		feedback.GraderID,
+		feedback.Text,
+		feedback.Score,
+		feedback.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// PublishFeedback publishes the feedback of the version and sets the status of the submission.
+// The empty text and the nil score of the feedback are taken from the draft, if any.
+// The publication is recorded in the history of the submission
+// and announced with the FeedbackPublished event.
+func (r *GradingRepo) PublishFeedback(
+	ctx context.Context,
+	feedback models.Feedback,
+	status models.SubmissionStatus,
+) error {
+	const op = "storage.postgres.PublishFeedback"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var (
+		text  string
+		score sql.NullFloat64
+	)
+
+	err = tx.QueryRowContext(
+		ctx,
+		`
+		UPDATE feedbacks
+		SET
+			grader_id = $2,
+			feedback = COALESCE(NULLIF($3, ''), feedback),
+			score = COALESCE($4, score),
+			is_published = TRUE,
+			published_at = $5,
+			updated_at = $5
+		WHERE submission_version_id = $1 AND NOT is_published
+		RETURNING feedback, score
+		`,
+		feedback.VersionID,
+		feedback.GraderID,
+		feedback.Text,
+		feedback.Score,
+		feedback.PublishedAt,
+	).Scan(&text, &score)
+	if errors.Is(err, sql.ErrNoRows) {
+		text, score = feedback.Text, nullFloat(feedback.Score)
+
+		_, err = tx.ExecContext(
+			ctx,
+			`
+			INSERT INTO feedbacks
+			(
+				id, submission_version_id, grader_id, feedback, score,
+				is_published, published_at, created_at, updated_at
+			)
+			VALUES (gen_random_uuid(), $1, $2, $3, $4, TRUE, $5, $5, $5)
+			`,
+			feedback.VersionID,
+			feedback.GraderID,
+			feedback.Text,
+			feedback.Score,
+			feedback.PublishedAt,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	var studentAssignmentID string
+	err = tx.QueryRowContext(
+		ctx,
+		`
+		UPDATE submissions
+		SET status = $2, revision = revision + 1
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING assignment_id
+		`,
+		feedback.SubmissionID,
+		status,
+	).Scan(&studentAssignmentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrSubmissionNotFound)
+		}
+
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	var (
+		assignmentID    string
+		assignmentTitle string
+		studentID       int64
+		teacherID       int64
+	)
+
+	err = tx.QueryRowContext(
+		ctx,
+		`
+		UPDATE student_assignments sa
+		SET status = $2, updated_at = CURRENT_TIMESTAMP
+		FROM assignments a
+		WHERE sa.id = $1 AND a.id = sa.assignment_id
+		RETURNING sa.assignment_id, a.title, sa.student_id, a.creator_id
+		`,
+		studentAssignmentID,
+		status,
+	).Scan(&assignmentID, &assignmentTitle, &studentID, &teacherID)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	err = insertEvent(ctx, tx, models.SubmissionEvent{
+		SubmissionID: feedback.SubmissionID,
+		Kind:         models.EventFeedbackPublished,
+		ActorID:      feedback.GraderID,
+		Status:       status,
+		Score:        floatPtr(score),
+		Comment:      text,
+		CreatedAt:    feedback.PublishedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	err = outbox.Insert(ctx, tx, feedback.SubmissionID, feedback.PublishedAt, &eventsv1.FeedbackPublished{
+		SubmissionId:        feedback.SubmissionID,
+		AssignmentId:        assignmentID,
+		StudentAssignmentId: studentAssignmentID,
+		StudentId:           strconv.FormatInt(studentID, 10),
+		TeacherId:           strconv.FormatInt(teacherID, 10),
+		VersionId:           feedback.VersionID,
+		Status:              eventSubmissionStatus(status),
+		Score:               floatPtr(score),
+		PublishedAt:         timestamppb.New(feedback.PublishedAt),
+		AssignmentTitle:     assignmentTitle,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// PublishedFeedback returns the latest published feedback of the submission.
+func (r *GradingRepo) PublishedFeedback(
+	ctx context.Context,
+	submissionID string,
+) (models.Feedback, error) {
+	const op = "storage.postgres.PublishedFeedback"
+
+	query := `
+		SELECT
+			f.id, v.submission_id, f.submission_version_id, f.grader_id, f.feedback, f.score,
+			f.published_at, f.created_at, f.updated_at
+		FROM feedbacks f
+		INNER JOIN submission_versions v ON v.id = f.submission_version_id
+		WHERE v.submission_id = $1 AND f.is_published
+		ORDER BY f.published_at DESC
+		LIMIT 1
+	`
+
+	var (
+		feedback    models.Feedback
+		score       sql.NullFloat64
+		publishedAt sql.NullTime
+	)
+
+	err := r.db.QueryRowContext(ctx, query, submissionID).Scan(
+		&feedback.ID,
+		&feedback.SubmissionID,
+		&feedback.VersionID,
+		&feedback.GraderID,
+		&feedback.Text,
+		&score,
+		&publishedAt,
+		&feedback.CreatedAt,
+		&feedback.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Feedback{}, fmt.Errorf("%s: %w", op, storage.ErrFeedbackNotFound)
+		}
+
+		return models.Feedback{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	feedback.Score = floatPtr(score)
+	feedback.Published = true
+	feedback.PublishedAt = publishedAt.Time
+
+	return feedback, nil
+}
+
+// SaveRegradeRequest saves the open regrade request, records it
+// in the history of the submission and announces it with the RegradeRequested event.
+// If the submission already has
+// the open request, it returns storage.ErrRegradeAlreadyOpen.
+func (r *GradingRepo) SaveRegradeRequest(
+	ctx context.Context,
+	request models.RegradeRequest,
+) (string, error) {
+	const op = "storage.postgres.SaveRegradeRequest"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var id string
+	err = tx.QueryRowContext(
+		ctx,
+		`
+		INSERT INTO regrade_requests
+		(id, submission_id, student_id, teacher_id, reason, status, old_score, created_at)
+		VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (submission_id) WHERE status = 'open' DO NOTHING
+		RETURNING id
+		`,
+		request.SubmissionID,
+		request.StudentID,
+		request.TeacherID,
+		request.Reason,
+		models.RegradeOpen,
+		request.OldScore,
+		request.CreatedAt,
+	).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrRegradeAlreadyOpen)
+		}
+
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	err = insertEvent(ctx, tx, models.SubmissionEvent{
+		SubmissionID: request.SubmissionID,
+		Kind:         models.EventRegradeRequested,
+		ActorID:      request.StudentID,
+		Score:        request.OldScore,
+		Comment:      request.Reason,
+		RegradeID:    id,
+		CreatedAt:    request.CreatedAt,
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	err = outbox.Insert(ctx, tx, request.SubmissionID, request.CreatedAt, &eventsv1.RegradeRequested{
+		RegradeId:    id,
+		SubmissionId: request.SubmissionID,
+		StudentId:    strconv.FormatInt(request.StudentID, 10),
+		TeacherId:    strconv.FormatInt(request.TeacherID, 10),
+		RequestedAt:  timestamppb.New(request.CreatedAt),
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	return id, nil
+}
+
+// ResolveRegrade closes the open regrade request with the status, resolution
+// and new score of the request. If the new score is set, it replaces the score
+// of the latest published feedback. The resolution is recorded in the history
+// of the submission and announced with the RegradeResolved event.
+// If the request is not open, it returns storage.ErrRegradeNotFound.
+func (r *GradingRepo) ResolveRegrade(
+	ctx context.Context,
+	request models.RegradeRequest,
+) error {
+	const op = "storage.postgres.ResolveRegrade"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var (
+		submissionID string
+		studentID    int64
+		teacherID    int64
+	)
+
+	err = tx.QueryRowContext(
+		ctx,
+		`
+		UPDATE regrade_requests
+		SET status = $2, resolution = $3, new_score = $4, resolved_at = $5
+		WHERE id = $1 AND status = 'open'
+		RETURNING submission_id, student_id, teacher_id
+		`,
+		request.ID,
+		request.Status,
+		request.Resolution,
+		request.NewScore,
+		request.ResolvedAt,
+	).Scan(&submissionID, &studentID, &teacherID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrRegradeNotFound)
+		}
+
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if request.NewScore != nil {
+		_, err = tx.ExecContext(
+			ctx,
+			`
+			UPDATE feedbacks
+			SET score = $2, updated_at = $3
+			WHERE id = (
+				SELECT f.id
+				FROM feedbacks f
+				INNER JOIN submission_versions v ON v.id = f.submission_version_id
+				WHERE v.submission_id = $1 AND f.is_published
+				ORDER BY f.published_at DESC
+				LIMIT 1
+			)
+			`,
+			submissionID,
+			*request.NewScore,
+			request.ResolvedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	err = insertEvent(ctx, tx, models.SubmissionEvent{
+		SubmissionID: submissionID,
+		Kind:         models.EventRegradeResolved,
+		ActorID:      request.TeacherID,
+		Score:        request.NewScore,
+		Comment:      request.Resolution,
+		RegradeID:    request.ID,
+		CreatedAt:    request.ResolvedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	err = outbox.Insert(ctx, tx, submissionID, request.ResolvedAt, &eventsv1.RegradeResolved{
+		RegradeId:    request.ID,
+		SubmissionId: submissionID,
+		StudentId:    strconv.FormatInt(studentID, 10),
+		TeacherId:    strconv.FormatInt(teacherID, 10),
+		Status:       eventRegradeStatus(request.Status),
+		NewScore:     request.NewScore,
+		ResolvedAt:   timestamppb.New(request.ResolvedAt),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+const selectRegradeRequest = `
+	SELECT
+		r.id, r.submission_id, a.id, a.title, r.student_id, r.teacher_id,
+		r.reason, r.status, r.resolution, r.old_score, r.new_score,
+		r.created_at, r.resolved_at
+	FROM regrade_requests r
+	INNER JOIN submissions s ON s.id = r.submission_id
+	INNER JOIN student_assignments sa ON sa.id = s.assignment_id
+	INNER JOIN assignments a ON a.id = sa.assignment_id
+`
+
+// RegradeRequest returns the regrade request.
+func (r *GradingRepo) RegradeRequest(
+	ctx context.Context,
+	requestID string,
+) (models.RegradeRequest, error) {
+	const op = "storage.postgres.RegradeRequest"
+
+	request, err := scanRegradeRequest(r.db.QueryRowContext(ctx, selectRegradeRequest+"WHERE r.id = $1", requestID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.RegradeRequest{}, fmt.Errorf("%s: %w", op, storage.ErrRegradeNotFound)
+		}
+
+		return models.RegradeRequest{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return request, nil
+}
+
+// OpenRegrades returns the page of the open regrade requests to the teacher, the oldest first.
+// Requests on the submissions and assignments in the trash are skipped.
+func (r *GradingRepo) OpenRegrades(
+	ctx context.Context,
+	teacherID int64,
+	filter models.Filter,
+) ([]models.RegradeRequest, error) {
+	const op = "storage.postgres.OpenRegrades"
+
+	query := selectRegradeRequest + `
+		WHERE r.teacher_id = $1 AND r.status = 'open'
+			AND s.deleted_at IS NULL AND a.deleted_at IS NULL
+		ORDER BY r.created_at, r.id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, teacherID, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var requests []models.RegradeRequest
+	for rows.Next() {
+		request, err := scanRegradeRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		requests = append(requests, request)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return requests, nil
+}
+
+// SubmissionEvents returns the history of the submission, the oldest first.
+func (r *GradingRepo) SubmissionEvents(
+	ctx context.Context,
+	submissionID string,
+) ([]models.SubmissionEvent, error) {
+	const op = "storage.postgres.SubmissionEvents"
+
+	query := `
+		SELECT
+			id, submission_id, kind, actor_id, COALESCE(status, ''), score,
+			comment, COALESCE(regrade_id::text, ''), created_at
+		FROM submission_events
+		WHERE submission_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, submissionID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var events []models.SubmissionEvent
+	for rows.Next() {
+		var (
+			event models.SubmissionEvent
+			score sql.NullFloat64
+		)
+		if err := rows.Scan(
+			&event.ID,
+			&event.SubmissionID,
+			&event.Kind,
+			&event.ActorID,
+			&event.Status,
+			&score,
+			&event.Comment,
+			&event.RegradeID,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		event.Score = floatPtr(score)
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return events, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRegradeRequest(row scanner) (models.RegradeRequest, error) {
+	var (
+		request    models.RegradeRequest
+		oldScore   sql.NullFloat64
+		newScore   sql.NullFloat64
+		resolvedAt sql.NullTime
+	)
+
+	err := row.Scan(
+		&request.ID,
+		&request.SubmissionID,
+		&request.AssignmentID,
+		&request.AssignmentTitle,
+		&request.StudentID,
+		&request.TeacherID,
+		&request.Reason,
+		&request.Status,
+		&request.Resolution,
+		&oldScore,
+		&newScore,
+		&request.CreatedAt,
+		&resolvedAt,
+	)
+	if err != nil {
+		return models.RegradeRequest{}, err
+	}
+
+	request.OldScore = floatPtr(oldScore)
+	request.NewScore = floatPtr(newScore)
+	request.ResolvedAt = resolvedAt.Time
+
+	return request, nil
+}
+
+// insertEvent records the event in the history of the submission.
+func insertEvent(ctx context.Context, tx *sql.Tx, event models.SubmissionEvent) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`
+		INSERT INTO submission_events
+		(id, submission_id, kind, actor_id, status, score, comment, regrade_id, created_at)
+		VALUES (gen_random_uuid(), $1, $2, $3, NULLIF($4, ''), $5, $6, NULLIF($7, '')::uuid, $8)
+		`,
+		event.SubmissionID,
+		event.Kind,
+		event.ActorID,
+		event.Status,
+		event.Score,
+		event.Comment,
+		event.RegradeID,
+		event.CreatedAt,
+	)
+
+	return err
+}
+
+func nullFloat(f *float64) sql.NullFloat64 {
+	if f == nil {
+		return sql.NullFloat64{}
+	}
+
+	return sql.NullFloat64{Float64: *f, Valid: true}
+}
+
+func floatPtr(f sql.NullFloat64) *float64 {
+	if !f.Valid {
+		return nil
+	}
+
+	return &f.Float64
+}
+
+// eventSubmissionStatus converts the status of the submission to its event representation.
+func eventSubmissionStatus(status models.SubmissionStatus) tasksv1.SubmissionStatus {
+	switch status {
+	case models.StatusGraded:
+		return tasksv1.SubmissionStatus_SUBMISSION_STATUS_GRADED
+	case models.StatusReturned:
+		return tasksv1.SubmissionStatus_SUBMISSION_STATUS_RETURNED
+	default:
+		return tasksv1.SubmissionStatus_SUBMISSION_STATUS_UNSPECIFIED
+	}
+}
+
+// eventRegradeStatus converts the status of the regrade request to its event representation.
+func eventRegradeStatus(status models.RegradeStatus) tasksv1.RegradeStatus {
+	switch status {
+	case models.RegradeAccepted:
+		return tasksv1.RegradeStatus_REGRADE_STATUS_ACCEPTED
+	case models.RegradeRejected:
+		return tasksv1.RegradeStatus_REGRADE_STATUS_REJECTED
+	default:
+		return tasksv1.RegradeStatus_REGRADE_STATUS_UNSPECIFIED
+	}
+}
@@ -0,0 +1,468 @@
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+)
+
+type ModerationRepo struct {
+	db *sql.DB
+}
+
+// New creates a new ModerationRepo instance.
+// That used to interact with the moderations and moderation_items tables.
+func New(db *sql.DB) *ModerationRepo {
+	return &ModerationRepo{db: db}
+}
+
+// GradedSubmissions returns the submitted submissions of the assignment
+// which have the scored draft feedback on their current version.
+// Submissions in the trash are skipped.
+func (r *ModerationRepo) GradedSubmissions(
+	ctx context.Context,
+	assignmentID string,
+) ([]models.ModerationItem, error) {
+	const op = "storage.postgres.GradedSubmissions"
+
+	query := `
+		SELECT s.id, v.id, f.id, f.score
+		FROM submissions s
+		INNER JOIN student_assignments sa ON sa.id = s.assignment_id
+		INNER JOIN submission_versions v ON v.id = s.current_version_id
+		INNER JOIN feedbacks f ON f.submission_version_id = v.id AND NOT f.is_published
+		WHERE sa.assignment_id = $1 AND s.status = $2
+			AND s.deleted_at IS NULL AND f.score IS NOT NULL
+		ORDER BY s.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, assignmentID, models.StatusSubmitted)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var items []models.ModerationItem
+	for rows.Next() {
+		var (
+			item  models.ModerationItem
+			score sql.NullFloat64
+		)
+		if err := rows.Scan(&item.SubmissionID, &item.VersionID, &item.FeedbackID, &score); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		item.AssignmentID = assignmentID
+		item.OriginalScore = floatPtr(score)
+		item.Decision = models.ModerationPending
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return items, nil
+}
+
+// SaveModeration saves the moderation together with its sample.
+// If the assignment is already moderated, it returns storage.ErrModerationAlreadyExists.
+func (r *ModerationRepo) SaveModeration(
+	ctx context.Context,
+	moderation models.Moderation,
+) error {
+	const op = "storage.postgres.SaveModeration"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(
+		ctx,
+		`
+		INSERT INTO moderations (assignment_id, requested_by, moderator_id, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (assignment_id) DO NOTHING
+		`,
+		moderation.AssignmentID,
+		moderation.RequestedBy,
+		moderation.ModeratorID,
+		moderation.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrModerationAlreadyExists)
+	}
+
+	for _, item := range moderation.Items {
+		_, err := tx.ExecContext(
+			ctx,
+			`
+			INSERT INTO moderation_items
+			(id, assignment_id, submission_id, feedback_id, original_score, decision)
+			VALUES (gen_random_uuid(), $1, $2, $3, $4, $5)
+			`,
+			moderation.AssignmentID,
+			item.SubmissionID,
+			item.FeedbackID,
+			item.OriginalScore,
+			models.ModerationPending,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+const selectModeration = `
+	SELECT
+		m.assignment_id, a.title, m.requested_by, m.moderator_id,
+		m.created_at, m.completed_at
+	FROM moderations m
+	INNER JOIN assignments a ON a.id = m.assignment_id
+`
+
+// Moderation returns the moderation of the assignment with its sample.
+func (r *ModerationRepo) Moderation(
+	ctx context.Context,
+	assignmentID string,
+) (models.Moderation, error) {
+	const op = "storage.postgres.Moderation"
+
+	moderation, err := r.moderation(ctx, selectModeration+"WHERE m.assignment_id = $1", assignmentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Moderation{}, fmt.Errorf("%s: %w", op, storage.ErrModerationNotFound)
+		}
+
+		return models.Moderation{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return moderation, nil
+}
+
+// SubmissionModeration returns the moderation of the assignment of the submission.
+func (r *ModerationRepo) SubmissionModeration(
+	ctx context.Context,
+	submissionID string,
+) (models.Moderation, error) {
+	const op = "storage.postgres.SubmissionModeration"
+
+	query := selectModeration + `
+		WHERE m.assignment_id = (
+			SELECT sa.assignment_id
+			FROM submissions s
+			INNER JOIN student_assignments sa ON sa.id = s.assignment_id
+			WHERE s.id = $1
+		)
+	`
+
+	moderation, err := r.moderation(ctx, query, submissionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Moderation{}, fmt.Errorf("%s: %w", op, storage.ErrModerationNotFound)
+		}
+
+		return models.Moderation{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return moderation, nil
+}
+
+// PendingModerations returns the page of the moderations of the moderator
+// which are not completed yet, the oldest first.
+// Moderations of the assignments in the trash are skipped.
+func (r *ModerationRepo) PendingModerations(
+	ctx context.Context,
+	moderatorID int64,
+	filter models.Filter,
+) ([]models.Moderation, error) {
+	const op = "storage.postgres.PendingModerations"
+
+	query := selectModeration + `
+		WHERE m.moderator_id = $1 AND m.completed_at IS NULL AND a.deleted_at IS NULL
+		ORDER BY m.created_at, m.assignment_id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, moderatorID, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var moderations []models.Moderation
+	for rows.Next() {
+		moderation, err := scanModeration(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		moderations = append(moderations, moderation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := r.loadItems(ctx, moderations); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return moderations, nil
+}
+
+const selectItem = `
+	SELECT
+		i.id, i.assignment_id, i.submission_id, f.submission_version_id, v.payload,
+		i.feedback_id, f.feedback, i.original_score, i.decision, i.adjusted_score,
+		i.comment, i.moderated_at
+	FROM moderation_items i
+	INNER JOIN feedbacks f ON f.id = i.feedback_id
+	INNER JOIN submission_versions v ON v.id = f.submission_version_id
+`
+
+// ModerationItem returns the item of the sample.
+func (r *ModerationRepo) ModerationItem(
+	ctx context.Context,
+	itemID string,
+) (models.ModerationItem, error) {
+	const op = "storage.postgres.ModerationItem"
+
+	item, err := scanItem(r.db.QueryRowContext(ctx, selectItem+"WHERE i.id = $1", itemID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ModerationItem{}, fmt.Errorf("%s: %w", op, storage.ErrModerationItemNotFound)
+		}
+
+		return models.ModerationItem{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return item, nil
+}
+
+// RecordModeration records the decision of the moderator on the pending item.
+// The adjusted score replaces the score of the draft feedback of the teacher.
+// The moderation is completed together with its last pending item.
+// If the item is not pending, it returns storage.ErrModerationItemNotFound.
+func (r *ModerationRepo) RecordModeration(
+	ctx context.Context,
+	item models.ModerationItem,
+) error {
+	const op = "storage.postgres.RecordModeration"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var assignmentID, feedbackID string
+	err = tx.QueryRowContext(
+		ctx,
+		`
+		UPDATE moderation_items
+		SET decision = $2, adjusted_score = $3, comment = $4, moderated_at = $5
+		WHERE id = $1 AND decision = 'pending'
+		RETURNING assignment_id, feedback_id
+		`,
+		item.ID,
+		item.Decision,
+		item.AdjustedScore,
+		item.Comment,
+		item.ModeratedAt,
+	).Scan(&assignmentID, &feedbackID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrModerationItemNotFound)
+		}
+
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if item.Decision == models.ModerationAdjusted {
+		_, err = tx.ExecContext(
+			ctx,
+			`
+			UPDATE feedbacks
+			SET score = $2, updated_at = $3
+			WHERE id = $1 AND NOT is_published
+			`,
+			feedbackID,
+			item.AdjustedScore,
+			item.ModeratedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`
+		UPDATE moderations
+		SET completed_at = $2
+		WHERE assignment_id = $1 AND completed_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM moderation_items
+				WHERE assignment_id = $1 AND decision = 'pending'
+			)
+		`,
+		assignmentID,
+		item.ModeratedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// moderation returns the single moderation selected by the query with its sample.
+func (r *ModerationRepo) moderation(
+	ctx context.Context,
+	query string,
+	args ...any,
+) (models.Moderation, error) {
+	moderation, err := scanModeration(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return models.Moderation{}, err
+	}
+
+	moderations := []models.Moderation{moderation}
+	if err := r.loadItems(ctx, moderations); err != nil {
+		return models.Moderation{}, err
+	}
+
+	return moderations[0], nil
+}
+
+// loadItems fills the samples of the moderations.
+func (r *ModerationRepo) loadItems(ctx context.Context, moderations []models.Moderation) error {
+	if len(moderations) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(moderations))
+	index := make(map[string]int, len(moderations))
+	for i, moderation := range moderations {
+		ids[i] = moderation.AssignmentID
+		index[moderation.AssignmentID] = i
+	}
+
+	query := selectItem + `
+		WHERE i.assignment_id = ANY($1)
+		ORDER BY i.submission_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return err
+		}
+
+		m := &moderations[index[item.AssignmentID]]
+		m.Items = append(m.Items, item)
+	}
+
+	return rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanModeration(row scanner) (models.Moderation, error) {
+	var (
+		moderation  models.Moderation
+		completedAt sql.NullTime
+	)
+
+	err := row.Scan(
+		&moderation.AssignmentID,
+		&moderation.AssignmentTitle,
+		&moderation.RequestedBy,
+		&moderation.ModeratorID,
+		&moderation.CreatedAt,
+		&completedAt,
+	)
+	if err != nil {
+		return models.Moderation{}, err
+	}
+
+	moderation.CompletedAt = completedAt.Time
+
+	return moderation, nil
+}
+
+func scanItem(row scanner) (models.ModerationItem, error) {
+	var (
+		item          models.ModerationItem
+		payload       []byte
+		originalScore sql.NullFloat64
+		adjustedScore sql.NullFloat64
+		moderatedAt   sql.NullTime
+	)
+
+	err := row.Scan(
+		&item.ID,
+		&item.AssignmentID,
+		&item.SubmissionID,
+		&item.VersionID,
+		&payload,
+		&item.FeedbackID,
+		&item.FeedbackText,
+		&originalScore,
+		&item.Decision,
+		&adjustedScore,
+		&item.Comment,
+		&moderatedAt,
+	)
+	if err != nil {
+		return models.ModerationItem{}, err
+	}
+
+	item.Payload = payload
+	item.OriginalScore = floatPtr(originalScore)
+	item.AdjustedScore = floatPtr(adjustedScore)
+	item.ModeratedAt = moderatedAt.Time
+
+	return item, nil
+}
+
+func floatPtr(f sql.NullFloat64) *float64 {
+	if !f.Valid {
+		return nil
+	}
+
+	return &f.Float64
+}
@@ -0,0 +1,257 @@
+package outbox
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"tasks/internal/domain/models"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	eventsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/events/v1"
+)
+
+type OutboxRepo struct {
+	db *sql.DB
+}
+
+// New creates a new OutboxRepo instance.
+// That used to interact with the outbox table.
+func New(db *sql.DB) *OutboxRepo {
+	return &OutboxRepo{db: db}
+}
+
+// Insert writes the domain event to the outbox in the transaction
+// of the change it describes, so the event is published if and only if
+// the change is committed.
+//
+// The event belongs to the app of the school of the assignment
+// the aggregate is or the submission the aggregate is made for,
+// its webhooks are the only ones the event is posted to.
+func Insert(
+	ctx context.Context,
+	tx *sql.Tx,
+	aggregateID string,
+	occurredAt time.Time,
+	payload proto.Message,
+) error {
+	body, err := anypb.New(payload)
+	if err != nil {
+		return err
+	}
+
+	appID, err := app(ctx, tx, aggregateID)
+	if err != nil {
+		return err
+	}
+
+	event := &eventsv1.Event{
+		Id:          uuid.NewString(),
+		AggregateId: aggregateID,
+		OccurredAt:  timestamppb.New(occurredAt),
+		Payload:     body,
+		AppId:       appID,
+	}
+
+	data, err := proto.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`
+		INSERT INTO outbox (id, event_type, aggregate_id, payload, created_at, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		`,
+		event.Id,
+		string(payload.ProtoReflect().Descriptor().FullName()),
+		aggregateID,
+		data,
+		occurredAt,
+	)
+
+	return err
+}
+
+// app returns the app of the school of the assignment or the submission with the id,
+// or zero if the assignment is not created in a school.
+func app(ctx context.Context, tx *sql.Tx, aggregateID string) (int32, error) {
+	query := `
+		SELECT school_id
+		FROM assignments
+		WHERE id = $1
+		UNION ALL
+		SELECT a.school_id
+		FROM submissions s
+		INNER JOIN student_assignments sa ON sa.id = s.assignment_id
+		INNER JOIN assignments a ON a.id = sa.assignment_id
+		WHERE s.id = $1
+		LIMIT 1
+	`
+
+	var schoolID int64
+	err := tx.QueryRowContext(ctx, query, aggregateID).Scan(&schoolID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	return int32(schoolID), nil
+}
+
+// ClaimEvents locks up to limit unpublished events due to be published
+// until lockedUntil and returns them in the order they were written.
+// Events of the aggregate which has an older unpublished event are not claimed,
+// so the events of the same aggregate are published in order.
+//
+// Rows are locked with SKIP LOCKED, so several instances of the relay
+// can run at the same time without claiming the same events.
+func (r *OutboxRepo) ClaimEvents(
+	ctx context.Context,
+	now time.Time,
+	lockedUntil time.Time,
+	limit int,
+) ([]models.OutboxEvent, error) {
+	const op = "storage.postgres.ClaimEvents"
+
+	query := `
+		UPDATE outbox
+		SET attempts = attempts + 1, next_attempt_at = $2
+		WHERE id IN (
+			SELECT o.id
+			FROM outbox o
+			WHERE o.published_at IS NULL AND o.next_attempt_at <= $1
+				AND NOT EXISTS (
+					SELECT 1
+					FROM outbox p
+					WHERE p.aggregate_id = o.aggregate_id AND p.published_at IS NULL
+						AND p.seq < o.seq
+				)
+			ORDER BY o.seq
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, seq, event_type, aggregate_id, payload, attempts, created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now, lockedUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var events []models.OutboxEvent
+	for rows.Next() {
+		var event models.OutboxEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.Seq,
+			&event.Type,
+			&event.AggregateID,
+			&event.Payload,
+			&event.Attempts,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	// UPDATE ... RETURNING does not keep the order of the subquery.
+	slices.SortFunc(events, func(a, b models.OutboxEvent) int {
+		return cmp.Compare(a.Seq, b.Seq)
+	})
+
+	return events, nil
+}
+
+// MarkPublished marks the event as published.
+func (r *OutboxRepo) MarkPublished(
+	ctx context.Context,
+	eventID string,
+	publishedAt time.Time,
+) error {
+	const op = "storage.postgres.MarkPublished"
+
+	query := `
+		UPDATE outbox
+		SET published_at = $2, last_error = ''
+		WHERE id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, eventID, publishedAt); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// MarkFailed records the failed attempt to publish the event
+// and postpones the next attempt.
+func (r *OutboxRepo) MarkFailed(
+	ctx context.Context,
+	eventID string,
+	nextAttemptAt time.Time,
+	reason string,
+) error {
+	const op = "storage.postgres.MarkFailed"
+
+	query := `
+		UPDATE outbox
+		SET next_attempt_at = $2, last_error = $3
+		WHERE id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, eventID, nextAttemptAt, reason); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// PurgePublished permanently deletes up to limit events
+// which were published before the given time.
+// It returns the number of deleted events.
+func (r *OutboxRepo) PurgePublished(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) (int64, error) {
+	const op = "storage.postgres.PurgePublished"
+
+	query := `
+		DELETE FROM outbox
+		WHERE id IN (
+			SELECT id
+			FROM outbox
+			WHERE published_at < $1
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+
+	res, err := r.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return affected, nil
+}
@@ -0,0 +1,460 @@
+package peerreview
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+)
+
+type PeerReviewRepo struct {
+	db *sql.DB
+}
+
+// New creates a new PeerReviewRepo instance.
+// That used to interact with the peer_reviews table.
+func New(db *sql.DB) *PeerReviewRepo {
+	return &PeerReviewRepo{db: db}
+}
+
+// PeerReviewsDue returns up to limit assignments with the peer review
+// whose due date has passed, but the reviewers are not assigned yet.
+func (r *PeerReviewRepo) PeerReviewsDue(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) ([]models.Assignment, error) {
+	const op = "storage.postgres.PeerReviewsDue"
+
+	query := `
+		SELECT id, peer_reviewers_count
+		FROM assignments
+		WHERE peer_reviewers_count > 0 AND peer_reviews_assigned_at IS NULL
+			AND due_date <= $1 AND published_at IS NOT NULL AND deleted_at IS NULL
+		ORDER BY due_date
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var assignments []models.Assignment
+	for rows.Next() {
+		var assignment models.Assignment
+		if err := rows.Scan(&assignment.ID, &assignment.PeerReview.ReviewersCount); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		assignments = append(assignments, assignment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return assignments, nil
+}
+
+// ReviewableSubmissions returns the submitted submissions of the assignment.
+func (r *PeerReviewRepo) ReviewableSubmissions(
+	ctx context.Context,
+	assignmentID string,
+) ([]models.Submission, error) {
+	const op = "storage.postgres.ReviewableSubmissions"
+
+	query := `
+		SELECT s.id, s.assignment_id, sa.student_id, s.status
+		FROM submissions s
+		INNER JOIN student_assignments sa ON sa.id = s.assignment_id
+		WHERE sa.assignment_id = $1 AND s.deleted_at IS NULL
+			AND s.submitted_at IS NOT NULL AND s.current_version_id IS NOT NULL
+		ORDER BY s.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, assignmentID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var submissions []models.Submission
+	for rows.Next() {
+		var submission models.Submission
+		if err := rows.Scan(
+			&submission.ID,
+			&submission.AssignmentID,
+			&submission.StudentID,
+			&submission.Status,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		submissions = append(submissions, submission)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return submissions, nil
+}
+
+// SavePeerReviews saves the reviews assigned to the reviewers
+// and marks the assignment as assigned.
+// If the reviewers of the assignment are already assigned, nothing is saved
+// and false is returned, so the assignment never happens twice.
+func (r *PeerReviewRepo) SavePeerReviews(
+	ctx context.Context,
+	assignmentID string,
+	reviews []models.PeerReview,
+	assignedAt time.Time,
+) (bool, error) {
+	const op = "storage.postgres.SavePeerReviews"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(
+		ctx,
+		`
+		UPDATE assignments
+		SET peer_reviews_assigned_at = $2
+		WHERE id = $1 AND peer_reviews_assigned_at IS NULL
+		`,
+		assignmentID,
+		assignedAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if affected == 0 {
+		return false, nil
+	}
+
+	query := `
+		INSERT INTO peer_reviews
+		(id, assignment_id, submission_id, reviewer_id, status, created_at)
+		VALUES (gen_random_uuid(), $1, $2, $3, $4, $5)
+	`
+
+	for _, review := range reviews {
+		_, err = tx.ExecContext(
+			ctx,
+			query,
+			assignmentID,
+			review.SubmissionID,
+			review.ReviewerID,
+			models.PeerReviewPending,
+			assignedAt,
+		)
+		if err != nil {
+			return false, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return true, nil
+}
+
+const selectPeerReview = `
+	SELECT
+		r.id, r.assignment_id, a.title, r.submission_id, r.reviewer_id, r.status,
+		a.peer_review_due_date, a.peer_review_criteria, r.scores, r.comment,
+		r.created_at, r.submitted_at
+	FROM peer_reviews r
+	INNER JOIN assignments a ON a.id = r.assignment_id
+	INNER JOIN submissions s ON s.id = r.submission_id
+`
+
+// PeerReview returns the peer review with the given ID.
+// Reviews of deleted assignments and submissions are reported as not found.
+func (r *PeerReviewRepo) PeerReview(
+	ctx context.Context,
+	reviewID string,
+) (models.PeerReview, error) {
+	const op = "storage.postgres.PeerReview"
+
+	query := selectPeerReview + `
+		WHERE r.id = $1 AND a.deleted_at IS NULL AND s.deleted_at IS NULL
+	`
+
+	review, err := scanPeerReview(r.db.QueryRowContext(ctx, query, reviewID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PeerReview{}, fmt.Errorf("%s: %w", op, storage.ErrPeerReviewNotFound)
+		}
+
+		return models.PeerReview{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return review, nil
+}
+
+// ReviewerReviews returns the page of the reviews assigned to the reviewer,
+// the ones closing first come first.
+func (r *PeerReviewRepo) ReviewerReviews(
+	ctx context.Context,
+	reviewerID int64,
+	filter models.Filter,
+) ([]models.PeerReview, error) {
+	const op = "storage.postgres.ReviewerReviews"
+
+	query := selectPeerReview + `
+		WHERE r.reviewer_id = $1 AND a.deleted_at IS NULL AND s.deleted_at IS NULL
+		ORDER BY a.peer_review_due_date NULLS LAST, r.created_at, r.id
+		LIMIT $2 OFFSET $3
+	`
+
+	return r.queryReviews(ctx, op, query, reviewerID, filter.Limit, filter.Offset)
+}
+
+// SubmissionReviews returns the reviews of the submission.
+func (r *PeerReviewRepo) SubmissionReviews(
+	ctx context.Context,
+	submissionID string,
+) ([]models.PeerReview, error) {
+	const op = "storage.postgres.SubmissionReviews"
+
+	query := selectPeerReview + `
+		WHERE r.submission_id = $1
+		ORDER BY r.submitted_at NULLS LAST, r.id
+	`
+
+	return r.queryReviews(ctx, op, query, submissionID)
+}
+
+// AssignmentReviews returns the reviews of the submissions of the assignment
+// which are not deleted.
+func (r *PeerReviewRepo) AssignmentReviews(
+	ctx context.Context,
+	assignmentID string,
+) ([]models.PeerReview, error) {
+	const op = "storage.postgres.AssignmentReviews"
+
+	query := selectPeerReview + `
+		WHERE r.assignment_id = $1 AND s.deleted_at IS NULL
+		ORDER BY r.submission_id, r.id
+	`
+
+	return r.queryReviews(ctx, op, query, assignmentID)
+}
+
+// ReviewedSubmissions returns the submitted submissions of the assignment
+// with the latest feedback of the teacher on their current versions.
+// Only SubmissionID, StudentID and TeacherFeedback of the summaries are set.
+func (r *PeerReviewRepo) ReviewedSubmissions(
+	ctx context.Context,
+	assignmentID string,
+) ([]models.PeerReviewSummary, error) {
+	const op = "storage.postgres.ReviewedSubmissions"
+
+	query := `
+		SELECT s.id, sa.student_id, COALESCE(f.feedback, '')
+		FROM submissions s
+		INNER JOIN student_assignments sa ON sa.id = s.assignment_id
+		LEFT JOIN LATERAL (
+			SELECT feedback
+			FROM feedbacks
+			WHERE submission_version_id = s.current_version_id
+			ORDER BY created_at DESC
+			LIMIT 1
+		) f ON TRUE
+		WHERE sa.assignment_id = $1 AND s.deleted_at IS NULL AND s.submitted_at IS NOT NULL
+		ORDER BY s.submitted_at, s.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, assignmentID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var summaries []models.PeerReviewSummary
+	for rows.Next() {
+		var summary models.PeerReviewSummary
+		if err := rows.Scan(
+			&summary.SubmissionID,
+			&summary.StudentID,
+			&summary.TeacherFeedback,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return summaries, nil
+}
+
+// SubmitPeerReview saves the scores and the comment of the review.
+// The submitted review can be changed until it is closed.
+func (r *PeerReviewRepo) SubmitPeerReview(
+	ctx context.Context,
+	reviewID string,
+	scores []models.CriterionScore,
+	comment string,
+	submittedAt time.Time,
+) error {
+	const op = "storage.postgres.SubmitPeerReview"
+
+	data, err := json.Marshal(scores)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	query := `
+		UPDATE peer_reviews
+		SET status = $2, scores = $3, comment = $4, submitted_at = $5
+		WHERE id = $1
+	`
+
+	res, err := r.db.ExecContext(
+		ctx,
+		query,
+		reviewID,
+		models.PeerReviewSubmitted,
+		data,
+		comment,
+		submittedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrPeerReviewNotFound)
+	}
+
+	return nil
+}
+
+// IsReviewer reports whether the submission is assigned to the user for review.
+func (r *PeerReviewRepo) IsReviewer(
+	ctx context.Context,
+	submissionID string,
+	userID int64,
+) (bool, error) {
+	const op = "storage.postgres.IsReviewer"
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM peer_reviews
+			WHERE submission_id = $1 AND reviewer_id = $2
+		)
+	`
+
+	var ok bool
+	if err := r.db.QueryRowContext(ctx, query, submissionID, userID).Scan(&ok); err != nil {
+		return false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return ok, nil
+}
+
+func (r *PeerReviewRepo) queryReviews(
+	ctx context.Context,
+	op string,
+	query string,
+	args ...any,
+) ([]models.PeerReview, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var reviews []models.PeerReview
+	for rows.Next() {
+		review, err := scanPeerReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		reviews = append(reviews, review)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return reviews, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPeerReview(row scanner) (models.PeerReview, error) {
+	var (
+		review      models.PeerReview
+		dueDate     sql.NullTime
+		criteria    []byte
+		scores      []byte
+		submittedAt sql.NullTime
+	)
+
+	err := row.Scan(
+		&review.ID,
+		&review.AssignmentID,
+		&review.AssignmentTitle,
+		&review.SubmissionID,
+		&review.ReviewerID,
+		&review.Status,
+		&dueDate,
+		&criteria,
+		&scores,
+		&review.Comment,
+		&review.CreatedAt,
+		&submittedAt,
+	)
+	if err != nil {
+		return models.PeerReview{}, err
+	}
+
+	review.DueDate = dueDate.Time
+	review.SubmittedAt = submittedAt.Time
+
+	if criteria != nil {
+		if err := json.Unmarshal(criteria, &review.Criteria); err != nil {
+			return models.PeerReview{}, err
+		}
+	}
+
+	if scores != nil {
+		if err := json.Unmarshal(scores, &review.Scores); err != nil {
+			return models.PeerReview{}, err
+		}
+	}
+
+	return review, nil
+}
@@ -5,8 +5,22 @@ import (
 	"fmt"
 
 	"tasks/internal/storage"
+	"tasks/internal/storage/postgres/activity"
 	"tasks/internal/storage/postgres/assignment"
+	"tasks/internal/storage/postgres/attachment"
+	"tasks/internal/storage/postgres/calendar"
+	"tasks/internal/storage/postgres/comment"
+	"tasks/internal/storage/postgres/course"
+	"tasks/internal/storage/postgres/grading"
+	"tasks/internal/storage/postgres/moderation"
+	"tasks/internal/storage/postgres/outbox"
+	"tasks/internal/storage/postgres/peerreview"
+	"tasks/internal/storage/postgres/rubric"
+	"tasks/internal/storage/postgres/search"
+	"tasks/internal/storage/postgres/similarity"
 	"tasks/internal/storage/postgres/submission"
+	"tasks/internal/storage/postgres/template"
+	"tasks/internal/storage/postgres/widget"
 
 	_ "github.com/jackc/pgx/v5"
 )
@@ -15,6 +29,20 @@ type Storage struct {
 	db *sql.DB
 	storage.AssignmentStorage
 	storage.SubmissionStorage
+	storage.AttachmentStorage
+	storage.TemplateStorage
+	storage.WidgetStorage
+	storage.CourseStorage
+	storage.RubricStorage
+	storage.SimilarityStorage
+	storage.PeerReviewStorage
+	storage.CommentStorage
+	storage.GradingStorage
+	storage.ModerationStorage
+	storage.SearchStorage
+	storage.OutboxStorage
+	storage.ActivityStorage
+	storage.CalendarStorage
 }
 
 func New(connString string) (*Storage, error) {
@@ -35,6 +63,20 @@ func New(connString string) (*Storage, error) {
 		db:                db,
 		AssignmentStorage: assignment.New(db),
 		SubmissionStorage: submission.New(db),
+		AttachmentStorage: attachment.New(db),
+		TemplateStorage:   template.New(db),
+		WidgetStorage:     widget.New(db),
+		CourseStorage:     course.New(db),
+		RubricStorage:     rubric.New(db),
+		SimilarityStorage: similarity.New(db),
+		PeerReviewStorage: peerreview.New(db),
+		CommentStorage:    comment.New(db),
+		GradingStorage:    grading.New(db),
+		ModerationStorage: moderation.New(db),
+		SearchStorage:     search.New(db),
+		OutboxStorage:     outbox.New(db),
+		ActivityStorage:   activity.New(db),
+		CalendarStorage:   calendar.New(db),
 	}, nil
 }
 
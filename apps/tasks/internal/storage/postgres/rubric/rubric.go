@@ -0,0 +1,70 @@
+package rubric
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"tasks/internal/domain/models"
+)
+
+type RubricRepo struct {
+	db *sql.DB
+}
+
+// New creates a new RubricRepo instance.
+// That used to interact with the rubrics table.
+func New(db *sql.DB) *RubricRepo {
+	return &RubricRepo{db: db}
+}
+
+// RubricsByTemplateIDs returns the rubrics of the templates with the given IDs.
+func (r *RubricRepo) RubricsByTemplateIDs(
+	ctx context.Context,
+	templateIDs []string,
+) ([]models.Rubric, error) {
+	const op = "storage.postgres.RubricsByTemplateIDs"
+
+	query := `
+		SELECT id, template_id, title, criteria
+		FROM rubrics
+		WHERE template_id = ANY($1)
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, templateIDs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var rubrics []models.Rubric
+	for rows.Next() {
+		var (
+			rubric   models.Rubric
+			criteria []byte
+		)
+
+		if err := rows.Scan(
+			&rubric.ID,
+			&rubric.TemplateID,
+			&rubric.Title,
+			&criteria,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		if err := json.Unmarshal(criteria, &rubric.Criteria); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		rubrics = append(rubrics, rubric)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return rubrics, nil
+}
@@ -0,0 +1,114 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"tasks/internal/domain/models"
+)
+
+type SearchRepo struct {
+	db *sql.DB
+}
+
+// New creates a new SearchRepo instance.
+// That used to search the assignments and assignment_templates tables.
+func New(db *sql.DB) *SearchRepo {
+	return &SearchRepo{db: db}
+}
+
+// Search returns the page of the assignments of the user and the templates
+// visible to the user from the school which match the query, the most relevant first.
+// The query is parsed with both the russian and the english stemming,
+// so the words are matched in their other forms. Assignments in the trash are skipped.
+func (r *SearchRepo) Search(
+	ctx context.Context,
+	userID int64,
+	schoolID int64,
+	query models.SearchQuery,
+	filter models.Filter,
+) ([]models.SearchResult, error) {
+	const op = "storage.postgres.Search"
+
+	q := `
+		WITH q AS (
+			SELECT websearch_to_tsquery('russian', $1) || websearch_to_tsquery('english', $1) AS query
+		)
+		SELECT r.kind, r.id, r.title, r.widget_type, r.creator_id, r.rank, r.updated_at
+		FROM (
+			SELECT
+				'assignment' AS kind, a.id, a.title, w.type AS widget_type, a.creator_id,
+				ts_rank_cd(a.search_vector, q.query) AS rank, a.updated_at
+			FROM assignments a
+			CROSS JOIN q
+			INNER JOIN assignment_templates t ON t.id = a.template_id
+			INNER JOIN widgets w ON w.id = t.widget_id
+			WHERE $2 IN ('', 'assignment') AND a.search_vector @@ q.query
+				AND a.creator_id = $3 AND a.deleted_at IS NULL
+
+			UNION ALL
+
+			SELECT
+				'template', t.id, t.title, w.type, t.creator_id,
+				ts_rank_cd(t.search_vector, q.query), t.updated_at
+			FROM assignment_templates t
+			CROSS JOIN q
+			INNER JOIN widgets w ON w.id = t.widget_id
+			WHERE $2 IN ('', 'template') AND t.search_vector @@ q.query
+				AND (
+					t.creator_id = $3
+					OR t.visibility = 'public'
+					OR (t.visibility = 'school' AND $4::BIGINT <> 0 AND t.school_id = $4)
+				)
+		) r
+		WHERE ($5 = '' OR r.widget_type = $5) AND ($6::BIGINT = 0 OR r.creator_id = $6)
+		ORDER BY r.rank DESC, r.updated_at DESC, r.id
+		LIMIT $7 OFFSET $8
+	`
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		q,
+		query.Text,
+		query.Kind,
+		userID,
+		schoolID,
+		query.WidgetType,
+		query.CreatorID,
+		filter.Limit,
+		filter.Offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var results []models.SearchResult
+	for rows.Next() {
+		var (
+			result    models.SearchResult
+			updatedAt sql.NullTime
+		)
+		if err := rows.Scan(
+			&result.Kind,
+			&result.ID,
+			&result.Title,
+			&result.WidgetType,
+			&result.CreatorID,
+			&result.Rank,
+			&updatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		result.UpdatedAt = updatedAt.Time
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,271 @@
+package similarity
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+)
+
+type SimilarityRepo struct {
+	db *sql.DB
+}
+
+// New creates a new SimilarityRepo instance.
+// That used to interact with the similarity_pairs table.
+func New(db *sql.DB) *SimilarityRepo {
+	return &SimilarityRepo{db: db}
+}
+
+// UncheckedAssignments returns the assignments with the submissions
+// submitted after their last similarity check.
+func (r *SimilarityRepo) UncheckedAssignments(
+	ctx context.Context,
+	limit int,
+) ([]string, error) {
+	const op = "storage.postgres.UncheckedAssignments"
+
+	query := `
+		SELECT a.id
+		FROM assignments a
+		WHERE a.deleted_at IS NULL AND EXISTS (
+			SELECT 1
+			FROM student_assignments sa
+			INNER JOIN submissions s ON s.assignment_id = sa.id
+			WHERE sa.assignment_id = a.id
+				AND s.deleted_at IS NULL
+				AND s.submitted_at > COALESCE(a.similarity_checked_at, '-infinity')
+		)
+		ORDER BY a.similarity_checked_at NULLS FIRST
+		LIMIT $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return ids, nil
+}
+
+// SubmittedSubmissions returns the submitted submissions of the assignment
+// with the payloads of their current versions.
+func (r *SimilarityRepo) SubmittedSubmissions(
+	ctx context.Context,
+	assignmentID string,
+) ([]models.Submission, error) {
+	const op = "storage.postgres.SubmittedSubmissions"
+
+	query := `
+		SELECT s.id, s.assignment_id, sa.student_id, s.status, v.id, v.version_number, v.payload
+		FROM submissions s
+		INNER JOIN student_assignments sa ON sa.id = s.assignment_id
+		INNER JOIN submission_versions v ON v.id = s.current_version_id
+		WHERE sa.assignment_id = $1 AND s.deleted_at IS NULL AND s.submitted_at IS NOT NULL
+		ORDER BY s.submitted_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, assignmentID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var submissions []models.Submission
+	for rows.Next() {
+		var (
+			submission models.Submission
+			version    models.SubmissionVersion
+		)
+
+		err := rows.Scan(
+			&submission.ID,
+			&submission.AssignmentID,
+			&submission.StudentID,
+			&submission.Status,
+			&version.ID,
+			&version.Number,
+			&version.Payload,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		version.SubmissionID = submission.ID
+		submission.CurrentVersion = &version
+
+		submissions = append(submissions, submission)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return submissions, nil
+}
+
+// SaveSimilarity replaces the similar pairs of the assignment
+// and marks it as checked at checkedAt.
+func (r *SimilarityRepo) SaveSimilarity(
+	ctx context.Context,
+	assignmentID string,
+	pairs []models.SimilarPair,
+	checkedAt time.Time,
+) error {
+	const op = "storage.postgres.SaveSimilarity"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM similarity_pairs WHERE assignment_id = $1", assignmentID)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	query := `
+		INSERT INTO similarity_pairs
+		(assignment_id, submission_id, other_submission_id, score, fragments, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	for _, pair := range pairs {
+		fragments, err := json.Marshal(pair.Fragments)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			query,
+			assignmentID,
+			pair.SubmissionID,
+			pair.OtherSubmissionID,
+			pair.Score,
+			fragments,
+			checkedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		"UPDATE assignments SET similarity_checked_at = $2 WHERE id = $1",
+		assignmentID,
+		checkedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// SimilarityReport returns the pairs of the assignment with the score
+// of at least minScore, the most similar first.
+// Pairs with deleted submissions are skipped.
+func (r *SimilarityRepo) SimilarityReport(
+	ctx context.Context,
+	assignmentID string,
+	minScore float64,
+) (models.SimilarityReport, error) {
+	const op = "storage.postgres.SimilarityReport"
+
+	report := models.SimilarityReport{AssignmentID: assignmentID}
+
+	var checkedAt sql.NullTime
+
+	err := r.db.QueryRowContext(
+		ctx,
+		"SELECT similarity_checked_at FROM assignments WHERE id = $1",
+		assignmentID,
+	).Scan(&checkedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.SimilarityReport{}, fmt.Errorf("%s: %w", op, storage.ErrAssignmentNotFound)
+		}
+
+		return models.SimilarityReport{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	report.CheckedAt = checkedAt.Time
+
+	query := `
+		SELECT
+			p.submission_id, sa.student_id, p.other_submission_id, osa.student_id,
+			p.score, p.fragments
+		FROM similarity_pairs p
+		INNER JOIN submissions s ON s.id = p.submission_id
+		INNER JOIN student_assignments sa ON sa.id = s.assignment_id
+		INNER JOIN submissions os ON os.id = p.other_submission_id
+		INNER JOIN student_assignments osa ON osa.id = os.assignment_id
+		WHERE p.assignment_id = $1 AND p.score >= $2
+			AND s.deleted_at IS NULL AND os.deleted_at IS NULL
+		ORDER BY p.score DESC, p.submission_id, p.other_submission_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, assignmentID, minScore)
+	if err != nil {
+		return models.SimilarityReport{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			pair      models.SimilarPair
+			fragments []byte
+		)
+
+		err := rows.Scan(
+			&pair.SubmissionID,
+			&pair.StudentID,
+			&pair.OtherSubmissionID,
+			&pair.OtherStudentID,
+			&pair.Score,
+			&fragments,
+		)
+		if err != nil {
+			return models.SimilarityReport{}, fmt.Errorf("%s: %v", op, err)
+		}
+
+		if err := json.Unmarshal(fragments, &pair.Fragments); err != nil {
+			return models.SimilarityReport{}, fmt.Errorf("%s: %v", op, err)
+		}
+
+		report.Pairs = append(report.Pairs, pair)
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.SimilarityReport{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return report, nil
+}
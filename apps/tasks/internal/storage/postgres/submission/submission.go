@@ -6,9 +6,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"tasks/internal/domain/models"
 	"tasks/internal/storage"
+	"tasks/internal/storage/postgres/activity"
+	"tasks/internal/storage/postgres/outbox"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	eventsv1 "github.com/Kaptoshka/creative-learning-platform/libs/gen/go/tasks/events/v1"
 )
 
 type SubmissionRepo struct {
@@ -21,87 +29,684 @@ func New(db *sql.DB) *SubmissionRepo {
 	return &SubmissionRepo{db: db}
 }
 
-func (r *SubmissionRepo) SubmissionByAssignmentID(
+const selectSubmission = `
+	SELECT
+		s.id, s.assignment_id, sa.student_id, a.creator_id, s.status, s.revision,
+		sa.due_date, sa.cutoff_date,
+		s.started_at, s.submitted_at, s.deleted_at,
+		v.id, v.version_number, v.payload, v.is_late, v.created_at, v.updated_at
+	FROM submissions s
+	INNER JOIN student_assignments sa ON sa.id = s.assignment_id
+	INNER JOIN assignments a ON a.id = sa.assignment_id
+	LEFT JOIN submission_versions v ON v.id = s.current_version_id
+`
+
+// Submission returns the submission with its current version.
+// Submissions in the trash are returned as well, see models.Submission.Deleted.
+func (r *SubmissionRepo) Submission(
 	ctx context.Context,
-	assignmentID int64,
-) ([]models.Submission, error) {
-	const op = "storage.postgres.SubmissionByAssignmentID"
+	submissionID string,
+) (models.Submission, error) {
+	const op = "storage.postgres.Submission"
+
+	query := selectSubmission + "WHERE s.id = $1"
+
+	submission, err := scanSubmission(r.db.QueryRowContext(ctx, query, submissionID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Submission{}, fmt.Errorf("%s: %w", op, storage.ErrSubmissionNotFound)
+		}
+
+		return models.Submission{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return submission, nil
+}
+
+// SubmissionVersion returns the version of the submission.
+func (r *SubmissionRepo) SubmissionVersion(
+	ctx context.Context,
+	versionID string,
+) (models.SubmissionVersion, error) {
+	const op = "storage.postgres.SubmissionVersion"
 
 	query := `
-		SELECT *
-		FROM submissions
-		WHERE assignment_id = $1
+		SELECT id, submission_id, version_number, payload, is_late, created_at, updated_at
+		FROM submission_versions
+		WHERE id = $1
+	`
+
+	var version models.SubmissionVersion
+	err := r.db.QueryRowContext(ctx, query, versionID).Scan(
+		&version.ID,
+		&version.SubmissionID,
+		&version.Number,
+		&version.Payload,
+		&version.IsLate,
+		&version.CreatedAt,
+		&version.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.SubmissionVersion{}, fmt.Errorf("%s: %w", op, storage.ErrVersionNotFound)
+		}
+
+		return models.SubmissionVersion{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return version, nil
+}
+
+// AssignmentSubmissions returns the page of the submissions of the assignment
+// which are not in the trash, the submitted ones first in the order of submission.
+func (r *SubmissionRepo) AssignmentSubmissions(
+	ctx context.Context,
+	assignmentID string,
+	filter models.Filter,
+) ([]models.Submission, error) {
+	const op = "storage.postgres.AssignmentSubmissions"
+
+	query := selectSubmission + `
+		WHERE sa.assignment_id = $1 AND s.deleted_at IS NULL
+		ORDER BY s.submitted_at NULLS LAST, s.started_at, s.id
+		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, assignmentID)
+	rows, err := r.db.QueryContext(ctx, query, assignmentID, filter.Limit, filter.Offset)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %v", op, err)
 	}
 	defer rows.Close()
 
 	var submissions []models.Submission
-	var contentBytes []byte
 	for rows.Next() {
-		var submission models.Submission
-		if err := rows.Scan(
-			&submission.ID,
-			&submission.AssignmentID,
-			&submission.StudentID,
-			&contentBytes,
-			&submission.StartedAt,
-			&submission.SubmittedAt,
-			&submission.Status,
-			&submission.Feedback,
-		); err != nil {
-			return nil, fmt.Errorf("%s: %v", op, err)
-		}
-
-		if err := json.Unmarshal(contentBytes, &submission.Content); err != nil {
+		submission, err := scanSubmission(rows)
+		if err != nil {
 			return nil, fmt.Errorf("%s: %v", op, err)
 		}
 
 		submissions = append(submissions, submission)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
 	return submissions, nil
 }
 
-func (r *SubmissionRepo) SubmissionByAssignmentIDAndStudentID(
+// StartSubmission starts the work of the student on the published student assignment.
+// If the student has already started it, the active submission is returned,
+// so starting is idempotent. It returns the id of the submission.
+// The start of the new submission is recorded in the activity of the assignment.
+func (r *SubmissionRepo) StartSubmission(
 	ctx context.Context,
-	assignmentID int64,
+	studentAssignmentID string,
 	studentID int64,
-) (models.Submission, error) {
-	const op = "storage.postgres.SubmissionByAssignmentIDAndStudentID"
+	now time.Time,
+) (string, error) {
+	const op = "storage.postgres.StartSubmission"
 
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	// the row of the student assignment serializes concurrent starts
 	query := `
-		SELECT *
+		SELECT sa.assignment_id
+		FROM student_assignments sa
+		INNER JOIN assignments a ON a.id = sa.assignment_id
+		WHERE sa.id = $1 AND sa.student_id = $2
+			AND a.published_at IS NOT NULL AND a.deleted_at IS NULL
+		FOR UPDATE OF sa
+	`
+
+	var assignmentID string
+	err = tx.QueryRowContext(ctx, query, studentAssignmentID, studentID).Scan(&assignmentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrAssignmentNotFound)
+		}
+
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	query = `
+		SELECT id
 		FROM submissions
-		WHERE assignment_id = $1 AND student_id = $2
+		WHERE assignment_id = $1 AND deleted_at IS NULL
+		ORDER BY started_at DESC
+		LIMIT 1
+	`
+
+	var id string
+	err = tx.QueryRowContext(ctx, query, studentAssignmentID).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	query = `
+		INSERT INTO submissions (id, assignment_id, creator_id, status, started_at)
+		VALUES (gen_random_uuid(), $1, $2, $3, $4)
+		RETURNING id
+	`
+
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		studentAssignmentID,
+		studentID,
+		models.StatusInProgress,
+		now,
+	).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`
+		UPDATE student_assignments
+		SET status = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		`,
+		studentAssignmentID,
+		models.StatusInProgress,
+	)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	err = activity.Insert(ctx, tx, models.Activity{
+		AssignmentID:        assignmentID,
+		StudentAssignmentID: studentAssignmentID,
+		SubmissionID:        id,
+		StudentID:           studentID,
+		Kind:                models.ActivityStarted,
+		CreatedAt:           now,
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	return id, nil
+}
+
+// SaveDraft saves the payload of the submission if its revision is still the expected one.
+//
+// While the submission is in progress, the payload overwrites the current version
+// created after coalesceSince, so rapid autosaves produce a single draft version.
+// Otherwise the new version is created and the submission becomes in progress again.
+// It returns the new revision of the submission,
+// or storage.ErrRevisionConflict if the submission was changed concurrently.
+// The autosave is recorded in the activity of the assignment.
+func (r *SubmissionRepo) SaveDraft(
+	ctx context.Context,
+	submissionID string,
+	expectedRevision int64,
+	payload json.RawMessage,
+	isLate bool,
+	coalesceSince time.Time,
+) (int64, error) {
+	const op = "storage.postgres.SaveDraft"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT s.assignment_id, sa.assignment_id, sa.student_id, s.revision, s.status, v.id, v.created_at
+		FROM submissions s
+		INNER JOIN student_assignments sa ON sa.id = s.assignment_id
+		LEFT JOIN submission_versions v ON v.id = s.current_version_id
+		WHERE s.id = $1 AND s.deleted_at IS NULL
+		FOR UPDATE OF s
+	`
+
+	var (
+		studentAssignmentID string
+		assignmentID        string
+		studentID           int64
+		revision            int64
+		status              models.SubmissionStatus
+		versionID           sql.NullString
+		versionCreatedAt    sql.NullTime
+	)
+
+	err = tx.QueryRowContext(ctx, query, submissionID).Scan(
+		&studentAssignmentID,
+		&assignmentID,
+		&studentID,
+		&revision,
+		&status,
+		&versionID,
+		&versionCreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrSubmissionNotFound)
+		}
+
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if revision != expectedRevision {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrRevisionConflict)
+	}
+
+	coalesce := status == models.StatusInProgress &&
+		versionID.Valid &&
+		!versionCreatedAt.Time.Before(coalesceSince)
+
+	if coalesce {
+		_, err = tx.ExecContext(
+			ctx,
+			`
+			UPDATE submission_versions
+			SET payload = $2, is_late = $3, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1
+			`,
+			versionID.String,
+			[]byte(payload),
+			isLate,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", op, err)
+		}
+	} else {
+		query = `
+			INSERT INTO submission_versions
+			(id, submission_id, version_number, payload, is_late)
+			SELECT gen_random_uuid(), $1, COALESCE(MAX(version_number), 0) + 1, $2, $3
+			FROM submission_versions
+			WHERE submission_id = $1
+			RETURNING id
+		`
+
+		err = tx.QueryRowContext(ctx, query, submissionID, []byte(payload), isLate).Scan(&versionID)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	query = `
+		UPDATE submissions
+		SET current_version_id = $2, status = $3, revision = revision + 1
+		WHERE id = $1
+		RETURNING revision
+	`
+
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		submissionID,
+		versionID.String,
+		models.StatusInProgress,
+	).Scan(&revision)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if status != models.StatusInProgress {
+		_, err = tx.ExecContext(
+			ctx,
+			`
+			UPDATE student_assignments
+			SET status = $2, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1
+			`,
+			studentAssignmentID,
+			models.StatusInProgress,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	err = activity.Insert(ctx, tx, models.Activity{
+		AssignmentID:        assignmentID,
+		StudentAssignmentID: studentAssignmentID,
+		SubmissionID:        submissionID,
+		StudentID:           studentID,
+		Kind:                models.ActivityAutosaved,
+		Revision:            revision,
+		IsLate:              isLate,
+		CreatedAt:           time.Now().UTC(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return revision, nil
+}
+
+// Submit hands the current version of the submission in for grading
+// if the revision of the submission is still the expected one.
+// It returns the new revision of the submission,
+// or storage.ErrRevisionConflict if the submission was changed concurrently.
+// The submission is announced with the SubmissionSubmitted event
+// and recorded in the activity of the assignment.
+func (r *SubmissionRepo) Submit(
+	ctx context.Context,
+	submissionID string,
+	expectedRevision int64,
+	isLate bool,
+	now time.Time,
+) (int64, error) {
+	const op = "storage.postgres.Submit"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE submissions
+		SET status = $3, submitted_at = $4, revision = revision + 1
+		WHERE id = $1 AND revision = $2 AND deleted_at IS NULL
+		RETURNING assignment_id, current_version_id, revision
+	`
+
+	var (
+		studentAssignmentID string
+		versionID           sql.NullString
+		revision            int64
+	)
+
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		submissionID,
+		expectedRevision,
+		models.StatusSubmitted,
+		now,
+	).Scan(&studentAssignmentID, &versionID, &revision)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrRevisionConflict)
+		}
+
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		"UPDATE submission_versions SET is_late = $2 WHERE id = $1",
+		versionID,
+		isLate,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	var (
+		assignmentID string
+		studentID    int64
+		teacherID    int64
+	)
+
+	err = tx.QueryRowContext(
+		ctx,
+		`
+		UPDATE student_assignments sa
+		SET status = $2, updated_at = CURRENT_TIMESTAMP
+		FROM assignments a
+		WHERE sa.id = $1 AND a.id = sa.assignment_id
+		RETURNING sa.assignment_id, sa.student_id, a.creator_id
+		`,
+		studentAssignmentID,
+		models.StatusSubmitted,
+	).Scan(&assignmentID, &studentID, &teacherID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	err = outbox.Insert(ctx, tx, submissionID, now, &eventsv1.SubmissionSubmitted{
+		SubmissionId:        submissionID,
+		AssignmentId:        assignmentID,
+		StudentAssignmentId: studentAssignmentID,
+		StudentId:           strconv.FormatInt(studentID, 10),
+		TeacherId:           strconv.FormatInt(teacherID, 10),
+		VersionId:           versionID.String,
+		IsLate:              isLate,
+		SubmittedAt:         timestamppb.New(now),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	err = activity.Insert(ctx, tx, models.Activity{
+		AssignmentID:        assignmentID,
+		StudentAssignmentID: studentAssignmentID,
+		SubmissionID:        submissionID,
+		StudentID:           studentID,
+		Kind:                models.ActivitySubmitted,
+		Revision:            revision,
+		IsLate:              isLate,
+		CreatedAt:           now,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return revision, nil
+}
+
+// DeleteSubmission moves the submission with all its versions to the trash.
+// The submission with the published feedback is kept
+// and reported as storage.ErrSubmissionNotFound.
+func (r *SubmissionRepo) DeleteSubmission(
+	ctx context.Context,
+	submissionID string,
+) error {
+	const op = "storage.postgres.DeleteSubmission"
+
+	query := `
+		UPDATE submissions
+		SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL AND NOT ` + hasPublishedFeedback + `
+	`
+
+	return r.exec(ctx, op, query, submissionID)
+}
+
+// RestoreSubmission moves the submission out of the trash.
+func (r *SubmissionRepo) RestoreSubmission(
+	ctx context.Context,
+	submissionID string,
+) error {
+	const op = "storage.postgres.RestoreSubmission"
+
+	query := `
+		UPDATE submissions
+		SET deleted_at = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`
+
+	return r.exec(ctx, op, query, submissionID)
+}
+
+// DeletedSubmissions returns the page of submissions of the student in the trash,
+// the most recently deleted first. Items are titled after their assignments.
+func (r *SubmissionRepo) DeletedSubmissions(
+	ctx context.Context,
+	studentID int64,
+	filter models.Filter,
+) ([]models.TrashItem, error) {
+	const op = "storage.postgres.DeletedSubmissions"
+
+	query := `
+		SELECT s.id, a.title, s.deleted_at
+		FROM submissions s
+		INNER JOIN student_assignments sa ON sa.id = s.assignment_id
+		INNER JOIN assignments a ON a.id = sa.assignment_id
+		WHERE sa.student_id = $1 AND s.deleted_at IS NOT NULL
+		ORDER BY s.deleted_at DESC, s.id
+		LIMIT $2 OFFSET $3
 	`
 
-	res := r.db.QueryRowContext(ctx, query, assignmentID, studentID)
+	rows, err := r.db.QueryContext(ctx, query, studentID, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var items []models.TrashItem
+	for rows.Next() {
+		item := models.TrashItem{Kind: models.TrashSubmission}
+		if err := rows.Scan(&item.ID, &item.Title, &item.DeletedAt); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return items, nil
+}
+
+// PurgeSubmissions permanently deletes up to limit submissions
+// which were moved to the trash before the given time.
+// Versions with their feedbacks and comments are deleted by cascade,
+// so the submissions with the published feedback are never purged.
+// It returns the number of deleted submissions.
+func (r *SubmissionRepo) PurgeSubmissions(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) (int64, error) {
+	const op = "storage.postgres.PurgeSubmissions"
+
+	query := `
+		DELETE FROM submissions
+		WHERE id IN (
+			SELECT id
+			FROM submissions
+			WHERE deleted_at < $1 AND NOT ` + hasPublishedFeedback + `
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+
+	res, err := r.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return affected, nil
+}
+
+// hasPublishedFeedback is the condition of the submission
+// whose grade is published to the student.
+const hasPublishedFeedback = `EXISTS (
+	SELECT 1
+	FROM submission_versions v
+	INNER JOIN feedbacks f ON f.submission_version_id = v.id
+	WHERE v.submission_id = submissions.id AND f.is_published
+)`
+
+// exec executes the update of the single submission.
+// If no rows are affected, it returns storage.ErrSubmissionNotFound.
+func (r *SubmissionRepo) exec(ctx context.Context, op string, query string, args ...any) error {
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
 
-	var submission models.Submission
-	var contentBytes []byte
-	err := res.Scan(
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrSubmissionNotFound)
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSubmission(row scanner) (models.Submission, error) {
+	var (
+		submission  models.Submission
+		submittedAt sql.NullTime
+		deletedAt   sql.NullTime
+		versionID   sql.NullString
+		number      sql.NullInt64
+		payload     []byte
+		isLate      sql.NullBool
+		createdAt   sql.NullTime
+		updatedAt   sql.NullTime
+	)
+
+	err := row.Scan(
 		&submission.ID,
 		&submission.AssignmentID,
 		&submission.StudentID,
-		&contentBytes,
-		&submission.StartedAt,
-		&submission.SubmittedAt,
+		&submission.TeacherID,
 		&submission.Status,
-		&submission.Feedback,
+		&submission.Revision,
+		&submission.DueDate,
+		&submission.CutoffDate,
+		&submission.StartedAt,
+		&submittedAt,
+		&deletedAt,
+		&versionID,
+		&number,
+		&payload,
+		&isLate,
+		&createdAt,
+		&updatedAt,
 	)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return models.Submission{}, fmt.Errorf("%s: %v", op, storage.ErrSubmissionNotFound)
-		}
-		return models.Submission{}, fmt.Errorf("%s: %v", op, err)
+		return models.Submission{}, err
 	}
 
-	if err := json.Unmarshal(contentBytes, &submission.Content); err != nil {
-		return models.Submission{}, fmt.Errorf("%s: %v", op, err)
+	submission.SubmittedAt = submittedAt.Time
+	submission.DeletedAt = deletedAt.Time
+
+	if versionID.Valid {
+		submission.CurrentVersion = &models.SubmissionVersion{
+			ID:           versionID.String,
+			SubmissionID: submission.ID,
+			Number:       int(number.Int64),
+			Payload:      payload,
+			IsLate:       isLate.Bool,
+			CreatedAt:    createdAt.Time,
+			UpdatedAt:    updatedAt.Time,
+		}
+		submission.UpdatedAt = updatedAt.Time
 	}
 
 	return submission, nil
@@ -0,0 +1,279 @@
+package template
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+)
+
+type TemplateRepo struct {
+	db *sql.DB
+}
+
+// New creates a new TemplateRepo instance.
+// That used to interact with the assignment_templates table.
+func New(db *sql.DB) *TemplateRepo {
+	return &TemplateRepo{db: db}
+}
+
+const selectTemplate = `
+	SELECT
+		t.id, t.creator_id, t.school_id, t.title,
+		w.id, w.type, w.version, t.widget_config,
+		t.visibility, COALESCE(t.cloned_from::text, ''),
+		t.created_at, t.updated_at
+	FROM assignment_templates t
+	INNER JOIN widgets w ON w.id = t.widget_id
+`
+
+// Template returns the template with the given ID.
+func (r *TemplateRepo) Template(
+	ctx context.Context,
+	templateID string,
+) (models.Template, error) {
+	const op = "storage.postgres.Template"
+
+	query := selectTemplate + `
+		WHERE t.id = $1
+	`
+
+	template, err := scanTemplate(r.db.QueryRowContext(ctx, query, templateID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Template{}, fmt.Errorf("%s: %w", op, storage.ErrTemplateNotFound)
+		}
+
+		return models.Template{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return template, nil
+}
+
+// TemplatesByIDs returns the templates with the given IDs.
+func (r *TemplateRepo) TemplatesByIDs(
+	ctx context.Context,
+	templateIDs []string,
+) ([]models.Template, error) {
+	const op = "storage.postgres.TemplatesByIDs"
+
+	query := selectTemplate + `
+		WHERE t.id = ANY($1)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, templateIDs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var templates []models.Template
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		templates = append(templates, template)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return templates, nil
+}
+
+// Templates returns the page of templates available in the given scope.
+func (r *TemplateRepo) Templates(
+	ctx context.Context,
+	scope models.TemplateScope,
+	userID int64,
+	schoolID int64,
+	filter models.Filter,
+) ([]models.Template, error) {
+	const op = "storage.postgres.Templates"
+
+	var (
+		where string
+		args  []any
+	)
+
+	switch scope {
+	case models.TemplateScopeMine:
+		where = "WHERE t.creator_id = $1"
+		args = append(args, userID)
+	case models.TemplateScopeSchool:
+		// the school 0 is unknown, there is no such school to share with
+		where = "WHERE t.school_id = $1 AND $1 <> 0 AND t.visibility IN ('school', 'public')"
+		args = append(args, schoolID)
+	case models.TemplateScopePublic:
+		where = "WHERE t.visibility = 'public'"
+	default:
+		return nil, fmt.Errorf("%s: unknown scope %d", op, scope)
+	}
+
+	query := selectTemplate + where + fmt.Sprintf(
+		" ORDER BY t.updated_at DESC, t.id LIMIT $%d OFFSET $%d",
+		len(args)+1,
+		len(args)+2,
+	)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var templates []models.Template
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		templates = append(templates, template)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return templates, nil
+}
+
+// SaveTemplate saves a new template and returns its ID.
+func (r *TemplateRepo) SaveTemplate(
+	ctx context.Context,
+	template models.Template,
+) (string, error) {
+	const op = "storage.postgres.SaveTemplate"
+
+	query := `
+		INSERT INTO assignment_templates
+		(id, creator_id, school_id, title, widget_id, widget_config, visibility)
+		VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	var id string
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		template.CreatorID,
+		template.SchoolID,
+		template.Title,
+		template.Widget.ID,
+		[]byte(template.Widget.Config),
+		template.Visibility,
+	).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	return id, nil
+}
+
+// CloneTemplate copies the template with the given ID to the new owner
+// and records the source template in cloned_from.
+// If title is empty, the title of the source template is kept.
+func (r *TemplateRepo) CloneTemplate(
+	ctx context.Context,
+	templateID string,
+	creatorID int64,
+	schoolID int64,
+	title string,
+) (string, error) {
+	const op = "storage.postgres.CloneTemplate"
+
+	query := `
+		INSERT INTO assignment_templates
+		(id, creator_id, school_id, title, widget_id, widget_config, visibility, cloned_from)
+		SELECT
+			gen_random_uuid(), $2, $3, COALESCE(NULLIF($4, ''), title),
+			widget_id, widget_config, 'private', id
+		FROM assignment_templates
+		WHERE id = $1
+		RETURNING id
+	`
+
+	var id string
+	err := r.db.QueryRowContext(ctx, query, templateID, creatorID, schoolID, title).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrTemplateNotFound)
+		}
+
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	return id, nil
+}
+
+// UpdateTemplateVisibility changes who the template is shared with.
+func (r *TemplateRepo) UpdateTemplateVisibility(
+	ctx context.Context,
+	templateID string,
+	visibility models.Visibility,
+) error {
+	const op = "storage.postgres.UpdateTemplateVisibility"
+
+	query := `
+		UPDATE assignment_templates
+		SET visibility = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+
+	res, err := r.db.ExecContext(ctx, query, templateID, visibility)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrTemplateNotFound)
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTemplate(row scanner) (models.Template, error) {
+	var (
+		template models.Template
+		config   []byte
+	)
+
+	err := row.Scan(
+		&template.ID,
+		&template.CreatorID,
+		&template.SchoolID,
+		&template.Title,
+		&template.Widget.ID,
+		&template.Widget.Type,
+		&template.Widget.Version,
+		&config,
+		&template.Visibility,
+		&template.ClonedFrom,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		return models.Template{}, err
+	}
+
+	template.Widget.Config = config
+
+	return template, nil
+}
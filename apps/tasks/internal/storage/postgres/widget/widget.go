@@ -0,0 +1,84 @@
+package widget
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"tasks/internal/domain/models"
+	"tasks/internal/storage"
+)
+
+type WidgetRepo struct {
+	db *sql.DB
+}
+
+// New creates a new WidgetRepo instance.
+// That used to interact with the widgets table.
+func New(db *sql.DB) *WidgetRepo {
+	return &WidgetRepo{db: db}
+}
+
+// Widget returns the widget with the given type and version.
+func (r *WidgetRepo) Widget(
+	ctx context.Context,
+	widgetType string,
+	version int,
+) (models.Widget, error) {
+	const op = "storage.postgres.Widget"
+
+	query := `
+		SELECT id, type, version
+		FROM widgets
+		WHERE type = $1 AND version = $2
+	`
+
+	var widget models.Widget
+	err := r.db.QueryRowContext(ctx, query, widgetType, version).Scan(
+		&widget.ID,
+		&widget.Type,
+		&widget.Version,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Widget{}, fmt.Errorf("%s: %w", op, storage.ErrWidgetNotFound)
+		}
+
+		return models.Widget{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return widget, nil
+}
+
+// LatestWidget returns the latest version of the widget with the given type.
+func (r *WidgetRepo) LatestWidget(
+	ctx context.Context,
+	widgetType string,
+) (models.Widget, error) {
+	const op = "storage.postgres.LatestWidget"
+
+	query := `
+		SELECT id, type, version
+		FROM widgets
+		WHERE type = $1
+		ORDER BY version DESC
+		LIMIT 1
+	`
+
+	var widget models.Widget
+	err := r.db.QueryRowContext(ctx, query, widgetType).Scan(
+		&widget.ID,
+		&widget.Type,
+		&widget.Version,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Widget{}, fmt.Errorf("%s: %w", op, storage.ErrWidgetNotFound)
+		}
+
+		return models.Widget{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return widget, nil
+}
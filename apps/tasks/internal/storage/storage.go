@@ -1,7 +1,13 @@
 package storage
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"io"
+	"time"
+
+	"tasks/internal/domain/models"
 )
 
 var (
@@ -9,10 +15,538 @@ var (
 	ErrAssignmentNotFound      = errors.New("assignment not found")
 	ErrAssignmentUpdateFailed  = errors.New("assignment update failed")
 	ErrSubmissionNotFound      = errors.New("submission not found")
+	ErrRevisionConflict        = errors.New("submission revision conflict")
+	ErrTemplateNotFound        = errors.New("template not found")
+	ErrWidgetNotFound          = errors.New("widget not found")
+	ErrCourseNotFound          = errors.New("course not found")
+	ErrAttachmentNotFound      = errors.New("attachment not found")
+	ErrQuotaExceeded           = errors.New("attachment quota exceeded")
+	ErrBlobNotFound            = errors.New("blob not found")
+	ErrPeerReviewNotFound      = errors.New("peer review not found")
+	ErrVersionNotFound         = errors.New("submission version not found")
+	ErrThreadNotFound          = errors.New("comment thread not found")
+	ErrCommentNotFound         = errors.New("comment not found")
+	ErrFeedbackNotFound        = errors.New("feedback not found")
+	ErrRegradeNotFound         = errors.New("regrade request not found")
+	ErrRegradeAlreadyOpen      = errors.New("regrade request is already open")
+	ErrModerationNotFound      = errors.New("moderation not found")
+	ErrModerationAlreadyExists = errors.New("moderation already exists")
+	ErrModerationItemNotFound  = errors.New("moderation item not found")
+	ErrCalendarFeedNotFound    = errors.New("calendar feed not found")
 )
 
 type SubmissionStorage interface {
+	Submission(
+		ctx context.Context,
+		submissionID string,
+	) (models.Submission, error)
+	StartSubmission(
+		ctx context.Context,
+		studentAssignmentID string,
+		studentID int64,
+		now time.Time,
+	) (string, error)
+	SaveDraft(
+		ctx context.Context,
+		submissionID string,
+		expectedRevision int64,
+		payload json.RawMessage,
+		isLate bool,
+		coalesceSince time.Time,
+	) (int64, error)
+	Submit(
+		ctx context.Context,
+		submissionID string,
+		expectedRevision int64,
+		isLate bool,
+		now time.Time,
+	) (int64, error)
+	DeleteSubmission(
+		ctx context.Context,
+		submissionID string,
+	) error
+	RestoreSubmission(
+		ctx context.Context,
+		submissionID string,
+	) error
+	DeletedSubmissions(
+		ctx context.Context,
+		studentID int64,
+		filter models.Filter,
+	) ([]models.TrashItem, error)
+	PurgeSubmissions(
+		ctx context.Context,
+		before time.Time,
+		limit int,
+	) (int64, error)
+	AssignmentSubmissions(
+		ctx context.Context,
+		assignmentID string,
+		filter models.Filter,
+	) ([]models.Submission, error)
+	SubmissionVersion(
+		ctx context.Context,
+		versionID string,
+	) (models.SubmissionVersion, error)
 }
 
 type AssignmentStorage interface {
+	SaveAssignment(
+		ctx context.Context,
+		assignment models.Assignment,
+	) (string, error)
+	UpdateAssignment(
+		ctx context.Context,
+		assignmentID string,
+		update models.AssignmentUpdate,
+	) error
+	Assignment(
+		ctx context.Context,
+		assignmentID string,
+	) (models.Assignment, error)
+	StudentAssignments(
+		ctx context.Context,
+		studentID int64,
+		filter models.Filter,
+	) ([]models.StudentAssignment, error)
+	StudentAssignment(
+		ctx context.Context,
+		studentAssignmentID string,
+		studentID int64,
+	) (models.StudentAssignment, error)
+	PublishDue(
+		ctx context.Context,
+		now time.Time,
+		limit int,
+	) ([]string, error)
+	DeleteAssignment(
+		ctx context.Context,
+		assignmentID string,
+	) error
+	RestoreAssignment(
+		ctx context.Context,
+		assignmentID string,
+	) error
+	DeletedAssignments(
+		ctx context.Context,
+		creatorID int64,
+		filter models.Filter,
+	) ([]models.TrashItem, error)
+	PurgeAssignments(
+		ctx context.Context,
+		before time.Time,
+		limit int,
+	) (int64, error)
+	RevealIdentities(
+		ctx context.Context,
+		reveal models.IdentityReveal,
+	) error
+	ExtendStudentAssignment(
+		ctx context.Context,
+		extension models.Extension,
+	) error
+	IdentityReveals(
+		ctx context.Context,
+		assignmentID string,
+	) ([]models.IdentityReveal, error)
+}
+
+type TemplateStorage interface {
+	Template(
+		ctx context.Context,
+		templateID string,
+	) (models.Template, error)
+	TemplatesByIDs(
+		ctx context.Context,
+		templateIDs []string,
+	) ([]models.Template, error)
+	Templates(
+		ctx context.Context,
+		scope models.TemplateScope,
+		userID int64,
+		schoolID int64,
+		filter models.Filter,
+	) ([]models.Template, error)
+	SaveTemplate(
+		ctx context.Context,
+		template models.Template,
+	) (string, error)
+	CloneTemplate(
+		ctx context.Context,
+		templateID string,
+		creatorID int64,
+		schoolID int64,
+		title string,
+	) (string, error)
+	UpdateTemplateVisibility(
+		ctx context.Context,
+		templateID string,
+		visibility models.Visibility,
+	) error
+}
+
+type WidgetStorage interface {
+	Widget(
+		ctx context.Context,
+		widgetType string,
+		version int,
+	) (models.Widget, error)
+	LatestWidget(
+		ctx context.Context,
+		widgetType string,
+	) (models.Widget, error)
+}
+
+type CourseStorage interface {
+	Course(
+		ctx context.Context,
+		courseID string,
+	) (models.Course, error)
+	SaveCourse(
+		ctx context.Context,
+		course models.Course,
+		templates []models.Template,
+		rubrics []models.Rubric,
+		commit bool,
+	) error
+	AddCourseSection(
+		ctx context.Context,
+		courseID string,
+		section models.CourseSection,
+	) error
+}
+
+type RubricStorage interface {
+	RubricsByTemplateIDs(
+		ctx context.Context,
+		templateIDs []string,
+	) ([]models.Rubric, error)
+}
+
+type AttachmentStorage interface {
+	SaveAttachment(
+		ctx context.Context,
+		attachment models.Attachment,
+		quota int64,
+	) (string, error)
+	Attachment(
+		ctx context.Context,
+		attachmentID string,
+	) (models.Attachment, error)
+	SubmissionAttachments(
+		ctx context.Context,
+		submissionID string,
+	) ([]models.Attachment, error)
+	UsedQuota(
+		ctx context.Context,
+		ownerID int64,
+	) (int64, error)
+	OrphanedAttachments(
+		ctx context.Context,
+		limit int,
+	) ([]models.Attachment, error)
+	DeleteAttachment(
+		ctx context.Context,
+		attachmentID string,
+	) error
+	ClaimPreviews(
+		ctx context.Context,
+		now time.Time,
+		lockedUntil time.Time,
+		limit int,
+	) ([]models.Attachment, error)
+	SavePreview(
+		ctx context.Context,
+		attachmentID string,
+		preview models.AttachmentPreview,
+	) error
+}
+
+type SimilarityStorage interface {
+	UncheckedAssignments(
+		ctx context.Context,
+		limit int,
+	) ([]string, error)
+	SubmittedSubmissions(
+		ctx context.Context,
+		assignmentID string,
+	) ([]models.Submission, error)
+	SaveSimilarity(
+		ctx context.Context,
+		assignmentID string,
+		pairs []models.SimilarPair,
+		checkedAt time.Time,
+	) error
+	SimilarityReport(
+		ctx context.Context,
+		assignmentID string,
+		minScore float64,
+	) (models.SimilarityReport, error)
+}
+
+type PeerReviewStorage interface {
+	PeerReviewsDue(
+		ctx context.Context,
+		now time.Time,
+		limit int,
+	) ([]models.Assignment, error)
+	ReviewableSubmissions(
+		ctx context.Context,
+		assignmentID string,
+	) ([]models.Submission, error)
+	SavePeerReviews(
+		ctx context.Context,
+		assignmentID string,
+		reviews []models.PeerReview,
+		assignedAt time.Time,
+	) (bool, error)
+	PeerReview(
+		ctx context.Context,
+		reviewID string,
+	) (models.PeerReview, error)
+	ReviewerReviews(
+		ctx context.Context,
+		reviewerID int64,
+		filter models.Filter,
+	) ([]models.PeerReview, error)
+	SubmissionReviews(
+		ctx context.Context,
+		submissionID string,
+	) ([]models.PeerReview, error)
+	AssignmentReviews(
+		ctx context.Context,
+		assignmentID string,
+	) ([]models.PeerReview, error)
+	ReviewedSubmissions(
+		ctx context.Context,
+		assignmentID string,
+	) ([]models.PeerReviewSummary, error)
+	SubmitPeerReview(
+		ctx context.Context,
+		reviewID string,
+		scores []models.CriterionScore,
+		comment string,
+		submittedAt time.Time,
+	) error
+	IsReviewer(
+		ctx context.Context,
+		submissionID string,
+		userID int64,
+	) (bool, error)
+}
+
+type CommentStorage interface {
+	SaveThread(
+		ctx context.Context,
+		thread models.CommentThread,
+	) (string, error)
+	SaveComment(
+		ctx context.Context,
+		comment models.Comment,
+	) (string, error)
+	UpdateComment(
+		ctx context.Context,
+		commentID string,
+		body string,
+		editedAt time.Time,
+	) error
+	ResolveThread(
+		ctx context.Context,
+		threadID string,
+		resolvedBy models.CommentAuthor,
+		resolvedAt time.Time,
+	) error
+	Thread(
+		ctx context.Context,
+		threadID string,
+	) (models.CommentThread, error)
+	Threads(
+		ctx context.Context,
+		submissionID string,
+		versionID string,
+	) ([]models.CommentThread, error)
+	Comment(
+		ctx context.Context,
+		commentID string,
+	) (models.Comment, error)
+}
+
+type GradingStorage interface {
+	SaveDraftFeedback(
+		ctx context.Context,
+		feedback models.Feedback,
+	) error
+	PublishFeedback(
+		ctx context.Context,
+		feedback models.Feedback,
+		status models.SubmissionStatus,
+	) error
+	PublishedFeedback(
+		ctx context.Context,
+		submissionID string,
+	) (models.Feedback, error)
+	SaveRegradeRequest(
+		ctx context.Context,
+		request models.RegradeRequest,
+	) (string, error)
+	ResolveRegrade(
+		ctx context.Context,
+		request models.RegradeRequest,
+	) error
+	RegradeRequest(
+		ctx context.Context,
+		requestID string,
+	) (models.RegradeRequest, error)
+	OpenRegrades(
+		ctx context.Context,
+		teacherID int64,
+		filter models.Filter,
+	) ([]models.RegradeRequest, error)
+	SubmissionEvents(
+		ctx context.Context,
+		submissionID string,
+	) ([]models.SubmissionEvent, error)
+}
+
+type ModerationStorage interface {
+	GradedSubmissions(
+		ctx context.Context,
+		assignmentID string,
+	) ([]models.ModerationItem, error)
+	SaveModeration(
+		ctx context.Context,
+		moderation models.Moderation,
+	) error
+	Moderation(
+		ctx context.Context,
+		assignmentID string,
+	) (models.Moderation, error)
+	SubmissionModeration(
+		ctx context.Context,
+		submissionID string,
+	) (models.Moderation, error)
+	PendingModerations(
+		ctx context.Context,
+		moderatorID int64,
+		filter models.Filter,
+	) ([]models.Moderation, error)
+	ModerationItem(
+		ctx context.Context,
+		itemID string,
+	) (models.ModerationItem, error)
+	RecordModeration(
+		ctx context.Context,
+		item models.ModerationItem,
+	) error
+}
+
+type SearchStorage interface {
+	Search(
+		ctx context.Context,
+		userID int64,
+		schoolID int64,
+		query models.SearchQuery,
+		filter models.Filter,
+	) ([]models.SearchResult, error)
+}
+
+type OutboxStorage interface {
+	ClaimEvents(
+		ctx context.Context,
+		now time.Time,
+		lockedUntil time.Time,
+		limit int,
+	) ([]models.OutboxEvent, error)
+	MarkPublished(
+		ctx context.Context,
+		eventID string,
+		publishedAt time.Time,
+	) error
+	MarkFailed(
+		ctx context.Context,
+		eventID string,
+		nextAttemptAt time.Time,
+		reason string,
+	) error
+	PurgePublished(
+		ctx context.Context,
+		before time.Time,
+		limit int,
+	) (int64, error)
+}
+
+// BlobStore keeps the content of the attachments.
+type BlobStore interface {
+	// Put stores size bytes read from r under the key.
+	Put(
+		ctx context.Context,
+		key string,
+		r io.Reader,
+		size int64,
+		contentType string,
+	) error
+	// Open returns the content stored under the key starting from the offset.
+	// If there is no such key, it returns ErrBlobNotFound.
+	Open(
+		ctx context.Context,
+		key string,
+		offset int64,
+	) (io.ReadCloser, error)
+	// Delete removes the content stored under the key.
+	// Deleting the missing key is not an error.
+	Delete(
+		ctx context.Context,
+		key string,
+	) error
+}
+
+type ActivityStorage interface {
+	Activities(
+		ctx context.Context,
+		assignmentID string,
+		afterID int64,
+		limit int,
+	) ([]models.Activity, error)
+	HasActivity(
+		ctx context.Context,
+		assignmentID string,
+		activityID int64,
+	) (bool, error)
+	LastActivityID(
+		ctx context.Context,
+		assignmentID string,
+	) (int64, error)
+	RecordTimeLimits(
+		ctx context.Context,
+		since time.Time,
+		now time.Time,
+	) (int64, error)
+	PurgeActivity(
+		ctx context.Context,
+		before time.Time,
+		limit int,
+	) (int64, error)
+}
+
+type CalendarStorage interface {
+	SaveCalendarFeed(
+		ctx context.Context,
+		userID int64,
+		tokenHash []byte,
+		createdAt time.Time,
+	) error
+	DeleteCalendarFeed(
+		ctx context.Context,
+		userID int64,
+	) error
+	CalendarFeedUser(
+		ctx context.Context,
+		tokenHash []byte,
+	) (int64, error)
+	CalendarEntries(
+		ctx context.Context,
+		studentID int64,
+		since time.Time,
+		limit int,
+	) ([]models.CalendarEntry, error)
 }
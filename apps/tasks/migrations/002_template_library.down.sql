@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_assignment_templates_cloned_from;
+DROP INDEX IF EXISTS idx_assignment_templates_school_visibility;
+DROP INDEX IF EXISTS idx_assignment_templates_creator_id;
+
+ALTER TABLE assignment_templates
+    DROP COLUMN IF EXISTS cloned_from,
+    DROP COLUMN IF EXISTS visibility,
+    DROP COLUMN IF EXISTS school_id;
@@ -0,0 +1,8 @@
+ALTER TABLE assignment_templates
+    ADD COLUMN IF NOT EXISTS school_id BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS visibility VARCHAR(60) NOT NULL DEFAULT 'private',
+    ADD COLUMN IF NOT EXISTS cloned_from UUID REFERENCES assignment_templates(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_assignment_templates_creator_id ON assignment_templates(creator_id);
+CREATE INDEX IF NOT EXISTS idx_assignment_templates_school_visibility ON assignment_templates(school_id, visibility);
+CREATE INDEX IF NOT EXISTS idx_assignment_templates_cloned_from ON assignment_templates(cloned_from);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS rubrics;
+DROP TABLE IF EXISTS course_items;
+DROP TABLE IF EXISTS course_sections;
+DROP TABLE IF EXISTS courses;
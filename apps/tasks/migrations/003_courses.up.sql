@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS courses (
+    id UUID PRIMARY KEY,
+    creator_id BIGINT NOT NULL,
+    school_id BIGINT NOT NULL DEFAULT 0,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_courses_creator_id ON courses(creator_id);
+
+CREATE TABLE IF NOT EXISTS course_sections (
+    id UUID PRIMARY KEY,
+    course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    position INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_course_sections_course_id ON course_sections(course_id);
+
+CREATE TABLE IF NOT EXISTS course_items (
+    section_id UUID NOT NULL REFERENCES course_sections(id) ON DELETE CASCADE,
+    template_id UUID NOT NULL REFERENCES assignment_templates(id),
+    position INTEGER NOT NULL,
+
+    PRIMARY KEY (section_id, template_id)
+);
+
+CREATE TABLE IF NOT EXISTS rubrics (
+    id UUID PRIMARY KEY,
+    template_id UUID NOT NULL REFERENCES assignment_templates(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    criteria JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_rubrics_template_id ON rubrics(template_id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_student_assignments_student_id;
+DROP INDEX IF EXISTS idx_student_assignments_assignment_student;
+
+ALTER TABLE student_assignments
+    DROP COLUMN IF EXISTS assignment_id;
+
+DROP TABLE IF EXISTS assignment_targets;
+DROP TABLE IF EXISTS assignments;
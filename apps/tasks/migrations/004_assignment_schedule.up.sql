@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS assignments (
+    id UUID PRIMARY KEY,
+    template_id UUID NOT NULL REFERENCES assignment_templates(id),
+    creator_id BIGINT NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    due_date TIMESTAMP NOT NULL,
+    cutoff_date TIMESTAMP NOT NULL,
+    publish_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_assignments_creator_id ON assignments(creator_id);
+CREATE INDEX IF NOT EXISTS idx_assignments_unpublished ON assignments(publish_at) WHERE published_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS assignment_targets (
+    assignment_id UUID NOT NULL REFERENCES assignments(id) ON DELETE CASCADE,
+    student_id BIGINT NOT NULL,
+
+    PRIMARY KEY (assignment_id, student_id)
+);
+
+-- user ids are issued by sso as integers
+ALTER TABLE student_assignments
+    ALTER COLUMN student_id TYPE BIGINT USING 0;
+
+ALTER TABLE student_assignments
+    ADD COLUMN IF NOT EXISTS assignment_id UUID REFERENCES assignments(id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_student_assignments_assignment_student
+    ON student_assignments(assignment_id, student_id);
+CREATE INDEX IF NOT EXISTS idx_student_assignments_student_id ON student_assignments(student_id);
@@ -0,0 +1,33 @@
+ALTER TABLE feedbacks
+    DROP CONSTRAINT IF EXISTS feedbacks_submission_version_id_fkey,
+    ADD CONSTRAINT feedbacks_submission_version_id_fkey
+        FOREIGN KEY (submission_version_id) REFERENCES submission_versions(id);
+
+ALTER TABLE submission_versions
+    DROP CONSTRAINT IF EXISTS submission_versions_submission_id_fkey,
+    ADD CONSTRAINT submission_versions_submission_id_fkey
+        FOREIGN KEY (submission_id) REFERENCES submissions(id);
+
+ALTER TABLE submissions
+    DROP CONSTRAINT IF EXISTS submissions_current_version_id_fkey,
+    ADD CONSTRAINT submissions_current_version_id_fkey
+        FOREIGN KEY (current_version_id) REFERENCES submission_versions(id);
+
+ALTER TABLE submissions
+    DROP CONSTRAINT IF EXISTS submissions_assignment_id_fkey,
+    ADD CONSTRAINT submissions_assignment_id_fkey
+        FOREIGN KEY (assignment_id) REFERENCES student_assignments(id);
+
+ALTER TABLE student_assignments
+    DROP CONSTRAINT IF EXISTS student_assignments_assignment_id_fkey,
+    ADD CONSTRAINT student_assignments_assignment_id_fkey
+        FOREIGN KEY (assignment_id) REFERENCES assignments(id);
+
+DROP INDEX IF EXISTS idx_submissions_deleted_at;
+DROP INDEX IF EXISTS idx_assignments_deleted_at;
+
+ALTER TABLE submissions
+    DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE assignments
+    DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,34 @@
+ALTER TABLE assignments
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+ALTER TABLE submissions
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_assignments_deleted_at ON assignments(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_submissions_deleted_at ON submissions(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- purge of the trash removes the dependent rows together with the deleted ones
+ALTER TABLE student_assignments
+    DROP CONSTRAINT IF EXISTS student_assignments_assignment_id_fkey,
+    ADD CONSTRAINT student_assignments_assignment_id_fkey
+        FOREIGN KEY (assignment_id) REFERENCES assignments(id) ON DELETE CASCADE;
+
+ALTER TABLE submissions
+    DROP CONSTRAINT IF EXISTS submissions_assignment_id_fkey,
+    ADD CONSTRAINT submissions_assignment_id_fkey
+        FOREIGN KEY (assignment_id) REFERENCES student_assignments(id) ON DELETE CASCADE;
+
+ALTER TABLE submissions
+    DROP CONSTRAINT IF EXISTS submissions_current_version_id_fkey,
+    ADD CONSTRAINT submissions_current_version_id_fkey
+        FOREIGN KEY (current_version_id) REFERENCES submission_versions(id) ON DELETE SET NULL;
+
+ALTER TABLE submission_versions
+    DROP CONSTRAINT IF EXISTS submission_versions_submission_id_fkey,
+    ADD CONSTRAINT submission_versions_submission_id_fkey
+        FOREIGN KEY (submission_id) REFERENCES submissions(id) ON DELETE CASCADE;
+
+ALTER TABLE feedbacks
+    DROP CONSTRAINT IF EXISTS feedbacks_submission_version_id_fkey,
+    ADD CONSTRAINT feedbacks_submission_version_id_fkey
+        FOREIGN KEY (submission_version_id) REFERENCES submission_versions(id) ON DELETE CASCADE;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_submission_versions_number;
+DROP INDEX IF EXISTS idx_submissions_assignment_id;
+
+ALTER TABLE submissions
+    DROP COLUMN IF EXISTS revision;
+
+ALTER TABLE submissions
+    ALTER COLUMN creator_id TYPE UUID USING NULL;
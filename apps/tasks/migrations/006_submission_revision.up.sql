@@ -0,0 +1,12 @@
+-- user ids are issued by sso as integers
+ALTER TABLE submissions
+    ALTER COLUMN creator_id TYPE BIGINT USING 0;
+
+-- revision is increased on every change of the submission and is used
+-- as the etag for optimistic concurrency of autosaves
+ALTER TABLE submissions
+    ADD COLUMN IF NOT EXISTS revision BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_submissions_assignment_id ON submissions(assignment_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_submission_versions_number
+    ON submission_versions(submission_id, version_number);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_attachments_orphaned;
+DROP INDEX IF EXISTS idx_attachments_owner_id;
+DROP INDEX IF EXISTS idx_attachments_submission_id;
+
+DROP TABLE IF EXISTS attachments;
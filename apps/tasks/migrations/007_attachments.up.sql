@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS attachments (
+    id UUID PRIMARY KEY,
+    -- attachments of purged submissions are kept until their blobs are deleted
+    submission_id UUID REFERENCES submissions(id) ON DELETE SET NULL,
+    owner_id BIGINT NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    checksum VARCHAR(64) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_attachments_submission_id ON attachments(submission_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_owner_id ON attachments(owner_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_orphaned ON attachments(created_at) WHERE submission_id IS NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_attachment_previews_pending;
+
+DROP TABLE IF EXISTS attachment_previews;
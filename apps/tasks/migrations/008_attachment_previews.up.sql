@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS attachment_previews (
+    attachment_id UUID PRIMARY KEY REFERENCES attachments(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    thumbnail_key VARCHAR(255),
+    preview_key VARCHAR(255),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    -- the worker holds the preview until the lease expires, so it is retried after a crash
+    locked_until TIMESTAMP,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_attachment_previews_pending
+    ON attachment_previews(created_at) WHERE status IN ('pending', 'processing');
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_similarity_pairs_assignment_id;
+
+DROP TABLE IF EXISTS similarity_pairs;
+
+ALTER TABLE assignments DROP COLUMN IF EXISTS similarity_checked_at;
@@ -0,0 +1,13 @@
+-- assignments with submissions submitted after the check are compared again
+ALTER TABLE assignments ADD COLUMN IF NOT EXISTS similarity_checked_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS similarity_pairs (
+    assignment_id UUID NOT NULL REFERENCES assignments(id) ON DELETE CASCADE,
+    submission_id UUID NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+    other_submission_id UUID NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+    score REAL NOT NULL,
+    fragments JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (submission_id, other_submission_id)
+);
+CREATE INDEX IF NOT EXISTS idx_similarity_pairs_assignment_id ON similarity_pairs(assignment_id, score DESC);
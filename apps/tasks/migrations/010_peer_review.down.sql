@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_peer_reviews_assignment_id;
+DROP INDEX IF EXISTS idx_peer_reviews_reviewer_id;
+
+DROP TABLE IF EXISTS peer_reviews;
+
+DROP INDEX IF EXISTS idx_assignments_peer_review_due;
+
+ALTER TABLE assignments
+    DROP COLUMN IF EXISTS peer_reviews_assigned_at,
+    DROP COLUMN IF EXISTS peer_review_criteria,
+    DROP COLUMN IF EXISTS peer_review_due_date,
+    DROP COLUMN IF EXISTS peer_reviewers_count;
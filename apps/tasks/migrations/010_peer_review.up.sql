@@ -0,0 +1,26 @@
+-- peer review is enabled when peer_reviewers_count is positive,
+-- the reviewers are assigned once the due date has passed
+ALTER TABLE assignments
+    ADD COLUMN IF NOT EXISTS peer_reviewers_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS peer_review_due_date TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS peer_review_criteria JSONB,
+    ADD COLUMN IF NOT EXISTS peer_reviews_assigned_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_assignments_peer_review_due
+    ON assignments(due_date) WHERE peer_reviewers_count > 0 AND peer_reviews_assigned_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS peer_reviews (
+    id UUID PRIMARY KEY,
+    assignment_id UUID NOT NULL REFERENCES assignments(id) ON DELETE CASCADE,
+    submission_id UUID NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+    reviewer_id BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    scores JSONB,
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    submitted_at TIMESTAMP,
+
+    UNIQUE (submission_id, reviewer_id)
+);
+CREATE INDEX IF NOT EXISTS idx_peer_reviews_reviewer_id ON peer_reviews(reviewer_id);
+CREATE INDEX IF NOT EXISTS idx_peer_reviews_assignment_id ON peer_reviews(assignment_id);
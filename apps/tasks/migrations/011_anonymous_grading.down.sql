@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_identity_reveals_assignment_id;
+
+DROP TABLE IF EXISTS identity_reveals;
+
+ALTER TABLE assignments
+    DROP COLUMN IF EXISTS identities_revealed_at,
+    DROP COLUMN IF EXISTS anonymity_key,
+    DROP COLUMN IF EXISTS anonymous;
@@ -0,0 +1,16 @@
+-- students of the anonymous assignment are shown to the teacher as pseudonyms
+-- derived from anonymity_key until the identities are revealed
+ALTER TABLE assignments
+    ADD COLUMN IF NOT EXISTS anonymous BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS anonymity_key BYTEA,
+    ADD COLUMN IF NOT EXISTS identities_revealed_at TIMESTAMP;
+
+-- audit log of the reveals, kept as long as the assignment
+CREATE TABLE IF NOT EXISTS identity_reveals (
+    id UUID PRIMARY KEY,
+    assignment_id UUID NOT NULL REFERENCES assignments(id) ON DELETE CASCADE,
+    teacher_id BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    revealed_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_identity_reveals_assignment_id ON identity_reveals(assignment_id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_comment_revisions_comment_id;
+DROP TABLE IF EXISTS comment_revisions;
+
+DROP INDEX IF EXISTS idx_comments_thread_id;
+DROP TABLE IF EXISTS comments;
+
+DROP INDEX IF EXISTS idx_comment_threads_submission_id;
+DROP TABLE IF EXISTS comment_threads;
@@ -0,0 +1,32 @@
+-- threads of comments on the submission versions,
+-- anchor is the JSON pointer or the image region, NULL for the whole version
+CREATE TABLE IF NOT EXISTS comment_threads (
+    id UUID PRIMARY KEY,
+    submission_id UUID NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+    submission_version_id UUID NOT NULL REFERENCES submission_versions(id) ON DELETE CASCADE,
+    anchor JSONB,
+    resolved_by VARCHAR(20),
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_comment_threads_submission_id ON comment_threads(submission_id);
+
+CREATE TABLE IF NOT EXISTS comments (
+    id UUID PRIMARY KEY,
+    thread_id UUID NOT NULL REFERENCES comment_threads(id) ON DELETE CASCADE,
+    author_id BIGINT NOT NULL,
+    author_role VARCHAR(20) NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    edited_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_comments_thread_id ON comments(thread_id);
+
+-- previous bodies of the edited comments
+CREATE TABLE IF NOT EXISTS comment_revisions (
+    id UUID PRIMARY KEY,
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_comment_revisions_comment_id ON comment_revisions(comment_id);
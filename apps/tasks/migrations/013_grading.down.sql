@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS idx_submission_events_submission_id;
+DROP TABLE IF EXISTS submission_events;
+
+DROP INDEX IF EXISTS idx_regrade_requests_teacher_open;
+DROP INDEX IF EXISTS idx_regrade_requests_open;
+DROP TABLE IF EXISTS regrade_requests;
+
+DROP INDEX IF EXISTS idx_feedbacks_draft;
+
+ALTER TABLE feedbacks
+    DROP COLUMN IF EXISTS published_at,
+    DROP COLUMN IF EXISTS score;
+
+ALTER TABLE feedbacks
+    ALTER COLUMN grader_id TYPE UUID USING NULL;
@@ -0,0 +1,45 @@
+-- user ids are issued by sso as integers
+ALTER TABLE feedbacks
+    ALTER COLUMN grader_id TYPE BIGINT USING 0;
+
+ALTER TABLE feedbacks
+    ADD COLUMN IF NOT EXISTS score DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS published_at TIMESTAMP;
+
+-- the teacher keeps at most one draft feedback per version
+CREATE UNIQUE INDEX IF NOT EXISTS idx_feedbacks_draft
+    ON feedbacks(submission_version_id) WHERE NOT is_published;
+
+CREATE TABLE IF NOT EXISTS regrade_requests (
+    id UUID PRIMARY KEY,
+    submission_id UUID NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+    student_id BIGINT NOT NULL,
+    teacher_id BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    resolution TEXT NOT NULL DEFAULT '',
+    old_score DOUBLE PRECISION,
+    new_score DOUBLE PRECISION,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP
+);
+-- the student can have only one open request per submission
+CREATE UNIQUE INDEX IF NOT EXISTS idx_regrade_requests_open
+    ON regrade_requests(submission_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_regrade_requests_teacher_open
+    ON regrade_requests(teacher_id, created_at) WHERE status = 'open';
+
+-- history of the grading of the submission
+CREATE TABLE IF NOT EXISTS submission_events (
+    id UUID PRIMARY KEY,
+    submission_id UUID NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+    kind VARCHAR(40) NOT NULL,
+    actor_id BIGINT NOT NULL,
+    status VARCHAR(60),
+    score DOUBLE PRECISION,
+    comment TEXT NOT NULL DEFAULT '',
+    regrade_id UUID,
+    created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_submission_events_submission_id
+    ON submission_events(submission_id, created_at);
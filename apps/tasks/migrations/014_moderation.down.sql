@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS moderation_items;
+
+DROP INDEX IF EXISTS idx_moderations_moderator_pending;
+DROP TABLE IF EXISTS moderations;
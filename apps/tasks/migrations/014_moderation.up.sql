@@ -0,0 +1,24 @@
+-- second marking of the sample of the graded submissions of the assignment
+CREATE TABLE IF NOT EXISTS moderations (
+    assignment_id UUID PRIMARY KEY REFERENCES assignments(id) ON DELETE CASCADE,
+    requested_by BIGINT NOT NULL,
+    moderator_id BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_moderations_moderator_pending
+    ON moderations(moderator_id, created_at) WHERE completed_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS moderation_items (
+    id UUID PRIMARY KEY,
+    assignment_id UUID NOT NULL REFERENCES moderations(assignment_id) ON DELETE CASCADE,
+    submission_id UUID NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+    -- the draft feedback of the teacher on the moderated version
+    feedback_id UUID NOT NULL REFERENCES feedbacks(id) ON DELETE CASCADE,
+    original_score DOUBLE PRECISION,
+    decision VARCHAR(20) NOT NULL DEFAULT 'pending',
+    adjusted_score DOUBLE PRECISION,
+    comment TEXT NOT NULL DEFAULT '',
+    moderated_at TIMESTAMP,
+    UNIQUE (assignment_id, submission_id)
+);
@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS idx_assignments_search_vector;
+DROP INDEX IF EXISTS idx_assignment_templates_search_vector;
+
+DROP TRIGGER IF EXISTS assignments_search_vector ON assignments;
+DROP FUNCTION IF EXISTS assignments_search_vector();
+
+DROP TRIGGER IF EXISTS assignment_templates_search_vector ON assignment_templates;
+DROP FUNCTION IF EXISTS assignment_templates_search_vector();
+
+DROP FUNCTION IF EXISTS build_search_vector(TEXT, TEXT, JSONB);
+
+ALTER TABLE assignments
+    DROP COLUMN IF EXISTS search_vector;
+
+ALTER TABLE assignment_templates
+    DROP COLUMN IF EXISTS search_vector;
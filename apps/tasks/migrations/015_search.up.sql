@@ -0,0 +1,68 @@
+-- full-text search over the library of templates and the assignments.
+-- Every text is indexed with both the russian and the english stemming,
+-- so the query in either language finds the word forms of the other words.
+ALTER TABLE assignment_templates
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+ALTER TABLE assignments
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+-- the title weighs more than the widget type, and the type more than the texts of the config
+CREATE OR REPLACE FUNCTION build_search_vector(title TEXT, widget_type TEXT, config JSONB)
+RETURNS TSVECTOR AS $$
+    SELECT
+        setweight(to_tsvector('russian', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('russian', replace(COALESCE(widget_type, ''), '_', ' ')), 'B') ||
+        setweight(to_tsvector('english', replace(COALESCE(widget_type, ''), '_', ' ')), 'B') ||
+        setweight(jsonb_to_tsvector('russian', COALESCE(config, '{}'), '["string"]'), 'C') ||
+        setweight(jsonb_to_tsvector('english', COALESCE(config, '{}'), '["string"]'), 'C')
+$$ LANGUAGE SQL STABLE;
+
+CREATE OR REPLACE FUNCTION assignment_templates_search_vector() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector := build_search_vector(
+        NEW.title,
+        (SELECT type FROM widgets WHERE id = NEW.widget_id),
+        NEW.widget_config
+    );
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER assignment_templates_search_vector
+    BEFORE INSERT OR UPDATE OF title, widget_id, widget_config ON assignment_templates
+    FOR EACH ROW EXECUTE FUNCTION assignment_templates_search_vector();
+
+-- the assignment is found by its own title and the widget of its template
+CREATE OR REPLACE FUNCTION assignments_search_vector() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector := (
+        SELECT build_search_vector(NEW.title, w.type, t.widget_config)
+        FROM assignment_templates t
+        INNER JOIN widgets w ON w.id = t.widget_id
+        WHERE t.id = NEW.template_id
+    );
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER assignments_search_vector
+    BEFORE INSERT OR UPDATE OF title, template_id ON assignments
+    FOR EACH ROW EXECUTE FUNCTION assignments_search_vector();
+
+UPDATE assignment_templates t
+SET search_vector = build_search_vector(t.title, w.type, t.widget_config)
+FROM widgets w
+WHERE w.id = t.widget_id;
+
+UPDATE assignments a
+SET search_vector = build_search_vector(a.title, w.type, t.widget_config)
+FROM assignment_templates t
+INNER JOIN widgets w ON w.id = t.widget_id
+WHERE t.id = a.template_id;
+
+CREATE INDEX IF NOT EXISTS idx_assignment_templates_search_vector
+    ON assignment_templates USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_assignments_search_vector
+    ON assignments USING GIN (search_vector);
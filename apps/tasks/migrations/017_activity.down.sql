@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_student_assignments_cutoff_date;
+DROP INDEX IF EXISTS idx_student_assignments_due_date;
+DROP TABLE IF EXISTS submission_activity;
@@ -0,0 +1,24 @@
+-- live activity of the students on the assignments, watched by the teacher;
+-- the id is the position in the stream the watcher resumes from
+CREATE TABLE IF NOT EXISTS submission_activity (
+    id BIGSERIAL PRIMARY KEY,
+    assignment_id UUID NOT NULL REFERENCES assignments(id) ON DELETE CASCADE,
+    student_assignment_id UUID NOT NULL,
+    submission_id UUID,
+    student_id BIGINT NOT NULL,
+    kind VARCHAR(40) NOT NULL,
+    revision BIGINT NOT NULL DEFAULT 0,
+    is_late BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_submission_activity_assignment_id
+    ON submission_activity(assignment_id, id);
+CREATE INDEX IF NOT EXISTS idx_submission_activity_created_at ON submission_activity(created_at);
+-- every time limit of the student assignment is recorded once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_submission_activity_time_limit
+    ON submission_activity(student_assignment_id, kind)
+    WHERE kind IN ('due_date_passed', 'cutoff_passed');
+
+-- the time limits passed recently are looked up by the scheduler
+CREATE INDEX IF NOT EXISTS idx_student_assignments_due_date ON student_assignments(due_date);
+CREATE INDEX IF NOT EXISTS idx_student_assignments_cutoff_date ON student_assignments(cutoff_date);
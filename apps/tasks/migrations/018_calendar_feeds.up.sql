@@ -0,0 +1,7 @@
+-- secret feeds of the due dates of the users for the calendar apps
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    user_id BIGINT PRIMARY KEY,
+    -- SHA-256 of the token in the url of the feed, the token itself is not kept
+    token_hash BYTEA NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,3 @@
+-- the sso ids are kept in the uuids, so the up migration restores them
+ALTER TABLE assignment_templates
+    ALTER COLUMN creator_id TYPE UUID USING lpad(to_hex(creator_id), 32, '0')::UUID;
@@ -0,0 +1,20 @@
+-- The creators of the templates are identified by the ids issued by sso,
+-- which are integers. The uuids are converted only if they encode such an id,
+-- as the down migration does: the other ones have no matching user, so the
+-- migration fails and they have to be mapped to the sso ids by hand.
+-- The column that is already an integer is left as it is.
+DO $$
+BEGIN
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'assignment_templates' AND column_name = 'creator_id') <> 'uuid'
+    THEN
+        RETURN;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM assignment_templates WHERE creator_id::TEXT NOT LIKE '00000000-0000-0000-%') THEN
+        RAISE EXCEPTION 'creators of templates are not sso ids, map them to the sso user ids first';
+    END IF;
+
+    ALTER TABLE assignment_templates
+        ALTER COLUMN creator_id TYPE BIGINT USING ('x' || right(replace(creator_id::TEXT, '-', ''), 16))::BIT(64)::BIGINT;
+END $$;
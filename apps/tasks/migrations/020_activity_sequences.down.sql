@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_submission_activity_seq;
+CREATE INDEX IF NOT EXISTS idx_submission_activity_assignment_id
+    ON submission_activity(assignment_id, id);
+ALTER TABLE submission_activity DROP COLUMN IF EXISTS seq;
+DROP TABLE IF EXISTS activity_sequences;
@@ -0,0 +1,21 @@
+-- the last number taken in the sequence of the activities of the assignment;
+-- the row is locked until the writer commits, so the numbers are committed in order
+CREATE TABLE IF NOT EXISTS activity_sequences (
+    assignment_id UUID PRIMARY KEY REFERENCES assignments(id) ON DELETE CASCADE,
+    seq BIGINT NOT NULL
+);
+
+-- the number the watcher resumes from, the ids recorded so far are kept as the numbers
+ALTER TABLE submission_activity ADD COLUMN IF NOT EXISTS seq BIGINT;
+UPDATE submission_activity SET seq = id WHERE seq IS NULL;
+ALTER TABLE submission_activity ALTER COLUMN seq SET NOT NULL;
+
+INSERT INTO activity_sequences (assignment_id, seq)
+SELECT assignment_id, MAX(seq)
+FROM submission_activity
+GROUP BY assignment_id
+ON CONFLICT (assignment_id) DO NOTHING;
+
+DROP INDEX IF EXISTS idx_submission_activity_assignment_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_submission_activity_seq
+    ON submission_activity(assignment_id, seq);
@@ -0,0 +1 @@
+ALTER TABLE student_assignments DROP COLUMN IF EXISTS extended_at;
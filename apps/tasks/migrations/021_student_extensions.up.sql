@@ -0,0 +1,3 @@
+-- set when the teacher moves the dates of one student,
+-- the later updates of the assignment dates do not bring them forward
+ALTER TABLE student_assignments ADD COLUMN IF NOT EXISTS extended_at TIMESTAMP;
@@ -0,0 +1,28 @@
+DROP INDEX IF EXISTS idx_submission_activity_unnumbered;
+
+INSERT INTO activity_sequences (assignment_id, seq)
+SELECT DISTINCT assignment_id, 0
+FROM submission_activity
+WHERE seq IS NULL
+ON CONFLICT (assignment_id) DO NOTHING;
+
+UPDATE submission_activity a
+SET seq = p.seq
+FROM (
+    SELECT u.id, s.seq + ROW_NUMBER() OVER (PARTITION BY u.assignment_id ORDER BY u.id) AS seq
+    FROM submission_activity u
+    INNER JOIN activity_sequences s ON s.assignment_id = u.assignment_id
+    WHERE u.seq IS NULL
+) p
+WHERE a.id = p.id;
+
+UPDATE activity_sequences s
+SET seq = m.seq
+FROM (
+    SELECT assignment_id, MAX(seq) AS seq
+    FROM submission_activity
+    GROUP BY assignment_id
+) m
+WHERE m.assignment_id = s.assignment_id;
+
+ALTER TABLE submission_activity ALTER COLUMN seq SET NOT NULL;
@@ -0,0 +1,7 @@
+-- the activity is inserted without the number, which is given by the reader
+-- once the activity is committed, so the writers never wait for the sequence
+ALTER TABLE submission_activity ALTER COLUMN seq DROP NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_submission_activity_unnumbered
+    ON submission_activity(assignment_id, id)
+    WHERE seq IS NULL;
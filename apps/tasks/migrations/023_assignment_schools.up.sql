@@ -0,0 +1,9 @@
+-- the school the assignment is created in, the events of the assignment
+-- are posted only to the webhooks of its app; zero is no school
+ALTER TABLE assignments ADD COLUMN IF NOT EXISTS school_id BIGINT NOT NULL DEFAULT 0;
+
+-- the assignments created before are taken to be of the school of their template
+UPDATE assignments a
+SET school_id = t.school_id
+FROM assignment_templates t
+WHERE t.id = a.template_id AND a.school_id = 0;
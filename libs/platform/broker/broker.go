@@ -0,0 +1,15 @@
+// Package broker describes the events published to the message broker
+// by the services of the platform.
+package broker
+
+// Message is the event published to the message broker.
+// It is implemented by the models of the events of every service.
+type Message interface {
+	// MessageID returns the id of the event, the broker drops
+	// the event published twice under the same id.
+	MessageID() string
+	// Subject returns the subject the event is published under.
+	Subject() string
+	// Data returns the serialized event.
+	Data() []byte
+}
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Kaptoshka/creative-learning-platform/libs/platform/broker"
+)
+
+// Handler handles the event delivered by the broker.
+type Handler[E broker.Message] func(ctx context.Context, event E) error
+
+type subscription[E broker.Message] struct {
+	subject string
+	handler Handler[E]
+}
+
+// Broker delivers the events to the subscribers in the same process.
+// It is used to run the service without the message broker and in the tests.
+type Broker[E broker.Message] struct {
+	mu            sync.RWMutex
+	subscriptions map[int]subscription[E]
+	nextID        int
+}
+
+// New creates a new Broker instance of the events of the given model.
+func New[E broker.Message]() *Broker[E] {
+	return &Broker[E]{
+		subscriptions: make(map[int]subscription[E]),
+	}
+}
+
+// Subscribe registers the handler of the events whose subject matches the given one.
+// The subject can contain the wildcards of NATS: "*" matches a single token
+// and ">" matches the rest of the subject.
+// It returns the function which cancels the subscription.
+func (b *Broker[E]) Subscribe(subject string, handler Handler[E]) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+
+	b.subscriptions[id] = subscription[E]{
+		subject: subject,
+		handler: handler,
+	}
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscriptions, id)
+	}
+}
+
+// Publish delivers the event under its subject to the matching subscribers
+// synchronously. If a handler fails, the error is returned, so the event is published
+// again as if the broker had not acknowledged it.
+func (b *Broker[E]) Publish(ctx context.Context, event E) error {
+	const op = "broker.memory.Publish"
+
+	b.mu.RLock()
+	var handlers []Handler[E]
+	for _, s := range b.subscriptions {
+		if matches(s.subject, event.Subject()) {
+			handlers = append(handlers, s.handler)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	return nil
+}
+
+// Close does nothing, there is no connection to close.
+func (b *Broker[E]) Close() error {
+	return nil
+}
+
+// matches reports whether the subject matches the pattern with the wildcards.
+func matches(pattern string, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+
+	for i, token := range patternTokens {
+		if token == ">" {
+			return i < len(subjectTokens)
+		}
+
+		if i >= len(subjectTokens) || (token != "*" && token != subjectTokens[i]) {
+			return false
+		}
+	}
+
+	return len(patternTokens) == len(subjectTokens)
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// event is the model of the events published in the tests.
+type event struct {
+	id      string
+	subject string
+}
+
+func (e event) MessageID() string { return e.id }
+func (e event) Subject() string   { return e.subject }
+func (e event) Data() []byte      { return nil }
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		subject string
+		want    bool
+	}{
+		{"tasks.events.v1.AssignmentCreated", "tasks.events.v1.AssignmentCreated", true},
+		{"tasks.events.v1.AssignmentCreated", "tasks.events.v1.AssignmentDeleted", false},
+		{"tasks.events.>", "tasks.events.v1.AssignmentCreated", true},
+		{"tasks.events.>", "tasks.events", false},
+		{"tasks.*.v1.AssignmentCreated", "tasks.events.v1.AssignmentCreated", true},
+		{"tasks.*", "tasks.events.v1", false},
+		{"tasks.events.v1", "tasks.events", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.subject, func(t *testing.T) {
+			assert.Equal(t, tt.want, matches(tt.pattern, tt.subject))
+		})
+	}
+}
+
+func TestBroker(t *testing.T) {
+	ctx := context.Background()
+	b := New[event]()
+
+	var all, created []string
+	b.Subscribe("tasks.events.>", func(_ context.Context, e event) error {
+		all = append(all, e.id)
+
+		return nil
+	})
+	unsubscribe := b.Subscribe("tasks.events.v1.AssignmentCreated", func(_ context.Context, e event) error {
+		created = append(created, e.id)
+
+		return nil
+	})
+
+	require.NoError(t, b.Publish(ctx, event{id: "1", subject: "tasks.events.v1.AssignmentCreated"}))
+	require.NoError(t, b.Publish(ctx, event{id: "2", subject: "tasks.events.v1.AssignmentDeleted"}))
+
+	unsubscribe()
+
+	require.NoError(t, b.Publish(ctx, event{id: "3", subject: "tasks.events.v1.AssignmentCreated"}))
+
+	assert.Equal(t, []string{"1", "2", "3"}, all)
+	assert.Equal(t, []string{"1"}, created)
+}
+
+func TestBrokerHandlerError(t *testing.T) {
+	b := New[event]()
+
+	errHandler := errors.New("handler failed")
+	b.Subscribe(">", func(context.Context, event) error {
+		return errHandler
+	})
+
+	err := b.Publish(context.Background(), event{id: "1", subject: "tasks.events.v1.AssignmentCreated"})
+	assert.ErrorContains(t, err, errHandler.Error())
+}
@@ -0,0 +1,224 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	// ackWait is how long the server waits for the acknowledgement
+	// before the message is delivered again.
+	ackWait = time.Minute
+	// minRetryDelay and maxRetryDelay bound the delay before the failed message
+	// is delivered again, which is doubled on every delivery.
+	minRetryDelay = time.Second
+	maxRetryDelay = 5 * time.Minute
+	// maxDeliver is the number of deliveries of the message after which
+	// it is parked instead, about an hour and a half with the backoff.
+	maxDeliver = 20
+	// parkTimeout bounds the time to park the message.
+	parkTimeout = 10 * time.Second
+)
+
+// Handler handles the data of the message.
+// If it returns an error, the message is delivered again.
+type Handler func(ctx context.Context, data []byte) error
+
+// Parker keeps the messages which have failed every delivery,
+// so they can be inspected and handled again by hand.
+type Parker interface {
+	ParkEvent(
+		ctx context.Context,
+		consumer string,
+		subject string,
+		data []byte,
+		reason string,
+		parkedAt time.Time,
+	) error
+}
+
+// Consumer consumes the messages of the JetStream stream by the durable consumer,
+// so the messages published while the service was down are consumed on start.
+type Consumer struct {
+	log      *slog.Logger
+	conn     *natsgo.Conn
+	durable  string
+	parker   Parker
+	consumer jetstream.Consumer
+	consume  jetstream.ConsumeContext
+	cancel   context.CancelFunc
+}
+
+// NewConsumer creates a new Consumer instance.
+// That used to consume the messages of the stream on the NATS server at the url
+// matching the subjects. The durable consumer is created if missing.
+// The message which has failed maxDeliver deliveries is given to the parker.
+func NewConsumer(
+	ctx context.Context,
+	log *slog.Logger,
+	url string,
+	stream string,
+	durable string,
+	subjects string,
+	parker Parker,
+) (*Consumer, error) {
+	const op = "broker.nats.NewConsumer"
+
+	conn, err := natsgo.Connect(url, natsgo.Name(durable))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	consumer, err := js.CreateOrUpdateConsumer(ctx, stream, jetstream.ConsumerConfig{
+		Durable:       durable,
+		FilterSubject: subjects,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       ackWait,
+		MaxDeliver:    maxDeliver,
+	})
+	if err != nil {
+		conn.Close()
+
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return &Consumer{
+		log:      log,
+		conn:     conn,
+		durable:  durable,
+		parker:   parker,
+		consumer: consumer,
+	}, nil
+}
+
+// Run starts handling the messages in the background.
+// The message is acknowledged once the handler succeeds,
+// otherwise it is delivered again with the exponential backoff
+// until the last delivery, when the message is parked.
+func (c *Consumer) Run(handler Handler) error {
+	const op = "broker.nats.Run"
+
+	log := c.log.With(
+		slog.String("op", op),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	consume, err := c.consumer.Consume(func(msg jetstream.Msg) {
+		if err := handler(ctx, msg.Data()); err != nil {
+			var delivered uint64
+			if meta, metaErr := msg.Metadata(); metaErr == nil {
+				delivered = meta.NumDelivered
+			}
+
+			log.Warn(
+				"failed to handle message",
+				slog.String("subject", msg.Subject()),
+				slog.Uint64("delivered", delivered),
+				slog.Any("error", err),
+			)
+
+			if delivered >= maxDeliver {
+				c.park(msg, err)
+
+				return
+			}
+
+			if err := msg.NakWithDelay(retryDelay(delivered)); err != nil {
+				log.Error("failed to nak message", slog.Any("error", err))
+			}
+
+			return
+		}
+
+		if err := msg.Ack(); err != nil {
+			log.Error("failed to ack message", slog.Any("error", err))
+		}
+	})
+	if err != nil {
+		cancel()
+
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	c.consume = consume
+
+	log.Info("consumer is running")
+
+	return nil
+}
+
+// park keeps the message which has failed the last delivery and terminates it,
+// so the server does not deliver it again. If the message cannot be parked,
+// it is left in the stream and can be found by the sequence in the log.
+func (c *Consumer) park(msg jetstream.Msg, reason error) {
+	const op = "broker.nats.park"
+
+	log := c.log.With(
+		slog.String("op", op),
+		slog.String("subject", msg.Subject()),
+	)
+
+	if meta, err := msg.Metadata(); err == nil {
+		log = log.With(slog.Uint64("stream_seq", meta.Sequence.Stream))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), parkTimeout)
+	defer cancel()
+
+	err := c.parker.ParkEvent(ctx, c.durable, msg.Subject(), msg.Data(), reason.Error(), time.Now().UTC())
+	if err != nil {
+		log.Error("failed to park message", slog.Any("error", err))
+	} else {
+		log.Error("message parked after the last delivery", slog.Any("error", reason))
+	}
+
+	if err := msg.Term(); err != nil {
+		log.Error("failed to terminate message", slog.Any("error", err))
+	}
+}
+
+// Stop stops handling the messages, waits for the message being handled
+// and closes the connection to the NATS server.
+func (c *Consumer) Stop() {
+	const op = "broker.nats.Stop"
+
+	c.log.With(
+		slog.String("op", op),
+	).Info("stopping consumer")
+
+	if c.consume != nil {
+		c.consume.Drain()
+		<-c.consume.Closed()
+	}
+
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	c.conn.Close()
+}
+
+// retryDelay returns the delay before the message is delivered again
+// after the given number of deliveries.
+func retryDelay(delivered uint64) time.Duration {
+	delay := minRetryDelay
+	for i := uint64(1); i < delivered && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRetryDelay)
+}
@@ -0,0 +1,101 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Kaptoshka/creative-learning-platform/libs/platform/broker"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// duplicateWindow is how long the stream remembers the ids of the events
+// to drop the ones published again by the relay after a failed acknowledgement.
+const duplicateWindow = 10 * time.Minute
+
+// Stream is the JetStream stream keeping the events of the service.
+type Stream struct {
+	// Name is the name of the stream, e.g. "TASKS_EVENTS".
+	Name string
+	// Subjects matches the subjects of every event of the service, e.g. "tasks.events.>".
+	Subjects string
+	// Retention is how long the events are kept in the stream.
+	Retention time.Duration
+}
+
+// Broker publishes the events to the JetStream stream of the NATS server.
+type Broker[E broker.Message] struct {
+	conn *natsgo.Conn
+	js   jetstream.JetStream
+}
+
+// New creates a new Broker instance.
+// That used to publish the events of the service with the given name
+// to the NATS server at the url. The stream of the events is created if missing.
+func New[E broker.Message](
+	ctx context.Context,
+	url string,
+	name string,
+	stream Stream,
+) (*Broker[E], error) {
+	const op = "broker.nats.New"
+
+	conn, err := natsgo.Connect(url, natsgo.Name(name))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:       stream.Name,
+		Subjects:   []string{stream.Subjects},
+		MaxAge:     stream.Retention,
+		Duplicates: duplicateWindow,
+	})
+	if err != nil {
+		conn.Close()
+
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return &Broker[E]{
+		conn: conn,
+		js:   js,
+	}, nil
+}
+
+// Publish publishes the event under its subject and waits
+// for the acknowledgement of the stream. The id of the event is sent
+// as the message id, so the stream drops the event published twice.
+func (b *Broker[E]) Publish(ctx context.Context, event E) error {
+	const op = "broker.nats.Publish"
+
+	msg := natsgo.NewMsg(event.Subject())
+	msg.Data = event.Data()
+	msg.Header.Set(jetstream.MsgIDHeader, event.MessageID())
+
+	if _, err := b.js.PublishMsg(ctx, msg); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// Close drains the connection to the NATS server.
+func (b *Broker[E]) Close() error {
+	const op = "broker.nats.Close"
+
+	if err := b.conn.Drain(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,3 @@
+module github.com/Kaptoshka/creative-learning-platform/libs/platform
+
+go 1.25.4
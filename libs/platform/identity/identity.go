@@ -0,0 +1,114 @@
+// Package identity passes the caller identified by the api-gateway
+// to the services of the platform in the metadata of the gRPC calls.
+package identity
+
+import (
+	"context"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// The metadata keys of the identity of the caller.
+// They are set by the api-gateway after it has verified the access token,
+// so the services trust them and do not parse the token themselves.
+const (
+	MetadataUserID = "x-user-id"
+	MetadataRole   = "x-user-role"
+	MetadataAppID  = "x-app-id"
+)
+
+// Identity is the caller of the RPC.
+type Identity struct {
+	UserID int64
+	Role   string
+	// AppID is the app the user has signed in to, 0 if it is unknown.
+	AppID int32
+}
+
+// WithFunc puts the identity into the context the way the service keeps it.
+type WithFunc func(ctx context.Context, identity Identity) context.Context
+
+// FromMetadata returns the caller identified by the incoming metadata.
+// If there is no valid user id in the metadata, it returns false
+// and the caller stays unauthenticated. The malformed app id is left unknown.
+func FromMetadata(ctx context.Context) (Identity, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return Identity{}, false
+	}
+
+	ids := md.Get(MetadataUserID)
+	if len(ids) != 1 {
+		return Identity{}, false
+	}
+
+	userID, err := strconv.ParseInt(ids[0], 10, 64)
+	if err != nil || userID <= 0 {
+		return Identity{}, false
+	}
+
+	identity := Identity{UserID: userID}
+
+	if roles := md.Get(MetadataRole); len(roles) == 1 {
+		identity.Role = roles[0]
+	}
+
+	if apps := md.Get(MetadataAppID); len(apps) == 1 {
+		appID, err := strconv.ParseInt(apps[0], 10, 32)
+		if err == nil && appID > 0 {
+			identity.AppID = int32(appID)
+		}
+	}
+
+	return identity, true
+}
+
+// UnaryServerInterceptor identifies the caller of the unary RPC by the metadata.
+func UnaryServerInterceptor(with WithFunc) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		_ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		return handler(identify(ctx, with), req)
+	}
+}
+
+// StreamServerInterceptor identifies the caller of the streaming RPC by the metadata.
+func StreamServerInterceptor(with WithFunc) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		_ *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, &serverStream{
+			ServerStream: ss,
+			ctx:          identify(ss.Context(), with),
+		})
+	}
+}
+
+// identify returns the context with the caller identified by the metadata,
+// or the context unchanged if the caller is unknown.
+func identify(ctx context.Context, with WithFunc) context.Context {
+	identity, ok := FromMetadata(ctx)
+	if !ok {
+		return ctx
+	}
+
+	return with(ctx, identity)
+}
+
+// serverStream is the server stream with the context of the identified caller.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
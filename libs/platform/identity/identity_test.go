@@ -0,0 +1,87 @@
+package identity
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestFromMetadata(t *testing.T) {
+	t.Run("identified", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+			MetadataUserID, "42",
+			MetadataRole, "teacher",
+			MetadataAppID, "3",
+		))
+
+		identity, ok := FromMetadata(ctx)
+		require.True(t, ok)
+		assert.Equal(t, Identity{UserID: 42, Role: "teacher", AppID: 3}, identity)
+	})
+
+	t.Run("malformed app id", func(t *testing.T) {
+		for _, appID := range []string{"abc", "0", "-1", "4294967296"} {
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+				MetadataUserID, "42",
+				MetadataAppID, appID,
+			))
+
+			identity, ok := FromMetadata(ctx)
+			require.True(t, ok)
+			assert.Zero(t, identity.AppID, appID)
+		}
+	})
+
+	tests := []struct {
+		name string
+		md   metadata.MD
+	}{
+		{"no metadata", nil},
+		{"no user id", metadata.Pairs(MetadataRole, "admin")},
+		{"malformed user id", metadata.Pairs(MetadataUserID, "abc", MetadataRole, "admin")},
+		{"ambiguous user id", metadata.Pairs(MetadataUserID, "1", MetadataUserID, "2")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+
+			_, ok := FromMetadata(ctx)
+			assert.False(t, ok)
+		})
+	}
+}
+
+type contextKey struct{}
+
+func withIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(withIdentity)
+
+	call := func(ctx context.Context) (Identity, bool) {
+		res, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+			return ctx.Value(contextKey{}), nil
+		})
+		require.NoError(t, err)
+
+		identity, ok := res.(Identity)
+		return identity, ok
+	}
+
+	identity, ok := call(metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataUserID, "7")))
+	require.True(t, ok)
+	assert.Equal(t, int64(7), identity.UserID)
+
+	_, ok = call(context.Background())
+	assert.False(t, ok, "unknown caller is not put into the context")
+}
@@ -0,0 +1,207 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Kaptoshka/creative-learning-platform/libs/platform/broker"
+)
+
+const (
+	// relayBatchSize is the number of events claimed by the relay at once.
+	relayBatchSize = 100
+	// relayLease is the time the claimed event is held by the relay.
+	relayLease = time.Minute
+	// purgeBatchSize limits the number of published events deleted at once.
+	purgeBatchSize = 1000
+
+	// minRetryDelay and maxRetryDelay bound the delay before the next attempt
+	// to publish the failed event, which is doubled on every attempt.
+	minRetryDelay = time.Second
+	maxRetryDelay = 10 * time.Minute
+)
+
+// Event is the domain event waiting in the outbox of the service to be published.
+// It is implemented by the outbox model of every service.
+type Event interface {
+	broker.Message
+	// AggregateKey returns the id of the aggregate the event is about,
+	// the events of the aggregate are published in order.
+	AggregateKey() string
+	// FailedAttempts returns the number of the failed attempts to publish the event.
+	FailedAttempts() int
+}
+
+type OutboxService[E Event] struct {
+	log           *slog.Logger
+	eventSaver    EventSaver
+	eventProvider EventProvider[E]
+	publisher     Publisher[E]
+}
+
+type EventSaver interface {
+	MarkPublished(ctx context.Context, eventID string, publishedAt time.Time) error
+	MarkFailed(ctx context.Context, eventID string, nextAttemptAt time.Time, reason string) error
+	PurgePublished(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+type EventProvider[E Event] interface {
+	ClaimEvents(
+		ctx context.Context,
+		now time.Time,
+		lockedUntil time.Time,
+		limit int,
+	) ([]E, error)
+}
+
+// Publisher delivers the events to the message broker.
+type Publisher[E Event] interface {
+	// Publish delivers the event under its subject.
+	// The broker may get the same event more than once,
+	// the id of the event is used to drop the duplicates.
+	Publish(ctx context.Context, event E) error
+}
+
+// New returns a new instance of OutboxService of the events of the given model.
+func New[E Event](
+	log *slog.Logger,
+	eventSaver EventSaver,
+	eventProvider EventProvider[E],
+	publisher Publisher[E],
+) *OutboxService[E] {
+	return &OutboxService[E]{
+		log:           log,
+		eventSaver:    eventSaver,
+		eventProvider: eventProvider,
+		publisher:     publisher,
+	}
+}
+
+// Relay publishes the events written to the outbox to the broker.
+// It is run periodically by the scheduler as the background worker.
+//
+// The event is marked as published only after the broker has accepted it,
+// so every event is delivered at least once. The failed event is retried
+// with the exponential backoff, and the later events of its aggregate
+// wait for it, so the events of the aggregate are delivered in order.
+func (s *OutboxService[E]) Relay(ctx context.Context) error {
+	const op = "outbox.Relay"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	for {
+		now := time.Now().UTC()
+
+		events, err := s.eventProvider.ClaimEvents(ctx, now, now.Add(relayLease), relayBatchSize)
+		if err != nil {
+			log.Error("failed to claim events", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		failed := make(map[string]bool)
+		for _, event := range events {
+			if failed[event.AggregateKey()] {
+				continue
+			}
+
+			if err := s.publish(ctx, event); err != nil {
+				if ctx.Err() != nil {
+					return fmt.Errorf("%s: %w", op, ctx.Err())
+				}
+
+				failed[event.AggregateKey()] = true
+			}
+		}
+
+		if len(events) < relayBatchSize {
+			return nil
+		}
+	}
+}
+
+// publish delivers the event to the broker and records the outcome.
+// If the broker rejects the event, the next attempt is postponed.
+func (s *OutboxService[E]) publish(ctx context.Context, event E) error {
+	const op = "outbox.publish"
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("event_id", event.MessageID()),
+		slog.String("type", event.Subject()),
+	)
+
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		delay := retryDelay(event.FailedAttempts())
+
+		log.Warn(
+			"failed to publish event",
+			slog.Any("error", err),
+			slog.Int("attempts", event.FailedAttempts()),
+			slog.Duration("retry_in", delay),
+		)
+
+		if err := s.eventSaver.MarkFailed(ctx, event.MessageID(), time.Now().UTC().Add(delay), err.Error()); err != nil {
+			log.Error("failed to mark event as failed", slog.Any("error", err))
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.eventSaver.MarkPublished(ctx, event.MessageID(), time.Now().UTC()); err != nil {
+		log.Error("failed to mark event as published", slog.Any("error", err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// PurgePublished permanently deletes the events published longer than retention ago.
+// It is run periodically by the scheduler.
+func (s *OutboxService[E]) PurgePublished(ctx context.Context, retention time.Duration) error {
+	const op = "outbox.PurgePublished"
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	before := time.Now().UTC().Add(-retention)
+
+	var total int64
+	for {
+		purged, err := s.eventSaver.PurgePublished(ctx, before, purgeBatchSize)
+		if err != nil {
+			log.Error("failed to purge events", slog.Any("error", err))
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		total += purged
+
+		if purged < purgeBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Info("published events purged", slog.Int64("count", total))
+	}
+
+	return nil
+}
+
+// retryDelay returns the delay before the next attempt to publish the event
+// which has failed the given number of attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := minRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRetryDelay)
+}
@@ -0,0 +1,102 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// event is the model of the events written to the outbox in the tests.
+type event struct {
+	id        string
+	aggregate string
+	attempts  int
+}
+
+func (e event) MessageID() string    { return e.id }
+func (e event) Subject() string      { return "tests.events.v1.Event" }
+func (e event) Data() []byte         { return nil }
+func (e event) AggregateKey() string { return e.aggregate }
+func (e event) FailedAttempts() int  { return e.attempts }
+
+// fakeOutbox hands out the events once and records their outcome.
+type fakeOutbox struct {
+	events    []event
+	published []string
+	failed    map[string]time.Time
+}
+
+func (f *fakeOutbox) ClaimEvents(_ context.Context, _ time.Time, _ time.Time, limit int) ([]event, error) {
+	n := min(limit, len(f.events))
+	claimed := f.events[:n]
+	f.events = f.events[n:]
+
+	return claimed, nil
+}
+
+func (f *fakeOutbox) MarkPublished(_ context.Context, eventID string, _ time.Time) error {
+	f.published = append(f.published, eventID)
+
+	return nil
+}
+
+func (f *fakeOutbox) MarkFailed(_ context.Context, eventID string, nextAttemptAt time.Time, _ string) error {
+	f.failed[eventID] = nextAttemptAt
+
+	return nil
+}
+
+func (f *fakeOutbox) PurgePublished(_ context.Context, _ time.Time, _ int) (int64, error) {
+	return 0, nil
+}
+
+// fakePublisher rejects the events with the given ids.
+type fakePublisher struct {
+	reject map[string]bool
+	sent   []string
+}
+
+func (p *fakePublisher) Publish(_ context.Context, e event) error {
+	p.sent = append(p.sent, e.id)
+	if p.reject[e.id] {
+		return errors.New("broker is unavailable")
+	}
+
+	return nil
+}
+
+func TestRelay(t *testing.T) {
+	st := &fakeOutbox{
+		events: []event{
+			{id: "1", aggregate: "a"},
+			{id: "2", aggregate: "b", attempts: 3},
+			{id: "3", aggregate: "a"},
+			{id: "4", aggregate: "b"},
+		},
+		failed: make(map[string]time.Time),
+	}
+	publisher := &fakePublisher{reject: map[string]bool{"2": true}}
+
+	s := New[event](slog.New(slog.NewTextHandler(io.Discard, nil)), st, st, publisher)
+
+	before := time.Now().UTC()
+	require.NoError(t, s.Relay(context.Background()))
+
+	assert.Equal(t, []string{"1", "2", "3"}, publisher.sent, "later events of the failed aggregate wait")
+	assert.Equal(t, []string{"1", "3"}, st.published)
+	require.Contains(t, st.failed, "2")
+	assert.WithinDuration(t, before.Add(4*time.Second), st.failed["2"], time.Second)
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, minRetryDelay, retryDelay(0))
+	assert.Equal(t, minRetryDelay, retryDelay(1))
+	assert.Equal(t, 2*minRetryDelay, retryDelay(2))
+	assert.Equal(t, maxRetryDelay, retryDelay(100))
+}
@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Job is the background work run periodically by the scheduler.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type App struct {
+	log    *slog.Logger
+	jobs   []Job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a new instance of the scheduler app struct.
+func New(
+	log *slog.Logger,
+	jobs ...Job,
+) *App {
+	return &App{
+		log:  log,
+		jobs: jobs,
+	}
+}
+
+// Run starts every job in its own goroutine.
+// Jobs are run once right away, so the work missed while
+// the service was down is done on start, and then on every interval.
+func (a *App) Run() {
+	const op = "scheduler.Run"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+
+	for _, job := range a.jobs {
+		a.wg.Add(1)
+
+		go func(job Job) {
+			defer a.wg.Done()
+
+			a.loop(ctx, job)
+		}(job)
+	}
+
+	a.log.Info("scheduler is running", slog.String("op", op), slog.Int("jobs", len(a.jobs)))
+}
+
+// Stop stops the scheduler and waits for the running jobs to finish.
+func (a *App) Stop() {
+	const op = "scheduler.Stop"
+
+	a.log.With(
+		slog.String("op", op),
+	).Info("stopping scheduler")
+
+	if a.cancel != nil {
+		a.cancel()
+	}
+
+	a.wg.Wait()
+}
+
+func (a *App) loop(ctx context.Context, job Job) {
+	log := a.log.With(
+		slog.String("job", job.Name),
+	)
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Error("job failed", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}